@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"kvstore/internal/audit"
+	"os"
+	"strings"
+)
+
+// Verifies the hash chain of an audit log and prints the entries matching
+// the filters. Exits 1 when the log has been tampered with.
+func main() {
+	var (
+		key       = flag.String("key", "", "only show entries for this key")
+		prefix    = flag.String("prefix", "", "only show entries for keys starting with this prefix")
+		principal = flag.String("principal", "", "only show entries made by this principal")
+		namespace = flag.String("namespace", "", "only show entries in this namespace")
+		asJSON    = flag.Bool("json", false, "print entries as JSON lines")
+		quiet     = flag.Bool("q", false, "only verify, print no entries")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <audit log>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	f, err := os.Open(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	defer f.Close()
+
+	match := func(e audit.Entry) bool {
+		return (*key == "" || e.Key == *key) &&
+			(*prefix == "" || strings.HasPrefix(e.Key, *prefix)) &&
+			(*principal == "" || e.Principal == *principal) &&
+			(*namespace == "" || e.Namespace == *namespace)
+	}
+
+	var total, matched int
+	// pending holds the Pending entries no outcome has referred to yet.
+	pending := make(map[int64]bool)
+	err = audit.Verify(f, func(e audit.Entry) {
+		total++
+		if e.Result == audit.ResultPending {
+			pending[e.Seq] = true
+		}
+		delete(pending, e.Ref)
+		if *quiet || !match(e) {
+			return
+		}
+		matched++
+
+		if *asJSON {
+			line, _ := json.Marshal(e)
+			fmt.Println(string(line))
+			return
+		}
+		fmt.Println(format(e))
+	})
+
+	switch {
+	case errors.Is(err, audit.ErrTampered):
+		fmt.Fprintf(os.Stderr, "❌ %v (%d entries before it verified)\n", err, total)
+		os.Exit(1)
+	case err != nil:
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if len(pending) > 0 {
+		// The server stopped between recording these calls and their result,
+		// so they may or may not have been applied.
+		fmt.Fprintf(os.Stderr, "⚠️  %d operations have no recorded result\n", len(pending))
+	}
+	if *quiet {
+		fmt.Fprintf(os.Stderr, "✅ %d entries verified\n", total)
+	} else {
+		fmt.Fprintf(os.Stderr, "✅ %d entries verified, %d shown\n", total, matched)
+	}
+}
+
+func format(e audit.Entry) string {
+	principal := e.Principal
+	if principal == "" {
+		principal = "-"
+	}
+
+	out := fmt.Sprintf("#%d %s %s %s %s", e.Seq, e.Time.Format("2006-01-02T15:04:05.000Z07:00"), principal, e.Operation, e.Result)
+	if e.Namespace != "" {
+		out += " namespace=" + e.Namespace
+	}
+	if e.Key != "" {
+		out += " key=" + e.Key
+	}
+	if e.Error != "" {
+		out += fmt.Sprintf(" error=%q", e.Error)
+	}
+	if e.Ref != 0 {
+		out += fmt.Sprintf(" ref=#%d", e.Ref)
+	}
+	return out
+}
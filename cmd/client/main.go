@@ -3,25 +3,85 @@ package main
 import (
 	"bufio"
 	"context"
+	"flag"
 	"fmt"
+	"kvstore/internal/redirect"
+	"kvstore/internal/shard"
+	"kvstore/internal/tlsconfig"
+	"kvstore/internal/tracing"
 	pb "kvstore/pkg/pb/api/proto"
 	"log"
+	"math"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 type InteractiveClient struct {
 	client pb.KVStoreClient
+	admin  pb.AdminClient
 	conn   *grpc.ClientConn
+	// redirects follows writes sent to a cluster follower to the leader,
+	// and keys sent to the wrong shard to their owner.
+	redirects *redirect.Follower
+	// dialOpts connect to the other nodes of a sharded deployment.
+	dialOpts []grpc.DialOption
+	// namespace is sent with every key operation; empty is the default
+	// namespace.
+	namespace string
+	// consistency and maxStaleness are sent with every read.
+	consistency  pb.ReadConsistency
+	maxStaleness *durationpb.Duration
+	// lastIndex is the highest log index seen in a response. Sequential
+	// reads wait for it, so the client reads its own writes on any node.
+	lastIndex uint64
 }
 
-func NewInteractiveClient(serverAddr string) (*InteractiveClient, error) {
-	conn, err := grpc.Dial(serverAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+// consistencies are the modes accepted by the consistency command.
+var consistencies = map[string]pb.ReadConsistency{
+	"linearizable": pb.ReadConsistency_READ_CONSISTENCY_LINEARIZABLE,
+	"lease":        pb.ReadConsistency_READ_CONSISTENCY_LEASE,
+	"sequential":   pb.ReadConsistency_READ_CONSISTENCY_SEQUENTIAL,
+	"stale":        pb.ReadConsistency_READ_CONSISTENCY_STALE,
+}
+
+// tokenCredentials sends a bearer token with every call.
+type tokenCredentials string
+
+func (t tokenCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+// RequireTransportSecurity is false so tokens also work against plaintext
+// development servers; main warns when that happens.
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+func NewInteractiveClient(serverAddr string, creds credentials.TransportCredentials, token string) (*InteractiveClient, error) {
+	// Every call is traced as a client span whose context is propagated to
+	// the server in the request metadata.
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	}
+	if token != "" {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(tokenCredentials(token)))
+	}
+
+	redirects := redirect.NewFollower(dialOpts...)
+	peerOpts := dialOpts
+	dialOpts = append(dialOpts[:len(dialOpts):len(dialOpts)], grpc.WithChainUnaryInterceptor(redirects.UnaryClientInterceptor()))
+
+	conn, err := grpc.Dial(serverAddr, dialOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect: %v", err)
 	}
@@ -29,8 +89,11 @@ func NewInteractiveClient(serverAddr string) (*InteractiveClient, error) {
 	client := pb.NewKVStoreClient(conn)
 
 	return &InteractiveClient{
-		client: client,
-		conn:   conn,
+		client:    client,
+		admin:     pb.NewAdminClient(conn),
+		conn:      conn,
+		redirects: redirects,
+		dialOpts:  peerOpts,
 	}, nil
 }
 
@@ -38,6 +101,7 @@ func (ic *InteractiveClient) Close() {
 	if ic.conn != nil {
 		ic.conn.Close()
 	}
+	ic.redirects.Close()
 }
 
 // createContext creates a new context with timeout for each operation
@@ -54,7 +118,11 @@ func (ic *InteractiveClient) Run() {
 	fmt.Println()
 
 	for {
-		fmt.Print("kvstore> ")
+		if ic.namespace != "" {
+			fmt.Printf("kvstore[%s]> ", ic.namespace)
+		} else {
+			fmt.Print("kvstore> ")
+		}
 		if !scanner.Scan() {
 			break
 		}
@@ -89,8 +157,32 @@ func (ic *InteractiveClient) Run() {
 			ic.handleSet(args)
 		case "delete":
 			ic.handleDelete(args)
+		case "incr":
+			ic.handleIncr(args)
+		case "sadd", "srem":
+			ic.handleMembers(command, args)
 		case "list":
 			ic.handleList(args)
+		case "reload":
+			ic.handleReload()
+		case "use":
+			ic.handleUse(args)
+		case "consistency":
+			ic.handleConsistency(args)
+		case "ns":
+			ic.handleNamespace(args)
+		case "usage":
+			ic.handleUsage(args)
+		case "slowlog":
+			ic.handleSlowLog(args)
+		case "cluster":
+			ic.handleCluster(args)
+		case "replication":
+			ic.handleReplication(args)
+		case "shards":
+			ic.handleShards(args)
+		case "gossip":
+			ic.handleGossip(args)
 		case "clear":
 			fmt.Print("\033[H\033[2J") // Clear screen
 		default:
@@ -105,7 +197,41 @@ func (ic *InteractiveClient) showHelp() {
 	fmt.Println("  get <key>                    - Get value for a key")
 	fmt.Println("  set <key> <value> [ttl]      - Set key-value pair with optional TTL")
 	fmt.Println("  delete <key>                 - Delete a key")
+	fmt.Println("  incr <key> [delta]           - Add delta (default 1) to a counter")
+	fmt.Println("  sadd <key> <member>...       - Add members to a set")
+	fmt.Println("  srem <key> <member>...       - Remove members from a set")
+	fmt.Println("                                 (counters and sets need active-active replication)")
 	fmt.Println("  list [limit]                 - List all key-value pairs")
+	fmt.Println("  reload                       - Reload the server configuration")
+	fmt.Println("  use [namespace]              - Switch namespace (default if omitted)")
+	fmt.Println("  consistency [mode] [max_staleness]")
+	fmt.Println("                               - Show or set the read consistency: linearizable, lease,")
+	fmt.Println("                                 sequential (default) or stale, e.g. consistency stale 5s")
+	fmt.Println("  ns list                      - List namespaces")
+	fmt.Println("  ns create <name> [ttl] [max_keys] [max_bytes]")
+	fmt.Println("                               - Create a namespace with default TTL and quota")
+	fmt.Println("  ns quota <name> <max_keys> [max_bytes] [max_value_bytes]")
+	fmt.Println("                               - Change a namespace quota (0 is unlimited)")
+	fmt.Println("  ns drop <name>               - Drop a namespace and all its keys")
+	fmt.Println("  usage [principal]            - Show quota usage")
+	fmt.Println("  slowlog [limit]              - Show the slowest recent operations, newest first")
+	fmt.Println("  slowlog reset                - Clear the slow log")
+	fmt.Println("  cluster members              - Show the cluster membership")
+	fmt.Println("  cluster add <id> <raft_addr> <grpc_addr>")
+	fmt.Println("                               - Add a node as a learner")
+	fmt.Println("  cluster promote <id>         - Make a learner a voter")
+	fmt.Println("  cluster remove <id>          - Remove a node from the cluster")
+	fmt.Println("  cluster transfer [id]        - Hand leadership to another voter")
+	fmt.Println("  replication                  - Show replication status")
+	fmt.Println("  replication check            - Compare a replica with its primary and show the key")
+	fmt.Println("                                 ranges that diverged, without repairing them")
+	fmt.Println("  shards                       - Show the shard map and each node's share of keys")
+	fmt.Println("  shards owner <key>           - Show which node owns a key")
+	fmt.Println("  shards status                - Show the migration status of every node")
+	fmt.Println("  shards rebalance <id=addr,...>")
+	fmt.Println("                               - Move keys onto a new set of nodes while serving traffic;")
+	fmt.Println("                                 run it again to resume an interrupted rebalance")
+	fmt.Println("  gossip                       - Show the servers this one learned of by gossip")
 	fmt.Println("  clear                        - Clear screen")
 	fmt.Println("  help                         - Show this help")
 	fmt.Println("  quit/exit                    - Exit the client")
@@ -122,11 +248,18 @@ func (ic *InteractiveClient) handleGet(args []string) {
 	ctx, cancel := ic.createContext()
 	defer cancel()
 
-	resp, err := ic.client.Get(ctx, &pb.GetRequest{Key: key})
+	resp, err := ic.client.Get(ctx, &pb.GetRequest{
+		Key:          key,
+		Namespace:    ic.namespace,
+		Consistency:  ic.consistency,
+		MinIndex:     ic.lastIndex,
+		MaxStaleness: ic.maxStaleness,
+	})
 	if err != nil {
 		fmt.Printf("❌ Get failed: %v\n", err)
 		return
 	}
+	ic.seen(resp.GetIndex())
 
 	if resp.Found {
 		fmt.Printf("✅ Key: %s\n", key)
@@ -146,8 +279,9 @@ func (ic *InteractiveClient) handleSet(args []string) {
 	value := args[1]
 
 	req := &pb.SetRequest{
-		Key:   key,
-		Value: value,
+		Key:       key,
+		Value:     value,
+		Namespace: ic.namespace,
 	}
 
 	// Handle optional TTL parameter
@@ -168,6 +302,7 @@ func (ic *InteractiveClient) handleSet(args []string) {
 		fmt.Printf("❌ Set failed: %v\n", err)
 		return
 	}
+	ic.seen(resp.GetIndex())
 
 	if resp.Success {
 		fmt.Printf("✅ Successfully set key '%s' with value '%s'\n", key, value)
@@ -189,11 +324,12 @@ func (ic *InteractiveClient) handleDelete(args []string) {
 	ctx, cancel := ic.createContext()
 	defer cancel()
 
-	resp, err := ic.client.Delete(ctx, &pb.DeleteRequest{Key: key})
+	resp, err := ic.client.Delete(ctx, &pb.DeleteRequest{Key: key, Namespace: ic.namespace})
 	if err != nil {
 		fmt.Printf("❌ Delete failed: %v\n", err)
 		return
 	}
+	ic.seen(resp.GetIndex())
 
 	if resp.Existed {
 		fmt.Printf("✅ Key '%s' deleted successfully\n", key)
@@ -202,6 +338,65 @@ func (ic *InteractiveClient) handleDelete(args []string) {
 	}
 }
 
+func (ic *InteractiveClient) handleIncr(args []string) {
+	if len(args) < 1 || len(args) > 2 {
+		fmt.Println("Usage: incr <key> [delta]")
+		return
+	}
+
+	delta := int64(1)
+	if len(args) == 2 {
+		var err error
+		if delta, err = strconv.ParseInt(args[1], 10, 64); err != nil {
+			fmt.Printf("❌ Invalid delta: %v\n", err)
+			return
+		}
+	}
+
+	ctx, cancel := ic.createContext()
+	defer cancel()
+
+	resp, err := ic.client.Increment(ctx, &pb.IncrementRequest{Key: args[0], Namespace: ic.namespace, Delta: delta})
+	if err != nil {
+		fmt.Printf("❌ Increment failed: %v\n", err)
+		return
+	}
+	ic.seen(resp.GetIndex())
+
+	fmt.Printf("✅ Key '%s' counts %d\n", args[0], resp.Value)
+}
+
+func (ic *InteractiveClient) handleMembers(command string, args []string) {
+	if len(args) < 2 {
+		fmt.Printf("Usage: %s <key> <member>...\n", command)
+		return
+	}
+
+	ctx, cancel := ic.createContext()
+	defer cancel()
+
+	req := &pb.MembersRequest{Key: args[0], Namespace: ic.namespace, Members: args[1:]}
+	var (
+		resp *pb.MembersResponse
+		err  error
+	)
+	if command == "sadd" {
+		resp, err = ic.client.AddMembers(ctx, req)
+	} else {
+		resp, err = ic.client.RemoveMembers(ctx, req)
+	}
+	if err != nil {
+		fmt.Printf("❌ Updating members failed: %v\n", err)
+		return
+	}
+	ic.seen(resp.GetIndex())
+
+	fmt.Printf("✅ Set '%s' has %d member(s)\n", args[0], len(resp.Members))
+	for _, m := range resp.Members {
+		fmt.Printf("  %s\n", m)
+	}
+}
+
 func (ic *InteractiveClient) handleList(args []string) {
 	var req *pb.ListRequest
 	if len(args) == 1 {
@@ -212,12 +407,16 @@ func (ic *InteractiveClient) handleList(args []string) {
 			return
 		}
 		limit := int32(parsedLimit)
-		req = &pb.ListRequest{Limit: &limit}
+		req = &pb.ListRequest{Limit: &limit, Namespace: ic.namespace}
 	} else {
 		// No limit provided - don't set the field
-		req = &pb.ListRequest{}
+		req = &pb.ListRequest{Namespace: ic.namespace}
 	}
 
+	req.Consistency = ic.consistency
+	req.MinIndex = ic.lastIndex
+	req.MaxStaleness = ic.maxStaleness
+
 	ctx, cancel := ic.createContext()
 	defer cancel()
 
@@ -226,6 +425,7 @@ func (ic *InteractiveClient) handleList(args []string) {
 		fmt.Printf("❌ List failed: %v\n", err)
 		return
 	}
+	ic.seen(resp.GetIndex())
 
 	if len(resp.Pairs) == 0 {
 		fmt.Println("📭 No key-value pairs found")
@@ -254,13 +454,754 @@ func (ic *InteractiveClient) handleList(args []string) {
 	fmt.Println("└─────────────────┴─────────────────┘")
 }
 
+func (ic *InteractiveClient) handleReload() {
+	ctx, cancel := ic.createContext()
+	defer cancel()
+
+	resp, err := ic.admin.ReloadConfig(ctx, &pb.ReloadConfigRequest{})
+	if err != nil {
+		fmt.Printf("❌ Reload failed: %v\n", err)
+		return
+	}
+
+	if len(resp.Applied) == 0 && len(resp.RequiresRestart) == 0 {
+		fmt.Println("✅ Configuration reloaded, nothing changed")
+		return
+	}
+
+	fmt.Println("✅ Configuration reloaded")
+	for _, setting := range resp.Applied {
+		fmt.Printf("🔄 Applied: %s\n", setting)
+	}
+	for _, setting := range resp.RequiresRestart {
+		fmt.Printf("⚠️  Requires restart: %s\n", setting)
+	}
+}
+
+func (ic *InteractiveClient) handleUse(args []string) {
+	if len(args) > 1 {
+		fmt.Println("Usage: use [namespace]")
+		return
+	}
+
+	ic.namespace = ""
+	if len(args) == 1 {
+		ic.namespace = args[0]
+	}
+	fmt.Printf("✅ Using namespace '%s'\n", namespaceOrDefault(ic.namespace))
+}
+
+func (ic *InteractiveClient) handleConsistency(args []string) {
+	if len(args) > 2 {
+		fmt.Println("Usage: consistency [linearizable|lease|sequential|stale] [max_staleness]")
+		return
+	}
+
+	if len(args) > 0 {
+		consistency, ok := consistencies[args[0]]
+		if !ok {
+			fmt.Printf("❌ Unknown consistency '%s'\n", args[0])
+			return
+		}
+
+		var maxStaleness *durationpb.Duration
+		if len(args) == 2 {
+			d, err := time.ParseDuration(args[1])
+			if err != nil || consistency != pb.ReadConsistency_READ_CONSISTENCY_STALE {
+				fmt.Println("❌ max_staleness must be a duration and only applies to stale reads")
+				return
+			}
+			maxStaleness = durationpb.New(d)
+		}
+
+		ic.consistency = consistency
+		ic.maxStaleness = maxStaleness
+	}
+
+	mode := "sequential"
+	for name, c := range consistencies {
+		if c == ic.consistency {
+			mode = name
+		}
+	}
+	if ic.maxStaleness != nil {
+		fmt.Printf("✅ Read consistency: %s, at most %s behind the leader\n", mode, ic.maxStaleness.AsDuration())
+	} else {
+		fmt.Printf("✅ Read consistency: %s\n", mode)
+	}
+}
+
+// seen records the log index of a response.
+func (ic *InteractiveClient) seen(index uint64) {
+	ic.lastIndex = max(ic.lastIndex, index)
+}
+
+func (ic *InteractiveClient) handleNamespace(args []string) {
+	if len(args) == 0 {
+		fmt.Println("Usage: ns list | ns create <name> [default_ttl] [max_keys] [max_bytes] | ns quota <name> <max_keys> [max_bytes] [max_value_bytes] | ns drop <name>")
+		return
+	}
+
+	ctx, cancel := ic.createContext()
+	defer cancel()
+
+	switch args[0] {
+	case "list":
+		resp, err := ic.admin.ListNamespaces(ctx, &pb.ListNamespacesRequest{})
+		if err != nil {
+			fmt.Printf("❌ List namespaces failed: %v\n", err)
+			return
+		}
+		for _, ns := range resp.Namespaces {
+			fmt.Printf("📁 %s (default TTL %ds): %s\n", ns.Name, ns.DefaultTtlSeconds, formatUsage(ns.Usage, ns.Quota))
+		}
+
+	case "create":
+		if len(args) < 2 || len(args) > 5 {
+			fmt.Println("Usage: ns create <name> [default_ttl] [max_keys] [max_bytes]")
+			return
+		}
+		values, err := parseInts(args[2:])
+		if err != nil {
+			fmt.Printf("❌ Invalid value: %v\n", err)
+			return
+		}
+		values = append(values, 0, 0, 0)
+		req := &pb.CreateNamespaceRequest{
+			Name:              args[1],
+			DefaultTtlSeconds: values[0],
+			Quota:             &pb.Quota{MaxKeys: values[1], MaxBytes: values[2]},
+		}
+		if _, err := ic.admin.CreateNamespace(ctx, req); err != nil {
+			fmt.Printf("❌ Create namespace failed: %v\n", err)
+			return
+		}
+		fmt.Printf("✅ Namespace '%s' created\n", req.Name)
+
+	case "drop":
+		if len(args) != 2 {
+			fmt.Println("Usage: ns drop <name>")
+			return
+		}
+		if _, err := ic.admin.DropNamespace(ctx, &pb.DropNamespaceRequest{Name: args[1]}); err != nil {
+			fmt.Printf("❌ Drop namespace failed: %v\n", err)
+			return
+		}
+		if ic.namespace == args[1] {
+			ic.namespace = ""
+		}
+		fmt.Printf("✅ Namespace '%s' dropped\n", args[1])
+
+	case "quota":
+		if len(args) < 3 || len(args) > 5 {
+			fmt.Println("Usage: ns quota <name> <max_keys> [max_bytes] [max_value_bytes]")
+			return
+		}
+		values, err := parseInts(args[2:])
+		if err != nil {
+			fmt.Printf("❌ Invalid value: %v\n", err)
+			return
+		}
+		values = append(values, 0, 0)
+		resp, err := ic.admin.SetNamespaceQuota(ctx, &pb.SetNamespaceQuotaRequest{
+			Name:  args[1],
+			Quota: &pb.Quota{MaxKeys: values[0], MaxBytes: values[1], MaxValueBytes: values[2]},
+		})
+		if err != nil {
+			fmt.Printf("❌ Set quota failed: %v\n", err)
+			return
+		}
+		fmt.Printf("✅ Namespace '%s': %s\n", args[1], formatUsage(resp.Namespace.Usage, resp.Namespace.Quota))
+
+	default:
+		fmt.Printf("Unknown namespace command: %s\n", args[0])
+	}
+}
+
+func (ic *InteractiveClient) handleUsage(args []string) {
+	if len(args) > 1 {
+		fmt.Println("Usage: usage [principal]")
+		return
+	}
+
+	req := &pb.GetUsageRequest{}
+	if len(args) == 1 {
+		req.Principal = args[0]
+	}
+
+	ctx, cancel := ic.createContext()
+	defer cancel()
+
+	resp, err := ic.admin.GetUsage(ctx, req)
+	if err != nil {
+		fmt.Printf("❌ Get usage failed: %v\n", err)
+		return
+	}
+
+	for _, ns := range resp.Namespaces {
+		fmt.Printf("📁 namespace %s: %s\n", ns.Name, formatUsage(ns.Usage, ns.Quota))
+	}
+	for _, p := range resp.Principals {
+		fmt.Printf("👤 principal %s: %s\n", p.Principal, formatUsage(p.Usage, p.Quota))
+	}
+}
+
+func (ic *InteractiveClient) handleCluster(args []string) {
+	usage := "Usage: cluster members | cluster add <id> <raft_addr> <grpc_addr> | cluster promote <id> | cluster remove <id> | cluster transfer [id]"
+	if len(args) == 0 {
+		fmt.Println(usage)
+		return
+	}
+
+	ctx, cancel := ic.createContext()
+	defer cancel()
+
+	var (
+		resp *pb.MembershipChangeResponse
+		err  error
+		done string
+	)
+	switch {
+	case args[0] == "members" && len(args) == 1:
+		resp, err := ic.admin.GetMembership(ctx, &pb.GetMembershipRequest{})
+		if err != nil {
+			fmt.Printf("❌ Get membership failed: %v\n", err)
+			return
+		}
+		fmt.Printf("🖧 Cluster membership as seen by %s (%s):\n", resp.NodeId, resp.State)
+		printMembership(resp.Membership)
+		return
+	case args[0] == "add" && len(args) == 4:
+		resp, err = ic.admin.AddLearner(ctx, &pb.AddLearnerRequest{Id: args[1], RaftAddr: args[2], GrpcAddr: args[3]})
+		done = fmt.Sprintf("Node '%s' added as a learner", args[1])
+	case args[0] == "promote" && len(args) == 2:
+		resp, err = ic.admin.PromoteLearner(ctx, &pb.PromoteLearnerRequest{Id: args[1]})
+		done = fmt.Sprintf("Node '%s' promoted to voter", args[1])
+	case args[0] == "remove" && len(args) == 2:
+		resp, err = ic.admin.RemoveNode(ctx, &pb.RemoveNodeRequest{Id: args[1]})
+		done = fmt.Sprintf("Node '%s' removed", args[1])
+	case args[0] == "transfer" && len(args) <= 2:
+		req := &pb.TransferLeadershipRequest{}
+		if len(args) == 2 {
+			req.Id = args[1]
+		}
+		resp, err = ic.admin.TransferLeadership(ctx, req)
+		done = "Leadership transferred"
+	default:
+		fmt.Println(usage)
+		return
+	}
+
+	if err != nil {
+		fmt.Printf("❌ Cluster %s failed: %v\n", args[0], err)
+		return
+	}
+	fmt.Printf("✅ %s\n", done)
+	printMembership(resp.Membership)
+}
+
+func printMembership(m *pb.Membership) {
+	for _, member := range m.GetMembers() {
+		role := "voter"
+		if member.Role == pb.ClusterMember_ROLE_LEARNER {
+			role = "learner"
+		}
+		if member.Leader {
+			role += ", leader"
+		}
+		fmt.Printf("  %-10s raft=%-21s grpc=%-21s %s\n", member.Id, member.RaftAddr, member.GrpcAddr, role)
+	}
+}
+
+func (ic *InteractiveClient) handleReplication(args []string) {
+	switch {
+	case len(args) == 1 && args[0] == "check":
+		ic.handleReplicationCheck()
+		return
+	case len(args) != 0:
+		fmt.Println("Usage: replication | replication check")
+		return
+	}
+
+	ctx, cancel := ic.createContext()
+	defer cancel()
+
+	resp, err := ic.admin.GetReplicationStatus(ctx, &pb.GetReplicationStatusRequest{})
+	if err != nil {
+		fmt.Printf("❌ Get replication status failed: %v\n", err)
+		return
+	}
+
+	switch resp.Role {
+	case pb.GetReplicationStatusResponse_ROLE_PRIMARY:
+		fmt.Printf("🔁 Primary, log %s at offset %d, %d replica(s) connected:\n", resp.LogId, resp.Offset, len(resp.Replicas))
+		for _, r := range resp.Replicas {
+			fmt.Printf("  %-24s from %-21s sent up to %d (lag %d), connected %s\n", r.Id, r.Addr, r.Offset,
+				resp.Offset-min(resp.Offset, r.Offset), r.ConnectedSince.AsTime().Local().Format(time.DateTime))
+		}
+	case pb.GetReplicationStatusResponse_ROLE_REPLICA:
+		state := "disconnected"
+		if resp.Connected {
+			state = "connected"
+		}
+		fmt.Printf("🔁 Replica of %s (%s), log %s\n", resp.PrimaryAddr, state, resp.LogId)
+		fmt.Printf("  Applied offset %d of %d (lag %d), %d resync(s)\n", resp.Offset, resp.PrimaryOffset,
+			resp.PrimaryOffset-min(resp.PrimaryOffset, resp.Offset), resp.Resyncs)
+		if resp.LastContact != nil {
+			fmt.Printf("  Last heard from the primary %s ago\n", time.Since(resp.LastContact.AsTime()).Round(time.Millisecond))
+		}
+		if resp.LastRepair != nil {
+			fmt.Printf("  Anti-entropy last ran %s ago, %d range(s) and %d key(s) repaired in total\n",
+				time.Since(resp.LastRepair.AsTime()).Round(time.Millisecond), resp.RangesRepaired, resp.KeysRepaired)
+		}
+		if resp.LastRepairError != "" {
+			fmt.Printf("  ⚠️  Last anti-entropy pass failed: %s\n", resp.LastRepairError)
+		}
+	case pb.GetReplicationStatusResponse_ROLE_ACTIVE:
+		fmt.Printf("🔁 Active site %s, log %s at offset %d, %d peer(s):\n", resp.SiteId, resp.LogId, resp.Offset, len(resp.Peers))
+		for _, p := range resp.Peers {
+			state := "disconnected"
+			if p.Connected {
+				state = "connected"
+			}
+			site := p.SiteId
+			if site == "" {
+				site = "?"
+			}
+			fmt.Printf("  %-10s at %-21s %-12s merged %d of %d (lag %d), %d resync(s)", site, p.Addr, state,
+				p.Offset, p.PeerOffset, p.PeerOffset-min(p.PeerOffset, p.Offset), p.Resyncs)
+			if p.LastContact != nil {
+				fmt.Printf(", last heard %s ago", time.Since(p.LastContact.AsTime()).Round(time.Millisecond))
+			}
+			fmt.Println()
+		}
+	}
+}
+
+func (ic *InteractiveClient) handleReplicationCheck() {
+	ctx, cancel := ic.createContext()
+	defer cancel()
+
+	resp, err := ic.admin.CheckConsistency(ctx, &pb.CheckConsistencyRequest{})
+	if err != nil {
+		fmt.Printf("❌ Consistency check failed: %v\n", err)
+		return
+	}
+
+	fmt.Printf("🔍 Compared %d namespace(s) of %d key ranges at offset %d (primary at %d)\n",
+		resp.Namespaces, resp.RangesPerNamespace, resp.Offset, resp.PrimaryOffset)
+	if len(resp.Divergent) == 0 {
+		fmt.Println("✅ The replica matches its primary")
+		return
+	}
+	fmt.Printf("⚠️  %d divergent range(s):\n", len(resp.Divergent))
+	for _, d := range resp.Divergent {
+		if d.MissingOn != "" {
+			fmt.Printf("  %-20s whole namespace, missing on the %s\n", d.Namespace, d.MissingOn)
+			continue
+		}
+		fmt.Printf("  %-20s range %-6d key hashes %016x-%016x\n", d.Namespace, d.Range, d.FirstHash, d.LastHash)
+	}
+	if resp.Offset > resp.PrimaryOffset {
+		fmt.Println("  Writes arrived during the check; some of these may already agree")
+	}
+}
+
+func (ic *InteractiveClient) handleShards(args []string) {
+	usage := "Usage: shards | shards owner <key> | shards status | shards rebalance <id=addr,...>"
+	switch {
+	case len(args) == 1 && args[0] == "status":
+		ic.handleShardStatus()
+		return
+	case len(args) == 2 && args[0] == "rebalance":
+		ic.handleRebalance(args[1])
+		return
+	case !(len(args) == 0 || (len(args) == 2 && args[0] == "owner")):
+		fmt.Println(usage)
+		return
+	}
+
+	ctx, cancel := ic.createContext()
+	defer cancel()
+
+	resp, err := ic.client.GetShardMap(ctx, &pb.GetShardMapRequest{})
+	if err != nil {
+		fmt.Printf("❌ Get shard map failed: %v\n", err)
+		return
+	}
+	sm := resp.ShardMap
+
+	if len(args) == 2 {
+		m, err := shard.MapFromProto(sm)
+		if err != nil {
+			fmt.Printf("❌ Invalid shard map: %v\n", err)
+			return
+		}
+		owner := m.Owner(args[1])
+		fmt.Printf("🧭 '%s' belongs to %s at %s\n", args[1], owner.ID, owner.Addr)
+		return
+	}
+
+	// Each token owns the arc of the ring since the previous one.
+	share := make(map[string]float64)
+	for i, t := range sm.Tokens {
+		prev := sm.Tokens[(i+len(sm.Tokens)-1)%len(sm.Tokens)].Token
+		share[t.NodeId] += float64(t.Token-prev) / math.Pow(2, 64)
+	}
+
+	fmt.Printf("🗺️  Shard map version %d, %d virtual nodes each", sm.Version, sm.VirtualNodes)
+	if resp.NodeId != "" {
+		fmt.Printf(", served by %s", resp.NodeId)
+	}
+	fmt.Println(":")
+	for _, n := range sm.Nodes {
+		fmt.Printf("  %-10s grpc=%-21s %5.1f%% of keys\n", n.Id, n.GrpcAddr, 100*share[n.Id])
+	}
+}
+
+// shardNodes returns the nodes of every map, each once, in order.
+func shardNodes(maps ...*pb.ShardMap) []*pb.ShardNode {
+	var nodes []*pb.ShardNode
+	seen := make(map[string]bool)
+	for _, m := range maps {
+		for _, n := range m.Nodes {
+			if !seen[n.Id] {
+				seen[n.Id] = true
+				nodes = append(nodes, n)
+			}
+		}
+	}
+	return nodes
+}
+
+// onNode calls fn with an Admin client of the node at addr.
+func (ic *InteractiveClient) onNode(addr string, fn func(ctx context.Context, admin pb.AdminClient) error) error {
+	conn, err := grpc.NewClient(addr, ic.dialOpts...)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, cancel := ic.createContext()
+	defer cancel()
+	return fn(ctx, pb.NewAdminClient(conn))
+}
+
+func (ic *InteractiveClient) handleShardStatus() {
+	ctx, cancel := ic.createContext()
+	defer cancel()
+
+	resp, err := ic.client.GetShardMap(ctx, &pb.GetShardMapRequest{})
+	if err != nil {
+		fmt.Printf("❌ Get shard map failed: %v\n", err)
+		return
+	}
+
+	fmt.Printf("🚚 Migration status of the nodes in shard map version %d:\n", resp.ShardMap.Version)
+	for _, n := range resp.ShardMap.Nodes {
+		err := ic.onNode(n.GrpcAddr, func(ctx context.Context, admin pb.AdminClient) error {
+			st, err := admin.GetMigrationStatus(ctx, &pb.GetMigrationStatusRequest{})
+			if err != nil {
+				return err
+			}
+			printMigrationStatus(st)
+			return nil
+		})
+		if err != nil {
+			fmt.Printf("  %-10s ❌ %v\n", n.Id, err)
+		}
+	}
+}
+
+func printMigrationStatus(st *pb.MigrationStatus) {
+	state := strings.ToLower(strings.TrimPrefix(st.State.String(), "MIGRATION_STATE_"))
+	fmt.Printf("  %-10s %-9s version=%d", st.NodeId, state, st.Version)
+	if st.TargetVersion != 0 {
+		fmt.Printf(" target=%d", st.TargetVersion)
+	}
+	fmt.Printf(" sent=%d received=%d remaining=%d", st.KeysSent, st.KeysReceived, st.KeysRemaining)
+	if st.LastError != "" {
+		fmt.Printf(" last_error=%q", st.LastError)
+	}
+	fmt.Println()
+}
+
+// handleRebalance moves the deployment onto the nodes in spec: it starts the
+// migration on every node of the current and new maps, waits for all of them
+// to hand their keys over and then switches them to the new map. Each step
+// does nothing on nodes that already took it, so an interrupted rebalance
+// resumes when run again.
+func (ic *InteractiveClient) handleRebalance(spec string) {
+	ctx, cancel := ic.createContext()
+	defer cancel()
+
+	resp, err := ic.client.GetShardMap(ctx, &pb.GetShardMapRequest{})
+	if err != nil {
+		fmt.Printf("❌ Get shard map failed: %v\n", err)
+		return
+	}
+	from := resp.ShardMap
+	to := &pb.ShardMap{Version: from.Version + 1, VirtualNodes: from.VirtualNodes}
+	for _, item := range strings.Split(spec, ",") {
+		id, addr, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok || id == "" || addr == "" {
+			fmt.Printf("❌ %q is not id=grpc_addr\n", item)
+			return
+		}
+		to.Nodes = append(to.Nodes, &pb.ShardNode{Id: id, GrpcAddr: addr})
+	}
+
+	// Joining nodes go first so they accept keys as soon as the others
+	// start sending them.
+	joining := make(map[string]bool)
+	for _, n := range to.Nodes {
+		joining[n.Id] = true
+	}
+	for _, n := range from.Nodes {
+		delete(joining, n.Id)
+	}
+	nodes := shardNodes(to, from)
+	sort.SliceStable(nodes, func(i, j int) bool { return joining[nodes[i].Id] && !joining[nodes[j].Id] })
+	fmt.Printf("🚚 Moving from shard map version %d to %d across %d nodes\n", from.Version, to.Version, len(nodes))
+	for _, n := range nodes {
+		err := ic.onNode(n.GrpcAddr, func(ctx context.Context, admin pb.AdminClient) error {
+			_, err := admin.BeginMigration(ctx, &pb.BeginMigrationRequest{From: from, To: to})
+			return err
+		})
+		if err != nil {
+			fmt.Printf("❌ Begin migration on %s failed: %v\n", n.Id, err)
+			return
+		}
+	}
+
+	for {
+		done := true
+		var sent, remaining uint64
+		for _, n := range nodes {
+			err := ic.onNode(n.GrpcAddr, func(ctx context.Context, admin pb.AdminClient) error {
+				st, err := admin.GetMigrationStatus(ctx, &pb.GetMigrationStatusRequest{})
+				if err != nil {
+					return err
+				}
+				if st.LastError != "" {
+					fmt.Printf("  ⚠️  %s: %s\n", n.Id, st.LastError)
+				}
+				sent += st.KeysSent
+				remaining += st.KeysRemaining
+				idle := st.State == pb.MigrationState_MIGRATION_STATE_IDLE && st.Version == to.Version
+				done = done && (idle || st.State == pb.MigrationState_MIGRATION_STATE_DONE)
+				return nil
+			})
+			if err != nil {
+				fmt.Printf("❌ Get migration status of %s failed: %v\n", n.Id, err)
+				return
+			}
+		}
+		fmt.Printf("  %d keys moved, %d left\n", sent, remaining)
+		if done {
+			break
+		}
+		time.Sleep(time.Second)
+	}
+
+	for _, n := range nodes {
+		err := ic.onNode(n.GrpcAddr, func(ctx context.Context, admin pb.AdminClient) error {
+			_, err := admin.CommitMigration(ctx, &pb.CommitMigrationRequest{Version: to.Version})
+			return err
+		})
+		if err != nil {
+			fmt.Printf("❌ Commit migration on %s failed: %v\n", n.Id, err)
+			return
+		}
+	}
+	fmt.Printf("✅ Now on shard map version %d\n", to.Version)
+}
+
+func (ic *InteractiveClient) handleGossip(args []string) {
+	if len(args) != 0 {
+		fmt.Println("Usage: gossip")
+		return
+	}
+
+	ctx, cancel := ic.createContext()
+	defer cancel()
+
+	resp, err := ic.admin.GetGossipMembers(ctx, &pb.GetGossipMembersRequest{})
+	if err != nil {
+		fmt.Printf("❌ Get gossip members failed: %v\n", err)
+		return
+	}
+
+	fmt.Printf("🗣  %d member(s) as seen by %s:\n", len(resp.Members), resp.NodeId)
+	for _, st := range resp.Members {
+		m := st.Member
+		state := strings.TrimPrefix(strings.ToLower(m.State.String()), "member_state_")
+		fmt.Printf("  %-10s %-21s %-8s incarnation=%-3d role=%s version=%s", m.Id, m.Addr, state, m.Incarnation,
+			m.Meta.GetRole(), m.Meta.GetVersion())
+		if m.Meta.GetShardId() != "" {
+			fmt.Printf(" shard=%s@%d", m.Meta.GetShardId(), m.Meta.GetShardMapVersion())
+		}
+		fmt.Printf(", for %s\n", time.Since(st.Since.AsTime()).Round(time.Second))
+	}
+}
+
+func (ic *InteractiveClient) handleSlowLog(args []string) {
+	if len(args) > 1 {
+		fmt.Println("Usage: slowlog [limit|reset]")
+		return
+	}
+
+	ctx, cancel := ic.createContext()
+	defer cancel()
+
+	if len(args) == 1 && args[0] == "reset" {
+		if _, err := ic.admin.ResetSlowLog(ctx, &pb.ResetSlowLogRequest{}); err != nil {
+			fmt.Printf("❌ Reset slow log failed: %v\n", err)
+			return
+		}
+		fmt.Println("✅ Slow log cleared")
+		return
+	}
+
+	req := &pb.GetSlowLogRequest{}
+	if len(args) == 1 {
+		limit, err := strconv.Atoi(args[0])
+		if err != nil {
+			fmt.Printf("❌ Invalid limit: %s\n", args[0])
+			return
+		}
+		req.Limit = int32(limit)
+	}
+
+	resp, err := ic.admin.GetSlowLog(ctx, req)
+	if err != nil {
+		fmt.Printf("❌ Get slow log failed: %v\n", err)
+		return
+	}
+
+	if len(resp.Operations) == 0 {
+		fmt.Printf("📭 No operations slower than %s\n", resp.Threshold.AsDuration())
+		return
+	}
+
+	fmt.Printf("🐢 Operations slower than %s:\n", resp.Threshold.AsDuration())
+	for _, op := range resp.Operations {
+		fmt.Printf("  #%d %s %s %s %s", op.Id,
+			op.StartTime.AsTime().Local().Format(time.DateTime), op.Duration.AsDuration(), op.Method, op.Code)
+		if op.Key != "" {
+			fmt.Printf(" key=%s", op.Key)
+		}
+		if op.Namespace != "" {
+			fmt.Printf(" namespace=%s", op.Namespace)
+		}
+		if op.Principal != "" {
+			fmt.Printf(" principal=%s", op.Principal)
+		}
+		if op.Peer != "" {
+			fmt.Printf(" peer=%s", op.Peer)
+		}
+		fmt.Println()
+	}
+}
+
+// formatUsage renders usage against quota, e.g. "3/10 keys, 120/∞ bytes".
+func formatUsage(usage *pb.Usage, quota *pb.Quota) string {
+	limit := func(n int64) string {
+		if n == 0 {
+			return "∞"
+		}
+		return strconv.FormatInt(n, 10)
+	}
+
+	out := fmt.Sprintf("%d/%s keys, %d/%s bytes",
+		usage.GetKeys(), limit(quota.GetMaxKeys()), usage.GetBytes(), limit(quota.GetMaxBytes()))
+	if quota.GetMaxValueBytes() > 0 {
+		out += fmt.Sprintf(", values up to %d bytes", quota.GetMaxValueBytes())
+	}
+	return out
+}
+
+func parseInts(args []string) ([]int64, error) {
+	var out []int64
+	for _, arg := range args {
+		n, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+func namespaceOrDefault(ns string) string {
+	if ns == "" {
+		return "default"
+	}
+	return ns
+}
+
 func main() {
+	var (
+		useTLS     = flag.Bool("tls", false, "connect using TLS")
+		caFile     = flag.String("ca", "", "CA bundle used to verify the server certificate (implies -tls)")
+		certFile   = flag.String("cert", "", "client certificate for mutual TLS (implies -tls)")
+		keyFile    = flag.String("key", "", "client private key for mutual TLS")
+		serverName = flag.String("server-name", "", "override the server name checked against its certificate")
+		token      = flag.String("token", os.Getenv("KVSTORE_TOKEN"), "API key or JWT sent as a bearer token (default $KVSTORE_TOKEN)")
+
+		traceExporter = flag.String("trace-exporter", tracing.ExporterNone, "trace exporter: none, otlp, stdout or file")
+		traceEndpoint = flag.String("trace-endpoint", "", "OTLP/gRPC collector address for the otlp trace exporter")
+		traceFile     = flag.String("trace-file", "", "file the file trace exporter appends to")
+		traceInsecure = flag.Bool("trace-insecure", false, "connect to the OTLP collector without TLS")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [server address]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
 	serverAddr := "localhost:9090"
-	if len(os.Args) > 1 {
-		serverAddr = os.Args[1]
+	if flag.NArg() > 0 {
+		serverAddr = flag.Arg(0)
+	}
+
+	creds := insecure.NewCredentials()
+	if *useTLS || *caFile != "" || *certFile != "" {
+		tlsConfig, err := tlsconfig.Client(tlsconfig.ClientOptions{
+			CAFile:     *caFile,
+			CertFile:   *certFile,
+			KeyFile:    *keyFile,
+			ServerName: *serverName,
+		})
+		if err != nil {
+			log.Fatalf("Invalid TLS options: %v", err)
+		}
+		creds = credentials.NewTLS(tlsConfig)
 	}
 
-	client, err := NewInteractiveClient(serverAddr)
+	if *token != "" && creds.Info().SecurityProtocol == "insecure" {
+		log.Printf("Warning: sending credentials without TLS")
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), "kvstore-cli", tracing.Options{
+		Exporter:    *traceExporter,
+		Endpoint:    *traceEndpoint,
+		Insecure:    *traceInsecure,
+		File:        *traceFile,
+		SampleRatio: 1,
+	})
+	if err != nil {
+		log.Fatalf("Invalid tracing options: %v", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			log.Printf("Failed to flush traces: %v", err)
+		}
+	}()
+
+	client, err := NewInteractiveClient(serverAddr, creds, *token)
 	if err != nil {
 		log.Fatalf("Failed to create client: %v", err)
 	}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"kvstore/internal/auth"
+	"kvstore/internal/proxy"
+	"kvstore/internal/tlsconfig"
+	"kvstore/internal/tracing"
+	pb "kvstore/pkg/pb/api/proto"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/stats"
+)
+
+// traceFlushTimeout bounds how long pending spans may take to export on exit.
+const traceFlushTimeout = 5 * time.Second
+
+// Serves the KVStore gRPC API in front of a sharded deployment, routing each
+// key to the node that owns it, so clients need not know the shard map.
+func main() {
+	var (
+		grpcAddr        = flag.String("grpc-addr", ":9090", "gRPC listen address")
+		nodes           = flag.String("nodes", "", "comma-separated gRPC addresses of nodes to load the shard map from")
+		refreshInterval = flag.Duration("refresh-interval", 10*time.Second, "how often to reload the shard map, besides when a node reports a newer one")
+		shutdownTimeout = flag.Duration("shutdown-timeout", 10*time.Second, "how long in-flight requests may take to drain on shutdown")
+		tlsCert         = flag.String("tls-cert", "", "TLS certificate file")
+		tlsKey          = flag.String("tls-key", "", "TLS private key file")
+		tlsClientCA     = flag.String("tls-client-ca", "", "CA bundle used to verify client certificates")
+		tlsClientAuth   = flag.String("tls-client-auth", tlsconfig.ClientAuthNone, "client certificate policy (none, request, require)")
+		nodeCAFile      = flag.String("node-ca-file", "", "CA bundle to verify nodes with, enabling TLS to them")
+
+		traceExporter    = flag.String("trace-exporter", tracing.ExporterNone, "trace exporter: none, otlp, stdout or file")
+		traceEndpoint    = flag.String("trace-endpoint", "", "OTLP/gRPC collector address for the otlp trace exporter")
+		traceFile        = flag.String("trace-file", "", "file the file trace exporter appends to")
+		traceSampleRatio = flag.Float64("trace-sample-ratio", 1, "fraction of new traces recorded, from 0 to 1")
+		traceInsecure    = flag.Bool("trace-insecure", false, "connect to the OTLP collector without TLS")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s -nodes <addr,...> [flags]\n", os.Args[0])
+		fmt.Fprintln(flag.CommandLine.Output(), "Bearer tokens and API keys of clients are passed on to the nodes, which authenticate them; this requires -node-ca-file.")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	var seeds []string
+	for _, addr := range strings.Split(*nodes, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			seeds = append(seeds, addr)
+		}
+	}
+	if len(seeds) == 0 || flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var serverOpts []grpc.ServerOption
+	if *tlsCert != "" {
+		tlsConfig, err := tlsconfig.Server(tlsconfig.ServerOptions{
+			CertFile:     *tlsCert,
+			KeyFile:      *tlsKey,
+			ClientCAFile: *tlsClientCA,
+			ClientAuth:   *tlsClientAuth,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to load TLS credentials: %v\n", err)
+			os.Exit(2)
+		}
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	nodeCreds := insecure.NewCredentials()
+	if *nodeCAFile != "" {
+		tlsConfig, err := tlsconfig.Client(tlsconfig.ClientOptions{CAFile: *nodeCAFile})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to load node CA: %v\n", err)
+			os.Exit(2)
+		}
+		nodeCreds = credentials.NewTLS(tlsConfig)
+	}
+
+	if *traceSampleRatio < 0 || *traceSampleRatio > 1 {
+		fmt.Fprintln(os.Stderr, "-trace-sample-ratio must be between 0 and 1")
+		os.Exit(2)
+	}
+	shutdownTracing, err := tracing.Setup(context.Background(), "kvstore-proxy", tracing.Options{
+		Exporter:    *traceExporter,
+		Endpoint:    *traceEndpoint,
+		Insecure:    *traceInsecure,
+		File:        *traceFile,
+		SampleRatio: *traceSampleRatio,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid tracing options: %v\n", err)
+		os.Exit(2)
+	}
+
+	// Requests are traced as they arrive and as they are passed on, so a
+	// caller's trace continues through the proxy to the nodes.
+	p := proxy.New(seeds, nodeCreds, grpc.WithStatsHandler(otelgrpc.NewClientHandler()))
+	serverOpts = append(serverOpts, grpc.StatsHandler(otelgrpc.NewServerHandler(otelgrpc.WithFilter(traced))))
+
+	err = run(p, *grpcAddr, serverOpts, *refreshInterval, *shutdownTimeout)
+	p.Close()
+
+	flushCtx, cancel := context.WithTimeout(context.Background(), traceFlushTimeout)
+	defer cancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Warn("Failed to flush traces", "error", err)
+	}
+
+	if err != nil {
+		slog.Error("Proxy failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+// traced leaves health checks out of traces, which would otherwise be
+// flooded by load balancer probes.
+func traced(info *stats.RPCTagInfo) bool {
+	return !slices.Contains(auth.DefaultSkip, info.FullMethodName)
+}
+
+func run(p *proxy.Proxy, addr string, serverOpts []grpc.ServerOption, refreshInterval, shutdownTimeout time.Duration) error {
+	listen, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	grpcServer := grpc.NewServer(serverOpts...)
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	pb.RegisterKVStoreServer(grpcServer, p)
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Requests fail until the shard map is loaded, so report NOT_SERVING
+	// until then in case the nodes start after the proxy.
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		for loaded := false; ; {
+			if err := p.Refresh(ctx); err != nil {
+				slog.Warn("Failed to load the shard map", "error", err)
+			} else if !loaded {
+				loaded = true
+				healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+				ticker.Reset(refreshInterval)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("Proxy starting", "addr", addr)
+		if err := grpcServer.Serve(listen); err != nil {
+			serveErr <- fmt.Errorf("failed to serve gRPC: %w", err)
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		slog.Info("Shutdown signal received, draining connections", "timeout", shutdownTimeout)
+	case err := <-serveErr:
+		return err
+	}
+
+	// Restore default signal handling so a second signal kills the process.
+	stop()
+	healthServer.Shutdown()
+
+	drained := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-time.After(shutdownTimeout):
+		grpcServer.Stop()
+		return fmt.Errorf("gRPC server did not drain within %s, remaining RPCs were cancelled", shutdownTimeout)
+	}
+}
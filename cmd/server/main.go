@@ -1,31 +1,834 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"kvstore/internal/audit"
+	"kvstore/internal/auth"
+	"kvstore/internal/cluster"
+	"kvstore/internal/config"
+	"kvstore/internal/gateway"
+	"kvstore/internal/gossip"
+	"kvstore/internal/ratelimit"
+	"kvstore/internal/replication"
 	"kvstore/internal/server"
+	"kvstore/internal/shard"
 	"kvstore/internal/storage"
+	"kvstore/internal/tlsconfig"
+	"kvstore/internal/tracing"
 	pb "kvstore/pkg/pb/api/proto"
-	"log"
+	"log/slog"
 	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/hashicorp/raft"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/stats"
 )
 
+// healthCheckInterval is how often storage readiness is re-evaluated for the
+// gRPC health service.
+const healthCheckInterval = time.Second
+
+// raftTimeout bounds Raft network operations between cluster nodes.
+const raftTimeout = 10 * time.Second
+
+// traceFlushTimeout bounds how long pending spans may take to export on exit.
+const traceFlushTimeout = 5 * time.Second
+
+// gossipLeaveTimeout bounds how long telling other servers this one is
+// leaving may take on shutdown.
+const gossipLeaveTimeout = 2 * time.Second
+
+// version is the build version published by gossip, set with
+// -ldflags "-X main.version=...".
+var version = "dev"
+
 func main() {
-	store := storage.NewMemoryStore()
-	kvServer := server.New(store)
+	cfg, opts, err := config.Load(os.Args[1:], os.LookupEnv, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if opts.PrintConfig {
+		if err := cfg.Redacted().Write(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	reloader := config.NewReloader(cfg, func() (*config.Config, error) {
+		cfg, _, err := config.Load(os.Args[1:], os.LookupEnv, io.Discard)
+		return cfg, err
+	})
+
+	// Exit with status 0 only when every in-flight request drained in time
+	// and the storage backend was closed cleanly.
+	if err := run(reloader); err != nil {
+		slog.Error("Server failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run(reloader *config.Reloader) error {
+	cfg := reloader.Current()
+
+	var logLevel slog.LevelVar
+	handlerOpts := &slog.HandlerOptions{Level: &logLevel}
+	if cfg.LogFormat == config.LogFormatJSON {
+		slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, handlerOpts)))
+	} else {
+		slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, handlerOpts)))
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), "kvstore-server", tracingOptions(cfg))
+	if err != nil {
+		return fmt.Errorf("failed to set up tracing: %w", err)
+	}
+
+	namespaces := storage.NewNamespaces()
+
+	var (
+		store       io.Closer = namespaces
+		kvOpts      []server.Option
+		adminOpts   []server.AdminOption
+		clusterNode *cluster.Node
+	)
+	if cfg.Cluster.Enabled() {
+		clusterNode, err = startCluster(cfg, namespaces)
+		if err != nil {
+			return err
+		}
+		store = clusterStore{clusterNode, namespaces}
+		kvOpts = append(kvOpts, server.WithReplicator(clusterNode))
+		adminOpts = append(adminOpts, server.WithNamespaceReplicator(clusterNode), server.WithCluster(clusterNode))
+		slog.Info("Cluster node started", "id", cfg.Cluster.NodeID, "raft_addr", cfg.Cluster.RaftAddr, "peers", len(cfg.Cluster.Peers))
+	}
+
+	var (
+		primary *replication.Primary
+		replica *replication.Replica
+		site    *replication.Site
+	)
+	switch replication.Role(cfg.Replication.Role) {
+	case replication.RolePrimary:
+		primary = replication.NewPrimary(namespaces, replication.WithLogSize(cfg.Replication.LogSize))
+		kvOpts = append(kvOpts, server.WithReplicator(primary))
+		adminOpts = append(adminOpts, server.WithNamespaceReplicator(primary), server.WithReplication(primary))
+		slog.Info("Replicating as primary", "log_size", cfg.Replication.LogSize)
+	case replication.RoleReplica:
+		replica, err = newReplica(cfg, namespaces)
+		if err != nil {
+			return err
+		}
+		kvOpts = append(kvOpts, server.WithReplicator(replica))
+		adminOpts = append(adminOpts, server.WithNamespaceReplicator(replica), server.WithReplication(replica))
+		slog.Info("Replicating as read-only replica", "primary", cfg.Replication.PrimaryAddr)
+	case replication.RoleActive:
+		site, err = newSite(cfg, namespaces)
+		if err != nil {
+			return err
+		}
+		kvOpts = append(kvOpts, server.WithReplicator(site))
+		adminOpts = append(adminOpts, server.WithNamespaceReplicator(site), server.WithReplication(site))
+		slog.Info("Replicating as active site", "site_id", cfg.Replication.SiteID, "peers", len(cfg.Replication.Peers))
+	}
+
+	var (
+		router   *shard.Router
+		migrator *shard.Migrator
+		importer *shard.GRPCImporter
+	)
+	if cfg.Sharding.Enabled() {
+		router, err = newShardRouter(cfg)
+		if err != nil {
+			return err
+		}
+		dialOpts, err := peerDialOptions(cfg.Sharding.APIKey, cfg.Sharding.CAFile)
+		if err != nil {
+			return fmt.Errorf("failed to load sharding TLS credentials: %w", err)
+		}
+		importer = shard.NewGRPCImporter(dialOpts...)
+		defer importer.Close()
+		migrator = shard.NewMigrator(router, namespaces, importer, shard.WithMapFile(cfg.Sharding.MapFile))
+
+		kvOpts = append(kvOpts, server.WithShards(router))
+		adminOpts = append(adminOpts, server.WithMigrator(migrator))
+		slog.Info("Serving a shard", "id", cfg.Sharding.NodeID, "version", router.Map().Version, "nodes", len(router.Map().Nodes), "virtual_nodes", router.Map().VirtualNodes)
+	}
+
+	var gossipNode *gossip.Node
+	if cfg.Gossip.Enabled() {
+		gossipNode, err = newGossip(cfg, router)
+		if err != nil {
+			return err
+		}
+		adminOpts = append(adminOpts, server.WithGossip(gossipNode))
+		slog.Info("Gossiping membership", "id", cfg.Gossip.NodeID, "seeds", len(cfg.Gossip.Seeds))
+	}
+
+	kvServer := server.New(namespaces, kvOpts...)
+	metrics := server.NewMetrics(namespaces)
+	accessLog := server.NewAccessLog(slog.Default(), accessLogOptions(cfg))
+	authenticator := auth.New(authOptions(cfg), auth.DefaultSkip...)
+	authorizer, err := auth.NewAuthorizer(authRoles(cfg))
+	if err != nil {
+		return fmt.Errorf("invalid roles: %w", err)
+	}
+	// Members held up by a client's rate limit would suspect each other.
+	limiter := ratelimit.New(rateLimitOptions(cfg), append(gossip.Methods(), auth.DefaultSkip...)...)
+
+	apply := func(cfg *config.Config) {
+		level, _ := config.ParseLogLevel(cfg.LogLevel)
+		logLevel.Set(level)
+
+		kvServer.SetLimits(server.Limits{
+			MaxKeyBytes:   cfg.Limits.MaxKeyBytes,
+			MaxValueBytes: cfg.Limits.MaxValueBytes,
+			MaxListLimit:  cfg.Limits.MaxListLimit,
+		})
+
+		policy, _ := storage.ParseEvictionPolicy(cfg.Storage.Memory.EvictionPolicy)
+		namespaces.SetEvictionPolicy(policy)
+		namespaces.SetMaxMemory(cfg.Storage.Memory.MaxMemoryBytes)
+		namespaces.Principals().SetQuotas(principalQuotas(cfg))
 
-	grpcServer := grpc.NewServer()
+		authenticator.Update(authOptions(cfg))
+		if err := authorizer.Update(authRoles(cfg)); err != nil {
+			slog.Error("Failed to apply roles, keeping the previous ones", "error", err)
+		}
+		limiter.Update(rateLimitOptions(cfg))
+		accessLog.Update(accessLogOptions(cfg))
+	}
+	apply(cfg)
+	reloader.Subscribe(apply)
+
+	var (
+		serverOpts []grpc.ServerOption
+		tlsConfig  *tls.Config
+	)
+	if cfg.TLS.CertFile != "" {
+		var err error
+		tlsConfig, err = tlsconfig.Server(tlsconfig.ServerOptions{
+			CertFile:     cfg.TLS.CertFile,
+			KeyFile:      cfg.TLS.KeyFile,
+			ClientCAFile: cfg.TLS.ClientCAFile,
+			ClientAuth:   cfg.TLS.ClientAuth,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to load TLS credentials: %w", err)
+		}
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	unaryInterceptors := []grpc.UnaryServerInterceptor{
+		metrics.UnaryInterceptor(),
+		authenticator.UnaryInterceptor(),
+		accessLog.UnaryInterceptor(),
+		limiter.UnaryInterceptor(),
+	}
+	var auditLog *audit.Log
+	if cfg.Audit.File != "" {
+		auditLog, err = audit.Open(cfg.Audit.File, audit.Options{Sync: cfg.Audit.Sync})
+		if err != nil {
+			return err
+		}
+		// Before the authorizer, so denied attempts are audited too.
+		unaryInterceptors = append(unaryInterceptors, auditLog.UnaryInterceptor())
+		slog.Info("Audit log enabled", "file", cfg.Audit.File)
+	}
+	unaryInterceptors = append(unaryInterceptors, authorizer.UnaryInterceptor())
+	streamInterceptors := []grpc.StreamServerInterceptor{
+		metrics.StreamInterceptor(),
+		authenticator.StreamInterceptor(),
+		limiter.StreamInterceptor(),
+		authorizer.StreamInterceptor(),
+	}
+	serverOpts = append(serverOpts,
+		grpc.StatsHandler(otelgrpc.NewServerHandler(otelgrpc.WithFilter(traced))),
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+	)
+
+	grpcServer := grpc.NewServer(serverOpts...)
+
+	healthServer := server.NewHealth(namespaces)
 
 	pb.RegisterKVStoreServer(grpcServer, kvServer)
+	pb.RegisterAdminServer(grpcServer, server.NewAdmin(append([]server.AdminOption{
+		server.WithReloader(reloader),
+		server.WithNamespaces(namespaces),
+		server.WithAccessLog(accessLog),
+	}, adminOpts...)...))
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	if primary != nil {
+		pb.RegisterReplicationServer(grpcServer, primary)
+	}
+	if site != nil {
+		pb.RegisterReplicationServer(grpcServer, site)
+	}
+	if gossipNode != nil {
+		pb.RegisterGossipServer(grpcServer, gossipNode)
+	}
 
-	listen, err := net.Listen("tcp", ":9090")
+	if cfg.Reflection {
+		reflection.Register(grpcServer)
+	}
+
+	listen, err := net.Listen("tcp", cfg.Listen.GRPC)
 	if err != nil {
-		log.Fatalf("Failed to listen: %v", err)
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 3)
+
+	var httpServer *http.Server
+	if cfg.Listen.HTTP != "" {
+		httpServer = &http.Server{
+			Addr:      cfg.Listen.HTTP,
+			Handler:   gateway.New(kvServer, gateway.WithInterceptors(unaryInterceptors...)),
+			TLSConfig: tlsConfig,
+		}
+
+		go func() {
+			slog.Info("HTTP gateway starting", "addr", cfg.Listen.HTTP, "tls", tlsConfig != nil)
+
+			var err error
+			if tlsConfig != nil {
+				err = httpServer.ListenAndServeTLS("", "")
+			} else {
+				err = httpServer.ListenAndServe()
+			}
+			if err != nil && err != http.ErrServerClosed {
+				serveErr <- fmt.Errorf("failed to serve HTTP: %w", err)
+			}
+		}()
+	}
+
+	var metricsServer *http.Server
+	if cfg.Listen.Metrics != "" {
+		mux := http.NewServeMux()
+		mux.Handle(server.MetricsPath, metrics.Handler())
+		metricsServer = &http.Server{Addr: cfg.Listen.Metrics, Handler: mux}
+
+		go func() {
+			slog.Info("Metrics endpoint starting", "addr", cfg.Listen.Metrics, "path", server.MetricsPath)
+
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				serveErr <- fmt.Errorf("failed to serve metrics: %w", err)
+			}
+		}()
+	}
+
+	go reloadOnSignal(reloader)
+	go healthServer.Run(ctx, healthCheckInterval)
+	if replica != nil {
+		go func() {
+			if err := replica.Run(ctx); err != nil {
+				serveErr <- err
+			}
+		}()
+	}
+	if site != nil {
+		go func() {
+			if err := site.Run(ctx); err != nil {
+				serveErr <- err
+			}
+		}()
+	}
+	if gossipNode != nil {
+		go gossipNode.Run(ctx)
+		if migrator != nil {
+			go followShardMaps(ctx, gossipNode, migrator, importer, cfg.Gossip.Interval)
+		}
+	}
+
+	if !authOptions(cfg).Enabled() {
+		slog.Warn("Authentication is disabled, every client has full access")
+	}
+
+	go func() {
+		slog.Info("gRPC server starting", "addr", cfg.Listen.GRPC, "tls", tlsConfig != nil, "client_auth", cfg.TLS.ClientAuth)
+
+		if err := grpcServer.Serve(listen); err != nil {
+			serveErr <- fmt.Errorf("failed to serve gRPC: %w", err)
+		}
+	}()
+
+	var errs []error
+	select {
+	case <-ctx.Done():
+		slog.Info("Shutdown signal received, draining connections", "timeout", cfg.ShutdownTimeout)
+	case err := <-serveErr:
+		errs = append(errs, err)
+	}
+
+	// Restore default signal handling so a second signal kills the process.
+	stop()
+
+	// Report NOT_SERVING first so load balancers stop routing new requests
+	// here while the existing ones drain.
+	healthServer.Shutdown()
+
+	// Tell the other servers this one is leaving rather than failing.
+	if gossipNode != nil {
+		leaveCtx, cancel := context.WithTimeout(context.Background(), gossipLeaveTimeout)
+		gossipNode.Leave(leaveCtx)
+		cancel()
+		defer gossipNode.Close()
+	}
+
+	// Replication streams never end on their own; end them so the gRPC
+	// server can drain.
+	if primary != nil {
+		primary.Close()
+	}
+	if site != nil {
+		site.Close()
+	}
+	// Stop handing keys over, which holds up requests for them.
+	if migrator != nil {
+		migrator.Close()
+	}
+
+	errs = append(errs, shutdown(grpcServer, httpServer, store, cfg.ShutdownTimeout))
+
+	if auditLog != nil {
+		if err := auditLog.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close audit log: %w", err))
+		}
+	}
+
+	// Metrics stay up until the end so the drain itself can be observed.
+	if metricsServer != nil {
+		metricsServer.Close()
+	}
+
+	flushCtx, cancel := context.WithTimeout(context.Background(), traceFlushTimeout)
+	defer cancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		errs = append(errs, fmt.Errorf("failed to flush traces: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// shutdown stops accepting new connections, waits up to timeout for
+// in-flight requests to finish and then closes the storage backend.
+func shutdown(grpcServer *grpc.Server, httpServer *http.Server, store io.Closer, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var errs []error
+
+	if httpServer != nil {
+		if err := httpServer.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("HTTP gateway did not drain: %w", err))
+		}
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		grpcServer.Stop()
+		errs = append(errs, fmt.Errorf("gRPC server did not drain within %s, remaining RPCs were cancelled", timeout))
+	}
+
+	if err := store.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to close storage: %w", err))
 	}
 
-	log.Println("gRPC server starting on port 9090...")
+	if len(errs) == 0 {
+		slog.Info("Server stopped")
+	}
+
+	return errors.Join(errs...)
+}
+
+// startCluster joins the Raft group described by cfg.Cluster, replicating
+// into namespaces.
+func startCluster(cfg *config.Config, namespaces *storage.Namespaces) (*cluster.Node, error) {
+	var peers []cluster.Peer
+	var advertise string
+	for _, p := range cfg.Cluster.Peers {
+		peers = append(peers, cluster.Peer{ID: p.ID, RaftAddr: p.RaftAddr, GRPCAddr: p.GRPCAddr})
+		if p.ID == cfg.Cluster.NodeID {
+			advertise = p.RaftAddr
+		}
+	}
+
+	advertiseAddr, err := net.ResolveTCPAddr("tcp", advertise)
+	if err != nil {
+		return nil, fmt.Errorf("invalid raft address for %s: %w", cfg.Cluster.NodeID, err)
+	}
+	var transport *raft.NetworkTransport
+	if cfg.Cluster.CAFile != "" {
+		serverTLS, err := tlsconfig.Server(tlsconfig.ServerOptions{
+			CertFile:     cfg.Cluster.CertFile,
+			KeyFile:      cfg.Cluster.KeyFile,
+			ClientCAFile: cfg.Cluster.CAFile,
+			ClientAuth:   tlsconfig.ClientAuthRequire,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to load raft TLS config: %w", err)
+		}
+		clientTLS, err := tlsconfig.Client(tlsconfig.ClientOptions{
+			CAFile:   cfg.Cluster.CAFile,
+			CertFile: cfg.Cluster.CertFile,
+			KeyFile:  cfg.Cluster.KeyFile,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to load raft TLS config: %w", err)
+		}
+		transport, err = cluster.NewTLSTransport(cfg.Cluster.RaftAddr, advertiseAddr, serverTLS, clientTLS, 3, raftTimeout, os.Stderr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen for raft: %w", err)
+		}
+	} else {
+		slog.Warn("Raft traffic is not encrypted or authenticated; set cluster.cert_file, key_file and ca_file to use mutual TLS")
+		transport, err = raft.NewTCPTransport(cfg.Cluster.RaftAddr, advertiseAddr, 3, raftTimeout, os.Stderr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen for raft: %w", err)
+		}
+	}
+
+	node, err := cluster.New(cluster.Options{
+		ID:        cfg.Cluster.NodeID,
+		Peers:     peers,
+		Bootstrap: cfg.Cluster.Bootstrap,
+		DataDir:   cfg.Cluster.DataDir,
+		Transport: transport,
+	}, namespaces)
+	if err != nil {
+		transport.Close()
+		return nil, err
+	}
+	return node, nil
+}
+
+// clusterStore leaves the Raft group before closing the storage it applies
+// to.
+type clusterStore struct {
+	node       *cluster.Node
+	namespaces *storage.Namespaces
+}
+
+func (c clusterStore) Close() error {
+	return errors.Join(c.node.Shutdown(), c.namespaces.Close())
+}
+
+// newShardRouter routes by the map last committed to cfg.Sharding.MapFile,
+// or else builds it from cfg.Sharding.
+func newShardRouter(cfg *config.Config) (*shard.Router, error) {
+	m, err := loadShardMap(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := m.Node(cfg.Sharding.NodeID); !ok {
+		slog.Warn("This node is not in the shard map and owns no keys until a rebalance adds it", "id", cfg.Sharding.NodeID)
+	}
+	return shard.NewRouter(cfg.Sharding.NodeID, m), nil
+}
+
+func loadShardMap(cfg *config.Config) (*shard.Map, error) {
+	if cfg.Sharding.MapFile != "" {
+		m, err := shard.LoadMap(cfg.Sharding.MapFile)
+		if err == nil {
+			slog.Info("Loaded the committed shard map", "file", cfg.Sharding.MapFile, "version", m.Version)
+			return m, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to load the shard map: %w", err)
+		}
+	}
+
+	var nodes []shard.Node
+	for _, n := range cfg.Sharding.Nodes {
+		nodes = append(nodes, shard.Node{ID: n.ID, Addr: n.GRPCAddr})
+	}
+	return shard.NewMap(1, nodes, cfg.Sharding.VirtualNodes)
+}
+
+// newReplica prepares a replica of the primary in cfg.Replication, applying
+// to namespaces.
+func newReplica(cfg *config.Config, namespaces *storage.Namespaces) (*replication.Replica, error) {
+	dialOpts, err := peerDialOptions(cfg.Replication.APIKey, cfg.Replication.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load replication TLS credentials: %w", err)
+	}
+
+	// Name the replica after where it serves, as seen from the primary.
+	host, err := os.Hostname()
+	if err != nil {
+		host = "replica"
+	}
+	if _, port, err := net.SplitHostPort(cfg.Listen.GRPC); err == nil {
+		host = net.JoinHostPort(host, port)
+	}
+
+	return replication.NewReplica(namespaces, replication.ReplicaOptions{
+		ID:                  host,
+		PrimaryAddr:         cfg.Replication.PrimaryAddr,
+		DialOptions:         dialOpts,
+		AntiEntropyInterval: cfg.Replication.AntiEntropyInterval,
+	}), nil
+}
+
+// newSite prepares the active site in cfg.Replication, applying to
+// namespaces.
+func newSite(cfg *config.Config, namespaces *storage.Namespaces) (*replication.Site, error) {
+	dialOpts, err := peerDialOptions(cfg.Replication.APIKey, cfg.Replication.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load replication TLS credentials: %w", err)
+	}
+
+	return replication.NewSite(namespaces, replication.SiteOptions{
+		ID:             cfg.Replication.SiteID,
+		Peers:          cfg.Replication.Peers,
+		DialOptions:    dialOpts,
+		LogSize:        cfg.Replication.LogSize,
+		MaxClockOffset: cfg.Replication.MaxClockOffset,
+	}), nil
+}
+
+// newGossip prepares the gossip node in cfg.Gossip. It publishes the
+// server's role, version and, if router is set, its shard.
+func newGossip(cfg *config.Config, router *shard.Router) (*gossip.Node, error) {
+	dialOpts, err := peerDialOptions(cfg.Gossip.APIKey, cfg.Gossip.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load gossip TLS credentials: %w", err)
+	}
+
+	addr := cfg.Gossip.AdvertiseAddr
+	if addr == "" {
+		addr = cfg.Listen.GRPC
+	}
+	role := "standalone"
+	switch {
+	case cfg.Replication.Role != "":
+		role = cfg.Replication.Role
+	case cfg.Cluster.Enabled():
+		role = "cluster"
+	}
+
+	return gossip.New(gossip.Options{
+		ID:               cfg.Gossip.NodeID,
+		Addr:             addr,
+		Seeds:            cfg.Gossip.Seeds,
+		DialOptions:      dialOpts,
+		Interval:         cfg.Gossip.Interval,
+		ProbeTimeout:     cfg.Gossip.ProbeTimeout,
+		SuspicionTimeout: cfg.Gossip.SuspicionTimeout,
+		IndirectChecks:   cfg.Gossip.IndirectChecks,
+		SyncInterval:     cfg.Gossip.SyncInterval,
+		Meta: func() gossip.Meta {
+			meta := gossip.Meta{Role: role, Version: version}
+			if router != nil {
+				meta.ShardID = router.Self()
+				meta.ShardMapVersion = router.Map().Version
+			}
+			return meta
+		},
+	}), nil
+}
+
+// followShardMaps catches the node up, every interval until ctx is done,
+// with the shard maps that live members gossip they route by.
+func followShardMaps(ctx context.Context, node *gossip.Node, migrator *shard.Migrator, fetcher shard.MapFetcher, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, m := range node.Members() {
+			if m.ID == node.Self() || m.State != gossip.StateAlive || m.Meta.ShardID == "" {
+				continue
+			}
+			if err := migrator.CatchUp(ctx, fetcher, m.Addr, m.Meta.ShardMapVersion); err != nil {
+				slog.Warn("Failed to catch up with the shard map of a gossip member", "member", m.ID, "error", err)
+			}
+		}
+	}
+}
+
+// peerDialOptions connects to other servers, over TLS verified against
+// caFile if set, authenticating with apiKey if set.
+func peerDialOptions(apiKey, caFile string) ([]grpc.DialOption, error) {
+	creds := insecure.NewCredentials()
+	if caFile != "" {
+		tlsConfig, err := tlsconfig.Client(tlsconfig.ClientOptions{CAFile: caFile})
+		if err != nil {
+			return nil, err
+		}
+		creds = credentials.NewTLS(tlsConfig)
+	}
+	dialOpts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	if apiKey != "" {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(apiKeyCredentials(apiKey)))
+	}
+	return dialOpts, nil
+}
+
+// apiKeyCredentials authenticates a server to other servers. The key is
+// only ever sent over TLS.
+type apiKeyCredentials string
+
+func (k apiKeyCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{auth.APIKeyKey: string(k)}, nil
+}
+
+func (k apiKeyCredentials) RequireTransportSecurity() bool {
+	return true
+}
+
+func authOptions(cfg *config.Config) auth.Options {
+	opts := auth.Options{
+		JWT: auth.JWTOptions{
+			Secret:   cfg.Auth.JWT.Secret,
+			Issuer:   cfg.Auth.JWT.Issuer,
+			Audience: cfg.Auth.JWT.Audience,
+		},
+	}
+
+	for _, key := range cfg.Auth.APIKeys {
+		opts.APIKeys = append(opts.APIKeys, auth.APIKey{
+			Principal: key.Principal,
+			Key:       key.Key,
+			Roles:     key.Roles,
+		})
+	}
+
+	return opts
+}
+
+func authRoles(cfg *config.Config) []auth.Role {
+	var roles []auth.Role
+
+	for _, rc := range cfg.Auth.Roles {
+		role := auth.Role{Name: rc.Name}
+		for _, gc := range rc.Grants {
+			grant := auth.Grant{Patterns: gc.Keys, Namespaces: gc.Namespaces}
+			for _, p := range gc.Permissions {
+				perm, _ := auth.ParsePermission(p)
+				grant.Permissions = append(grant.Permissions, perm)
+			}
+			role.Grants = append(role.Grants, grant)
+		}
+		roles = append(roles, role)
+	}
+
+	return roles
+}
+
+func rateLimitOptions(cfg *config.Config) ratelimit.Options {
+	rate := func(r config.RateConfig) ratelimit.Rate {
+		return ratelimit.Rate{PerSecond: r.Rate, Burst: r.Burst}
+	}
+
+	opts := ratelimit.Options{
+		PerClient:   rate(cfg.RateLimits.PerClient),
+		Methods:     make(map[string]ratelimit.Rate, len(cfg.RateLimits.Methods)),
+		MaxInFlight: cfg.RateLimits.MaxInFlight,
+	}
+	for method, r := range cfg.RateLimits.Methods {
+		opts.Methods[method] = rate(r)
+	}
+
+	return opts
+}
+
+func accessLogOptions(cfg *config.Config) server.AccessLogOptions {
+	level, _ := config.ParseLogLevel(cfg.AccessLog.Level)
+	return server.AccessLogOptions{
+		Enabled:       cfg.AccessLog.Enabled,
+		Level:         level,
+		RedactKeys:    cfg.AccessLog.RedactKeys,
+		SlowThreshold: cfg.AccessLog.SlowThreshold,
+		SlowLogSize:   cfg.AccessLog.SlowLogSize,
+	}
+}
+
+func tracingOptions(cfg *config.Config) tracing.Options {
+	return tracing.Options{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		File:        cfg.Tracing.File,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	}
+}
+
+// traced leaves health checks and reflection out of traces, which would
+// otherwise be flooded by load balancer probes.
+func traced(info *stats.RPCTagInfo) bool {
+	for _, method := range auth.DefaultSkip {
+		if info.FullMethodName == method {
+			return false
+		}
+	}
+	return true
+}
+
+func principalQuotas(cfg *config.Config) map[string]storage.Quota {
+	quotas := make(map[string]storage.Quota, len(cfg.Quotas.Principals))
+	for principal, q := range cfg.Quotas.Principals {
+		quotas[principal] = storage.Quota{
+			MaxKeys:       q.MaxKeys,
+			MaxBytes:      q.MaxBytes,
+			MaxValueBytes: q.MaxValueBytes,
+		}
+	}
+	return quotas
+}
+
+// reloadOnSignal reloads the configuration every time the process receives
+// SIGHUP.
+func reloadOnSignal(reloader *config.Reloader) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	for range hup {
+		result, err := reloader.Reload()
+		if err != nil {
+			slog.Error("Config reload failed, keeping current settings", "error", err)
+			continue
+		}
 
-	if err := grpcServer.Serve(listen); err != nil {
-		log.Fatalf("Failed to server: %v", err)
+		slog.Info("Config reloaded", "applied", result.Applied)
+		if len(result.RequiresRestart) > 0 {
+			slog.Warn("Some changed settings require a restart", "settings", result.RequiresRestart)
+		}
 	}
 }
@@ -0,0 +1,280 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"kvstore/internal/auth"
+	pb "kvstore/pkg/pb/api/proto"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ResultPending marks the entry written before an operation runs.
+const ResultPending = "Pending"
+
+// GenesisHash is the PrevHash of the first entry of every log.
+var GenesisHash = strings.Repeat("0", sha256.Size*2)
+
+// ErrTampered is matched by every *ChainError.
+var ErrTampered = errors.New("audit: log has been tampered with")
+
+// Entry is one audited operation. Entries are stored one JSON object per
+// line; each carries the hash of the previous entry, so editing, inserting
+// or removing a line breaks the chain from that point on. Rewriting the
+// whole file from the edit onwards recomputes a valid chain, so ship the
+// latest hash off the host to detect that too.
+type Entry struct {
+	Seq       int64     `json:"seq"`
+	Time      time.Time `json:"time"`
+	Principal string    `json:"principal,omitempty"`
+	// Operation is the RPC name, such as Set, Delete or CreateNamespace.
+	Operation string `json:"operation"`
+	Namespace string `json:"namespace,omitempty"`
+	Key       string `json:"key,omitempty"`
+	// Result is the gRPC status code name, OK on success, or Pending on the
+	// entry written before the operation runs.
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+	// Ref is the Seq of the Pending entry an outcome belongs to.
+	Ref      int64  `json:"ref,omitempty"`
+	PrevHash string `json:"prev_hash"`
+	Hash     string `json:"hash"`
+}
+
+// computeHash hashes every field but Hash itself.
+func (e Entry) computeHash() string {
+	e.Hash = ""
+	data, _ := json.Marshal(e)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ChainError reports the first entry that does not follow from the ones
+// before it.
+type ChainError struct {
+	// Line is 1-based.
+	Line   int
+	Reason string
+}
+
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("audit: log has been tampered with: line %d: %s", e.Line, e.Reason)
+}
+
+func (e *ChainError) Is(target error) bool {
+	return target == ErrTampered
+}
+
+type Options struct {
+	// Sync flushes every entry to stable storage before the RPC returns.
+	Sync bool
+}
+
+// Log appends entries to an append-only file.
+type Log struct {
+	mu       sync.Mutex
+	file     *os.File
+	opts     Options
+	seq      int64
+	lastHash string
+	now      func() time.Time
+}
+
+// Open opens the log at path, creating it if needed. An existing log is
+// verified first and new entries continue its chain; Open refuses to
+// append to a log that fails verification.
+func Open(path string, opts Options) (*Log, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	l := &Log{file: file, opts: opts, lastHash: GenesisHash, now: time.Now}
+
+	err = Verify(file, func(e Entry) {
+		l.seq = e.Seq
+		l.lastHash = e.Hash
+	})
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("refusing to append to %s: %w", path, err)
+	}
+
+	return l, nil
+}
+
+// Append chains e to the log and writes it. Seq, Time, PrevHash and Hash
+// are filled in.
+func (l *Log) Append(e Entry) error {
+	_, err := l.append(e)
+	return err
+}
+
+// append is Append returning the Seq given to e.
+func (l *Log) append(e Entry) (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	e.Seq = l.seq + 1
+	e.Time = l.now().UTC()
+	e.PrevHash = l.lastHash
+	e.Hash = e.computeHash()
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return 0, fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	if l.opts.Sync {
+		if err := l.file.Sync(); err != nil {
+			return 0, fmt.Errorf("failed to sync audit log: %w", err)
+		}
+	}
+
+	l.seq = e.Seq
+	l.lastHash = e.Hash
+	return e.Seq, nil
+}
+
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// Verify reads a log from r and checks its chain, calling fn with every
+// entry that verifies. It returns a *ChainError at the first entry that
+// does not.
+func Verify(r io.Reader, fn func(Entry)) error {
+	reader := bufio.NewReader(r)
+	prev := Entry{Hash: GenesisHash}
+
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if len(data) == 0 && errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to read audit log: %w", err)
+		}
+		if !bytes.HasSuffix(data, []byte("\n")) {
+			return &ChainError{Line: line, Reason: "truncated entry"}
+		}
+
+		var e Entry
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&e); err != nil {
+			return &ChainError{Line: line, Reason: fmt.Sprintf("malformed entry: %v", err)}
+		}
+
+		switch {
+		case e.Seq != prev.Seq+1:
+			return &ChainError{Line: line, Reason: fmt.Sprintf("sequence %d follows %d", e.Seq, prev.Seq)}
+		case e.PrevHash != prev.Hash:
+			return &ChainError{Line: line, Reason: "previous hash does not match"}
+		case e.Hash != e.computeHash():
+			return &ChainError{Line: line, Reason: "entry hash does not match its contents"}
+		}
+
+		if fn != nil {
+			fn(e)
+		}
+		prev = e
+	}
+}
+
+// UnaryInterceptor records every call that may change data, see audited,
+// in two entries. A Pending entry is written before the call runs, and the
+// call is refused with Unavailable if it cannot be, so no change is applied
+// without a record of it. An entry with the result follows once the call
+// returns; failing to write that one is logged at error level, since the
+// call has been applied by then. It should run before the Authorizer's
+// interceptor so that denied attempts are recorded too.
+func (l *Log) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !audited(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		e := Entry{
+			Operation: info.FullMethod[strings.LastIndex(info.FullMethod, "/")+1:],
+			Result:    ResultPending,
+		}
+		if id, ok := auth.FromContext(ctx); ok {
+			e.Principal = id.Principal
+		}
+		switch r := req.(type) {
+		case interface{ GetNamespace() string }:
+			e.Namespace = r.GetNamespace()
+		case interface{ GetName() string }:
+			e.Namespace = r.GetName()
+		}
+		if r, ok := req.(interface{ GetKey() string }); ok {
+			e.Key = r.GetKey()
+		}
+
+		ref, auditErr := l.append(e)
+		if auditErr != nil {
+			slog.ErrorContext(ctx, "Failed to write audit entry, refusing the call", "error", auditErr,
+				"operation", e.Operation, "principal", e.Principal, "key", e.Key)
+			return nil, status.Error(codes.Unavailable, "audit log unavailable")
+		}
+
+		resp, err := handler(ctx, req)
+
+		e.Ref = ref
+		e.Result = status.Code(err).String()
+		if err != nil {
+			e.Error = status.Convert(err).Message()
+		}
+		if auditErr := l.Append(e); auditErr != nil {
+			slog.ErrorContext(ctx, "Failed to write audit entry", "error", auditErr,
+				"operation", e.Operation, "principal", e.Principal, "key", e.Key, "ref", ref)
+		}
+
+		return resp, err
+	}
+}
+
+// audited reports whether method is recorded: every Admin call, and every
+// KVStore call that may change data. That is any KVStore method but those
+// needing only the read permission, so a new RPC is audited until it is
+// known to only read.
+func audited(method string) bool {
+	if strings.HasPrefix(method, "/"+pb.Admin_ServiceDesc.ServiceName+"/") {
+		return true
+	}
+	if !strings.HasPrefix(method, "/"+pb.KVStore_ServiceDesc.ServiceName+"/") {
+		return false
+	}
+	perm, ok := auth.MethodPermission(method)
+	return !ok || (perm != auth.PermissionRead && perm != auth.PermissionAuthenticated)
+}
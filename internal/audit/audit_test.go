@@ -0,0 +1,168 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"kvstore/internal/auth"
+	pb "kvstore/pkg/pb/api/proto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func readEntries(t *testing.T, path string) []Entry {
+	t.Helper()
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var entries []Entry
+	require.NoError(t, Verify(f, func(e Entry) { entries = append(entries, e) }))
+	return entries
+}
+
+// Test reopening a log continues its chain
+func TestLog_AppendReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	l, err := Open(path, Options{Sync: true})
+	require.NoError(t, err)
+	require.NoError(t, l.Append(Entry{Principal: "ci", Operation: "Set", Key: "a", Result: "OK"}))
+	require.NoError(t, l.Close())
+
+	l, err = Open(path, Options{})
+	require.NoError(t, err)
+	require.NoError(t, l.Append(Entry{Principal: "ci", Operation: "Delete", Key: "a", Result: "OK"}))
+	require.NoError(t, l.Close())
+
+	entries := readEntries(t, path)
+	require.Len(t, entries, 2)
+	assert.Equal(t, GenesisHash, entries[0].PrevHash)
+	assert.Equal(t, entries[0].Hash, entries[1].PrevHash)
+	assert.Equal(t, int64(2), entries[1].Seq)
+	assert.Equal(t, "Delete", entries[1].Operation)
+
+	assert.ErrorIs(t, l.Append(Entry{}), os.ErrClosed)
+}
+
+// Test editing, removing or reordering entries breaks the chain
+func TestVerify_Tampering(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := Open(path, Options{})
+	require.NoError(t, err)
+	for _, key := range []string{"a", "b", "c"} {
+		require.NoError(t, l.Append(Entry{Principal: "ci", Operation: "Set", Key: key, Result: "OK"}))
+	}
+	require.NoError(t, l.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.SplitAfter(string(data), "\n")[:3]
+
+	tests := []struct {
+		name   string
+		log    string
+		line   int
+		reason string
+	}{
+		{"edited", strings.Replace(string(data), `"principal":"ci","operation":"Set","key":"b"`, `"principal":"ops","operation":"Set","key":"b"`, 1), 2, "entry hash"},
+		{"removed", lines[0] + lines[2], 2, "sequence 3 follows 1"},
+		{"reordered", lines[1] + lines[0] + lines[2], 1, "sequence 2 follows 0"},
+		{"truncated", string(data[:len(data)-5]), 3, "truncated"},
+		{"garbage", string(data) + "not json\n", 4, "malformed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var verified int
+			err := Verify(strings.NewReader(tt.log), func(Entry) { verified++ })
+
+			var chainErr *ChainError
+			require.ErrorAs(t, err, &chainErr)
+			assert.ErrorIs(t, err, ErrTampered)
+			assert.Equal(t, tt.line, chainErr.Line)
+			assert.Contains(t, chainErr.Reason, tt.reason)
+			assert.Equal(t, tt.line-1, verified)
+		})
+	}
+
+	// A tampered log is never appended to
+	require.NoError(t, os.WriteFile(path, []byte(tests[0].log), 0o600))
+	_, err = Open(path, Options{})
+	assert.ErrorIs(t, err, ErrTampered)
+}
+
+// Test the interceptor records every call that may change data before and
+// after it runs, and refuses calls it cannot record
+func TestLog_UnaryInterceptor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := Open(path, Options{})
+	require.NoError(t, err)
+	defer l.Close()
+
+	interceptor := l.UnaryInterceptor()
+	ctx := auth.WithIdentity(context.Background(), &auth.Identity{Principal: "ci"})
+	var handled int
+	ok := func(ctx context.Context, req any) (any, error) {
+		handled++
+		return nil, nil
+	}
+	denied := func(ctx context.Context, req any) (any, error) {
+		return nil, status.Error(codes.PermissionDenied, "not allowed")
+	}
+	call := func(method string, req any, handler grpc.UnaryHandler) error {
+		_, err := interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+
+	call(pb.KVStore_Set_FullMethodName, &pb.SetRequest{Key: "a", Value: "secret", Namespace: "team-a"}, ok)
+	call(pb.KVStore_Get_FullMethodName, &pb.GetRequest{Key: "a"}, ok)
+	call(pb.KVStore_Delete_FullMethodName, &pb.DeleteRequest{Key: "b"}, denied)
+	call(pb.Admin_DropNamespace_FullMethodName, &pb.DropNamespaceRequest{Name: "team-a"}, ok)
+	call(pb.KVStore_Increment_FullMethodName, &pb.IncrementRequest{Key: "n"}, ok)
+	call(pb.KVStore_AddMembers_FullMethodName, &pb.MembersRequest{Key: "s"}, ok)
+
+	entries := readEntries(t, path)
+	require.Len(t, entries, 10)
+
+	assert.Equal(t, "ci", entries[0].Principal)
+	assert.Equal(t, "Set", entries[0].Operation)
+	assert.Equal(t, "team-a", entries[0].Namespace)
+	assert.Equal(t, "a", entries[0].Key)
+	assert.Equal(t, ResultPending, entries[0].Result)
+	assert.Zero(t, entries[0].Ref)
+	assert.Equal(t, "Set", entries[1].Operation)
+	assert.Equal(t, "OK", entries[1].Result)
+	assert.Equal(t, entries[0].Seq, entries[1].Ref)
+
+	assert.Equal(t, "Delete", entries[3].Operation)
+	assert.Equal(t, "PermissionDenied", entries[3].Result)
+	assert.Equal(t, "not allowed", entries[3].Error)
+
+	assert.Equal(t, "DropNamespace", entries[5].Operation)
+	assert.Equal(t, "team-a", entries[5].Namespace)
+
+	assert.Equal(t, "Increment", entries[7].Operation)
+	assert.Equal(t, "AddMembers", entries[9].Operation)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.False(t, bytes.Contains(data, []byte("secret")), "values are never audited")
+
+	// A call that cannot be recorded is not run
+	require.NoError(t, l.Close())
+	handled = 0
+	err = call(pb.KVStore_Set_FullMethodName, &pb.SetRequest{Key: "a"}, ok)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Zero(t, handled)
+	require.NoError(t, call(pb.KVStore_Get_FullMethodName, &pb.GetRequest{Key: "a"}, ok))
+	assert.Equal(t, 1, handled)
+}
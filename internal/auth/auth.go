@@ -0,0 +1,241 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata keys read by the interceptors. A token may be sent either as
+// "authorization: Bearer <token>" or in the x-api-key header.
+const (
+	AuthorizationKey = "authorization"
+	APIKeyKey        = "x-api-key"
+)
+
+// Identity is the authenticated caller attached to the request context.
+type Identity struct {
+	Principal string
+	Roles     []string
+	// Method is "api_key" or "jwt".
+	Method string
+}
+
+type APIKey struct {
+	Principal string
+	Key       string
+	Roles     []string
+}
+
+type JWTOptions struct {
+	// Secret is the HMAC key for HS256, HS384 and HS512 tokens.
+	Secret   string
+	Issuer   string
+	Audience string
+}
+
+type Options struct {
+	APIKeys []APIKey
+	JWT     JWTOptions
+}
+
+// Enabled reports whether any credential is configured. When it is not,
+// the interceptors let every request through.
+func (o Options) Enabled() bool {
+	return len(o.APIKeys) > 0 || o.JWT.Secret != ""
+}
+
+// Claims are the JWT claims understood by the server. The subject is the
+// principal.
+type Claims struct {
+	Roles []string `json:"roles,omitempty"`
+	jwt.RegisteredClaims
+}
+
+type Authenticator struct {
+	opts atomic.Pointer[Options]
+	// Skip lists full method names that do not require credentials.
+	skip map[string]bool
+}
+
+// DefaultSkip are the infrastructure services load balancers and tools call
+// without credentials.
+var DefaultSkip = []string{
+	"/grpc.health.v1.Health/Check",
+	"/grpc.health.v1.Health/Watch",
+	"/grpc.health.v1.Health/List",
+	"/grpc.reflection.v1.ServerReflection/ServerReflectionInfo",
+	"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo",
+}
+
+func New(opts Options, skip ...string) *Authenticator {
+	a := &Authenticator{skip: make(map[string]bool)}
+	for _, method := range skip {
+		a.skip[method] = true
+	}
+	a.Update(opts)
+	return a
+}
+
+// Update swaps in new credentials. It is safe to call while serving.
+func (a *Authenticator) Update(opts Options) {
+	a.opts.Store(&opts)
+}
+
+// Authenticate resolves the caller from the incoming metadata in ctx.
+func (a *Authenticator) Authenticate(ctx context.Context) (*Identity, error) {
+	opts := a.opts.Load()
+
+	token := tokenFromMetadata(ctx)
+	if token == "" {
+		return nil, status.Error(codes.Unauthenticated, "missing credentials")
+	}
+
+	for _, key := range opts.APIKeys {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(key.Key)) == 1 {
+			return &Identity{Principal: key.Principal, Roles: key.Roles, Method: "api_key"}, nil
+		}
+	}
+
+	if opts.JWT.Secret != "" && strings.Count(token, ".") == 2 {
+		id, err := verifyJWT(token, opts.JWT)
+		if err != nil {
+			return nil, status.Errorf(codes.Unauthenticated, "invalid token: %v", err)
+		}
+		return id, nil
+	}
+
+	return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+}
+
+func tokenFromMetadata(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if values := md.Get(AuthorizationKey); len(values) > 0 {
+		scheme, token, ok := strings.Cut(values[0], " ")
+		if ok && strings.EqualFold(scheme, "bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+
+	if values := md.Get(APIKeyKey); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+func verifyJWT(token string, opts JWTOptions) (*Identity, error) {
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"HS256", "HS384", "HS512"}),
+		jwt.WithExpirationRequired(),
+	}
+	if opts.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(opts.Issuer))
+	}
+	if opts.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(opts.Audience))
+	}
+
+	var claims Claims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (any, error) {
+		return []byte(opts.Secret), nil
+	}, parserOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+
+	return &Identity{Principal: claims.Subject, Roles: claims.Roles, Method: "jwt"}, nil
+}
+
+// IssueToken signs an HS256 token for principal that expires after ttl.
+func IssueToken(opts JWTOptions, principal string, roles []string, ttl time.Duration) (string, error) {
+	if opts.Secret == "" {
+		return "", fmt.Errorf("no JWT secret configured")
+	}
+
+	now := time.Now()
+	claims := Claims{
+		Roles: roles,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   principal,
+			Issuer:    opts.Issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+	if opts.Audience != "" {
+		claims.Audience = jwt.ClaimStrings{opts.Audience}
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(opts.Secret))
+}
+
+type identityKey struct{}
+
+func WithIdentity(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// FromContext returns the identity attached by the interceptors, if any.
+func FromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(*Identity)
+	return id, ok
+}
+
+func (a *Authenticator) authenticate(ctx context.Context, method string) (context.Context, error) {
+	if a.skip[method] || !a.opts.Load().Enabled() {
+		return ctx, nil
+	}
+
+	id, err := a.Authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return WithIdentity(ctx, id), nil
+}
+
+func (a *Authenticator) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := a.authenticate(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (a *Authenticator) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.authenticate(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// serverStream overrides the context of a grpc.ServerStream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
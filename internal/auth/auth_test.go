@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var testOptions = Options{
+	APIKeys: []APIKey{{Principal: "ci", Key: "ci-key-0123456789", Roles: []string{"writer"}}},
+	JWT:     JWTOptions{Secret: "jwt-secret-0123456789", Issuer: "kvstore"},
+}
+
+func incoming(kv ...string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(kv...))
+}
+
+func assertCode(t *testing.T, code codes.Code, err error) {
+	t.Helper()
+	assert.Equal(t, code, status.Code(err), "error: %v", err)
+}
+
+// Test API keys in either header
+func TestAuthenticate_APIKey(t *testing.T) {
+	a := New(testOptions)
+
+	for _, ctx := range []context.Context{
+		incoming(AuthorizationKey, "Bearer ci-key-0123456789"),
+		incoming(APIKeyKey, "ci-key-0123456789"),
+	} {
+		id, err := a.Authenticate(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, &Identity{Principal: "ci", Roles: []string{"writer"}, Method: "api_key"}, id)
+	}
+
+	_, err := a.Authenticate(incoming(APIKeyKey, "wrong-key-0123456789"))
+	assertCode(t, codes.Unauthenticated, err)
+
+	_, err = a.Authenticate(context.Background())
+	assertCode(t, codes.Unauthenticated, err)
+}
+
+func TestAuthenticate_JWT(t *testing.T) {
+	a := New(testOptions)
+
+	token, err := IssueToken(testOptions.JWT, "alice", []string{"reader"}, time.Minute)
+	require.NoError(t, err)
+
+	id, err := a.Authenticate(incoming(AuthorizationKey, "Bearer "+token))
+	require.NoError(t, err)
+	assert.Equal(t, &Identity{Principal: "alice", Roles: []string{"reader"}, Method: "jwt"}, id)
+}
+
+// Test expired, forged and unsigned tokens are rejected
+func TestAuthenticate_InvalidJWT(t *testing.T) {
+	a := New(testOptions)
+
+	expired, err := IssueToken(testOptions.JWT, "alice", nil, -time.Minute)
+	require.NoError(t, err)
+
+	forged, err := IssueToken(JWTOptions{Secret: "some-other-secret-0123", Issuer: "kvstore"}, "alice", nil, time.Minute)
+	require.NoError(t, err)
+
+	wrongIssuer, err := IssueToken(JWTOptions{Secret: testOptions.JWT.Secret, Issuer: "elsewhere"}, "alice", nil, time.Minute)
+	require.NoError(t, err)
+
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.RegisteredClaims{
+		Subject:   "alice",
+		Issuer:    "kvstore",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+
+	noExpiry, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Subject: "alice",
+		Issuer:  "kvstore",
+	}).SignedString([]byte(testOptions.JWT.Secret))
+	require.NoError(t, err)
+
+	for name, token := range map[string]string{
+		"expired":      expired,
+		"forged":       forged,
+		"wrong issuer": wrongIssuer,
+		"alg none":     unsigned,
+		"no expiry":    noExpiry,
+	} {
+		_, err := a.Authenticate(incoming(AuthorizationKey, "Bearer "+token))
+		assert.Equal(t, codes.Unauthenticated, status.Code(err), name)
+	}
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	a := New(testOptions, "/grpc.health.v1.Health/Check")
+	interceptor := a.UnaryInterceptor()
+
+	var seen *Identity
+	handler := func(ctx context.Context, req any) (any, error) {
+		seen, _ = FromContext(ctx)
+		return "ok", nil
+	}
+
+	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/kvstore.v1.KVStore/Get"}, handler)
+	assertCode(t, codes.Unauthenticated, err)
+
+	resp, err := interceptor(incoming(APIKeyKey, "ci-key-0123456789"), nil, &grpc.UnaryServerInfo{FullMethod: "/kvstore.v1.KVStore/Get"}, handler)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+	require.NotNil(t, seen)
+	assert.Equal(t, "ci", seen.Principal)
+
+	// Skipped methods need no credentials
+	_, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}, handler)
+	assert.NoError(t, err)
+}
+
+// Test an authenticator without credentials lets everything through, and
+// that credentials can be swapped in later
+func TestAuthenticator_Update(t *testing.T) {
+	a := New(Options{})
+	interceptor := a.UnaryInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/kvstore.v1.KVStore/Get"}
+	handler := func(ctx context.Context, req any) (any, error) { return nil, nil }
+
+	_, err := interceptor(context.Background(), nil, info, handler)
+	assert.NoError(t, err)
+
+	a.Update(testOptions)
+	_, err = interceptor(context.Background(), nil, info, handler)
+	assertCode(t, codes.Unauthenticated, err)
+}
@@ -0,0 +1,354 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"kvstore/internal/storage"
+	pb "kvstore/pkg/pb/api/proto"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Permission string
+
+const (
+	PermissionRead   Permission = "read"
+	PermissionWrite  Permission = "write"
+	PermissionDelete Permission = "delete"
+	// PermissionAdmin allows the Admin service. It is not scoped to keys, so
+	// the patterns of a grant carrying it are ignored.
+	PermissionAdmin Permission = "admin"
+)
+
+func ParsePermission(s string) (Permission, error) {
+	switch p := Permission(s); p {
+	case PermissionRead, PermissionWrite, PermissionDelete, PermissionAdmin:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown permission %q (want %s, %s, %s or %s)",
+		s, PermissionRead, PermissionWrite, PermissionDelete, PermissionAdmin)
+}
+
+// Grant gives permissions on the keys matching any of Patterns. A pattern
+// without wildcards is a key prefix; otherwise it must match the whole key,
+// with * matching any run of characters (including /) and ? a single one.
+type Grant struct {
+	Permissions []Permission
+	Patterns    []string
+	// Namespaces restricts the grant to the named namespaces. Empty means
+	// every namespace.
+	Namespaces []string
+}
+
+type Role struct {
+	Name   string
+	Grants []Grant
+}
+
+// AuditEntry describes a denied request.
+type AuditEntry struct {
+	Principal  string
+	Roles      []string
+	Method     string
+	Permission Permission
+	Namespace  string
+	Key        string
+}
+
+type AuthorizerOption func(*Authorizer)
+
+// WithAudit replaces the default audit sink, which logs denials at warn
+// level.
+func WithAudit(fn func(context.Context, AuditEntry)) AuthorizerOption {
+	return func(a *Authorizer) {
+		a.audit = fn
+	}
+}
+
+// Authorizer enforces role grants on the identity attached by the
+// Authenticator. With no roles configured it allows everything, so
+// authentication can be enabled on its own.
+type Authorizer struct {
+	roles atomic.Pointer[map[string][]grant]
+	audit func(context.Context, AuditEntry)
+}
+
+// grant is a Grant with its patterns compiled.
+type grant struct {
+	permissions map[Permission]bool
+	patterns    []matcher
+	namespaces  map[string]bool
+}
+
+type matcher func(key string) bool
+
+func NewAuthorizer(roles []Role, opts ...AuthorizerOption) (*Authorizer, error) {
+	a := &Authorizer{audit: logAudit}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	if err := a.Update(roles); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// Update swaps in new roles. It is safe to call while serving. On error the
+// previous roles stay in effect.
+func (a *Authorizer) Update(roles []Role) error {
+	compiled := make(map[string][]grant, len(roles))
+
+	for _, role := range roles {
+		for _, g := range role.Grants {
+			cg := grant{permissions: make(map[Permission]bool)}
+			for _, p := range g.Permissions {
+				cg.permissions[p] = true
+			}
+			if len(g.Namespaces) > 0 {
+				cg.namespaces = make(map[string]bool)
+				for _, ns := range g.Namespaces {
+					cg.namespaces[ns] = true
+				}
+			}
+			for _, pattern := range g.Patterns {
+				m, err := compilePattern(pattern)
+				if err != nil {
+					return fmt.Errorf("role %s: %w", role.Name, err)
+				}
+				cg.patterns = append(cg.patterns, m)
+			}
+			compiled[role.Name] = append(compiled[role.Name], cg)
+		}
+	}
+
+	a.roles.Store(&compiled)
+	return nil
+}
+
+// ValidPattern reports whether pattern can be used in a Grant.
+func ValidPattern(pattern string) error {
+	_, err := compilePattern(pattern)
+	return err
+}
+
+func compilePattern(pattern string) (matcher, error) {
+	if !strings.ContainsAny(pattern, "*?") {
+		return func(key string) bool { return strings.HasPrefix(key, pattern) }, nil
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid key pattern %q: %w", pattern, err)
+	}
+	return re.MatchString, nil
+}
+
+func (a *Authorizer) enabled() bool {
+	return len(*a.roles.Load()) > 0
+}
+
+// Allowed reports whether id may use perm on key in namespace. The empty
+// namespace is the default one.
+func (a *Authorizer) Allowed(id *Identity, perm Permission, namespace, key string) bool {
+	roles := *a.roles.Load()
+	if namespace == "" {
+		namespace = storage.DefaultNamespace
+	}
+
+	for _, name := range id.Roles {
+		for _, g := range roles[name] {
+			if !g.permissions[perm] {
+				continue
+			}
+			if perm == PermissionAdmin {
+				return true
+			}
+			if g.namespaces != nil && !g.namespaces[namespace] {
+				continue
+			}
+			for _, match := range g.patterns {
+				if match(key) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+func (a *Authorizer) deny(ctx context.Context, id *Identity, method string, perm Permission, namespace, key string) error {
+	a.audit(ctx, AuditEntry{
+		Principal:  id.Principal,
+		Roles:      id.Roles,
+		Method:     method,
+		Permission: perm,
+		Namespace:  namespace,
+		Key:        key,
+	})
+
+	if perm == PermissionAdmin {
+		return status.Errorf(codes.PermissionDenied, "%s may not call %s", id.Principal, method)
+	}
+	return status.Errorf(codes.PermissionDenied, "%s may not %s key %q", id.Principal, perm, key)
+}
+
+func logAudit(ctx context.Context, entry AuditEntry) {
+	slog.WarnContext(ctx, "Access denied",
+		"principal", entry.Principal,
+		"roles", entry.Roles,
+		"method", entry.Method,
+		"permission", entry.Permission,
+		"namespace", entry.Namespace,
+		"key", entry.Key,
+	)
+}
+
+// PermissionAuthenticated is needed by methods any authenticated caller
+// may use. It cannot be granted.
+const PermissionAuthenticated Permission = ""
+
+// kvPermissions maps every KVStore method to the permission it needs on the
+// request's key. KVStore methods missing here are denied, so a new RPC stays
+// unreachable until it is given a permission.
+var kvPermissions = map[string]Permission{
+	pb.KVStore_Get_FullMethodName:           PermissionRead,
+	pb.KVStore_List_FullMethodName:          PermissionRead,
+	pb.KVStore_Set_FullMethodName:           PermissionWrite,
+	pb.KVStore_Increment_FullMethodName:     PermissionWrite,
+	pb.KVStore_AddMembers_FullMethodName:    PermissionWrite,
+	pb.KVStore_RemoveMembers_FullMethodName: PermissionWrite,
+	pb.KVStore_Delete_FullMethodName:        PermissionDelete,
+	// The shard map holds server addresses and hash ranges but no keys, and
+	// clients need it to route.
+	pb.KVStore_GetShardMap_FullMethodName: PermissionAuthenticated,
+}
+
+// MethodPermission returns the permission a KVStore method needs on the
+// request's key. It reports false for any other method.
+func MethodPermission(fullMethod string) (Permission, bool) {
+	perm, ok := kvPermissions[fullMethod]
+	return perm, ok
+}
+
+// adminServices need the admin permission for every method.
+var adminServices = []string{
+	pb.Admin_ServiceDesc.ServiceName,
+	pb.Replication_ServiceDesc.ServiceName,
+	pb.Gossip_ServiceDesc.ServiceName,
+}
+
+// UnaryInterceptor checks every call against the caller's roles: KVStore
+// calls for the permission of their method on the request's key, and
+// Admin, Replication and Gossip calls for the admin permission. Any other
+// method is denied. It must run after the Authenticator's interceptor.
+// Requests without an identity, which only happens when authentication is
+// disabled or skipped for the method, are let through.
+//
+// List calls get a filter attached to their context, see ReadFilter, so
+// that the keys the caller may not read are skipped before the limit
+// applies.
+func (a *Authorizer) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		id, ok := FromContext(ctx)
+		if !ok || !a.enabled() {
+			return handler(ctx, req)
+		}
+
+		if adminMethod(info.FullMethod) {
+			if !a.Allowed(id, PermissionAdmin, "", "") {
+				return nil, a.deny(ctx, id, info.FullMethod, PermissionAdmin, "", "")
+			}
+			return handler(ctx, req)
+		}
+
+		perm, ok := kvPermissions[info.FullMethod]
+		if !ok {
+			return nil, a.deny(ctx, id, info.FullMethod, PermissionAdmin, "", "")
+		}
+
+		switch r := req.(type) {
+		case *pb.ListRequest:
+			namespace := r.GetNamespace()
+			ctx = context.WithValue(ctx, readFilterKey{}, func(key string) bool {
+				return a.Allowed(id, PermissionRead, namespace, key)
+			})
+		case keyRequest:
+			if !a.Allowed(id, perm, r.GetNamespace(), r.GetKey()) {
+				return nil, a.deny(ctx, id, info.FullMethod, perm, r.GetNamespace(), r.GetKey())
+			}
+		default:
+			if perm != PermissionAuthenticated {
+				return nil, a.deny(ctx, id, info.FullMethod, perm, "", "")
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor checks Replication streams, which carry every key, for
+// the admin permission, and denies every other stream. It must run after
+// the Authenticator's interceptor.
+func (a *Authorizer) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		id, ok := FromContext(ss.Context())
+		if !ok || !a.enabled() {
+			return handler(srv, ss)
+		}
+
+		if !adminMethod(info.FullMethod) || !a.Allowed(id, PermissionAdmin, "", "") {
+			return a.deny(ss.Context(), id, info.FullMethod, PermissionAdmin, "", "")
+		}
+		return handler(srv, ss)
+	}
+}
+
+// keyRequest is implemented by requests that address a single key.
+type keyRequest interface {
+	GetNamespace() string
+	GetKey() string
+}
+
+func adminMethod(fullMethod string) bool {
+	for _, service := range adminServices {
+		if strings.HasPrefix(fullMethod, "/"+service+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+type readFilterKey struct{}
+
+// ReadFilter returns the filter the Authorizer attached to a List call,
+// which reports whether the caller may read a key. It returns nil when every
+// key may be read. Apply it before the limit, so that a page is only short
+// when no readable keys are left.
+func ReadFilter(ctx context.Context) func(key string) bool {
+	keep, _ := ctx.Value(readFilterKey{}).(func(key string) bool)
+	return keep
+}
@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"context"
+	pb "kvstore/pkg/pb/api/proto"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+var testRoles = []Role{
+	{Name: "team-a", Grants: []Grant{
+		{Permissions: []Permission{PermissionRead, PermissionWrite, PermissionDelete}, Patterns: []string{"team-a/"}},
+		{Permissions: []Permission{PermissionRead}, Patterns: []string{"*/public/*"}},
+	}},
+	{Name: "tenant", Grants: []Grant{
+		{Permissions: []Permission{PermissionRead, PermissionWrite}, Patterns: []string{"*"}, Namespaces: []string{"tenant-1"}},
+	}},
+	{Name: "ops", Grants: []Grant{
+		{Permissions: []Permission{PermissionAdmin}},
+	}},
+}
+
+func TestAuthorizer_Allowed(t *testing.T) {
+	a, err := NewAuthorizer(testRoles)
+	require.NoError(t, err)
+
+	alice := &Identity{Principal: "alice", Roles: []string{"team-a"}}
+	tests := []struct {
+		perm Permission
+		key  string
+		want bool
+	}{
+		{PermissionRead, "team-a/config", true},
+		{PermissionWrite, "team-a/config", true},
+		{PermissionDelete, "team-a/x/y", true},
+		{PermissionRead, "team-b/config", false},
+		{PermissionRead, "team-b/public/logo", true},
+		{PermissionWrite, "team-b/public/logo", false},
+		{PermissionAdmin, "", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, a.Allowed(alice, tt.perm, "", tt.key), "%s %s", tt.perm, tt.key)
+	}
+
+	ops := &Identity{Principal: "bob", Roles: []string{"ops", "unknown"}}
+	assert.True(t, a.Allowed(ops, PermissionAdmin, "", ""))
+	assert.False(t, a.Allowed(ops, PermissionRead, "", "team-a/config"))
+
+	tenant := &Identity{Principal: "carol", Roles: []string{"tenant"}}
+	assert.True(t, a.Allowed(tenant, PermissionWrite, "tenant-1", "anything"))
+	assert.False(t, a.Allowed(tenant, PermissionWrite, "tenant-2", "anything"))
+	assert.False(t, a.Allowed(tenant, PermissionRead, "", "anything"))
+
+	// Grants without namespaces apply to every namespace
+	assert.True(t, a.Allowed(alice, PermissionRead, "tenant-2", "team-a/config"))
+}
+
+// Test denied calls return PermissionDenied and are audited
+func TestAuthorizer_UnaryInterceptor(t *testing.T) {
+	var audited []AuditEntry
+	a, err := NewAuthorizer(testRoles, WithAudit(func(ctx context.Context, entry AuditEntry) {
+		audited = append(audited, entry)
+	}))
+	require.NoError(t, err)
+	interceptor := a.UnaryInterceptor()
+
+	ctx := WithIdentity(context.Background(), &Identity{Principal: "alice", Roles: []string{"team-a"}})
+	handler := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+	info := func(method string) *grpc.UnaryServerInfo { return &grpc.UnaryServerInfo{FullMethod: method} }
+
+	resp, err := interceptor(ctx, &pb.SetRequest{Key: "team-a/k"}, info(pb.KVStore_Set_FullMethodName), handler)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+	assert.Empty(t, audited)
+
+	_, err = interceptor(ctx, &pb.DeleteRequest{Key: "team-b/k"}, info(pb.KVStore_Delete_FullMethodName), handler)
+	assertCode(t, codes.PermissionDenied, err)
+	_, err = interceptor(ctx, &pb.IncrementRequest{Key: "team-b/k"}, info(pb.KVStore_Increment_FullMethodName), handler)
+	assertCode(t, codes.PermissionDenied, err)
+	_, err = interceptor(ctx, &pb.ReloadConfigRequest{}, info(pb.Admin_ReloadConfig_FullMethodName), handler)
+	assertCode(t, codes.PermissionDenied, err)
+	_, err = interceptor(ctx, &pb.GetRangeRecordsRequest{}, info(pb.Replication_GetRangeRecords_FullMethodName), handler)
+	assertCode(t, codes.PermissionDenied, err)
+	_, err = interceptor(ctx, &pb.PingRequest{}, info(pb.Gossip_Ping_FullMethodName), handler)
+	assertCode(t, codes.PermissionDenied, err)
+
+	assert.Equal(t, []AuditEntry{
+		{Principal: "alice", Roles: []string{"team-a"}, Method: pb.KVStore_Delete_FullMethodName, Permission: PermissionDelete, Key: "team-b/k"},
+		{Principal: "alice", Roles: []string{"team-a"}, Method: pb.KVStore_Increment_FullMethodName, Permission: PermissionWrite, Key: "team-b/k"},
+		{Principal: "alice", Roles: []string{"team-a"}, Method: pb.Admin_ReloadConfig_FullMethodName, Permission: PermissionAdmin},
+		{Principal: "alice", Roles: []string{"team-a"}, Method: pb.Replication_GetRangeRecords_FullMethodName, Permission: PermissionAdmin},
+		{Principal: "alice", Roles: []string{"team-a"}, Method: pb.Gossip_Ping_FullMethodName, Permission: PermissionAdmin},
+	}, audited)
+
+	// Without an identity authentication is disabled and everything passes
+	_, err = interceptor(context.Background(), &pb.DeleteRequest{Key: "team-b/k"}, info(pb.KVStore_Delete_FullMethodName), handler)
+	assert.NoError(t, err)
+}
+
+// Test replication streams require the admin permission
+func TestAuthorizer_StreamInterceptor(t *testing.T) {
+	a, err := NewAuthorizer(testRoles)
+	require.NoError(t, err)
+	interceptor := a.StreamInterceptor()
+
+	handler := func(srv any, ss grpc.ServerStream) error { return nil }
+	info := &grpc.StreamServerInfo{FullMethod: pb.Replication_StreamMutations_FullMethodName, IsServerStream: true}
+	stream := func(roles ...string) grpc.ServerStream {
+		return &serverStream{ctx: WithIdentity(context.Background(), &Identity{Principal: "p", Roles: roles})}
+	}
+
+	assertCode(t, codes.PermissionDenied, interceptor(nil, stream("team-a"), info, handler))
+	assert.NoError(t, interceptor(nil, stream("ops"), info, handler))
+}
+
+// Test List calls carry a filter down to the readable keys
+func TestAuthorizer_ListFiltered(t *testing.T) {
+	a, err := NewAuthorizer(testRoles)
+	require.NoError(t, err)
+
+	ctx := WithIdentity(context.Background(), &Identity{Principal: "alice", Roles: []string{"team-a"}})
+	var keys []string
+	handler := func(ctx context.Context, req any) (any, error) {
+		keep := ReadFilter(ctx)
+		require.NotNil(t, keep)
+		for _, key := range []string{"team-a/1", "team-b/1", "team-b/public/1", "team-c/2"} {
+			if keep(key) {
+				keys = append(keys, key)
+			}
+		}
+		return &pb.ListResponse{}, nil
+	}
+
+	_, err = a.UnaryInterceptor()(ctx, &pb.ListRequest{}, &grpc.UnaryServerInfo{FullMethod: pb.KVStore_List_FullMethodName}, handler)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"team-a/1", "team-b/public/1"}, keys)
+	assert.Nil(t, ReadFilter(context.Background()))
+}
+
+// Test methods without a permission are denied
+func TestAuthorizer_DenyUnknown(t *testing.T) {
+	a, err := NewAuthorizer(testRoles)
+	require.NoError(t, err)
+	interceptor := a.UnaryInterceptor()
+
+	ctx := WithIdentity(context.Background(), &Identity{Principal: "alice", Roles: []string{"team-a"}})
+	handler := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+
+	_, err = interceptor(ctx, &pb.GetShardMapRequest{}, &grpc.UnaryServerInfo{FullMethod: pb.KVStore_GetShardMap_FullMethodName}, handler)
+	assert.NoError(t, err, "any principal may read the shard map")
+
+	_, err = interceptor(ctx, &pb.GetRequest{Key: "team-a/k"}, &grpc.UnaryServerInfo{FullMethod: "/kvstore.v1.KVStore/Compact"}, handler)
+	assertCode(t, codes.PermissionDenied, err)
+	_, err = interceptor(ctx, &pb.GetRequest{Key: "team-a/k"}, &grpc.UnaryServerInfo{FullMethod: "/other.v1.Service/Get"}, handler)
+	assertCode(t, codes.PermissionDenied, err)
+
+	stream := &serverStream{ctx: WithIdentity(context.Background(), &Identity{Principal: "bob", Roles: []string{"ops"}})}
+	err = a.StreamInterceptor()(nil, stream, &grpc.StreamServerInfo{FullMethod: "/kvstore.v1.KVStore/Watch"}, func(srv any, ss grpc.ServerStream) error { return nil })
+	assertCode(t, codes.PermissionDenied, err)
+}
+
+// Test no roles means no restrictions, and roles can be swapped in later
+func TestAuthorizer_Update(t *testing.T) {
+	a, err := NewAuthorizer(nil)
+	require.NoError(t, err)
+	interceptor := a.UnaryInterceptor()
+
+	ctx := WithIdentity(context.Background(), &Identity{Principal: "alice"})
+	info := &grpc.UnaryServerInfo{FullMethod: pb.KVStore_Get_FullMethodName}
+	handler := func(ctx context.Context, req any) (any, error) { return nil, nil }
+
+	_, err = interceptor(ctx, &pb.GetRequest{Key: "k"}, info, handler)
+	assert.NoError(t, err)
+
+	require.NoError(t, a.Update(testRoles))
+	_, err = interceptor(ctx, &pb.GetRequest{Key: "k"}, info, handler)
+	assertCode(t, codes.PermissionDenied, err)
+}
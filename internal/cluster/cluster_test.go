@@ -0,0 +1,283 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"kvstore/internal/storage"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memorySink is a raft.SnapshotSink writing to a buffer.
+type memorySink struct {
+	buf bytes.Buffer
+}
+
+func (s *memorySink) Write(p []byte) (int, error) { return s.buf.Write(p) }
+func (s *memorySink) Close() error                { return nil }
+func (s *memorySink) Cancel() error               { return nil }
+func (s *memorySink) ID() string                  { return "test" }
+
+// harness runs a Raft group in-process over in-memory transports.
+type harness struct {
+	t          *testing.T
+	nodes      []*Node
+	namespaces []*storage.Namespaces
+	transports []*raft.InmemTransport
+	peers      []Peer
+}
+
+func newHarness(t *testing.T, size int) *harness {
+	t.Helper()
+
+	h := &harness{t: t}
+	for i := 0; i < size; i++ {
+		h.transport()
+	}
+	for i := range h.peers {
+		h.start(i, h.peers, true)
+	}
+
+	t.Cleanup(func() {
+		for _, n := range h.nodes {
+			n.Shutdown()
+		}
+	})
+
+	return h
+}
+
+// transport adds a transport connected to every other one and the peer
+// using it, and returns its index.
+func (h *harness) transport() int {
+	addr, trans := raft.NewInmemTransport("")
+	for _, other := range h.transports {
+		trans.Connect(other.LocalAddr(), other)
+		other.Connect(addr, trans)
+	}
+	trans.Connect(addr, trans)
+
+	i := len(h.transports)
+	h.transports = append(h.transports, trans)
+	h.peers = append(h.peers, Peer{
+		ID:       fmt.Sprintf("n%d", i+1),
+		RaftAddr: string(addr),
+		GRPCAddr: fmt.Sprintf("127.0.0.1:%d", 9090+i),
+	})
+	return i
+}
+
+// start runs node i.
+func (h *harness) start(i int, peers []Peer, bootstrap bool) {
+	h.t.Helper()
+
+	namespaces := storage.NewNamespaces()
+	node, err := New(Options{
+		ID:                 h.peers[i].ID,
+		Peers:              peers,
+		Bootstrap:          bootstrap,
+		Transport:          h.transports[i],
+		HeartbeatTimeout:   50 * time.Millisecond,
+		ElectionTimeout:    50 * time.Millisecond,
+		LeaderLeaseTimeout: 50 * time.Millisecond,
+		CommitTimeout:      5 * time.Millisecond,
+		LogOutput:          io.Discard,
+	}, namespaces)
+	require.NoError(h.t, err)
+
+	h.nodes = append(h.nodes, node)
+	h.namespaces = append(h.namespaces, namespaces)
+}
+
+// join starts a node that is not a member yet and returns its index.
+func (h *harness) join() int {
+	i := h.transport()
+	h.start(i, []Peer{h.peers[i]}, false)
+	return i
+}
+
+// leader waits for a leader among the nodes that are not isolated.
+func (h *harness) leader(exclude ...int) int {
+	h.t.Helper()
+
+	leader := -1
+	require.Eventually(h.t, func() bool {
+		for i, n := range h.nodes {
+			if n.IsLeader() && !contains(exclude, i) {
+				leader = i
+				return true
+			}
+		}
+		return false
+	}, 5*time.Second, 10*time.Millisecond, "no leader elected")
+
+	return leader
+}
+
+// isolate cuts node i off from every other node.
+func (h *harness) isolate(i int) {
+	h.transports[i].DisconnectAll()
+	for j, trans := range h.transports {
+		if j != i {
+			trans.Disconnect(h.transports[i].LocalAddr())
+		}
+	}
+}
+
+// eventually waits until node i holds key with want, or "" for absent.
+func (h *harness) eventually(i int, key, want string) {
+	h.t.Helper()
+
+	assert.Eventually(h.t, func() bool {
+		got, _ := h.namespaces[i].Default().Get(key)
+		return got == want
+	}, 5*time.Second, 10*time.Millisecond, "node %d: %s != %q", i, key, want)
+}
+
+// set discards the index of a write.
+func set(n *Node, ctx context.Context, namespace string, r storage.Record) error {
+	_, err := n.Set(ctx, namespace, r)
+	return err
+}
+
+func contains(s []int, v int) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Test writes on the leader are applied on every node
+func TestCluster_Replicates(t *testing.T) {
+	h := newHarness(t, 3)
+	leader := h.nodes[h.leader()]
+	ctx := context.Background()
+
+	require.NoError(t, set(leader, ctx, storage.DefaultNamespace, storage.Record{Key: "a", Value: "1", Owner: "ci"}))
+	require.NoError(t, set(leader, ctx, storage.DefaultNamespace, storage.Record{Key: "b", Value: "2"}))
+	existed, _, err := leader.Delete(ctx, storage.DefaultNamespace, "b")
+	require.NoError(t, err)
+	assert.True(t, existed)
+
+	require.NoError(t, leader.CreateNamespace(ctx, "team-a", storage.NamespaceSettings{Quota: storage.Quota{MaxKeys: 1}}))
+	require.NoError(t, set(leader, ctx, "team-a", storage.Record{Key: "x", Value: "y"}))
+	err = set(leader, ctx, "team-a", storage.Record{Key: "z", Value: "y"})
+	assert.ErrorIs(t, err, storage.ErrQuotaExceeded, "storage errors reach the proposer")
+
+	for i := range h.nodes {
+		h.eventually(i, "a", "1")
+		h.eventually(i, "b", "")
+		assert.Eventually(t, func() bool {
+			ns, err := h.namespaces[i].Get("team-a")
+			return err == nil && ns.Usage().Keys == 1
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+}
+
+// Test followers apply every committed write, even one their own memory
+// limit would have rejected
+func TestCluster_FollowersApplyAdmittedWrites(t *testing.T) {
+	h := newHarness(t, 3)
+	leader := h.leader()
+	for i := range h.nodes {
+		if i != leader {
+			h.namespaces[i].SetMaxMemory(4)
+		}
+	}
+
+	require.NoError(t, set(h.nodes[leader], context.Background(), storage.DefaultNamespace, storage.Record{Key: "a", Value: "12345678"}))
+	for i := range h.nodes {
+		h.eventually(i, "a", "12345678")
+	}
+
+	// The leader admits writes against its own limit
+	h.namespaces[leader].SetMaxMemory(4)
+	err := set(h.nodes[leader], context.Background(), storage.DefaultNamespace, storage.Record{Key: "b", Value: "12345678"})
+	assert.ErrorIs(t, err, storage.ErrOutOfMemory)
+}
+
+// Test followers reject writes with the leader's address
+func TestCluster_FollowerRedirects(t *testing.T) {
+	h := newHarness(t, 3)
+	leader := h.leader()
+	follower := (leader + 1) % len(h.nodes)
+	require.Eventually(t, func() bool {
+		id, _ := h.nodes[follower].Leader()
+		return id != ""
+	}, 5*time.Second, 10*time.Millisecond, "the follower learns of the leader")
+
+	err := set(h.nodes[follower], context.Background(), storage.DefaultNamespace, storage.Record{Key: "a", Value: "1"})
+
+	var notLeader *NotLeaderError
+	require.ErrorAs(t, err, &notLeader)
+	assert.ErrorIs(t, err, ErrNotLeader)
+	assert.Equal(t, h.peers[leader].ID, notLeader.LeaderID)
+	assert.Equal(t, h.peers[leader].GRPCAddr, notLeader.LeaderAddr)
+
+	_, found := h.namespaces[follower].Default().Get("a")
+	assert.False(t, found)
+}
+
+// Test the group keeps accepting writes after losing its leader
+func TestCluster_ToleratesMinorityFailure(t *testing.T) {
+	h := newHarness(t, 3)
+	ctx := context.Background()
+	old := h.leader()
+	require.NoError(t, set(h.nodes[old], ctx, storage.DefaultNamespace, storage.Record{Key: "a", Value: "1"}))
+
+	h.isolate(old)
+	leader := h.leader(old)
+	require.NotEqual(t, old, leader)
+
+	require.Eventually(t, func() bool {
+		return set(h.nodes[leader], ctx, storage.DefaultNamespace, storage.Record{Key: "b", Value: "2"}) == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	for i := range h.nodes {
+		if i != old {
+			h.eventually(i, "a", "1")
+			h.eventually(i, "b", "2")
+		}
+	}
+
+	// The old leader cannot commit without a majority
+	shortCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	assert.Error(t, set(h.nodes[old], shortCtx, storage.DefaultNamespace, storage.Record{Key: "c", Value: "3"}))
+	_, found := h.namespaces[leader].Default().Get("c")
+	assert.False(t, found)
+}
+
+// Test a snapshot carries keys, owners, expiries and namespaces
+func TestFSM_SnapshotRestore(t *testing.T) {
+	src := storage.NewNamespaces()
+	f := newFSM(src, nil)
+	expiresAt := time.Now().Add(time.Hour).Unix()
+
+	require.NoError(t, f.apply(command{Op: opCreateNamespace, Namespace: "team-a",
+		Settings: &storage.NamespaceSettings{DefaultTTLSeconds: 60}}).err)
+	require.NoError(t, f.apply(command{Op: opSet, Namespace: "team-a",
+		Record: &storage.Record{Key: "a", Value: "1", Owner: "ci", ExpiresAt: expiresAt}}).err)
+
+	snap, err := f.Snapshot()
+	require.NoError(t, err)
+	sink := &memorySink{}
+	require.NoError(t, snap.Persist(sink))
+
+	dst := storage.NewNamespaces()
+	require.NoError(t, newFSM(dst, nil).Restore(io.NopCloser(&sink.buf)))
+
+	ns, err := dst.Get("team-a")
+	require.NoError(t, err)
+	assert.Equal(t, int64(60), ns.Settings().DefaultTTLSeconds)
+	assert.Equal(t, []storage.Record{{Key: "a", Value: "1", Owner: "ci", ExpiresAt: expiresAt}}, ns.Records(""))
+	assert.Equal(t, int64(1), dst.Principals().Usage("ci").Usage.Keys)
+}
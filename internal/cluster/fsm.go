@@ -0,0 +1,212 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"kvstore/internal/storage"
+	"sync"
+
+	"github.com/hashicorp/raft"
+)
+
+// Operations carried by commands.
+const (
+	opSet             = "set"
+	opDelete          = "delete"
+	opCreateNamespace = "create_namespace"
+	opDropNamespace   = "drop_namespace"
+	opSetQuota        = "set_quota"
+	opAddPeers        = "add_peers"
+	// opNoop changes nothing. A new leader commits one before serving reads
+	// so that its commit index covers its predecessor's entries, like the
+	// no-op Raft itself commits, but one the FSM sees.
+	opNoop = "noop"
+)
+
+// command is one mutation in the replicated log. Sets carry an absolute
+// expiry so every node expires the key at the same moment.
+type command struct {
+	Op        string                     `json:"op"`
+	Namespace string                     `json:"namespace,omitempty"`
+	Record    *storage.Record            `json:"record,omitempty"`
+	Key       string                     `json:"key,omitempty"`
+	Settings  *storage.NamespaceSettings `json:"settings,omitempty"`
+	Peers     []Peer                     `json:"peers,omitempty"`
+}
+
+// result is what FSM.Apply returns to the leader that proposed the command.
+type result struct {
+	existed bool
+	index   uint64
+	err     error
+}
+
+// fsm applies committed commands to the node's namespaces. Sets are
+// applied without quota or memory checks: the leader admits them before
+// they are committed, and a follower rejecting a committed entry would
+// diverge from the others.
+type fsm struct {
+	namespaces *storage.Namespaces
+
+	mu      sync.Mutex
+	applied uint64
+	// changed is closed and replaced whenever applied advances.
+	changed chan struct{}
+	// peers holds the addresses of every node that has been a member, by
+	// ID. The Raft configuration only knows Raft addresses; redirects need
+	// gRPC ones.
+	peers map[string]Peer
+}
+
+func newFSM(namespaces *storage.Namespaces, peers []Peer) *fsm {
+	f := &fsm{namespaces: namespaces, changed: make(chan struct{}), peers: make(map[string]Peer)}
+	for _, p := range peers {
+		f.peers[p.ID] = p
+	}
+	return f
+}
+
+// peer returns the addresses of the node with id.
+func (f *fsm) peer(id string) (Peer, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, ok := f.peers[id]
+	return p, ok
+}
+
+func (f *fsm) Apply(l *raft.Log) any {
+	defer f.advance(l.Index)
+
+	var cmd command
+	if err := json.Unmarshal(l.Data, &cmd); err != nil {
+		return &result{err: fmt.Errorf("cluster: corrupt log entry %d: %w", l.Index, err)}
+	}
+	return f.apply(cmd)
+}
+
+// advance records that every entry up to index has been applied.
+func (f *fsm) advance(index uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if index > f.applied {
+		f.applied = index
+		close(f.changed)
+		f.changed = make(chan struct{})
+	}
+}
+
+// appliedIndex returns the last applied index and a channel closed once it
+// advances.
+func (f *fsm) appliedIndex() (uint64, <-chan struct{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.applied, f.changed
+}
+
+// StoreConfiguration implements raft.ConfigurationStore, so that
+// membership changes advance the applied index like any other entry.
+func (f *fsm) StoreConfiguration(index uint64, _ raft.Configuration) {
+	f.advance(index)
+}
+
+func (f *fsm) apply(cmd command) *result {
+	ctx := context.Background()
+
+	switch cmd.Op {
+	case opSet:
+		ns, err := f.namespaces.Get(cmd.Namespace)
+		if err != nil {
+			return &result{err: err}
+		}
+		return &result{err: ns.Load(*cmd.Record)}
+
+	case opDelete:
+		ns, err := f.namespaces.Get(cmd.Namespace)
+		if err != nil {
+			return &result{err: err}
+		}
+		existed, err := ns.DeleteContext(ctx, cmd.Key)
+		return &result{existed: existed, err: err}
+
+	case opCreateNamespace:
+		_, err := f.namespaces.Create(cmd.Namespace, *cmd.Settings)
+		return &result{err: err}
+
+	case opDropNamespace:
+		return &result{err: f.namespaces.Drop(cmd.Namespace)}
+
+	case opSetQuota:
+		_, err := f.namespaces.SetQuota(cmd.Namespace, cmd.Settings.Quota)
+		return &result{err: err}
+
+	case opNoop:
+		return &result{}
+
+	case opAddPeers:
+		f.mu.Lock()
+		for _, p := range cmd.Peers {
+			f.peers[p.ID] = p
+		}
+		f.mu.Unlock()
+		return &result{}
+	}
+
+	return &result{err: fmt.Errorf("cluster: unknown operation %q", cmd.Op)}
+}
+
+func (f *fsm) Snapshot() (raft.FSMSnapshot, error) {
+	snap := &fsmSnapshot{Namespaces: f.namespaces.Snapshot()}
+
+	f.mu.Lock()
+	snap.Applied = f.applied
+	for _, p := range f.peers {
+		snap.Peers = append(snap.Peers, p)
+	}
+	f.mu.Unlock()
+
+	return snap, nil
+}
+
+func (f *fsm) Restore(r io.ReadCloser) error {
+	defer r.Close()
+
+	var snap fsmSnapshot
+	if err := json.NewDecoder(r).Decode(&snap); err != nil {
+		return fmt.Errorf("cluster: corrupt snapshot: %w", err)
+	}
+	if err := f.namespaces.Restore(snap.Namespaces); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	f.peers = make(map[string]Peer, len(snap.Peers))
+	for _, p := range snap.Peers {
+		f.peers[p.ID] = p
+	}
+	f.mu.Unlock()
+
+	f.advance(snap.Applied)
+	return nil
+}
+
+type fsmSnapshot struct {
+	Namespaces []storage.NamespaceSnapshot `json:"namespaces"`
+	Peers      []Peer                      `json:"peers,omitempty"`
+	// Applied is the last entry the namespaces reflect.
+	Applied uint64 `json:"applied,omitempty"`
+}
+
+func (s *fsmSnapshot) Persist(sink raft.SnapshotSink) error {
+	if err := json.NewEncoder(sink).Encode(s); err != nil {
+		sink.Cancel()
+		return err
+	}
+	return sink.Close()
+}
+
+func (s *fsmSnapshot) Release() {}
@@ -0,0 +1,204 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+var (
+	ErrUnknownMember = errors.New("cluster: no such member")
+	ErrMemberExists  = errors.New("cluster: member already exists")
+	// ErrLastVoter is returned for changes that would leave the group
+	// without a voter.
+	ErrLastVoter = errors.New("cluster: cannot remove the last voter")
+	// ErrNotLearner is returned when promoting a member that already votes.
+	ErrNotLearner = errors.New("cluster: member is not a learner")
+	// ErrNotVoter is returned when transferring leadership to a learner.
+	ErrNotVoter = errors.New("cluster: member is not a voter")
+	// ErrInvalidPeer is returned for a peer missing its ID or an address.
+	ErrInvalidPeer = errors.New("cluster: id, raft address and gRPC address are required")
+)
+
+// Member is a node in the Raft configuration.
+type Member struct {
+	Peer
+	// Voter members count towards the quorum. Learners receive the log but
+	// neither vote nor count towards commits.
+	Voter bool
+}
+
+// Membership is the Raft configuration as seen by one node.
+type Membership struct {
+	// Members are sorted by ID.
+	Members  []Member
+	LeaderID string
+	// Index is the log index of the configuration. Every node converges on
+	// the one with the highest index.
+	Index uint64
+}
+
+// Membership returns the latest configuration this node knows of. Any
+// member can serve it; a follower's view lags the leader's by at most the
+// entries it has yet to receive.
+func (n *Node) Membership() (Membership, error) {
+	future := n.raft.GetConfiguration()
+	if err := future.Error(); err != nil {
+		return Membership{}, fmt.Errorf("cluster: %w", err)
+	}
+
+	leaderID, _ := n.Leader()
+	out := Membership{LeaderID: leaderID, Index: future.Index()}
+	for _, server := range future.Configuration().Servers {
+		peer, ok := n.fsm.peer(string(server.ID))
+		if !ok {
+			peer = Peer{ID: string(server.ID)}
+		}
+		peer.RaftAddr = string(server.Address)
+
+		out.Members = append(out.Members, Member{Peer: peer, Voter: server.Suffrage == raft.Voter})
+	}
+	sort.Slice(out.Members, func(i, j int) bool { return out.Members[i].ID < out.Members[j].ID })
+
+	return out, nil
+}
+
+// AddLearner adds p as a learner. It starts receiving the log, or a
+// snapshot when it is too far behind, and can be promoted once it has
+// caught up.
+func (n *Node) AddLearner(ctx context.Context, p Peer) error {
+	if p.ID == "" || p.RaftAddr == "" || p.GRPCAddr == "" {
+		return ErrInvalidPeer
+	}
+
+	_, index, err := n.member(p.ID)
+	if err == nil {
+		return fmt.Errorf("%w: %s", ErrMemberExists, p.ID)
+	}
+	if !errors.Is(err, ErrUnknownMember) {
+		return err
+	}
+
+	// Record the gRPC address first, so that clients can be redirected to
+	// the learner as soon as it joins. The addresses of the members that
+	// bootstrapped the group come from their configuration rather than the
+	// log, so they are recorded along with it for the learner to see.
+	membership, err := n.Membership()
+	if err != nil {
+		return err
+	}
+	peers := []Peer{p}
+	for _, m := range membership.Members {
+		if m.GRPCAddr != "" {
+			peers = append(peers, m.Peer)
+		}
+	}
+	res, err := n.apply(ctx, command{Op: opAddPeers, Peers: peers})
+	if err != nil {
+		return err
+	}
+	if res.err != nil {
+		return res.err
+	}
+
+	return n.await(ctx, n.raft.AddNonvoter(raft.ServerID(p.ID), raft.ServerAddress(p.RaftAddr), index, timeout(ctx)))
+}
+
+// Promote makes learner id a voter. Promoting a learner that has not
+// caught up yet is safe but may stall commits until it has.
+func (n *Node) Promote(ctx context.Context, id string) error {
+	server, index, err := n.member(id)
+	if err != nil {
+		return err
+	}
+	if server.Suffrage == raft.Voter {
+		return fmt.Errorf("%w: %s", ErrNotLearner, id)
+	}
+
+	return n.await(ctx, n.raft.AddVoter(server.ID, server.Address, index, timeout(ctx)))
+}
+
+// Remove takes id out of the group. Removing the leader makes it step down
+// once the change commits, and the remaining voters elect a new one.
+func (n *Node) Remove(ctx context.Context, id string) error {
+	server, index, err := n.member(id)
+	if err != nil {
+		return err
+	}
+
+	if server.Suffrage == raft.Voter {
+		membership, err := n.Membership()
+		if err != nil {
+			return err
+		}
+		voters := 0
+		for _, m := range membership.Members {
+			if m.Voter {
+				voters++
+			}
+		}
+		if voters == 1 {
+			return ErrLastVoter
+		}
+	}
+
+	return n.await(ctx, n.raft.RemoveServer(server.ID, index, timeout(ctx)))
+}
+
+// TransferLeadership hands leadership to voter id, or to the most
+// up-to-date voter when id is empty, and waits until it has taken over.
+func (n *Node) TransferLeadership(ctx context.Context, id string) error {
+	if n.raft.State() != raft.Leader {
+		return n.notLeader()
+	}
+	if id == "" {
+		return n.await(ctx, n.raft.LeadershipTransfer())
+	}
+
+	server, _, err := n.member(id)
+	if err != nil {
+		return err
+	}
+	if server.Suffrage != raft.Voter {
+		return fmt.Errorf("%w: %s", ErrNotVoter, id)
+	}
+	if id == n.id {
+		return nil
+	}
+
+	return n.await(ctx, n.raft.LeadershipTransferToServer(server.ID, server.Address))
+}
+
+// member looks id up in the configuration as seen by the leader. The
+// returned index makes a following change fail rather than apply on top of
+// a concurrent one.
+func (n *Node) member(id string) (raft.Server, uint64, error) {
+	if n.raft.State() != raft.Leader {
+		return raft.Server{}, 0, n.notLeader()
+	}
+
+	future := n.raft.GetConfiguration()
+	if err := future.Error(); err != nil {
+		return raft.Server{}, 0, fmt.Errorf("cluster: %w", err)
+	}
+	for _, server := range future.Configuration().Servers {
+		if string(server.ID) == id {
+			return server, future.Index(), nil
+		}
+	}
+
+	return raft.Server{}, future.Index(), fmt.Errorf("%w: %s", ErrUnknownMember, id)
+}
+
+// timeout converts the deadline of ctx into a raft enqueue timeout. Zero
+// waits indefinitely.
+func timeout(ctx context.Context) time.Duration {
+	if deadline, ok := ctx.Deadline(); ok {
+		return time.Until(deadline)
+	}
+	return 0
+}
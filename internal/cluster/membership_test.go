@@ -0,0 +1,93 @@
+package cluster
+
+import (
+	"context"
+	"kvstore/internal/storage"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// roles returns the members of m by ID, true for voters.
+func roles(m Membership) map[string]bool {
+	out := make(map[string]bool)
+	for _, member := range m.Members {
+		out[member.ID] = member.Voter
+	}
+	return out
+}
+
+// Test a node joins as a learner, is promoted, takes over leadership and
+// the old leader is removed, with every step visible from any node
+func TestMembership_ReplaceNode(t *testing.T) {
+	h := newHarness(t, 3)
+	old := h.leader()
+	ctx := context.Background()
+	require.NoError(t, set(h.nodes[old], ctx, storage.DefaultNamespace, storage.Record{Key: "a", Value: "1"}))
+
+	n4 := h.join()
+	require.NoError(t, h.nodes[old].AddLearner(ctx, h.peers[n4]))
+	h.eventually(n4, "a", "1")
+
+	require.Eventually(t, func() bool {
+		m, err := h.nodes[n4].Membership()
+		return err == nil && len(m.Members) == 4 && m.LeaderID == h.peers[old].ID
+	}, 5*time.Second, 10*time.Millisecond)
+	m, err := h.nodes[n4].Membership()
+	require.NoError(t, err)
+	assert.False(t, roles(m)["n4"], "n4 joins as a learner")
+	assert.Equal(t, h.peers[n4], m.Members[3].Peer)
+
+	require.NoError(t, h.nodes[old].Promote(ctx, "n4"))
+	require.NoError(t, h.nodes[old].TransferLeadership(ctx, "n4"))
+	assert.Equal(t, n4, h.leader())
+
+	// The learner's gRPC address reaches redirects on every node
+	follower := (old + 1) % 3
+	require.Eventually(t, func() bool {
+		_, addr := h.nodes[follower].Leader()
+		return addr == h.peers[n4].GRPCAddr
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, h.nodes[n4].Remove(ctx, h.peers[old].ID))
+	require.Eventually(t, func() bool {
+		m, err := h.nodes[follower].Membership()
+		if err != nil {
+			return false
+		}
+		r := roles(m)
+		_, stillThere := r[h.peers[old].ID]
+		return len(r) == 3 && r["n4"] && !stillThere
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, set(h.nodes[n4], ctx, storage.DefaultNamespace, storage.Record{Key: "b", Value: "2"}))
+	h.eventually(follower, "b", "2")
+}
+
+// Test invalid membership changes are refused
+func TestMembership_Errors(t *testing.T) {
+	h := newHarness(t, 3)
+	leader := h.nodes[h.leader()]
+	follower := h.nodes[(h.leader()+1)%3]
+	ctx := context.Background()
+
+	assert.ErrorIs(t, leader.AddLearner(ctx, h.peers[0]), ErrMemberExists)
+	assert.ErrorIs(t, leader.Promote(ctx, "n1"), ErrNotLearner)
+	assert.ErrorIs(t, leader.Remove(ctx, "n9"), ErrUnknownMember)
+	assert.ErrorIs(t, leader.TransferLeadership(ctx, "n9"), ErrUnknownMember)
+	assert.Error(t, leader.AddLearner(ctx, Peer{ID: "n9"}), "addresses are required")
+
+	var notLeader *NotLeaderError
+	assert.ErrorAs(t, follower.AddLearner(ctx, Peer{ID: "n9", RaftAddr: "x", GRPCAddr: "y"}), &notLeader)
+	assert.ErrorAs(t, follower.Remove(ctx, "n1"), &notLeader)
+
+	// Shrink to a single voter, which cannot be removed
+	for _, p := range h.peers {
+		if p.ID != leader.ID() {
+			require.NoError(t, leader.Remove(ctx, p.ID))
+		}
+	}
+	assert.ErrorIs(t, leader.Remove(ctx, leader.ID()), ErrLastVoter)
+}
@@ -0,0 +1,353 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"kvstore/internal/storage"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/raft"
+	raftboltdb "github.com/hashicorp/raft-boltdb/v2"
+)
+
+// ErrNotLeader is matched by every *NotLeaderError.
+var ErrNotLeader = errors.New("cluster: not the leader")
+
+// NotLeaderError is returned for writes sent to a node that is not the
+// leader. LeaderAddr is the leader's gRPC address, empty while no leader is
+// known, such as during an election.
+type NotLeaderError struct {
+	LeaderID   string
+	LeaderAddr string
+}
+
+func (e *NotLeaderError) Error() string {
+	if e.LeaderID == "" {
+		return "cluster: not the leader and no leader is known"
+	}
+	return fmt.Sprintf("cluster: not the leader; the leader is %s at %s", e.LeaderID, e.LeaderAddr)
+}
+
+func (e *NotLeaderError) Is(target error) bool {
+	return target == ErrNotLeader
+}
+
+// Peer is a member of the Raft group.
+type Peer struct {
+	ID       string `json:"id"`
+	RaftAddr string `json:"raft_addr"`
+	// GRPCAddr is where clients redirected to this peer should connect.
+	GRPCAddr string `json:"grpc_addr"`
+}
+
+// Options configure a Node. Zero timeouts use the raft defaults.
+type Options struct {
+	ID string
+	// Peers lists the initial members of the group, including this node.
+	// They must agree on it; it seeds the group on first start. Nodes added
+	// later only need to list themselves.
+	Peers []Peer
+	// Bootstrap forms a new group from Peers if the node has no Raft state
+	// yet. It is safe to set on every member of a new group.
+	Bootstrap bool
+	// DataDir holds the Raft log and snapshots. Empty keeps them in memory,
+	// so a restarted node rejoins empty and catches up from the leader.
+	DataDir string
+	// Transport carries Raft traffic: raft.NewTCPTransport between
+	// processes, raft.NewInmemTransport in tests.
+	Transport raft.Transport
+
+	HeartbeatTimeout   time.Duration
+	ElectionTimeout    time.Duration
+	LeaderLeaseTimeout time.Duration
+	CommitTimeout      time.Duration
+	// SnapshotThreshold is how many log entries trigger a snapshot.
+	SnapshotThreshold uint64
+
+	// LogOutput receives Raft's own logs, warnings and above. Nil uses
+	// stderr.
+	LogOutput io.Writer
+}
+
+// Node is one member of a Raft group replicating a storage.Namespaces.
+// Writes go through the leader's log and are applied to every member's
+// namespaces; reads are served by each node from its own copy.
+type Node struct {
+	id    string
+	raft  *raft.Raft
+	trans raft.Transport
+	fsm   *fsm
+
+	// leaseDuration is how long after a confirmed heartbeat round no other
+	// leader can be elected, with a margin for clock drift.
+	leaseDuration time.Duration
+
+	mu sync.Mutex
+	// barrierTerm is the last term the node committed a no-op in as
+	// leader.
+	barrierTerm uint64
+	leaseTerm   uint64
+	leaseExpiry time.Time
+
+	closers []io.Closer
+}
+
+// leaseDriftMargin is the share of the heartbeat timeout a lease gives up,
+// since the leader's and the followers' clocks may run at different
+// rates.
+const leaseDriftMargin = 0.2
+
+// New starts a node applying the replicated log to namespaces. Whatever
+// namespaces holds is replaced when the node restores a snapshot.
+func New(opts Options, namespaces *storage.Namespaces) (*Node, error) {
+	if opts.ID == "" {
+		return nil, errors.New("cluster: node ID is required")
+	}
+	if opts.Transport == nil {
+		return nil, errors.New("cluster: transport is required")
+	}
+
+	logOutput := opts.LogOutput
+	if logOutput == nil {
+		logOutput = os.Stderr
+	}
+
+	conf := raft.DefaultConfig()
+	conf.LocalID = raft.ServerID(opts.ID)
+	conf.Logger = hclog.New(&hclog.LoggerOptions{
+		Name:   "raft",
+		Level:  hclog.Warn,
+		Output: logOutput,
+	})
+	if opts.HeartbeatTimeout > 0 {
+		conf.HeartbeatTimeout = opts.HeartbeatTimeout
+	}
+	if opts.ElectionTimeout > 0 {
+		conf.ElectionTimeout = opts.ElectionTimeout
+	}
+	if opts.LeaderLeaseTimeout > 0 {
+		conf.LeaderLeaseTimeout = opts.LeaderLeaseTimeout
+	}
+	if opts.CommitTimeout > 0 {
+		conf.CommitTimeout = opts.CommitTimeout
+	}
+	if opts.SnapshotThreshold > 0 {
+		conf.SnapshotThreshold = opts.SnapshotThreshold
+	}
+
+	n := &Node{
+		id:            opts.ID,
+		trans:         opts.Transport,
+		leaseDuration: time.Duration(float64(min(conf.HeartbeatTimeout, conf.ElectionTimeout)) * (1 - leaseDriftMargin)),
+	}
+
+	var (
+		logs   raft.LogStore
+		stable raft.StableStore
+		snaps  raft.SnapshotStore
+	)
+	if opts.DataDir == "" {
+		store := raft.NewInmemStore()
+		logs, stable = store, store
+		snaps = raft.NewInmemSnapshotStore()
+	} else {
+		if err := os.MkdirAll(opts.DataDir, 0o700); err != nil {
+			return nil, fmt.Errorf("cluster: failed to create data dir: %w", err)
+		}
+		store, err := raftboltdb.NewBoltStore(filepath.Join(opts.DataDir, "raft.db"))
+		if err != nil {
+			return nil, fmt.Errorf("cluster: failed to open raft log: %w", err)
+		}
+		n.closers = append(n.closers, store)
+		logs, stable = store, store
+
+		snaps, err = raft.NewFileSnapshotStore(opts.DataDir, 2, logOutput)
+		if err != nil {
+			store.Close()
+			return nil, fmt.Errorf("cluster: failed to open snapshot store: %w", err)
+		}
+	}
+
+	if opts.Bootstrap {
+		existing, err := raft.HasExistingState(logs, stable, snaps)
+		if err != nil {
+			n.close()
+			return nil, fmt.Errorf("cluster: failed to inspect raft state: %w", err)
+		}
+		if !existing {
+			var servers []raft.Server
+			for _, p := range opts.Peers {
+				servers = append(servers, raft.Server{
+					ID:      raft.ServerID(p.ID),
+					Address: raft.ServerAddress(p.RaftAddr),
+				})
+			}
+			err := raft.BootstrapCluster(conf, logs, stable, snaps, opts.Transport,
+				raft.Configuration{Servers: servers})
+			if err != nil {
+				n.close()
+				return nil, fmt.Errorf("cluster: failed to bootstrap: %w", err)
+			}
+		}
+	}
+
+	n.fsm = newFSM(namespaces, opts.Peers)
+	r, err := raft.NewRaft(conf, n.fsm, logs, stable, snaps, opts.Transport)
+	if err != nil {
+		n.close()
+		return nil, fmt.Errorf("cluster: failed to start raft: %w", err)
+	}
+	n.raft = r
+
+	return n, nil
+}
+
+func (n *Node) ID() string {
+	return n.id
+}
+
+func (n *Node) IsLeader() bool {
+	return n.raft.State() == raft.Leader
+}
+
+// State is the node's Raft state: Follower, Candidate, Leader or Shutdown.
+func (n *Node) State() string {
+	return n.raft.State().String()
+}
+
+// Leader returns the ID and gRPC address of the current leader, or empty
+// strings while none is known.
+func (n *Node) Leader() (id, addr string) {
+	_, leaderID := n.raft.LeaderWithID()
+	peer, _ := n.fsm.peer(string(leaderID))
+	return string(leaderID), peer.GRPCAddr
+}
+
+// Set replicates r into namespace and returns the index it committed at.
+// The leader checks r against the quotas and the memory limit before
+// committing it. Writes admitted concurrently may together exceed them by
+// the size of the entries in flight.
+func (n *Node) Set(ctx context.Context, namespace string, r storage.Record) (uint64, error) {
+	if n.raft.State() != raft.Leader {
+		return 0, n.notLeader()
+	}
+	ns, err := n.fsm.namespaces.Get(namespace)
+	if err != nil {
+		return 0, err
+	}
+	if err := ns.Admit(r); err != nil {
+		return 0, err
+	}
+
+	res, err := n.apply(ctx, command{Op: opSet, Namespace: namespace, Record: &r})
+	if err != nil {
+		return 0, err
+	}
+	return res.index, res.err
+}
+
+// Delete replicates the deletion of key from namespace, reports whether it
+// existed and returns the index it committed at.
+func (n *Node) Delete(ctx context.Context, namespace, key string) (bool, uint64, error) {
+	res, err := n.apply(ctx, command{Op: opDelete, Namespace: namespace, Key: key})
+	if err != nil {
+		return false, 0, err
+	}
+	return res.existed, res.index, res.err
+}
+
+func (n *Node) CreateNamespace(ctx context.Context, name string, settings storage.NamespaceSettings) error {
+	res, err := n.apply(ctx, command{Op: opCreateNamespace, Namespace: name, Settings: &settings})
+	if err != nil {
+		return err
+	}
+	return res.err
+}
+
+func (n *Node) DropNamespace(ctx context.Context, name string) error {
+	res, err := n.apply(ctx, command{Op: opDropNamespace, Namespace: name})
+	if err != nil {
+		return err
+	}
+	return res.err
+}
+
+func (n *Node) SetNamespaceQuota(ctx context.Context, name string, quota storage.Quota) error {
+	res, err := n.apply(ctx, command{Op: opSetQuota, Namespace: name, Settings: &storage.NamespaceSettings{Quota: quota}})
+	if err != nil {
+		return err
+	}
+	return res.err
+}
+
+// apply commits cmd and waits until this node has applied it. It fails with
+// a *NotLeaderError unless the node is the leader.
+func (n *Node) apply(ctx context.Context, cmd command) (*result, error) {
+	if n.raft.State() != raft.Leader {
+		return nil, n.notLeader()
+	}
+
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	future := n.raft.Apply(data, timeout(ctx))
+	if err := n.await(ctx, future); err != nil {
+		return nil, err
+	}
+
+	res := future.Response().(*result)
+	res.index = future.Index()
+	return res, nil
+}
+
+// await waits for future, converting lost leadership into a
+// *NotLeaderError.
+func (n *Node) await(ctx context.Context, future raft.Future) error {
+	errc := make(chan error, 1)
+	go func() { errc <- future.Error() }()
+
+	select {
+	case err := <-errc:
+		switch {
+		case err == nil:
+			return nil
+		case errors.Is(err, raft.ErrNotLeader), errors.Is(err, raft.ErrLeadershipLost):
+			return n.notLeader()
+		}
+		return fmt.Errorf("cluster: %w", err)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (n *Node) notLeader() error {
+	id, addr := n.Leader()
+	return &NotLeaderError{LeaderID: id, LeaderAddr: addr}
+}
+
+// Shutdown stops the node. Other members keep serving as long as a majority
+// of them remains.
+func (n *Node) Shutdown() error {
+	err := n.raft.Shutdown().Error()
+	if closer, ok := n.trans.(io.Closer); ok {
+		closer.Close()
+	}
+	n.close()
+	return err
+}
+
+func (n *Node) close() {
+	for _, c := range n.closers {
+		c.Close()
+	}
+	n.closers = nil
+}
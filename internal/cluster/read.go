@@ -0,0 +1,202 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+// Consistency is how up to date a read must be. See ReadConsistency in
+// api/proto/kvstore.proto for the guarantees of each.
+type Consistency int
+
+const (
+	Sequential Consistency = iota
+	Linearizable
+	Lease
+	Stale
+)
+
+// ErrTooStale is matched by every *StaleError.
+var ErrTooStale = errors.New("cluster: replica is too stale")
+
+// StaleError is returned for a stale read on a follower that has not heard
+// from the leader within the requested bound.
+type StaleError struct {
+	// Staleness is how long ago the follower last heard from the leader.
+	Staleness  time.Duration
+	Bound      time.Duration
+	LeaderID   string
+	LeaderAddr string
+}
+
+func (e *StaleError) Error() string {
+	return fmt.Sprintf("cluster: replica is too stale: last heard from the leader %s ago, bound is %s",
+		e.Staleness.Round(time.Millisecond), e.Bound)
+}
+
+func (e *StaleError) Is(target error) bool {
+	return target == ErrTooStale
+}
+
+type ReadOptions struct {
+	Consistency Consistency
+	// MinIndex is the index a Sequential read waits for.
+	MinIndex uint64
+	// MaxStaleness bounds a Stale read on a follower. Zero is unbounded.
+	MaxStaleness time.Duration
+}
+
+// Read blocks until the node may serve a read with opts from its own
+// namespaces, and returns the index the node has applied. Linearizable and
+// Lease reads fail with a *NotLeaderError on followers, bounded Stale reads
+// with a *StaleError.
+func (n *Node) Read(ctx context.Context, opts ReadOptions) (uint64, error) {
+	switch opts.Consistency {
+	case Linearizable, Lease:
+		if n.raft.State() != raft.Leader {
+			return 0, n.notLeader()
+		}
+		index, ok := uint64(0), false
+		if opts.Consistency == Lease {
+			index, ok = n.leaseIndex()
+		}
+		if !ok {
+			var err error
+			if index, err = n.readIndex(ctx); err != nil {
+				return 0, err
+			}
+		}
+		return n.waitApplied(ctx, index)
+
+	case Sequential:
+		return n.waitApplied(ctx, opts.MinIndex)
+
+	case Stale:
+		if opts.MaxStaleness > 0 && n.raft.State() != raft.Leader {
+			if staleness := n.staleness(); staleness > opts.MaxStaleness {
+				id, addr := n.Leader()
+				return 0, &StaleError{Staleness: staleness, Bound: opts.MaxStaleness, LeaderID: id, LeaderAddr: addr}
+			}
+		}
+		return n.AppliedIndex(), nil
+	}
+
+	return 0, fmt.Errorf("cluster: unknown read consistency %d", opts.Consistency)
+}
+
+// AppliedIndex is the last log index applied to this node's namespaces.
+// Only entries the FSM sees count, so it stays at the last command or
+// configuration change when Raft appends a no-op or barrier entry: reads
+// wait only for indexes the node has returned, which are all of that kind.
+func (n *Node) AppliedIndex() uint64 {
+	applied, _ := n.fsm.appliedIndex()
+	return applied
+}
+
+// readIndex returns an index covering every write committed before it was
+// called, once the node has confirmed with a majority that it still leads.
+// A new leader may not know the commit index of its predecessor's last
+// entries, so it first commits a no-op in its own term. A successful
+// confirmation renews the lease.
+func (n *Node) readIndex(ctx context.Context) (uint64, error) {
+	term := n.raft.CurrentTerm()
+	if err := n.barrier(ctx, term); err != nil {
+		return 0, err
+	}
+
+	index := n.raft.CommitIndex()
+	start := time.Now()
+	if err := n.verifyLeader(ctx); err != nil {
+		return 0, err
+	}
+	// Leading again in a later term, the commit index may be the no-op
+	// Raft starts it with, which the FSM never applies.
+	if n.raft.CurrentTerm() != term {
+		return 0, n.notLeader()
+	}
+
+	n.mu.Lock()
+	if term > n.leaseTerm || (term == n.leaseTerm && start.Add(n.leaseDuration).After(n.leaseExpiry)) {
+		n.leaseTerm = term
+		n.leaseExpiry = start.Add(n.leaseDuration)
+	}
+	n.mu.Unlock()
+
+	return index, nil
+}
+
+// barrier commits a no-op in term unless one already has been.
+func (n *Node) barrier(ctx context.Context, term uint64) error {
+	n.mu.Lock()
+	done := n.barrierTerm >= term
+	n.mu.Unlock()
+	if done {
+		return nil
+	}
+
+	if _, err := n.apply(ctx, command{Op: opNoop}); err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	n.barrierTerm = max(n.barrierTerm, term)
+	n.mu.Unlock()
+	return nil
+}
+
+// leaseIndex returns the commit index if the node holds a lease in its
+// current term. The lease starts when the node sends a heartbeat round that
+// a majority answers: those followers reset their timers after that, and
+// none stands for election before its heartbeat timeout passes, so no other
+// leader can be elected until then.
+func (n *Node) leaseIndex() (uint64, bool) {
+	term := n.raft.CurrentTerm()
+
+	n.mu.Lock()
+	held := n.leaseTerm == term && time.Now().Before(n.leaseExpiry)
+	n.mu.Unlock()
+
+	if !held || n.raft.State() != raft.Leader {
+		return 0, false
+	}
+	return n.raft.CommitIndex(), true
+}
+
+// verifyLeader confirms with a majority that the node still leads.
+func (n *Node) verifyLeader(ctx context.Context) error {
+	err := n.await(ctx, n.raft.VerifyLeader())
+	if err != nil && ctx.Err() == nil {
+		return n.notLeader()
+	}
+	return err
+}
+
+// waitApplied blocks until the node has applied index.
+func (n *Node) waitApplied(ctx context.Context, index uint64) (uint64, error) {
+	for {
+		applied, changed := n.fsm.appliedIndex()
+		if applied >= index {
+			return applied, nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return 0, fmt.Errorf("cluster: index %d not applied yet, at %d: %w", index, applied, ctx.Err())
+		}
+	}
+}
+
+// staleness is how long ago a follower last heard from the leader.
+func (n *Node) staleness() time.Duration {
+	last := n.raft.LastContact()
+	if last.IsZero() {
+		return math.MaxInt64
+	}
+	return time.Since(last)
+}
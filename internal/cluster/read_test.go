@@ -0,0 +1,116 @@
+package cluster
+
+import (
+	"context"
+	"kvstore/internal/storage"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test linearizable and lease reads are served by the leader only and see
+// every acknowledged write
+func TestRead_Linearizable(t *testing.T) {
+	h := newHarness(t, 3)
+	leader := h.leader()
+	follower := (leader + 1) % len(h.nodes)
+	ctx := context.Background()
+
+	index, err := h.nodes[leader].Set(ctx, storage.DefaultNamespace, storage.Record{Key: "a", Value: "1"})
+	require.NoError(t, err)
+
+	for _, c := range []Consistency{Linearizable, Lease} {
+		applied, err := h.nodes[leader].Read(ctx, ReadOptions{Consistency: c})
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, applied, index)
+
+		_, err = h.nodes[follower].Read(ctx, ReadOptions{Consistency: c})
+		var notLeader *NotLeaderError
+		require.ErrorAs(t, err, &notLeader)
+		assert.Equal(t, h.peers[leader].GRPCAddr, notLeader.LeaderAddr)
+	}
+
+	// An isolated leader cannot confirm it still leads once the replies to
+	// what it sent before are in, nor serve from its lease once it expires
+	h.isolate(leader)
+	for _, c := range []Consistency{Linearizable, Lease} {
+		assert.Eventually(t, func() bool {
+			shortCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+			defer cancel()
+			_, err := h.nodes[leader].Read(shortCtx, ReadOptions{Consistency: c})
+			return err != nil
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+}
+
+// Test a new leader's linearizable reads see the writes its predecessor
+// acknowledged
+func TestRead_NewLeader(t *testing.T) {
+	h := newHarness(t, 3)
+	old := h.leader()
+	ctx := context.Background()
+	index, err := h.nodes[old].Set(ctx, storage.DefaultNamespace, storage.Record{Key: "a", Value: "1"})
+	require.NoError(t, err)
+
+	h.isolate(old)
+	leader := h.leader(old)
+	for _, c := range []Consistency{Linearizable, Lease} {
+		require.Eventually(t, func() bool {
+			shortCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+			defer cancel()
+			applied, err := h.nodes[leader].Read(shortCtx, ReadOptions{Consistency: c})
+			return err == nil && applied >= index
+		}, 5*time.Second, 10*time.Millisecond)
+		got, _ := h.namespaces[leader].Default().Get("a")
+		assert.Equal(t, "1", got)
+	}
+}
+
+// Test sequential reads wait for the index they are given
+func TestRead_SequentialMinIndex(t *testing.T) {
+	h := newHarness(t, 3)
+	leader := h.leader()
+	follower := (leader + 1) % len(h.nodes)
+	ctx := context.Background()
+
+	index, err := h.nodes[leader].Set(ctx, storage.DefaultNamespace, storage.Record{Key: "a", Value: "1"})
+	require.NoError(t, err)
+
+	applied, err := h.nodes[follower].Read(ctx, ReadOptions{Consistency: Sequential, MinIndex: index})
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, applied, index)
+	value, _ := h.namespaces[follower].Default().Get("a")
+	assert.Equal(t, "1", value, "the follower has applied the write the client saw")
+
+	shortCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = h.nodes[follower].Read(shortCtx, ReadOptions{Consistency: Sequential, MinIndex: index + 100})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+// Test bounded stale reads are refused by followers cut off from the leader
+func TestRead_BoundedStaleness(t *testing.T) {
+	h := newHarness(t, 3)
+	leader := h.leader()
+	follower := (leader + 1) % len(h.nodes)
+	ctx := context.Background()
+
+	require.Eventually(t, func() bool {
+		_, err := h.nodes[follower].Read(ctx, ReadOptions{Consistency: Stale, MaxStaleness: time.Second})
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond, "a follower in contact with the leader serves bounded reads")
+
+	h.isolate(follower)
+	time.Sleep(200 * time.Millisecond)
+
+	_, err := h.nodes[follower].Read(ctx, ReadOptions{Consistency: Stale, MaxStaleness: 100 * time.Millisecond})
+	var stale *StaleError
+	require.ErrorAs(t, err, &stale)
+	assert.ErrorIs(t, err, ErrTooStale)
+	assert.Greater(t, stale.Staleness, 100*time.Millisecond)
+
+	_, err = h.nodes[follower].Read(ctx, ReadOptions{Consistency: Stale})
+	assert.NoError(t, err, "unbounded stale reads are always served")
+}
@@ -0,0 +1,49 @@
+package cluster
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+// NewTLSTransport returns a Raft transport over mutual TLS listening on
+// bind. server must require and verify client certificates and client must
+// present one, so that only members holding a certificate of the cluster CA
+// can read or append to the log. advertise is the address other members
+// reach this node at.
+func NewTLSTransport(bind string, advertise net.Addr, server, client *tls.Config, maxPool int, timeout time.Duration, logOutput io.Writer) (*raft.NetworkTransport, error) {
+	ln, err := net.Listen("tcp", bind)
+	if err != nil {
+		return nil, err
+	}
+	if advertise == nil {
+		advertise = ln.Addr()
+	}
+	if addr, ok := advertise.(*net.TCPAddr); !ok || addr.IP == nil || addr.IP.IsUnspecified() {
+		ln.Close()
+		return nil, fmt.Errorf("cluster: %s is not an address other members can reach", advertise)
+	}
+
+	stream := &tlsStreamLayer{Listener: tls.NewListener(ln, server), advertise: advertise, client: client}
+	return raft.NewNetworkTransport(stream, maxPool, timeout, logOutput), nil
+}
+
+// tlsStreamLayer is a raft.StreamLayer over TLS.
+type tlsStreamLayer struct {
+	net.Listener
+	advertise net.Addr
+	client    *tls.Config
+}
+
+func (s *tlsStreamLayer) Addr() net.Addr {
+	return s.advertise
+}
+
+func (s *tlsStreamLayer) Dial(address raft.ServerAddress, timeout time.Duration) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	return tls.DialWithDialer(dialer, "tcp", string(address), s.client)
+}
@@ -0,0 +1,96 @@
+package cluster
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"kvstore/internal/tlsconfig"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// selfSigned writes a certificate for 127.0.0.1 that is its own CA, and
+// returns the paths of the certificate and its key.
+func selfSigned(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "raft"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+// Test members holding a certificate exchange Raft RPCs over TLS, and a
+// connection without one is refused
+func TestTLSTransport(t *testing.T) {
+	certFile, keyFile := selfSigned(t)
+	server, err := tlsconfig.Server(tlsconfig.ServerOptions{
+		CertFile: certFile, KeyFile: keyFile, ClientCAFile: certFile, ClientAuth: tlsconfig.ClientAuthRequire,
+	})
+	require.NoError(t, err)
+	client, err := tlsconfig.Client(tlsconfig.ClientOptions{CAFile: certFile, CertFile: certFile, KeyFile: keyFile})
+	require.NoError(t, err)
+
+	transport := func() *raft.NetworkTransport {
+		trans, err := NewTLSTransport("127.0.0.1:0", nil, server, client, 1, time.Second, io.Discard)
+		require.NoError(t, err)
+		t.Cleanup(func() { trans.Close() })
+		return trans
+	}
+	a, b := transport(), transport()
+
+	go func() {
+		rpc := <-b.Consumer()
+		rpc.Respond(&raft.AppendEntriesResponse{Term: 1, Success: true}, nil)
+	}()
+	var resp raft.AppendEntriesResponse
+	require.NoError(t, a.AppendEntries("b", b.LocalAddr(), &raft.AppendEntriesRequest{Term: 1}, &resp))
+	assert.True(t, resp.Success)
+
+	anonymous, err := tlsconfig.Client(tlsconfig.ClientOptions{CAFile: certFile})
+	require.NoError(t, err)
+	conn, err := tls.Dial("tcp", string(b.LocalAddr()), anonymous)
+	if err == nil {
+		defer conn.Close()
+		// With TLS 1.3 the server rejects the missing certificate after
+		// the client considers the handshake done.
+		conn.SetDeadline(time.Now().Add(time.Second))
+		_, err = conn.Read(make([]byte, 1))
+	}
+	require.Error(t, err)
+	var netErr net.Error
+	assert.False(t, errors.As(err, &netErr) && netErr.Timeout(), "the connection is refused, not left hanging")
+}
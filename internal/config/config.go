@@ -0,0 +1,831 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"kvstore/internal/auth"
+	"kvstore/internal/gossip"
+	"kvstore/internal/replication"
+	"kvstore/internal/shard"
+	"kvstore/internal/storage"
+	"kvstore/internal/tlsconfig"
+	"kvstore/internal/tracing"
+	"log/slog"
+	"net"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the complete server configuration. Values are resolved from, in
+// increasing order of precedence: built-in defaults, the config file,
+// KVSTORE_* environment variables and command-line flags.
+type Config struct {
+	Listen     ListenConfig    `yaml:"listen"`
+	Storage    StorageConfig   `yaml:"storage"`
+	TLS        TLSConfig       `yaml:"tls"`
+	Limits     LimitsConfig    `yaml:"limits"`
+	Auth       AuthConfig      `yaml:"auth"`
+	Quotas     QuotasConfig    `yaml:"quotas"`
+	RateLimits RateLimitConfig `yaml:"rate_limits"`
+	Tracing    TracingConfig   `yaml:"tracing"`
+	AccessLog  AccessLogConfig `yaml:"access_log"`
+	Audit      AuditConfig     `yaml:"audit"`
+	Cluster    ClusterConfig   `yaml:"cluster"`
+	// Replication is a simpler alternative to Cluster: asynchronous
+	// replication from a primary to read-only replicas.
+	Replication ReplicationConfig `yaml:"replication"`
+	Sharding    ShardingConfig    `yaml:"sharding"`
+	Gossip      GossipConfig      `yaml:"gossip"`
+	LogLevel    string            `yaml:"log_level"`
+	// LogFormat is text (logfmt) or json.
+	LogFormat string `yaml:"log_format"`
+	// Reflection registers the gRPC server reflection service so tools such
+	// as grpcurl can discover the API without the .proto files.
+	Reflection bool `yaml:"reflection"`
+	// ShutdownTimeout bounds how long in-flight requests may drain after
+	// SIGINT or SIGTERM before they are cut off.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+type ListenConfig struct {
+	GRPC string `yaml:"grpc"`
+	// HTTP is the REST gateway address. Empty disables the gateway.
+	HTTP string `yaml:"http"`
+	// Metrics serves Prometheus metrics on /metrics, without TLS or
+	// authentication. Empty disables the endpoint.
+	Metrics string `yaml:"metrics"`
+}
+
+type StorageConfig struct {
+	Backend string       `yaml:"backend"`
+	Memory  MemoryConfig `yaml:"memory"`
+}
+
+// MemoryConfig limits the memory of all namespaces together.
+type MemoryConfig struct {
+	MaxMemoryBytes int64  `yaml:"max_memory_bytes"`
+	EvictionPolicy string `yaml:"eviction_policy"`
+}
+
+// TLSConfig enables TLS on the gRPC server and REST gateway when CertFile
+// is set. Certificate files are watched and rotated without a restart.
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// ClientCAFile verifies client certificates for mutual TLS.
+	ClientCAFile string `yaml:"client_ca_file"`
+	// ClientAuth is none, request (verify if presented) or require.
+	ClientAuth string `yaml:"client_auth"`
+}
+
+type LimitsConfig struct {
+	MaxKeyBytes   int `yaml:"max_key_bytes"`
+	MaxValueBytes int `yaml:"max_value_bytes"`
+	MaxListLimit  int `yaml:"max_list_limit"`
+}
+
+// AuthConfig enables authentication when at least one API key or a JWT
+// secret is configured. Defining roles additionally restricts every
+// principal to the keys its roles grant.
+type AuthConfig struct {
+	APIKeys []APIKeyConfig `yaml:"api_keys,omitempty"`
+	JWT     JWTConfig      `yaml:"jwt"`
+	Roles   []RoleConfig   `yaml:"roles,omitempty"`
+}
+
+// Enabled reports whether requests must authenticate.
+func (c AuthConfig) Enabled() bool {
+	return len(c.APIKeys) > 0 || c.JWT.Secret != ""
+}
+
+type APIKeyConfig struct {
+	Principal string   `yaml:"principal"`
+	Key       string   `yaml:"key"`
+	Roles     []string `yaml:"roles,omitempty"`
+}
+
+type JWTConfig struct {
+	// Secret is the HMAC key used to verify HS256/384/512 tokens.
+	Secret   string `yaml:"secret"`
+	Issuer   string `yaml:"issuer"`
+	Audience string `yaml:"audience"`
+}
+
+type RoleConfig struct {
+	Name   string        `yaml:"name"`
+	Grants []GrantConfig `yaml:"grants"`
+}
+
+// GrantConfig gives permissions (read, write, delete, admin) on keys
+// matching any of the patterns: a plain prefix such as "team-a/", or a glob
+// such as "*/public/*".
+type GrantConfig struct {
+	Permissions []string `yaml:"permissions"`
+	Keys        []string `yaml:"keys"`
+	// Namespaces limits the grant to the named namespaces. Empty means
+	// every namespace.
+	Namespaces []string `yaml:"namespaces,omitempty"`
+}
+
+// QuotasConfig limits what each principal may store across all namespaces.
+// Namespace quotas are managed through the Admin API alongside the
+// namespaces themselves.
+type QuotasConfig struct {
+	Principals map[string]QuotaConfig `yaml:"principals,omitempty"`
+}
+
+// QuotaConfig limits are unlimited when zero.
+type QuotaConfig struct {
+	MaxKeys       int64 `yaml:"max_keys"`
+	MaxBytes      int64 `yaml:"max_bytes"`
+	MaxValueBytes int64 `yaml:"max_value_bytes"`
+}
+
+// RateLimitConfig throttles clients, identified by principal or, without
+// authentication, by IP address. Zero rates are unlimited.
+type RateLimitConfig struct {
+	// PerClient applies to each client across all methods.
+	PerClient RateConfig `yaml:"per_client"`
+	// Methods applies to each client per method, in addition to PerClient.
+	// Keys are full gRPC method names or bare names such as "Set".
+	Methods map[string]RateConfig `yaml:"methods,omitempty"`
+	// MaxInFlight caps concurrent requests across all clients.
+	MaxInFlight int `yaml:"max_in_flight"`
+}
+
+type RateConfig struct {
+	// Rate is in requests per second.
+	Rate float64 `yaml:"rate"`
+	// Burst defaults to one second's worth of requests.
+	Burst int `yaml:"burst"`
+}
+
+// TracingConfig exports OpenTelemetry spans for every RPC, with child spans
+// for storage operations and lock waits.
+type TracingConfig struct {
+	// Exporter is none, otlp, stdout or file.
+	Exporter string `yaml:"exporter"`
+	// Endpoint is the OTLP/gRPC collector address, host:port.
+	Endpoint string `yaml:"endpoint"`
+	Insecure bool   `yaml:"insecure"`
+	// File is where the file exporter appends spans as JSON.
+	File string `yaml:"file"`
+	// SampleRatio is the fraction of new traces recorded, from 0 to 1.
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// AccessLogConfig logs every request and records slow ones in the slow log,
+// which is read through the Admin API.
+type AccessLogConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Level applies to successful requests and client errors. Server errors
+	// are logged at error and slow requests at warn.
+	Level string `yaml:"level"`
+	// RedactKeys logs a hash instead of each key.
+	RedactKeys bool `yaml:"redact_keys"`
+	// SlowThreshold is the latency from which a request is slow. Zero
+	// disables the slow log.
+	SlowThreshold time.Duration `yaml:"slow_threshold"`
+	// SlowLogSize is how many slow operations are kept.
+	SlowLogSize int `yaml:"slow_log_size"`
+}
+
+// AuditConfig records every Set, Delete and Admin call, with its principal
+// and result, in a hash-chained append-only file. Verify it with
+// cmd/audit.
+type AuditConfig struct {
+	// File is the audit log path. Empty disables auditing.
+	File string `yaml:"file"`
+	// Sync flushes every entry to disk before the call returns.
+	Sync bool `yaml:"sync"`
+}
+
+// ClusterConfig replicates every write through a Raft group of servers,
+// which keeps serving while a majority of them is up. An empty NodeID runs
+// a standalone server.
+type ClusterConfig struct {
+	NodeID string `yaml:"node_id"`
+	// RaftAddr is where this node listens for Raft traffic.
+	RaftAddr string `yaml:"raft_addr"`
+	// DataDir keeps the Raft log and snapshots. Empty keeps them in memory,
+	// so a restarted node rejoins empty and catches up from the leader.
+	DataDir string `yaml:"data_dir"`
+	// Bootstrap forms a new group from Peers on first start. It is safe to
+	// set on every member of the initial group.
+	Bootstrap bool `yaml:"bootstrap"`
+	// Peers lists every member, including this node.
+	Peers []PeerConfig `yaml:"peers,omitempty"`
+	// CertFile and KeyFile are this node's certificate for Raft traffic,
+	// which runs over mutual TLS when they are set: every member presents
+	// its certificate and verifies the others' against CAFile. The
+	// certificate must be valid for both server and client authentication
+	// and name the host of this node's raft_addr. Required when
+	// authentication is enabled.
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	CAFile   string `yaml:"ca_file"`
+}
+
+type PeerConfig struct {
+	ID string `yaml:"id"`
+	// RaftAddr is the address other members reach the peer's Raft
+	// listener at.
+	RaftAddr string `yaml:"raft_addr"`
+	// GRPCAddr is the address clients are redirected to.
+	GRPCAddr string `yaml:"grpc_addr"`
+}
+
+// Enabled reports whether the server runs as a member of a Raft group.
+func (c ClusterConfig) Enabled() bool {
+	return c.NodeID != ""
+}
+
+// ReplicationConfig streams every write of a primary to read-only replicas,
+// which serve reads from their own copy and redirect writes to the
+// primary.
+type ReplicationConfig struct {
+	// Role is primary, replica, active, or empty to disable replication.
+	// Active sites all accept writes and replicate them to each other.
+	Role string `yaml:"role"`
+	// PrimaryAddr is the gRPC address of the primary, which replicas stream
+	// from and redirect writes to.
+	PrimaryAddr string `yaml:"primary_addr"`
+	// LogSize is how many recent writes a primary retains for replicas to
+	// catch up from after reconnecting. Replicas further behind resync from
+	// a full snapshot.
+	LogSize int `yaml:"log_size"`
+	// APIKey authenticates a replica to a primary that requires
+	// authentication. Its principal needs the admin permission. It
+	// requires CAFile.
+	APIKey string `yaml:"api_key"`
+	// CAFile enables TLS to the primary, verified against this CA bundle.
+	CAFile string `yaml:"ca_file"`
+	// AntiEntropyInterval is how often a replica compares its keys with the
+	// primary's and repairs the ranges that diverged. Zero disables it.
+	// Cluster members and shards are never checked this way.
+	AntiEntropyInterval time.Duration `yaml:"anti_entropy_interval"`
+	// SiteID names an active site. Every site needs a different one.
+	SiteID string `yaml:"site_id"`
+	// Peers are the gRPC addresses of every other active site. APIKey and
+	// CAFile apply to them as to a primary.
+	Peers []string `yaml:"peers,omitempty"`
+	// MaxClockOffset is how far ahead of an active site's clock the writes
+	// of other sites may be stamped. Later ones wait for the clock to
+	// catch up.
+	MaxClockOffset time.Duration `yaml:"max_clock_offset"`
+}
+
+// ShardingConfig partitions keys across servers with a consistent hash
+// ring. Each server serves only the keys it owns and redirects requests
+// for the others to their owner. An empty NodeID disables sharding.
+type ShardingConfig struct {
+	NodeID string `yaml:"node_id"`
+	// VirtualNodes is how many points each node has on the ring. Every node
+	// must use the same value.
+	VirtualNodes int `yaml:"virtual_nodes"`
+	// Nodes lists every shard identically on every node. A node joining
+	// the deployment lists the current shards without itself, and owns no
+	// keys until a rebalance adds it.
+	Nodes []ShardNodeConfig `yaml:"nodes,omitempty"`
+	// MapFile keeps the shard map last committed by a rebalance, which the
+	// node routes by instead of Nodes once it exists. Empty forgets
+	// rebalances on restart, starting again from Nodes at version 1.
+	MapFile string `yaml:"map_file"`
+	// APIKey authenticates this node to the others when it hands keys over
+	// during a rebalance. Its principal needs the admin permission. It
+	// requires CAFile.
+	APIKey string `yaml:"api_key"`
+	// CAFile enables TLS to the other nodes, verified against this CA
+	// bundle.
+	CAFile string `yaml:"ca_file"`
+}
+
+type ShardNodeConfig struct {
+	ID string `yaml:"id"`
+	// GRPCAddr is the address requests for the node's keys are redirected
+	// to.
+	GRPCAddr string `yaml:"grpc_addr"`
+}
+
+// Enabled reports whether keys are partitioned across servers.
+func (c ShardingConfig) Enabled() bool {
+	return c.NodeID != ""
+}
+
+// GossipConfig lets servers discover each other and detect failures with
+// the SWIM gossip protocol, and publish their role, shard and version.
+// Shard nodes that missed a rebalance, such as while they were down, catch
+// up with the shard map the others gossip they route by. Cluster and
+// replication still route by their own peer lists. An empty NodeID
+// disables gossip.
+type GossipConfig struct {
+	NodeID string `yaml:"node_id"`
+	// AdvertiseAddr is the gRPC address other servers reach this one at.
+	// Empty uses listen.grpc, which must then name a host.
+	AdvertiseAddr string `yaml:"advertise_addr"`
+	// Seeds are gRPC addresses of servers to learn the membership from on
+	// start. The first server of a deployment may list none.
+	Seeds []string `yaml:"seeds,omitempty"`
+	// Interval is how often a member is probed, and ProbeTimeout how long
+	// it has to answer before other members are asked to probe it.
+	Interval     time.Duration `yaml:"interval"`
+	ProbeTimeout time.Duration `yaml:"probe_timeout"`
+	// SuspicionTimeout is how long a member that missed a probe has to
+	// refute the suspicion before it is declared dead.
+	SuspicionTimeout time.Duration `yaml:"suspicion_timeout"`
+	// IndirectChecks is how many members probe a member that did not
+	// answer directly.
+	IndirectChecks int `yaml:"indirect_checks"`
+	// SyncInterval is how often the whole membership is exchanged with a
+	// random member, which heals partitions.
+	SyncInterval time.Duration `yaml:"sync_interval"`
+	// APIKey authenticates this server to the others. Its principal needs
+	// the admin permission. It requires CAFile.
+	APIKey string `yaml:"api_key"`
+	// CAFile enables TLS to the other servers, verified against this CA
+	// bundle.
+	CAFile string `yaml:"ca_file"`
+}
+
+// Enabled reports whether the server gossips with others.
+func (c GossipConfig) Enabled() bool {
+	return c.NodeID != ""
+}
+
+const BackendMemory = "memory"
+
+// Log formats accepted in Config.LogFormat.
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// minSecretBytes is the shortest accepted API key or JWT secret.
+const minSecretBytes = 16
+
+const redacted = "REDACTED"
+
+func Default() *Config {
+	return &Config{
+		Listen: ListenConfig{
+			GRPC:    ":9090",
+			HTTP:    ":8080",
+			Metrics: ":9091",
+		},
+		Storage: StorageConfig{
+			Backend: BackendMemory,
+			Memory: MemoryConfig{
+				EvictionPolicy: string(storage.NoEviction),
+			},
+		},
+		TLS: TLSConfig{
+			ClientAuth: tlsconfig.ClientAuthNone,
+		},
+		Limits: LimitsConfig{
+			MaxKeyBytes:   1024,
+			MaxValueBytes: 1 << 20,
+			MaxListLimit:  10000,
+		},
+		Tracing: TracingConfig{
+			Exporter:    tracing.ExporterNone,
+			SampleRatio: 1,
+		},
+		AccessLog: AccessLogConfig{
+			Enabled:       true,
+			Level:         "info",
+			SlowThreshold: 10 * time.Millisecond,
+			SlowLogSize:   128,
+		},
+		Audit: AuditConfig{
+			Sync: true,
+		},
+		Replication: ReplicationConfig{
+			LogSize:             replication.DefaultLogSize,
+			AntiEntropyInterval: replication.DefaultAntiEntropyInterval,
+			MaxClockOffset:      replication.DefaultMaxClockOffset,
+		},
+		Sharding: ShardingConfig{
+			VirtualNodes: shard.DefaultVirtualNodes,
+		},
+		Gossip: GossipConfig{
+			Interval:         gossip.DefaultInterval,
+			ProbeTimeout:     gossip.DefaultProbeTimeout,
+			SuspicionTimeout: gossip.DefaultSuspicionTimeout,
+			IndirectChecks:   gossip.DefaultIndirectChecks,
+			SyncInterval:     gossip.DefaultSyncInterval,
+		},
+		LogLevel:        "info",
+		LogFormat:       LogFormatText,
+		ShutdownTimeout: 30 * time.Second,
+	}
+}
+
+// LoadFile overlays the YAML or JSON file at path onto c. Unknown fields are
+// rejected so that typos do not silently fall back to defaults.
+func (c *Config) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(field, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+	}
+
+	if c.Listen.GRPC == "" {
+		fail("listen.grpc", "must not be empty")
+	}
+	if c.Listen.HTTP != "" && c.Listen.HTTP == c.Listen.GRPC {
+		fail("listen.http", "must differ from listen.grpc (%s)", c.Listen.GRPC)
+	}
+	if c.Listen.Metrics != "" && (c.Listen.Metrics == c.Listen.GRPC || c.Listen.Metrics == c.Listen.HTTP) {
+		fail("listen.metrics", "must differ from listen.grpc and listen.http")
+	}
+
+	if c.Storage.Backend != BackendMemory {
+		fail("storage.backend", "unsupported backend %q (want %s)", c.Storage.Backend, BackendMemory)
+	}
+	if c.Storage.Memory.MaxMemoryBytes < 0 {
+		fail("storage.memory.max_memory_bytes", "must not be negative")
+	}
+	if _, err := storage.ParseEvictionPolicy(c.Storage.Memory.EvictionPolicy); err != nil {
+		fail("storage.memory.eviction_policy", "%v", err)
+	} else if c.Storage.Memory.EvictionPolicy != string(storage.NoEviction) && (c.Cluster.Enabled() || c.Replication.Role != "") {
+		// Evictions are local, so they would drop keys on one node only.
+		fail("storage.memory.eviction_policy", "must be %s with cluster or replication, where every node must keep the same keys", storage.NoEviction)
+	}
+
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		fail("tls", "cert_file and key_file must be set together")
+	}
+	if c.TLS.CertFile != "" {
+		if _, err := os.Stat(c.TLS.CertFile); err != nil {
+			fail("tls.cert_file", "%v", err)
+		}
+	}
+	if c.TLS.KeyFile != "" {
+		if _, err := os.Stat(c.TLS.KeyFile); err != nil {
+			fail("tls.key_file", "%v", err)
+		}
+	}
+	if c.TLS.ClientCAFile != "" {
+		if _, err := os.Stat(c.TLS.ClientCAFile); err != nil {
+			fail("tls.client_ca_file", "%v", err)
+		}
+	}
+	if err := tlsconfig.ValidClientAuth(c.TLS.ClientAuth); err != nil {
+		fail("tls.client_auth", "%v", err)
+	}
+	if c.TLS.ClientAuth != "" && c.TLS.ClientAuth != tlsconfig.ClientAuthNone {
+		if c.TLS.CertFile == "" {
+			fail("tls.client_auth", "requires cert_file and key_file")
+		}
+		if c.TLS.ClientCAFile == "" {
+			fail("tls.client_auth", "requires client_ca_file")
+		}
+	}
+
+	if c.Limits.MaxKeyBytes < 0 {
+		fail("limits.max_key_bytes", "must not be negative")
+	}
+	if c.Limits.MaxValueBytes < 0 {
+		fail("limits.max_value_bytes", "must not be negative")
+	}
+	if c.Limits.MaxListLimit < 0 {
+		fail("limits.max_list_limit", "must not be negative")
+	}
+
+	seenKeys := make(map[string]bool)
+	for i, key := range c.Auth.APIKeys {
+		field := fmt.Sprintf("auth.api_keys[%d]", i)
+		if key.Principal == "" {
+			fail(field, "principal must not be empty")
+		}
+		if len(key.Key) < minSecretBytes {
+			fail(field, "key must be at least %d bytes", minSecretBytes)
+		}
+		if seenKeys[key.Key] {
+			fail(field, "key is already assigned to another principal")
+		}
+		seenKeys[key.Key] = true
+	}
+	if c.Auth.JWT.Secret != "" && len(c.Auth.JWT.Secret) < minSecretBytes {
+		fail("auth.jwt.secret", "must be at least %d bytes", minSecretBytes)
+	}
+
+	if len(c.Auth.Roles) > 0 && len(c.Auth.APIKeys) == 0 && c.Auth.JWT.Secret == "" {
+		fail("auth.roles", "require api_keys or a jwt secret to identify principals")
+	}
+	roles := make(map[string]bool)
+	for i, role := range c.Auth.Roles {
+		field := fmt.Sprintf("auth.roles[%d]", i)
+		if role.Name == "" {
+			fail(field, "name must not be empty")
+		}
+		if roles[role.Name] {
+			fail(field, "role %q is defined twice", role.Name)
+		}
+		roles[role.Name] = true
+
+		for j, grant := range role.Grants {
+			field := fmt.Sprintf("%s.grants[%d]", field, j)
+			if len(grant.Permissions) == 0 {
+				fail(field, "permissions must not be empty")
+			}
+			for _, p := range grant.Permissions {
+				if _, err := auth.ParsePermission(p); err != nil {
+					fail(field, "%v", err)
+				}
+			}
+			for _, pattern := range grant.Keys {
+				if pattern == "" {
+					fail(field, "key pattern must not be empty, use \"*\" to match every key")
+				} else if err := auth.ValidPattern(pattern); err != nil {
+					fail(field, "%v", err)
+				}
+			}
+			for _, ns := range grant.Namespaces {
+				if err := storage.ValidNamespace(ns); err != nil {
+					fail(field, "%v", err)
+				}
+			}
+		}
+	}
+	if len(c.Auth.Roles) > 0 {
+		for i, key := range c.Auth.APIKeys {
+			for _, role := range key.Roles {
+				if !roles[role] {
+					fail(fmt.Sprintf("auth.api_keys[%d]", i), "unknown role %q", role)
+				}
+			}
+		}
+	}
+
+	for principal, quota := range c.Quotas.Principals {
+		if quota.MaxKeys < 0 || quota.MaxBytes < 0 || quota.MaxValueBytes < 0 {
+			fail("quotas.principals."+principal, "limits must not be negative")
+		}
+	}
+
+	checkRate := func(field string, r RateConfig) {
+		if r.Rate < 0 || r.Burst < 0 {
+			fail(field, "rate and burst must not be negative")
+		}
+	}
+	checkRate("rate_limits.per_client", c.RateLimits.PerClient)
+	for method, r := range c.RateLimits.Methods {
+		checkRate("rate_limits.methods."+method, r)
+	}
+	if c.RateLimits.MaxInFlight < 0 {
+		fail("rate_limits.max_in_flight", "must not be negative")
+	}
+
+	if err := tracing.ValidExporter(c.Tracing.Exporter); err != nil {
+		fail("tracing.exporter", "%v", err)
+	}
+	if c.Tracing.Exporter == tracing.ExporterFile && c.Tracing.File == "" {
+		fail("tracing.file", "must be set for the %s exporter", tracing.ExporterFile)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		fail("tracing.sample_ratio", "must be between 0 and 1")
+	}
+
+	if _, err := ParseLogLevel(c.AccessLog.Level); err != nil {
+		fail("access_log.level", "%v", err)
+	}
+	if c.AccessLog.SlowThreshold < 0 {
+		fail("access_log.slow_threshold", "must not be negative")
+	}
+	if c.AccessLog.SlowLogSize < 0 {
+		fail("access_log.slow_log_size", "must not be negative")
+	}
+
+	if c.Cluster.Enabled() {
+		if c.Cluster.RaftAddr == "" {
+			fail("cluster.raft_addr", "must not be empty")
+		}
+
+		self := false
+		peers := make(map[string]bool)
+		for i, peer := range c.Cluster.Peers {
+			field := fmt.Sprintf("cluster.peers[%d]", i)
+			if peer.ID == "" || peer.RaftAddr == "" || peer.GRPCAddr == "" {
+				fail(field, "id, raft_addr and grpc_addr must be set")
+			}
+			if peers[peer.ID] {
+				fail(field, "peer %q is listed twice", peer.ID)
+			}
+			peers[peer.ID] = true
+			self = self || peer.ID == c.Cluster.NodeID
+		}
+		if !self {
+			fail("cluster.peers", "must include this node (%s)", c.Cluster.NodeID)
+		}
+
+		if c.Cluster.CertFile == "" && c.Cluster.KeyFile == "" && c.Cluster.CAFile == "" {
+			if c.Auth.Enabled() {
+				fail("cluster.cert_file", "cert_file, key_file and ca_file must be set with auth, or the Raft log travels unauthenticated")
+			}
+		} else if c.Cluster.CertFile == "" || c.Cluster.KeyFile == "" || c.Cluster.CAFile == "" {
+			fail("cluster", "cert_file, key_file and ca_file must be set together")
+		}
+		if c.Cluster.CertFile != "" {
+			if _, err := os.Stat(c.Cluster.CertFile); err != nil {
+				fail("cluster.cert_file", "%v", err)
+			}
+		}
+		if c.Cluster.KeyFile != "" {
+			if _, err := os.Stat(c.Cluster.KeyFile); err != nil {
+				fail("cluster.key_file", "%v", err)
+			}
+		}
+		if c.Cluster.CAFile != "" {
+			if _, err := os.Stat(c.Cluster.CAFile); err != nil {
+				fail("cluster.ca_file", "%v", err)
+			}
+		}
+	} else if len(c.Cluster.Peers) > 0 || c.Cluster.Bootstrap {
+		fail("cluster.node_id", "must be set to join a cluster")
+	}
+
+	if c.Replication.Role != "" {
+		role, err := replication.ParseRole(c.Replication.Role)
+		if err != nil {
+			fail("replication.role", "%v", err)
+		}
+		if c.Cluster.Enabled() {
+			fail("replication.role", "cannot be combined with cluster")
+		}
+		if role == replication.RoleReplica && c.Replication.PrimaryAddr == "" {
+			fail("replication.primary_addr", "must be set on a replica")
+		}
+		if role == replication.RoleActive {
+			if c.Replication.SiteID == "" {
+				fail("replication.site_id", "must be set on an active site")
+			}
+			if len(c.Replication.Peers) == 0 {
+				fail("replication.peers", "must list the other active sites")
+			}
+			seen := make(map[string]bool)
+			for i, addr := range c.Replication.Peers {
+				if seen[addr] {
+					fail(fmt.Sprintf("replication.peers[%d]", i), "%s is listed twice", addr)
+				}
+				seen[addr] = true
+			}
+		}
+	}
+	if c.Replication.LogSize <= 0 {
+		fail("replication.log_size", "must be positive")
+	}
+	if c.Replication.AntiEntropyInterval < 0 {
+		fail("replication.anti_entropy_interval", "must not be negative")
+	}
+	if c.Replication.MaxClockOffset <= 0 {
+		fail("replication.max_clock_offset", "must be positive")
+	}
+
+	if c.Sharding.Enabled() {
+		if c.Cluster.Enabled() || c.Replication.Role != "" {
+			fail("sharding.node_id", "cannot be combined with cluster or replication")
+		}
+
+		if len(c.Sharding.Nodes) == 0 {
+			fail("sharding.nodes", "must not be empty")
+		}
+		nodes := make(map[string]bool)
+		for i, node := range c.Sharding.Nodes {
+			field := fmt.Sprintf("sharding.nodes[%d]", i)
+			if node.ID == "" || node.GRPCAddr == "" {
+				fail(field, "id and grpc_addr must be set")
+			}
+			if nodes[node.ID] {
+				fail(field, "node %q is listed twice", node.ID)
+			}
+			nodes[node.ID] = true
+		}
+	} else if len(c.Sharding.Nodes) > 0 {
+		fail("sharding.node_id", "must be set to serve a shard")
+	}
+	if c.Sharding.VirtualNodes <= 0 {
+		fail("sharding.virtual_nodes", "must be positive")
+	}
+
+	if c.Gossip.Enabled() {
+		if c.Gossip.AdvertiseAddr == "" {
+			host, _, err := net.SplitHostPort(c.Listen.GRPC)
+			if ip := net.ParseIP(host); err == nil && (host == "" || ip != nil && ip.IsUnspecified()) {
+				fail("gossip.advertise_addr", "must be set when listen.grpc (%s) names no host", c.Listen.GRPC)
+			}
+		}
+	} else if len(c.Gossip.Seeds) > 0 {
+		fail("gossip.node_id", "must be set to gossip")
+	}
+	if c.Gossip.Interval <= 0 {
+		fail("gossip.interval", "must be positive")
+	}
+	if c.Gossip.ProbeTimeout <= 0 || c.Gossip.ProbeTimeout >= c.Gossip.Interval {
+		fail("gossip.probe_timeout", "must be positive and shorter than gossip.interval")
+	}
+	if c.Gossip.SuspicionTimeout <= 0 {
+		fail("gossip.suspicion_timeout", "must be positive")
+	}
+	if c.Gossip.IndirectChecks <= 0 {
+		fail("gossip.indirect_checks", "must be positive")
+	}
+	if c.Gossip.SyncInterval <= 0 {
+		fail("gossip.sync_interval", "must be positive")
+	}
+
+	// An API key sent to other servers in plaintext could be read off the
+	// network and replayed.
+	for _, peer := range []struct{ section, apiKey, caFile string }{
+		{"replication", c.Replication.APIKey, c.Replication.CAFile},
+		{"sharding", c.Sharding.APIKey, c.Sharding.CAFile},
+		{"gossip", c.Gossip.APIKey, c.Gossip.CAFile},
+	} {
+		if peer.apiKey != "" && peer.caFile == "" {
+			fail(peer.section+".ca_file", "must be set with api_key, which would otherwise travel in plaintext")
+		}
+	}
+
+	if _, err := ParseLogLevel(c.LogLevel); err != nil {
+		fail("log_level", "%v", err)
+	}
+	if c.LogFormat != LogFormatText && c.LogFormat != LogFormatJSON {
+		fail("log_format", "unknown format %q (want %s or %s)", c.LogFormat, LogFormatText, LogFormatJSON)
+	}
+
+	if c.ShutdownTimeout <= 0 {
+		fail("shutdown_timeout", "must be positive")
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// Redacted returns a copy of c with secrets masked, suitable for printing.
+func (c *Config) Redacted() *Config {
+	out := *c
+
+	out.Auth.APIKeys = make([]APIKeyConfig, len(c.Auth.APIKeys))
+	for i, key := range c.Auth.APIKeys {
+		key.Key = redacted
+		out.Auth.APIKeys[i] = key
+	}
+	if out.Auth.JWT.Secret != "" {
+		out.Auth.JWT.Secret = redacted
+	}
+	if out.Replication.APIKey != "" {
+		out.Replication.APIKey = redacted
+	}
+	if out.Sharding.APIKey != "" {
+		out.Sharding.APIKey = redacted
+	}
+	if out.Gossip.APIKey != "" {
+		out.Gossip.APIKey = redacted
+	}
+
+	return &out
+}
+
+// Write prints the configuration as YAML.
+func (c *Config) Write(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
+
+func ParseLogLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q (want debug, info, warn or error)", s)
+	}
+
+	return level, nil
+}
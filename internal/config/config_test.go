@@ -0,0 +1,373 @@
+package config
+
+import (
+	"io"
+	"kvstore/internal/storage"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+// Test defaults are valid on their own
+func TestLoad_Defaults(t *testing.T) {
+	cfg, opts, err := Load(nil, env(nil), io.Discard)
+	require.NoError(t, err)
+
+	assert.Equal(t, Default(), cfg)
+	assert.Empty(t, opts.ConfigFile)
+	assert.False(t, opts.PrintConfig)
+}
+
+// Test file < env < flags precedence
+func TestLoad_Precedence(t *testing.T) {
+	path := writeFile(t, "kvstore.yaml", `
+listen:
+  grpc: ":7000"
+  http: ":7001"
+log_level: warn
+limits:
+  max_value_bytes: 100
+`)
+
+	cfg, _, err := Load(
+		[]string{"-config", path, "-log-level", "debug"},
+		env(map[string]string{
+			"KVSTORE_HTTP_ADDR": ":7002",
+			"KVSTORE_LOG_LEVEL": "error",
+		}),
+		io.Discard,
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, ":7000", cfg.Listen.GRPC, "file overrides default")
+	assert.Equal(t, ":7002", cfg.Listen.HTTP, "env overrides file")
+	assert.Equal(t, "debug", cfg.LogLevel, "flag overrides env")
+	assert.Equal(t, 100, cfg.Limits.MaxValueBytes)
+	assert.Equal(t, Default().Limits.MaxKeyBytes, cfg.Limits.MaxKeyBytes, "unset fields keep defaults")
+}
+
+// Test JSON files are accepted and the path can come from the environment
+func TestLoad_JSONFromEnv(t *testing.T) {
+	path := writeFile(t, "kvstore.json", `{"storage": {"memory": {"max_memory_bytes": 4096, "eviction_policy": "allkeys-lru"}}}`)
+
+	cfg, opts, err := Load(nil, env(map[string]string{"KVSTORE_CONFIG": path}), io.Discard)
+	require.NoError(t, err)
+
+	assert.Equal(t, path, opts.ConfigFile)
+	assert.Equal(t, int64(4096), cfg.Storage.Memory.MaxMemoryBytes)
+	assert.Equal(t, "allkeys-lru", cfg.Storage.Memory.EvictionPolicy)
+}
+
+// Test boolean flags work with and without a value
+func TestLoad_BoolFlag(t *testing.T) {
+	cfg, _, err := Load([]string{"-reflection"}, env(nil), io.Discard)
+	require.NoError(t, err)
+	assert.True(t, cfg.Reflection)
+
+	cfg, _, err = Load([]string{"-reflection=false"}, env(map[string]string{"KVSTORE_REFLECTION": "true"}), io.Discard)
+	require.NoError(t, err)
+	assert.False(t, cfg.Reflection)
+}
+
+func TestLoad_UnknownField(t *testing.T) {
+	path := writeFile(t, "kvstore.yaml", "listen:\n  grcp: \":7000\"\n")
+
+	_, _, err := Load([]string{"-config", path}, env(nil), io.Discard)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "grcp")
+}
+
+func TestLoad_InvalidNumber(t *testing.T) {
+	_, _, err := Load(nil, env(map[string]string{"KVSTORE_MAX_KEY_BYTES": "lots"}), io.Discard)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "KVSTORE_MAX_KEY_BYTES")
+}
+
+// Test validation reports every problem at once
+func TestValidate(t *testing.T) {
+	cfg := Default()
+	cfg.Listen.GRPC = ""
+	cfg.Listen.Metrics = cfg.Listen.HTTP
+	cfg.Storage.Backend = "etcd"
+	cfg.Storage.Memory.EvictionPolicy = "lru"
+	cfg.TLS.CertFile = "server.crt"
+	cfg.TLS.ClientAuth = "require"
+	cfg.Limits.MaxListLimit = -1
+	cfg.Auth.APIKeys = []APIKeyConfig{{Principal: "ci", Key: "short", Roles: []string{"ops"}}}
+	cfg.Auth.Roles = []RoleConfig{
+		{Name: "team-a", Grants: []GrantConfig{{Permissions: []string{"read", "own"}, Keys: []string{""}}}},
+		{Name: "team-a"},
+	}
+	cfg.Quotas.Principals = map[string]QuotaConfig{"ci": {MaxBytes: -1}}
+	cfg.RateLimits.Methods = map[string]RateConfig{"Set": {Rate: -1}}
+	cfg.Tracing.Exporter = "jaeger"
+	cfg.Tracing.SampleRatio = 2
+	cfg.AccessLog.Level = "chatty"
+	cfg.AccessLog.SlowLogSize = -1
+	cfg.LogLevel = "loud"
+	cfg.LogFormat = "xml"
+
+	err := cfg.Validate()
+	require.Error(t, err)
+
+	for _, field := range []string{
+		"listen.grpc",
+		"listen.metrics",
+		"storage.backend",
+		"storage.memory.eviction_policy",
+		"tls: cert_file and key_file must be set together",
+		"tls.cert_file",
+		"tls.client_auth: requires client_ca_file",
+		"limits.max_list_limit",
+		"auth.api_keys[0]: key must be at least",
+		`auth.api_keys[0]: unknown role "ops"`,
+		`auth.roles[0].grants[0]: unknown permission "own"`,
+		"auth.roles[0].grants[0]: key pattern must not be empty",
+		`auth.roles[1]: role "team-a" is defined twice`,
+		"quotas.principals.ci",
+		"rate_limits.methods.Set",
+		"tracing.exporter",
+		"tracing.sample_ratio",
+		"access_log.level",
+		"access_log.slow_log_size",
+		"log_level",
+		"log_format",
+	} {
+		assert.Contains(t, err.Error(), field)
+	}
+}
+
+// Test cluster peers parse from a flag and the cluster is validated
+func TestLoad_Cluster(t *testing.T) {
+	cfg, _, err := Load([]string{
+		"-cluster-node-id", "n1",
+		"-cluster-raft-addr", ":7000",
+		"-cluster-bootstrap",
+		"-cluster-peers", "n1=10.0.0.1:7000/10.0.0.1:9090, n2=10.0.0.2:7000/10.0.0.2:9090",
+	}, env(nil), io.Discard)
+	require.NoError(t, err)
+	assert.True(t, cfg.Cluster.Enabled())
+	assert.True(t, cfg.Cluster.Bootstrap)
+	assert.Equal(t, []PeerConfig{
+		{ID: "n1", RaftAddr: "10.0.0.1:7000", GRPCAddr: "10.0.0.1:9090"},
+		{ID: "n2", RaftAddr: "10.0.0.2:7000", GRPCAddr: "10.0.0.2:9090"},
+	}, cfg.Cluster.Peers)
+
+	_, _, err = Load([]string{"-cluster-peers", "n1=10.0.0.1:7000"}, env(nil), io.Discard)
+	assert.ErrorContains(t, err, "id=raft_addr/grpc_addr")
+
+	cfg.Cluster.Peers = append(cfg.Cluster.Peers[1:], PeerConfig{ID: "n2"})
+	cfg.Storage.Memory.EvictionPolicy = string(storage.AllKeysLRU)
+	err = cfg.Validate()
+	require.Error(t, err)
+	for _, field := range []string{
+		"storage.memory.eviction_policy: must be noeviction with cluster or replication",
+		"cluster.peers[1]: id, raft_addr and grpc_addr must be set",
+		`cluster.peers[1]: peer "n2" is listed twice`,
+		"cluster.peers: must include this node (n1)",
+	} {
+		assert.Contains(t, err.Error(), field)
+	}
+
+	// The Raft log must not travel unauthenticated once clients have to
+	// authenticate
+	cfg.Storage.Memory.EvictionPolicy = string(storage.NoEviction)
+	cfg.Cluster.Peers = []PeerConfig{{ID: "n1", RaftAddr: "10.0.0.1:7000", GRPCAddr: "10.0.0.1:9090"}}
+	cfg.Auth.JWT.Secret = strings.Repeat("s", 32)
+	assert.ErrorContains(t, cfg.Validate(), "cluster.cert_file: cert_file, key_file and ca_file must be set with auth")
+	cfg.Cluster.CAFile = filepath.Join(t.TempDir(), "ca.pem")
+	err = cfg.Validate()
+	assert.ErrorContains(t, err, "cluster: cert_file, key_file and ca_file must be set together")
+	assert.ErrorContains(t, err, "cluster.ca_file:")
+}
+
+// Test replication roles are validated
+func TestLoad_Replication(t *testing.T) {
+	cfg, _, err := Load([]string{
+		"-replication-role", "replica",
+		"-replication-primary-addr", "10.0.0.1:9090",
+		"-replication-anti-entropy-interval", "1h",
+	}, env(map[string]string{"KVSTORE_REPLICATION_LOG_SIZE": "10"}), io.Discard)
+	require.NoError(t, err)
+	assert.Equal(t, "replica", cfg.Replication.Role)
+	assert.Equal(t, "10.0.0.1:9090", cfg.Replication.PrimaryAddr)
+	assert.Equal(t, 10, cfg.Replication.LogSize)
+	assert.Equal(t, time.Hour, cfg.Replication.AntiEntropyInterval)
+
+	cfg.Replication.Role = "leader"
+	cfg.Replication.LogSize = 0
+	cfg.Replication.AntiEntropyInterval = -time.Second
+	err = cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `replication.role: unknown replication role "leader"`)
+	assert.Contains(t, err.Error(), "replication.log_size: must be positive")
+	assert.Contains(t, err.Error(), "replication.anti_entropy_interval: must not be negative")
+
+	cfg.Replication = ReplicationConfig{Role: "replica", LogSize: 1}
+	cfg.Cluster.NodeID = "n1"
+	cfg.Storage.Memory.EvictionPolicy = string(storage.AllKeysLRU)
+	err = cfg.Validate()
+	require.Error(t, err)
+	for _, field := range []string{
+		"replication.role: cannot be combined with cluster",
+		"replication.primary_addr: must be set on a replica",
+		"storage.memory.eviction_policy: must be noeviction with cluster or replication",
+	} {
+		assert.Contains(t, err.Error(), field)
+	}
+}
+
+// Test active sites need an ID and their peers
+func TestLoad_ActiveReplication(t *testing.T) {
+	cfg, _, err := Load([]string{
+		"-replication-role", "active",
+		"-replication-site-id", "eu",
+		"-replication-peers", "10.0.1.1:9090, 10.0.2.1:9090",
+		"-replication-max-clock-offset", "2s",
+	}, env(nil), io.Discard)
+	require.NoError(t, err)
+	assert.Equal(t, "eu", cfg.Replication.SiteID)
+	assert.Equal(t, []string{"10.0.1.1:9090", "10.0.2.1:9090"}, cfg.Replication.Peers)
+	assert.Equal(t, 2*time.Second, cfg.Replication.MaxClockOffset)
+
+	cfg.Replication.SiteID = ""
+	cfg.Replication.Peers = []string{"10.0.1.1:9090", "10.0.1.1:9090"}
+	cfg.Replication.MaxClockOffset = 0
+	err = cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "replication.site_id: must be set on an active site")
+	assert.Contains(t, err.Error(), "replication.peers[1]: 10.0.1.1:9090 is listed twice")
+	assert.Contains(t, err.Error(), "replication.max_clock_offset: must be positive")
+
+	cfg.Replication.Peers = nil
+	assert.ErrorContains(t, cfg.Validate(), "replication.peers: must list the other active sites")
+}
+
+// Test API keys for other servers are only accepted along with TLS
+func TestValidate_PeerAPIKeys(t *testing.T) {
+	cfg := Default()
+	cfg.Replication.APIKey = "replica-key-0123456789"
+	cfg.Gossip.APIKey = "gossip-key-0123456789"
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "replication.ca_file: must be set with api_key")
+	assert.Contains(t, err.Error(), "gossip.ca_file: must be set with api_key")
+	assert.NotContains(t, err.Error(), "sharding.ca_file")
+}
+
+// Test gossip settings parse from flags and a server must be reachable at
+// the address it gossips
+func TestLoad_Gossip(t *testing.T) {
+	cfg, _, err := Load([]string{
+		"-gossip-node-id", "n1",
+		"-gossip-seeds", "10.0.0.1:9090,10.0.0.2:9090",
+		"-gossip-interval", "500ms",
+		"-grpc-addr", "10.0.0.3:9090",
+	}, env(map[string]string{"KVSTORE_GOSSIP_SUSPICION_TIMEOUT": "3s"}), io.Discard)
+	require.NoError(t, err)
+	assert.True(t, cfg.Gossip.Enabled())
+	assert.Equal(t, []string{"10.0.0.1:9090", "10.0.0.2:9090"}, cfg.Gossip.Seeds)
+	assert.Equal(t, 500*time.Millisecond, cfg.Gossip.Interval)
+	assert.Equal(t, 3*time.Second, cfg.Gossip.SuspicionTimeout)
+
+	cfg.Listen.GRPC = "0.0.0.0:9090"
+	cfg.Gossip.ProbeTimeout = time.Second
+	err = cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "gossip.advertise_addr: must be set when listen.grpc (0.0.0.0:9090) names no host")
+	assert.Contains(t, err.Error(), "gossip.probe_timeout: must be positive and shorter than gossip.interval")
+
+	cfg.Gossip.AdvertiseAddr = "10.0.0.3:9090"
+	cfg.Gossip.ProbeTimeout = 100 * time.Millisecond
+	require.NoError(t, cfg.Validate())
+
+	cfg.Gossip.NodeID = ""
+	assert.ErrorContains(t, cfg.Validate(), "gossip.node_id: must be set to gossip")
+}
+
+// Test shard nodes parse from a flag and the shard map is validated
+func TestLoad_Sharding(t *testing.T) {
+	cfg, _, err := Load([]string{
+		"-shard-node-id", "s1",
+		"-shard-nodes", "s1=10.0.0.1:9090, s2=10.0.0.2:9090",
+		"-shard-map-file", "/var/lib/kvstore/shard-map.json",
+	}, env(map[string]string{"KVSTORE_SHARD_VIRTUAL_NODES": "64"}), io.Discard)
+	require.NoError(t, err)
+	assert.True(t, cfg.Sharding.Enabled())
+	assert.Equal(t, 64, cfg.Sharding.VirtualNodes)
+	assert.Equal(t, "/var/lib/kvstore/shard-map.json", cfg.Sharding.MapFile)
+	assert.Equal(t, []ShardNodeConfig{
+		{ID: "s1", GRPCAddr: "10.0.0.1:9090"},
+		{ID: "s2", GRPCAddr: "10.0.0.2:9090"},
+	}, cfg.Sharding.Nodes)
+
+	_, _, err = Load([]string{"-shard-nodes", "s1"}, env(nil), io.Discard)
+	assert.ErrorContains(t, err, "id=grpc_addr")
+
+	cfg.Sharding.NodeID = "s3"
+	require.NoError(t, cfg.Validate(), "a joining node is not in the map yet")
+
+	cfg.Sharding.Nodes = append(cfg.Sharding.Nodes[1:], ShardNodeConfig{ID: "s2"})
+	cfg.Sharding.VirtualNodes = 0
+	cfg.Replication.Role = "primary"
+	err = cfg.Validate()
+	require.Error(t, err)
+	for _, field := range []string{
+		"sharding.node_id: cannot be combined with cluster or replication",
+		"sharding.nodes[1]: id and grpc_addr must be set",
+		`sharding.nodes[1]: node "s2" is listed twice`,
+		"sharding.virtual_nodes: must be positive",
+	} {
+		assert.Contains(t, err.Error(), field)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	var sb strings.Builder
+	require.NoError(t, Default().Write(&sb))
+
+	path := writeFile(t, "roundtrip.yaml", sb.String())
+
+	cfg := &Config{}
+	require.NoError(t, cfg.LoadFile(path))
+	assert.Equal(t, Default(), cfg)
+}
+
+// Test secrets never reach printed output
+func TestRedacted(t *testing.T) {
+	cfg := Default()
+	cfg.Auth.APIKeys = []APIKeyConfig{{Principal: "ci", Key: "ci-key-0123456789"}}
+	cfg.Auth.JWT.Secret = "jwt-secret-0123456789"
+	cfg.Replication.APIKey = "replica-key-0123456789"
+	cfg.Sharding.APIKey = "shard-key-0123456789"
+
+	var sb strings.Builder
+	require.NoError(t, cfg.Redacted().Write(&sb))
+
+	assert.NotContains(t, sb.String(), "ci-key-0123456789")
+	assert.NotContains(t, sb.String(), "jwt-secret-0123456789")
+	assert.NotContains(t, sb.String(), "replica-key-0123456789")
+	assert.NotContains(t, sb.String(), "shard-key-0123456789")
+	assert.Contains(t, sb.String(), "principal: ci")
+	assert.Equal(t, "ci-key-0123456789", cfg.Auth.APIKeys[0].Key, "original is untouched")
+}
@@ -0,0 +1,423 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvPrefix is prepended to a setting's flag name, upper-cased with dashes
+// replaced by underscores, to form its environment variable.
+const EnvPrefix = "KVSTORE_"
+
+// Options are command-line switches that control loading rather than being
+// part of the configuration.
+type Options struct {
+	ConfigFile  string
+	PrintConfig bool
+}
+
+type setting struct {
+	flag  string
+	usage string
+	set   func(c *Config, v string) error
+}
+
+// boolSettings may be given as flags without a value.
+var boolSettings = map[string]bool{
+	"reflection":        true,
+	"access-log":        true,
+	"cluster-bootstrap": true,
+}
+
+var settings = []setting{
+	{"grpc-addr", "gRPC listen address", func(c *Config, v string) error {
+		c.Listen.GRPC = v
+		return nil
+	}},
+	{"http-addr", "REST gateway listen address, empty to disable", func(c *Config, v string) error {
+		c.Listen.HTTP = v
+		return nil
+	}},
+	{"metrics-addr", "Prometheus metrics listen address, empty to disable", func(c *Config, v string) error {
+		c.Listen.Metrics = v
+		return nil
+	}},
+	{"storage-backend", "storage backend", func(c *Config, v string) error {
+		c.Storage.Backend = v
+		return nil
+	}},
+	{"max-memory", "memory limit for keys and values in bytes, 0 for unlimited", func(c *Config, v string) error {
+		return parseInt64(v, &c.Storage.Memory.MaxMemoryBytes)
+	}},
+	{"eviction-policy", "eviction policy when max-memory is reached", func(c *Config, v string) error {
+		c.Storage.Memory.EvictionPolicy = v
+		return nil
+	}},
+	{"tls-cert", "TLS certificate file", func(c *Config, v string) error {
+		c.TLS.CertFile = v
+		return nil
+	}},
+	{"tls-key", "TLS private key file", func(c *Config, v string) error {
+		c.TLS.KeyFile = v
+		return nil
+	}},
+	{"tls-client-ca", "CA bundle used to verify client certificates", func(c *Config, v string) error {
+		c.TLS.ClientCAFile = v
+		return nil
+	}},
+	{"tls-client-auth", "client certificate policy (none, request, require)", func(c *Config, v string) error {
+		c.TLS.ClientAuth = v
+		return nil
+	}},
+	{"max-key-bytes", "maximum key size in bytes", func(c *Config, v string) error {
+		return parseInt(v, &c.Limits.MaxKeyBytes)
+	}},
+	{"max-value-bytes", "maximum value size in bytes", func(c *Config, v string) error {
+		return parseInt(v, &c.Limits.MaxValueBytes)
+	}},
+	{"max-list-limit", "maximum number of pairs returned by List", func(c *Config, v string) error {
+		return parseInt(v, &c.Limits.MaxListLimit)
+	}},
+	{"rate-limit", "requests per second allowed per client, 0 for unlimited", func(c *Config, v string) error {
+		return parseFloat(v, &c.RateLimits.PerClient.Rate)
+	}},
+	{"max-in-flight", "maximum concurrent requests, 0 for unlimited", func(c *Config, v string) error {
+		return parseInt(v, &c.RateLimits.MaxInFlight)
+	}},
+	{"trace-exporter", "trace exporter: none, otlp, stdout or file", func(c *Config, v string) error {
+		c.Tracing.Exporter = v
+		return nil
+	}},
+	{"trace-endpoint", "OTLP/gRPC collector address for the otlp trace exporter", func(c *Config, v string) error {
+		c.Tracing.Endpoint = v
+		return nil
+	}},
+	{"trace-file", "file the file trace exporter appends to", func(c *Config, v string) error {
+		c.Tracing.File = v
+		return nil
+	}},
+	{"trace-sample-ratio", "fraction of new traces recorded, from 0 to 1", func(c *Config, v string) error {
+		return parseFloat(v, &c.Tracing.SampleRatio)
+	}},
+	{"jwt-secret", "HMAC secret used to verify JWT bearer tokens", func(c *Config, v string) error {
+		c.Auth.JWT.Secret = v
+		return nil
+	}},
+	{"jwt-issuer", "required JWT issuer", func(c *Config, v string) error {
+		c.Auth.JWT.Issuer = v
+		return nil
+	}},
+	{"jwt-audience", "required JWT audience", func(c *Config, v string) error {
+		c.Auth.JWT.Audience = v
+		return nil
+	}},
+	{"log-level", "log level (debug, info, warn, error)", func(c *Config, v string) error {
+		c.LogLevel = v
+		return nil
+	}},
+	{"log-format", "log format (text, json)", func(c *Config, v string) error {
+		c.LogFormat = v
+		return nil
+	}},
+	{"access-log", "log every request", func(c *Config, v string) error {
+		return parseBool(v, &c.AccessLog.Enabled)
+	}},
+	{"slow-threshold", "latency from which requests are recorded in the slow log, 0 to disable", func(c *Config, v string) error {
+		return parseDuration(v, &c.AccessLog.SlowThreshold)
+	}},
+	{"audit-file", "append-only audit log of mutating operations, empty to disable", func(c *Config, v string) error {
+		c.Audit.File = v
+		return nil
+	}},
+	{"cluster-node-id", "Raft node ID, empty to run standalone", func(c *Config, v string) error {
+		c.Cluster.NodeID = v
+		return nil
+	}},
+	{"cluster-raft-addr", "Raft listen address", func(c *Config, v string) error {
+		c.Cluster.RaftAddr = v
+		return nil
+	}},
+	{"cluster-data-dir", "directory for the Raft log and snapshots, empty to keep them in memory", func(c *Config, v string) error {
+		c.Cluster.DataDir = v
+		return nil
+	}},
+	{"cluster-bootstrap", "form a new cluster from the peers on first start", func(c *Config, v string) error {
+		return parseBool(v, &c.Cluster.Bootstrap)
+	}},
+	{"cluster-peers", "comma-separated cluster members as id=raft_addr/grpc_addr", func(c *Config, v string) error {
+		return parsePeers(v, &c.Cluster.Peers)
+	}},
+	{"cluster-cert-file", "certificate for mutual TLS between cluster members", func(c *Config, v string) error {
+		c.Cluster.CertFile = v
+		return nil
+	}},
+	{"cluster-key-file", "private key of the cluster certificate", func(c *Config, v string) error {
+		c.Cluster.KeyFile = v
+		return nil
+	}},
+	{"cluster-ca-file", "CA bundle to verify other cluster members with", func(c *Config, v string) error {
+		c.Cluster.CAFile = v
+		return nil
+	}},
+	{"replication-role", "primary, replica or active, empty to disable replication", func(c *Config, v string) error {
+		c.Replication.Role = v
+		return nil
+	}},
+	{"replication-primary-addr", "gRPC address of the primary a replica streams from", func(c *Config, v string) error {
+		c.Replication.PrimaryAddr = v
+		return nil
+	}},
+	{"replication-log-size", "writes a primary retains for replicas to catch up from", func(c *Config, v string) error {
+		return parseInt(v, &c.Replication.LogSize)
+	}},
+	{"replication-api-key", "API key a replica authenticates to the primary with", func(c *Config, v string) error {
+		c.Replication.APIKey = v
+		return nil
+	}},
+	{"replication-ca-file", "CA bundle to verify the primary with, enabling TLS to it", func(c *Config, v string) error {
+		c.Replication.CAFile = v
+		return nil
+	}},
+	{"replication-site-id", "ID of this active site, unique among the sites", func(c *Config, v string) error {
+		c.Replication.SiteID = v
+		return nil
+	}},
+	{"replication-peers", "comma-separated gRPC addresses of the other active sites", func(c *Config, v string) error {
+		c.Replication.Peers = parseList(v)
+		return nil
+	}},
+	{"replication-anti-entropy-interval", "how often a replica repairs what diverged from the primary, 0 to disable", func(c *Config, v string) error {
+		return parseDuration(v, &c.Replication.AntiEntropyInterval)
+	}},
+	{"replication-max-clock-offset", "how far ahead of an active site's clock other sites' writes may be stamped", func(c *Config, v string) error {
+		return parseDuration(v, &c.Replication.MaxClockOffset)
+	}},
+	{"shard-node-id", "ID of this node in the shard map, empty to disable sharding", func(c *Config, v string) error {
+		c.Sharding.NodeID = v
+		return nil
+	}},
+	{"shard-virtual-nodes", "points each node has on the consistent hash ring", func(c *Config, v string) error {
+		return parseInt(v, &c.Sharding.VirtualNodes)
+	}},
+	{"shard-nodes", "comma-separated shard nodes as id=grpc_addr", func(c *Config, v string) error {
+		return parseShardNodes(v, &c.Sharding.Nodes)
+	}},
+	{"shard-map-file", "file keeping the shard map committed by the last rebalance", func(c *Config, v string) error {
+		c.Sharding.MapFile = v
+		return nil
+	}},
+	{"shard-api-key", "API key this node hands keys to other shards with", func(c *Config, v string) error {
+		c.Sharding.APIKey = v
+		return nil
+	}},
+	{"shard-ca-file", "CA bundle to verify other shards with, enabling TLS to them", func(c *Config, v string) error {
+		c.Sharding.CAFile = v
+		return nil
+	}},
+	{"gossip-node-id", "ID of this server in the gossip group, empty to disable gossip", func(c *Config, v string) error {
+		c.Gossip.NodeID = v
+		return nil
+	}},
+	{"gossip-advertise-addr", "gRPC address other servers reach this one at, empty for the gRPC listen address", func(c *Config, v string) error {
+		c.Gossip.AdvertiseAddr = v
+		return nil
+	}},
+	{"gossip-seeds", "comma-separated gRPC addresses of servers to join the gossip group through", func(c *Config, v string) error {
+		c.Gossip.Seeds = parseList(v)
+		return nil
+	}},
+	{"gossip-interval", "how often to probe a member", func(c *Config, v string) error {
+		return parseDuration(v, &c.Gossip.Interval)
+	}},
+	{"gossip-probe-timeout", "how long a member has to answer a probe before others probe it", func(c *Config, v string) error {
+		return parseDuration(v, &c.Gossip.ProbeTimeout)
+	}},
+	{"gossip-suspicion-timeout", "how long a suspected member has to refute before it is declared dead", func(c *Config, v string) error {
+		return parseDuration(v, &c.Gossip.SuspicionTimeout)
+	}},
+	{"gossip-indirect-checks", "members asked to probe a member that did not answer", func(c *Config, v string) error {
+		return parseInt(v, &c.Gossip.IndirectChecks)
+	}},
+	{"gossip-sync-interval", "how often to exchange the whole membership with a random member", func(c *Config, v string) error {
+		return parseDuration(v, &c.Gossip.SyncInterval)
+	}},
+	{"gossip-api-key", "API key this server authenticates to the others with when gossiping", func(c *Config, v string) error {
+		c.Gossip.APIKey = v
+		return nil
+	}},
+	{"gossip-ca-file", "CA bundle to verify other servers with, enabling TLS to them", func(c *Config, v string) error {
+		c.Gossip.CAFile = v
+		return nil
+	}},
+	{"shutdown-timeout", "how long to drain in-flight requests on shutdown", func(c *Config, v string) error {
+		return parseDuration(v, &c.ShutdownTimeout)
+	}},
+	{"reflection", "enable gRPC server reflection", func(c *Config, v string) error {
+		return parseBool(v, &c.Reflection)
+	}},
+}
+
+func envName(flagName string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// Load resolves the configuration from defaults, the config file, the
+// environment and args, then validates the result.
+func Load(args []string, lookupEnv func(string) (string, bool), output io.Writer) (*Config, Options, error) {
+	var opts Options
+
+	fs := flag.NewFlagSet("kvstore-server", flag.ContinueOnError)
+	fs.SetOutput(output)
+
+	configFile, _ := lookupEnv(envName("config"))
+	fs.StringVar(&opts.ConfigFile, "config", configFile, "path to a YAML or JSON config file (env "+envName("config")+")")
+	fs.BoolVar(&opts.PrintConfig, "print-config", false, "print the effective configuration and exit")
+
+	type flagValue struct {
+		setting setting
+		value   string
+	}
+	var flagValues []flagValue
+
+	for _, s := range settings {
+		usage := fmt.Sprintf("%s (env %s)", s.usage, envName(s.flag))
+		record := func(v string) error {
+			flagValues = append(flagValues, flagValue{s, v})
+			return nil
+		}
+
+		if boolSettings[s.flag] {
+			fs.BoolFunc(s.flag, usage, record)
+		} else {
+			fs.Func(s.flag, usage, record)
+		}
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, opts, err
+	}
+
+	cfg := Default()
+
+	if opts.ConfigFile != "" {
+		if err := cfg.LoadFile(opts.ConfigFile); err != nil {
+			return nil, opts, err
+		}
+	}
+
+	for _, s := range settings {
+		name := envName(s.flag)
+		if v, ok := lookupEnv(name); ok {
+			if err := s.set(cfg, v); err != nil {
+				return nil, opts, fmt.Errorf("invalid %s: %w", name, err)
+			}
+		}
+	}
+
+	for _, fv := range flagValues {
+		if err := fv.setting.set(cfg, fv.value); err != nil {
+			return nil, opts, fmt.Errorf("invalid -%s: %w", fv.setting.flag, err)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, opts, err
+	}
+
+	return cfg, opts, nil
+}
+
+func parseInt(v string, dst *int) error {
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("%q is not an integer", v)
+	}
+	*dst = n
+	return nil
+}
+
+func parseInt64(v string, dst *int64) error {
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%q is not an integer", v)
+	}
+	*dst = n
+	return nil
+}
+
+func parseFloat(v string, dst *float64) error {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return fmt.Errorf("%q is not a number", v)
+	}
+	*dst = f
+	return nil
+}
+
+func parseDuration(v string, dst *time.Duration) error {
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return fmt.Errorf("%q is not a duration", v)
+	}
+	*dst = d
+	return nil
+}
+
+func parseBool(v string, dst *bool) error {
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return fmt.Errorf("%q is not a boolean", v)
+	}
+	*dst = b
+	return nil
+}
+
+// parseList parses "a,b,...", skipping empty items.
+func parseList(v string) []string {
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// parsePeers parses "n1=10.0.0.1:7000/10.0.0.1:9090,n2=...".
+func parsePeers(v string, dst *[]PeerConfig) error {
+	var peers []PeerConfig
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		id, addrs, ok := strings.Cut(item, "=")
+		raftAddr, grpcAddr, ok2 := strings.Cut(addrs, "/")
+		if !ok || !ok2 {
+			return fmt.Errorf("%q is not id=raft_addr/grpc_addr", item)
+		}
+		peers = append(peers, PeerConfig{ID: id, RaftAddr: raftAddr, GRPCAddr: grpcAddr})
+	}
+	*dst = peers
+	return nil
+}
+
+// parseShardNodes parses "s1=10.0.0.1:9090,s2=...".
+func parseShardNodes(v string, dst *[]ShardNodeConfig) error {
+	var nodes []ShardNodeConfig
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		id, addr, ok := strings.Cut(item, "=")
+		if !ok {
+			return fmt.Errorf("%q is not id=grpc_addr", item)
+		}
+		nodes = append(nodes, ShardNodeConfig{ID: id, GRPCAddr: addr})
+	}
+	*dst = nodes
+	return nil
+}
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// reloadable lists the settings that can change while the server is running.
+// Each entry names a config path and copies that section between configs.
+var reloadable = []struct {
+	path string
+	copy func(dst, src *Config)
+}{
+	{"log_level", func(dst, src *Config) { dst.LogLevel = src.LogLevel }},
+	{"limits", func(dst, src *Config) { dst.Limits = src.Limits }},
+	{"storage.memory", func(dst, src *Config) { dst.Storage.Memory = src.Storage.Memory }},
+	{"auth", func(dst, src *Config) { dst.Auth = src.Auth }},
+	{"quotas", func(dst, src *Config) { dst.Quotas = src.Quotas }},
+	{"rate_limits", func(dst, src *Config) { dst.RateLimits = src.RateLimits }},
+	{"access_log", func(dst, src *Config) { dst.AccessLog = src.AccessLog }},
+}
+
+// ReloadResult describes the settings that changed in a reload.
+type ReloadResult struct {
+	// Applied settings are live once Reload returns.
+	Applied []string
+	// RequiresRestart settings changed on disk but keep their old value
+	// until the server is restarted.
+	RequiresRestart []string
+}
+
+// Reloader re-resolves the configuration on demand and hands the reloadable
+// part of it to subscribers.
+type Reloader struct {
+	load func() (*Config, error)
+
+	mu          sync.Mutex
+	current     *Config
+	subscribers []func(*Config)
+}
+
+func NewReloader(current *Config, load func() (*Config, error)) *Reloader {
+	return &Reloader{load: load, current: current}
+}
+
+// Current returns the configuration the server is running with.
+func (r *Reloader) Current() *Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.current
+}
+
+// Subscribe registers fn to be called with the new effective configuration
+// after every successful reload.
+func (r *Reloader) Subscribe(fn func(*Config)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.subscribers = append(r.subscribers, fn)
+}
+
+// Reload loads and validates the configuration again. If it is invalid,
+// nothing changes. Otherwise the reloadable settings are swapped in and the
+// remaining differences are reported as requiring a restart.
+func (r *Reloader) Reload() (ReloadResult, error) {
+	next, err := r.load()
+	if err != nil {
+		return ReloadResult{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changed, err := diff(r.current, next)
+	if err != nil {
+		return ReloadResult{}, err
+	}
+
+	var result ReloadResult
+	for _, path := range changed {
+		if isReloadable(path) {
+			result.Applied = append(result.Applied, path)
+		} else {
+			result.RequiresRestart = append(result.RequiresRestart, path)
+		}
+	}
+
+	if len(result.Applied) == 0 {
+		return result, nil
+	}
+
+	effective := *r.current
+	for _, section := range reloadable {
+		section.copy(&effective, next)
+	}
+	r.current = &effective
+
+	for _, fn := range r.subscribers {
+		fn(&effective)
+	}
+
+	return result, nil
+}
+
+func isReloadable(path string) bool {
+	for _, section := range reloadable {
+		if path == section.path || strings.HasPrefix(path, section.path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// diff returns the dotted paths of every leaf setting that differs between a
+// and b, sorted.
+func diff(a, b *Config) ([]string, error) {
+	fa, err := flatten(a)
+	if err != nil {
+		return nil, err
+	}
+	fb, err := flatten(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	for path, v := range fa {
+		if fb[path] != v {
+			changed = append(changed, path)
+		}
+	}
+	for path := range fb {
+		if _, ok := fa[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+
+	return changed, nil
+}
+
+func flatten(c *Config) (map[string]string, error) {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var tree map[string]any
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]string)
+	var walk func(prefix string, node any)
+	walk = func(prefix string, node any) {
+		if m, ok := node.(map[string]any); ok {
+			for k, v := range m {
+				if prefix != "" {
+					k = prefix + "." + k
+				}
+				walk(k, v)
+			}
+			return
+		}
+		out[prefix] = fmt.Sprint(node)
+	}
+	walk("", tree)
+
+	return out, nil
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test reloadable settings are applied and the rest reported
+func TestReloader_Reload(t *testing.T) {
+	next := Default()
+	next.LogLevel = "debug"
+	next.Limits.MaxValueBytes = 10
+	next.Listen.GRPC = ":7000"
+
+	r := NewReloader(Default(), func() (*Config, error) { return next, nil })
+
+	var notified *Config
+	r.Subscribe(func(c *Config) { notified = c })
+
+	result, err := r.Reload()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"limits.max_value_bytes", "log_level"}, result.Applied)
+	assert.Equal(t, []string{"listen.grpc"}, result.RequiresRestart)
+
+	require.NotNil(t, notified)
+	assert.Equal(t, "debug", notified.LogLevel)
+	assert.Equal(t, 10, notified.Limits.MaxValueBytes)
+	assert.Equal(t, ":9090", notified.Listen.GRPC, "restart-only settings keep their running value")
+	assert.Same(t, notified, r.Current())
+
+	// Reloading again still reports the pending restart but applies nothing
+	notified = nil
+	result, err = r.Reload()
+	require.NoError(t, err)
+	assert.Empty(t, result.Applied)
+	assert.Equal(t, []string{"listen.grpc"}, result.RequiresRestart)
+	assert.Nil(t, notified)
+}
+
+// Test a failed load leaves the running configuration untouched
+func TestReloader_LoadError(t *testing.T) {
+	current := Default()
+	r := NewReloader(current, func() (*Config, error) { return nil, errors.New("bad config") })
+
+	called := false
+	r.Subscribe(func(*Config) { called = true })
+
+	_, err := r.Reload()
+	assert.Error(t, err)
+	assert.False(t, called)
+	assert.Same(t, current, r.Current())
+}
@@ -0,0 +1,342 @@
+// Package crdt holds the values of keys written on several sites at once,
+// such that any two sites that have seen the same writes, in any order and
+// any number of times, hold the same value.
+//
+// Every value is an Entry: a last-writer-wins register for plain strings,
+// a counter, or a set. Set and Delete replace the whole entry and are
+// ordered by their Stamp, so the later one wins. Increments of a counter,
+// and additions to and removals from a set, change the entry in place and
+// all take effect, however many sites make them concurrently: a counter
+// sums the increments of every site, and a set keeps a member that one
+// site added while another concurrently removed it.
+//
+// A counter or set started on a key that was deleted, or never written,
+// keeps the Stamp of that delete. Sites that start one concurrently thus
+// merge their changes, and a later Set or Delete still replaces them. A
+// counter started on a set whose members were all removed takes the Stamp
+// of the increment instead, and so replaces the set on every site.
+package crdt
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"kvstore/internal/hlc"
+	"maps"
+	"sort"
+	"strconv"
+)
+
+// ErrWrongKind is returned for increments of a key that holds something
+// other than a counter, and member changes of one that is not a set.
+var ErrWrongKind = errors.New("crdt: key holds the wrong kind of value")
+
+// Stamp identifies a write by when and where it was made. No two writes
+// have the same Stamp, since a site never hands out a timestamp twice.
+type Stamp struct {
+	Time hlc.Timestamp `json:"time"`
+	Site string        `json:"site,omitempty"`
+}
+
+// Compare orders stamps by time, then by site.
+func (s Stamp) Compare(o Stamp) int {
+	if c := s.Time.Compare(o.Time); c != 0 {
+		return c
+	}
+	return cmp.Compare(s.Site, o.Site)
+}
+
+func (s Stamp) String() string {
+	return fmt.Sprintf("%s@%s", s.Time, s.Site)
+}
+
+// Kind is what an Entry holds. When two entries have the same Stamp, the
+// greater kind wins.
+type Kind uint8
+
+const (
+	// None is a deleted or never written key.
+	None Kind = iota
+	Register
+	Counter
+	Set
+)
+
+func (k Kind) String() string {
+	switch k {
+	case None:
+		return "none"
+	case Register:
+		return "string"
+	case Counter:
+		return "counter"
+	case Set:
+		return "set"
+	}
+	return fmt.Sprintf("kind(%d)", uint8(k))
+}
+
+// Count is what one site added to and subtracted from a counter.
+type Count struct {
+	Inc uint64 `json:"inc,omitempty"`
+	Dec uint64 `json:"dec,omitempty"`
+}
+
+// Entry is the value of one key. The zero Entry is a key never written.
+// Entries are values: operations return a changed copy.
+type Entry struct {
+	// Born is the Set or Delete that created the entry.
+	Born  Stamp  `json:"born"`
+	Kind  Kind   `json:"kind,omitempty"`
+	Owner string `json:"owner,omitempty"`
+
+	// Registers only. ExpiresAt is a Unix time in seconds; zero never
+	// expires.
+	Value     string `json:"value,omitempty"`
+	ExpiresAt int64  `json:"expires_at,omitempty"`
+
+	// Counters only: the count of every site.
+	Counts map[string]Count `json:"counts,omitempty"`
+
+	// Sets only. A member is present while it has a tag, the site and
+	// sequence number of an addition, that no site has removed. Seen is the
+	// highest sequence number of every site's additions seen so far, which
+	// tells a tag that was removed from one that has not arrived yet.
+	Members map[string]map[string]uint64 `json:"members,omitempty"`
+	Seen    map[string]uint64            `json:"seen,omitempty"`
+}
+
+// NewRegister returns the entry Set writes at stamp.
+func NewRegister(stamp Stamp, value, owner string, expiresAt int64) Entry {
+	return Entry{Born: stamp, Kind: Register, Owner: owner, Value: value, ExpiresAt: expiresAt}
+}
+
+// Tombstone returns the entry Delete writes at stamp.
+func Tombstone(stamp Stamp) Entry {
+	return Entry{Born: stamp}
+}
+
+// Expired reports whether e is a register that expired by now, a Unix time
+// in seconds.
+func (e Entry) Expired(now int64) bool {
+	return e.Kind == Register && e.ExpiresAt > 0 && e.ExpiresAt <= now
+}
+
+// Dead reports whether e is a tombstone or a register that expired by now.
+// Either is only kept so that older writes arriving late lose to it.
+func (e Entry) Dead(now int64) bool {
+	return e.Kind == None || e.Expired(now)
+}
+
+// Size approximates the bytes e takes in memory.
+func (e Entry) Size() int64 {
+	const stampSize, countSize, tagSize = 32, 16, 8
+
+	size := int64(stampSize + len(e.Born.Site) + len(e.Owner) + len(e.Value) + 16)
+	for site := range e.Counts {
+		size += int64(len(site) + countSize)
+	}
+	for m, tags := range e.Members {
+		size += int64(len(m))
+		for site := range tags {
+			size += int64(len(site) + tagSize)
+		}
+	}
+	for site := range e.Seen {
+		size += int64(len(site) + tagSize)
+	}
+	return size
+}
+
+// Read returns what a read of the key sees: a register's value, a
+// counter's total in decimal, or a set's members as a sorted JSON array of
+// strings. ok is false for deleted keys and empty sets.
+func (e Entry) Read() (value string, ok bool) {
+	switch e.Kind {
+	case Register:
+		return e.Value, true
+	case Counter:
+		return strconv.FormatInt(e.Total(), 10), true
+	case Set:
+		members := e.MemberList()
+		if len(members) == 0 {
+			return "", false
+		}
+		data, _ := json.Marshal(members)
+		return string(data), true
+	}
+	return "", false
+}
+
+// Total is the sum of a counter's increments, less its decrements.
+func (e Entry) Total() int64 {
+	var total uint64
+	for _, c := range e.Counts {
+		total += c.Inc - c.Dec
+	}
+	return int64(total)
+}
+
+// MemberList returns the members of a set, sorted.
+func (e Entry) MemberList() []string {
+	out := make([]string, 0, len(e.Members))
+	for m := range e.Members {
+		out = append(out, m)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Increment adds delta, which may be negative, to the counter of the site
+// that made now, a new stamp. A deleted key or an empty set becomes a
+// counter owned by owner.
+func (e Entry) Increment(now Stamp, owner string, delta int64) (Entry, error) {
+	e, err := e.become(Counter, owner, now)
+	if err != nil {
+		return Entry{}, err
+	}
+	site := now.Site
+
+	e.Counts = maps.Clone(e.Counts)
+	if e.Counts == nil {
+		e.Counts = make(map[string]Count)
+	}
+	c := e.Counts[site]
+	if delta >= 0 {
+		c.Inc += uint64(delta)
+	} else {
+		c.Dec += uint64(-delta)
+	}
+	e.Counts[site] = c
+	return e, nil
+}
+
+// Add adds members to the set, with tags of the site that made now, a new
+// stamp. A deleted key becomes a set owned by owner.
+func (e Entry) Add(now Stamp, owner string, members ...string) (Entry, error) {
+	e, err := e.become(Set, owner, now)
+	if err != nil {
+		return Entry{}, err
+	}
+	site := now.Site
+
+	e.Members = cloneMembers(e.Members)
+	e.Seen = maps.Clone(e.Seen)
+	if e.Seen == nil {
+		e.Seen = make(map[string]uint64)
+	}
+	for _, m := range members {
+		e.Seen[site]++
+		// The new tag supersedes the ones seen so far.
+		e.Members[m] = map[string]uint64{site: e.Seen[site]}
+	}
+	return e, nil
+}
+
+// Remove removes members from the set, along with every tag of theirs seen
+// so far. Removing from a deleted key changes nothing.
+func (e Entry) Remove(members ...string) (Entry, error) {
+	if e.Kind == None {
+		return e, nil
+	}
+	if e.Kind != Set {
+		return Entry{}, fmt.Errorf("%w: %s, not a set", ErrWrongKind, e.Kind)
+	}
+
+	e.Members = cloneMembers(e.Members)
+	for _, m := range members {
+		delete(e.Members, m)
+	}
+	return e, nil
+}
+
+// become returns e as an entry of kind, starting one if e is deleted or an
+// empty set. One started on an empty set is born at now: sites still
+// holding the set, which wins over other kinds born at the same time,
+// would otherwise keep it.
+func (e Entry) become(kind Kind, owner string, now Stamp) (Entry, error) {
+	switch {
+	case e.Kind == kind:
+		return e, nil
+	case e.Kind == None:
+		return Entry{Born: e.Born, Kind: kind, Owner: owner}, nil
+	case e.Kind == Set && len(e.Members) == 0:
+		return Entry{Born: now, Kind: kind, Owner: owner}, nil
+	}
+	return Entry{}, fmt.Errorf("%w: %s, not a %s", ErrWrongKind, e.Kind, kind)
+}
+
+// Merge returns the entry holding the changes of both a and b. It is
+// commutative, associative and idempotent, so sites converge however their
+// writes are delivered.
+func Merge(a, b Entry) Entry {
+	if c := a.Born.Compare(b.Born); c != 0 {
+		if c > 0 {
+			return a
+		}
+		return b
+	}
+	if a.Kind != b.Kind {
+		if a.Kind > b.Kind {
+			return a
+		}
+		return b
+	}
+
+	// Registers and tombstones with the same Stamp are the same write.
+	// Counters and sets started on the same tombstone merge their changes.
+	out := Entry{Born: a.Born, Kind: a.Kind, Owner: max(a.Owner, b.Owner)}
+	switch a.Kind {
+	case None, Register:
+		return a
+
+	case Counter:
+		out.Counts = maps.Clone(a.Counts)
+		if out.Counts == nil {
+			out.Counts = make(map[string]Count)
+		}
+		for site, c := range b.Counts {
+			o := out.Counts[site]
+			out.Counts[site] = Count{Inc: max(o.Inc, c.Inc), Dec: max(o.Dec, c.Dec)}
+		}
+
+	case Set:
+		out.Members = make(map[string]map[string]uint64)
+		keep := func(m string, tags map[string]uint64, other Entry) {
+			for site, n := range tags {
+				// A tag missing on the other side was removed there if the
+				// other side has seen it.
+				if other.Members[m][site] != n && n <= other.Seen[site] {
+					continue
+				}
+				if out.Members[m] == nil {
+					out.Members[m] = make(map[string]uint64)
+				}
+				out.Members[m][site] = max(out.Members[m][site], n)
+			}
+		}
+		for m, tags := range a.Members {
+			keep(m, tags, b)
+		}
+		for m, tags := range b.Members {
+			keep(m, tags, a)
+		}
+		out.Seen = maps.Clone(a.Seen)
+		if out.Seen == nil {
+			out.Seen = make(map[string]uint64)
+		}
+		for site, n := range b.Seen {
+			out.Seen[site] = max(out.Seen[site], n)
+		}
+	}
+	return out
+}
+
+func cloneMembers(members map[string]map[string]uint64) map[string]map[string]uint64 {
+	out := make(map[string]map[string]uint64, len(members))
+	for m, tags := range members {
+		out[m] = maps.Clone(tags)
+	}
+	return out
+}
@@ -0,0 +1,158 @@
+package crdt
+
+import (
+	"kvstore/internal/hlc"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func stamp(wall int64, site string) Stamp {
+	return Stamp{Time: hlc.Timestamp{Wall: wall}, Site: site}
+}
+
+func read(t *testing.T, e Entry) string {
+	t.Helper()
+	v, ok := e.Read()
+	require.True(t, ok, "%+v holds nothing", e)
+	return v
+}
+
+// Test the later Set or Delete wins, with ties broken by site
+func TestMerge_Register(t *testing.T) {
+	a := NewRegister(stamp(2, "a"), "from a", "", 0)
+	b := NewRegister(stamp(1, "b"), "from b", "", 0)
+	assert.Equal(t, "from a", read(t, Merge(a, b)))
+	assert.Equal(t, "from a", read(t, Merge(b, a)))
+
+	tie := NewRegister(stamp(2, "b"), "tie", "", 0)
+	assert.Equal(t, "tie", read(t, Merge(a, tie)))
+
+	deleted := Merge(a, Tombstone(stamp(3, "b")))
+	_, ok := deleted.Read()
+	assert.False(t, ok)
+	_, ok = Entry{}.Read()
+	assert.False(t, ok)
+
+	assert.True(t, NewRegister(stamp(1, "a"), "v", "", 10).Expired(10))
+	assert.False(t, NewRegister(stamp(1, "a"), "v", "", 0).Expired(10))
+}
+
+// Test concurrent increments of every site add up, and sites started on
+// the same tombstone share the counter
+func TestMerge_Counter(t *testing.T) {
+	a, err := Entry{}.Increment(stamp(3, "a"), "alice", 5)
+	require.NoError(t, err)
+	b, err := Entry{}.Increment(stamp(3, "b"), "bob", -2)
+	require.NoError(t, err)
+	b, err = b.Increment(stamp(3, "b"), "bob", 10)
+	require.NoError(t, err)
+
+	merged := Merge(a, b)
+	assert.EqualValues(t, 13, merged.Total())
+	assert.Equal(t, "13", read(t, merged))
+	assert.Equal(t, "bob", merged.Owner)
+	assert.Equal(t, merged, Merge(merged, a), "merging again changes nothing")
+
+	// A Set replaces the counter, which can no longer be incremented
+	set := NewRegister(stamp(1, "a"), "text", "", 0)
+	assert.Equal(t, "text", read(t, Merge(merged, set)))
+	_, err = set.Increment(stamp(3, "a"), "", 1)
+	assert.ErrorIs(t, err, ErrWrongKind)
+
+	// A counter started on the Delete of the Set replaces it
+	deleted := Tombstone(stamp(2, "b"))
+	counter, err := deleted.Increment(stamp(3, "b"), "", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "1", read(t, Merge(Merge(set, counter), deleted)))
+}
+
+// Test a member added on one site while another removes it stays, and
+// removals only remove the additions they saw
+func TestMerge_Set(t *testing.T) {
+	base, err := Entry{}.Add(stamp(3, "a"), "", "x", "y")
+	require.NoError(t, err)
+	assert.Equal(t, `["x","y"]`, read(t, base))
+
+	removed, err := base.Remove("x", "y")
+	require.NoError(t, err)
+	readded, err := base.Add(stamp(3, "b"), "", "x")
+	require.NoError(t, err)
+
+	merged := Merge(removed, readded)
+	assert.Equal(t, []string{"x"}, merged.MemberList())
+	assert.Equal(t, merged.MemberList(), Merge(readded, removed).MemberList())
+
+	// The removal has seen y's addition, so y stays removed however often
+	// the older state arrives
+	assert.Equal(t, []string{"x"}, Merge(merged, base).MemberList())
+
+	empty, err := base.Remove("x", "y")
+	require.NoError(t, err)
+	_, ok := empty.Read()
+	assert.False(t, ok, "an empty set holds nothing")
+	counter, err := empty.Increment(stamp(4, "a"), "", 1)
+	require.NoError(t, err, "an empty set can become a counter")
+	assert.Equal(t, "1", read(t, Merge(empty, counter)), "the counter replaces the set where it is still held")
+	assert.Equal(t, "1", read(t, Merge(counter, readded)))
+
+	_, err = NewRegister(stamp(1, "a"), "v", "", 0).Add(stamp(3, "a"), "", "x")
+	assert.ErrorIs(t, err, ErrWrongKind)
+	_, err = base.Increment(stamp(3, "a"), "", 1)
+	assert.ErrorIs(t, err, ErrWrongKind)
+	nothing, err := Entry{}.Remove("x")
+	require.NoError(t, err)
+	assert.Equal(t, Entry{}, nothing)
+}
+
+// Test sites applying random operations converge to the same value however
+// their states are merged
+func TestMerge_Converges(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	sites := []string{"a", "b", "c"}
+
+	for round := range 200 {
+		states := make([]Entry, len(sites))
+		var wall int64
+		for range 30 {
+			i := rng.Intn(len(sites))
+			site, e := sites[i], states[i]
+			wall++
+			switch rng.Intn(6) {
+			case 0:
+				e = NewRegister(stamp(wall, site), "v", "", 0)
+			case 1:
+				e = Tombstone(stamp(wall, site))
+			case 2:
+				if next, err := e.Increment(stamp(wall, site), "", int64(rng.Intn(10)-5)); err == nil {
+					e = next
+				}
+			case 3:
+				if next, err := e.Add(stamp(wall, site), "", string(rune('p'+rng.Intn(4)))); err == nil {
+					e = next
+				}
+			case 4:
+				if next, err := e.Remove(string(rune('p' + rng.Intn(4)))); err == nil {
+					e = next
+				}
+			case 5:
+				// Receive another site's state
+				e = Merge(e, states[rng.Intn(len(sites))])
+			}
+			states[i] = e
+		}
+
+		forward := Merge(Merge(states[0], states[1]), states[2])
+		backward := Merge(states[2], Merge(states[1], states[0]))
+		want, wantOK := forward.Read()
+		got, gotOK := backward.Read()
+		require.Equal(t, wantOK, gotOK, "round %d", round)
+		require.Equal(t, want, got, "round %d", round)
+
+		again, againOK := Merge(forward, backward).Read()
+		require.Equal(t, wantOK, againOK, "round %d", round)
+		require.Equal(t, want, again, "round %d", round)
+	}
+}
@@ -0,0 +1,430 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	pb "kvstore/pkg/pb/api/proto"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// TTLHeader may be used instead of the ttl query parameter on PUT requests.
+const TTLHeader = "X-Kvstore-Ttl"
+
+// NamespaceHeader may be used instead of the namespace query parameter.
+const NamespaceHeader = "X-Kvstore-Namespace"
+
+// IndexHeader carries the log index of responses from a cluster. Pass it
+// back in the min_index query parameter of sequential reads.
+const IndexHeader = "X-Kvstore-Index"
+
+// consistencies maps the consistency query parameter of reads.
+var consistencies = map[string]pb.ReadConsistency{
+	"linearizable": pb.ReadConsistency_READ_CONSISTENCY_LINEARIZABLE,
+	"lease":        pb.ReadConsistency_READ_CONSISTENCY_LEASE,
+	"sequential":   pb.ReadConsistency_READ_CONSISTENCY_SEQUENTIAL,
+	"stale":        pb.ReadConsistency_READ_CONSISTENCY_STALE,
+}
+
+const maxBodyBytes = 4 << 20
+
+// forwardedHeaders are copied into the incoming gRPC metadata so that
+// interceptors see REST calls the same way as native gRPC calls.
+var forwardedHeaders = []string{"Authorization", "X-Api-Key"}
+
+// tracer is a no-op until a TracerProvider is installed, see
+// internal/tracing.
+var tracer = otel.Tracer("kvstore/internal/gateway")
+
+// Gateway exposes the KVStore service as a JSON REST API.
+type Gateway struct {
+	kv          pb.KVStoreServer
+	mux         *http.ServeMux
+	interceptor grpc.UnaryServerInterceptor
+}
+
+type Option func(*Gateway)
+
+// WithInterceptors runs every REST call through the given gRPC interceptors,
+// in order, before it reaches the KVStore service.
+func WithInterceptors(interceptors ...grpc.UnaryServerInterceptor) Option {
+	return func(g *Gateway) {
+		g.interceptor = chainUnary(interceptors)
+	}
+}
+
+type keyValue struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type setBody struct {
+	Value      string `json:"value"`
+	TTLSeconds *int64 `json:"ttl_seconds,omitempty"`
+}
+
+type deleteResult struct {
+	Key     string `json:"key"`
+	Existed bool   `json:"existed"`
+}
+
+type listResult struct {
+	Pairs []keyValue `json:"pairs"`
+}
+
+type errorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func New(kv pb.KVStoreServer, opts ...Option) *Gateway {
+	g := &Gateway{kv: kv, mux: http.NewServeMux()}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	g.mux.HandleFunc("GET /v1/keys", g.handleList)
+	g.mux.HandleFunc("GET /v1/keys/{key...}", g.handleGet)
+	g.mux.HandleFunc("PUT /v1/keys/{key...}", g.handleSet)
+	g.mux.HandleFunc("DELETE /v1/keys/{key...}", g.handleDelete)
+
+	return g
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mux.ServeHTTP(w, r)
+}
+
+func (g *Gateway) handleGet(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	consistency, minIndex, maxStaleness, err := parseConsistency(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resp, err := invoke(g, r, pb.KVStore_Get_FullMethodName, &pb.GetRequest{
+		Key:          key,
+		Namespace:    namespace(r),
+		Consistency:  consistency,
+		MinIndex:     minIndex,
+		MaxStaleness: maxStaleness,
+	}, g.kv.Get)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeIndex(w, resp.GetIndex())
+
+	if !resp.GetFound() {
+		writeError(w, status.Errorf(codes.NotFound, "key %q not found", key))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, keyValue{Key: key, Value: resp.GetValue()})
+}
+
+func (g *Gateway) handleSet(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	var body setBody
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxBodyBytes)).Decode(&body); err != nil {
+		writeError(w, status.Errorf(codes.InvalidArgument, "invalid request body: %v", err))
+		return
+	}
+
+	ttl, err := parseTTL(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if ttl == nil {
+		ttl = body.TTLSeconds
+	}
+
+	resp, err := invoke(g, r, pb.KVStore_Set_FullMethodName, &pb.SetRequest{
+		Key:        key,
+		Value:      body.Value,
+		TtlSeconds: ttl,
+		Namespace:  namespace(r),
+	}, g.kv.Set)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeIndex(w, resp.GetIndex())
+
+	writeJSON(w, http.StatusOK, keyValue{Key: key, Value: body.Value})
+}
+
+func (g *Gateway) handleDelete(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	resp, err := invoke(g, r, pb.KVStore_Delete_FullMethodName, &pb.DeleteRequest{Key: key, Namespace: namespace(r)}, g.kv.Delete)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeIndex(w, resp.GetIndex())
+
+	code := http.StatusOK
+	if !resp.GetExisted() {
+		code = http.StatusNotFound
+	}
+
+	writeJSON(w, code, deleteResult{Key: key, Existed: resp.GetExisted()})
+}
+
+func (g *Gateway) handleList(w http.ResponseWriter, r *http.Request) {
+	consistency, minIndex, maxStaleness, err := parseConsistency(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	req := &pb.ListRequest{
+		Namespace:    namespace(r),
+		Consistency:  consistency,
+		MinIndex:     minIndex,
+		MaxStaleness: maxStaleness,
+	}
+
+	query := r.URL.Query()
+	if query.Has("prefix") {
+		prefix := query.Get("prefix")
+		req.Prefix = &prefix
+	}
+	if query.Has("limit") {
+		parsed, err := strconv.ParseInt(query.Get("limit"), 10, 32)
+		if err != nil {
+			writeError(w, status.Errorf(codes.InvalidArgument, "invalid limit: %v", err))
+			return
+		}
+		limit := int32(parsed)
+		req.Limit = &limit
+	}
+
+	resp, err := invoke(g, r, pb.KVStore_List_FullMethodName, req, g.kv.List)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeIndex(w, resp.GetIndex())
+
+	result := listResult{Pairs: make([]keyValue, 0, len(resp.GetPairs()))}
+	for _, pair := range resp.GetPairs() {
+		result.Pairs = append(result.Pairs, keyValue{Key: pair.GetKey(), Value: pair.GetValue()})
+	}
+	sort.Slice(result.Pairs, func(i, j int) bool {
+		return result.Pairs[i].Key < result.Pairs[j].Key
+	})
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// namespace returns the namespace named by the query parameter or header.
+// Empty selects the default namespace.
+func namespace(r *http.Request) string {
+	if ns := r.URL.Query().Get("namespace"); ns != "" {
+		return ns
+	}
+	return r.Header.Get(NamespaceHeader)
+}
+
+// invoke calls method on the KVStore service through the gateway's
+// interceptors, with the forwarded HTTP headers as incoming metadata. The
+// call is traced as a server span continuing the caller's trace context.
+func invoke[Req, Resp any](g *Gateway, r *http.Request, method string, req Req, call func(context.Context, Req) (Resp, error)) (_ Resp, err error) {
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx, span := tracer.Start(ctx, strings.TrimPrefix(method, "/"),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("url.path", r.URL.Path),
+		),
+	)
+	defer func() {
+		if err != nil {
+			span.SetStatus(otelcodes.Error, err.Error())
+		}
+		span.SetAttributes(attribute.String("rpc.grpc.status_code", status.Code(err).String()))
+		span.End()
+	}()
+
+	md := metadata.MD{}
+	for _, name := range forwardedHeaders {
+		if values := r.Header.Values(name); len(values) > 0 {
+			md.Set(name, values...)
+		}
+	}
+	ctx = metadata.NewIncomingContext(ctx, md)
+	if addr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr); err == nil {
+		ctx = peer.NewContext(ctx, &peer.Peer{Addr: addr})
+	}
+
+	if g.interceptor == nil {
+		return call(ctx, req)
+	}
+
+	info := &grpc.UnaryServerInfo{Server: g.kv, FullMethod: method}
+	resp, err := g.interceptor(ctx, req, info, func(ctx context.Context, req any) (any, error) {
+		return call(ctx, req.(Req))
+	})
+	if err != nil {
+		var zero Resp
+		return zero, err
+	}
+
+	return resp.(Resp), nil
+}
+
+// chainUnary combines interceptors into one, with the first being the
+// outermost, matching grpc.ChainUnaryInterceptor.
+func chainUnary(interceptors []grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	if len(interceptors) == 0 {
+		return nil
+	}
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		next := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, inner := interceptors[i], next
+			next = func(ctx context.Context, req any) (any, error) {
+				return interceptor(ctx, req, info, inner)
+			}
+		}
+		return next(ctx, req)
+	}
+}
+
+// parseTTL reads the TTL from the ttl query parameter or TTLHeader, in that
+// order. It returns nil when neither is present.
+func parseTTL(r *http.Request) (*int64, error) {
+	raw := r.URL.Query().Get("ttl")
+	if raw == "" {
+		raw = r.Header.Get(TTLHeader)
+	}
+	if raw == "" {
+		return nil, nil
+	}
+
+	ttl, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid ttl: %v", err)
+	}
+
+	return &ttl, nil
+}
+
+// parseConsistency reads the consistency, min_index and max_staleness query
+// parameters of a read.
+func parseConsistency(r *http.Request) (pb.ReadConsistency, uint64, *durationpb.Duration, error) {
+	query := r.URL.Query()
+
+	var consistency pb.ReadConsistency
+	if raw := query.Get("consistency"); raw != "" {
+		var ok bool
+		if consistency, ok = consistencies[raw]; !ok {
+			return 0, 0, nil, status.Errorf(codes.InvalidArgument,
+				"invalid consistency %q (want linearizable, lease, sequential or stale)", raw)
+		}
+	}
+
+	var minIndex uint64
+	if raw := query.Get("min_index"); raw != "" {
+		var err error
+		if minIndex, err = strconv.ParseUint(raw, 10, 64); err != nil {
+			return 0, 0, nil, status.Errorf(codes.InvalidArgument, "invalid min_index: %v", err)
+		}
+	}
+
+	var maxStaleness *durationpb.Duration
+	if raw := query.Get("max_staleness"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return 0, 0, nil, status.Errorf(codes.InvalidArgument, "invalid max_staleness: %v", err)
+		}
+		maxStaleness = durationpb.New(d)
+	}
+
+	return consistency, minIndex, maxStaleness, nil
+}
+
+// writeIndex sets IndexHeader on responses from a cluster.
+func writeIndex(w http.ResponseWriter, index uint64) {
+	if index > 0 {
+		w.Header().Set(IndexHeader, strconv.FormatUint(index, 10))
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	st, _ := status.FromError(err)
+
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok {
+			seconds := math.Ceil(info.GetRetryDelay().AsDuration().Seconds())
+			w.Header().Set("Retry-After", strconv.Itoa(max(1, int(seconds))))
+		}
+	}
+
+	writeJSON(w, HTTPStatusFromCode(st.Code()), errorBody{
+		Code:    st.Code().String(),
+		Message: st.Message(),
+	})
+}
+
+// HTTPStatusFromCode maps a gRPC status code to the closest HTTP status.
+func HTTPStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499 // Client Closed Request
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,261 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"kvstore/internal/ratelimit"
+	"kvstore/internal/server"
+	"kvstore/internal/storage"
+	pb "kvstore/pkg/pb/api/proto"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func newTestGateway() (*Gateway, *storage.Namespaces) {
+	namespaces := storage.NewNamespaces()
+	return New(server.New(namespaces)), namespaces
+}
+
+func do(t *testing.T, g *Gateway, method, target, body string, header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	rec := httptest.NewRecorder()
+	g.ServeHTTP(rec, req)
+	return rec
+}
+
+// Test PUT then GET round trip
+func TestGateway_SetGet(t *testing.T) {
+	g, _ := newTestGateway()
+
+	rec := do(t, g, http.MethodPut, "/v1/keys/user/1", `{"value":"alice"}`, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = do(t, g, http.MethodGet, "/v1/keys/user/1", "", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var got keyValue
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+	assert.Equal(t, keyValue{Key: "user/1", Value: "alice"}, got)
+}
+
+// Test missing keys map to 404
+func TestGateway_NotFound(t *testing.T) {
+	g, _ := newTestGateway()
+
+	rec := do(t, g, http.MethodGet, "/v1/keys/missing", "", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	var body errorBody
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Equal(t, codes.NotFound.String(), body.Code)
+
+	rec = do(t, g, http.MethodDelete, "/v1/keys/missing", "", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+// Test DELETE removes the key
+func TestGateway_Delete(t *testing.T) {
+	g, namespaces := newTestGateway()
+	store := namespaces.Default()
+	require.NoError(t, store.Set("key1", "value1", nil))
+
+	rec := do(t, g, http.MethodDelete, "/v1/keys/key1", "", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var got deleteResult
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+	assert.True(t, got.Existed)
+
+	_, found := store.Get("key1")
+	assert.False(t, found)
+}
+
+// Test TTL from query parameter, header and body
+func TestGateway_TTL(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		body   string
+		header http.Header
+	}{
+		{"query", "/v1/keys/k?ttl=1", `{"value":"v"}`, nil},
+		{"header", "/v1/keys/k", `{"value":"v"}`, http.Header{TTLHeader: {"1"}}},
+		{"body", "/v1/keys/k", `{"value":"v","ttl_seconds":1}`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, namespaces := newTestGateway()
+			store := namespaces.Default()
+
+			rec := do(t, g, http.MethodPut, tt.target, tt.body, tt.header)
+			require.Equal(t, http.StatusOK, rec.Code)
+
+			_, found := store.Get("k")
+			assert.True(t, found)
+
+			time.Sleep(1100 * time.Millisecond)
+
+			_, found = store.Get("k")
+			assert.False(t, found)
+		})
+	}
+}
+
+// Test invalid input maps to 400
+func TestGateway_BadRequest(t *testing.T) {
+	g, _ := newTestGateway()
+
+	rec := do(t, g, http.MethodPut, "/v1/keys/k?ttl=soon", `{"value":"v"}`, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = do(t, g, http.MethodPut, "/v1/keys/k", `not json`, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = do(t, g, http.MethodGet, "/v1/keys?limit=-1", "", nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = do(t, g, http.MethodGet, "/v1/keys/k?consistency=strong", "", nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = do(t, g, http.MethodGet, "/v1/keys?consistency=stale&max_staleness=soon", "", nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+// Test list with prefix and limit
+func TestGateway_List(t *testing.T) {
+	g, namespaces := newTestGateway()
+	store := namespaces.Default()
+	for _, key := range []string{"a/2", "a/1", "a/3", "b/1"} {
+		require.NoError(t, store.Set(key, "v", nil))
+	}
+
+	rec := do(t, g, http.MethodGet, "/v1/keys?prefix=a/", "", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var got listResult
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+	require.Len(t, got.Pairs, 3)
+	assert.Equal(t, "a/1", got.Pairs[0].Key)
+	assert.Equal(t, "a/3", got.Pairs[2].Key)
+
+	rec = do(t, g, http.MethodGet, "/v1/keys?prefix=a/&limit=2", "", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+	assert.Len(t, got.Pairs, 2)
+}
+
+// Test namespaces from the query parameter and header are isolated
+func TestGateway_Namespaces(t *testing.T) {
+	g, namespaces := newTestGateway()
+	_, err := namespaces.Create("team-a", storage.NamespaceSettings{})
+	require.NoError(t, err)
+
+	rec := do(t, g, http.MethodPut, "/v1/keys/k?namespace=team-a", `{"value":"a"}`, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = do(t, g, http.MethodGet, "/v1/keys/k", "", http.Header{NamespaceHeader: {"team-a"}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = do(t, g, http.MethodGet, "/v1/keys/k", "", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = do(t, g, http.MethodGet, "/v1/keys?namespace=team-b", "", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+// Test quota errors map to 429 and name the quota
+func TestGateway_QuotaExceeded(t *testing.T) {
+	g, namespaces := newTestGateway()
+	_, err := namespaces.SetQuota(storage.DefaultNamespace, storage.Quota{MaxKeys: 1})
+	require.NoError(t, err)
+
+	rec := do(t, g, http.MethodPut, "/v1/keys/a", `{"value":"v"}`, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = do(t, g, http.MethodPut, "/v1/keys/b", `{"value":"v"}`, nil)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+
+	var body errorBody
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Equal(t, codes.ResourceExhausted.String(), body.Code)
+	assert.Contains(t, body.Message, "namespace default max_keys")
+}
+
+// Test rate-limited calls map to 429 with a Retry-After header
+func TestGateway_RateLimited(t *testing.T) {
+	limiter := ratelimit.New(ratelimit.Options{PerClient: ratelimit.Rate{PerSecond: 0.5, Burst: 1}})
+	g := New(server.New(storage.NewNamespaces()), WithInterceptors(limiter.UnaryInterceptor()))
+
+	rec := do(t, g, http.MethodPut, "/v1/keys/a", `{"value":"v"}`, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = do(t, g, http.MethodPut, "/v1/keys/a", `{"value":"v"}`, nil)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("Retry-After"))
+}
+
+// Test interceptors see forwarded headers and can reject calls
+func TestGateway_Interceptors(t *testing.T) {
+	var calls []string
+	record := func(name string) grpc.UnaryServerInterceptor {
+		return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			calls = append(calls, name+" "+info.FullMethod)
+			return handler(ctx, req)
+		}
+	}
+	requireToken := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if got := md.Get("authorization"); len(got) == 0 || got[0] != "Bearer secret" {
+			return nil, status.Error(codes.Unauthenticated, "missing credentials")
+		}
+		return handler(ctx, req)
+	}
+
+	namespaces := storage.NewNamespaces()
+	store := namespaces.Default()
+	g := New(server.New(namespaces), WithInterceptors(record("outer"), record("inner"), requireToken))
+
+	rec := do(t, g, http.MethodPut, "/v1/keys/k", `{"value":"v"}`, nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	_, found := store.Get("k")
+	assert.False(t, found)
+
+	calls = nil
+	rec = do(t, g, http.MethodPut, "/v1/keys/k", `{"value":"v"}`, http.Header{"Authorization": {"Bearer secret"}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{
+		"outer " + pb.KVStore_Set_FullMethodName,
+		"inner " + pb.KVStore_Set_FullMethodName,
+	}, calls)
+
+	_, found = store.Get("k")
+	assert.True(t, found)
+}
+
+func TestHTTPStatusFromCode(t *testing.T) {
+	assert.Equal(t, http.StatusOK, HTTPStatusFromCode(codes.OK))
+	assert.Equal(t, http.StatusBadRequest, HTTPStatusFromCode(codes.InvalidArgument))
+	assert.Equal(t, http.StatusUnauthorized, HTTPStatusFromCode(codes.Unauthenticated))
+	assert.Equal(t, http.StatusForbidden, HTTPStatusFromCode(codes.PermissionDenied))
+	assert.Equal(t, http.StatusTooManyRequests, HTTPStatusFromCode(codes.ResourceExhausted))
+	assert.Equal(t, http.StatusServiceUnavailable, HTTPStatusFromCode(codes.Unavailable))
+	assert.Equal(t, http.StatusInternalServerError, HTTPStatusFromCode(codes.Internal))
+}
@@ -0,0 +1,687 @@
+// Package gossip discovers the servers of a deployment and detects their
+// failures with SWIM, the Scalable Weakly-consistent Infection-style
+// Membership protocol.
+//
+// Every Interval a node probes one other member, taking them in a random
+// order. It pings the member directly and, without an acknowledgement
+// within ProbeTimeout, asks IndirectChecks other members to ping it on its
+// behalf, so a broken link between two nodes does not get either declared
+// dead. A member no probe reached is suspected, and declared dead unless it
+// refutes the suspicion within SuspicionTimeout, which it does by raising
+// its incarnation as soon as it hears of it.
+//
+// Membership changes travel on the probes: every message piggybacks the
+// changes its sender learned recently, each sent a few times per doubling
+// of the membership, so they reach every member within a logarithmic
+// number of rounds. Every SyncInterval a node also exchanges its whole
+// view with a random member. That is how a new node learns the membership
+// from its seeds, and how both sides of a healed partition catch up.
+package gossip
+
+import (
+	"context"
+	"fmt"
+	pb "kvstore/pkg/pb/api/proto"
+	"log/slog"
+	"math/bits"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Defaults for the zero values of Options.
+const (
+	DefaultInterval         = time.Second
+	DefaultProbeTimeout     = 300 * time.Millisecond
+	DefaultSuspicionTimeout = 5 * time.Second
+	DefaultIndirectChecks   = 3
+	DefaultSyncInterval     = 30 * time.Second
+	DefaultReclaimTimeout   = time.Hour
+)
+
+const (
+	// maxPiggyback is how many membership changes one message carries.
+	maxPiggyback = 16
+	// retransmitMult scales how often every change is piggybacked, per
+	// doubling of the membership.
+	retransmitMult = 3
+	// leaveFanout is how many members a leaving node tells directly.
+	leaveFanout = 3
+)
+
+// State is what a node believes about a member. At the same incarnation
+// a worse state overrides a better one.
+type State uint8
+
+const (
+	StateAlive State = iota
+	StateSuspect
+	StateDead
+	// StateLeft is a member that shut down and said so.
+	StateLeft
+)
+
+func (s State) String() string {
+	switch s {
+	case StateAlive:
+		return "alive"
+	case StateSuspect:
+		return "suspect"
+	case StateDead:
+		return "dead"
+	case StateLeft:
+		return "left"
+	}
+	return fmt.Sprintf("state(%d)", uint8(s))
+}
+
+// rank orders states at the same incarnation. Dead and left members rank
+// the same, so neither overrides the other.
+func (s State) rank() int {
+	return min(int(s), int(StateDead))
+}
+
+// Meta is what a node publishes about itself.
+type Meta struct {
+	// Role is the node's replication or cluster role.
+	Role string
+	// Version is the build version of the server.
+	Version string
+	// ShardID is the node's ID in the shard map, and ShardMapVersion the
+	// version of the map it serves.
+	ShardID         string
+	ShardMapVersion uint64
+}
+
+// Member is what a node believes about a member of the group.
+type Member struct {
+	ID string
+	// Addr is the gRPC address the member is reached at.
+	Addr        string
+	Incarnation uint64
+	State       State
+	Meta        Meta
+	// Since is when this node learned of the member's current state.
+	Since time.Time
+}
+
+// supersedes reports whether m is news over cur: it has a higher
+// incarnation, or the same one and a worse state.
+func (m Member) supersedes(cur Member) bool {
+	if m.Incarnation != cur.Incarnation {
+		return m.Incarnation > cur.Incarnation
+	}
+	return m.State.rank() > cur.State.rank()
+}
+
+// Options configure a Node.
+type Options struct {
+	// ID names the node. Every node needs a different one; a node that
+	// restarts with the same ID takes its place again.
+	ID string
+	// Addr is the gRPC address other nodes reach this one at.
+	Addr string
+	// Seeds are gRPC addresses of nodes to learn the membership from when
+	// this node knows no live member, such as on start. Listing this
+	// node's own address is harmless.
+	Seeds []string
+	// DialOptions are used to connect to other nodes, such as transport
+	// credentials and an API key.
+	DialOptions []grpc.DialOption
+	// Interval is how often to probe a member, and ProbeTimeout how long
+	// to wait for it to answer before asking others to probe it. The
+	// indirect probes get the rest of the interval. Zero uses the
+	// defaults.
+	Interval     time.Duration
+	ProbeTimeout time.Duration
+	// SuspicionTimeout is how long a suspected member has to refute the
+	// suspicion before it is declared dead. Zero uses
+	// DefaultSuspicionTimeout.
+	SuspicionTimeout time.Duration
+	// IndirectChecks is how many members are asked to probe a member that
+	// did not answer. Zero uses DefaultIndirectChecks; negative disables
+	// indirect probes.
+	IndirectChecks int
+	// SyncInterval is how often to exchange the whole membership with a
+	// random member. Zero uses DefaultSyncInterval.
+	SyncInterval time.Duration
+	// ReclaimTimeout is how long dead and left members are kept before
+	// they are forgotten. Zero uses DefaultReclaimTimeout.
+	ReclaimTimeout time.Duration
+	// Meta returns what the node publishes about itself. It is called every
+	// Interval, and a change is gossiped under a new incarnation. Nil
+	// publishes nothing.
+	Meta func() Meta
+}
+
+// Node is one member of the gossip group. It serves the Gossip service for
+// the other members and probes them while Run runs.
+type Node struct {
+	pb.UnimplementedGossipServer
+
+	opts Options
+
+	mu sync.Mutex
+	// members holds every member this node knows of, itself included.
+	members map[string]*Member
+	// broadcasts counts how often each member's latest change was
+	// piggybacked; changes sent often enough are dropped.
+	broadcasts map[string]int
+	// order is the probe order of this round, and next its position.
+	order []string
+	next  int
+	left  bool
+	conns map[string]*grpc.ClientConn
+	rand  *rand.Rand
+}
+
+// New returns a node that joins the group through opts.Seeds once Run is
+// called.
+func New(opts Options) *Node {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	if opts.ProbeTimeout <= 0 {
+		opts.ProbeTimeout = DefaultProbeTimeout
+	}
+	if opts.SuspicionTimeout <= 0 {
+		opts.SuspicionTimeout = DefaultSuspicionTimeout
+	}
+	if opts.IndirectChecks == 0 {
+		opts.IndirectChecks = DefaultIndirectChecks
+	}
+	if opts.SyncInterval <= 0 {
+		opts.SyncInterval = DefaultSyncInterval
+	}
+	if opts.ReclaimTimeout <= 0 {
+		opts.ReclaimTimeout = DefaultReclaimTimeout
+	}
+
+	self := &Member{ID: opts.ID, Addr: opts.Addr, State: StateAlive, Since: time.Now()}
+	if opts.Meta != nil {
+		self.Meta = opts.Meta()
+	}
+	return &Node{
+		opts:       opts,
+		members:    map[string]*Member{opts.ID: self},
+		broadcasts: make(map[string]int),
+		conns:      make(map[string]*grpc.ClientConn),
+		rand:       rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Methods returns the full names of the Gossip RPCs, such as for
+// interceptors to tell them from client requests.
+func Methods() []string {
+	var out []string
+	for _, m := range pb.Gossip_ServiceDesc.Methods {
+		out = append(out, "/"+pb.Gossip_ServiceDesc.ServiceName+"/"+m.MethodName)
+	}
+	return out
+}
+
+// Self returns the node's ID.
+func (n *Node) Self() string {
+	return n.opts.ID
+}
+
+// Members returns every member the node knows of, itself included, sorted
+// by ID.
+func (n *Node) Members() []Member {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	out := make([]Member, 0, len(n.members))
+	for _, m := range n.members {
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// Run probes the members every Interval and syncs with one every
+// SyncInterval until ctx is done. It first joins through the seeds, and
+// does so again whenever the node knows no live member.
+func (n *Node) Run(ctx context.Context) {
+	if !n.join(ctx) && len(n.opts.Seeds) > 0 {
+		slog.Warn("Failed to join the gossip group through any seed, retrying", "seeds", n.opts.Seeds)
+	}
+
+	probes := time.NewTicker(n.opts.Interval)
+	defer probes.Stop()
+	syncs := time.NewTicker(n.opts.SyncInterval)
+	defer syncs.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-probes.C:
+			n.sweep()
+			if !n.probe(ctx) {
+				n.join(ctx)
+			}
+		case <-syncs.C:
+			if target, ok := n.random(1, ""); ok {
+				n.sync(ctx, target[0].Addr)
+			}
+		}
+	}
+}
+
+// Leave tells the group the node is shutting down, so it is not suspected
+// and declared dead. The node no longer refutes suspicions afterwards.
+func (n *Node) Leave(ctx context.Context) {
+	n.mu.Lock()
+	self := n.members[n.opts.ID]
+	self.Incarnation++
+	self.State = StateLeft
+	self.Since = time.Now()
+	n.left = true
+	n.broadcast(self.ID)
+	n.mu.Unlock()
+
+	targets, _ := n.random(leaveFanout, "")
+	var wg sync.WaitGroup
+	for _, m := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n.sync(ctx, m.Addr)
+		}()
+	}
+	wg.Wait()
+}
+
+// Close closes the connections to other members.
+func (n *Node) Close() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for addr, conn := range n.conns {
+		conn.Close()
+		delete(n.conns, addr)
+	}
+}
+
+func (n *Node) Ping(ctx context.Context, req *pb.PingRequest) (*pb.PingResponse, error) {
+	if req.GetTargetId() != n.opts.ID {
+		return nil, status.Errorf(codes.FailedPrecondition, "this is %s, not %s", n.opts.ID, req.GetTargetId())
+	}
+	n.merge(req.GetUpdates())
+	return &pb.PingResponse{Updates: n.piggyback()}, nil
+}
+
+func (n *Node) PingReq(ctx context.Context, req *pb.PingReqRequest) (*pb.PingResponse, error) {
+	n.merge(req.GetUpdates())
+	if err := n.ping(ctx, req.GetTargetId(), req.GetTargetAddr()); err != nil {
+		return nil, status.Errorf(codes.Unavailable, "%s did not answer: %v", req.GetTargetId(), err)
+	}
+	return &pb.PingResponse{Updates: n.piggyback()}, nil
+}
+
+func (n *Node) Sync(ctx context.Context, req *pb.SyncRequest) (*pb.SyncResponse, error) {
+	n.merge(req.GetMembers())
+	return &pb.SyncResponse{Members: n.all()}, nil
+}
+
+// join syncs with every seed and reports whether any answered.
+func (n *Node) join(ctx context.Context) bool {
+	var joined bool
+	for _, addr := range n.opts.Seeds {
+		if addr != n.opts.Addr && n.sync(ctx, addr) == nil {
+			joined = true
+		}
+	}
+	return joined
+}
+
+// probe pings the next member, directly and then through others, and
+// suspects it if neither reached it. It reports false if there was no
+// live member to probe.
+func (n *Node) probe(ctx context.Context) bool {
+	target, ok := n.nextTarget()
+	if !ok {
+		return false
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, n.opts.ProbeTimeout)
+	err := n.ping(pingCtx, target.ID, target.Addr)
+	cancel()
+	if err == nil || ctx.Err() != nil {
+		return true
+	}
+
+	helpers, _ := n.random(n.opts.IndirectChecks, target.ID)
+	if len(helpers) > 0 {
+		indirectCtx, cancel := context.WithTimeout(ctx, n.opts.Interval-n.opts.ProbeTimeout)
+		defer cancel()
+
+		acked := make(chan bool, len(helpers))
+		for _, h := range helpers {
+			go func() {
+				acked <- n.pingReq(indirectCtx, h, target) == nil
+			}()
+		}
+		for range helpers {
+			if <-acked {
+				return true
+			}
+		}
+		if ctx.Err() != nil {
+			return true
+		}
+	}
+
+	n.suspect(target)
+	return true
+}
+
+func (n *Node) ping(ctx context.Context, id, addr string) error {
+	client, err := n.client(addr)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Ping(ctx, &pb.PingRequest{TargetId: id, Updates: n.piggyback()})
+	if err != nil {
+		return err
+	}
+	n.merge(resp.GetUpdates())
+	return nil
+}
+
+func (n *Node) pingReq(ctx context.Context, helper, target Member) error {
+	client, err := n.client(helper.Addr)
+	if err != nil {
+		return err
+	}
+	resp, err := client.PingReq(ctx, &pb.PingReqRequest{
+		TargetId:   target.ID,
+		TargetAddr: target.Addr,
+		Updates:    n.piggyback(),
+	})
+	if err != nil {
+		return err
+	}
+	n.merge(resp.GetUpdates())
+	return nil
+}
+
+// sync exchanges the whole membership with the node at addr.
+func (n *Node) sync(ctx context.Context, addr string) error {
+	client, err := n.client(addr)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, n.opts.Interval)
+	defer cancel()
+
+	resp, err := client.Sync(ctx, &pb.SyncRequest{Members: n.all()})
+	if err != nil {
+		slog.Debug("Gossip sync failed", "addr", addr, "error", err)
+		return err
+	}
+	n.merge(resp.GetMembers())
+	return nil
+}
+
+func (n *Node) client(addr string) (pb.GossipClient, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	conn, ok := n.conns[addr]
+	if !ok {
+		var err error
+		if conn, err = grpc.NewClient(addr, n.opts.DialOptions...); err != nil {
+			return nil, err
+		}
+		n.conns[addr] = conn
+	}
+	return pb.NewGossipClient(conn), nil
+}
+
+// nextTarget returns the next live member to probe, starting a new round
+// in a new random order once every member was probed.
+func (n *Node) nextTarget() (Member, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for restarted := false; ; {
+		if n.next >= len(n.order) {
+			if restarted {
+				return Member{}, false
+			}
+			restarted = true
+			n.order = n.order[:0]
+			for id, m := range n.members {
+				if id != n.opts.ID && m.State.rank() < StateDead.rank() {
+					n.order = append(n.order, id)
+				}
+			}
+			n.rand.Shuffle(len(n.order), func(i, j int) { n.order[i], n.order[j] = n.order[j], n.order[i] })
+			n.next = 0
+			continue
+		}
+
+		m, ok := n.members[n.order[n.next]]
+		n.next++
+		if ok && m.State.rank() < StateDead.rank() {
+			return *m, true
+		}
+	}
+}
+
+// random returns up to k random live members other than this node and
+// exclude. ok is false if there are none.
+func (n *Node) random(k int, exclude string) (_ []Member, ok bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var live []Member
+	for id, m := range n.members {
+		if id != n.opts.ID && id != exclude && m.State.rank() < StateDead.rank() {
+			live = append(live, *m)
+		}
+	}
+	n.rand.Shuffle(len(live), func(i, j int) { live[i], live[j] = live[j], live[i] })
+	return live[:min(k, len(live))], len(live) > 0
+}
+
+// suspect marks target as suspected, unless the node has heard from it
+// since the probe started.
+func (n *Node) suspect(target Member) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	m, ok := n.members[target.ID]
+	if !ok || m.Incarnation != target.Incarnation || m.State != StateAlive {
+		return
+	}
+	m.State = StateSuspect
+	m.Since = time.Now()
+	n.broadcast(m.ID)
+	slog.Warn("Gossip member did not answer probes, suspecting it", "member", m.ID, "addr", m.Addr)
+}
+
+// sweep declares dead the suspected members that did not refute in time,
+// forgets long dead ones, and publishes the node's metadata if it changed.
+func (n *Node) sweep() {
+	var meta Meta
+	if n.opts.Meta != nil {
+		meta = n.opts.Meta()
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	now := time.Now()
+	for id, m := range n.members {
+		switch {
+		case m.State == StateSuspect && now.Sub(m.Since) >= n.opts.SuspicionTimeout:
+			m.State = StateDead
+			m.Since = now
+			n.broadcast(id)
+			slog.Warn("Gossip member declared dead", "member", id, "addr", m.Addr)
+		case m.State.rank() == StateDead.rank() && now.Sub(m.Since) >= n.opts.ReclaimTimeout:
+			delete(n.members, id)
+			delete(n.broadcasts, id)
+			if conn, ok := n.conns[m.Addr]; ok {
+				conn.Close()
+				delete(n.conns, m.Addr)
+			}
+		}
+	}
+
+	self := n.members[n.opts.ID]
+	if n.opts.Meta != nil && !n.left && meta != self.Meta {
+		self.Meta = meta
+		self.Incarnation++
+		n.broadcast(self.ID)
+	}
+}
+
+// merge applies the beliefs of another node.
+func (n *Node) merge(updates []*pb.GossipMember) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	now := time.Now()
+	for _, u := range updates {
+		m := fromProto(u)
+		if m.ID == "" {
+			continue
+		}
+		m.Since = now
+		if m.ID == n.opts.ID {
+			n.refute(m)
+			continue
+		}
+
+		cur, ok := n.members[m.ID]
+		switch {
+		case !ok:
+			// Dead members nobody here knew of need no news.
+			if m.State.rank() >= StateDead.rank() {
+				continue
+			}
+			slog.Info("Gossip member joined", "member", m.ID, "addr", m.Addr, "state", m.State)
+		case !m.supersedes(*cur):
+			continue
+		case m.State != cur.State:
+			logTransition(*cur, m)
+		default:
+			m.Since = cur.Since
+		}
+		n.members[m.ID] = &m
+		n.broadcast(m.ID)
+	}
+}
+
+// refute answers news about the node itself from an earlier incarnation,
+// or a suspicion, by gossiping a higher incarnation. It must be called
+// with mu held.
+func (n *Node) refute(m Member) {
+	self := n.members[n.opts.ID]
+	if n.left || m.Incarnation < self.Incarnation || (m.Incarnation == self.Incarnation && m.State == StateAlive) {
+		return
+	}
+	if m.State != StateAlive {
+		slog.Info("Refuting gossip about this node", "state", m.State, "incarnation", m.Incarnation)
+	}
+	self.Incarnation = m.Incarnation + 1
+	n.broadcast(self.ID)
+}
+
+func logTransition(from, to Member) {
+	switch to.State {
+	case StateAlive:
+		slog.Info("Gossip member is alive", "member", to.ID, "addr", to.Addr, "was", from.State)
+	case StateSuspect:
+		slog.Warn("Gossip member is suspected", "member", to.ID, "addr", to.Addr)
+	case StateDead:
+		slog.Warn("Gossip member is dead", "member", to.ID, "addr", to.Addr)
+	case StateLeft:
+		slog.Info("Gossip member left", "member", to.ID, "addr", to.Addr)
+	}
+}
+
+// broadcast queues the latest change of the member to be piggybacked. It
+// must be called with mu held.
+func (n *Node) broadcast(id string) {
+	n.broadcasts[id] = 0
+}
+
+// piggyback returns the changes least sent so far, counting them as sent
+// once more.
+func (n *Node) piggyback() []*pb.GossipMember {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	ids := make([]string, 0, len(n.broadcasts))
+	for id := range n.broadcasts {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return n.broadcasts[ids[i]] < n.broadcasts[ids[j]] })
+
+	limit := retransmitMult * bits.Len(uint(len(n.members)))
+	var out []*pb.GossipMember
+	for _, id := range ids[:min(maxPiggyback, len(ids))] {
+		out = append(out, n.members[id].Proto())
+		if n.broadcasts[id]++; n.broadcasts[id] >= limit {
+			delete(n.broadcasts, id)
+		}
+	}
+	return out
+}
+
+// all returns every member, for a sync.
+func (n *Node) all() []*pb.GossipMember {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	out := make([]*pb.GossipMember, 0, len(n.members))
+	for _, m := range n.members {
+		out = append(out, m.Proto())
+	}
+	return out
+}
+
+// Proto returns the wire form of m.
+func (m Member) Proto() *pb.GossipMember {
+	return &pb.GossipMember{
+		Id:          m.ID,
+		Addr:        m.Addr,
+		Incarnation: m.Incarnation,
+		State:       pb.MemberState(m.State + 1),
+		Meta: &pb.NodeMeta{
+			Role:            m.Meta.Role,
+			Version:         m.Meta.Version,
+			ShardId:         m.Meta.ShardID,
+			ShardMapVersion: m.Meta.ShardMapVersion,
+		},
+	}
+}
+
+func fromProto(m *pb.GossipMember) Member {
+	state := StateAlive
+	if m.GetState() != pb.MemberState_MEMBER_STATE_UNSPECIFIED {
+		state = State(m.GetState() - 1)
+	}
+	return Member{
+		ID:          m.GetId(),
+		Addr:        m.GetAddr(),
+		Incarnation: m.GetIncarnation(),
+		State:       state,
+		Meta: Meta{
+			Role:            m.GetMeta().GetRole(),
+			Version:         m.GetMeta().GetVersion(),
+			ShardID:         m.GetMeta().GetShardId(),
+			ShardMapVersion: m.GetMeta().GetShardMapVersion(),
+		},
+	}
+}
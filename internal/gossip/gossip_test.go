@@ -0,0 +1,238 @@
+package gossip
+
+import (
+	"context"
+	"kvstore/internal/testnet"
+	pb "kvstore/pkg/pb/api/proto"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+type testNode struct {
+	*Node
+	addr string
+	stop func()
+}
+
+// newNodes starts a node for every ID, all seeded with the first one.
+// Options for a node are taken from opts by ID.
+func newNodes(t *testing.T, n *testnet.Network, opts map[string]Options, ids ...string) []*testNode {
+	t.Helper()
+
+	listeners := make([]net.Listener, len(ids))
+	for i := range ids {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		listeners[i] = ln
+	}
+
+	var nodes []*testNode
+	for i, id := range ids {
+		addr := listeners[i].Addr().String()
+		o := opts[id]
+		o.ID = id
+		o.Addr = addr
+		o.Seeds = []string{listeners[0].Addr().String()}
+		o.Interval = 40 * time.Millisecond
+		o.ProbeTimeout = 15 * time.Millisecond
+		if o.SuspicionTimeout == 0 {
+			o.SuspicionTimeout = 200 * time.Millisecond
+		}
+		o.SyncInterval = 200 * time.Millisecond
+		o.DialOptions = []grpc.DialOption{
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithConnectParams(grpc.ConnectParams{Backoff: backoff.Config{BaseDelay: 10 * time.Millisecond, Multiplier: 1.6, MaxDelay: 50 * time.Millisecond}}),
+			n.Dialer(addr),
+		}
+
+		node := New(o)
+		srv := grpc.NewServer()
+		pb.RegisterGossipServer(srv, node)
+		go srv.Serve(listeners[i])
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			node.Run(ctx)
+		}()
+		var once sync.Once
+		stop := func() {
+			once.Do(func() {
+				cancel()
+				<-done
+				srv.Stop()
+				node.Close()
+			})
+		}
+		t.Cleanup(stop)
+
+		nodes = append(nodes, &testNode{Node: node, addr: addr, stop: stop})
+	}
+	return nodes
+}
+
+// states returns what n believes about every member.
+func states(n *testNode) map[string]State {
+	out := make(map[string]State)
+	for _, m := range n.Members() {
+		out[m.ID] = m.State
+	}
+	return out
+}
+
+// eventually waits until every node in nodes believes want.
+func eventually(t *testing.T, want map[string]State, nodes ...*testNode) {
+	t.Helper()
+
+	require.Eventually(t, func() bool {
+		for _, n := range nodes {
+			if !assert.ObjectsAreEqual(want, states(n)) {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, 10*time.Millisecond, "want %v", want)
+}
+
+// Test nodes learn of each other through a seed and of each other's
+// metadata, including changes to it
+func TestNode_Join(t *testing.T) {
+	var mu sync.Mutex
+	meta := Meta{Role: "primary", Version: "v1", ShardID: "s1", ShardMapVersion: 1}
+	opts := map[string]Options{"a": {Meta: func() Meta {
+		mu.Lock()
+		defer mu.Unlock()
+		return meta
+	}}}
+	nodes := newNodes(t, testnet.New(), opts, "a", "b", "c")
+
+	alive := map[string]State{"a": StateAlive, "b": StateAlive, "c": StateAlive}
+	eventually(t, alive, nodes...)
+	members := nodes[2].Members()
+	assert.Equal(t, "a", members[0].ID)
+	assert.Equal(t, nodes[0].addr, members[0].Addr)
+	assert.Equal(t, meta, members[0].Meta)
+
+	mu.Lock()
+	meta.ShardMapVersion = 2
+	mu.Unlock()
+	require.Eventually(t, func() bool {
+		for _, n := range nodes[1:] {
+			if a := n.Members()[0]; a.Meta.ShardMapVersion != 2 || a.Incarnation == 0 {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+// Test a node that stops answering is suspected and then declared dead,
+// and one that leaves is not
+func TestNode_Failure(t *testing.T) {
+	nodes := newNodes(t, testnet.New(), nil, "a", "b", "c", "d")
+	a, b, c, d := nodes[0], nodes[1], nodes[2], nodes[3]
+	eventually(t, map[string]State{"a": StateAlive, "b": StateAlive, "c": StateAlive, "d": StateAlive}, nodes...)
+
+	c.stop()
+	eventually(t, map[string]State{"a": StateAlive, "b": StateAlive, "c": StateDead, "d": StateAlive}, a, b, d)
+
+	d.Leave(context.Background())
+	d.stop()
+	eventually(t, map[string]State{"a": StateAlive, "b": StateAlive, "c": StateDead, "d": StateLeft}, a, b)
+}
+
+// Test a node cut off from one member still reaches it through the
+// others, and a node cut off from all of them refutes being declared dead
+// once the partition heals
+func TestNode_Partition(t *testing.T) {
+	n := testnet.New()
+	nodes := newNodes(t, n, nil, "a", "b", "c")
+	a, b, c := nodes[0], nodes[1], nodes[2]
+	alive := map[string]State{"a": StateAlive, "b": StateAlive, "c": StateAlive}
+	eventually(t, alive, nodes...)
+
+	n.Partition(a.addr, c.addr)
+	time.Sleep(400 * time.Millisecond)
+	assert.Equal(t, alive, states(a), "b probes c for a")
+	assert.Equal(t, alive, states(c))
+
+	n.Partition(c.addr, b.addr)
+	eventually(t, map[string]State{"a": StateAlive, "b": StateAlive, "c": StateDead}, a, b)
+
+	n.HealAll()
+	eventually(t, alive, nodes...)
+	for _, node := range nodes {
+		assert.NotZero(t, node.Members()[2].Incarnation, "c refuted its death")
+	}
+}
+
+// Test newer incarnations and, at the same incarnation, worse states win,
+// and a node refutes news of its own failure
+func TestNode_Merge(t *testing.T) {
+	n := New(Options{ID: "self", Addr: "self:1"})
+	member := func(id string, incarnation uint64, state State) *pb.GossipMember {
+		return Member{ID: id, Addr: id + ":1", Incarnation: incarnation, State: state}.Proto()
+	}
+	get := func(id string) (uint64, State) {
+		for _, m := range n.Members() {
+			if m.ID == id {
+				return m.Incarnation, m.State
+			}
+		}
+		return 0, State(255)
+	}
+
+	n.merge([]*pb.GossipMember{member("gone", 3, StateDead)})
+	_, state := get("gone")
+	assert.Equal(t, State(255), state, "unknown dead members are ignored")
+
+	steps := []struct {
+		update      *pb.GossipMember
+		incarnation uint64
+		state       State
+	}{
+		{member("x", 1, StateAlive), 1, StateAlive},
+		{member("x", 1, StateSuspect), 1, StateSuspect},
+		{member("x", 1, StateAlive), 1, StateSuspect},
+		{member("x", 0, StateDead), 1, StateSuspect},
+		{member("x", 2, StateAlive), 2, StateAlive},
+		{member("x", 2, StateLeft), 2, StateLeft},
+		{member("x", 2, StateDead), 2, StateLeft},
+		{member("x", 3, StateAlive), 3, StateAlive},
+	}
+	for i, step := range steps {
+		n.merge([]*pb.GossipMember{step.update})
+		incarnation, state := get("x")
+		assert.Equal(t, step.incarnation, incarnation, "step %d", i)
+		assert.Equal(t, step.state, state, "step %d", i)
+	}
+
+	n.merge([]*pb.GossipMember{member("self", 0, StateSuspect)})
+	incarnation, state := get("self")
+	assert.Equal(t, uint64(1), incarnation)
+	assert.Equal(t, StateAlive, state)
+	n.merge([]*pb.GossipMember{member("self", 4, StateDead)})
+	incarnation, _ = get("self")
+	assert.Equal(t, uint64(5), incarnation)
+
+	_, err := n.Ping(context.Background(), &pb.PingRequest{TargetId: "other"})
+	assert.Error(t, err, "a ping meant for another node fails")
+}
+
+// Test every Gossip RPC is listed by its full name
+func TestMethods(t *testing.T) {
+	assert.ElementsMatch(t, []string{
+		"/" + pb.Gossip_ServiceDesc.ServiceName + "/Ping",
+		"/" + pb.Gossip_ServiceDesc.ServiceName + "/PingReq",
+		"/" + pb.Gossip_ServiceDesc.ServiceName + "/Sync",
+	}, Methods())
+}
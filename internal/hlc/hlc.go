@@ -0,0 +1,121 @@
+// Package hlc implements hybrid logical clocks. A timestamp pairs the
+// physical time with a logical counter, so that timestamps stay close to
+// wall-clock time while still ordering every event after the events its
+// node has observed, even when the node's own clock is behind.
+//
+// A node stamps its events with Now, and passes the timestamps it receives
+// from other nodes to Update. An event that happened after another one was
+// observed then always has the higher timestamp; concurrent events are
+// ordered by physical time, as well as the clocks agree.
+package hlc
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Timestamp is a point in hybrid logical time. The zero Timestamp is before
+// every other one.
+type Timestamp struct {
+	// Wall is physical time in nanoseconds since the Unix epoch.
+	Wall int64 `json:"wall"`
+	// Logical orders timestamps with the same Wall.
+	Logical uint32 `json:"logical,omitempty"`
+}
+
+// Compare returns -1, 0 or +1 as t is before, equal to or after u.
+func (t Timestamp) Compare(u Timestamp) int {
+	if c := cmp.Compare(t.Wall, u.Wall); c != 0 {
+		return c
+	}
+	return cmp.Compare(t.Logical, u.Logical)
+}
+
+func (t Timestamp) IsZero() bool {
+	return t == Timestamp{}
+}
+
+// Time returns the physical part of t.
+func (t Timestamp) Time() time.Time {
+	return time.Unix(0, t.Wall)
+}
+
+func (t Timestamp) String() string {
+	return fmt.Sprintf("%s+%d", t.Time().UTC().Format(time.RFC3339Nano), t.Logical)
+}
+
+// ErrClockOffset is returned by Update for timestamps too far ahead of the
+// local clock.
+var ErrClockOffset = errors.New("hlc: timestamp is too far ahead of the local clock")
+
+// Clock hands out increasing timestamps. It is safe for concurrent use.
+type Clock struct {
+	now       func() time.Time
+	maxOffset time.Duration
+
+	mu   sync.Mutex
+	last Timestamp
+}
+
+type ClockOption func(*Clock)
+
+// WithMaxOffset makes Update reject timestamps more than d ahead of the
+// local physical time. Without it, a node whose clock runs far ahead drags
+// every clock it talks to along, and their writes would all lose to its
+// own until the physical time catches up.
+func WithMaxOffset(d time.Duration) ClockOption {
+	return func(c *Clock) {
+		c.maxOffset = d
+	}
+}
+
+// NewClock returns a clock reading physical time from now, or time.Now if
+// nil.
+func NewClock(now func() time.Time, opts ...ClockOption) *Clock {
+	if now == nil {
+		now = time.Now
+	}
+	c := &Clock{now: now}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Now returns a timestamp after every one returned or observed before.
+func (c *Clock) Now() Timestamp {
+	wall := c.now().UnixNano()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if wall > c.last.Wall {
+		c.last = Timestamp{Wall: wall}
+	} else {
+		c.last.Logical++
+	}
+	return c.last
+}
+
+// Update observes a timestamp received from another node, so that every
+// later Now is after it. It fails with ErrClockOffset, leaving the clock
+// as it is, if t is further ahead than the maximum offset.
+func (c *Clock) Update(t Timestamp) error {
+	if c.maxOffset > 0 {
+		if wall := c.now(); t.Wall > wall.Add(c.maxOffset).UnixNano() {
+			return fmt.Errorf("%w: %s is %s ahead, at most %s is allowed",
+				ErrClockOffset, t, t.Time().Sub(wall), c.maxOffset)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if t.Compare(c.last) > 0 {
+		c.last = t
+	}
+	return nil
+}
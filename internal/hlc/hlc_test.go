@@ -0,0 +1,61 @@
+package hlc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test timestamps keep increasing while the physical clock stands still or
+// goes back
+func TestClock_Now(t *testing.T) {
+	wall := time.Unix(100, 0)
+	c := NewClock(func() time.Time { return wall })
+
+	a := c.Now()
+	assert.Equal(t, Timestamp{Wall: wall.UnixNano()}, a)
+	b := c.Now()
+	assert.Equal(t, 1, b.Compare(a))
+	assert.Equal(t, Timestamp{Wall: wall.UnixNano(), Logical: 1}, b)
+
+	wall = wall.Add(-time.Second)
+	assert.Equal(t, 1, c.Now().Compare(b))
+
+	wall = wall.Add(time.Minute)
+	assert.Equal(t, Timestamp{Wall: wall.UnixNano()}, c.Now())
+}
+
+// Test a clock that is behind stamps events after the ones it observed
+func TestClock_Update(t *testing.T) {
+	ahead := NewClock(func() time.Time { return time.Unix(200, 0) })
+	behind := NewClock(func() time.Time { return time.Unix(100, 0) })
+
+	seen := ahead.Now()
+	require.NoError(t, behind.Update(seen))
+	assert.Equal(t, 1, behind.Now().Compare(seen))
+
+	// Older timestamps change nothing
+	last := behind.Now()
+	require.NoError(t, behind.Update(Timestamp{Wall: 1}))
+	assert.Equal(t, 1, behind.Now().Compare(last))
+
+	assert.True(t, Timestamp{}.IsZero())
+	assert.Equal(t, -1, Timestamp{}.Compare(seen))
+	assert.Equal(t, "1970-01-01T00:03:20Z+0", seen.String())
+}
+
+// Test timestamps further ahead than the maximum offset are rejected
+func TestClock_MaxOffset(t *testing.T) {
+	wall := time.Unix(100, 0)
+	c := NewClock(func() time.Time { return wall }, WithMaxOffset(time.Second))
+
+	require.NoError(t, c.Update(Timestamp{Wall: wall.Add(time.Second).UnixNano()}))
+	err := c.Update(Timestamp{Wall: wall.Add(time.Hour).UnixNano()})
+	require.ErrorIs(t, err, ErrClockOffset)
+	assert.Equal(t, wall.Add(time.Second).UnixNano(), c.Now().Wall, "the clock stays where it was")
+
+	wall = wall.Add(time.Hour)
+	assert.NoError(t, c.Update(Timestamp{Wall: wall.UnixNano()}), "accepted once the local clock catches up")
+}
@@ -0,0 +1,160 @@
+// Package merkle summarizes the records of a namespace as a Merkle tree over
+// key ranges, so that two copies of it can find the ranges where they
+// differ by exchanging hashes rather than records.
+//
+// A key belongs to the range numbered by the top Depth bits of the 64-bit
+// FNV-1a hash of its bytes, so a tree of depth d has 2^d ranges. The hash of
+// a range is the SHA-256 of its records in key order, and an inner node
+// hashes its two children. Empty ranges and subtrees hash to nil, which
+// keeps sparse trees cheap to compare.
+package merkle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"kvstore/internal/storage"
+	"sort"
+)
+
+// DefaultDepth splits the key space into 1024 ranges.
+const DefaultDepth = 10
+
+// MaxDepth bounds the size of a tree, which holds 2^(depth+1) hashes.
+const MaxDepth = 16
+
+// ErrDepth is returned for depths outside [0, MaxDepth] and for trees of
+// different depths.
+var ErrDepth = errors.New("merkle: invalid depth")
+
+// Tree is a Merkle tree over the key ranges of one namespace.
+type Tree struct {
+	Depth int
+	// nodes is a binary heap: the root is nodes[1] and the children of
+	// nodes[i] are nodes[2i] and nodes[2i+1], so range r is at
+	// nodes[2^Depth+r].
+	nodes [][]byte
+}
+
+// Range returns the range key belongs to in a tree of depth.
+func Range(key string, depth int) int {
+	if depth == 0 {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return int(h.Sum64() >> (64 - depth))
+}
+
+// Bounds returns the first and last key hash of range r in a tree of depth.
+func Bounds(r, depth int) (first, last uint64) {
+	if depth == 0 {
+		return 0, ^uint64(0)
+	}
+	first = uint64(r) << (64 - depth)
+	return first, first | (^uint64(0) >> depth)
+}
+
+// Build returns the tree of depth over records, which may be in any order.
+func Build(depth int, records []storage.Record) (*Tree, error) {
+	if depth < 0 || depth > MaxDepth {
+		return nil, fmt.Errorf("%w: %d", ErrDepth, depth)
+	}
+
+	sorted := make([]storage.Record, len(records))
+	copy(sorted, records)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Key < sorted[j].Key })
+
+	ranges := make([][]storage.Record, 1<<depth)
+	for _, r := range sorted {
+		i := Range(r.Key, depth)
+		ranges[i] = append(ranges[i], r)
+	}
+
+	leaves := make([][]byte, len(ranges))
+	for i, records := range ranges {
+		if len(records) == 0 {
+			continue
+		}
+		h := sha256.New()
+		for _, r := range records {
+			writeRecord(h, r)
+		}
+		leaves[i] = h.Sum(nil)
+	}
+	return FromLeaves(depth, leaves)
+}
+
+// FromLeaves rebuilds a tree from the hashes of its ranges, as returned by
+// Leaves.
+func FromLeaves(depth int, leaves [][]byte) (*Tree, error) {
+	if depth < 0 || depth > MaxDepth {
+		return nil, fmt.Errorf("%w: %d", ErrDepth, depth)
+	}
+	if len(leaves) != 1<<depth {
+		return nil, fmt.Errorf("merkle: a tree of depth %d has %d ranges, got %d", depth, 1<<depth, len(leaves))
+	}
+
+	t := &Tree{Depth: depth, nodes: make([][]byte, 2<<depth)}
+	copy(t.nodes[1<<depth:], leaves)
+	for i := 1<<depth - 1; i >= 1; i-- {
+		left, right := t.nodes[2*i], t.nodes[2*i+1]
+		if len(left) == 0 && len(right) == 0 {
+			continue
+		}
+		h := sha256.New()
+		h.Write(left)
+		h.Write(right)
+		t.nodes[i] = h.Sum(nil)
+	}
+	return t, nil
+}
+
+// Root returns the hash of the whole tree, nil if it holds no records.
+func (t *Tree) Root() []byte {
+	return t.nodes[1]
+}
+
+// Leaves returns the hashes of the ranges, in order.
+func (t *Tree) Leaves() [][]byte {
+	return t.nodes[1<<t.Depth:]
+}
+
+// Diff returns the ranges whose hashes differ between a and b, in order. It
+// only descends into subtrees whose hashes differ.
+func Diff(a, b *Tree) ([]int, error) {
+	if a.Depth != b.Depth {
+		return nil, fmt.Errorf("%w: comparing trees of depth %d and %d", ErrDepth, a.Depth, b.Depth)
+	}
+
+	var ranges []int
+	var walk func(i int)
+	walk = func(i int) {
+		if bytes.Equal(a.nodes[i], b.nodes[i]) {
+			return
+		}
+		if i >= 1<<a.Depth {
+			ranges = append(ranges, i-1<<a.Depth)
+			return
+		}
+		walk(2 * i)
+		walk(2*i + 1)
+	}
+	walk(1)
+	return ranges, nil
+}
+
+// writeRecord writes every field of r, each prefixed by its length so that
+// different records never encode alike.
+func writeRecord(w io.Writer, r storage.Record) {
+	var buf [binary.MaxVarintLen64]byte
+	for _, field := range []string{r.Key, r.Value, r.Owner} {
+		w.Write(buf[:binary.PutUvarint(buf[:], uint64(len(field)))])
+		w.Write([]byte(field))
+	}
+	w.Write(buf[:binary.PutVarint(buf[:], r.ExpiresAt)])
+}
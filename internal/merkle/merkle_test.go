@@ -0,0 +1,94 @@
+package merkle
+
+import (
+	"fmt"
+	"kvstore/internal/storage"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func records(n int) []storage.Record {
+	var out []storage.Record
+	for i := range n {
+		out = append(out, storage.Record{Key: fmt.Sprintf("key-%d", i), Value: "v"})
+	}
+	return out
+}
+
+// Test trees over the same records match regardless of their order
+func TestBuild(t *testing.T) {
+	rs := records(100)
+	a, err := Build(4, rs)
+	require.NoError(t, err)
+	reversed := make([]storage.Record, len(rs))
+	for i, r := range rs {
+		reversed[len(rs)-1-i] = r
+	}
+	b, err := Build(4, reversed)
+	require.NoError(t, err)
+
+	assert.Equal(t, a.Root(), b.Root())
+	assert.Len(t, a.Leaves(), 16)
+	diff, err := Diff(a, b)
+	require.NoError(t, err)
+	assert.Empty(t, diff)
+
+	empty, err := Build(4, nil)
+	require.NoError(t, err)
+	assert.Nil(t, empty.Root())
+
+	_, err = Build(MaxDepth+1, nil)
+	assert.ErrorIs(t, err, ErrDepth)
+	_, err = Diff(a, empty)
+	assert.NoError(t, err)
+	_, err = Diff(a, &Tree{Depth: 3})
+	assert.ErrorIs(t, err, ErrDepth)
+}
+
+// Test every changed, added or removed record is found in its range
+func TestDiff(t *testing.T) {
+	rs := records(1000)
+	before, err := Build(DefaultDepth, rs)
+	require.NoError(t, err)
+
+	changed := records(1000)
+	changed[3].Value = "changed"
+	changed[7].ExpiresAt = 1
+	changed[11].Owner = "ci"
+	changed = append(changed[:20], changed[21:]...)
+	changed = append(changed, storage.Record{Key: "added"})
+	after, err := Build(DefaultDepth, changed)
+	require.NoError(t, err)
+
+	want := map[int]bool{}
+	for _, key := range []string{"key-3", "key-7", "key-11", "key-20", "added"} {
+		want[Range(key, DefaultDepth)] = true
+	}
+	diff, err := Diff(before, after)
+	require.NoError(t, err)
+	assert.Len(t, diff, len(want))
+	for _, r := range diff {
+		assert.True(t, want[r], "range %d differs", r)
+	}
+
+	rebuilt, err := FromLeaves(DefaultDepth, after.Leaves())
+	require.NoError(t, err)
+	assert.Equal(t, after.Root(), rebuilt.Root())
+	_, err = FromLeaves(DefaultDepth, after.Leaves()[1:])
+	assert.Error(t, err)
+}
+
+// Test ranges cover the hash space in order
+func TestBounds(t *testing.T) {
+	first, last := Bounds(0, 0)
+	assert.Equal(t, uint64(0), first)
+	assert.Equal(t, ^uint64(0), last)
+
+	_, last = Bounds(0, 2)
+	first, _ = Bounds(1, 2)
+	assert.Equal(t, last+1, first)
+	_, last = Bounds(3, 2)
+	assert.Equal(t, ^uint64(0), last)
+}
@@ -0,0 +1,430 @@
+// Package proxy serves the KVStore API in front of a sharded deployment, for
+// clients that cannot route keys to their shard themselves.
+package proxy
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"kvstore/internal/auth"
+	"kvstore/internal/redirect"
+	"kvstore/internal/shard"
+	pb "kvstore/pkg/pb/api/proto"
+	"log/slog"
+	"slices"
+	"strconv"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// maxHops bounds how many redirects a request follows, in case nodes
+// disagree about where a key belongs.
+const maxHops = 3
+
+// Proxy sends each request to the node that owns its key according to the
+// shard map, and fans List out to every node. It learns the map from the
+// nodes and refreshes it whenever a node reports a newer one.
+//
+// Callers authenticate to the nodes rather than to the proxy: their
+// credentials are passed through with every request.
+type Proxy struct {
+	pb.UnimplementedKVStoreServer
+
+	seeds    []string
+	dialOpts []grpc.DialOption
+	// secure is whether nodes are dialled over TLS, which callers'
+	// credentials are only passed on over.
+	secure bool
+
+	mu      sync.RWMutex
+	current *shard.Map
+	// target is the map of a migration in progress, nil otherwise.
+	target *shard.Map
+
+	// refreshMu lets one refresh run at a time, so a burst of redirects
+	// fetches the map once.
+	refreshMu sync.Mutex
+
+	connMu sync.Mutex
+	conns  map[string]*grpc.ClientConn
+}
+
+// New returns a proxy that first asks the nodes at seeds for the shard map
+// and dials nodes with creds and dialOpts. Refresh must succeed before it
+// routes requests.
+func New(seeds []string, creds credentials.TransportCredentials, dialOpts ...grpc.DialOption) *Proxy {
+	return &Proxy{
+		seeds:    seeds,
+		dialOpts: append([]grpc.DialOption{grpc.WithTransportCredentials(creds)}, dialOpts...),
+		secure:   creds.Info().SecurityProtocol != "insecure",
+		conns:    make(map[string]*grpc.ClientConn),
+	}
+}
+
+// Maps returns the shard map requests are routed by and the map of a
+// migration in progress, if any.
+func (p *Proxy) Maps() (current, target *shard.Map) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.current, p.target
+}
+
+// Refresh fetches the shard map from the seeds and the nodes already known.
+func (p *Proxy) Refresh(ctx context.Context) error {
+	p.refreshMu.Lock()
+	defer p.refreshMu.Unlock()
+	return p.refreshLocked(ctx, 0)
+}
+
+// refresh fetches the shard map unless the proxy already knows version or a
+// later one.
+func (p *Proxy) refresh(ctx context.Context, version uint64) error {
+	p.refreshMu.Lock()
+	defer p.refreshMu.Unlock()
+
+	if current, _ := p.Maps(); current != nil && current.Version >= version {
+		return nil
+	}
+	return p.refreshLocked(ctx, version)
+}
+
+// refreshLocked asks the known nodes in turn for their shard map until one
+// has at least version, keeping the latest map seen. Nodes switch to a new
+// map one at a time, so some may still report an older one.
+func (p *Proxy) refreshLocked(ctx context.Context, version uint64) error {
+	var errs []error
+	for _, addr := range p.addrs() {
+		resp, err := p.getShardMap(ctx, addr)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		current, err := shard.MapFromProto(resp.GetShardMap())
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		var target *shard.Map
+		if resp.GetTarget() != nil {
+			if target, err = shard.MapFromProto(resp.GetTarget()); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+
+		p.mu.Lock()
+		if p.current == nil || current.Version >= p.current.Version {
+			if p.current == nil || current.Version > p.current.Version {
+				slog.Info("Shard map updated", "version", current.Version, "from", addr)
+			}
+			p.current, p.target = current, target
+		}
+		latest := p.current.Version
+		p.mu.Unlock()
+
+		if latest >= version {
+			return nil
+		}
+	}
+
+	if current, _ := p.Maps(); current != nil {
+		return nil
+	}
+	if len(errs) == 0 {
+		return errors.New("no nodes to load the shard map from")
+	}
+	return fmt.Errorf("no node returned a shard map, last error: %w", errs[len(errs)-1])
+}
+
+func (p *Proxy) getShardMap(ctx context.Context, addr string) (*pb.GetShardMapResponse, error) {
+	c, err := p.client(addr)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetShardMap(ctx, &pb.GetShardMapRequest{})
+}
+
+// addrs returns the seeds followed by the nodes of the known maps, each once.
+func (p *Proxy) addrs() []string {
+	addrs := slices.Clone(p.seeds)
+	current, target := p.Maps()
+	for _, m := range []*shard.Map{current, target} {
+		if m == nil {
+			continue
+		}
+		for _, n := range m.Nodes {
+			if !slices.Contains(addrs, n.Addr) {
+				addrs = append(addrs, n.Addr)
+			}
+		}
+	}
+	return addrs
+}
+
+func (p *Proxy) client(addr string) (pb.KVStoreClient, error) {
+	p.connMu.Lock()
+	defer p.connMu.Unlock()
+
+	conn, ok := p.conns[addr]
+	if !ok {
+		var err error
+		if conn, err = grpc.NewClient(addr, p.dialOpts...); err != nil {
+			return nil, status.Errorf(codes.Unavailable, "failed to dial %s: %v", addr, err)
+		}
+		p.conns[addr] = conn
+	}
+	return pb.NewKVStoreClient(conn), nil
+}
+
+func (p *Proxy) Close() error {
+	p.connMu.Lock()
+	defer p.connMu.Unlock()
+
+	for addr, conn := range p.conns {
+		conn.Close()
+		delete(p.conns, addr)
+	}
+	return nil
+}
+
+func (p *Proxy) Get(ctx context.Context, req *pb.GetRequest) (resp *pb.GetResponse, err error) {
+	err = p.forward(ctx, req.GetKey(), func(ctx context.Context, c pb.KVStoreClient) error {
+		resp, err = c.Get(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+func (p *Proxy) Set(ctx context.Context, req *pb.SetRequest) (resp *pb.SetResponse, err error) {
+	err = p.forward(ctx, req.GetKey(), func(ctx context.Context, c pb.KVStoreClient) error {
+		resp, err = c.Set(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+func (p *Proxy) Delete(ctx context.Context, req *pb.DeleteRequest) (resp *pb.DeleteResponse, err error) {
+	err = p.forward(ctx, req.GetKey(), func(ctx context.Context, c pb.KVStoreClient) error {
+		resp, err = c.Delete(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+func (p *Proxy) Increment(ctx context.Context, req *pb.IncrementRequest) (resp *pb.IncrementResponse, err error) {
+	err = p.forward(ctx, req.GetKey(), func(ctx context.Context, c pb.KVStoreClient) error {
+		resp, err = c.Increment(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+func (p *Proxy) AddMembers(ctx context.Context, req *pb.MembersRequest) (resp *pb.MembersResponse, err error) {
+	err = p.forward(ctx, req.GetKey(), func(ctx context.Context, c pb.KVStoreClient) error {
+		resp, err = c.AddMembers(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+func (p *Proxy) RemoveMembers(ctx context.Context, req *pb.MembersRequest) (resp *pb.MembersResponse, err error) {
+	err = p.forward(ctx, req.GetKey(), func(ctx context.Context, c pb.KVStoreClient) error {
+		resp, err = c.RemoveMembers(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+// List asks every node for its first matching keys up to the limit and
+// merges them in key order, which yields the first keys of the whole
+// deployment. While a migration runs it also asks the nodes only in the
+// target map, and a key being handed over is listed once. The response
+// carries no index, as the nodes do not share a log.
+func (p *Proxy) List(ctx context.Context, req *pb.ListRequest) (*pb.ListResponse, error) {
+	current, target := p.Maps()
+	if current == nil {
+		return nil, status.Error(codes.Unavailable, "shard map is not loaded yet")
+	}
+
+	nodes := slices.Clone(current.Nodes)
+	if target != nil {
+		for _, n := range target.Nodes {
+			if _, ok := current.Node(n.ID); !ok {
+				nodes = append(nodes, n)
+			}
+		}
+	}
+
+	ctx, err := p.outgoing(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resps := make([]*pb.ListResponse, len(nodes))
+	errs := make([]error, len(nodes))
+	var wg sync.WaitGroup
+	for i, n := range nodes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, err := p.client(n.Addr)
+			if err == nil {
+				resps[i], err = c.List(ctx, req)
+			}
+			if err != nil {
+				st := status.Convert(err)
+				errs[i] = status.Errorf(st.Code(), "failed to list keys on %s: %s", n.ID, st.Message())
+			}
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &pb.ListResponse{Pairs: merge(resps, int(req.GetLimit()))}, nil
+}
+
+// merge merges the pairs of resps, each sorted by key, into the first limit
+// pairs in key order, or all of them if limit is zero. A key listed by
+// several nodes is kept once.
+func merge(resps []*pb.ListResponse, limit int) []*pb.KeyValuePair {
+	h := make(pairHeap, 0, len(resps))
+	for _, resp := range resps {
+		if pairs := resp.GetPairs(); len(pairs) > 0 {
+			h = append(h, pairs)
+		}
+	}
+	heap.Init(&h)
+
+	var out []*pb.KeyValuePair
+	for h.Len() > 0 && (limit <= 0 || len(out) < limit) {
+		pair := h[0][0]
+		if n := len(out); n == 0 || out[n-1].GetKey() != pair.GetKey() {
+			out = append(out, pair)
+		}
+		if h[0] = h[0][1:]; len(h[0]) == 0 {
+			heap.Pop(&h)
+		} else {
+			heap.Fix(&h, 0)
+		}
+	}
+	return out
+}
+
+// pairHeap orders the pairs left of each node by their first key.
+type pairHeap [][]*pb.KeyValuePair
+
+func (h pairHeap) Len() int           { return len(h) }
+func (h pairHeap) Less(i, j int) bool { return h[i][0].GetKey() < h[j][0].GetKey() }
+func (h pairHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *pairHeap) Push(x any)        { *h = append(*h, x.([]*pb.KeyValuePair)) }
+func (h *pairHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// GetShardMap returns the maps the proxy routes by. No node answered, so
+// node_id is empty.
+func (p *Proxy) GetShardMap(ctx context.Context, req *pb.GetShardMapRequest) (*pb.GetShardMapResponse, error) {
+	current, target := p.Maps()
+	if current == nil {
+		return nil, status.Error(codes.Unavailable, "shard map is not loaded yet")
+	}
+
+	resp := &pb.GetShardMapResponse{ShardMap: current.Proto()}
+	if target != nil {
+		resp.Target = target.Proto()
+	}
+	return resp, nil
+}
+
+// forward calls fn with a client of the node owning key, following
+// redirects. A WRONG_SHARD redirect means the proxy's shard map is out of
+// date, so it is refreshed before retrying. Redirects are only followed to
+// nodes of the shard map, as the caller's credentials go along.
+func (p *Proxy) forward(ctx context.Context, key string, fn func(context.Context, pb.KVStoreClient) error) error {
+	current, _ := p.Maps()
+	if current == nil {
+		return status.Error(codes.Unavailable, "shard map is not loaded yet")
+	}
+
+	ctx, err := p.outgoing(ctx)
+	if err != nil {
+		return err
+	}
+	addr, hopCtx := current.Owner(key).Addr, ctx
+	for hop := 0; ; hop++ {
+		c, err := p.client(addr)
+		if err != nil {
+			return err
+		}
+		err = fn(hopCtx, c)
+
+		info, ok := redirect.Info(err)
+		if err == nil || !ok || info.GetMetadata()[redirect.AddrKey] == "" || hop == maxHops {
+			return err
+		}
+
+		from := addr
+		addr, hopCtx = info.GetMetadata()[redirect.AddrKey], ctx
+		switch info.GetReason() {
+		case redirect.ReasonWrongShard:
+			version, _ := strconv.ParseUint(info.GetMetadata()["shard_map_version"], 10, 64)
+			if err := p.refresh(ctx, version); err != nil {
+				slog.Warn("Failed to refresh the shard map", "error", err)
+			}
+		case redirect.ReasonAsk:
+			hopCtx = metadata.AppendToOutgoingContext(ctx, redirect.AskingHeader, "true")
+		}
+		if !p.known(addr) {
+			slog.Warn("Refusing to follow a redirect to a node outside the shard map", "from", from, "to", addr)
+			return status.Errorf(codes.Unavailable, "node at %s redirected to %s, which is not in the shard map", from, addr)
+		}
+	}
+}
+
+// known reports whether addr is the address of a node in the shard map or
+// the map migrated to.
+func (p *Proxy) known(addr string) bool {
+	current, target := p.Maps()
+	for _, m := range []*shard.Map{current, target} {
+		if m == nil {
+			continue
+		}
+		for _, n := range m.Nodes {
+			if n.Addr == addr {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// outgoing passes the caller's credentials on to the nodes. It refuses to
+// send them without TLS, as they could be read off the network and
+// replayed.
+func (p *Proxy) outgoing(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var kv []string
+	for _, key := range []string{auth.AuthorizationKey, auth.APIKeyKey} {
+		for _, v := range md.Get(key) {
+			kv = append(kv, key, v)
+		}
+	}
+	if len(kv) > 0 && !p.secure {
+		slog.Warn("Refusing to pass credentials on to nodes without TLS")
+		return nil, status.Error(codes.Unauthenticated, "credentials are not passed on to nodes without TLS")
+	}
+	return metadata.AppendToOutgoingContext(ctx, kv...), nil
+}
@@ -0,0 +1,216 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"kvstore/internal/auth"
+	"kvstore/internal/redirect"
+	"kvstore/internal/server"
+	"kvstore/internal/shard"
+	"kvstore/internal/storage"
+	pb "kvstore/pkg/pb/api/proto"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var creds = grpc.WithTransportCredentials(insecure.NewCredentials())
+
+type node struct {
+	id         string
+	addr       string
+	namespaces *storage.Namespaces
+	migrator   *shard.Migrator
+}
+
+// serve starts node id on ln with shard map m. Listeners are opened first,
+// as the map names every node's address.
+func serve(t *testing.T, id string, ln net.Listener, m *shard.Map) *node {
+	t.Helper()
+
+	n := &node{id: id, addr: ln.Addr().String(), namespaces: storage.NewNamespaces()}
+	router := shard.NewRouter(id, m)
+	importer := shard.NewGRPCImporter(creds)
+	n.migrator = shard.NewMigrator(router, n.namespaces, importer, shard.WithRetryInterval(10*time.Millisecond))
+
+	srv := grpc.NewServer()
+	pb.RegisterKVStoreServer(srv, server.New(n.namespaces, server.WithShards(router)))
+	pb.RegisterAdminServer(srv, server.NewAdmin(server.WithMigrator(n.migrator)))
+	go srv.Serve(ln)
+	t.Cleanup(func() {
+		n.migrator.Close()
+		srv.Stop()
+		importer.Close()
+	})
+	return n
+}
+
+func listen(t *testing.T) net.Listener {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	return ln
+}
+
+// Test the proxy routes keys to their shard, merges List across shards and
+// follows the shard map as keys move to a new node
+func TestProxy(t *testing.T) {
+	ctx := context.Background()
+	lnA, lnB, lnC := listen(t), listen(t), listen(t)
+	from, err := shard.NewMap(1, []shard.Node{{ID: "a", Addr: lnA.Addr().String()}, {ID: "b", Addr: lnB.Addr().String()}}, 16)
+	require.NoError(t, err)
+	to, err := shard.NewMap(2, append(from.Nodes, shard.Node{ID: "c", Addr: lnC.Addr().String()}), 16)
+	require.NoError(t, err)
+	nodes := []*node{serve(t, "a", lnA, from), serve(t, "b", lnB, from), serve(t, "c", lnC, from)}
+
+	p := New([]string{nodes[0].addr}, insecure.NewCredentials())
+	defer p.Close()
+	_, err = p.Get(ctx, &pb.GetRequest{Key: "key"})
+	assert.Error(t, err, "nothing is routed before the map is loaded")
+	require.NoError(t, p.Refresh(ctx))
+
+	for i := range 100 {
+		key := fmt.Sprintf("key-%02d", i)
+		resp, err := p.Set(ctx, &pb.SetRequest{Key: key, Value: key})
+		require.NoError(t, err)
+		require.True(t, resp.GetSuccess())
+	}
+	for _, n := range nodes[:2] {
+		usage := n.namespaces.Default().Usage()
+		assert.InDelta(t, 50, usage.Keys, 30, "node %s holds its share of keys", n.id)
+	}
+
+	list, err := p.List(ctx, &pb.ListRequest{})
+	require.NoError(t, err)
+	require.Len(t, list.GetPairs(), 100)
+	assert.Equal(t, "key-00", list.GetPairs()[0].GetKey())
+	limit := int32(10)
+	list, err = p.List(ctx, &pb.ListRequest{Limit: &limit})
+	require.NoError(t, err)
+	require.Len(t, list.GetPairs(), 10)
+	for i, pair := range list.GetPairs() {
+		assert.Equal(t, fmt.Sprintf("key-%02d", i), pair.GetKey(), "the first keys of every node are listed in order")
+	}
+
+	// Add node c; the proxy keeps routing by the old map until a node
+	// reports the new one
+	for _, n := range []*node{nodes[2], nodes[0], nodes[1]} {
+		require.NoError(t, n.migrator.Begin(from, to))
+	}
+	require.NoError(t, p.Refresh(ctx))
+	current, target := p.Maps()
+	assert.EqualValues(t, 1, current.Version)
+	assert.EqualValues(t, 2, target.Version)
+
+	for i := range 100 {
+		key := fmt.Sprintf("key-%02d", i)
+		resp, err := p.Get(ctx, &pb.GetRequest{Key: key})
+		require.NoError(t, err)
+		assert.Equal(t, key, resp.GetValue(), "%s is readable while moving", key)
+	}
+	for _, n := range nodes {
+		require.Eventually(t, func() bool { return n.migrator.Status().State == shard.MigrationDone }, 5*time.Second, 10*time.Millisecond)
+	}
+	list, err = p.List(ctx, &pb.ListRequest{})
+	require.NoError(t, err)
+	assert.Len(t, list.GetPairs(), 100, "keys on the joining node are listed")
+
+	for _, n := range nodes {
+		require.NoError(t, n.migrator.Commit(2))
+	}
+	for i := range 100 {
+		key := fmt.Sprintf("key-%02d", i)
+		resp, err := p.Delete(ctx, &pb.DeleteRequest{Key: key})
+		require.NoError(t, err)
+		assert.True(t, resp.GetExisted(), "%s is deleted on its new owner", key)
+	}
+	current, target = p.Maps()
+	assert.EqualValues(t, 2, current.Version, "a redirect refreshed the map")
+	assert.Nil(t, target)
+	for _, n := range nodes {
+		assert.Zero(t, n.namespaces.Default().Usage().Keys)
+	}
+}
+
+// redirector serves a shard map with itself as the only node, and redirects
+// every Get to addr.
+type redirector struct {
+	pb.UnimplementedKVStoreServer
+	self, addr string
+}
+
+func (r *redirector) GetShardMap(ctx context.Context, req *pb.GetShardMapRequest) (*pb.GetShardMapResponse, error) {
+	m, err := shard.NewMap(1, []shard.Node{{ID: "a", Addr: r.self}}, 1)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetShardMapResponse{ShardMap: m.Proto()}, nil
+}
+
+func (r *redirector) Get(ctx context.Context, req *pb.GetRequest) (*pb.GetResponse, error) {
+	return nil, redirect.Error(codes.FailedPrecondition, redirect.ReasonAsk, r.addr, "key is moving", nil)
+}
+
+// Test the proxy does not follow a redirect to a node outside the shard
+// map, which would receive the caller's credentials
+func TestProxy_UnknownRedirect(t *testing.T) {
+	ctx := context.Background()
+	ln, rogue := listen(t), listen(t)
+	dialed := make(chan struct{}, 1)
+	go func() {
+		if conn, err := rogue.Accept(); err == nil {
+			dialed <- struct{}{}
+			conn.Close()
+		}
+	}()
+	t.Cleanup(func() { rogue.Close() })
+
+	srv := grpc.NewServer()
+	pb.RegisterKVStoreServer(srv, &redirector{self: ln.Addr().String(), addr: rogue.Addr().String()})
+	go srv.Serve(ln)
+	t.Cleanup(srv.Stop)
+
+	p := New([]string{ln.Addr().String()}, insecure.NewCredentials())
+	defer p.Close()
+	require.NoError(t, p.Refresh(ctx))
+
+	_, err := p.Get(ctx, &pb.GetRequest{Key: "key"})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.ErrorContains(t, err, "not in the shard map")
+	select {
+	case <-dialed:
+		t.Fatal("the proxy dialed the redirect target")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// Test the proxy refuses to pass the caller's credentials on to nodes
+// without TLS
+func TestProxy_InsecureCredentials(t *testing.T) {
+	ctx := context.Background()
+	ln := listen(t)
+	m, err := shard.NewMap(1, []shard.Node{{ID: "a", Addr: ln.Addr().String()}}, 16)
+	require.NoError(t, err)
+	serve(t, "a", ln, m)
+
+	p := New([]string{ln.Addr().String()}, insecure.NewCredentials())
+	defer p.Close()
+	require.NoError(t, p.Refresh(ctx))
+
+	_, err = p.Set(ctx, &pb.SetRequest{Key: "key", Value: "value"})
+	require.NoError(t, err, "requests without credentials are passed on")
+
+	authed := metadata.NewIncomingContext(ctx, metadata.Pairs(auth.APIKeyKey, "secret"))
+	_, err = p.Get(authed, &pb.GetRequest{Key: "key"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = p.List(authed, &pb.ListRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
@@ -0,0 +1,272 @@
+package ratelimit
+
+import (
+	"context"
+	"kvstore/internal/auth"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// RetryAfterKey is the response header carrying the number of seconds a
+// rejected client should wait before retrying.
+const RetryAfterKey = "retry-after"
+
+// overloadRetryAfter is suggested when the in-flight cap rejects a request,
+// since there is no bucket to compute a better estimate from.
+const overloadRetryAfter = time.Second
+
+// sweepInterval is how often idle buckets are dropped.
+const sweepInterval = time.Minute
+
+// Rate is a token bucket refilled at PerSecond tokens per second holding at
+// most Burst tokens. A zero PerSecond is unlimited.
+type Rate struct {
+	PerSecond float64
+	Burst     int
+}
+
+type Options struct {
+	// PerClient limits every client identity across all methods.
+	PerClient Rate
+	// Methods limits every client identity on a single method, in addition
+	// to PerClient. Keys are full method names such as
+	// "/kvstore.v1.KVStore/Set", or bare method names such as "Set".
+	Methods map[string]Rate
+	// MaxInFlight caps the requests being handled at once across all
+	// clients. Zero is unlimited.
+	MaxInFlight int
+}
+
+// Limiter admits requests through per-client token buckets and a global
+// in-flight cap.
+type Limiter struct {
+	opts     atomic.Pointer[Options]
+	inFlight atomic.Int64
+	skip     map[string]bool
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+
+	now func() time.Time
+}
+
+func New(opts Options, skip ...string) *Limiter {
+	l := &Limiter{
+		skip:    make(map[string]bool),
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+	for _, method := range skip {
+		l.skip[method] = true
+	}
+	l.Update(opts)
+	return l
+}
+
+// Update swaps in new limits. It is safe to call while serving. Buckets are
+// kept, so reloading unchanged limits does not hand every client a fresh
+// burst; a bucket whose rate changed is rescaled when next used.
+func (l *Limiter) Update(opts Options) {
+	l.opts.Store(&opts)
+}
+
+// Allow takes a token for client calling method and reports how long to wait
+// when none is available.
+func (l *Limiter) Allow(client, method string) (time.Duration, bool) {
+	opts := l.opts.Load()
+
+	methodRate, hasMethodRate := opts.Methods[method]
+	if !hasMethodRate {
+		methodRate, hasMethodRate = opts.Methods[method[strings.LastIndex(method, "/")+1:]]
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	// Check both buckets before taking from either, so a rejection by one
+	// does not consume a token from the other.
+	var buckets []*bucket
+	if opts.PerClient.PerSecond > 0 {
+		buckets = append(buckets, l.bucket(client, opts.PerClient, now))
+	}
+	if hasMethodRate && methodRate.PerSecond > 0 {
+		buckets = append(buckets, l.bucket(client+" "+method, methodRate, now))
+	}
+
+	var wait time.Duration
+	for _, b := range buckets {
+		wait = max(wait, b.wait())
+	}
+	if wait > 0 {
+		return wait, false
+	}
+
+	for _, b := range buckets {
+		b.tokens--
+	}
+	return 0, true
+}
+
+func (l *Limiter) bucket(key string, rate Rate, now time.Time) *bucket {
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{rate: rate, tokens: float64(burst(rate)), last: now}
+		l.buckets[key] = b
+	}
+	b.refill(now)
+	if b.rate != rate {
+		b.rescale(rate)
+	}
+	return b
+}
+
+// sweep drops buckets that have refilled completely, which behave exactly
+// like new ones. The caller must hold l.mu.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		b.refill(now)
+		if b.tokens >= float64(burst(b.rate)) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// burst defaults to one second's worth of tokens, and at least one.
+func burst(rate Rate) int {
+	if rate.Burst > 0 {
+		return rate.Burst
+	}
+	return max(1, int(math.Ceil(rate.PerSecond)))
+}
+
+type bucket struct {
+	rate   Rate
+	tokens float64
+	last   time.Time
+}
+
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	b.tokens = math.Min(float64(burst(b.rate)), b.tokens+elapsed*b.rate.PerSecond)
+	b.last = now
+}
+
+// rescale switches b to rate, keeping the share of the burst it holds. The
+// caller must refill b at its old rate first.
+func (b *bucket) rescale(rate Rate) {
+	b.tokens = b.tokens / float64(burst(b.rate)) * float64(burst(rate))
+	b.rate = rate
+}
+
+// wait returns how long until a token is available.
+func (b *bucket) wait() time.Duration {
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate.PerSecond * float64(time.Second))
+}
+
+// ClientID identifies the caller for rate limiting: the authenticated
+// principal, or the peer's IP address when authentication is disabled.
+func ClientID(ctx context.Context) string {
+	if id, ok := auth.FromContext(ctx); ok {
+		return "principal:" + id.Principal
+	}
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		addr := p.Addr.String()
+		if i := strings.LastIndex(addr, ":"); i > 0 {
+			addr = addr[:i]
+		}
+		return "addr:" + addr
+	}
+
+	return "anonymous"
+}
+
+// UnaryInterceptor rejects requests over the limits with ResourceExhausted,
+// a RetryInfo detail and a retry-after header. It must run after the
+// Authenticator's interceptor so requests are limited per principal.
+func (l *Limiter) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if l.skip[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		if limit := l.opts.Load().MaxInFlight; limit > 0 {
+			if l.inFlight.Add(1) > int64(limit) {
+				l.inFlight.Add(-1)
+				return nil, rejected(ctx, overloadRetryAfter, "server is handling too many requests")
+			}
+			defer l.inFlight.Add(-1)
+		}
+
+		if wait, ok := l.Allow(ClientID(ctx), info.FullMethod); !ok {
+			return nil, rejected(ctx, wait, "rate limit exceeded for "+info.FullMethod)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor applies the rate limits to opening streams. Streams are
+// not counted against MaxInFlight: they stay open for as long as a replica
+// follows its primary, and would hold their slots for good.
+func (l *Limiter) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if l.skip[info.FullMethod] {
+			return handler(srv, ss)
+		}
+
+		ctx := ss.Context()
+		if wait, ok := l.Allow(ClientID(ctx), info.FullMethod); !ok {
+			return rejected(ctx, wait, "rate limit exceeded for "+info.FullMethod)
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+func rejected(ctx context.Context, wait time.Duration, msg string) error {
+	// Outside a gRPC server, e.g. in the REST gateway, there is no header to
+	// set and the gateway reads the RetryInfo detail instead.
+	_ = grpc.SetHeader(ctx, metadata.Pairs(RetryAfterKey, strconv.Itoa(retryAfterSeconds(wait))))
+
+	st, err := status.New(codes.ResourceExhausted, msg).WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(wait),
+	})
+	if err != nil {
+		return status.Error(codes.ResourceExhausted, msg)
+	}
+	return st.Err()
+}
+
+// retryAfterSeconds rounds wait up to whole seconds for the retry-after
+// header.
+func retryAfterSeconds(wait time.Duration) int {
+	return max(1, int(math.Ceil(wait.Seconds())))
+}
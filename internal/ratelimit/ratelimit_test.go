@@ -0,0 +1,199 @@
+package ratelimit
+
+import (
+	"context"
+	"kvstore/internal/auth"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const setMethod = "/kvstore.v1.KVStore/Set"
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestLimiter(opts Options, skip ...string) (*Limiter, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	l := New(opts, skip...)
+	l.now = clock.now
+	return l, clock
+}
+
+// Test the burst is available at once and then refills at the rate
+func TestLimiter_TokenBucket(t *testing.T) {
+	l, clock := newTestLimiter(Options{PerClient: Rate{PerSecond: 2, Burst: 3}})
+
+	for i := 0; i < 3; i++ {
+		_, ok := l.Allow("a", setMethod)
+		require.True(t, ok, "request %d", i)
+	}
+
+	wait, ok := l.Allow("a", setMethod)
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	// Other clients have their own bucket
+	_, ok = l.Allow("b", setMethod)
+	assert.True(t, ok)
+
+	clock.advance(500 * time.Millisecond)
+	_, ok = l.Allow("a", setMethod)
+	assert.True(t, ok)
+	_, ok = l.Allow("a", setMethod)
+	assert.False(t, ok)
+}
+
+// Test method limits apply on top of the client limit, and that a rejection
+// does not spend a token from the other bucket
+func TestLimiter_Methods(t *testing.T) {
+	l, clock := newTestLimiter(Options{
+		PerClient: Rate{PerSecond: 10, Burst: 3},
+		Methods:   map[string]Rate{"Set": {PerSecond: 1, Burst: 1}},
+	})
+
+	_, ok := l.Allow("a", setMethod)
+	require.True(t, ok)
+	_, ok = l.Allow("a", setMethod)
+	require.False(t, ok)
+
+	_, ok = l.Allow("a", "/kvstore.v1.KVStore/Get")
+	assert.True(t, ok)
+	_, ok = l.Allow("a", "/kvstore.v1.KVStore/Get")
+	assert.True(t, ok, "rejected Set must not consume a client token")
+	_, ok = l.Allow("a", "/kvstore.v1.KVStore/Get")
+	assert.False(t, ok)
+
+	clock.advance(time.Second)
+	_, ok = l.Allow("a", setMethod)
+	assert.True(t, ok)
+}
+
+func TestLimiter_Unlimited(t *testing.T) {
+	l, _ := newTestLimiter(Options{})
+
+	for i := 0; i < 1000; i++ {
+		_, ok := l.Allow("a", setMethod)
+		require.True(t, ok)
+	}
+}
+
+// Test rejections carry ResourceExhausted with a RetryInfo detail
+func TestLimiter_UnaryInterceptor(t *testing.T) {
+	l, _ := newTestLimiter(Options{PerClient: Rate{PerSecond: 1}}, "/grpc.health.v1.Health/Check")
+	interceptor := l.UnaryInterceptor()
+	handler := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+
+	ctx := auth.WithIdentity(context.Background(), &auth.Identity{Principal: "batch"})
+	info := &grpc.UnaryServerInfo{FullMethod: setMethod}
+
+	_, err := interceptor(ctx, nil, info, handler)
+	require.NoError(t, err)
+
+	_, err = interceptor(ctx, nil, info, handler)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	details := status.Convert(err).Details()
+	require.Len(t, details, 1)
+	assert.Equal(t, time.Second, details[0].(*errdetails.RetryInfo).GetRetryDelay().AsDuration())
+
+	// Skipped methods are never limited
+	for i := 0; i < 3; i++ {
+		_, err = interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}, handler)
+		assert.NoError(t, err)
+	}
+}
+
+// Test reloading keeps the buckets, rescaling those whose rate changed
+func TestLimiter_Update(t *testing.T) {
+	opts := Options{PerClient: Rate{PerSecond: 1, Burst: 2}}
+	l, _ := newTestLimiter(opts)
+
+	for i := 0; i < 2; i++ {
+		_, ok := l.Allow("a", setMethod)
+		require.True(t, ok)
+	}
+
+	l.Update(opts)
+	_, ok := l.Allow("a", setMethod)
+	assert.False(t, ok, "an unchanged limit does not refill the bucket")
+
+	// Half of the old burst is half of the new one
+	_, ok = l.Allow("b", setMethod)
+	require.True(t, ok)
+	l.Update(Options{PerClient: Rate{PerSecond: 1, Burst: 4}})
+	for i := 0; i < 2; i++ {
+		_, ok = l.Allow("b", setMethod)
+		assert.True(t, ok, "request %d", i)
+	}
+	_, ok = l.Allow("b", setMethod)
+	assert.False(t, ok)
+}
+
+// Test opening streams is rate-limited too
+func TestLimiter_StreamInterceptor(t *testing.T) {
+	l, _ := newTestLimiter(Options{PerClient: Rate{PerSecond: 1}})
+	interceptor := l.StreamInterceptor()
+	handler := func(srv any, ss grpc.ServerStream) error { return nil }
+
+	stream := &serverStream{ctx: auth.WithIdentity(context.Background(), &auth.Identity{Principal: "replica"})}
+	info := &grpc.StreamServerInfo{FullMethod: "/kvstore.v1.Replication/StreamMutations", IsServerStream: true}
+
+	require.NoError(t, interceptor(nil, stream, info, handler))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(interceptor(nil, stream, info, handler)))
+}
+
+func TestLimiter_MaxInFlight(t *testing.T) {
+	l, _ := newTestLimiter(Options{MaxInFlight: 1})
+	interceptor := l.UnaryInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: setMethod}
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		_, err := interceptor(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+			close(entered)
+			<-release
+			return nil, nil
+		})
+		done <- err
+	}()
+	<-entered
+
+	_, err := interceptor(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) { return nil, nil })
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	close(release)
+	require.NoError(t, <-done)
+
+	_, err = interceptor(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) { return nil, nil })
+	assert.NoError(t, err)
+}
+
+func TestClientID(t *testing.T) {
+	assert.Equal(t, "anonymous", ClientID(context.Background()))
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.7"), Port: 5123}})
+	assert.Equal(t, "addr:10.0.0.7", ClientID(ctx))
+
+	ctx = auth.WithIdentity(ctx, &auth.Identity{Principal: "ci"})
+	assert.Equal(t, "principal:ci", ClientID(ctx))
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context { return s.ctx }
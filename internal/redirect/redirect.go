@@ -0,0 +1,148 @@
+package redirect
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Domain is the ErrorInfo domain of kvstore redirects.
+const Domain = "kvstore"
+
+// AddrKey is the ErrorInfo metadata key holding the gRPC address to retry
+// at.
+const AddrKey = "addr"
+
+// Reasons reported in the ErrorInfo of a redirect.
+const (
+	// ReasonNotLeader: the node is a Raft follower; retry at the leader.
+	ReasonNotLeader = "NOT_LEADER"
+	// ReasonTooStale: the replica lags further behind than the read allows;
+	// retry at the leader or primary.
+	ReasonTooStale = "TOO_STALE"
+	// ReasonReadOnly: the node is a read-only replica; retry at the
+	// primary.
+	ReasonReadOnly = "READ_ONLY_REPLICA"
+	// ReasonWrongShard: another node owns the key; retry there, and refresh
+	// the shard map if it is cached.
+	ReasonWrongShard = "WRONG_SHARD"
+	// ReasonAsk: the key is moving to another shard and has left this one;
+	// retry this request there with AskingHeader set, but keep routing
+	// the key here until the migration is committed.
+	ReasonAsk = "ASK"
+)
+
+// AskingHeader marks a request retried after an ASK redirect, which the new
+// owner of a moving key only serves when it is set.
+const AskingHeader = "x-kvstore-asking"
+
+// maxHops bounds how many redirects a call follows, in case nodes disagree
+// about where a request belongs.
+const maxHops = 3
+
+// Error returns a status error with code whose ErrorInfo names the node to
+// retry at. addr may be empty when the right node is not known yet.
+func Error(code codes.Code, reason, addr, msg string, metadata map[string]string) error {
+	md := map[string]string{AddrKey: addr}
+	for k, v := range metadata {
+		md[k] = v
+	}
+
+	st, err := status.New(code, msg).WithDetails(&errdetails.ErrorInfo{
+		Reason:   reason,
+		Domain:   Domain,
+		Metadata: md,
+	})
+	if err != nil {
+		return status.Error(code, msg)
+	}
+	return st.Err()
+}
+
+// Info returns the redirect ErrorInfo carried by err, if any.
+func Info(err error) (*errdetails.ErrorInfo, bool) {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.GetDomain() == Domain {
+			return info, true
+		}
+	}
+	return nil, false
+}
+
+// Follower retries redirected calls at the node they were redirected to.
+// Connections to redirect targets are kept open until Close.
+type Follower struct {
+	dialOpts []grpc.DialOption
+
+	mu    sync.Mutex
+	conns map[string]*grpc.ClientConn
+}
+
+// NewFollower dials redirect targets with dialOpts, which should match the
+// options of the original connection.
+func NewFollower(dialOpts ...grpc.DialOption) *Follower {
+	return &Follower{dialOpts: dialOpts, conns: make(map[string]*grpc.ClientConn)}
+}
+
+func (f *Follower) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+
+		for hop := 0; hop < maxHops && err != nil; hop++ {
+			info, ok := Info(err)
+			if !ok || info.GetMetadata()[AddrKey] == "" {
+				return err
+			}
+
+			conn, dialErr := f.conn(info.GetMetadata()[AddrKey])
+			if dialErr != nil {
+				return err
+			}
+			hopCtx := ctx
+			if info.GetReason() == ReasonAsk {
+				hopCtx = metadata.AppendToOutgoingContext(ctx, AskingHeader, "true")
+			}
+			err = conn.Invoke(hopCtx, method, req, reply, opts...)
+		}
+
+		return err
+	}
+}
+
+func (f *Follower) conn(addr string) (*grpc.ClientConn, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if conn, ok := f.conns[addr]; ok {
+		return conn, nil
+	}
+
+	conn, err := grpc.NewClient(addr, f.dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+	f.conns[addr] = conn
+	return conn, nil
+}
+
+func (f *Follower) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for addr, conn := range f.conns {
+		conn.Close()
+		delete(f.conns, addr)
+	}
+	return nil
+}
+
+// Asking reports whether the incoming request follows an ASK redirect.
+func Asking(ctx context.Context) bool {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return len(md.Get(AskingHeader)) > 0
+}
@@ -0,0 +1,97 @@
+package redirect
+
+import (
+	"context"
+	pb "kvstore/pkg/pb/api/proto"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// node accepts Sets if leader and ask are empty, or redirects them.
+type node struct {
+	pb.UnimplementedKVStoreServer
+	leader string
+	ask    string
+	// asked counts Sets following an ASK redirect.
+	sets, asked int
+}
+
+func (n *node) Set(ctx context.Context, req *pb.SetRequest) (*pb.SetResponse, error) {
+	if n.leader != "" {
+		return nil, Error(codes.Unavailable, ReasonNotLeader, n.leader, "not the leader", nil)
+	}
+	if n.ask != "" {
+		return nil, Error(codes.Unavailable, ReasonAsk, n.ask, "moving", nil)
+	}
+	if Asking(ctx) {
+		n.asked++
+	}
+	n.sets++
+	return &pb.SetResponse{Success: true}, nil
+}
+
+func serve(t *testing.T, n *node) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpc.NewServer()
+	pb.RegisterKVStoreServer(srv, n)
+	go srv.Serve(ln)
+	t.Cleanup(srv.Stop)
+
+	return ln.Addr().String()
+}
+
+// Test redirected calls are retried at the node named in the redirect
+func TestFollower(t *testing.T) {
+	leader := &node{}
+	leaderAddr := serve(t, leader)
+	followerAddr := serve(t, &node{leader: leaderAddr})
+
+	creds := grpc.WithTransportCredentials(insecure.NewCredentials())
+	follower := NewFollower(creds)
+	defer follower.Close()
+
+	conn, err := grpc.NewClient(followerAddr, creds, grpc.WithUnaryInterceptor(follower.UnaryClientInterceptor()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	resp, err := pb.NewKVStoreClient(conn).Set(context.Background(), &pb.SetRequest{Key: "a", Value: "1"})
+	require.NoError(t, err)
+	assert.True(t, resp.GetSuccess())
+	assert.Equal(t, 1, leader.sets)
+
+	// Redirects without a known target carry no address
+	err = Error(codes.Unavailable, ReasonNotLeader, "", "no leader", nil)
+	info, ok := Info(err)
+	require.True(t, ok)
+	assert.Empty(t, info.GetMetadata()[AddrKey])
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+// Test calls following an ASK redirect are marked as such
+func TestFollower_Ask(t *testing.T) {
+	target := &node{}
+	sourceAddr := serve(t, &node{ask: serve(t, target)})
+
+	creds := grpc.WithTransportCredentials(insecure.NewCredentials())
+	follower := NewFollower(creds)
+	defer follower.Close()
+
+	conn, err := grpc.NewClient(sourceAddr, creds, grpc.WithUnaryInterceptor(follower.UnaryClientInterceptor()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = pb.NewKVStoreClient(conn).Set(context.Background(), &pb.SetRequest{Key: "a", Value: "1"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, target.sets)
+	assert.Equal(t, 1, target.asked)
+}
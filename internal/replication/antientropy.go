@@ -0,0 +1,417 @@
+package replication
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"kvstore/internal/merkle"
+	"kvstore/internal/storage"
+	pb "kvstore/pkg/pb/api/proto"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Anti-entropy finds where a replica diverged from its primary, through a
+// bug or a bad disk rather than lag, and repairs it. The primary builds a
+// Merkle tree over the key ranges of each namespace as of an offset; the
+// replica builds its own once it has applied that offset and compares them.
+// Ranges that differ are repaired by replacing the replica's records in
+// them with the primary's.
+//
+// Only primary-replica replication is checked. Raft members and shards are
+// not compared with anything: a Raft follower that diverged from its
+// leader, or a shard that lost keys, goes unnoticed.
+
+// DefaultAntiEntropyInterval is how often a replica compares itself with
+// the primary by default.
+const DefaultAntiEntropyInterval = 10 * time.Minute
+
+// repairTimeout bounds a background anti-entropy pass.
+const repairTimeout = 5 * time.Minute
+
+// ErrNotSynced is returned when comparing a replica with its primary before
+// it follows the primary's current log.
+var ErrNotSynced = errors.New("replication: replica has not synced with the primary's log yet")
+
+// Missing sides of a namespace that only exists on one of them.
+const (
+	OnPrimary = "primary"
+	OnReplica = "replica"
+)
+
+// Divergence is a key range of a namespace, or a whole namespace, where a
+// replica and its primary differ.
+type Divergence struct {
+	Namespace string
+	// MissingOn is OnPrimary or OnReplica when the namespace only exists on
+	// one side; Range is then zero.
+	MissingOn string
+	Range     int
+}
+
+// CheckResult is the outcome of comparing a replica with its primary.
+type CheckResult struct {
+	// PrimaryOffset and Offset are the offsets the primary's and the
+	// replica's trees reflect. The primary's trees may lack mutations
+	// logged while they were built. Ranges written then, or between the
+	// two offsets when Offset is ahead, may be reported although the two
+	// agree on them.
+	PrimaryOffset uint64
+	Offset        uint64
+	// Depth splits each namespace into 2^Depth ranges.
+	Depth      int
+	Namespaces int
+	Divergent  []Divergence
+}
+
+// namespaceDiff is how one namespace differs, with what repairing it needs.
+type namespaceDiff struct {
+	name      string
+	missingOn string
+	// settings are the primary's, for creating the namespace.
+	settings storage.NamespaceSettings
+	ranges   []int
+}
+
+// GetMerkleTrees implements the Replication service.
+func (p *Primary) GetMerkleTrees(ctx context.Context, req *pb.GetMerkleTreesRequest) (*pb.GetMerkleTreesResponse, error) {
+	depth, err := treeDepth(req.GetDepth())
+	if err != nil {
+		return nil, err
+	}
+
+	// Building the trees from the namespaces directly rather than a
+	// snapshot at an offset keeps writes going meanwhile. Those writes may
+	// be missing from the trees, as from trees of an earlier offset, but
+	// the offset read afterwards covers them.
+	snaps := p.namespaces.Snapshot()
+	offset := p.Offset()
+	resp := &pb.GetMerkleTreesResponse{LogId: p.logID, Offset: offset, Depth: uint32(depth)}
+	for _, snap := range snaps {
+		tree, err := merkle.Build(depth, snap.Records)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to build the tree of %s: %v", snap.Name, err)
+		}
+		settings, err := json.Marshal(snap.Settings)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to encode the settings of %s: %v", snap.Name, err)
+		}
+		resp.Namespaces = append(resp.Namespaces, &pb.NamespaceTree{
+			Namespace: snap.Name,
+			Settings:  settings,
+			Ranges:    tree.Leaves(),
+		})
+	}
+	return resp, nil
+}
+
+// GetRangeRecords implements the Replication service.
+func (p *Primary) GetRangeRecords(ctx context.Context, req *pb.GetRangeRecordsRequest) (*pb.GetRangeRecordsResponse, error) {
+	depth, err := treeDepth(req.GetDepth())
+	if err != nil {
+		return nil, err
+	}
+	if req.GetRange() >= 1<<depth {
+		return nil, status.Errorf(codes.InvalidArgument, "a tree of depth %d has no range %d", depth, req.GetRange())
+	}
+
+	// Read after the offset, the records reflect every mutation up to it
+	// and perhaps some later ones.
+	offset := p.Offset()
+	ns, err := p.namespaces.Get(req.GetNamespace())
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "%v", err)
+	}
+	all := ns.Records("")
+
+	records, err := json.Marshal(inRange(all, depth, int(req.GetRange())))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode records: %v", err)
+	}
+	return &pb.GetRangeRecordsResponse{LogId: p.logID, Offset: offset, Records: records}, nil
+}
+
+func treeDepth(depth uint32) (int, error) {
+	if depth == 0 {
+		return merkle.DefaultDepth, nil
+	}
+	if depth > merkle.MaxDepth {
+		return 0, status.Errorf(codes.InvalidArgument, "depth must be at most %d", merkle.MaxDepth)
+	}
+	return int(depth), nil
+}
+
+// inRange returns the records in range r of a tree of depth.
+func inRange(records []storage.Record, depth, r int) []storage.Record {
+	var out []storage.Record
+	for _, rec := range records {
+		if merkle.Range(rec.Key, depth) == r {
+			out = append(out, rec)
+		}
+	}
+	return out
+}
+
+// Check compares the replica with its primary and reports where they
+// differ, without repairing anything.
+func (r *Replica) Check(ctx context.Context) (CheckResult, error) {
+	result, _, err := r.compare(ctx)
+	return result, err
+}
+
+// Repair compares the replica with its primary and replaces what differs
+// with the primary's state. It returns what differed and how many keys it
+// changed.
+//
+// Each range is repaired while the replica applies no mutations, with the
+// primary's records as of at least the offset the replica has applied, so a
+// repair never undoes a newer write. Mutations applied afterwards may
+// briefly take a repaired key back to an older value before the one it was
+// repaired to.
+func (r *Replica) Repair(ctx context.Context) (CheckResult, int, error) {
+	result, diffs, err := r.compare(ctx)
+	if err != nil {
+		return result, 0, err
+	}
+
+	keys := 0
+	for _, d := range diffs {
+		n, err := r.repairNamespace(ctx, result.Depth, d)
+		keys += n
+		if err != nil {
+			return result, keys, fmt.Errorf("replication: failed to repair %s: %w", d.name, err)
+		}
+	}
+	return result, keys, nil
+}
+
+// compare builds the replica's trees at the offset of the primary's, or
+// as close after it as writes allow, and compares them.
+func (r *Replica) compare(ctx context.Context) (CheckResult, []namespaceDiff, error) {
+	client, err := r.primary()
+	if err != nil {
+		return CheckResult{}, nil, err
+	}
+
+	resp, err := client.GetMerkleTrees(ctx, &pb.GetMerkleTreesRequest{})
+	if err != nil {
+		return CheckResult{}, nil, fmt.Errorf("replication: failed to get the primary's trees: %w", err)
+	}
+	if logID := r.Status().LogID; logID != resp.GetLogId() {
+		return CheckResult{}, nil, ErrNotSynced
+	}
+	if _, err := r.waitApplied(ctx, resp.GetOffset()); err != nil {
+		return CheckResult{}, nil, err
+	}
+
+	r.applyMu.Lock()
+	snaps := r.namespaces.Snapshot()
+	st := r.Status()
+	r.applyMu.Unlock()
+	if st.LogID != resp.GetLogId() {
+		return CheckResult{}, nil, ErrNotSynced
+	}
+
+	depth := int(resp.GetDepth())
+	result := CheckResult{PrimaryOffset: resp.GetOffset(), Offset: st.Offset, Depth: depth}
+	local := make(map[string]storage.NamespaceSnapshot, len(snaps))
+	for _, snap := range snaps {
+		local[snap.Name] = snap
+	}
+
+	var diffs []namespaceDiff
+	for _, nt := range resp.GetNamespaces() {
+		result.Namespaces++
+		d := namespaceDiff{name: nt.GetNamespace()}
+		if err := json.Unmarshal(nt.GetSettings(), &d.settings); err != nil {
+			return result, nil, fmt.Errorf("replication: corrupt settings of %s: %w", d.name, err)
+		}
+
+		snap, ok := local[d.name]
+		delete(local, d.name)
+		if !ok {
+			d.missingOn = OnReplica
+		}
+
+		theirs, err := merkle.FromLeaves(depth, nt.GetRanges())
+		if err != nil {
+			return result, nil, fmt.Errorf("replication: corrupt tree of %s: %w", d.name, err)
+		}
+		ours, err := merkle.Build(depth, snap.Records)
+		if err != nil {
+			return result, nil, err
+		}
+		if d.ranges, err = merkle.Diff(theirs, ours); err != nil {
+			return result, nil, err
+		}
+
+		switch {
+		case d.missingOn != "":
+			result.Divergent = append(result.Divergent, Divergence{Namespace: d.name, MissingOn: d.missingOn})
+		case len(d.ranges) == 0:
+			continue
+		default:
+			for _, rg := range d.ranges {
+				result.Divergent = append(result.Divergent, Divergence{Namespace: d.name, Range: rg})
+			}
+		}
+		diffs = append(diffs, d)
+	}
+
+	for _, snap := range snaps {
+		if _, ok := local[snap.Name]; ok {
+			result.Namespaces++
+			result.Divergent = append(result.Divergent, Divergence{Namespace: snap.Name, MissingOn: OnPrimary})
+			diffs = append(diffs, namespaceDiff{name: snap.Name, missingOn: OnPrimary})
+		}
+	}
+	return result, diffs, nil
+}
+
+// repairNamespace repairs d and returns how many keys it changed.
+func (r *Replica) repairNamespace(ctx context.Context, depth int, d namespaceDiff) (int, error) {
+	if d.missingOn != "" {
+		if done, err := r.repairExistence(ctx, depth, d); done || err != nil {
+			return 0, err
+		}
+	}
+
+	keys := 0
+	for _, rg := range d.ranges {
+		n, err := r.repairRange(ctx, depth, d.name, rg)
+		keys += n
+		if err != nil {
+			return keys, err
+		}
+	}
+	return keys, nil
+}
+
+// repairExistence creates or drops the namespace of d if the primary still
+// has or lacks it, and reports whether nothing is left to repair.
+func (r *Replica) repairExistence(ctx context.Context, depth int, d namespaceDiff) (bool, error) {
+	r.applyMu.Lock()
+	defer r.applyMu.Unlock()
+
+	client, err := r.primary()
+	if err != nil {
+		return false, err
+	}
+	// Any range tells whether the namespace exists on the primary now.
+	_, err = client.GetRangeRecords(ctx, &pb.GetRangeRecordsRequest{Namespace: d.name, Depth: uint32(depth)})
+	onPrimary := err == nil
+	if err != nil && status.Code(err) != codes.NotFound {
+		return false, err
+	}
+	_, localErr := r.namespaces.Get(d.name)
+	onReplica := localErr == nil
+
+	switch {
+	case onPrimary && !onReplica:
+		_, err := r.namespaces.Create(d.name, d.settings)
+		return false, err
+	case !onPrimary && onReplica:
+		return true, r.namespaces.Drop(d.name)
+	}
+	// The namespace was created or dropped since the comparison.
+	return !onPrimary, nil
+}
+
+// repairRange replaces the replica's records in range rg of namespace with
+// the primary's and returns how many keys it changed.
+func (r *Replica) repairRange(ctx context.Context, depth int, namespace string, rg int) (int, error) {
+	r.applyMu.Lock()
+	defer r.applyMu.Unlock()
+
+	client, err := r.primary()
+	if err != nil {
+		return 0, err
+	}
+	resp, err := client.GetRangeRecords(ctx, &pb.GetRangeRecordsRequest{Namespace: namespace, Depth: uint32(depth), Range: uint32(rg)})
+	if status.Code(err) == codes.NotFound {
+		// Dropped since the comparison; the mutation dropping it follows.
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if resp.GetLogId() != r.Status().LogID {
+		return 0, ErrNotSynced
+	}
+
+	var theirs []storage.Record
+	if err := json.Unmarshal(resp.GetRecords(), &theirs); err != nil {
+		return 0, fmt.Errorf("corrupt records of range %d: %w", rg, err)
+	}
+	ns, err := r.namespaces.Get(namespace)
+	if err != nil {
+		return 0, err
+	}
+
+	ours := make(map[string]storage.Record)
+	for _, rec := range inRange(ns.Records(""), depth, rg) {
+		ours[rec.Key] = rec
+	}
+
+	keys := 0
+	for _, rec := range theirs {
+		if cur, ok := ours[rec.Key]; !ok || cur != rec {
+			// The primary accepted the record, as it did every write the
+			// replica applies.
+			if err := ns.Load(rec); err != nil {
+				return keys, err
+			}
+			keys++
+		}
+		delete(ours, rec.Key)
+	}
+	for key := range ours {
+		if _, err := ns.DeleteContext(ctx, key); err != nil {
+			return keys, err
+		}
+		keys++
+	}
+	return keys, nil
+}
+
+// antiEntropy repairs the replica every interval until ctx is done.
+func (r *Replica) antiEntropy(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		passCtx, cancel := context.WithTimeout(ctx, repairTimeout)
+		result, keys, err := r.Repair(passCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+
+		r.mu.Lock()
+		r.lastRepair = time.Now()
+		r.lastRepairErr = err
+		r.keysRepaired += uint64(keys)
+		if err == nil {
+			r.rangesRepaired += uint64(len(result.Divergent))
+		}
+		r.mu.Unlock()
+
+		switch {
+		case err != nil:
+			slog.Warn("Anti-entropy failed to compare with the primary", "error", err, "retry_in", interval)
+		case len(result.Divergent) > 0:
+			slog.Warn("Anti-entropy repaired a divergence from the primary",
+				"ranges", len(result.Divergent), "keys", keys, "offset", result.Offset)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package replication
+
+import (
+	"context"
+	"fmt"
+	"kvstore/internal/merkle"
+	"kvstore/internal/storage"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// diverge syncs a replica with a primary holding a few namespaces, then
+// changes the replica behind the primary's back.
+func diverge(t *testing.T, antiEntropy time.Duration) (*Primary, *Replica, *storage.Namespaces) {
+	t.Helper()
+	ctx := context.Background()
+
+	primary := NewPrimary(storage.NewNamespaces())
+	require.NoError(t, primary.CreateNamespace(ctx, "users", storage.NamespaceSettings{DefaultTTLSeconds: 60}))
+	var offset uint64
+	for i := range 100 {
+		var err error
+		offset, err = primary.Set(ctx, storage.DefaultNamespace, storage.Record{Key: fmt.Sprintf("key-%d", i), Value: "v"})
+		require.NoError(t, err)
+	}
+	_, err := primary.Set(ctx, "users", storage.Record{Key: "alice", Value: "1"})
+	require.NoError(t, err)
+
+	replica, namespaces := newReplica(serve(t, primary))
+	replica.opts.AntiEntropyInterval = antiEntropy
+	start(t, replica)
+	caughtUp(t, replica, offset+1)
+
+	ns := namespaces.Default()
+	require.NoError(t, ns.PutRecordContext(ctx, storage.Record{Key: "key-1", Value: "corrupt"}))
+	_, err = ns.DeleteContext(ctx, "key-2")
+	require.NoError(t, err)
+	require.NoError(t, ns.PutRecordContext(ctx, storage.Record{Key: "stray", Value: "v"}))
+	require.NoError(t, namespaces.Drop("users"))
+	_, err = namespaces.Create("extra", storage.NamespaceSettings{})
+	require.NoError(t, err)
+
+	return primary, replica, namespaces
+}
+
+// divergent is where diverge makes the replica differ.
+func divergent() []Divergence {
+	out := []Divergence{{Namespace: "extra", MissingOn: OnPrimary}, {Namespace: "users", MissingOn: OnReplica}}
+	seen := map[int]bool{}
+	for _, key := range []string{"key-1", "key-2", "stray"} {
+		if r := merkle.Range(key, merkle.DefaultDepth); !seen[r] {
+			seen[r] = true
+			out = append(out, Divergence{Namespace: storage.DefaultNamespace, Range: r})
+		}
+	}
+	return out
+}
+
+// Test checking reports the diverged ranges and namespaces without
+// repairing them, and repairing makes the replica match the primary
+func TestReplica_Repair(t *testing.T) {
+	ctx := context.Background()
+	primary, replica, namespaces := diverge(t, 0)
+
+	result, err := replica.Check(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, merkle.DefaultDepth, result.Depth)
+	assert.Equal(t, 3, result.Namespaces)
+	assert.Equal(t, result.PrimaryOffset, result.Offset)
+
+	want := divergent()
+	assert.ElementsMatch(t, want, result.Divergent)
+	_, err = namespaces.Get("extra")
+	assert.NoError(t, err, "checking repairs nothing")
+
+	result, keys, err := replica.Repair(ctx)
+	require.NoError(t, err)
+	assert.Len(t, result.Divergent, len(want))
+	assert.Equal(t, 4, keys, "key-1, key-2, stray and alice")
+	assert.Equal(t, primary.namespaces.Snapshot(), namespaces.Snapshot())
+
+	result, err = replica.Check(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, result.Divergent)
+
+	// Repaired namespaces keep replicating
+	offset, err := primary.Set(ctx, "users", storage.Record{Key: "bob", Value: "2"})
+	require.NoError(t, err)
+	caughtUp(t, replica, offset)
+	assert.Equal(t, primary.namespaces.Snapshot(), namespaces.Snapshot())
+}
+
+// Test repairs restore the primary's records even beyond the replica's
+// quotas, as replicated writes are
+func TestReplica_RepairIgnoresQuota(t *testing.T) {
+	ctx := context.Background()
+	_, replica, namespaces := diverge(t, 0)
+	_, err := namespaces.SetQuota(storage.DefaultNamespace, storage.Quota{MaxKeys: 1})
+	require.NoError(t, err)
+
+	_, _, err = replica.Repair(ctx)
+	require.NoError(t, err)
+	value, found := namespaces.Default().Get("key-2")
+	assert.True(t, found)
+	assert.Equal(t, "v", value)
+}
+
+// Test replicas repair themselves in the background
+func TestReplica_AntiEntropy(t *testing.T) {
+	primary, replica, namespaces := diverge(t, 10*time.Millisecond)
+
+	require.Eventually(t, func() bool {
+		return replica.Status().KeysRepaired == 4
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, primary.namespaces.Snapshot(), namespaces.Snapshot())
+
+	st := replica.Status()
+	assert.EqualValues(t, len(divergent()), st.RangesRepaired)
+	assert.NoError(t, st.LastRepairError)
+	assert.False(t, st.LastRepair.IsZero())
+}
+
+// Test replicas only compare with the primary while running and following
+// its current log
+func TestReplica_CheckNotSynced(t *testing.T) {
+	ctx := context.Background()
+	replica, _ := newReplica(serve(t, NewPrimary(storage.NewNamespaces())))
+	_, err := replica.Check(ctx)
+	assert.ErrorContains(t, err, "not running")
+
+	start(t, replica)
+	require.Eventually(t, func() bool { return replica.Status().LogID != "" }, 5*time.Second, 10*time.Millisecond)
+	replica.mu.Lock()
+	replica.logID = "previous"
+	replica.mu.Unlock()
+	_, err = replica.Check(ctx)
+	assert.ErrorIs(t, err, ErrNotSynced)
+}
@@ -0,0 +1,351 @@
+package replication
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"kvstore/internal/cluster"
+	"kvstore/internal/storage"
+	pb "kvstore/pkg/pb/api/proto"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultLogSize is how many mutations a primary retains by default.
+const DefaultLogSize = 100_000
+
+const (
+	// heartbeatInterval is how often an idle stream tells the replica it is
+	// still connected.
+	heartbeatInterval = time.Second
+	// maxBatch bounds how many mutations a stream takes from the log at a
+	// time, so slow replicas do not hold the log lock.
+	maxBatch = 1024
+	// snapshotChunkBytes bounds the size of a SnapshotChunk, well below the
+	// default gRPC message limit.
+	snapshotChunkBytes = 1 << 20
+)
+
+type PrimaryOption func(*Primary)
+
+// WithLogSize sets how many recent mutations the primary retains for
+// replicas to catch up from. Replicas further behind resync from a
+// snapshot.
+func WithLogSize(n int) PrimaryOption {
+	return func(p *Primary) {
+		if n > 0 {
+			p.log = make([]*pb.Mutation, n)
+		}
+	}
+}
+
+// Primary applies writes to its namespaces and streams them to replicas.
+// Writes are serialized, so that the log holds them in the order they were
+// applied.
+type Primary struct {
+	pb.UnimplementedReplicationServer
+
+	namespaces *storage.Namespaces
+	logID      string
+
+	mu sync.Mutex
+	// log is a ring holding the latest mutations; offset o is at
+	// log[o%len(log)].
+	log    []*pb.Mutation
+	offset uint64
+	// changed is closed and replaced whenever offset advances.
+	changed chan struct{}
+	// done is closed by Close to end every stream.
+	done chan struct{}
+
+	replicasMu sync.Mutex
+	replicas   map[*replicaStream]struct{}
+}
+
+type replicaStream struct {
+	id    string
+	addr  string
+	since time.Time
+
+	mu     sync.Mutex
+	offset uint64
+}
+
+// NewPrimary starts a new mutation log for namespaces. Its ID is random,
+// so replicas of a previous log, such as before a restart, resync.
+func NewPrimary(namespaces *storage.Namespaces, opts ...PrimaryOption) *Primary {
+	id := make([]byte, 8)
+	rand.Read(id)
+
+	p := &Primary{
+		namespaces: namespaces,
+		logID:      hex.EncodeToString(id),
+		log:        make([]*pb.Mutation, DefaultLogSize),
+		changed:    make(chan struct{}),
+		done:       make(chan struct{}),
+		replicas:   make(map[*replicaStream]struct{}),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Set stores r in namespace and returns its offset.
+func (p *Primary) Set(ctx context.Context, namespace string, r storage.Record) (uint64, error) {
+	_, offset, err := p.commit(ctx, mutation{Op: opSet, Namespace: namespace, Record: &r})
+	return offset, err
+}
+
+// Delete removes key from namespace, reports whether it existed and returns
+// its offset, or the current one if there was nothing to delete.
+func (p *Primary) Delete(ctx context.Context, namespace, key string) (bool, uint64, error) {
+	return p.commit(ctx, mutation{Op: opDelete, Namespace: namespace, Key: key})
+}
+
+func (p *Primary) CreateNamespace(ctx context.Context, name string, settings storage.NamespaceSettings) error {
+	_, _, err := p.commit(ctx, mutation{Op: opCreateNamespace, Namespace: name, Settings: &settings})
+	return err
+}
+
+func (p *Primary) DropNamespace(ctx context.Context, name string) error {
+	_, _, err := p.commit(ctx, mutation{Op: opDropNamespace, Namespace: name})
+	return err
+}
+
+func (p *Primary) SetNamespaceQuota(ctx context.Context, name string, quota storage.Quota) error {
+	_, _, err := p.commit(ctx, mutation{Op: opSetQuota, Namespace: name, Settings: &storage.NamespaceSettings{Quota: quota}})
+	return err
+}
+
+// Read serves every consistency right away: the primary applies writes
+// before acknowledging them.
+func (p *Primary) Read(ctx context.Context, opts cluster.ReadOptions) (uint64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.offset, nil
+}
+
+// commit applies m and appends it to the log. Writes that fail, or delete
+// nothing, are not logged.
+func (p *Primary) commit(ctx context.Context, m mutation) (bool, uint64, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return false, 0, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	existed, err := apply(ctx, p.namespaces, m, true)
+	if err != nil {
+		return false, 0, err
+	}
+	if m.Op == opDelete && !existed {
+		return false, p.offset, nil
+	}
+
+	p.offset++
+	p.log[p.offset%uint64(len(p.log))] = &pb.Mutation{Offset: p.offset, Data: data}
+	close(p.changed)
+	p.changed = make(chan struct{})
+
+	return existed, p.offset, nil
+}
+
+// since returns the retained mutations after offset and a channel closed
+// once more are logged. ok is false if the mutations right after offset
+// are no longer retained, or offset is ahead of the log.
+func (p *Primary) since(offset uint64) (_ []*pb.Mutation, changed <-chan struct{}, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	size := uint64(len(p.log))
+	if offset > p.offset || p.offset-offset > size {
+		return nil, p.changed, false
+	}
+
+	var out []*pb.Mutation
+	for o := offset + 1; o <= p.offset && len(out) < maxBatch; o++ {
+		out = append(out, p.log[o%size])
+	}
+	return out, p.changed, true
+}
+
+// snapshot returns the state of every namespace and the offset it reflects.
+func (p *Primary) snapshot() ([]storage.NamespaceSnapshot, uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.namespaces.Snapshot(), p.offset
+}
+
+// StreamMutations implements the Replication service.
+func (p *Primary) StreamMutations(req *pb.StreamMutationsRequest, stream pb.Replication_StreamMutationsServer) error {
+	ctx := stream.Context()
+
+	rs := &replicaStream{id: req.GetReplicaId(), since: time.Now()}
+	if pr, ok := peer.FromContext(ctx); ok {
+		rs.addr = pr.Addr.String()
+	}
+	p.track(rs)
+	defer p.untrack(rs)
+
+	cursor := req.GetOffset()
+	resync := req.GetLogId() != p.logID
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return status.Error(codes.Unavailable, "primary is shutting down")
+		default:
+		}
+
+		var (
+			batch   []*pb.Mutation
+			changed <-chan struct{}
+		)
+		if !resync {
+			var ok bool
+			batch, changed, ok = p.since(cursor)
+			resync = !ok
+		}
+
+		if resync {
+			offset, err := p.sendSnapshot(stream)
+			if err != nil {
+				return err
+			}
+			cursor, resync = offset, false
+			rs.sent(cursor)
+			continue
+		}
+
+		for _, m := range batch {
+			if err := stream.Send(&pb.StreamMutationsResponse{Message: &pb.StreamMutationsResponse_Mutation{Mutation: m}}); err != nil {
+				return err
+			}
+			cursor = m.Offset
+		}
+		if len(batch) > 0 {
+			rs.sent(cursor)
+			continue
+		}
+
+		select {
+		case <-changed:
+		case <-heartbeat.C:
+			offset := p.Offset()
+			if err := stream.Send(&pb.StreamMutationsResponse{Message: &pb.StreamMutationsResponse_Heartbeat{Heartbeat: &pb.Heartbeat{Offset: offset}}}); err != nil {
+				return err
+			}
+		case <-p.done:
+			return status.Error(codes.Unavailable, "primary is shutting down")
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		}
+	}
+}
+
+// sendSnapshot streams the primary's state in chunks and returns the offset
+// it reflects.
+func (p *Primary) sendSnapshot(stream pb.Replication_StreamMutationsServer) (uint64, error) {
+	snaps, offset := p.snapshot()
+	data, err := json.Marshal(snaps)
+	if err != nil {
+		return 0, status.Errorf(codes.Internal, "failed to encode snapshot: %v", err)
+	}
+
+	err = sendChunks(p.logID, offset, data, func(chunk *pb.SnapshotChunk) error {
+		return stream.Send(&pb.StreamMutationsResponse{Message: &pb.StreamMutationsResponse_Snapshot{Snapshot: chunk}})
+	})
+	return offset, err
+}
+
+// sendChunks sends data, a snapshot of log logID as of offset, in chunks of
+// at most snapshotChunkBytes.
+func sendChunks(logID string, offset uint64, data []byte, send func(*pb.SnapshotChunk) error) error {
+	for {
+		n := min(len(data), snapshotChunkBytes)
+		chunk := &pb.SnapshotChunk{LogId: logID, Offset: offset, Data: data[:n], Last: n == len(data)}
+		if err := send(chunk); err != nil {
+			return err
+		}
+		data = data[n:]
+		if chunk.Last {
+			return nil
+		}
+	}
+}
+
+// Offset is the last mutation logged.
+func (p *Primary) Offset() uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.offset
+}
+
+func (p *Primary) Status() Status {
+	st := Status{Role: RolePrimary, LogID: p.logID, Offset: p.Offset()}
+
+	p.replicasMu.Lock()
+	for rs := range p.replicas {
+		rs.mu.Lock()
+		st.Replicas = append(st.Replicas, ReplicaStatus{ID: rs.id, Addr: rs.addr, Offset: rs.offset, ConnectedSince: rs.since})
+		rs.mu.Unlock()
+	}
+	p.replicasMu.Unlock()
+
+	sort.Slice(st.Replicas, func(i, j int) bool {
+		if st.Replicas[i].ID != st.Replicas[j].ID {
+			return st.Replicas[i].ID < st.Replicas[j].ID
+		}
+		return st.Replicas[i].Addr < st.Replicas[j].Addr
+	})
+	return st
+}
+
+// Close ends every stream, so that a graceful stop of the gRPC server does
+// not wait for replicas to disconnect. Writes are still accepted.
+func (p *Primary) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	select {
+	case <-p.done:
+	default:
+		close(p.done)
+	}
+}
+
+func (p *Primary) track(rs *replicaStream) {
+	p.replicasMu.Lock()
+	defer p.replicasMu.Unlock()
+
+	p.replicas[rs] = struct{}{}
+}
+
+func (p *Primary) untrack(rs *replicaStream) {
+	p.replicasMu.Lock()
+	defer p.replicasMu.Unlock()
+
+	delete(p.replicas, rs)
+}
+
+func (rs *replicaStream) sent(offset uint64) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	rs.offset = offset
+}
@@ -0,0 +1,364 @@
+package replication
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"kvstore/internal/cluster"
+	"kvstore/internal/storage"
+	pb "kvstore/pkg/pb/api/proto"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// DefaultRetryInterval is how long a replica waits before reconnecting to
+// the primary by default.
+const DefaultRetryInterval = time.Second
+
+type ReplicaOptions struct {
+	// ID names the replica in the primary's status.
+	ID string
+	// PrimaryAddr is the gRPC address of the primary. Writes are redirected
+	// to it.
+	PrimaryAddr string
+	// DialOptions are used to connect to the primary, such as transport
+	// credentials and an API key.
+	DialOptions []grpc.DialOption
+	// RetryInterval is how long to wait before reconnecting after the
+	// stream breaks. Zero uses DefaultRetryInterval.
+	RetryInterval time.Duration
+	// AntiEntropyInterval is how often to compare the replica with the
+	// primary and repair what diverged. Zero disables it.
+	AntiEntropyInterval time.Duration
+}
+
+// Replica applies the mutations of a primary to its namespaces, which it
+// serves reads from. It rejects writes with a *ReadOnlyError.
+type Replica struct {
+	namespaces *storage.Namespaces
+	opts       ReplicaOptions
+
+	// applyMu is held while applying mutations and snapshots, so
+	// anti-entropy sees and repairs a state no mutation is half applied to.
+	applyMu sync.Mutex
+
+	mu sync.Mutex
+	// client calls the primary while Run runs.
+	client        pb.ReplicationClient
+	logID         string
+	applied       uint64
+	primaryOffset uint64
+	lastContact   time.Time
+	connected     bool
+	resyncs       uint64
+	// changed is closed and replaced whenever applied changes.
+	changed chan struct{}
+
+	lastRepair     time.Time
+	lastRepairErr  error
+	rangesRepaired uint64
+	keysRepaired   uint64
+}
+
+// NewReplica returns a replica of the primary at opts.PrimaryAddr applying
+// to namespaces. Whatever namespaces holds is replaced by the primary's
+// state on the first sync.
+func NewReplica(namespaces *storage.Namespaces, opts ReplicaOptions) *Replica {
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = DefaultRetryInterval
+	}
+	return &Replica{namespaces: namespaces, opts: opts, changed: make(chan struct{})}
+}
+
+// Run streams from the primary until ctx is done, reconnecting whenever the
+// stream breaks and resuming after the last mutation applied.
+func (r *Replica) Run(ctx context.Context) error {
+	conn, err := grpc.NewClient(r.opts.PrimaryAddr, r.opts.DialOptions...)
+	if err != nil {
+		return fmt.Errorf("replication: failed to connect to the primary: %w", err)
+	}
+	defer conn.Close()
+	client := pb.NewReplicationClient(conn)
+
+	r.mu.Lock()
+	r.client = client
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		r.client = nil
+		r.mu.Unlock()
+	}()
+
+	if r.opts.AntiEntropyInterval > 0 {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go r.antiEntropy(ctx, r.opts.AntiEntropyInterval)
+	}
+
+	for {
+		err := r.stream(ctx, client)
+		r.setConnected(false)
+		if ctx.Err() != nil {
+			return nil
+		}
+		slog.Warn("Replication stream from the primary broke, reconnecting",
+			"primary", r.opts.PrimaryAddr, "error", err, "retry_in", r.opts.RetryInterval)
+
+		select {
+		case <-time.After(r.opts.RetryInterval):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// stream applies what the primary sends until the stream breaks.
+func (r *Replica) stream(ctx context.Context, client pb.ReplicationClient) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	r.mu.Lock()
+	req := &pb.StreamMutationsRequest{LogId: r.logID, Offset: r.applied, ReplicaId: r.opts.ID}
+	r.mu.Unlock()
+
+	stream, err := client.StreamMutations(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	var snapshot bytes.Buffer
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return errors.New("primary closed the stream")
+		}
+		if err != nil {
+			return err
+		}
+		r.contact()
+
+		switch msg := resp.Message.(type) {
+		case *pb.StreamMutationsResponse_Mutation:
+			if err := r.applyMutation(ctx, msg.Mutation); err != nil {
+				// Start over from a snapshot rather than diverge.
+				r.forget()
+				return err
+			}
+
+		case *pb.StreamMutationsResponse_Snapshot:
+			snapshot.Write(msg.Snapshot.Data)
+			if !msg.Snapshot.Last {
+				continue
+			}
+			err := r.restore(msg.Snapshot.LogId, msg.Snapshot.Offset, snapshot.Bytes())
+			snapshot.Reset()
+			if err != nil {
+				r.forget()
+				return err
+			}
+
+		case *pb.StreamMutationsResponse_Heartbeat:
+			r.mu.Lock()
+			r.primaryOffset = max(r.primaryOffset, msg.Heartbeat.Offset)
+			r.mu.Unlock()
+		}
+	}
+}
+
+func (r *Replica) applyMutation(ctx context.Context, m *pb.Mutation) error {
+	r.applyMu.Lock()
+	defer r.applyMu.Unlock()
+
+	r.mu.Lock()
+	applied := r.applied
+	r.mu.Unlock()
+
+	if m.Offset != applied+1 {
+		return fmt.Errorf("replication: expected mutation %d, got %d", applied+1, m.Offset)
+	}
+
+	var mut mutation
+	if err := json.Unmarshal(m.Data, &mut); err != nil {
+		return fmt.Errorf("replication: corrupt mutation %d: %w", m.Offset, err)
+	}
+	if _, err := apply(ctx, r.namespaces, mut, false); err != nil {
+		return fmt.Errorf("replication: failed to apply mutation %d: %w", m.Offset, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.primaryOffset = max(r.primaryOffset, m.Offset)
+	r.advance(m.Offset)
+	return nil
+}
+
+func (r *Replica) restore(logID string, offset uint64, data []byte) error {
+	var snaps []storage.NamespaceSnapshot
+	if err := json.Unmarshal(data, &snaps); err != nil {
+		return fmt.Errorf("replication: corrupt snapshot: %w", err)
+	}
+
+	r.applyMu.Lock()
+	defer r.applyMu.Unlock()
+	if err := r.namespaces.Restore(snaps); err != nil {
+		return fmt.Errorf("replication: failed to restore snapshot: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.logID = logID
+	r.primaryOffset = offset
+	r.resyncs++
+	r.advance(offset)
+	slog.Info("Replica resynced from a snapshot of the primary", "log_id", logID, "offset", offset)
+	return nil
+}
+
+// advance records the last applied offset, which goes back when the
+// replica starts following a new log. r.mu must be held.
+func (r *Replica) advance(offset uint64) {
+	r.applied = offset
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// forget makes the next stream start with a snapshot.
+func (r *Replica) forget() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.logID = ""
+}
+
+func (r *Replica) contact() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.connected = true
+	r.lastContact = time.Now()
+}
+
+func (r *Replica) setConnected(connected bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.connected = connected
+}
+
+// primary returns a client of the primary, which only exists while Run
+// runs.
+func (r *Replica) primary() (pb.ReplicationClient, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.client == nil {
+		return nil, errors.New("replication: replica is not running")
+	}
+	return r.client, nil
+}
+
+func (r *Replica) readOnly() error {
+	return &ReadOnlyError{PrimaryAddr: r.opts.PrimaryAddr}
+}
+
+func (r *Replica) Set(context.Context, string, storage.Record) (uint64, error) {
+	return 0, r.readOnly()
+}
+
+func (r *Replica) Delete(context.Context, string, string) (bool, uint64, error) {
+	return false, 0, r.readOnly()
+}
+
+func (r *Replica) CreateNamespace(context.Context, string, storage.NamespaceSettings) error {
+	return r.readOnly()
+}
+
+func (r *Replica) DropNamespace(context.Context, string) error {
+	return r.readOnly()
+}
+
+func (r *Replica) SetNamespaceQuota(context.Context, string, storage.Quota) error {
+	return r.readOnly()
+}
+
+// Read blocks until the replica may serve a read with opts and returns the
+// offset it has applied. Linearizable and Lease reads can only be served by
+// the primary and fail with a *ReadOnlyError; Sequential reads wait for
+// MinIndex, an offset returned by the primary; bounded Stale reads fail
+// with a *StaleError once the replica has not heard from the primary
+// within the bound.
+func (r *Replica) Read(ctx context.Context, opts cluster.ReadOptions) (uint64, error) {
+	switch opts.Consistency {
+	case cluster.Linearizable, cluster.Lease:
+		return 0, r.readOnly()
+
+	case cluster.Sequential:
+		return r.waitApplied(ctx, opts.MinIndex)
+
+	case cluster.Stale:
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if opts.MaxStaleness > 0 {
+			if staleness := r.staleness(); staleness > opts.MaxStaleness {
+				return 0, &StaleError{Staleness: staleness, Bound: opts.MaxStaleness, PrimaryAddr: r.opts.PrimaryAddr}
+			}
+		}
+		return r.applied, nil
+	}
+
+	return 0, fmt.Errorf("replication: unknown read consistency %d", opts.Consistency)
+}
+
+// waitApplied blocks until the replica has applied offset.
+func (r *Replica) waitApplied(ctx context.Context, offset uint64) (uint64, error) {
+	for {
+		r.mu.Lock()
+		applied, changed := r.applied, r.changed
+		r.mu.Unlock()
+		if applied >= offset {
+			return applied, nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return 0, fmt.Errorf("replication: offset %d not applied yet, at %d: %w", offset, applied, ctx.Err())
+		}
+	}
+}
+
+// staleness is how long ago the replica last heard from the primary. r.mu
+// must be held.
+func (r *Replica) staleness() time.Duration {
+	if r.lastContact.IsZero() {
+		return math.MaxInt64
+	}
+	return time.Since(r.lastContact)
+}
+
+func (r *Replica) Status() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return Status{
+		Role:            RoleReplica,
+		LogID:           r.logID,
+		Offset:          r.applied,
+		PrimaryAddr:     r.opts.PrimaryAddr,
+		Connected:       r.connected,
+		LastContact:     r.lastContact,
+		PrimaryOffset:   r.primaryOffset,
+		Resyncs:         r.resyncs,
+		LastRepair:      r.lastRepair,
+		LastRepairError: r.lastRepairErr,
+		RangesRepaired:  r.rangesRepaired,
+		KeysRepaired:    r.keysRepaired,
+	}
+}
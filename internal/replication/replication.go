@@ -0,0 +1,197 @@
+// Package replication implements asynchronous primary-replica replication.
+// The primary applies every write to its own namespaces and appends it to
+// an in-memory mutation log; read-only replicas stream the log and apply it
+// in order to theirs. A replica that falls behind the part of the log the
+// primary retains, or that follows a log the primary no longer writes,
+// replaces its state with a snapshot and continues from there.
+//
+// Replication is asynchronous: the primary acknowledges writes before any
+// replica has them, and loses the ones it has not streamed yet if it fails.
+//
+// In active-active mode there is no primary: every site accepts writes and
+// streams the changes it made to every other site, which merge them. Keys
+// hold CRDTs stamped with hybrid logical clocks, so sites that have merged
+// the same changes agree, however long they were partitioned.
+package replication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"kvstore/internal/storage"
+	"time"
+)
+
+// Operations carried by mutations.
+const (
+	opSet             = "set"
+	opDelete          = "delete"
+	opCreateNamespace = "create_namespace"
+	opDropNamespace   = "drop_namespace"
+	opSetQuota        = "set_quota"
+)
+
+// mutation is one write in the log. Sets carry an absolute expiry so that
+// replicas expire the key at the same moment as the primary.
+type mutation struct {
+	Op        string                     `json:"op"`
+	Namespace string                     `json:"namespace,omitempty"`
+	Record    *storage.Record            `json:"record,omitempty"`
+	Key       string                     `json:"key,omitempty"`
+	Settings  *storage.NamespaceSettings `json:"settings,omitempty"`
+}
+
+// apply applies m to namespaces and reports whether a deleted key existed.
+// The primary applies sets checked against the quotas and the memory limit.
+// Replicas apply them unchecked, since rejecting one the primary accepted
+// would leave them diverged, and resyncing would only reject it again.
+func apply(ctx context.Context, namespaces *storage.Namespaces, m mutation, checked bool) (bool, error) {
+	switch m.Op {
+	case opSet:
+		ns, err := namespaces.Get(m.Namespace)
+		if err != nil {
+			return false, err
+		}
+		if !checked {
+			return false, ns.Load(*m.Record)
+		}
+		return false, ns.PutRecordContext(ctx, *m.Record)
+
+	case opDelete:
+		ns, err := namespaces.Get(m.Namespace)
+		if err != nil {
+			return false, err
+		}
+		return ns.DeleteContext(ctx, m.Key)
+
+	case opCreateNamespace:
+		_, err := namespaces.Create(m.Namespace, *m.Settings)
+		return false, err
+
+	case opDropNamespace:
+		return false, namespaces.Drop(m.Namespace)
+
+	case opSetQuota:
+		_, err := namespaces.SetQuota(m.Namespace, m.Settings.Quota)
+		return false, err
+	}
+
+	return false, fmt.Errorf("replication: unknown operation %q", m.Op)
+}
+
+// ErrReadOnly is matched by every *ReadOnlyError.
+var ErrReadOnly = errors.New("replication: read-only replica")
+
+// ReadOnlyError is returned for writes, and reads only the primary can
+// serve, sent to a replica.
+type ReadOnlyError struct {
+	// PrimaryAddr is the gRPC address of the primary.
+	PrimaryAddr string
+}
+
+func (e *ReadOnlyError) Error() string {
+	return fmt.Sprintf("replication: read-only replica; the primary is at %s", e.PrimaryAddr)
+}
+
+func (e *ReadOnlyError) Is(target error) bool {
+	return target == ErrReadOnly
+}
+
+// ErrTooStale is matched by every *StaleError.
+var ErrTooStale = errors.New("replication: replica is too stale")
+
+// StaleError is returned for a bounded stale read on a replica that has
+// not heard from the primary within the bound.
+type StaleError struct {
+	// Staleness is how long ago the replica last heard from the primary.
+	Staleness   time.Duration
+	Bound       time.Duration
+	PrimaryAddr string
+}
+
+func (e *StaleError) Error() string {
+	return fmt.Sprintf("replication: replica is too stale: last heard from the primary %s ago, bound is %s",
+		e.Staleness.Round(time.Millisecond), e.Bound)
+}
+
+func (e *StaleError) Is(target error) bool {
+	return target == ErrTooStale
+}
+
+// ErrMultiLeader is returned for linearizable and lease reads sent to an
+// active-active site. Every site accepts writes, so none can tell whether
+// it has seen the latest.
+var ErrMultiLeader = errors.New("replication: active-active sites cannot serve linearizable reads")
+
+// Role is a server's part in replication.
+type Role string
+
+const (
+	RolePrimary Role = "primary"
+	RoleReplica Role = "replica"
+	RoleActive  Role = "active"
+)
+
+// ParseRole accepts the roles a server can be configured with.
+func ParseRole(s string) (Role, error) {
+	switch r := Role(s); r {
+	case RolePrimary, RoleReplica, RoleActive:
+		return r, nil
+	}
+	return "", fmt.Errorf("unknown replication role %q (want %s, %s or %s)", s, RolePrimary, RoleReplica, RoleActive)
+}
+
+// Status is a snapshot of a primary's or a replica's replication state.
+type Status struct {
+	Role  Role
+	LogID string
+	// Offset is the last mutation applied.
+	Offset uint64
+
+	// Replicas are the replicas streaming from a primary.
+	Replicas []ReplicaStatus
+
+	// The remaining fields describe a replica.
+	PrimaryAddr string
+	Connected   bool
+	// LastContact is zero until the replica first hears from the primary.
+	LastContact   time.Time
+	PrimaryOffset uint64
+	Resyncs       uint64
+	// LastRepair is when anti-entropy last compared the replica with the
+	// primary, zero if never, and LastRepairError why it failed.
+	LastRepair      time.Time
+	LastRepairError error
+	// RangesRepaired and KeysRepaired count what anti-entropy found
+	// diverged and repaired.
+	RangesRepaired uint64
+	KeysRepaired   uint64
+
+	// The remaining fields describe an active-active site.
+	SiteID string
+	Peers  []PeerStatus
+}
+
+// ReplicaStatus is a replica as seen by the primary.
+type ReplicaStatus struct {
+	ID   string
+	Addr string
+	// Offset is the last mutation sent to the replica.
+	Offset         uint64
+	ConnectedSince time.Time
+}
+
+// PeerStatus is another active-active site as seen by this one.
+type PeerStatus struct {
+	Addr string
+	// SiteID is empty until the peer first answers.
+	SiteID    string
+	Connected bool
+	// LastContact is zero until the site first hears from the peer.
+	LastContact time.Time
+	// Offset is the last change of the peer's log merged, and PeerOffset
+	// the last one the peer reported.
+	Offset     uint64
+	PeerOffset uint64
+	Resyncs    uint64
+}
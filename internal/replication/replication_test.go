@@ -0,0 +1,200 @@
+package replication
+
+import (
+	"context"
+	"kvstore/internal/cluster"
+	"kvstore/internal/storage"
+	pb "kvstore/pkg/pb/api/proto"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func serve(t *testing.T, p *Primary) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpc.NewServer()
+	pb.RegisterReplicationServer(srv, p)
+	go srv.Serve(ln)
+	t.Cleanup(srv.Stop)
+
+	return ln.Addr().String()
+}
+
+// start runs r until the returned function is called.
+func start(t *testing.T, r *Replica) (stop func()) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, r.Run(ctx))
+	}()
+
+	stop = func() {
+		cancel()
+		<-done
+	}
+	t.Cleanup(stop)
+	return stop
+}
+
+func newReplica(addr string) (*Replica, *storage.Namespaces) {
+	namespaces := storage.NewNamespaces()
+	return NewReplica(namespaces, ReplicaOptions{
+		ID:            "r1",
+		PrimaryAddr:   addr,
+		DialOptions:   []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())},
+		RetryInterval: 10 * time.Millisecond,
+	}), namespaces
+}
+
+// caughtUp waits until r has applied offset.
+func caughtUp(t *testing.T, r *Replica, offset uint64) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := r.Read(ctx, cluster.ReadOptions{Consistency: cluster.Sequential, MinIndex: offset})
+	require.NoError(t, err)
+}
+
+// Test replicas apply the primary's writes in order, including those made
+// before they connected
+func TestReplica_Replicates(t *testing.T) {
+	ctx := context.Background()
+	primary := NewPrimary(storage.NewNamespaces())
+	_, err := primary.Set(ctx, storage.DefaultNamespace, storage.Record{Key: "early", Value: "1"})
+	require.NoError(t, err)
+
+	replica, namespaces := newReplica(serve(t, primary))
+	start(t, replica)
+
+	require.NoError(t, primary.CreateNamespace(ctx, "users", storage.NamespaceSettings{}))
+	_, err = primary.Set(ctx, "users", storage.Record{Key: "a", Value: "1", ExpiresAt: time.Now().Add(time.Hour).Unix()})
+	require.NoError(t, err)
+	_, err = primary.Set(ctx, "users", storage.Record{Key: "a", Value: "2", ExpiresAt: time.Now().Add(time.Hour).Unix()})
+	require.NoError(t, err)
+	existed, offset, err := primary.Delete(ctx, storage.DefaultNamespace, "early")
+	require.NoError(t, err)
+	assert.True(t, existed)
+
+	caughtUp(t, replica, offset)
+	assert.Equal(t, primary.namespaces.Snapshot(), namespaces.Snapshot())
+	assert.EqualValues(t, 1, replica.Status().Resyncs, "a new replica starts from a snapshot")
+
+	status := primary.Status()
+	require.Len(t, status.Replicas, 1)
+	assert.Equal(t, "r1", status.Replicas[0].ID)
+}
+
+// Test a replica applies every write the primary accepted, even one its
+// own memory limit would have rejected, without resyncing
+func TestReplica_AppliesUnchecked(t *testing.T) {
+	ctx := context.Background()
+	primary := NewPrimary(storage.NewNamespaces())
+	replica, namespaces := newReplica(serve(t, primary))
+	namespaces.SetMaxMemory(4)
+	start(t, replica)
+
+	offset, err := primary.Set(ctx, storage.DefaultNamespace, storage.Record{Key: "a", Value: "12345678"})
+	require.NoError(t, err)
+	caughtUp(t, replica, offset)
+	value, _ := namespaces.Default().Get("a")
+	assert.Equal(t, "12345678", value)
+	assert.EqualValues(t, 1, replica.Status().Resyncs, "only the initial snapshot")
+}
+
+// Test a reconnecting replica catches up from the log, and resyncs from a
+// snapshot once it is too far behind
+func TestReplica_CatchUp(t *testing.T) {
+	ctx := context.Background()
+	primary := NewPrimary(storage.NewNamespaces(), WithLogSize(4))
+	replica, namespaces := newReplica(serve(t, primary))
+
+	stop := start(t, replica)
+	offset, err := primary.Set(ctx, storage.DefaultNamespace, storage.Record{Key: "a", Value: "1"})
+	require.NoError(t, err)
+	caughtUp(t, replica, offset)
+	stop()
+
+	for _, v := range []string{"2", "3", "4"} {
+		offset, err = primary.Set(ctx, storage.DefaultNamespace, storage.Record{Key: "a", Value: v})
+		require.NoError(t, err)
+	}
+	stop = start(t, replica)
+	caughtUp(t, replica, offset)
+	value, _ := namespaces.Default().Get("a")
+	assert.Equal(t, "4", value)
+	assert.EqualValues(t, 1, replica.Status().Resyncs, "the missed writes were still in the log")
+	stop()
+
+	for i := range 10 {
+		offset, err = primary.Set(ctx, storage.DefaultNamespace, storage.Record{Key: "b", Value: string(rune('a' + i))})
+		require.NoError(t, err)
+	}
+	start(t, replica)
+	caughtUp(t, replica, offset)
+	value, _ = namespaces.Default().Get("b")
+	assert.Equal(t, "j", value)
+	assert.EqualValues(t, 2, replica.Status().Resyncs)
+}
+
+// Test a replica of a restarted primary resyncs, even though offsets
+// restart from zero
+func TestReplica_NewLog(t *testing.T) {
+	ctx := context.Background()
+	namespaces := storage.NewNamespaces()
+	first := NewPrimary(namespaces)
+	replica, replicated := newReplica(serve(t, first))
+	stop := start(t, replica)
+
+	offset, err := first.Set(ctx, storage.DefaultNamespace, storage.Record{Key: "a", Value: "1"})
+	require.NoError(t, err)
+	caughtUp(t, replica, offset)
+	stop()
+
+	second := NewPrimary(namespaces)
+	_, err = second.Set(ctx, storage.DefaultNamespace, storage.Record{Key: "b", Value: "2"})
+	require.NoError(t, err)
+	replica.opts.PrimaryAddr = serve(t, second)
+	start(t, replica)
+
+	require.Eventually(t, func() bool {
+		return replica.Status().LogID == second.logID
+	}, 5*time.Second, 10*time.Millisecond)
+	value, _ := replicated.Default().Get("b")
+	assert.Equal(t, "2", value)
+}
+
+// Test replicas reject writes and strong reads with the primary's address,
+// and bounded stale reads once the primary is unreachable
+func TestReplica_ReadOnly(t *testing.T) {
+	ctx := context.Background()
+	replica, _ := newReplica("127.0.0.1:1")
+
+	_, err := replica.Set(ctx, storage.DefaultNamespace, storage.Record{Key: "a", Value: "1"})
+	var readOnly *ReadOnlyError
+	require.ErrorAs(t, err, &readOnly)
+	assert.Equal(t, "127.0.0.1:1", readOnly.PrimaryAddr)
+	assert.ErrorIs(t, replica.DropNamespace(ctx, "users"), ErrReadOnly)
+
+	_, err = replica.Read(ctx, cluster.ReadOptions{Consistency: cluster.Linearizable})
+	assert.ErrorIs(t, err, ErrReadOnly)
+
+	_, err = replica.Read(ctx, cluster.ReadOptions{Consistency: cluster.Stale, MaxStaleness: time.Second})
+	var stale *StaleError
+	require.ErrorAs(t, err, &stale)
+	assert.Equal(t, "127.0.0.1:1", stale.PrimaryAddr)
+
+	_, err = replica.Read(ctx, cluster.ReadOptions{Consistency: cluster.Stale})
+	assert.NoError(t, err, "unbounded stale reads are always served")
+}
@@ -0,0 +1,992 @@
+package replication
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"kvstore/internal/cluster"
+	"kvstore/internal/crdt"
+	"kvstore/internal/hlc"
+	"kvstore/internal/storage"
+	pb "kvstore/pkg/pb/api/proto"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type SiteOptions struct {
+	// ID names the site. Every site needs a different one: it breaks ties
+	// between writes made at the same time on different sites.
+	ID string
+	// Peers are the gRPC addresses of every other site.
+	Peers []string
+	// DialOptions are used to connect to peers, such as transport
+	// credentials and an API key.
+	DialOptions []grpc.DialOption
+	// RetryInterval is how long to wait before reconnecting to a peer after
+	// its stream breaks. Zero uses DefaultRetryInterval.
+	RetryInterval time.Duration
+	// LogSize is how many recent changes the site retains for peers to
+	// catch up from. Zero uses DefaultLogSize.
+	LogSize int
+	// Clock stamps the site's writes. Nil uses the system clock, bounded
+	// by MaxClockOffset.
+	Clock *hlc.Clock
+	// MaxClockOffset is how far ahead of the system clock the changes of
+	// other sites may be stamped. Later ones are not merged until the
+	// system clock catches up. Zero uses DefaultMaxClockOffset.
+	MaxClockOffset time.Duration
+}
+
+// DefaultMaxClockOffset is how far ahead of a site's clock the changes of
+// other sites may be stamped by default.
+const DefaultMaxClockOffset = 500 * time.Millisecond
+
+// collectInterval is how often a site collects the tombstones every site
+// has merged.
+const collectInterval = 10 * time.Second
+
+// errUnstable is returned when merging a change that started an entry on a
+// tombstone the peer collected, while this site cannot collect it yet.
+var errUnstable = errors.New("replication: change depends on a tombstone not yet collected")
+
+// Site is one of several servers that all accept writes, typically in
+// different locations. It applies writes to its own namespaces right away,
+// stamped with its hybrid logical clock, and logs them for the other sites
+// to stream; it merges the changes it streams from them in turn.
+//
+// A Set or Delete replaces the key on every site unless a later one
+// arrives, counters add up the increments made on every site, and sets
+// keep the members added on any site unless a removal saw the addition.
+// Namespaces merge alike: the later drop or create wins, and dropping a
+// namespace discards the keys written to it, on every site, until it is
+// created again.
+//
+// A site keeps the state of every key it has seen besides its namespaces,
+// deleted and expired ones included, so that changes still arriving from
+// another site cannot bring them back. It counts against the memory limit
+// of the namespaces. Deleted and expired keys are collected once every
+// site has merged the changes made up to their deletion, so that none can
+// still arrive; sites that cannot be reached hold collection up.
+type Site struct {
+	pb.UnimplementedReplicationServer
+
+	namespaces *storage.Namespaces
+	opts       SiteOptions
+	clock      *hlc.Clock
+	logID      string
+	peers      []*peerSite
+
+	mu     sync.Mutex
+	spaces map[string]*siteNamespace
+	// log is a ring holding the latest changes made on the site; offset o
+	// is at log[o%len(log)].
+	log    []*pb.Mutation
+	offset uint64
+	// changed is closed and replaced whenever offset advances.
+	changed chan struct{}
+	// done is closed by Close to end every stream.
+	done chan struct{}
+}
+
+type siteNamespace struct {
+	state namespaceState
+	// keys holds the entry of every key written to the current incarnation.
+	// It is nil while the namespace is dropped.
+	keys map[string]crdt.Entry
+	// bytes approximates the memory keys takes.
+	bytes int64
+}
+
+// vector is how far a site has merged the changes of each other site: the
+// time of the last one, by site ID.
+type vector map[string]hlc.Timestamp
+
+// namespaceState is whether a namespace exists and its settings. Keys
+// belong to an incarnation of their namespace, and are discarded when a
+// drop ends it.
+type namespaceState struct {
+	// Incarnation is the drop the namespace was last created after, or zero
+	// if it never was, and while Dropped the drop itself. Sites creating a
+	// namespace concurrently thus create the same incarnation.
+	Incarnation crdt.Stamp                `json:"incarnation"`
+	Dropped     bool                      `json:"dropped,omitempty"`
+	Settings    storage.NamespaceSettings `json:"settings"`
+	// Updated is the last create or quota change, whose settings win.
+	Updated crdt.Stamp `json:"updated"`
+}
+
+// compare orders states by incarnation, then the namespace created on a
+// drop after the drop, then by the settings' last update.
+func (s namespaceState) compare(o namespaceState) int {
+	if c := s.Incarnation.Compare(o.Incarnation); c != 0 {
+		return c
+	}
+	if s.Dropped != o.Dropped {
+		if s.Dropped {
+			return -1
+		}
+		return 1
+	}
+	return s.Updated.Compare(o.Updated)
+}
+
+// change is a write made on a site: the state of its namespace, and for
+// writes to a key, the key's entry after it.
+type change struct {
+	Time      hlc.Timestamp  `json:"time"`
+	Namespace string         `json:"namespace"`
+	State     namespaceState `json:"state"`
+	Key       string         `json:"key,omitempty"`
+	Entry     *crdt.Entry    `json:"entry,omitempty"`
+	// Seen is the vector of the site as of the change.
+	Seen vector `json:"seen,omitempty"`
+}
+
+type peerSite struct {
+	addr string
+
+	mu          sync.Mutex
+	siteID      string
+	logID       string
+	offset      uint64
+	peerOffset  uint64
+	connected   bool
+	lastContact time.Time
+	resyncs     uint64
+	// merged is the time of the last change merged from the peer, and seen
+	// its vector as of that change or a later heartbeat.
+	merged hlc.Timestamp
+	seen   vector
+}
+
+// NewSite returns a site applying writes to namespaces, which must hold
+// nothing but the empty default namespace. Its log ID is random, so peers
+// that streamed from it before a restart merge a snapshot of it again.
+func NewSite(namespaces *storage.Namespaces, opts SiteOptions) *Site {
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = DefaultRetryInterval
+	}
+	if opts.LogSize <= 0 {
+		opts.LogSize = DefaultLogSize
+	}
+	if opts.MaxClockOffset <= 0 {
+		opts.MaxClockOffset = DefaultMaxClockOffset
+	}
+	clock := opts.Clock
+	if clock == nil {
+		clock = hlc.NewClock(nil, hlc.WithMaxOffset(opts.MaxClockOffset))
+	}
+	id := make([]byte, 8)
+	rand.Read(id)
+
+	s := &Site{
+		namespaces: namespaces,
+		opts:       opts,
+		clock:      clock,
+		logID:      hex.EncodeToString(id),
+		spaces: map[string]*siteNamespace{
+			storage.DefaultNamespace: {keys: make(map[string]crdt.Entry)},
+		},
+		log:     make([]*pb.Mutation, opts.LogSize),
+		changed: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	for _, addr := range opts.Peers {
+		s.peers = append(s.peers, &peerSite{addr: addr})
+	}
+	return s
+}
+
+func (s *Site) stamp() crdt.Stamp {
+	return crdt.Stamp{Time: s.clock.Now(), Site: s.opts.ID}
+}
+
+// vector returns how far the site has merged the changes of each peer it
+// knows the ID of.
+func (s *Site) vector() vector {
+	v := make(vector, len(s.peers))
+	for _, p := range s.peers {
+		p.mu.Lock()
+		if p.siteID != "" {
+			v[p.siteID] = p.merged
+		}
+		p.mu.Unlock()
+	}
+	return v
+}
+
+// stable reports whether an entry born at t can no longer lose to another
+// change: the site has merged every change made up to t on any site, and
+// every peer has merged the one made at t.
+func (s *Site) stable(t crdt.Stamp) bool {
+	for _, p := range s.peers {
+		p.mu.Lock()
+		ok := p.siteID != ""
+		if p.siteID == t.Site {
+			ok = ok && p.merged.Compare(t.Time) >= 0
+		} else {
+			// The peer stamps every write after merging t later than t.
+			ok = ok && p.seen[t.Site].Compare(t.Time) >= 0
+		}
+		p.mu.Unlock()
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// keySize approximates the memory the site takes for key's entry e.
+func keySize(key string, e crdt.Entry) int64 {
+	return int64(len(key)) + e.Size()
+}
+
+// Set stores r in namespace, replacing whatever the key held, and returns
+// the change's offset.
+func (s *Site) Set(ctx context.Context, namespace string, r storage.Record) (uint64, error) {
+	_, offset, err := s.write(ctx, namespace, r.Key, func(_ crdt.Entry, now crdt.Stamp) (crdt.Entry, bool, error) {
+		return crdt.NewRegister(now, r.Value, r.Owner, r.ExpiresAt), true, nil
+	})
+	return offset, err
+}
+
+// Delete removes key from namespace, reports whether it existed and returns
+// the change's offset, or the current one if there was nothing to delete.
+func (s *Site) Delete(ctx context.Context, namespace, key string) (bool, uint64, error) {
+	var existed bool
+	_, offset, err := s.write(ctx, namespace, key, func(e crdt.Entry, now crdt.Stamp) (crdt.Entry, bool, error) {
+		_, existed = unexpired(e).Read()
+		return crdt.Tombstone(now), existed, nil
+	})
+	return existed, offset, err
+}
+
+// Increment adds delta to the counter key holds, starting one if the key
+// does not exist, and returns its total and the change's offset.
+func (s *Site) Increment(ctx context.Context, namespace, key, owner string, delta int64) (int64, uint64, error) {
+	e, offset, err := s.write(ctx, namespace, key, func(e crdt.Entry, now crdt.Stamp) (crdt.Entry, bool, error) {
+		e, err := unexpired(e).Increment(now, owner, delta)
+		return e, true, err
+	})
+	return e.Total(), offset, err
+}
+
+// AddMembers adds members to the set key holds, starting one if the key
+// does not exist, and returns its members and the change's offset.
+func (s *Site) AddMembers(ctx context.Context, namespace, key, owner string, members []string) ([]string, uint64, error) {
+	e, offset, err := s.write(ctx, namespace, key, func(e crdt.Entry, now crdt.Stamp) (crdt.Entry, bool, error) {
+		e, err := unexpired(e).Add(now, owner, members...)
+		return e, true, err
+	})
+	return e.MemberList(), offset, err
+}
+
+// RemoveMembers removes members from the set key holds and returns its
+// remaining members and the change's offset.
+func (s *Site) RemoveMembers(ctx context.Context, namespace, key string, members []string) ([]string, uint64, error) {
+	e, offset, err := s.write(ctx, namespace, key, func(e crdt.Entry, _ crdt.Stamp) (crdt.Entry, bool, error) {
+		e = unexpired(e)
+		removed, err := e.Remove(members...)
+		return removed, e.Kind != crdt.None, err
+	})
+	return e.MemberList(), offset, err
+}
+
+// unexpired returns e, or a tombstone in its place if it expired. Counters
+// and sets started on the tombstone replace e on every site.
+func unexpired(e crdt.Entry) crdt.Entry {
+	if e.Expired(time.Now().Unix()) {
+		return crdt.Tombstone(e.Born)
+	}
+	return e
+}
+
+// write changes the entry of key with update, which is passed the entry
+// and a new stamp, and logs the result. Updates that change nothing return
+// false, and the current entry and offset are returned.
+func (s *Site) write(ctx context.Context, namespace, key string, update func(crdt.Entry, crdt.Stamp) (crdt.Entry, bool, error)) (crdt.Entry, uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ns, err := s.namespaces.Get(namespace)
+	if err != nil {
+		return crdt.Entry{}, 0, err
+	}
+	sn := s.spaces[namespace]
+	if sn == nil || sn.state.Dropped {
+		return crdt.Entry{}, 0, fmt.Errorf("%w: %s", storage.ErrNamespaceNotFound, namespace)
+	}
+
+	now := s.stamp()
+	old, existed := sn.keys[key]
+	e, changed, err := update(old, now)
+	if err != nil {
+		return crdt.Entry{}, 0, err
+	}
+	if !changed {
+		return old, s.offset, nil
+	}
+
+	data, err := json.Marshal(change{Time: now.Time, Namespace: namespace, State: sn.state, Key: key, Entry: &e, Seen: s.vector()})
+	if err != nil {
+		return crdt.Entry{}, 0, err
+	}
+	grow := keySize(key, e)
+	if existed {
+		grow -= keySize(key, old)
+	}
+	memory := s.namespaces.Memory()
+	if err := memory.Reserve(grow); err != nil {
+		return crdt.Entry{}, 0, err
+	}
+	if err := materialize(ctx, ns, key, e, true); err != nil {
+		memory.Charge(-grow)
+		return crdt.Entry{}, 0, err
+	}
+	sn.keys[key] = e
+	sn.bytes += grow
+	return e, s.append(data), nil
+}
+
+// put stores e as the entry of key in sn, charging the memory it takes
+// whether or not it fits. s.mu must be held.
+func (s *Site) put(sn *siteNamespace, key string, e crdt.Entry) {
+	grow := keySize(key, e)
+	if old, ok := sn.keys[key]; ok {
+		grow -= keySize(key, old)
+	}
+	sn.keys[key] = e
+	sn.bytes += grow
+	s.namespaces.Memory().Charge(grow)
+}
+
+// discard releases the memory taken by the keys of sn, whose incarnation
+// ended. s.mu must be held.
+func (s *Site) discard(sn *siteNamespace) {
+	if sn != nil {
+		s.namespaces.Memory().Charge(-sn.bytes)
+	}
+}
+
+// materialize stores what reads of key see in e. Local writes are held to
+// quotas; changes merged from other sites are not, since the site that
+// made them accepted them.
+func materialize(ctx context.Context, ns *storage.Namespace, key string, e crdt.Entry, local bool) error {
+	value, ok := e.Read()
+	if !ok || e.Expired(time.Now().Unix()) {
+		_, err := ns.DeleteContext(ctx, key)
+		return err
+	}
+
+	r := storage.Record{Key: key, Value: value, Owner: e.Owner, ExpiresAt: e.ExpiresAt}
+	if local {
+		return ns.PutRecordContext(ctx, r)
+	}
+	return ns.Load(r)
+}
+
+func (s *Site) CreateNamespace(ctx context.Context, name string, settings storage.NamespaceSettings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := namespaceState{Settings: settings, Updated: s.stamp()}
+	if sn := s.spaces[name]; sn != nil {
+		state.Incarnation = sn.state.Incarnation
+	}
+	data, err := json.Marshal(change{Time: state.Updated.Time, Namespace: name, State: state})
+	if err != nil {
+		return err
+	}
+	if _, err := s.namespaces.Create(name, settings); err != nil {
+		return err
+	}
+	s.spaces[name] = &siteNamespace{state: state, keys: make(map[string]crdt.Entry)}
+	s.append(data)
+	return nil
+}
+
+func (s *Site) DropNamespace(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.stamp()
+	state := namespaceState{Incarnation: now, Dropped: true, Updated: now}
+	data, err := json.Marshal(change{Time: now.Time, Namespace: name, State: state})
+	if err != nil {
+		return err
+	}
+	if err := s.namespaces.Drop(name); err != nil {
+		return err
+	}
+	s.discard(s.spaces[name])
+	s.spaces[name] = &siteNamespace{state: state}
+	s.append(data)
+	return nil
+}
+
+func (s *Site) SetNamespaceQuota(ctx context.Context, name string, quota storage.Quota) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sn := s.spaces[name]
+	if sn == nil || sn.state.Dropped {
+		return fmt.Errorf("%w: %s", storage.ErrNamespaceNotFound, name)
+	}
+	state := sn.state
+	state.Settings.Quota = quota
+	state.Updated = s.stamp()
+	data, err := json.Marshal(change{Time: state.Updated.Time, Namespace: name, State: state})
+	if err != nil {
+		return err
+	}
+	if _, err := s.namespaces.SetQuota(name, quota); err != nil {
+		return err
+	}
+	sn.state = state
+	s.append(data)
+	return nil
+}
+
+// append logs a change and returns its offset. s.mu must be held.
+func (s *Site) append(data []byte) uint64 {
+	s.offset++
+	s.log[s.offset%uint64(len(s.log))] = &pb.Mutation{Offset: s.offset, Data: data}
+	close(s.changed)
+	s.changed = make(chan struct{})
+	return s.offset
+}
+
+// merge applies a change made on another site, streamed from p.
+func (s *Site) merge(ctx context.Context, p *peerSite, c change) error {
+	if err := s.clock.Update(c.Time); err != nil {
+		return fmt.Errorf("replication: refusing a change from %s: %w", p.addr, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sn := s.spaces[c.Namespace]
+	if sn == nil || c.State.compare(sn.state) > 0 {
+		if err := s.adopt(c.Namespace, sn, c.State); err != nil {
+			return fmt.Errorf("replication: failed to merge namespace %s: %w", c.Namespace, err)
+		}
+		sn = s.spaces[c.Namespace]
+	}
+	// Keys written to an incarnation that has since been dropped are
+	// discarded with it.
+	if c.Entry == nil || sn.state.Dropped || sn.state.Incarnation != c.State.Incarnation {
+		return nil
+	}
+
+	ns, err := s.namespaces.Get(c.Namespace)
+	if err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	local, e := sn.keys[c.Key], *c.Entry
+	switch {
+	case e.Dead(now) && s.stable(e.Born):
+		// Collected here, or about to be.
+		return nil
+	case !e.Dead(now) && local.Dead(now) && e.Born.Compare(local.Born) < 0 && p.after(c, local.Born):
+		// The peer collected the tombstone before starting a counter or
+		// set in its place, which must not lose to it here.
+		if !s.stable(local.Born) {
+			return errUnstable
+		}
+		local = crdt.Entry{}
+	}
+	e = crdt.Merge(local, e)
+	s.put(sn, c.Key, e)
+	return materialize(ctx, ns, c.Key, e, false)
+}
+
+// collect deletes the entries of keys that are deleted or expired and can
+// no longer lose to another change.
+func (s *Site) collect() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().Unix()
+	for _, sn := range s.spaces {
+		for key, e := range sn.keys {
+			if e.Dead(now) && s.stable(e.Born) {
+				delete(sn.keys, key)
+				sn.bytes -= keySize(key, e)
+				s.namespaces.Memory().Charge(-keySize(key, e))
+			}
+		}
+	}
+}
+
+// adopt makes the namespace name, currently sn if the site knows it, match
+// state, which supersedes it. s.mu must be held.
+func (s *Site) adopt(name string, sn *siteNamespace, state namespaceState) error {
+	exists := sn != nil && !sn.state.Dropped
+
+	switch {
+	case state.Dropped:
+		if exists {
+			if err := s.namespaces.Drop(name); err != nil {
+				return err
+			}
+		}
+		s.discard(sn)
+		s.spaces[name] = &siteNamespace{state: state}
+
+	case exists && sn.state.Incarnation == state.Incarnation:
+		// The same namespace with newer settings. Only the quota can change
+		// in place; the default TTL takes recreating it.
+		if state.Settings.DefaultTTLSeconds == sn.state.Settings.DefaultTTLSeconds {
+			if _, err := s.namespaces.SetQuota(name, state.Settings.Quota); err != nil {
+				return err
+			}
+		} else if err := s.recreate(name, sn.keys, state.Settings); err != nil {
+			return err
+		}
+		sn.state = state
+
+	default:
+		// A new incarnation, without the keys of the one it replaces.
+		if exists {
+			if err := s.namespaces.Drop(name); err != nil {
+				return err
+			}
+		}
+		if _, err := s.namespaces.Create(name, state.Settings); err != nil {
+			return err
+		}
+		s.discard(sn)
+		s.spaces[name] = &siteNamespace{state: state, keys: make(map[string]crdt.Entry)}
+	}
+	return nil
+}
+
+// recreate replaces the namespace name with one with settings holding the
+// same keys.
+func (s *Site) recreate(name string, keys map[string]crdt.Entry, settings storage.NamespaceSettings) error {
+	if err := s.namespaces.Drop(name); err != nil {
+		return err
+	}
+	ns, err := s.namespaces.Create(name, settings)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	var records []storage.Record
+	for key, e := range keys {
+		if value, ok := e.Read(); ok && !e.Expired(now) {
+			records = append(records, storage.Record{Key: key, Value: value, Owner: e.Owner, ExpiresAt: e.ExpiresAt})
+		}
+	}
+	return ns.Load(records...)
+}
+
+// Read serves sequential and stale reads right away: the site applies its
+// own writes before acknowledging them, and there is no primary for it to
+// fall behind. Linearizable and lease reads fail with ErrMultiLeader.
+func (s *Site) Read(ctx context.Context, opts cluster.ReadOptions) (uint64, error) {
+	switch opts.Consistency {
+	case cluster.Linearizable, cluster.Lease:
+		return 0, ErrMultiLeader
+	}
+	return s.Offset(), nil
+}
+
+// Offset is the last change made on the site.
+func (s *Site) Offset() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.offset
+}
+
+// since returns the retained changes after offset and a channel closed
+// once more are logged. ok is false if the changes right after offset are
+// no longer retained, or offset is ahead of the log.
+func (s *Site) since(offset uint64) (_ []*pb.Mutation, changed <-chan struct{}, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	size := uint64(len(s.log))
+	if offset > s.offset || s.offset-offset > size {
+		return nil, s.changed, false
+	}
+
+	var out []*pb.Mutation
+	for o := offset + 1; o <= s.offset && len(out) < maxBatch; o++ {
+		out = append(out, s.log[o%size])
+	}
+	return out, s.changed, true
+}
+
+// snapshot returns the complete state of the site as changes, and the
+// offset it reflects.
+func (s *Site) snapshot() ([]change, uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now, seen := s.clock.Now(), s.vector()
+	names := make([]string, 0, len(s.spaces))
+	for name := range s.spaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var out []change
+	for _, name := range names {
+		sn := s.spaces[name]
+		out = append(out, change{Time: now, Namespace: name, State: sn.state, Seen: seen})
+		for key, e := range sn.keys {
+			out = append(out, change{Time: now, Namespace: name, State: sn.state, Key: key, Entry: &e, Seen: seen})
+		}
+	}
+	return out, s.offset
+}
+
+// StreamChanges implements the Replication service.
+func (s *Site) StreamChanges(req *pb.StreamChangesRequest, stream pb.Replication_StreamChangesServer) error {
+	ctx := stream.Context()
+	send := func(resp *pb.StreamChangesResponse) error {
+		resp.SiteId = s.opts.ID
+		return stream.Send(resp)
+	}
+
+	cursor := req.GetOffset()
+	resync := req.GetLogId() != s.logID
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return status.Error(codes.Unavailable, "site is shutting down")
+		default:
+		}
+
+		var (
+			batch   []*pb.Mutation
+			changed <-chan struct{}
+		)
+		if !resync {
+			var ok bool
+			batch, changed, ok = s.since(cursor)
+			resync = !ok
+		}
+
+		if resync {
+			changes, offset := s.snapshot()
+			data, err := json.Marshal(changes)
+			if err != nil {
+				return status.Errorf(codes.Internal, "failed to encode snapshot: %v", err)
+			}
+			err = sendChunks(s.logID, offset, data, func(chunk *pb.SnapshotChunk) error {
+				return send(&pb.StreamChangesResponse{Message: &pb.StreamChangesResponse_Snapshot{Snapshot: chunk}})
+			})
+			if err != nil {
+				return err
+			}
+			cursor, resync = offset, false
+			continue
+		}
+
+		for _, m := range batch {
+			if err := send(&pb.StreamChangesResponse{Message: &pb.StreamChangesResponse_Change{Change: m}}); err != nil {
+				return err
+			}
+			cursor = m.Offset
+		}
+		if len(batch) > 0 {
+			continue
+		}
+
+		select {
+		case <-changed:
+		case <-heartbeat.C:
+			hb, err := s.heartbeat()
+			if err != nil {
+				return status.Errorf(codes.Internal, "failed to encode heartbeat: %v", err)
+			}
+			if err := send(&pb.StreamChangesResponse{Message: &pb.StreamChangesResponse_Heartbeat{Heartbeat: hb}}); err != nil {
+				return err
+			}
+		case <-s.done:
+			return status.Error(codes.Unavailable, "site is shutting down")
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		}
+	}
+}
+
+// heartbeat returns the site's offset with its vector as of it.
+func (s *Site) heartbeat() (*pb.Heartbeat, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen, err := json.Marshal(s.vector())
+	if err != nil {
+		return nil, err
+	}
+	return &pb.Heartbeat{Offset: s.offset, Seen: seen}, nil
+}
+
+// Close ends every stream, so that a graceful stop of the gRPC server does
+// not wait for peers to disconnect. Writes are still accepted.
+func (s *Site) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.done:
+	default:
+		close(s.done)
+	}
+}
+
+// Run streams the changes of every peer until ctx is done, reconnecting
+// whenever a stream breaks and resuming after the last change merged, and
+// periodically collects deleted and expired keys.
+func (s *Site) Run(ctx context.Context) error {
+	clients := make([]pb.ReplicationClient, len(s.peers))
+	for i, p := range s.peers {
+		conn, err := grpc.NewClient(p.addr, s.opts.DialOptions...)
+		if err != nil {
+			return fmt.Errorf("replication: failed to connect to site %s: %w", p.addr, err)
+		}
+		defer conn.Close()
+		clients[i] = pb.NewReplicationClient(conn)
+	}
+
+	var wg sync.WaitGroup
+	for i, p := range s.peers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.follow(ctx, p, clients[i])
+		}()
+	}
+
+	ticker := time.NewTicker(collectInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.collect()
+		case <-ctx.Done():
+			wg.Wait()
+			return nil
+		}
+	}
+}
+
+// follow streams from p until ctx is done.
+func (s *Site) follow(ctx context.Context, p *peerSite, client pb.ReplicationClient) {
+	for {
+		err := s.stream(ctx, p, client)
+		p.setConnected(false)
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Warn("Change stream from a peer site broke, reconnecting",
+			"peer", p.addr, "error", err, "retry_in", s.opts.RetryInterval)
+
+		select {
+		case <-time.After(s.opts.RetryInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// stream merges what p sends until the stream breaks.
+func (s *Site) stream(ctx context.Context, p *peerSite, client pb.ReplicationClient) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	p.mu.Lock()
+	req := &pb.StreamChangesRequest{LogId: p.logID, Offset: p.offset, SiteId: s.opts.ID}
+	p.mu.Unlock()
+
+	stream, err := client.StreamChanges(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	var snapshot bytes.Buffer
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return errors.New("peer closed the stream")
+		}
+		if err != nil {
+			return err
+		}
+		if resp.SiteId == s.opts.ID {
+			return fmt.Errorf("replication: peer %s is this site, %s", p.addr, s.opts.ID)
+		}
+		p.contact(resp.SiteId)
+
+		switch msg := resp.Message.(type) {
+		case *pb.StreamChangesResponse_Change:
+			if err := s.mergeChange(ctx, p, msg.Change); err != nil {
+				// Start over from a snapshot rather than miss changes,
+				// unless the change can be merged later.
+				if !retry(err) {
+					p.forget()
+				}
+				return err
+			}
+
+		case *pb.StreamChangesResponse_Snapshot:
+			snapshot.Write(msg.Snapshot.Data)
+			if !msg.Snapshot.Last {
+				continue
+			}
+			err := s.mergeSnapshot(ctx, p, msg.Snapshot.LogId, msg.Snapshot.Offset, snapshot.Bytes())
+			snapshot.Reset()
+			if err != nil {
+				if !retry(err) {
+					p.forget()
+				}
+				return err
+			}
+
+		case *pb.StreamChangesResponse_Heartbeat:
+			var seen vector
+			if len(msg.Heartbeat.Seen) > 0 {
+				if err := json.Unmarshal(msg.Heartbeat.Seen, &seen); err != nil {
+					return fmt.Errorf("replication: corrupt heartbeat: %w", err)
+				}
+			}
+			p.mu.Lock()
+			p.peerOffset = max(p.peerOffset, msg.Heartbeat.Offset)
+			// A heartbeat ahead of the changes merged speaks for changes
+			// still to come.
+			if seen != nil && p.offset >= msg.Heartbeat.Offset {
+				p.seen = seen
+			}
+			p.mu.Unlock()
+		}
+	}
+}
+
+// retry reports whether merging a change failed only for now, so that the
+// stream resumes from it rather than start over from a snapshot.
+func retry(err error) bool {
+	return errors.Is(err, errUnstable) || errors.Is(err, hlc.ErrClockOffset)
+}
+
+func (s *Site) mergeChange(ctx context.Context, p *peerSite, m *pb.Mutation) error {
+	p.mu.Lock()
+	offset := p.offset
+	p.mu.Unlock()
+
+	if m.Offset != offset+1 {
+		return fmt.Errorf("replication: expected change %d, got %d", offset+1, m.Offset)
+	}
+
+	var c change
+	if err := json.Unmarshal(m.Data, &c); err != nil {
+		return fmt.Errorf("replication: corrupt change %d: %w", m.Offset, err)
+	}
+	if err := s.merge(ctx, p, c); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.offset = m.Offset
+	p.peerOffset = max(p.peerOffset, m.Offset)
+	p.merged, p.seen = c.Time, c.Seen
+	return nil
+}
+
+func (s *Site) mergeSnapshot(ctx context.Context, p *peerSite, logID string, offset uint64, data []byte) error {
+	var changes []change
+	if err := json.Unmarshal(data, &changes); err != nil {
+		return fmt.Errorf("replication: corrupt snapshot: %w", err)
+	}
+	for _, c := range changes {
+		if err := s.merge(ctx, p, c); err != nil {
+			return err
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(changes) > 0 {
+		p.merged, p.seen = changes[0].Time, changes[0].Seen
+	}
+	p.logID = logID
+	p.offset = offset
+	p.peerOffset = max(p.peerOffset, offset)
+	p.resyncs++
+	slog.Info("Merged a snapshot of a peer site", "peer", p.addr, "site", p.siteID, "log_id", logID, "offset", offset)
+	return nil
+}
+
+func (p *peerSite) contact(siteID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.siteID = siteID
+	p.connected = true
+	p.lastContact = time.Now()
+}
+
+func (p *peerSite) setConnected(connected bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.connected = connected
+}
+
+// after reports whether p made c after it merged the change made at t.
+func (p *peerSite) after(c change, t crdt.Stamp) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.siteID == t.Site {
+		return c.Time.Compare(t.Time) > 0
+	}
+	return c.Seen[t.Site].Compare(t.Time) >= 0
+}
+
+// forget makes the next stream start with a snapshot.
+func (p *peerSite) forget() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.logID = ""
+}
+
+func (s *Site) Status() Status {
+	st := Status{Role: RoleActive, LogID: s.logID, Offset: s.Offset(), SiteID: s.opts.ID}
+	for _, p := range s.peers {
+		p.mu.Lock()
+		st.Peers = append(st.Peers, PeerStatus{
+			Addr:        p.addr,
+			SiteID:      p.siteID,
+			Connected:   p.connected,
+			LastContact: p.lastContact,
+			Offset:      p.offset,
+			PeerOffset:  p.peerOffset,
+			Resyncs:     p.resyncs,
+		})
+		p.mu.Unlock()
+	}
+	return st
+}
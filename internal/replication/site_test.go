@@ -0,0 +1,451 @@
+package replication
+
+import (
+	"context"
+	"fmt"
+	"kvstore/internal/cluster"
+	"kvstore/internal/crdt"
+	"kvstore/internal/hlc"
+	"kvstore/internal/storage"
+	"kvstore/internal/testnet"
+	pb "kvstore/pkg/pb/api/proto"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+type testSite struct {
+	*Site
+	namespaces *storage.Namespaces
+	addr       string
+}
+
+// newSites starts a site for every ID, each streaming from all the others.
+// Options for a site are taken from opts by ID.
+func newSites(t *testing.T, n *testnet.Network, opts map[string]SiteOptions, ids ...string) []*testSite {
+	t.Helper()
+
+	listeners := make([]net.Listener, len(ids))
+	for i := range ids {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		listeners[i] = ln
+	}
+
+	var sites []*testSite
+	for i, id := range ids {
+		addr := listeners[i].Addr().String()
+		o := opts[id]
+		o.ID = id
+		o.RetryInterval = 10 * time.Millisecond
+		o.DialOptions = []grpc.DialOption{
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithConnectParams(grpc.ConnectParams{Backoff: backoff.Config{BaseDelay: 10 * time.Millisecond, Multiplier: 1.6, MaxDelay: 50 * time.Millisecond}}),
+			n.Dialer(addr),
+		}
+		for j, ln := range listeners {
+			if j != i {
+				o.Peers = append(o.Peers, ln.Addr().String())
+			}
+		}
+
+		namespaces := storage.NewNamespaces()
+		site := NewSite(namespaces, o)
+		srv := grpc.NewServer()
+		pb.RegisterReplicationServer(srv, site)
+		go srv.Serve(listeners[i])
+		t.Cleanup(srv.Stop)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			assert.NoError(t, site.Run(ctx))
+		}()
+		t.Cleanup(func() {
+			cancel()
+			<-done
+		})
+
+		sites = append(sites, &testSite{Site: site, namespaces: namespaces, addr: addr})
+	}
+	return sites
+}
+
+// converge waits until every site has merged every change of the others,
+// and checks they then hold the same keys.
+func converge(t *testing.T, sites ...*testSite) {
+	t.Helper()
+
+	offsets := func() map[string]uint64 {
+		out := make(map[string]uint64)
+		for _, s := range sites {
+			out[s.addr] = s.Offset()
+		}
+		return out
+	}
+	require.Eventually(t, func() bool {
+		want := offsets()
+		for _, s := range sites {
+			for _, p := range s.Status().Peers {
+				// Before a peer first answers its offset is zero too.
+				if p.SiteID == "" || p.Offset != want[p.Addr] {
+					return false
+				}
+			}
+		}
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+
+	for _, s := range sites[1:] {
+		assert.Equal(t, sites[0].namespaces.Snapshot(), s.namespaces.Snapshot(), "%s and %s differ", sites[0].opts.ID, s.opts.ID)
+	}
+}
+
+func value(t *testing.T, s *testSite, namespace, key string) string {
+	t.Helper()
+	ns, err := s.namespaces.Get(namespace)
+	require.NoError(t, err)
+	v, _ := ns.Get(key)
+	return v
+}
+
+// Test writes made on any site reach every other one
+func TestSite_Replicates(t *testing.T) {
+	ctx := context.Background()
+	sites := newSites(t, testnet.New(), nil, "a", "b", "c")
+	a, b, c := sites[0], sites[1], sites[2]
+
+	_, err := a.Set(ctx, storage.DefaultNamespace, storage.Record{Key: "k", Value: "1", Owner: "alice"})
+	require.NoError(t, err)
+	require.NoError(t, b.CreateNamespace(ctx, "users", storage.NamespaceSettings{DefaultTTLSeconds: 60}))
+	converge(t, sites...)
+
+	_, err = c.Set(ctx, "users", storage.Record{Key: "u", Value: "v", ExpiresAt: time.Now().Add(time.Hour).Unix()})
+	require.NoError(t, err)
+	for i, s := range sites {
+		total, _, err := s.Increment(ctx, storage.DefaultNamespace, "hits", "", int64(i+1))
+		require.NoError(t, err)
+		assert.Positive(t, total)
+		_, _, err = s.AddMembers(ctx, storage.DefaultNamespace, "tags", "", []string{s.opts.ID})
+		require.NoError(t, err)
+	}
+	existed, _, err := b.Delete(ctx, storage.DefaultNamespace, "k")
+	require.NoError(t, err)
+	assert.True(t, existed)
+	require.NoError(t, a.SetNamespaceQuota(ctx, "users", storage.Quota{MaxKeys: 10}))
+
+	converge(t, sites...)
+	assert.Equal(t, "6", value(t, c, storage.DefaultNamespace, "hits"))
+	assert.Equal(t, `["a","b","c"]`, value(t, a, storage.DefaultNamespace, "tags"))
+	assert.Equal(t, "", value(t, c, storage.DefaultNamespace, "k"))
+	assert.Equal(t, "v", value(t, a, "users", "u"))
+
+	st := a.Status()
+	assert.Equal(t, RoleActive, st.Role)
+	assert.Equal(t, "a", st.SiteID)
+	require.Len(t, st.Peers, 2)
+	assert.True(t, st.Peers[0].Connected)
+	assert.ElementsMatch(t, []string{"b", "c"}, []string{st.Peers[0].SiteID, st.Peers[1].SiteID})
+	assert.EqualValues(t, 1, st.Peers[0].Resyncs, "a new site starts from a snapshot")
+}
+
+// Test partitioned sites keep accepting writes and resolve conflicting ones
+// the same way once healed
+func TestSite_Partition(t *testing.T) {
+	ctx := context.Background()
+	n := testnet.New()
+	sites := newSites(t, n, nil, "a", "b")
+	a, b := sites[0], sites[1]
+
+	for _, key := range []string{"lww", "gone"} {
+		_, err := a.Set(ctx, storage.DefaultNamespace, storage.Record{Key: key, Value: "before"})
+		require.NoError(t, err)
+	}
+	_, _, err := a.AddMembers(ctx, storage.DefaultNamespace, "set", "", []string{"x"})
+	require.NoError(t, err)
+	converge(t, sites...)
+
+	n.Partition(a.addr, b.addr)
+	require.Eventually(t, func() bool {
+		return !a.Status().Peers[0].Connected && !b.Status().Peers[0].Connected
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The later Set wins, and so does a Set after a Delete
+	_, err = a.Set(ctx, storage.DefaultNamespace, storage.Record{Key: "lww", Value: "from a"})
+	require.NoError(t, err)
+	_, _, err = a.Delete(ctx, storage.DefaultNamespace, "gone")
+	require.NoError(t, err)
+	_, err = b.Set(ctx, storage.DefaultNamespace, storage.Record{Key: "lww", Value: "from b"})
+	require.NoError(t, err)
+	_, err = b.Set(ctx, storage.DefaultNamespace, storage.Record{Key: "gone", Value: "back"})
+	require.NoError(t, err)
+
+	// Every increment counts
+	for range 3 {
+		_, _, err = a.Increment(ctx, storage.DefaultNamespace, "counter", "", 2)
+		require.NoError(t, err)
+	}
+	total, _, err := b.Increment(ctx, storage.DefaultNamespace, "counter", "", -1)
+	require.NoError(t, err)
+	assert.EqualValues(t, -1, total, "b only sees its own increments while partitioned")
+
+	// An addition wins over a concurrent removal
+	members, _, err := b.RemoveMembers(ctx, storage.DefaultNamespace, "set", []string{"x"})
+	require.NoError(t, err)
+	assert.Empty(t, members)
+	_, _, err = a.AddMembers(ctx, storage.DefaultNamespace, "set", "", []string{"x", "y"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "from a", value(t, a, storage.DefaultNamespace, "lww"))
+	assert.Equal(t, "from b", value(t, b, storage.DefaultNamespace, "lww"))
+
+	n.Heal(a.addr, b.addr)
+	converge(t, sites...)
+	for _, s := range sites {
+		assert.Equal(t, "from b", value(t, s, storage.DefaultNamespace, "lww"))
+		assert.Equal(t, "back", value(t, s, storage.DefaultNamespace, "gone"))
+		assert.Equal(t, "5", value(t, s, storage.DefaultNamespace, "counter"))
+		assert.Equal(t, `["x","y"]`, value(t, s, storage.DefaultNamespace, "set"))
+	}
+}
+
+// Test a site whose clock is behind still orders its writes after the ones
+// it has seen, while concurrent writes are ordered by their clocks
+func TestSite_ClockSkew(t *testing.T) {
+	ctx := context.Background()
+	n := testnet.New()
+	behind := hlc.NewClock(func() time.Time { return time.Now().Add(-time.Hour) })
+	sites := newSites(t, n, map[string]SiteOptions{"b": {Clock: behind}}, "a", "b")
+	a, b := sites[0], sites[1]
+
+	_, err := a.Set(ctx, storage.DefaultNamespace, storage.Record{Key: "k", Value: "a"})
+	require.NoError(t, err)
+	converge(t, sites...)
+	_, err = b.Set(ctx, storage.DefaultNamespace, storage.Record{Key: "k", Value: "b saw a"})
+	require.NoError(t, err)
+	converge(t, sites...)
+	assert.Equal(t, "b saw a", value(t, a, storage.DefaultNamespace, "k"))
+
+	n.Partition(a.addr, b.addr)
+	_, err = a.Set(ctx, storage.DefaultNamespace, storage.Record{Key: "k", Value: "a again"})
+	require.NoError(t, err)
+	_, err = b.Set(ctx, storage.DefaultNamespace, storage.Record{Key: "k", Value: "b, an hour behind"})
+	require.NoError(t, err)
+	n.Heal(a.addr, b.addr)
+	converge(t, sites...)
+	assert.Equal(t, "a again", value(t, b, storage.DefaultNamespace, "k"))
+}
+
+// Test dropping a namespace discards keys written to it concurrently, and
+// sites creating one concurrently share it
+func TestSite_Namespaces(t *testing.T) {
+	ctx := context.Background()
+	n := testnet.New()
+	sites := newSites(t, n, nil, "a", "b")
+	a, b := sites[0], sites[1]
+
+	require.NoError(t, a.CreateNamespace(ctx, "users", storage.NamespaceSettings{}))
+	converge(t, sites...)
+
+	n.Partition(a.addr, b.addr)
+	require.NoError(t, a.DropNamespace(ctx, "users"))
+	_, err := b.Set(ctx, "users", storage.Record{Key: "late", Value: "v"})
+	require.NoError(t, err)
+
+	require.NoError(t, a.CreateNamespace(ctx, "events", storage.NamespaceSettings{DefaultTTLSeconds: 60}))
+	_, err = a.Set(ctx, "events", storage.Record{Key: "from-a", Value: "v"})
+	require.NoError(t, err)
+	require.NoError(t, b.CreateNamespace(ctx, "events", storage.NamespaceSettings{DefaultTTLSeconds: 120}))
+	_, err = b.Set(ctx, "events", storage.Record{Key: "from-b", Value: "v"})
+	require.NoError(t, err)
+
+	n.Heal(a.addr, b.addr)
+	converge(t, sites...)
+	_, err = b.namespaces.Get("users")
+	assert.ErrorIs(t, err, storage.ErrNamespaceNotFound)
+	_, err = b.Set(ctx, "users", storage.Record{Key: "k", Value: "v"})
+	assert.ErrorIs(t, err, storage.ErrNamespaceNotFound)
+
+	ns, err := a.namespaces.Get("events")
+	require.NoError(t, err)
+	assert.EqualValues(t, 120, ns.Settings().DefaultTTLSeconds, "the later create's settings win")
+	assert.Len(t, ns.Records(""), 2)
+
+	// The namespace comes back empty
+	require.NoError(t, b.CreateNamespace(ctx, "users", storage.NamespaceSettings{}))
+	converge(t, sites...)
+	ns, err = a.namespaces.Get("users")
+	require.NoError(t, err)
+	assert.Empty(t, ns.Records(""))
+}
+
+// Test a site that missed more changes than a peer retains merges a
+// snapshot of it
+func TestSite_Resync(t *testing.T) {
+	ctx := context.Background()
+	n := testnet.New()
+	sites := newSites(t, n, map[string]SiteOptions{"a": {LogSize: 2}}, "a", "b")
+	a, b := sites[0], sites[1]
+	converge(t, sites...)
+
+	n.Partition(a.addr, b.addr)
+	for i := range 5 {
+		_, err := a.Set(ctx, storage.DefaultNamespace, storage.Record{Key: fmt.Sprintf("k%d", i), Value: "v"})
+		require.NoError(t, err)
+	}
+	_, err := b.Set(ctx, storage.DefaultNamespace, storage.Record{Key: "b", Value: "v"})
+	require.NoError(t, err)
+	n.Heal(a.addr, b.addr)
+
+	converge(t, sites...)
+	assert.EqualValues(t, 2, b.Status().Peers[0].Resyncs)
+	assert.Len(t, b.namespaces.Default().Records(""), 6)
+}
+
+// Test sites only serve reads that do not need a single leader, and reject
+// typed changes to keys holding another kind of value
+func TestSite_Errors(t *testing.T) {
+	ctx := context.Background()
+	site := NewSite(storage.NewNamespaces(), SiteOptions{ID: "a"})
+
+	_, err := site.Read(ctx, cluster.ReadOptions{Consistency: cluster.Linearizable})
+	assert.ErrorIs(t, err, ErrMultiLeader)
+	_, err = site.Read(ctx, cluster.ReadOptions{Consistency: cluster.Stale, MaxStaleness: time.Millisecond})
+	assert.NoError(t, err)
+
+	_, err = site.Set(ctx, storage.DefaultNamespace, storage.Record{Key: "s", Value: "text"})
+	require.NoError(t, err)
+	_, _, err = site.Increment(ctx, storage.DefaultNamespace, "s", "", 1)
+	assert.ErrorIs(t, err, crdt.ErrWrongKind)
+	_, _, err = site.AddMembers(ctx, storage.DefaultNamespace, "s", "", []string{"x"})
+	assert.ErrorIs(t, err, crdt.ErrWrongKind)
+
+	// Expired values no longer count
+	_, err = site.Set(ctx, storage.DefaultNamespace, storage.Record{Key: "old", Value: "text", ExpiresAt: 1})
+	require.NoError(t, err)
+	total, _, err := site.Increment(ctx, storage.DefaultNamespace, "old", "", 1)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+
+	// A set whose members were all removed takes other kinds
+	_, _, err = site.AddMembers(ctx, storage.DefaultNamespace, "set", "", []string{"x"})
+	require.NoError(t, err)
+	_, _, err = site.RemoveMembers(ctx, storage.DefaultNamespace, "set", []string{"x"})
+	require.NoError(t, err)
+	total, _, err = site.Increment(ctx, storage.DefaultNamespace, "set", "", 2)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, total)
+
+	existed, offset, err := site.Delete(ctx, storage.DefaultNamespace, "missing")
+	require.NoError(t, err)
+	assert.False(t, existed)
+	assert.Equal(t, site.Offset(), offset)
+	_, _, err = site.Increment(ctx, "missing", "k", "", 1)
+	assert.ErrorIs(t, err, storage.ErrNamespaceNotFound)
+}
+
+// entries returns how many keys the site keeps the state of in namespace.
+func entries(s *testSite, namespace string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.spaces[namespace].keys)
+}
+
+// Test deleted keys are collected once every site has merged the delete,
+// and a counter a site starts after collecting one is not lost to it
+func TestSite_Collect(t *testing.T) {
+	ctx := context.Background()
+	n := testnet.New()
+	sites := newSites(t, n, nil, "a", "b")
+	a, b := sites[0], sites[1]
+
+	for _, key := range []string{"k", "hits"} {
+		_, err := a.Set(ctx, storage.DefaultNamespace, storage.Record{Key: key, Value: "v"})
+		require.NoError(t, err)
+	}
+	converge(t, sites...)
+
+	n.Partition(a.addr, b.addr)
+	for _, key := range []string{"k", "hits"} {
+		_, _, err := a.Delete(ctx, storage.DefaultNamespace, key)
+		require.NoError(t, err)
+	}
+	a.collect()
+	assert.Equal(t, 2, entries(a, storage.DefaultNamespace), "b has not merged the deletes")
+	assert.Positive(t, a.namespaces.Memory().Used(), "tombstones take memory")
+
+	n.Heal(a.addr, b.addr)
+	converge(t, sites...)
+	require.Eventually(t, func() bool {
+		a.collect()
+		return entries(a, storage.DefaultNamespace) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Zero(t, a.namespaces.Memory().Used())
+	assert.Equal(t, 2, entries(b, storage.DefaultNamespace), "b collects on its own schedule")
+
+	total, _, err := a.Increment(ctx, storage.DefaultNamespace, "hits", "", 1)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+	converge(t, sites...)
+	assert.Equal(t, "1", value(t, b, storage.DefaultNamespace, "hits"))
+
+	require.Eventually(t, func() bool {
+		b.collect()
+		return entries(b, storage.DefaultNamespace) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+// Test the state a site keeps counts against the memory limit, and is
+// released with its namespace
+func TestSite_Memory(t *testing.T) {
+	ctx := context.Background()
+	namespaces := storage.NewNamespaces()
+	site := NewSite(namespaces, SiteOptions{ID: "a"})
+	memory := namespaces.Memory()
+
+	require.NoError(t, site.CreateNamespace(ctx, "users", storage.NamespaceSettings{}))
+	_, err := site.Set(ctx, "users", storage.Record{Key: "k", Value: "value"})
+	require.NoError(t, err)
+	stored := memory.Used()
+	_, _, err = site.Delete(ctx, "users", "k")
+	require.NoError(t, err)
+	assert.Positive(t, memory.Used(), "the tombstone is kept")
+	assert.Less(t, memory.Used(), stored)
+
+	namespaces.SetMaxMemory(memory.Used())
+	_, err = site.Set(ctx, "users", storage.Record{Key: "other", Value: "value"})
+	assert.ErrorIs(t, err, storage.ErrOutOfMemory)
+
+	require.NoError(t, site.DropNamespace(ctx, "users"))
+	assert.Zero(t, memory.Used())
+}
+
+// Test a site does not merge changes stamped too far ahead of its clock,
+// while the site making them still merges its changes
+func TestSite_ClockOffset(t *testing.T) {
+	ctx := context.Background()
+	ahead := hlc.NewClock(func() time.Time { return time.Now().Add(time.Hour) })
+	sites := newSites(t, testnet.New(), map[string]SiteOptions{"b": {Clock: ahead}}, "a", "b")
+	a, b := sites[0], sites[1]
+
+	_, err := a.Set(ctx, storage.DefaultNamespace, storage.Record{Key: "from-a", Value: "v"})
+	require.NoError(t, err)
+	_, err = b.Set(ctx, storage.DefaultNamespace, storage.Record{Key: "from-b", Value: "v"})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return value(t, b, storage.DefaultNamespace, "from-a") == "v"
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Never(t, func() bool {
+		return value(t, a, storage.DefaultNamespace, "from-b") != ""
+	}, 500*time.Millisecond, 10*time.Millisecond)
+	assert.Zero(t, a.Status().Peers[0].Resyncs)
+}
@@ -0,0 +1,271 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	pb "kvstore/pkg/pb/api/proto"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type AccessLogOptions struct {
+	// Enabled logs every request. Slow operations are recorded in the slow
+	// log either way.
+	Enabled bool
+	// Level is the level of successful requests and client errors. Server
+	// errors are logged at error and slow requests at warn; gossip at
+	// debug.
+	Level slog.Level
+	// RedactKeys replaces keys and prefixes with a short hash in the log and
+	// the slow log, so requests can be correlated without revealing keys.
+	RedactKeys bool
+	// SlowThreshold is the latency from which a request is slow. Zero
+	// disables the slow log.
+	SlowThreshold time.Duration
+	// SlowLogSize is how many slow operations are kept.
+	SlowLogSize int
+}
+
+// AccessLog logs requests through slog and keeps the slowest recent ones in
+// a SlowLog.
+type AccessLog struct {
+	logger *slog.Logger
+	opts   atomic.Pointer[AccessLogOptions]
+	slow   *SlowLog
+}
+
+func NewAccessLog(logger *slog.Logger, opts AccessLogOptions) *AccessLog {
+	a := &AccessLog{logger: logger, slow: &SlowLog{}}
+	a.Update(opts)
+	return a
+}
+
+// Update swaps in new options. It is safe to call while serving. Shrinking
+// the slow log drops its oldest entries.
+func (a *AccessLog) Update(opts AccessLogOptions) {
+	a.opts.Store(&opts)
+	a.slow.resize(opts.SlowLogSize)
+}
+
+func (a *AccessLog) SlowLog() *SlowLog {
+	return a.slow
+}
+
+// UnaryInterceptor logs every request after it completes. It must run after
+// the Authenticator's interceptor to log the principal.
+func (a *AccessLog) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		a.record(ctx, info.FullMethod, req, start, time.Since(start), err)
+		return resp, err
+	}
+}
+
+func (a *AccessLog) record(ctx context.Context, method string, req any, start time.Time, latency time.Duration, err error) {
+	opts := a.opts.Load()
+	slow := opts.SlowThreshold > 0 && latency >= opts.SlowThreshold && !background(method)
+	if !opts.Enabled && !slow {
+		return
+	}
+
+	op := SlowOperation{
+		Start:    start,
+		Duration: latency,
+		Method:   method,
+		Code:     status.Code(err),
+	}
+	if r, ok := req.(interface{ GetNamespace() string }); ok {
+		op.Namespace = r.GetNamespace()
+	}
+	switch r := req.(type) {
+	case interface{ GetKey() string }:
+		op.Key = r.GetKey()
+	case interface{ GetPrefix() string }:
+		op.Key = r.GetPrefix()
+	}
+	if op.Key != "" && opts.RedactKeys {
+		op.Key = redactKey(op.Key)
+	}
+	op.Principal = principal(ctx)
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		op.Peer = p.Addr.String()
+	}
+
+	if slow {
+		a.slow.add(op)
+	}
+	if !opts.Enabled {
+		return
+	}
+
+	level := opts.Level
+	switch {
+	case background(method):
+		level = slog.LevelDebug
+	case serverError(op.Code):
+		level = slog.LevelError
+	case slow:
+		level = slog.LevelWarn
+	}
+
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.String("code", op.Code.String()),
+		slog.Duration("latency", latency),
+	}
+	if op.Namespace != "" {
+		attrs = append(attrs, slog.String("namespace", op.Namespace))
+	}
+	if op.Key != "" {
+		attrs = append(attrs, slog.String("key", op.Key))
+	}
+	if op.Principal != "" {
+		attrs = append(attrs, slog.String("principal", op.Principal))
+	}
+	if op.Peer != "" {
+		attrs = append(attrs, slog.String("peer", op.Peer))
+	}
+	if slow {
+		attrs = append(attrs, slog.Bool("slow", true))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", status.Convert(err).Message()))
+	}
+
+	a.logger.LogAttrs(ctx, level, "Request", attrs...)
+}
+
+// serverError reports whether code means the server, not the client, is at
+// fault.
+func serverError(code codes.Code) bool {
+	switch code {
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+// redactKey hashes key so the same key always logs the same way.
+func redactKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
+
+type SlowOperation struct {
+	// ID increases with every slow operation recorded.
+	ID        int64
+	Start     time.Time
+	Duration  time.Duration
+	Method    string
+	Namespace string
+	Key       string
+	Code      codes.Code
+	Principal string
+	Peer      string
+}
+
+// SlowLog keeps the most recent slow operations in a ring buffer.
+type SlowLog struct {
+	mu      sync.Mutex
+	entries []SlowOperation
+	// next is the ring position the next entry is written to.
+	next   int
+	full   bool
+	lastID int64
+}
+
+func (s *SlowLog) add(op SlowOperation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.entries) == 0 {
+		return
+	}
+
+	s.lastID++
+	op.ID = s.lastID
+	s.entries[s.next] = op
+	s.next = (s.next + 1) % len(s.entries)
+	if s.next == 0 {
+		s.full = true
+	}
+}
+
+// Entries returns up to limit operations, newest first. A limit of zero
+// returns all of them.
+func (s *SlowLog) Entries(limit int) []SlowOperation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.newest(limit)
+}
+
+// newest returns up to limit entries, newest first. The caller must hold
+// s.mu.
+func (s *SlowLog) newest(limit int) []SlowOperation {
+	n := s.next
+	if s.full {
+		n = len(s.entries)
+	}
+	if limit > 0 && limit < n {
+		n = limit
+	}
+
+	out := make([]SlowOperation, 0, n)
+	for i := 1; i <= n; i++ {
+		out = append(out, s.entries[(s.next-i+len(s.entries))%len(s.entries)])
+	}
+	return out
+}
+
+func (s *SlowLog) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = make([]SlowOperation, len(s.entries))
+	s.next = 0
+	s.full = false
+}
+
+// resize changes the capacity, keeping the newest entries that fit.
+func (s *SlowLog) resize(size int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if size == len(s.entries) {
+		return
+	}
+
+	var kept []SlowOperation
+	if size > 0 {
+		kept = s.newest(size)
+	}
+
+	s.entries = make([]SlowOperation, max(size, 0))
+	s.next = 0
+	s.full = false
+	for i := len(kept) - 1; i >= 0; i-- {
+		s.entries[s.next] = kept[i]
+		s.next = (s.next + 1) % size
+		if s.next == 0 {
+			s.full = true
+		}
+	}
+}
+
+// background reports whether method is gossip, which servers exchange
+// every second or so on their own. It is logged at debug level, and probes
+// that wait for an unreachable member are not slow operations.
+func background(method string) bool {
+	return strings.HasPrefix(method, "/"+pb.Gossip_ServiceDesc.ServiceName+"/")
+}
@@ -0,0 +1,159 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"kvstore/internal/auth"
+	pb "kvstore/pkg/pb/api/proto"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func newTestAccessLog(opts AccessLogOptions) (*AccessLog, *bytes.Buffer) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	return NewAccessLog(logger, opts), &buf
+}
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var out []map[string]any
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var line map[string]any
+		require.NoError(t, dec.Decode(&line))
+		out = append(out, line)
+	}
+	return out
+}
+
+// Test requests are logged with method, key, code, principal and peer
+func TestAccessLog_Fields(t *testing.T) {
+	a, buf := newTestAccessLog(AccessLogOptions{Enabled: true, Level: slog.LevelInfo})
+	interceptor := a.UnaryInterceptor()
+
+	ctx := auth.WithIdentity(context.Background(), &auth.Identity{Principal: "ci"})
+	ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.7"), Port: 5123}})
+	info := &grpc.UnaryServerInfo{FullMethod: pb.KVStore_Get_FullMethodName}
+
+	_, err := interceptor(ctx, &pb.GetRequest{Key: "user/1", Namespace: "team-a"}, info, func(ctx context.Context, req any) (any, error) {
+		return &pb.GetResponse{}, nil
+	})
+	require.NoError(t, err)
+
+	_, err = interceptor(ctx, &pb.GetRequest{Key: "user/2"}, info, func(ctx context.Context, req any) (any, error) {
+		return nil, status.Error(codes.Internal, "boom")
+	})
+	require.Error(t, err)
+
+	lines := decodeLines(t, buf)
+	require.Len(t, lines, 2)
+
+	assert.Equal(t, "INFO", lines[0]["level"])
+	assert.Equal(t, pb.KVStore_Get_FullMethodName, lines[0]["method"])
+	assert.Equal(t, "OK", lines[0]["code"])
+	assert.Equal(t, "user/1", lines[0]["key"])
+	assert.Equal(t, "team-a", lines[0]["namespace"])
+	assert.Equal(t, "ci", lines[0]["principal"])
+	assert.Equal(t, "10.0.0.7:5123", lines[0]["peer"])
+	assert.Contains(t, lines[0], "latency")
+
+	assert.Equal(t, "ERROR", lines[1]["level"])
+	assert.Equal(t, "Internal", lines[1]["code"])
+	assert.Equal(t, "boom", lines[1]["error"])
+}
+
+func TestAccessLog_RedactKeys(t *testing.T) {
+	a, buf := newTestAccessLog(AccessLogOptions{Enabled: true, RedactKeys: true})
+	interceptor := a.UnaryInterceptor()
+	handler := func(ctx context.Context, req any) (any, error) { return nil, nil }
+	prefix := "secret"
+
+	for _, req := range []any{&pb.GetRequest{Key: "secret"}, &pb.ListRequest{Prefix: &prefix}} {
+		_, err := interceptor(context.Background(), req, &grpc.UnaryServerInfo{FullMethod: "/kvstore.v1.KVStore/Get"}, handler)
+		require.NoError(t, err)
+	}
+
+	lines := decodeLines(t, buf)
+	require.Len(t, lines, 2)
+	assert.Equal(t, redactKey("secret"), lines[0]["key"])
+	assert.Equal(t, lines[0]["key"], lines[1]["key"], "the same key always redacts the same way")
+	assert.NotContains(t, buf.String(), "secret")
+}
+
+// Test slow requests other than gossip are recorded even with the access
+// log disabled
+func TestAccessLog_SlowLog(t *testing.T) {
+	a, buf := newTestAccessLog(AccessLogOptions{SlowThreshold: 5 * time.Millisecond, SlowLogSize: 2})
+	interceptor := a.UnaryInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: pb.KVStore_Set_FullMethodName}
+	slow := func(ctx context.Context, req any) (any, error) {
+		time.Sleep(6 * time.Millisecond)
+		return nil, nil
+	}
+	fast := func(ctx context.Context, req any) (any, error) { return nil, nil }
+
+	for _, key := range []string{"a", "b", "c"} {
+		_, _ = interceptor(context.Background(), &pb.SetRequest{Key: key}, info, slow)
+	}
+	_, _ = interceptor(context.Background(), &pb.SetRequest{Key: "fast"}, info, fast)
+	// Gossip probes wait for unreachable members by design
+	_, _ = interceptor(context.Background(), &pb.PingReqRequest{}, &grpc.UnaryServerInfo{FullMethod: pb.Gossip_PingReq_FullMethodName}, slow)
+
+	assert.Empty(t, buf.String())
+
+	entries := a.SlowLog().Entries(0)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "c", entries[0].Key)
+	assert.Equal(t, int64(3), entries[0].ID)
+	assert.Equal(t, "b", entries[1].Key)
+	assert.GreaterOrEqual(t, entries[0].Duration, 5*time.Millisecond)
+
+	assert.Len(t, a.SlowLog().Entries(1), 1)
+
+	// Growing keeps the entries, shrinking keeps the newest
+	a.Update(AccessLogOptions{SlowThreshold: 5 * time.Millisecond, SlowLogSize: 4})
+	assert.Len(t, a.SlowLog().Entries(0), 2)
+	a.Update(AccessLogOptions{SlowThreshold: 5 * time.Millisecond, SlowLogSize: 1})
+	entries = a.SlowLog().Entries(0)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "c", entries[0].Key)
+
+	a.SlowLog().Reset()
+	assert.Empty(t, a.SlowLog().Entries(0))
+}
+
+func TestAdmin_GetSlowLog(t *testing.T) {
+	a, _ := newTestAccessLog(AccessLogOptions{SlowThreshold: time.Nanosecond, SlowLogSize: 8})
+	_, _ = a.UnaryInterceptor()(context.Background(), &pb.DeleteRequest{Key: "k"},
+		&grpc.UnaryServerInfo{FullMethod: pb.KVStore_Delete_FullMethodName},
+		func(ctx context.Context, req any) (any, error) { return nil, status.Error(codes.NotFound, "gone") })
+
+	admin := NewAdmin(WithAccessLog(a))
+	resp, err := admin.GetSlowLog(context.Background(), &pb.GetSlowLogRequest{})
+	require.NoError(t, err)
+	require.Len(t, resp.Operations, 1)
+	assert.Equal(t, "k", resp.Operations[0].Key)
+	assert.Equal(t, "NotFound", resp.Operations[0].Code)
+	assert.Equal(t, time.Nanosecond, resp.Threshold.AsDuration())
+
+	_, err = admin.ResetSlowLog(context.Background(), &pb.ResetSlowLogRequest{})
+	require.NoError(t, err)
+	resp, err = admin.GetSlowLog(context.Background(), &pb.GetSlowLogRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, resp.Operations)
+
+	_, err = NewAdmin().GetSlowLog(context.Background(), &pb.GetSlowLogRequest{})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
@@ -0,0 +1,448 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"kvstore/internal/cluster"
+	"kvstore/internal/config"
+	"kvstore/internal/gossip"
+	"kvstore/internal/merkle"
+	"kvstore/internal/replication"
+	"kvstore/internal/shard"
+	"kvstore/internal/storage"
+	pb "kvstore/pkg/pb/api/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type AdminOption func(*AdminServer)
+
+func WithReloader(reloader *config.Reloader) AdminOption {
+	return func(a *AdminServer) {
+		a.reloader = reloader
+	}
+}
+
+func WithNamespaces(namespaces *storage.Namespaces) AdminOption {
+	return func(a *AdminServer) {
+		a.namespaces = namespaces
+	}
+}
+
+// WithCluster serves and changes the membership of node's Raft group.
+func WithCluster(node *cluster.Node) AdminOption {
+	return func(a *AdminServer) {
+		a.cluster = node
+	}
+}
+
+// WithReplication serves the replication status of a primary or replica,
+// and consistency checks of a replica against its primary.
+func WithReplication(r interface{ Status() replication.Status }) AdminOption {
+	return func(a *AdminServer) {
+		a.replication = r
+	}
+}
+
+// WithAccessLog serves the access log's slow log.
+func WithAccessLog(accessLog *AccessLog) AdminOption {
+	return func(a *AdminServer) {
+		a.accessLog = accessLog
+	}
+}
+
+type AdminServer struct {
+	pb.UnimplementedAdminServer
+	reloader    *config.Reloader
+	namespaces  *storage.Namespaces
+	accessLog   *AccessLog
+	replicator  Replicator
+	cluster     *cluster.Node
+	replication interface{ Status() replication.Status }
+	migrator    *shard.Migrator
+	gossip      *gossip.Node
+}
+
+func NewAdmin(opts ...AdminOption) *AdminServer {
+	a := &AdminServer{}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a
+}
+
+func (a *AdminServer) ReloadConfig(ctx context.Context, req *pb.ReloadConfigRequest) (*pb.ReloadConfigResponse, error) {
+	if a.reloader == nil {
+		return nil, status.Error(codes.Unimplemented, "config reload is not enabled")
+	}
+
+	result, err := a.reloader.Reload()
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "failed to reload config: %v", err)
+	}
+
+	return &pb.ReloadConfigResponse{
+		Applied:         result.Applied,
+		RequiresRestart: result.RequiresRestart,
+	}, nil
+}
+
+func (a *AdminServer) CreateNamespace(ctx context.Context, req *pb.CreateNamespaceRequest) (*pb.CreateNamespaceResponse, error) {
+	if a.namespaces == nil {
+		return nil, status.Error(codes.Unimplemented, "namespaces are not enabled")
+	}
+
+	settings := storage.NamespaceSettings{
+		DefaultTTLSeconds: req.GetDefaultTtlSeconds(),
+		Quota:             quotaFromProto(req.GetQuota()),
+	}
+
+	var ns *storage.Namespace
+	var err error
+	if a.replicator != nil {
+		if err = a.replicator.CreateNamespace(ctx, req.GetName(), settings); err == nil {
+			ns, err = a.namespaces.Get(req.GetName())
+		}
+	} else {
+		ns, err = a.namespaces.Create(req.GetName(), settings)
+	}
+	if err != nil {
+		return nil, clusterError(err, "create namespace")
+	}
+
+	return &pb.CreateNamespaceResponse{Namespace: namespaceInfo(ns)}, nil
+}
+
+func (a *AdminServer) DropNamespace(ctx context.Context, req *pb.DropNamespaceRequest) (*pb.DropNamespaceResponse, error) {
+	if a.namespaces == nil {
+		return nil, status.Error(codes.Unimplemented, "namespaces are not enabled")
+	}
+
+	var err error
+	if a.replicator != nil {
+		err = a.replicator.DropNamespace(ctx, req.GetName())
+	} else {
+		err = a.namespaces.Drop(req.GetName())
+	}
+	if err != nil {
+		return nil, clusterError(err, "drop namespace")
+	}
+
+	return &pb.DropNamespaceResponse{}, nil
+}
+
+func (a *AdminServer) ListNamespaces(ctx context.Context, req *pb.ListNamespacesRequest) (*pb.ListNamespacesResponse, error) {
+	if a.namespaces == nil {
+		return nil, status.Error(codes.Unimplemented, "namespaces are not enabled")
+	}
+
+	var out []*pb.Namespace
+	for _, ns := range a.namespaces.List() {
+		out = append(out, namespaceInfo(ns))
+	}
+
+	return &pb.ListNamespacesResponse{Namespaces: out}, nil
+}
+
+func (a *AdminServer) SetNamespaceQuota(ctx context.Context, req *pb.SetNamespaceQuotaRequest) (*pb.SetNamespaceQuotaResponse, error) {
+	if a.namespaces == nil {
+		return nil, status.Error(codes.Unimplemented, "namespaces are not enabled")
+	}
+
+	var ns *storage.Namespace
+	var err error
+	if a.replicator != nil {
+		if err = a.replicator.SetNamespaceQuota(ctx, req.GetName(), quotaFromProto(req.GetQuota())); err == nil {
+			ns, err = a.namespaces.Get(req.GetName())
+		}
+	} else {
+		ns, err = a.namespaces.SetQuota(req.GetName(), quotaFromProto(req.GetQuota()))
+	}
+	if err != nil {
+		return nil, clusterError(err, "set quota")
+	}
+
+	return &pb.SetNamespaceQuotaResponse{Namespace: namespaceInfo(ns)}, nil
+}
+
+func (a *AdminServer) GetUsage(ctx context.Context, req *pb.GetUsageRequest) (*pb.GetUsageResponse, error) {
+	if a.namespaces == nil {
+		return nil, status.Error(codes.Unimplemented, "namespaces are not enabled")
+	}
+
+	resp := &pb.GetUsageResponse{}
+
+	switch {
+	case req.GetNamespace() != "":
+		ns, err := a.namespaces.Get(req.GetNamespace())
+		if err != nil {
+			return nil, status.Errorf(storageCode(err), "%v", err)
+		}
+		resp.Namespaces = append(resp.Namespaces, namespaceInfo(ns))
+
+	case req.GetPrincipal() != "":
+		resp.Principals = append(resp.Principals, principalInfo(a.namespaces.Principals().Usage(req.GetPrincipal())))
+
+	default:
+		for _, ns := range a.namespaces.List() {
+			resp.Namespaces = append(resp.Namespaces, namespaceInfo(ns))
+		}
+		for _, usage := range a.namespaces.Principals().All() {
+			resp.Principals = append(resp.Principals, principalInfo(usage))
+		}
+	}
+
+	return resp, nil
+}
+
+func (a *AdminServer) GetSlowLog(ctx context.Context, req *pb.GetSlowLogRequest) (*pb.GetSlowLogResponse, error) {
+	if a.accessLog == nil {
+		return nil, status.Error(codes.Unimplemented, "the slow log is not enabled")
+	}
+	if req.GetLimit() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "limit cannot be negative")
+	}
+
+	resp := &pb.GetSlowLogResponse{
+		Threshold: durationpb.New(a.accessLog.opts.Load().SlowThreshold),
+	}
+	for _, op := range a.accessLog.SlowLog().Entries(int(req.GetLimit())) {
+		resp.Operations = append(resp.Operations, &pb.SlowOperation{
+			Id:        op.ID,
+			StartTime: timestamppb.New(op.Start),
+			Duration:  durationpb.New(op.Duration),
+			Method:    op.Method,
+			Namespace: op.Namespace,
+			Key:       op.Key,
+			Code:      op.Code.String(),
+			Principal: op.Principal,
+			Peer:      op.Peer,
+		})
+	}
+
+	return resp, nil
+}
+
+func (a *AdminServer) ResetSlowLog(ctx context.Context, req *pb.ResetSlowLogRequest) (*pb.ResetSlowLogResponse, error) {
+	if a.accessLog == nil {
+		return nil, status.Error(codes.Unimplemented, "the slow log is not enabled")
+	}
+
+	a.accessLog.SlowLog().Reset()
+	return &pb.ResetSlowLogResponse{}, nil
+}
+
+func namespaceInfo(ns *storage.Namespace) *pb.Namespace {
+	settings := ns.Settings()
+	return &pb.Namespace{
+		Name:              ns.Name(),
+		DefaultTtlSeconds: settings.DefaultTTLSeconds,
+		Quota:             quotaToProto(settings.Quota),
+		Usage:             usageToProto(ns.Usage()),
+	}
+}
+
+func principalInfo(usage storage.PrincipalUsage) *pb.PrincipalUsage {
+	return &pb.PrincipalUsage{
+		Principal: usage.Principal,
+		Quota:     quotaToProto(usage.Quota),
+		Usage:     usageToProto(usage.Usage),
+	}
+}
+
+func quotaFromProto(q *pb.Quota) storage.Quota {
+	return storage.Quota{
+		MaxKeys:       q.GetMaxKeys(),
+		MaxBytes:      q.GetMaxBytes(),
+		MaxValueBytes: q.GetMaxValueBytes(),
+	}
+}
+
+func quotaToProto(q storage.Quota) *pb.Quota {
+	return &pb.Quota{
+		MaxKeys:       q.MaxKeys,
+		MaxBytes:      q.MaxBytes,
+		MaxValueBytes: q.MaxValueBytes,
+	}
+}
+
+func usageToProto(u storage.Usage) *pb.Usage {
+	return &pb.Usage{Keys: u.Keys, Bytes: u.Bytes}
+}
+
+func (a *AdminServer) GetMembership(ctx context.Context, req *pb.GetMembershipRequest) (*pb.GetMembershipResponse, error) {
+	if a.cluster == nil {
+		return nil, status.Error(codes.Unimplemented, "clustering is not enabled")
+	}
+
+	membership, err := a.cluster.Membership()
+	if err != nil {
+		return nil, clusterError(err, "get membership")
+	}
+
+	return &pb.GetMembershipResponse{
+		Membership: membershipInfo(membership),
+		NodeId:     a.cluster.ID(),
+		State:      a.cluster.State(),
+	}, nil
+}
+
+func (a *AdminServer) AddLearner(ctx context.Context, req *pb.AddLearnerRequest) (*pb.MembershipChangeResponse, error) {
+	return a.changeMembership("add learner", func() error {
+		return a.cluster.AddLearner(ctx, cluster.Peer{ID: req.GetId(), RaftAddr: req.GetRaftAddr(), GRPCAddr: req.GetGrpcAddr()})
+	})
+}
+
+func (a *AdminServer) PromoteLearner(ctx context.Context, req *pb.PromoteLearnerRequest) (*pb.MembershipChangeResponse, error) {
+	return a.changeMembership("promote learner", func() error {
+		return a.cluster.Promote(ctx, req.GetId())
+	})
+}
+
+func (a *AdminServer) RemoveNode(ctx context.Context, req *pb.RemoveNodeRequest) (*pb.MembershipChangeResponse, error) {
+	return a.changeMembership("remove node", func() error {
+		return a.cluster.Remove(ctx, req.GetId())
+	})
+}
+
+func (a *AdminServer) TransferLeadership(ctx context.Context, req *pb.TransferLeadershipRequest) (*pb.MembershipChangeResponse, error) {
+	return a.changeMembership("transfer leadership", func() error {
+		return a.cluster.TransferLeadership(ctx, req.GetId())
+	})
+}
+
+// changeMembership runs change and returns the resulting membership.
+func (a *AdminServer) changeMembership(action string, change func() error) (*pb.MembershipChangeResponse, error) {
+	if a.cluster == nil {
+		return nil, status.Error(codes.Unimplemented, "clustering is not enabled")
+	}
+
+	if err := change(); err != nil {
+		return nil, clusterError(err, action)
+	}
+
+	membership, err := a.cluster.Membership()
+	if err != nil {
+		return nil, clusterError(err, "get membership")
+	}
+	return &pb.MembershipChangeResponse{Membership: membershipInfo(membership)}, nil
+}
+
+func membershipInfo(m cluster.Membership) *pb.Membership {
+	out := &pb.Membership{Index: m.Index}
+	for _, member := range m.Members {
+		role := pb.ClusterMember_ROLE_LEARNER
+		if member.Voter {
+			role = pb.ClusterMember_ROLE_VOTER
+		}
+		out.Members = append(out.Members, &pb.ClusterMember{
+			Id:       member.ID,
+			RaftAddr: member.RaftAddr,
+			GrpcAddr: member.GRPCAddr,
+			Role:     role,
+			Leader:   member.ID == m.LeaderID,
+		})
+	}
+	return out
+}
+
+func (a *AdminServer) GetReplicationStatus(ctx context.Context, req *pb.GetReplicationStatusRequest) (*pb.GetReplicationStatusResponse, error) {
+	if a.replication == nil {
+		return nil, status.Error(codes.Unimplemented, "replication is not enabled")
+	}
+
+	st := a.replication.Status()
+	resp := &pb.GetReplicationStatusResponse{
+		LogId:          st.LogID,
+		Offset:         st.Offset,
+		PrimaryAddr:    st.PrimaryAddr,
+		Connected:      st.Connected,
+		PrimaryOffset:  st.PrimaryOffset,
+		Resyncs:        st.Resyncs,
+		RangesRepaired: st.RangesRepaired,
+		KeysRepaired:   st.KeysRepaired,
+		SiteId:         st.SiteID,
+	}
+	switch st.Role {
+	case replication.RolePrimary:
+		resp.Role = pb.GetReplicationStatusResponse_ROLE_PRIMARY
+	case replication.RoleReplica:
+		resp.Role = pb.GetReplicationStatusResponse_ROLE_REPLICA
+	case replication.RoleActive:
+		resp.Role = pb.GetReplicationStatusResponse_ROLE_ACTIVE
+	}
+	if !st.LastContact.IsZero() {
+		resp.LastContact = timestamppb.New(st.LastContact)
+	}
+	if !st.LastRepair.IsZero() {
+		resp.LastRepair = timestamppb.New(st.LastRepair)
+	}
+	if st.LastRepairError != nil {
+		resp.LastRepairError = st.LastRepairError.Error()
+	}
+	for _, r := range st.Replicas {
+		resp.Replicas = append(resp.Replicas, &pb.ReplicaStatus{
+			Id:             r.ID,
+			Addr:           r.Addr,
+			Offset:         r.Offset,
+			ConnectedSince: timestamppb.New(r.ConnectedSince),
+		})
+	}
+	for _, p := range st.Peers {
+		peer := &pb.PeerSiteStatus{
+			Addr:       p.Addr,
+			SiteId:     p.SiteID,
+			Connected:  p.Connected,
+			Offset:     p.Offset,
+			PeerOffset: p.PeerOffset,
+			Resyncs:    p.Resyncs,
+		}
+		if !p.LastContact.IsZero() {
+			peer.LastContact = timestamppb.New(p.LastContact)
+		}
+		resp.Peers = append(resp.Peers, peer)
+	}
+
+	return resp, nil
+}
+
+func (a *AdminServer) CheckConsistency(ctx context.Context, req *pb.CheckConsistencyRequest) (*pb.CheckConsistencyResponse, error) {
+	if a.replication == nil {
+		return nil, status.Error(codes.Unimplemented, "replication is not enabled; Raft clusters and shards have no consistency check")
+	}
+	checker, ok := a.replication.(interface {
+		Check(context.Context) (replication.CheckResult, error)
+	})
+	if !ok {
+		return nil, status.Error(codes.FailedPrecondition, "only replicas check their consistency with the primary")
+	}
+
+	result, err := checker.Check(ctx)
+	switch {
+	case errors.Is(err, replication.ErrNotSynced):
+		return nil, status.Errorf(codes.FailedPrecondition, "%v", err)
+	case err != nil:
+		return nil, status.Errorf(codes.Unavailable, "failed to check consistency: %v", err)
+	}
+
+	resp := &pb.CheckConsistencyResponse{
+		PrimaryOffset:      result.PrimaryOffset,
+		Offset:             result.Offset,
+		Namespaces:         uint32(result.Namespaces),
+		RangesPerNamespace: 1 << result.Depth,
+	}
+	for _, d := range result.Divergent {
+		r := &pb.DivergentRange{Namespace: d.Namespace, MissingOn: d.MissingOn}
+		if d.MissingOn == "" {
+			r.Range = uint32(d.Range)
+			r.FirstHash, r.LastHash = merkle.Bounds(d.Range, result.Depth)
+		}
+		resp.Divergent = append(resp.Divergent, r)
+	}
+	return resp, nil
+}
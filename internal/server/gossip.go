@@ -0,0 +1,33 @@
+package server
+
+import (
+	"context"
+	"kvstore/internal/gossip"
+	pb "kvstore/pkg/pb/api/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// WithGossip serves the membership node learned by gossip.
+func WithGossip(node *gossip.Node) AdminOption {
+	return func(a *AdminServer) {
+		a.gossip = node
+	}
+}
+
+func (a *AdminServer) GetGossipMembers(ctx context.Context, req *pb.GetGossipMembersRequest) (*pb.GetGossipMembersResponse, error) {
+	if a.gossip == nil {
+		return nil, status.Error(codes.Unimplemented, "gossip is not enabled")
+	}
+
+	resp := &pb.GetGossipMembersResponse{NodeId: a.gossip.Self()}
+	for _, m := range a.gossip.Members() {
+		resp.Members = append(resp.Members, &pb.GossipMemberStatus{
+			Member: m.Proto(),
+			Since:  timestamppb.New(m.Since),
+		})
+	}
+	return resp, nil
+}
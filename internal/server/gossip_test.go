@@ -0,0 +1,33 @@
+package server
+
+import (
+	"context"
+	"kvstore/internal/gossip"
+	pb "kvstore/pkg/pb/api/proto"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Test the gossip membership is served with what each member published
+func TestAdmin_GetGossipMembers(t *testing.T) {
+	ctx := context.Background()
+	_, err := NewAdmin().GetGossipMembers(ctx, &pb.GetGossipMembersRequest{})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+
+	node := gossip.New(gossip.Options{ID: "n1", Addr: "10.0.0.1:9090", Meta: func() gossip.Meta {
+		return gossip.Meta{Role: "primary", Version: "v1.2.0"}
+	}})
+	resp, err := NewAdmin(WithGossip(node)).GetGossipMembers(ctx, &pb.GetGossipMembersRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "n1", resp.GetNodeId())
+	require.Len(t, resp.GetMembers(), 1)
+	m := resp.GetMembers()[0]
+	assert.Equal(t, "10.0.0.1:9090", m.GetMember().GetAddr())
+	assert.Equal(t, pb.MemberState_MEMBER_STATE_ALIVE, m.GetMember().GetState())
+	assert.Equal(t, "primary", m.GetMember().GetMeta().GetRole())
+	assert.NotNil(t, m.GetSince())
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"kvstore/internal/storage"
+	pb "kvstore/pkg/pb/api/proto"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Health publishes the server's serving status through the standard
+// grpc.health.v1.Health service. The overall status ("") and each registered
+// service follow the readiness of the storage backend.
+type Health struct {
+	*health.Server
+	store    storage.Readiness
+	services []string
+}
+
+func NewHealth(store storage.Readiness) *Health {
+	h := &Health{
+		Server: health.NewServer(),
+		store:  store,
+		services: []string{
+			"",
+			pb.KVStore_ServiceDesc.ServiceName,
+			pb.Admin_ServiceDesc.ServiceName,
+		},
+	}
+
+	h.update()
+	return h
+}
+
+// Run re-checks storage readiness every interval until ctx is done.
+func (h *Health) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.update()
+		}
+	}
+}
+
+func (h *Health) update() {
+	status := healthpb.HealthCheckResponse_SERVING
+
+	if err := h.store.Ready(); err != nil {
+		slog.Debug("Storage not ready", "error", err)
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+
+	for _, service := range h.services {
+		h.SetServingStatus(service, status)
+	}
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"kvstore/internal/storage"
+	pb "kvstore/pkg/pb/api/proto"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type replayingStore struct {
+	*storage.MemoryStore
+	replaying atomic.Bool
+}
+
+func (s *replayingStore) Ready() error {
+	if s.replaying.Load() {
+		return errors.New("replaying log")
+	}
+	return s.MemoryStore.Ready()
+}
+
+func checkStatus(t *testing.T, h *Health, service string) healthpb.HealthCheckResponse_ServingStatus {
+	t.Helper()
+
+	resp, err := h.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+	require.NoError(t, err)
+	return resp.GetStatus()
+}
+
+// Test health status follows storage readiness
+func TestHealth_StorageReadiness(t *testing.T) {
+	store := &replayingStore{MemoryStore: storage.NewMemoryStore()}
+	store.replaying.Store(true)
+
+	h := NewHealth(store)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, checkStatus(t, h, ""))
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, checkStatus(t, h, pb.KVStore_ServiceDesc.ServiceName))
+
+	store.replaying.Store(false)
+	h.update()
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, checkStatus(t, h, ""))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, checkStatus(t, h, pb.KVStore_ServiceDesc.ServiceName))
+
+	require.NoError(t, store.Close())
+	h.update()
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, checkStatus(t, h, ""))
+}
+
+// Test shutdown reports NOT_SERVING even if storage is still ready
+func TestHealth_Shutdown(t *testing.T) {
+	h := NewHealth(storage.NewMemoryStore())
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, checkStatus(t, h, ""))
+
+	h.Shutdown()
+	h.update()
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, checkStatus(t, h, ""))
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, checkStatus(t, h, pb.Admin_ServiceDesc.ServiceName))
+}
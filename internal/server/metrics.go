@@ -0,0 +1,145 @@
+package server
+
+import (
+	"context"
+	"kvstore/internal/storage"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// MetricsPath is where Metrics.Handler is mounted.
+const MetricsPath = "/metrics"
+
+// Metrics collects Prometheus metrics about RPCs, through its interceptors,
+// and about storage, through storage hooks and a scrape-time collector.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+
+	expired *prometheus.CounterVec
+	evicted *prometheus.CounterVec
+}
+
+// NewMetrics registers the metrics on a registry of their own and installs
+// hooks on namespaces to count expired and evicted keys.
+func NewMetrics(namespaces *storage.Namespaces) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kvstore_rpc_requests_total",
+			Help: "RPCs handled, by service and method.",
+		}, []string{"service", "method"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kvstore_rpc_errors_total",
+			Help: "RPCs that failed, by service, method and gRPC status code.",
+		}, []string{"service", "method", "code"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "kvstore_rpc_duration_seconds",
+			Help:    "RPC latency, by service and method.",
+			Buckets: []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"service", "method"}),
+		expired: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kvstore_expired_keys_total",
+			Help: "Keys removed because their TTL passed, by namespace.",
+		}, []string{"namespace"}),
+		evicted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kvstore_evicted_keys_total",
+			Help: "Keys evicted to stay under the memory limit, by namespace.",
+		}, []string{"namespace"}),
+	}
+
+	m.registry.MustRegister(
+		m.requests, m.errors, m.latency, m.expired, m.evicted,
+		&storageCollector{namespaces: namespaces},
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	namespaces.SetHooks(storage.Hooks{
+		Expired: func(name string) { m.expired.WithLabelValues(name).Inc() },
+		Evicted: func(name string) { m.evicted.WithLabelValues(name).Inc() },
+	})
+
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// UnaryInterceptor records every RPC. It should run first in the chain so
+// that requests rejected by later interceptors are counted too.
+func (m *Metrics) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		m.observe(info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+func (m *Metrics) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		m.observe(info.FullMethod, start, err)
+		return err
+	}
+}
+
+func (m *Metrics) observe(fullMethod string, start time.Time, err error) {
+	service, method := splitMethod(fullMethod)
+
+	m.requests.WithLabelValues(service, method).Inc()
+	m.latency.WithLabelValues(service, method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		m.errors.WithLabelValues(service, method, status.Code(err).String()).Inc()
+	}
+}
+
+// splitMethod splits "/kvstore.v1.KVStore/Get" into its service and method.
+func splitMethod(fullMethod string) (string, string) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return "unknown", fullMethod
+	}
+	return service, method
+}
+
+// storageCollector reports the size of every namespace at scrape time.
+type storageCollector struct {
+	namespaces *storage.Namespaces
+}
+
+var (
+	keysDesc = prometheus.NewDesc("kvstore_keys",
+		"Keys stored, including expired keys not removed yet, by namespace.",
+		[]string{"namespace"}, nil)
+	memoryDesc = prometheus.NewDesc("kvstore_memory_bytes",
+		"Approximate bytes used by keys and values, by namespace.",
+		[]string{"namespace"}, nil)
+)
+
+func (c *storageCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- keysDesc
+	ch <- memoryDesc
+}
+
+func (c *storageCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, ns := range c.namespaces.List() {
+		usage := ns.Usage()
+		ch <- prometheus.MustNewConstMetric(keysDesc, prometheus.GaugeValue, float64(usage.Keys), ns.Name())
+		ch <- prometheus.MustNewConstMetric(memoryDesc, prometheus.GaugeValue, float64(usage.Bytes), ns.Name())
+	}
+}
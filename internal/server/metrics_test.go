@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"io"
+	"kvstore/internal/storage"
+	pb "kvstore/pkg/pb/api/proto"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Test the interceptor counts requests, errors by code and latency
+func TestMetrics_UnaryInterceptor(t *testing.T) {
+	m := NewMetrics(storage.NewNamespaces())
+	interceptor := m.UnaryInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: pb.KVStore_Get_FullMethodName}
+
+	ok := func(ctx context.Context, req any) (any, error) { return nil, nil }
+	notFound := func(ctx context.Context, req any) (any, error) {
+		return nil, status.Error(codes.NotFound, "key not found")
+	}
+
+	_, _ = interceptor(context.Background(), nil, info, ok)
+	_, _ = interceptor(context.Background(), nil, info, notFound)
+	_, _ = interceptor(context.Background(), nil, info, notFound)
+
+	assert.Equal(t, 3.0, testutil.ToFloat64(m.requests.WithLabelValues("kvstore.v1.KVStore", "Get")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.errors.WithLabelValues("kvstore.v1.KVStore", "Get", "NotFound")))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.latency))
+}
+
+// Test the handler exposes storage gauges and hook counters per namespace
+func TestMetrics_Handler(t *testing.T) {
+	namespaces := storage.NewNamespaces()
+	m := NewMetrics(namespaces)
+
+	require.NoError(t, namespaces.Default().Set("a", "12345", nil))
+	namespaces.SetEvictionPolicy(storage.AllKeysRandom)
+	require.NoError(t, namespaces.Default().Set("b", "12345", nil))
+	namespaces.SetMaxMemory(6)
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, MetricsPath, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	lines := strings.Split(string(body), "\n")
+
+	assert.Contains(t, lines, `kvstore_keys{namespace="default"} 1`)
+	assert.Contains(t, lines, `kvstore_memory_bytes{namespace="default"} 6`)
+	assert.Contains(t, lines, `kvstore_evicted_keys_total{namespace="default"} 1`)
+	assert.Contains(t, string(body), "go_goroutines")
+}
+
+func TestSplitMethod(t *testing.T) {
+	service, method := splitMethod(pb.Admin_ReloadConfig_FullMethodName)
+	assert.Equal(t, "kvstore.v1.Admin", service)
+	assert.Equal(t, "ReloadConfig", method)
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"kvstore/internal/cluster"
+	"kvstore/internal/crdt"
+	"kvstore/internal/redirect"
+	"kvstore/internal/replication"
+	"kvstore/internal/shard"
+	"kvstore/internal/storage"
+	pb "kvstore/pkg/pb/api/proto"
+	"strconv"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Replicator commits writes through a replicated log instead of applying
+// them to local storage directly; every node then applies them to its own
+// namespaces. *cluster.Node, *replication.Primary, *replication.Replica
+// and *replication.Site implement it.
+type Replicator interface {
+	// Set and Delete return the log index the write committed at, which
+	// reads may pass back as their minimum index.
+	Set(ctx context.Context, namespace string, r storage.Record) (uint64, error)
+	Delete(ctx context.Context, namespace, key string) (bool, uint64, error)
+	CreateNamespace(ctx context.Context, name string, settings storage.NamespaceSettings) error
+	DropNamespace(ctx context.Context, name string) error
+	SetNamespaceQuota(ctx context.Context, name string, quota storage.Quota) error
+	// Read blocks until the local namespaces may serve a read with opts and
+	// returns the log index they have applied.
+	Read(ctx context.Context, opts cluster.ReadOptions) (uint64, error)
+}
+
+// TypedReplicator is a Replicator that merges concurrent changes to
+// counters and sets, which typed values need. *replication.Site implements
+// it.
+type TypedReplicator interface {
+	Replicator
+	// Increment returns the counter's total, and AddMembers and
+	// RemoveMembers the set's members, after the change.
+	Increment(ctx context.Context, namespace, key, owner string, delta int64) (int64, uint64, error)
+	AddMembers(ctx context.Context, namespace, key, owner string, members []string) ([]string, uint64, error)
+	RemoveMembers(ctx context.Context, namespace, key string, members []string) ([]string, uint64, error)
+}
+
+// readRequest is implemented by GetRequest and ListRequest.
+type readRequest interface {
+	GetConsistency() pb.ReadConsistency
+	GetMinIndex() uint64
+	GetMaxStaleness() *durationpb.Duration
+}
+
+// readOptions converts the consistency requested by req.
+func readOptions(req readRequest) (cluster.ReadOptions, error) {
+	opts := cluster.ReadOptions{MinIndex: req.GetMinIndex()}
+
+	switch req.GetConsistency() {
+	case pb.ReadConsistency_READ_CONSISTENCY_UNSPECIFIED, pb.ReadConsistency_READ_CONSISTENCY_SEQUENTIAL:
+		opts.Consistency = cluster.Sequential
+	case pb.ReadConsistency_READ_CONSISTENCY_LINEARIZABLE:
+		opts.Consistency = cluster.Linearizable
+	case pb.ReadConsistency_READ_CONSISTENCY_LEASE:
+		opts.Consistency = cluster.Lease
+	case pb.ReadConsistency_READ_CONSISTENCY_STALE:
+		opts.Consistency = cluster.Stale
+	default:
+		return opts, status.Errorf(codes.InvalidArgument, "unknown read consistency %v", req.GetConsistency())
+	}
+
+	if d := req.GetMaxStaleness(); d != nil {
+		if err := d.CheckValid(); err != nil || d.AsDuration() < 0 {
+			return opts, status.Error(codes.InvalidArgument, "max_staleness must be a non-negative duration")
+		}
+		opts.MaxStaleness = d.AsDuration()
+	}
+
+	return opts, nil
+}
+
+// WithReplicator sends Set and Delete through r and has Get and List wait
+// for the consistency they request.
+func WithReplicator(r Replicator) Option {
+	return func(s *Server) {
+		s.replicator = r
+	}
+}
+
+// WithNamespaceReplicator sends namespace changes through r.
+func WithNamespaceReplicator(r Replicator) AdminOption {
+	return func(a *AdminServer) {
+		a.replicator = r
+	}
+}
+
+// clusterError converts an error from storage, the cluster, replication or
+// sharding into a status. Requests a follower, replica or shard cannot serve
+// fail with Unavailable and a redirect to the leader, primary or owner.
+func clusterError(err error, action string) error {
+	var (
+		notLeader    *cluster.NotLeaderError
+		stale        *cluster.StaleError
+		readOnly     *replication.ReadOnlyError
+		replicaStale *replication.StaleError
+		wrongShard   *shard.WrongShardError
+		ask          *shard.AskError
+	)
+	switch {
+	case errors.As(err, &notLeader):
+		return redirect.Error(codes.Unavailable, redirect.ReasonNotLeader, notLeader.LeaderAddr,
+			notLeader.Error(), map[string]string{"leader_id": notLeader.LeaderID})
+	case errors.As(err, &stale):
+		return redirect.Error(codes.Unavailable, redirect.ReasonTooStale, stale.LeaderAddr,
+			stale.Error(), map[string]string{"leader_id": stale.LeaderID})
+	case errors.As(err, &readOnly):
+		return redirect.Error(codes.Unavailable, redirect.ReasonReadOnly, readOnly.PrimaryAddr, readOnly.Error(), nil)
+	case errors.As(err, &replicaStale):
+		return redirect.Error(codes.Unavailable, redirect.ReasonTooStale, replicaStale.PrimaryAddr, replicaStale.Error(), nil)
+	case errors.As(err, &wrongShard):
+		return redirect.Error(codes.Unavailable, redirect.ReasonWrongShard, wrongShard.Owner.Addr, wrongShard.Error(),
+			map[string]string{"node_id": wrongShard.Owner.ID, "shard_map_version": strconv.FormatUint(wrongShard.Version, 10)})
+	case errors.As(err, &ask):
+		return redirect.Error(codes.Unavailable, redirect.ReasonAsk, ask.Owner.Addr, ask.Error(),
+			map[string]string{"node_id": ask.Owner.ID, "shard_map_version": strconv.FormatUint(ask.Version, 10)})
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	}
+	return status.Errorf(clusterCode(err), "failed to %s: %v", action, err)
+}
+
+// clusterCode picks the gRPC code for an error returned by the cluster,
+// replication, sharding or the storage layer.
+func clusterCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, cluster.ErrUnknownMember):
+		return codes.NotFound
+	case errors.Is(err, cluster.ErrMemberExists):
+		return codes.AlreadyExists
+	case errors.Is(err, cluster.ErrInvalidPeer):
+		return codes.InvalidArgument
+	case errors.Is(err, cluster.ErrLastVoter), errors.Is(err, cluster.ErrNotLearner), errors.Is(err, cluster.ErrNotVoter):
+		return codes.FailedPrecondition
+	case errors.Is(err, shard.ErrMigrating), errors.Is(err, shard.ErrNotMigrating),
+		errors.Is(err, shard.ErrInvalidMigration), errors.Is(err, shard.ErrMigrationPending):
+		return codes.FailedPrecondition
+	case errors.Is(err, crdt.ErrWrongKind), errors.Is(err, replication.ErrMultiLeader):
+		return codes.FailedPrecondition
+	}
+	return storageCode(err)
+}
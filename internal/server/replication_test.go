@@ -0,0 +1,228 @@
+package server
+
+import (
+	"context"
+	"io"
+	"kvstore/internal/cluster"
+	"kvstore/internal/redirect"
+	"kvstore/internal/replication"
+	"kvstore/internal/storage"
+	pb "kvstore/pkg/pb/api/proto"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// recordingReplicator records writes and reads, or fails them all with err.
+type recordingReplicator struct {
+	Replicator
+	records []storage.Record
+	reads   []cluster.ReadOptions
+	err     error
+}
+
+func (r *recordingReplicator) Set(ctx context.Context, namespace string, rec storage.Record) (uint64, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	r.records = append(r.records, rec)
+	return uint64(len(r.records)), nil
+}
+
+func (r *recordingReplicator) Read(ctx context.Context, opts cluster.ReadOptions) (uint64, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	r.reads = append(r.reads, opts)
+	return uint64(len(r.records)), nil
+}
+
+// Test writes go through the replicator and followers redirect to the leader
+func TestServer_Replicator(t *testing.T) {
+	namespaces := storage.NewNamespaces()
+	replicator := &recordingReplicator{}
+	s := New(namespaces, WithReplicator(replicator))
+
+	ttl := int64(60)
+	resp, err := s.Set(context.Background(), &pb.SetRequest{Key: "a", Value: "1", TtlSeconds: &ttl})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), resp.GetIndex())
+	require.Len(t, replicator.records, 1)
+	assert.Equal(t, "a", replicator.records[0].Key)
+	assert.NotZero(t, replicator.records[0].ExpiresAt, "the TTL is resolved before replicating")
+
+	_, found := namespaces.Default().Get("a")
+	assert.False(t, found, "only the replicated log applies writes")
+
+	replicator.err = &cluster.NotLeaderError{LeaderID: "n2", LeaderAddr: "10.0.0.2:9090"}
+	_, err = s.Set(context.Background(), &pb.SetRequest{Key: "a", Value: "1"})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	info, ok := redirect.Info(err)
+	require.True(t, ok)
+	assert.Equal(t, redirect.ReasonNotLeader, info.GetReason())
+	assert.Equal(t, "10.0.0.2:9090", info.GetMetadata()[redirect.AddrKey])
+	assert.Equal(t, "n2", info.GetMetadata()["leader_id"])
+}
+
+// Test reads pass their consistency to the replicator
+func TestServer_ReadConsistency(t *testing.T) {
+	replicator := &recordingReplicator{}
+	s := New(storage.NewNamespaces(), WithReplicator(replicator))
+	ctx := context.Background()
+
+	_, err := s.Get(ctx, &pb.GetRequest{Key: "a"})
+	require.NoError(t, err)
+	_, err = s.Get(ctx, &pb.GetRequest{Key: "a", Consistency: pb.ReadConsistency_READ_CONSISTENCY_LINEARIZABLE})
+	require.NoError(t, err)
+	_, err = s.List(ctx, &pb.ListRequest{Consistency: pb.ReadConsistency_READ_CONSISTENCY_SEQUENTIAL, MinIndex: 7})
+	require.NoError(t, err)
+	_, err = s.List(ctx, &pb.ListRequest{
+		Consistency:  pb.ReadConsistency_READ_CONSISTENCY_STALE,
+		MaxStaleness: durationpb.New(time.Second),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []cluster.ReadOptions{
+		{Consistency: cluster.Sequential},
+		{Consistency: cluster.Linearizable},
+		{Consistency: cluster.Sequential, MinIndex: 7},
+		{Consistency: cluster.Stale, MaxStaleness: time.Second},
+	}, replicator.reads)
+
+	_, err = s.Get(ctx, &pb.GetRequest{Key: "a", MaxStaleness: durationpb.New(-time.Second)})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	replicator.err = &cluster.StaleError{Staleness: time.Minute, Bound: time.Second, LeaderAddr: "10.0.0.2:9090"}
+	_, err = s.Get(ctx, &pb.GetRequest{Key: "a", Consistency: pb.ReadConsistency_READ_CONSISTENCY_STALE})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	info, ok := redirect.Info(err)
+	require.True(t, ok)
+	assert.Equal(t, redirect.ReasonTooStale, info.GetReason())
+	assert.Equal(t, "10.0.0.2:9090", info.GetMetadata()[redirect.AddrKey])
+
+	// A standalone server serves every mode immediately
+	resp, err := New(storage.NewNamespaces()).Get(ctx, &pb.GetRequest{Key: "a", Consistency: pb.ReadConsistency_READ_CONSISTENCY_LINEARIZABLE})
+	require.NoError(t, err)
+	assert.Zero(t, resp.GetIndex())
+}
+
+// Test membership is served and changed through the Admin API
+func TestAdmin_Membership(t *testing.T) {
+	_, err := NewAdmin().GetMembership(context.Background(), &pb.GetMembershipRequest{})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+
+	addr, transport := raft.NewInmemTransport("")
+	node, err := cluster.New(cluster.Options{
+		ID:        "n1",
+		Peers:     []cluster.Peer{{ID: "n1", RaftAddr: string(addr), GRPCAddr: "127.0.0.1:9090"}},
+		Bootstrap: true,
+		Transport: transport,
+		LogOutput: io.Discard,
+	}, storage.NewNamespaces())
+	require.NoError(t, err)
+	defer node.Shutdown()
+	require.Eventually(t, node.IsLeader, 5*time.Second, 10*time.Millisecond)
+
+	admin := NewAdmin(WithCluster(node))
+	ctx := context.Background()
+
+	resp, err := admin.GetMembership(ctx, &pb.GetMembershipRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "n1", resp.GetNodeId())
+	assert.Equal(t, "Leader", resp.GetState())
+	require.Len(t, resp.GetMembership().GetMembers(), 1)
+	member := resp.GetMembership().GetMembers()[0]
+	assert.Equal(t, "127.0.0.1:9090", member.GetGrpcAddr())
+	assert.Equal(t, pb.ClusterMember_ROLE_VOTER, member.GetRole())
+	assert.True(t, member.GetLeader())
+
+	_, err = admin.PromoteLearner(ctx, &pb.PromoteLearnerRequest{Id: "n9"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = admin.PromoteLearner(ctx, &pb.PromoteLearnerRequest{Id: "n1"})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	_, err = admin.AddLearner(ctx, &pb.AddLearnerRequest{Id: "n1", RaftAddr: "x", GrpcAddr: "y"})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	_, err = admin.AddLearner(ctx, &pb.AddLearnerRequest{Id: "n2", RaftAddr: "x"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+// Test replicas redirect writes to the primary and report their status
+func TestServer_ReadOnlyReplica(t *testing.T) {
+	replica := replication.NewReplica(storage.NewNamespaces(), replication.ReplicaOptions{PrimaryAddr: "10.0.0.1:9090"})
+	ctx := context.Background()
+
+	_, err := New(storage.NewNamespaces(), WithReplicator(replica)).Set(ctx, &pb.SetRequest{Key: "a", Value: "1"})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	info, ok := redirect.Info(err)
+	require.True(t, ok)
+	assert.Equal(t, redirect.ReasonReadOnly, info.GetReason())
+	assert.Equal(t, "10.0.0.1:9090", info.GetMetadata()[redirect.AddrKey])
+
+	_, err = NewAdmin().GetReplicationStatus(ctx, &pb.GetReplicationStatusRequest{})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+
+	resp, err := NewAdmin(WithReplication(replica)).GetReplicationStatus(ctx, &pb.GetReplicationStatusRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, pb.GetReplicationStatusResponse_ROLE_REPLICA, resp.GetRole())
+	assert.Equal(t, "10.0.0.1:9090", resp.GetPrimaryAddr())
+	assert.False(t, resp.GetConnected())
+	assert.Nil(t, resp.GetLastContact())
+
+	_, err = NewAdmin().CheckConsistency(ctx, &pb.CheckConsistencyRequest{})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+	_, err = NewAdmin(WithReplication(replication.NewPrimary(storage.NewNamespaces()))).CheckConsistency(ctx, &pb.CheckConsistencyRequest{})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err), "primaries have no primary to check against")
+	_, err = NewAdmin(WithReplication(replica)).CheckConsistency(ctx, &pb.CheckConsistencyRequest{})
+	assert.Equal(t, codes.Unavailable, status.Code(err), "the replica is not running")
+}
+
+// Test counters and sets need an active site, which rejects linearizable
+// reads and reports its peers
+func TestServer_ActiveSite(t *testing.T) {
+	namespaces := storage.NewNamespaces()
+	site := replication.NewSite(namespaces, replication.SiteOptions{ID: "a", Peers: []string{"10.0.0.2:9090"}})
+	s := New(namespaces, WithReplicator(site))
+	ctx := context.Background()
+
+	_, err := New(storage.NewNamespaces()).Increment(ctx, &pb.IncrementRequest{Key: "n", Delta: 1})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	incr, err := s.Increment(ctx, &pb.IncrementRequest{Key: "n", Delta: 5})
+	require.NoError(t, err)
+	incr, err = s.Increment(ctx, &pb.IncrementRequest{Key: "n", Delta: -2})
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, incr.GetValue())
+	assert.Equal(t, uint64(2), incr.GetIndex())
+
+	members, err := s.AddMembers(ctx, &pb.MembersRequest{Key: "s", Members: []string{"y", "x"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"x", "y"}, members.GetMembers())
+	members, err = s.RemoveMembers(ctx, &pb.MembersRequest{Key: "s", Members: []string{"y"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"x"}, members.GetMembers())
+	_, err = s.AddMembers(ctx, &pb.MembersRequest{Key: "s"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = s.AddMembers(ctx, &pb.MembersRequest{Key: "n", Members: []string{"x"}})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err), "n holds a counter")
+
+	get, err := s.Get(ctx, &pb.GetRequest{Key: "n"})
+	require.NoError(t, err)
+	assert.Equal(t, "3", get.GetValue())
+	_, err = s.Get(ctx, &pb.GetRequest{Key: "n", Consistency: pb.ReadConsistency_READ_CONSISTENCY_LINEARIZABLE})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	resp, err := NewAdmin(WithReplication(site)).GetReplicationStatus(ctx, &pb.GetReplicationStatusRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, pb.GetReplicationStatusResponse_ROLE_ACTIVE, resp.GetRole())
+	assert.Equal(t, "a", resp.GetSiteId())
+	require.Len(t, resp.GetPeers(), 1)
+	assert.Equal(t, "10.0.0.2:9090", resp.GetPeers()[0].GetAddr())
+	assert.False(t, resp.GetPeers()[0].GetConnected())
+}
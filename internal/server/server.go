@@ -2,64 +2,165 @@ package server
 
 import (
 	"context"
+	"errors"
+	"kvstore/internal/auth"
+	"kvstore/internal/shard"
 	"kvstore/internal/storage"
 	pb "kvstore/pkg/pb/api/proto"
+	"slices"
+	"strings"
+	"sync/atomic"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
+// Limits bound the size of requests accepted by the server. Zero values
+// disable the corresponding check.
+type Limits struct {
+	MaxKeyBytes   int
+	MaxValueBytes int
+	MaxListLimit  int
+}
+
+type Option func(*Server)
+
+func WithLimits(limits Limits) Option {
+	return func(s *Server) {
+		s.SetLimits(limits)
+	}
+}
+
 type Server struct {
 	pb.UnimplementedKVStoreServer
-	storage storage.Storage
+	namespaces *storage.Namespaces
+	limits     atomic.Pointer[Limits]
+	replicator Replicator
+	shards     *shard.Router
+}
+
+func New(namespaces *storage.Namespaces, opts ...Option) *Server {
+	s := &Server{namespaces: namespaces}
+	s.limits.Store(&Limits{})
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
 }
 
-func New(storage storage.Storage) *Server {
-	return &Server{storage: storage}
+// SetLimits replaces the request limits. It is safe to call while serving.
+func (s *Server) SetLimits(limits Limits) {
+	s.limits.Store(&limits)
 }
 
 func (s *Server) Get(ctx context.Context, req *pb.GetRequest) (*pb.GetResponse, error) {
-	if req.GetKey() == "" {
-		return nil, status.Error(codes.InvalidArgument, "key cannot be empty")
+	if err := s.validateKey(req.GetKey()); err != nil {
+		return nil, err
+	}
+
+	index, err := s.awaitRead(ctx, req)
+	if err != nil {
+		return nil, err
 	}
 
-	val, found := s.storage.Get(req.GetKey())
+	ns, err := s.namespace(req.GetNamespace())
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := s.route(ctx, ns, req.GetKey())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	val, found := ns.GetContext(ctx, req.GetKey())
 	return &pb.GetResponse{
 		Value: val,
 		Found: found,
+		Index: index,
 	}, nil
 }
 
 func (s *Server) Set(ctx context.Context, req *pb.SetRequest) (*pb.SetResponse, error) {
-	if req.GetKey() == "" {
-		return &pb.SetResponse{Success: false}, status.Error(codes.InvalidArgument, "key cannot be empty")
+	if err := s.validateKey(req.GetKey()); err != nil {
+		return &pb.SetResponse{Success: false}, err
 	}
 
-	ttlSeconds := req.GetTtlSeconds()
-	err := s.storage.Set(req.GetKey(), req.GetValue(), &ttlSeconds)
-	if err != nil {
+	if max := s.limits.Load().MaxValueBytes; max > 0 && len(req.GetValue()) > max {
 		return &pb.SetResponse{Success: false},
-			status.Errorf(codes.Internal, "failed to set key: %v", err)
+			status.Errorf(codes.InvalidArgument, "value exceeds maximum size of %d bytes", max)
+	}
+
+	ns, err := s.namespace(req.GetNamespace())
+	if err != nil {
+		return &pb.SetResponse{Success: false}, err
+	}
+
+	release, err := s.route(ctx, ns, req.GetKey())
+	if err != nil {
+		return &pb.SetResponse{Success: false}, err
+	}
+	defer release()
+
+	owner := principal(ctx)
+
+	var index uint64
+	if s.replicator != nil {
+		index, err = s.replicator.Set(ctx, ns.Name(), storage.Record{
+			Key:       req.GetKey(),
+			Value:     req.GetValue(),
+			Owner:     owner,
+			ExpiresAt: ns.ExpiresAt(req.TtlSeconds),
+		})
+	} else {
+		err = ns.SetAsContext(ctx, owner, req.GetKey(), req.GetValue(), req.TtlSeconds)
+	}
+	if err != nil {
+		return &pb.SetResponse{Success: false}, clusterError(err, "set key")
 	}
 
 	return &pb.SetResponse{
 		Success: true,
+		Index:   index,
 	}, nil
 }
 
 func (s *Server) Delete(ctx context.Context, req *pb.DeleteRequest) (*pb.DeleteResponse, error) {
-	if req.GetKey() == "" {
-		return nil, status.Error(codes.InvalidArgument, "key cannot be empty")
+	if err := s.validateKey(req.GetKey()); err != nil {
+		return nil, err
 	}
 
-	existed, err := s.storage.Delete(req.GetKey())
+	ns, err := s.namespace(req.GetNamespace())
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to delete key: %v", err)
+		return nil, err
+	}
+
+	release, err := s.route(ctx, ns, req.GetKey())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var (
+		existed bool
+		index   uint64
+	)
+	if s.replicator != nil {
+		existed, index, err = s.replicator.Delete(ctx, ns.Name(), req.GetKey())
+	} else {
+		existed, err = ns.DeleteContext(ctx, req.GetKey())
+	}
+	if err != nil {
+		return nil, clusterError(err, "delete key")
 	}
 
 	return &pb.DeleteResponse{
 		Success: true,
 		Existed: existed,
+		Index:   index,
 	}, nil
 }
 
@@ -69,9 +170,23 @@ func (s *Server) List(ctx context.Context, req *pb.ListRequest) (*pb.ListRespons
 		return nil, status.Error(codes.InvalidArgument, "limit cannot be negative")
 	}
 
-	data, err := s.storage.List(limit)
+	if max := s.limits.Load().MaxListLimit; max > 0 && (limit == 0 || limit > max) {
+		limit = max
+	}
+
+	index, err := s.awaitRead(ctx, req)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to list keys: %v", err)
+		return nil, err
+	}
+
+	ns, err := s.namespace(req.GetNamespace())
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ns.ScanFuncContext(ctx, req.GetPrefix(), limit, auth.ReadFilter(ctx))
+	if err != nil {
+		return nil, status.Errorf(storageCode(err), "failed to list keys: %v", err)
 	}
 
 	var pairs []*pb.KeyValuePair
@@ -81,6 +196,64 @@ func (s *Server) List(ctx context.Context, req *pb.ListRequest) (*pb.ListRespons
 			Value: v,
 		})
 	}
+	slices.SortFunc(pairs, func(a, b *pb.KeyValuePair) int { return strings.Compare(a.GetKey(), b.GetKey()) })
+
+	return &pb.ListResponse{Pairs: pairs, Index: index}, nil
+}
+
+// awaitRead waits until the namespaces may serve req at the consistency it
+// requests and returns the log index they have applied. A standalone server
+// serves every read immediately.
+func (s *Server) awaitRead(ctx context.Context, req readRequest) (uint64, error) {
+	opts, err := readOptions(req)
+	if err != nil {
+		return 0, err
+	}
+	if s.replicator == nil {
+		return 0, nil
+	}
 
-	return &pb.ListResponse{Pairs: pairs}, nil
+	index, err := s.replicator.Read(ctx, opts)
+	if err != nil {
+		return 0, clusterError(err, "read")
+	}
+	return index, nil
+}
+
+func (s *Server) namespace(name string) (*storage.Namespace, error) {
+	ns, err := s.namespaces.Get(name)
+	if err != nil {
+		return nil, status.Errorf(storageCode(err), "%v", err)
+	}
+	return ns, nil
+}
+
+func (s *Server) validateKey(key string) error {
+	if key == "" {
+		return status.Error(codes.InvalidArgument, "key cannot be empty")
+	}
+
+	if max := s.limits.Load().MaxKeyBytes; max > 0 && len(key) > max {
+		return status.Errorf(codes.InvalidArgument, "key exceeds maximum size of %d bytes", max)
+	}
+
+	return nil
+}
+
+// storageCode picks the gRPC code for an error returned by the storage layer.
+func storageCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, storage.ErrOutOfMemory), errors.Is(err, storage.ErrQuotaExceeded):
+		return codes.ResourceExhausted
+	case errors.Is(err, storage.ErrNamespaceNotFound):
+		return codes.NotFound
+	case errors.Is(err, storage.ErrNamespaceExists):
+		return codes.AlreadyExists
+	case errors.Is(err, storage.ErrInvalidNamespace):
+		return codes.InvalidArgument
+	case errors.Is(err, storage.ErrClosed):
+		return codes.Unavailable
+	default:
+		return codes.Internal
+	}
 }
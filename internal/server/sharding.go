@@ -0,0 +1,147 @@
+package server
+
+import (
+	"context"
+	"kvstore/internal/redirect"
+	"kvstore/internal/shard"
+	"kvstore/internal/storage"
+	pb "kvstore/pkg/pb/api/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// WithShards has Get, Set and Delete reject keys the node does not serve
+// according to r with a redirect to the node that does. List only covers
+// the keys of this node; while a migration runs a key being handed over may
+// briefly be listed by both its old and new owner.
+func WithShards(r *shard.Router) Option {
+	return func(s *Server) {
+		s.shards = r
+	}
+}
+
+// WithMigrator serves shard migrations and hands keys over through m.
+func WithMigrator(m *shard.Migrator) AdminOption {
+	return func(a *AdminServer) {
+		a.migrator = m
+	}
+}
+
+// route fails with a redirect unless the node serves key in ns. Otherwise
+// the request must call release once it is done with the key.
+func (s *Server) route(ctx context.Context, ns *storage.Namespace, key string) (release func(), err error) {
+	if s.shards == nil {
+		return func() {}, nil
+	}
+
+	release, err = s.shards.Acquire(key, redirect.Asking(ctx), func() bool {
+		_, ok := ns.Record(key)
+		return ok
+	})
+	if err != nil {
+		return nil, clusterError(err, "route key")
+	}
+	return release, nil
+}
+
+func (s *Server) GetShardMap(ctx context.Context, req *pb.GetShardMapRequest) (*pb.GetShardMapResponse, error) {
+	if s.shards == nil {
+		return nil, status.Error(codes.Unimplemented, "sharding is not enabled")
+	}
+	current, next := s.shards.Maps()
+	resp := &pb.GetShardMapResponse{
+		NodeId:   s.shards.Self(),
+		ShardMap: current.Proto(),
+	}
+	if next != nil {
+		resp.Target = next.Proto()
+	}
+	return resp, nil
+}
+
+func (a *AdminServer) BeginMigration(ctx context.Context, req *pb.BeginMigrationRequest) (*pb.MigrationStatus, error) {
+	if a.migrator == nil {
+		return nil, status.Error(codes.Unimplemented, "sharding is not enabled")
+	}
+
+	from, err := shard.MapFromProto(req.GetFrom())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid from map: %v", err)
+	}
+	to, err := shard.MapFromProto(req.GetTo())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid to map: %v", err)
+	}
+
+	if err := a.migrator.Begin(from, to); err != nil {
+		return nil, clusterError(err, "begin migration")
+	}
+	return a.migrationStatus(), nil
+}
+
+func (a *AdminServer) GetMigrationStatus(ctx context.Context, req *pb.GetMigrationStatusRequest) (*pb.MigrationStatus, error) {
+	if a.migrator == nil {
+		return nil, status.Error(codes.Unimplemented, "sharding is not enabled")
+	}
+	return a.migrationStatus(), nil
+}
+
+func (a *AdminServer) CommitMigration(ctx context.Context, req *pb.CommitMigrationRequest) (*pb.MigrationStatus, error) {
+	if a.migrator == nil {
+		return nil, status.Error(codes.Unimplemented, "sharding is not enabled")
+	}
+	if err := a.migrator.Commit(req.GetVersion()); err != nil {
+		return nil, clusterError(err, "commit migration")
+	}
+	return a.migrationStatus(), nil
+}
+
+func (a *AdminServer) ImportKeys(ctx context.Context, req *pb.ImportKeysRequest) (*pb.ImportKeysResponse, error) {
+	if a.migrator == nil {
+		return nil, status.Error(codes.Unimplemented, "sharding is not enabled")
+	}
+
+	records := make([]storage.Record, 0, len(req.GetRecords()))
+	for _, r := range req.GetRecords() {
+		records = append(records, storage.Record{
+			Key:       r.GetKey(),
+			Value:     r.GetValue(),
+			Owner:     r.GetOwner(),
+			ExpiresAt: r.GetExpiresAt(),
+		})
+	}
+	settings := storage.NamespaceSettings{
+		DefaultTTLSeconds: req.GetDefaultTtlSeconds(),
+		Quota:             quotaFromProto(req.GetQuota()),
+	}
+
+	if err := a.migrator.Import(ctx, req.GetVersion(), req.GetNamespace(), settings, records); err != nil {
+		return nil, clusterError(err, "import keys")
+	}
+	return &pb.ImportKeysResponse{}, nil
+}
+
+func (a *AdminServer) migrationStatus() *pb.MigrationStatus {
+	st := a.migrator.Status()
+	resp := &pb.MigrationStatus{
+		NodeId:        a.migrator.Self(),
+		Version:       st.Version,
+		TargetVersion: st.TargetVersion,
+		KeysSent:      st.KeysSent,
+		KeysReceived:  st.KeysReceived,
+		KeysRemaining: st.KeysRemaining,
+		LastError:     st.LastError,
+	}
+	switch st.State {
+	case shard.MigrationMigrating:
+		resp.State = pb.MigrationState_MIGRATION_STATE_MIGRATING
+	case shard.MigrationDone:
+		resp.State = pb.MigrationState_MIGRATION_STATE_DONE
+	}
+	if !st.StartedAt.IsZero() {
+		resp.StartedAt = timestamppb.New(st.StartedAt)
+	}
+	return resp
+}
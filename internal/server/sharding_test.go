@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"kvstore/internal/redirect"
+	"kvstore/internal/shard"
+	"kvstore/internal/storage"
+	pb "kvstore/pkg/pb/api/proto"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Test keys owned by other shards are redirected to their owner and the
+// shard map is served
+func TestServer_Shards(t *testing.T) {
+	m, err := shard.NewMap(2, []shard.Node{{ID: "a", Addr: "10.0.0.1:9090"}, {ID: "b", Addr: "10.0.0.2:9090"}}, 16)
+	require.NoError(t, err)
+	s := New(storage.NewNamespaces(), WithShards(shard.NewRouter("a", m)))
+	ctx := context.Background()
+
+	var own, other string
+	for i := 0; own == "" || other == ""; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if m.Owner(key).ID == "a" {
+			own = key
+		} else {
+			other = key
+		}
+	}
+
+	_, err = s.Set(ctx, &pb.SetRequest{Key: own, Value: "1"})
+	require.NoError(t, err)
+	resp, err := s.Get(ctx, &pb.GetRequest{Key: own})
+	require.NoError(t, err)
+	assert.True(t, resp.GetFound())
+
+	_, err = s.Set(ctx, &pb.SetRequest{Key: other, Value: "1"})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	info, ok := redirect.Info(err)
+	require.True(t, ok)
+	assert.Equal(t, redirect.ReasonWrongShard, info.GetReason())
+	assert.Equal(t, "10.0.0.2:9090", info.GetMetadata()[redirect.AddrKey])
+	assert.Equal(t, "b", info.GetMetadata()["node_id"])
+	assert.Equal(t, "2", info.GetMetadata()["shard_map_version"])
+
+	_, err = s.Get(ctx, &pb.GetRequest{Key: other})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	_, err = s.Delete(ctx, &pb.DeleteRequest{Key: other})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	mapResp, err := s.GetShardMap(ctx, &pb.GetShardMapRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "a", mapResp.GetNodeId())
+	assert.EqualValues(t, 2, mapResp.GetShardMap().GetVersion())
+	assert.Len(t, mapResp.GetShardMap().GetNodes(), 2)
+	assert.Len(t, mapResp.GetShardMap().GetTokens(), 32)
+
+	_, err = New(storage.NewNamespaces()).GetShardMap(ctx, &pb.GetShardMapRequest{})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
+
+// importer hands records to an admin server like the ImportKeys RPC would.
+type importer struct {
+	admin *AdminServer
+}
+
+func (i importer) Import(ctx context.Context, to shard.Node, version uint64, ns *storage.Namespace, records []storage.Record) error {
+	req := &pb.ImportKeysRequest{Version: version, Namespace: ns.Name()}
+	for _, r := range records {
+		req.Records = append(req.Records, &pb.MigratedRecord{Key: r.Key, Value: r.Value, Owner: r.Owner, ExpiresAt: r.ExpiresAt})
+	}
+	_, err := i.admin.ImportKeys(ctx, req)
+	return err
+}
+
+// Test a migration driven through the Admin API moves keys, redirecting
+// requests for them with ASK until it is committed
+func TestAdmin_Migration(t *testing.T) {
+	ctx := context.Background()
+	_, err := NewAdmin().GetMigrationStatus(ctx, &pb.GetMigrationStatusRequest{})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+
+	from := &pb.ShardMap{Version: 1, VirtualNodes: 16, Nodes: []*pb.ShardNode{{Id: "a", GrpcAddr: "10.0.0.1:9090"}}}
+	to := &pb.ShardMap{Version: 2, VirtualNodes: 16, Nodes: append(from.Nodes, &pb.ShardNode{Id: "b", GrpcAddr: "10.0.0.2:9090"})}
+	m, err := shard.MapFromProto(from)
+	require.NoError(t, err)
+	next, err := shard.MapFromProto(to)
+	require.NoError(t, err)
+
+	newNode := func(id string, imp shard.Importer) (*Server, *AdminServer, *storage.Namespaces) {
+		namespaces := storage.NewNamespaces()
+		router := shard.NewRouter(id, m)
+		migrator := shard.NewMigrator(router, namespaces, imp)
+		t.Cleanup(func() { migrator.Close() })
+		return New(namespaces, WithShards(router)), NewAdmin(WithMigrator(migrator)), namespaces
+	}
+	_, adminB, namespacesB := newNode("b", nil)
+	a, adminA, _ := newNode("a", importer{adminB})
+
+	var key string
+	for i := 0; key == ""; i++ {
+		k := fmt.Sprintf("key-%d", i)
+		_, err := a.Set(ctx, &pb.SetRequest{Key: k, Value: "1"})
+		require.NoError(t, err)
+		if next.Owner(k).ID == "b" {
+			key = k
+		}
+	}
+
+	_, err = adminB.ImportKeys(ctx, &pb.ImportKeysRequest{Version: 2})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err), "b is not migrating yet")
+
+	_, err = adminB.BeginMigration(ctx, &pb.BeginMigrationRequest{From: from, To: to})
+	require.NoError(t, err)
+	_, err = adminA.BeginMigration(ctx, &pb.BeginMigrationRequest{From: from, To: to})
+	require.NoError(t, err)
+
+	mapResp, err := a.GetShardMap(ctx, &pb.GetShardMapRequest{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, mapResp.GetShardMap().GetVersion())
+	assert.EqualValues(t, 2, mapResp.GetTarget().GetVersion())
+
+	require.Eventually(t, func() bool {
+		st, err := adminA.GetMigrationStatus(ctx, &pb.GetMigrationStatusRequest{})
+		require.NoError(t, err)
+		return st.GetState() == pb.MigrationState_MIGRATION_STATE_DONE
+	}, 5*time.Second, 10*time.Millisecond)
+
+	value, _ := namespacesB.Default().Get(key)
+	assert.Equal(t, "1", value)
+
+	_, err = a.Get(ctx, &pb.GetRequest{Key: key})
+	info, ok := redirect.Info(err)
+	require.True(t, ok)
+	assert.Equal(t, redirect.ReasonAsk, info.GetReason())
+	assert.Equal(t, "10.0.0.2:9090", info.GetMetadata()[redirect.AddrKey])
+
+	st, err := adminA.CommitMigration(ctx, &pb.CommitMigrationRequest{Version: 2})
+	require.NoError(t, err)
+	assert.Equal(t, pb.MigrationState_MIGRATION_STATE_IDLE, st.GetState())
+	assert.EqualValues(t, 2, st.GetVersion())
+	assert.NotZero(t, st.GetKeysSent())
+	mapResp, err = a.GetShardMap(ctx, &pb.GetShardMapRequest{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, mapResp.GetShardMap().GetVersion())
+	assert.Nil(t, mapResp.GetTarget())
+
+	_, err = a.Get(ctx, &pb.GetRequest{Key: key})
+	info, ok = redirect.Info(err)
+	require.True(t, ok)
+	assert.Equal(t, redirect.ReasonWrongShard, info.GetReason())
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"kvstore/internal/auth"
+	"kvstore/internal/storage"
+	pb "kvstore/pkg/pb/api/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Increment adds the request's delta to the counter held by the key,
+// starting one at zero if the key holds nothing.
+func (s *Server) Increment(ctx context.Context, req *pb.IncrementRequest) (*pb.IncrementResponse, error) {
+	typed, ns, release, err := s.typed(ctx, req.GetNamespace(), req.GetKey())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	total, index, err := typed.Increment(ctx, ns.Name(), req.GetKey(), principal(ctx), req.GetDelta())
+	if err != nil {
+		return nil, clusterError(err, "increment key")
+	}
+	return &pb.IncrementResponse{Value: total, Index: index}, nil
+}
+
+// AddMembers adds the request's members to the set held by the key,
+// starting an empty one if the key holds nothing.
+func (s *Server) AddMembers(ctx context.Context, req *pb.MembersRequest) (*pb.MembersResponse, error) {
+	if err := s.validateMembers(req.GetMembers()); err != nil {
+		return nil, err
+	}
+	typed, ns, release, err := s.typed(ctx, req.GetNamespace(), req.GetKey())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	members, index, err := typed.AddMembers(ctx, ns.Name(), req.GetKey(), principal(ctx), req.GetMembers())
+	if err != nil {
+		return nil, clusterError(err, "add members")
+	}
+	return &pb.MembersResponse{Members: members, Index: index}, nil
+}
+
+// RemoveMembers removes the request's members from the set held by the
+// key.
+func (s *Server) RemoveMembers(ctx context.Context, req *pb.MembersRequest) (*pb.MembersResponse, error) {
+	if err := s.validateMembers(req.GetMembers()); err != nil {
+		return nil, err
+	}
+	typed, ns, release, err := s.typed(ctx, req.GetNamespace(), req.GetKey())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	members, index, err := typed.RemoveMembers(ctx, ns.Name(), req.GetKey(), req.GetMembers())
+	if err != nil {
+		return nil, clusterError(err, "remove members")
+	}
+	return &pb.MembersResponse{Members: members, Index: index}, nil
+}
+
+// typed resolves the namespace and routes the key of a typed value change,
+// which only a TypedReplicator can merge.
+func (s *Server) typed(ctx context.Context, namespace, key string) (TypedReplicator, *storage.Namespace, func(), error) {
+	if err := s.validateKey(key); err != nil {
+		return nil, nil, nil, err
+	}
+	typed, ok := s.replicator.(TypedReplicator)
+	if !ok {
+		return nil, nil, nil, status.Error(codes.FailedPrecondition, "typed values need active-active replication")
+	}
+
+	ns, err := s.namespace(namespace)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	release, err := s.route(ctx, ns, key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return typed, ns, release, nil
+}
+
+func (s *Server) validateMembers(members []string) error {
+	if len(members) == 0 {
+		return status.Error(codes.InvalidArgument, "no members given")
+	}
+	max := s.limits.Load().MaxValueBytes
+	for _, m := range members {
+		if max > 0 && len(m) > max {
+			return status.Errorf(codes.InvalidArgument, "member exceeds maximum size of %d bytes", max)
+		}
+	}
+	return nil
+}
+
+// principal returns the authenticated caller, who owns the keys it
+// creates.
+func principal(ctx context.Context) string {
+	if id, ok := auth.FromContext(ctx); ok {
+		return id.Principal
+	}
+	return ""
+}
@@ -0,0 +1,96 @@
+package shard
+
+import (
+	"context"
+	"kvstore/internal/storage"
+	pb "kvstore/pkg/pb/api/proto"
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+// GRPCImporter hands records over through the Admin ImportKeys RPC of their
+// new owner, and fetches the maps of other nodes through their GetShardMap
+// RPC. Connections are kept open until Close.
+type GRPCImporter struct {
+	dialOpts []grpc.DialOption
+
+	mu    sync.Mutex
+	conns map[string]*grpc.ClientConn
+}
+
+// NewGRPCImporter dials other nodes with dialOpts, which must authenticate
+// as a principal with the admin permission if the nodes require it.
+func NewGRPCImporter(dialOpts ...grpc.DialOption) *GRPCImporter {
+	return &GRPCImporter{dialOpts: dialOpts, conns: make(map[string]*grpc.ClientConn)}
+}
+
+func (i *GRPCImporter) Import(ctx context.Context, to Node, version uint64, ns *storage.Namespace, records []storage.Record) error {
+	conn, err := i.conn(to.Addr)
+	if err != nil {
+		return err
+	}
+
+	settings := ns.Settings()
+	req := &pb.ImportKeysRequest{
+		Version:           version,
+		Namespace:         ns.Name(),
+		DefaultTtlSeconds: settings.DefaultTTLSeconds,
+		Quota: &pb.Quota{
+			MaxKeys:       settings.Quota.MaxKeys,
+			MaxBytes:      settings.Quota.MaxBytes,
+			MaxValueBytes: settings.Quota.MaxValueBytes,
+		},
+	}
+	for _, r := range records {
+		req.Records = append(req.Records, &pb.MigratedRecord{
+			Key:       r.Key,
+			Value:     r.Value,
+			Owner:     r.Owner,
+			ExpiresAt: r.ExpiresAt,
+		})
+	}
+
+	_, err = pb.NewAdminClient(conn).ImportKeys(ctx, req)
+	return err
+}
+
+func (i *GRPCImporter) FetchMap(ctx context.Context, addr string) (*Map, error) {
+	conn, err := i.conn(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := pb.NewKVStoreClient(conn).GetShardMap(ctx, &pb.GetShardMapRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return MapFromProto(resp.GetShardMap())
+}
+
+func (i *GRPCImporter) conn(addr string) (*grpc.ClientConn, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if conn, ok := i.conns[addr]; ok {
+		return conn, nil
+	}
+
+	conn, err := grpc.NewClient(addr, i.dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+	i.conns[addr] = conn
+	return conn, nil
+}
+
+func (i *GRPCImporter) Close() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for addr, conn := range i.conns {
+		conn.Close()
+		delete(i.conns, addr)
+	}
+	return nil
+}
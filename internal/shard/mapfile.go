@@ -0,0 +1,62 @@
+package shard
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// mapFile is how a Map is stored on disk; its tokens follow from the rest.
+type mapFile struct {
+	Version      uint64 `json:"version"`
+	VirtualNodes int    `json:"virtual_nodes"`
+	Nodes        []Node `json:"nodes"`
+}
+
+// LoadMap reads the map SaveMap stored at path. The error matches
+// os.ErrNotExist if there is none.
+func LoadMap(path string) (*Map, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f mapFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("shard: invalid map file %s: %w", path, err)
+	}
+	m, err := NewMap(f.Version, f.Nodes, f.VirtualNodes)
+	if err != nil {
+		return nil, fmt.Errorf("shard: invalid map file %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// SaveMap stores m at path, replacing the map stored there at once so that
+// a crash leaves either the old or the new one.
+func SaveMap(path string, m *Map) error {
+	data, err := json.MarshalIndent(mapFile{Version: m.Version, VirtualNodes: m.VirtualNodes, Nodes: m.Nodes}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
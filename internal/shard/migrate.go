@@ -0,0 +1,455 @@
+package shard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"kvstore/internal/storage"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRetryInterval is how long a migration waits before retrying a
+	// batch the new owner did not accept.
+	DefaultRetryInterval = time.Second
+	// batchSize bounds how many keys are handed over at once. Requests for
+	// the keys of a batch wait while it is in flight.
+	batchSize = 256
+	// importTimeout bounds how long a batch may take.
+	importTimeout = 30 * time.Second
+)
+
+// MigrationState is where a node is in a migration.
+type MigrationState int
+
+const (
+	// MigrationIdle: the node routes by a single map.
+	MigrationIdle MigrationState = iota
+	// MigrationMigrating: the node is handing over its leaving keys.
+	MigrationMigrating
+	// MigrationDone: every leaving key has been handed over and the
+	// migration can be committed.
+	MigrationDone
+)
+
+func (s MigrationState) String() string {
+	switch s {
+	case MigrationMigrating:
+		return "migrating"
+	case MigrationDone:
+		return "done"
+	}
+	return "idle"
+}
+
+// MigrationStatus reports the progress of a node's migration.
+type MigrationStatus struct {
+	State MigrationState
+	// Version is the version of the map the node routes by, or migrates
+	// from; TargetVersion the version it migrates to.
+	Version       uint64
+	TargetVersion uint64
+	KeysSent      uint64
+	KeysReceived  uint64
+	// KeysRemaining is how many leaving keys the last pass over the
+	// keyspace found.
+	KeysRemaining uint64
+	StartedAt     time.Time
+	// LastError is the last failure to hand over keys, cleared once a batch
+	// goes through.
+	LastError string
+}
+
+// Importer hands records to the node that takes over their keys, which
+// must accept them with Migrator.Import.
+type Importer interface {
+	Import(ctx context.Context, to Node, version uint64, ns *storage.Namespace, records []storage.Record) error
+}
+
+// MapFetcher gets the shard map the node at addr routes by, as returned by
+// its GetShardMap RPC.
+type MapFetcher interface {
+	FetchMap(ctx context.Context, addr string) (*Map, error)
+}
+
+type MigratorOption func(*Migrator)
+
+// WithMapFile saves every map the node commits to path with SaveMap, so
+// that it routes by it again after a restart.
+func WithMapFile(path string) MigratorOption {
+	return func(m *Migrator) {
+		m.mapFile = path
+	}
+}
+
+// WithRetryInterval sets how long to wait before retrying a failed batch.
+func WithRetryInterval(d time.Duration) MigratorOption {
+	return func(m *Migrator) {
+		m.retryInterval = d
+	}
+}
+
+// Migrator moves keys between nodes when the shard map changes, while the
+// router keeps serving them. Records move with their absolute expiry and
+// owner, so TTLs keep counting down and quotas stay charged to the same
+// principal.
+type Migrator struct {
+	router        *Router
+	namespaces    *storage.Namespaces
+	importer      Importer
+	retryInterval time.Duration
+	mapFile       string
+
+	mu     sync.Mutex
+	status MigrationStatus
+	// cancel stops the running migration, and done is closed once it has
+	// stopped.
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewMigrator migrates the keys in namespaces that router serves, handing
+// them over through importer.
+func NewMigrator(router *Router, namespaces *storage.Namespaces, importer Importer, opts ...MigratorOption) *Migrator {
+	m := &Migrator{
+		router:        router,
+		namespaces:    namespaces,
+		importer:      importer,
+		retryInterval: DefaultRetryInterval,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Begin starts migrating from one map to the next and returns immediately;
+// leaving keys are handed over in the background. Beginning the migration
+// the node is already in, or has committed, does nothing.
+//
+// m.mu is held across the switch of maps so that Commit cannot slip in
+// before the migration is recorded. Neither waits for the router's lock
+// while keys are being handed over, which would deadlock with requests
+// waiting for the batch in flight.
+func (m *Migrator) Begin(from, to *Map) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	started, err := m.router.begin(from, to)
+	if err != nil || !started {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel, m.done = cancel, make(chan struct{})
+	m.status = MigrationStatus{State: MigrationMigrating, StartedAt: time.Now()}
+	slog.Info("Shard migration started", "from_version", from.Version, "to_version", to.Version)
+
+	go m.run(ctx, from, to, m.done)
+	return nil
+}
+
+// Commit switches the node to the map migrated to, which must have version,
+// once it has handed over all of its leaving keys. Other nodes must not
+// commit before every node is done, or they stop asking for keys that have
+// not reached them yet.
+func (m *Migrator) Commit(version uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.commit(version)
+}
+
+// commit is Commit with m.mu held.
+func (m *Migrator) commit(version uint64) error {
+	if m.status.State == MigrationMigrating {
+		return fmt.Errorf("%w: %d keys left to hand over", ErrMigrationPending, m.status.KeysRemaining)
+	}
+	// Saved first, a map the node routes by is never lost on restart.
+	if _, next := m.router.Maps(); m.mapFile != "" && next != nil && next.Version == version {
+		if err := SaveMap(m.mapFile, next); err != nil {
+			return fmt.Errorf("failed to save shard map version %d: %w", version, err)
+		}
+	}
+	if err := m.router.commit(version); err != nil {
+		return err
+	}
+	if m.status.State == MigrationDone {
+		m.status.State = MigrationIdle
+		slog.Info("Shard migration committed", "version", version)
+	}
+	return nil
+}
+
+// CatchUp switches the node to the map the node at addr routes by, if that
+// has a later version than the node's own. Nodes commit a map only once
+// every node has handed its leaving keys over, so a node that missed the
+// commit, such as while it was down, has none left to send: it either
+// commits the migration it finished or, not migrating, adopts the map
+// outright. version is the one addr is known to route by, so that nodes
+// already up to date fetch nothing.
+func (m *Migrator) CatchUp(ctx context.Context, fetcher MapFetcher, addr string, version uint64) error {
+	if current := m.router.Map(); current.Version >= version {
+		return nil
+	}
+	latest, err := fetcher.FetchMap(ctx, addr)
+	if err != nil {
+		return fmt.Errorf("failed to fetch the shard map of %s: %w", addr, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current, next := m.router.Maps()
+	switch {
+	case latest.Version <= current.Version:
+		return nil
+	case next != nil && next.Version == latest.Version:
+		if err := m.commit(latest.Version); err != nil {
+			return err
+		}
+	case next != nil:
+		return fmt.Errorf("%w to version %d, not %d", ErrMigrating, next.Version, latest.Version)
+	default:
+		if m.mapFile != "" {
+			if err := SaveMap(m.mapFile, latest); err != nil {
+				return fmt.Errorf("failed to save shard map version %d: %w", latest.Version, err)
+			}
+		}
+		if err := m.router.adopt(latest); err != nil {
+			return err
+		}
+	}
+	slog.Info("Caught up with the shard map of another node", "version", latest.Version, "from", addr)
+	return nil
+}
+
+// Self is the ID of the node the migrator serves.
+func (m *Migrator) Self() string {
+	return m.router.Self()
+}
+
+// Status reports the progress of the current or last migration.
+func (m *Migrator) Status() MigrationStatus {
+	m.mu.Lock()
+	status := m.status
+	m.mu.Unlock()
+
+	current, next := m.router.Maps()
+	status.Version = current.Version
+	status.TargetVersion = 0
+	if next != nil {
+		status.TargetVersion = next.Version
+	}
+	return status
+}
+
+// Close stops handing over keys. Requests keep being routed by both maps.
+func (m *Migrator) Close() error {
+	m.mu.Lock()
+	cancel, done := m.cancel, m.done
+	m.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+	return nil
+}
+
+// Import stores records handed over by their old owner into the namespace
+// name, creating it with settings if needed. The node must be migrating to
+// version and own every key in the map migrated to.
+func (m *Migrator) Import(ctx context.Context, version uint64, name string, settings storage.NamespaceSettings, records []storage.Record) error {
+	if err := m.store(ctx, version, name, settings, records); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.status.KeysReceived += uint64(len(records))
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *Migrator) store(ctx context.Context, version uint64, name string, settings storage.NamespaceSettings, records []storage.Record) error {
+	// Commits wait until the records are stored.
+	m.router.mu.RLock()
+	defer m.router.mu.RUnlock()
+
+	next := m.router.next
+	if next == nil || next.Version != version {
+		return fmt.Errorf("%w to version %d", ErrNotMigrating, version)
+	}
+	for _, r := range records {
+		if owner := next.Owner(r.Key); owner.ID != m.router.self {
+			return fmt.Errorf("%w: key %q moves to node %s", ErrInvalidMigration, r.Key, owner.ID)
+		}
+	}
+
+	ns, err := m.namespaces.Get(name)
+	if errors.Is(err, storage.ErrNamespaceNotFound) {
+		ns, err = m.namespaces.Create(name, settings)
+		if errors.Is(err, storage.ErrNamespaceExists) {
+			ns, err = m.namespaces.Get(name)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	// The old owner accepted the records, and a migration that quotas or
+	// the memory limit could stop would never finish.
+	return ns.Load(records...)
+}
+
+// run hands over leaving keys until a pass over the keyspace finds none.
+// Requests for leaving keys the node no longer holds are redirected to the
+// new owner, so no new ones appear once a pass comes up empty.
+func (m *Migrator) run(ctx context.Context, from, to *Map, done chan struct{}) {
+	defer close(done)
+
+	for {
+		found, err := m.pass(ctx, from, to)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil && found == 0 {
+			m.mu.Lock()
+			m.status.State = MigrationDone
+			m.status.KeysRemaining = 0
+			m.mu.Unlock()
+			slog.Info("Shard migration handed over every key", "to_version", to.Version)
+			return
+		}
+		if err != nil {
+			slog.Warn("Shard migration failed to hand over keys, retrying", "error", err, "retry_in", m.retryInterval)
+			m.mu.Lock()
+			m.status.LastError = err.Error()
+			m.mu.Unlock()
+
+			select {
+			case <-time.After(m.retryInterval):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// pass hands over every leaving key found in one scan of the namespaces and
+// returns how many it found.
+func (m *Migrator) pass(ctx context.Context, from, to *Map) (int, error) {
+	type batch struct {
+		ns   *storage.Namespace
+		to   Node
+		keys []string
+	}
+
+	var (
+		batches []batch
+		found   int
+	)
+	for _, ns := range m.namespaces.List() {
+		byOwner := make(map[string][]string)
+		for _, r := range ns.Records("") {
+			if from.Owner(r.Key).ID == m.router.self {
+				if owner := to.Owner(r.Key); owner.ID != m.router.self {
+					byOwner[owner.ID] = append(byOwner[owner.ID], r.Key)
+					found++
+				}
+			}
+		}
+		for id, keys := range byOwner {
+			owner, _ := to.Node(id)
+			for len(keys) > 0 {
+				n := min(len(keys), batchSize)
+				batches = append(batches, batch{ns: ns, to: owner, keys: keys[:n]})
+				keys = keys[n:]
+			}
+		}
+	}
+
+	m.mu.Lock()
+	m.status.KeysRemaining = uint64(found)
+	m.mu.Unlock()
+
+	for _, b := range batches {
+		if err := m.send(ctx, b.ns, b.to, to.Version, b.keys); err != nil {
+			return found, fmt.Errorf("failed to hand keys to node %s: %w", b.to.ID, err)
+		}
+	}
+	return found, nil
+}
+
+// send hands keys over to their new owner and deletes them here. The
+// records are copied under the router's lock, which is released while they
+// are sent: only requests for these keys wait meanwhile, so none changes
+// under the copy and none is written here after its new owner has it.
+func (m *Migrator) send(ctx context.Context, ns *storage.Namespace, to Node, version uint64, keys []string) error {
+	records, sent := m.copy(ns, keys)
+	if len(records) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, importTimeout)
+	err := m.importer.Import(ctx, to, version, ns, records)
+	cancel()
+	if err != nil {
+		m.release(ns, records, sent, false)
+		return err
+	}
+	// A key left here is sent again on the next pass.
+	if err := m.release(ns, records, sent, true); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.status.KeysSent += uint64(len(records))
+	m.status.KeysRemaining -= min(m.status.KeysRemaining, uint64(len(records)))
+	m.status.LastError = ""
+	m.mu.Unlock()
+	return nil
+}
+
+// copy reads the records of keys from ns and marks them as being sent.
+// Requests for them wait until sent is closed.
+func (m *Migrator) copy(ns *storage.Namespace, keys []string) (records []storage.Record, sent chan struct{}) {
+	m.router.moving.Lock()
+	defer m.router.moving.Unlock()
+
+	if m.router.sending == nil {
+		m.router.sending = make(map[string]chan struct{})
+	}
+	sent = make(chan struct{})
+	records = make([]storage.Record, 0, len(keys))
+	for _, key := range keys {
+		// The key may have changed or gone since the scan.
+		if r, ok := ns.Record(key); ok {
+			records = append(records, r)
+			m.router.sending[key] = sent
+		}
+	}
+	return records, sent
+}
+
+// release lets requests for records go on, deleting them from ns first if
+// their new owner has them.
+func (m *Migrator) release(ns *storage.Namespace, records []storage.Record, sent chan struct{}, handedOver bool) error {
+	m.router.moving.Lock()
+	defer m.router.moving.Unlock()
+	defer close(sent)
+
+	var errs []error
+	for _, r := range records {
+		delete(m.router.sending, r.Key)
+		if !handedOver {
+			continue
+		}
+		if _, err := ns.DeleteContext(context.Background(), r.Key); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete handed over key %q: %w", r.Key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,386 @@
+package shard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"kvstore/internal/storage"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testNode is a node of an in-process sharded deployment.
+type testNode struct {
+	router     *Router
+	namespaces *storage.Namespaces
+	migrator   *Migrator
+}
+
+// localImporter hands records straight to the migrator of their new owner,
+// failing the first failures calls.
+type localImporter struct {
+	mu       sync.Mutex
+	nodes    map[string]*testNode
+	failures int
+}
+
+func (l *localImporter) Import(ctx context.Context, to Node, version uint64, ns *storage.Namespace, records []storage.Record) error {
+	l.mu.Lock()
+	if l.failures > 0 {
+		l.failures--
+		l.mu.Unlock()
+		return errors.New("unreachable")
+	}
+	target := l.nodes[to.ID]
+	l.mu.Unlock()
+
+	return target.migrator.Import(ctx, version, ns.Name(), ns.Settings(), records)
+}
+
+func deployment(t *testing.T, m *Map, ids ...string) (map[string]*testNode, *localImporter) {
+	t.Helper()
+
+	importer := &localImporter{nodes: make(map[string]*testNode)}
+	for _, id := range ids {
+		n := &testNode{router: NewRouter(id, m), namespaces: storage.NewNamespaces()}
+		n.migrator = NewMigrator(n.router, n.namespaces, importer, WithRetryInterval(10*time.Millisecond))
+		t.Cleanup(func() { n.migrator.Close() })
+		importer.nodes[id] = n
+	}
+	return importer.nodes, importer
+}
+
+// get serves a read of key like the server would, following redirects.
+func get(t *testing.T, nodes map[string]*testNode, start, key string) (string, bool) {
+	t.Helper()
+
+	id, asking := start, false
+	for range 3 {
+		n := nodes[id]
+		ns := n.namespaces.Default()
+		release, err := n.router.Acquire(key, asking, func() bool {
+			_, ok := ns.Record(key)
+			return ok
+		})
+		if err == nil {
+			defer release()
+			return ns.Get(key)
+		}
+
+		var (
+			wrong *WrongShardError
+			ask   *AskError
+		)
+		switch {
+		case errors.As(err, &wrong):
+			id, asking = wrong.Owner.ID, false
+		case errors.As(err, &ask):
+			id, asking = ask.Owner.ID, true
+		default:
+			t.Fatal(err)
+		}
+	}
+	t.Fatalf("too many redirects for %s", key)
+	return "", false
+}
+
+// Test adding a node moves its keys to it, with their TTL and owner, while
+// every key stays readable
+func TestMigrator_AddNode(t *testing.T) {
+	from, err := NewMap(1, nodes("a", "b"), 16)
+	require.NoError(t, err)
+	to, err := NewMap(2, nodes("a", "b", "c"), 16)
+	require.NoError(t, err)
+
+	deploy, importer := deployment(t, from, "a", "b", "c")
+	importer.failures = 2
+
+	expiresAt := time.Now().Add(time.Hour).Unix()
+	users, err := deploy["a"].namespaces.Create("users", storage.NamespaceSettings{DefaultTTLSeconds: 60})
+	require.NoError(t, err)
+	for i := range 1000 {
+		key := fmt.Sprintf("key-%d", i)
+		ns := deploy[from.Owner(key).ID].namespaces.Default()
+		require.NoError(t, ns.PutRecordContext(context.Background(), storage.Record{Key: key, Value: key, Owner: "ci", ExpiresAt: expiresAt}))
+		if from.Owner(key).ID == "a" {
+			require.NoError(t, users.Set(key, key, nil))
+		}
+	}
+
+	for _, n := range deploy {
+		require.NoError(t, n.migrator.Begin(from, to))
+		require.NoError(t, n.migrator.Begin(from, to), "beginning again resumes")
+	}
+	for i := range 1000 {
+		key := fmt.Sprintf("key-%d", i)
+		value, found := get(t, deploy, from.Owner(key).ID, key)
+		require.True(t, found, "%s is readable while migrating", key)
+		assert.Equal(t, key, value)
+	}
+
+	for _, n := range deploy {
+		require.Eventually(t, func() bool { return n.migrator.Status().State == MigrationDone }, 5*time.Second, 10*time.Millisecond)
+	}
+	assert.NotZero(t, deploy["c"].migrator.Status().KeysReceived)
+	assert.Zero(t, deploy["c"].migrator.Status().KeysSent)
+
+	for _, n := range deploy {
+		require.NoError(t, n.migrator.Commit(2))
+		status := n.migrator.Status()
+		assert.Equal(t, MigrationIdle, status.State)
+		assert.EqualValues(t, 2, status.Version)
+		assert.Zero(t, status.TargetVersion)
+	}
+
+	for i := range 1000 {
+		key := fmt.Sprintf("key-%d", i)
+		owner := deploy[to.Owner(key).ID]
+		r, ok := owner.namespaces.Default().Record(key)
+		require.True(t, ok, "%s moved to its new owner", key)
+		assert.Equal(t, storage.Record{Key: key, Value: key, Owner: "ci", ExpiresAt: expiresAt}, r)
+
+		for id, n := range deploy {
+			if id != to.Owner(key).ID {
+				_, ok := n.namespaces.Default().Record(key)
+				assert.False(t, ok, "%s left node %s", key, id)
+			}
+		}
+	}
+
+	moved, err := deploy["c"].namespaces.Get("users")
+	require.NoError(t, err, "namespaces are created on their new node")
+	assert.EqualValues(t, 60, moved.Settings().DefaultTTLSeconds)
+	assert.NotZero(t, moved.Usage().Keys)
+}
+
+// Test requests for leaving keys are served until the key is handed over,
+// and redirected to the new owner after
+func TestRouter_Migrating(t *testing.T) {
+	from, err := NewMap(1, nodes("a"), 16)
+	require.NoError(t, err)
+	to, err := NewMap(2, nodes("a", "b"), 16)
+	require.NoError(t, err)
+
+	a, b := NewRouter("a", from), NewRouter("b", from)
+	for _, r := range []*Router{a, b} {
+		started, err := r.begin(from, to)
+		require.NoError(t, err)
+		assert.True(t, started)
+	}
+
+	var key string
+	for i := 0; key == ""; i++ {
+		if k := fmt.Sprintf("key-%d", i); to.Owner(k).ID == "b" {
+			key = k
+		}
+	}
+
+	release, err := a.Acquire(key, false, func() bool { return true })
+	require.NoError(t, err, "a still holds the key")
+	release()
+
+	_, err = a.Acquire(key, false, func() bool { return false })
+	var ask *AskError
+	require.ErrorAs(t, err, &ask)
+	assert.Equal(t, "b", ask.Owner.ID)
+	assert.EqualValues(t, 2, ask.Version)
+
+	_, err = b.Acquire(key, false, nil)
+	var wrong *WrongShardError
+	require.ErrorAs(t, err, &wrong, "b only serves the key when asked")
+	assert.Equal(t, "a", wrong.Owner.ID)
+	release, err = b.Acquire(key, true, nil)
+	require.NoError(t, err)
+	release()
+
+	_, err = a.begin(from, &Map{Version: 3})
+	assert.ErrorIs(t, err, ErrMigrating)
+	assert.ErrorIs(t, a.commit(3), ErrInvalidMigration)
+	require.NoError(t, a.commit(2))
+	require.NoError(t, a.commit(2), "committing again does nothing")
+	_, err = a.Acquire(key, false, nil)
+	require.ErrorAs(t, err, &wrong)
+	assert.Equal(t, "b", wrong.Owner.ID)
+}
+
+// blockingImporter reports the first batch on started and holds it until
+// unblock is closed.
+type blockingImporter struct {
+	started chan []storage.Record
+	unblock chan struct{}
+}
+
+func (b *blockingImporter) Import(ctx context.Context, to Node, version uint64, ns *storage.Namespace, records []storage.Record) error {
+	select {
+	case b.started <- records:
+	case <-b.unblock:
+		return nil
+	}
+	select {
+	case <-b.unblock:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Test only requests for the keys of the batch in flight wait for it, and
+// are redirected to the new owner once it went through
+func TestMigrator_SendHoldsOnlyItsBatch(t *testing.T) {
+	from, err := NewMap(1, nodes("a"), 16)
+	require.NoError(t, err)
+	to, err := NewMap(2, nodes("a", "b"), 16)
+	require.NoError(t, err)
+
+	var leaving []string
+	for i := 0; len(leaving) < 2; i++ {
+		if k := fmt.Sprintf("key-%d", i); to.Owner(k).ID == "b" {
+			leaving = append(leaving, k)
+		}
+	}
+
+	importer := &blockingImporter{started: make(chan []storage.Record), unblock: make(chan struct{})}
+	router, namespaces := NewRouter("a", from), storage.NewNamespaces()
+	migrator := NewMigrator(router, namespaces, importer)
+	t.Cleanup(func() { migrator.Close() })
+
+	ns := namespaces.Default()
+	require.NoError(t, ns.Set(leaving[0], "v", nil))
+	require.NoError(t, migrator.Begin(from, to))
+	records := <-importer.started
+	require.Len(t, records, 1)
+	assert.Equal(t, leaving[0], records[0].Key)
+
+	held := func(key string) func() bool {
+		return func() bool {
+			_, ok := ns.Record(key)
+			return ok
+		}
+	}
+	require.NoError(t, ns.Set(leaving[1], "v", nil))
+	release, err := router.Acquire(leaving[1], false, held(leaving[1]))
+	require.NoError(t, err, "a key outside the batch is served while it is in flight")
+	release()
+
+	acquired := make(chan error, 1)
+	go func() {
+		_, err := router.Acquire(leaving[0], false, held(leaving[0]))
+		acquired <- err
+	}()
+	select {
+	case err := <-acquired:
+		t.Fatalf("a key in flight was served: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(importer.unblock)
+	var ask *AskError
+	require.ErrorAs(t, <-acquired, &ask, "the key was handed over")
+	_, ok := ns.Record(leaving[0])
+	assert.False(t, ok)
+}
+
+// Test imported records are stored even beyond the quotas of their new
+// namespace, since their old owner accepted them
+func TestMigrator_ImportIgnoresQuota(t *testing.T) {
+	from, err := NewMap(1, nodes("a"), 16)
+	require.NoError(t, err)
+	to, err := NewMap(2, nodes("b"), 16)
+	require.NoError(t, err)
+
+	deploy, _ := deployment(t, from, "b")
+	require.NoError(t, deploy["b"].migrator.Begin(from, to))
+
+	settings := storage.NamespaceSettings{Quota: storage.Quota{MaxKeys: 1}}
+	records := []storage.Record{{Key: "k1", Value: "v"}, {Key: "k2", Value: "v"}}
+	require.NoError(t, deploy["b"].migrator.Import(context.Background(), 2, "small", settings, records))
+
+	ns, err := deploy["b"].namespaces.Get("small")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, ns.Usage().Keys)
+}
+
+// Test a committed map is saved, and loaded again as it was
+func TestMigrator_MapFile(t *testing.T) {
+	from, err := NewMap(1, nodes("a"), 16)
+	require.NoError(t, err)
+	to, err := NewMap(2, nodes("a", "b"), 16)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "shard-map.json")
+	router := NewRouter("a", from)
+	migrator := NewMigrator(router, storage.NewNamespaces(), &localImporter{}, WithMapFile(path))
+	t.Cleanup(func() { migrator.Close() })
+
+	require.NoError(t, migrator.Begin(from, to))
+	require.Eventually(t, func() bool { return migrator.Status().State == MigrationDone }, 5*time.Second, 10*time.Millisecond)
+	_, err = LoadMap(path)
+	require.ErrorIs(t, err, os.ErrNotExist, "nothing is saved before the commit")
+
+	require.NoError(t, migrator.Commit(2))
+	saved, err := LoadMap(path)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, saved.Version)
+	assert.Equal(t, to.VirtualNodes, saved.VirtualNodes)
+	assert.Equal(t, to.Nodes, saved.Nodes)
+	assert.Equal(t, to.Tokens(), saved.Tokens())
+}
+
+// mapFetcher returns the maps of nodes by address, counting the fetches.
+type mapFetcher struct {
+	maps    map[string]*Map
+	fetches int
+}
+
+func (f *mapFetcher) FetchMap(ctx context.Context, addr string) (*Map, error) {
+	f.fetches++
+	if m, ok := f.maps[addr]; ok {
+		return m, nil
+	}
+	return nil, errors.New("unreachable")
+}
+
+// Test a node that missed a commit adopts the map the others route by, or
+// commits the migration it finished, but never leaves one unfinished
+func TestMigrator_CatchUp(t *testing.T) {
+	ctx := context.Background()
+	var maps []*Map
+	for version, ids := range [][]string{{"a"}, {"a", "b"}, {"a", "b", "c"}, {"a", "b", "c", "d"}} {
+		m, err := NewMap(uint64(version+1), nodes(ids...), 16)
+		require.NoError(t, err)
+		maps = append(maps, m)
+	}
+
+	path := filepath.Join(t.TempDir(), "shard-map.json")
+	router := NewRouter("a", maps[0])
+	migrator := NewMigrator(router, storage.NewNamespaces(), &localImporter{}, WithMapFile(path))
+	t.Cleanup(func() { migrator.Close() })
+	fetcher := &mapFetcher{maps: map[string]*Map{"b": maps[1]}}
+
+	require.NoError(t, migrator.CatchUp(ctx, fetcher, "b", 1))
+	assert.Zero(t, fetcher.fetches, "nodes at the same version are not asked")
+	assert.Error(t, migrator.CatchUp(ctx, fetcher, "gone", 2))
+
+	require.NoError(t, migrator.CatchUp(ctx, fetcher, "b", 2))
+	assert.EqualValues(t, 2, router.Map().Version)
+	saved, err := LoadMap(path)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, saved.Version)
+
+	// Migrating, only the map migrated to is caught up with
+	require.NoError(t, migrator.Begin(maps[1], maps[2]))
+	require.Eventually(t, func() bool { return migrator.Status().State == MigrationDone }, 5*time.Second, 10*time.Millisecond)
+	fetcher.maps["c"] = maps[3]
+	assert.ErrorIs(t, migrator.CatchUp(ctx, fetcher, "c", 4), ErrMigrating)
+	fetcher.maps["b"] = maps[2]
+	require.NoError(t, migrator.CatchUp(ctx, fetcher, "b", 3))
+	current, next := router.Maps()
+	assert.EqualValues(t, 3, current.Version)
+	assert.Nil(t, next)
+}
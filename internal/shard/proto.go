@@ -0,0 +1,27 @@
+package shard
+
+import pb "kvstore/pkg/pb/api/proto"
+
+// Proto returns m as sent by the GetShardMap RPC.
+func (m *Map) Proto() *pb.ShardMap {
+	out := &pb.ShardMap{
+		Version:      m.Version,
+		VirtualNodes: int32(m.VirtualNodes),
+	}
+	for _, n := range m.Nodes {
+		out.Nodes = append(out.Nodes, &pb.ShardNode{Id: n.ID, GrpcAddr: n.Addr})
+	}
+	for _, t := range m.Tokens() {
+		out.Tokens = append(out.Tokens, &pb.ShardToken{Token: t.Token, NodeId: t.NodeID})
+	}
+	return out
+}
+
+// MapFromProto rebuilds m from its nodes; the tokens follow from them.
+func MapFromProto(m *pb.ShardMap) (*Map, error) {
+	var nodes []Node
+	for _, n := range m.GetNodes() {
+		nodes = append(nodes, Node{ID: n.GetId(), Addr: n.GetGrpcAddr()})
+	}
+	return NewMap(m.GetVersion(), nodes, int(m.GetVirtualNodes()))
+}
@@ -0,0 +1,364 @@
+// Package shard partitions keys across servers with a consistent hash
+// ring. Every node is placed on the ring at several pseudo-random points,
+// its virtual nodes, and owns the keys that hash to the arc ending at each
+// of them. Adding or removing a node only moves the keys on its arcs, and
+// virtual nodes keep the arcs of every node roughly the same total length.
+//
+// A key's position is Hash(key): the 64-bit FNV-1a hash of its bytes,
+// finalized with the splitmix64 mixer. The point of a node's i-th virtual
+// node is the Hash of "<id>#<i>". Clients in any language can route keys
+// from the tokens of a Map without this package.
+package shard
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// DefaultVirtualNodes is how many points each node has on the ring by
+// default.
+const DefaultVirtualNodes = 128
+
+var (
+	// ErrWrongShard is matched by every *WrongShardError and *AskError.
+	ErrWrongShard = errors.New("shard: key belongs to another node")
+
+	ErrMigrating        = errors.New("shard: already migrating")
+	ErrNotMigrating     = errors.New("shard: not migrating")
+	ErrInvalidMigration = errors.New("shard: invalid migration")
+	// ErrMigrationPending is returned when committing a migration before the
+	// node has handed over all of its moving keys.
+	ErrMigrationPending = errors.New("shard: keys are still moving")
+)
+
+// WrongShardError is returned for requests for a key the node does not
+// own.
+type WrongShardError struct {
+	Key   string
+	Owner Node
+	// Version is the version of the shard map the owner was looked up in.
+	Version uint64
+}
+
+func (e *WrongShardError) Error() string {
+	return fmt.Sprintf("shard: key %q belongs to node %s at %s (shard map version %d)", e.Key, e.Owner.ID, e.Owner.Addr, e.Version)
+}
+
+func (e *WrongShardError) Is(target error) bool {
+	return target == ErrWrongShard
+}
+
+// AskError is returned for requests for a key that is moving to another
+// node and has already left this one. Unlike a *WrongShardError, it only
+// applies to this one request: the key's owner stays the same until the
+// migration is committed.
+type AskError struct {
+	Key   string
+	Owner Node
+	// Version is the version of the shard map being migrated to.
+	Version uint64
+}
+
+func (e *AskError) Error() string {
+	return fmt.Sprintf("shard: key %q is moving to node %s at %s (shard map version %d)", e.Key, e.Owner.ID, e.Owner.Addr, e.Version)
+}
+
+func (e *AskError) Is(target error) bool {
+	return target == ErrWrongShard
+}
+
+// Node is a server that owns part of the ring.
+type Node struct {
+	ID string `json:"id"`
+	// Addr is the gRPC address requests for the node's keys go to.
+	Addr string `json:"addr"`
+}
+
+// Token is a point on the ring. The node owns the keys hashing after the
+// previous token up to and including this one; the first token also owns
+// the keys hashing after the last.
+type Token struct {
+	Token  uint64
+	NodeID string
+}
+
+// Map is an immutable, versioned assignment of keys to nodes. Every node
+// and client building a Map from the same nodes and virtual node count
+// routes keys the same way.
+type Map struct {
+	Version      uint64
+	VirtualNodes int
+	// Nodes are sorted by ID.
+	Nodes []Node
+
+	tokens []Token
+	byID   map[string]Node
+}
+
+// NewMap places nodes on the ring with virtualNodes points each, or
+// DefaultVirtualNodes if it is zero.
+func NewMap(version uint64, nodes []Node, virtualNodes int) (*Map, error) {
+	if len(nodes) == 0 {
+		return nil, errors.New("shard: at least one node is required")
+	}
+	if virtualNodes < 0 {
+		return nil, errors.New("shard: virtual nodes must not be negative")
+	}
+	if virtualNodes == 0 {
+		virtualNodes = DefaultVirtualNodes
+	}
+
+	m := &Map{
+		Version:      version,
+		VirtualNodes: virtualNodes,
+		Nodes:        append([]Node(nil), nodes...),
+		tokens:       make([]Token, 0, len(nodes)*virtualNodes),
+		byID:         make(map[string]Node, len(nodes)),
+	}
+	sort.Slice(m.Nodes, func(i, j int) bool { return m.Nodes[i].ID < m.Nodes[j].ID })
+
+	for _, n := range m.Nodes {
+		if n.ID == "" || n.Addr == "" {
+			return nil, errors.New("shard: every node needs an ID and an address")
+		}
+		if _, ok := m.byID[n.ID]; ok {
+			return nil, fmt.Errorf("shard: node %s is listed twice", n.ID)
+		}
+		m.byID[n.ID] = n
+
+		for i := range virtualNodes {
+			m.tokens = append(m.tokens, Token{Token: Hash(n.ID + "#" + strconv.Itoa(i)), NodeID: n.ID})
+		}
+	}
+	// Ties, however unlikely, go to the lowest ID on every node alike.
+	sort.Slice(m.tokens, func(i, j int) bool {
+		if m.tokens[i].Token != m.tokens[j].Token {
+			return m.tokens[i].Token < m.tokens[j].Token
+		}
+		return m.tokens[i].NodeID < m.tokens[j].NodeID
+	})
+
+	return m, nil
+}
+
+// Owner returns the node that owns key.
+func (m *Map) Owner(key string) Node {
+	h := Hash(key)
+	i := sort.Search(len(m.tokens), func(i int) bool { return m.tokens[i].Token >= h })
+	if i == len(m.tokens) {
+		i = 0
+	}
+	return m.byID[m.tokens[i].NodeID]
+}
+
+// Node returns the node with id.
+func (m *Map) Node(id string) (Node, bool) {
+	n, ok := m.byID[id]
+	return n, ok
+}
+
+// Tokens returns the points of the ring in increasing order.
+func (m *Map) Tokens() []Token {
+	return append([]Token(nil), m.tokens...)
+}
+
+// Hash is the position of key on the ring.
+func Hash(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return mix(h.Sum64())
+}
+
+// mix is the splitmix64 finalizer. FNV-1a alone spreads short keys that
+// differ in their last bytes, such as virtual node names, poorly.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// Router holds a node's shard map and decides which requests it serves.
+// During a migration it routes by two maps at once: keys that move to
+// another node are served by this node for as long as it still holds them,
+// and keys that move to this node only on request of their old owner.
+type Router struct {
+	self string
+
+	// mu is held for reading while a request is served and for writing
+	// while the maps change, so that no request straddles two maps.
+	mu      sync.RWMutex
+	current *Map
+	// next is the map being migrated to, or nil.
+	next *Map
+
+	// moving is held for reading while a leaving key is served and for
+	// writing while a batch of keys is copied or handed over, so that a key
+	// is never written here once its new owner has it.
+	moving sync.RWMutex
+	// sending holds the leaving keys of the batch in flight, mapped to a
+	// channel closed once it has been handed over. Requests for them wait
+	// meanwhile, so that none changes under the copy being sent.
+	sending map[string]chan struct{}
+}
+
+// NewRouter routes for node self by m. A node missing from m owns no keys
+// until a migration assigns it some.
+func NewRouter(self string, m *Map) *Router {
+	return &Router{self: self, current: m}
+}
+
+// Self is the ID of the node the router serves.
+func (r *Router) Self() string {
+	return r.self
+}
+
+// Map returns the map the node routes by. While migrating, it is the map
+// being migrated from.
+func (r *Router) Map() *Map {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current
+}
+
+// Maps returns the map the node routes by and, while migrating, the map
+// being migrated to.
+func (r *Router) Maps() (current, next *Map) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current, r.next
+}
+
+// Acquire decides whether the node serves a request for key. If it does,
+// the request must call release once it is done with the key; otherwise
+// the error is a *WrongShardError or an *AskError naming the node to ask
+// instead.
+//
+// asking is set on requests redirected by an *AskError, which are served
+// for keys moving to this node. held reports whether the node still holds
+// key; it is only called for keys moving away.
+func (r *Router) Acquire(key string, asking bool, held func() bool) (release func(), err error) {
+	for {
+		release, sent, err := r.acquire(key, asking, held)
+		if sent == nil {
+			return release, err
+		}
+		<-sent
+	}
+}
+
+// acquire is Acquire, except that it returns a channel to wait on instead
+// if key is being handed over.
+func (r *Router) acquire(key string, asking bool, held func() bool) (release func(), sent <-chan struct{}, err error) {
+	r.mu.RLock()
+
+	if r.next == nil {
+		if owner := r.current.Owner(key); owner.ID != r.self {
+			r.mu.RUnlock()
+			return nil, nil, &WrongShardError{Key: key, Owner: owner, Version: r.current.Version}
+		}
+		return r.mu.RUnlock, nil, nil
+	}
+
+	from, to := r.current.Owner(key), r.next.Owner(key)
+	switch {
+	case from.ID == r.self && to.ID == r.self:
+		return r.mu.RUnlock, nil, nil
+
+	case from.ID == r.self:
+		r.moving.RLock()
+		if sent := r.sending[key]; sent != nil {
+			r.moving.RUnlock()
+			r.mu.RUnlock()
+			return nil, sent, nil
+		}
+		if held() {
+			return func() {
+				r.moving.RUnlock()
+				r.mu.RUnlock()
+			}, nil, nil
+		}
+		r.moving.RUnlock()
+		err = &AskError{Key: key, Owner: to, Version: r.next.Version}
+
+	case to.ID == r.self && asking:
+		return r.mu.RUnlock, nil, nil
+
+	default:
+		err = &WrongShardError{Key: key, Owner: from, Version: r.current.Version}
+	}
+
+	r.mu.RUnlock()
+	return nil, nil, err
+}
+
+// begin starts routing by both maps. It reports false if the node already
+// migrates to, or has committed, to.
+func (r *Router) begin(from, to *Map) (bool, error) {
+	// Resuming is common, and a running migration may hold requests up, so
+	// settle what needs no change without waiting for them.
+	switch current, next := r.Maps(); {
+	case next != nil && next.Version == to.Version, next == nil && current.Version == to.Version:
+		return false, nil
+	case next != nil:
+		return false, fmt.Errorf("%w to version %d", ErrMigrating, next.Version)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch {
+	case to.Version <= from.Version:
+		return false, fmt.Errorf("%w: version %d does not follow %d", ErrInvalidMigration, to.Version, from.Version)
+	case r.next != nil && r.next.Version == to.Version, r.next == nil && r.current.Version == to.Version:
+		return false, nil
+	case r.next != nil:
+		return false, fmt.Errorf("%w to version %d", ErrMigrating, r.next.Version)
+	case r.current.Version > from.Version:
+		return false, fmt.Errorf("%w: the node routes by version %d, not %d", ErrInvalidMigration, r.current.Version, from.Version)
+	}
+
+	r.current, r.next = from, to
+	return true, nil
+}
+
+// adopt switches to m, a later map than the current one, without
+// migrating to it.
+func (r *Router) adopt(m *Map) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch {
+	case r.next != nil:
+		return fmt.Errorf("%w to version %d", ErrMigrating, r.next.Version)
+	case m.Version <= r.current.Version:
+		return nil
+	}
+
+	r.current = m
+	return nil
+}
+
+// commit switches to the map migrated to, which must have version.
+func (r *Router) commit(version uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch {
+	case r.next == nil && r.current.Version == version:
+		return nil
+	case r.next == nil:
+		return fmt.Errorf("%w to version %d", ErrNotMigrating, version)
+	case r.next.Version != version:
+		return fmt.Errorf("%w: migrating to version %d, not %d", ErrInvalidMigration, r.next.Version, version)
+	}
+
+	r.current, r.next = r.next, nil
+	return nil
+}
@@ -0,0 +1,95 @@
+package shard
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func nodes(ids ...string) []Node {
+	var ns []Node
+	for _, id := range ids {
+		ns = append(ns, Node{ID: id, Addr: id + ":9090"})
+	}
+	return ns
+}
+
+// Test keys spread evenly and route the same regardless of node order
+func TestMap_Owner(t *testing.T) {
+	m, err := NewMap(1, nodes("a", "b", "c"), 0)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultVirtualNodes, m.VirtualNodes)
+	assert.Len(t, m.Tokens(), 3*DefaultVirtualNodes)
+
+	reversed, err := NewMap(1, nodes("c", "b", "a"), 0)
+	require.NoError(t, err)
+
+	counts := map[string]int{}
+	for i := range 30000 {
+		key := fmt.Sprintf("key-%d", i)
+		owner := m.Owner(key)
+		counts[owner.ID]++
+		require.Equal(t, owner, reversed.Owner(key))
+	}
+	for id, n := range counts {
+		assert.InDelta(t, 10000, n, 2000, "node %s owns %d keys", id, n)
+	}
+}
+
+// Test adding a node only moves keys to it
+func TestMap_AddNode(t *testing.T) {
+	before, err := NewMap(1, nodes("a", "b", "c"), 0)
+	require.NoError(t, err)
+	after, err := NewMap(2, nodes("a", "b", "c", "d"), 0)
+	require.NoError(t, err)
+
+	moved := 0
+	for i := range 10000 {
+		key := fmt.Sprintf("key-%d", i)
+		if from, to := before.Owner(key), after.Owner(key); from != to {
+			assert.Equal(t, "d", to.ID)
+			moved++
+		}
+	}
+	assert.InDelta(t, 2500, moved, 700)
+}
+
+func TestNewMap_Invalid(t *testing.T) {
+	_, err := NewMap(1, nil, 0)
+	assert.Error(t, err)
+	_, err = NewMap(1, nodes("a", "a"), 0)
+	assert.ErrorContains(t, err, "listed twice")
+	_, err = NewMap(1, []Node{{ID: "a"}}, 0)
+	assert.Error(t, err)
+	_, err = NewMap(1, nodes("a"), -1)
+	assert.Error(t, err)
+}
+
+// Test the router rejects keys owned by other nodes, naming the owner
+func TestRouter_Acquire(t *testing.T) {
+	m, err := NewMap(3, nodes("a", "b"), 16)
+	require.NoError(t, err)
+	r := NewRouter("a", m)
+	held := func() bool { return true }
+
+	for i := range 100 {
+		key := fmt.Sprintf("key-%d", i)
+		release, err := r.Acquire(key, false, held)
+		if m.Owner(key).ID == "a" {
+			require.NoError(t, err)
+			release()
+			continue
+		}
+
+		var wrong *WrongShardError
+		require.ErrorAs(t, err, &wrong)
+		assert.ErrorIs(t, err, ErrWrongShard)
+		assert.Equal(t, Node{ID: "b", Addr: "b:9090"}, wrong.Owner)
+		assert.EqualValues(t, 3, wrong.Version)
+	}
+
+	_, err = NewRouter("z", m).Acquire("key", false, held)
+	assert.ErrorIs(t, err, ErrWrongShard, "a node missing from the map owns nothing")
+}
@@ -0,0 +1,319 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+)
+
+// ErrOutOfMemory is returned by Set when the store is at its memory limit
+// and the eviction policy cannot make room for the new value.
+var ErrOutOfMemory = errors.New("storage: out of memory")
+
+type EvictionPolicy string
+
+const (
+	// NoEviction rejects writes once the memory limit is reached.
+	NoEviction EvictionPolicy = "noeviction"
+	// AllKeysLRU evicts the least recently used keys.
+	AllKeysLRU EvictionPolicy = "allkeys-lru"
+	// AllKeysRandom evicts arbitrary keys.
+	AllKeysRandom EvictionPolicy = "allkeys-random"
+	// VolatileTTL evicts the keys closest to expiring, and only keys with a TTL.
+	VolatileTTL EvictionPolicy = "volatile-ttl"
+)
+
+// evictionSamples is the number of keys inspected to pick each eviction
+// victim, trading accuracy for constant-time eviction.
+const evictionSamples = 5
+
+func ParseEvictionPolicy(s string) (EvictionPolicy, error) {
+	switch p := EvictionPolicy(s); p {
+	case NoEviction, AllKeysLRU, AllKeysRandom, VolatileTTL:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown eviction policy %q (want %s, %s, %s or %s)",
+		s, NoEviction, AllKeysLRU, AllKeysRandom, VolatileTTL)
+}
+
+// MemoryLimit caps the approximate number of bytes used by keys and values
+// across every store sharing it, and picks how they make room. Stores
+// evict their own keys first and, when those do not free enough, the keys
+// of the other stores sharing the limit.
+type MemoryLimit struct {
+	mu     sync.Mutex
+	max    int64
+	policy EvictionPolicy
+	used   int64
+	stores map[*MemoryStore]bool
+}
+
+// NewMemoryLimit returns a limit of max bytes. Zero means unlimited.
+func NewMemoryLimit(max int64, policy EvictionPolicy) *MemoryLimit {
+	return &MemoryLimit{max: max, policy: policy, stores: make(map[*MemoryStore]bool)}
+}
+
+// Used returns the bytes held by every store sharing the limit.
+func (l *MemoryLimit) Used() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.used
+}
+
+// SetMax changes the limit at runtime. Lowering it evicts keys immediately
+// when the eviction policy allows; otherwise further writes fail until
+// enough keys are deleted.
+func (l *MemoryLimit) SetMax(bytes int64) {
+	l.mu.Lock()
+	l.max = bytes
+	l.mu.Unlock()
+
+	l.reclaim(0)
+}
+
+func (l *MemoryLimit) SetPolicy(policy EvictionPolicy) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.policy = policy
+}
+
+// tryAdd accounts for bytes more if they fit under the limit.
+func (l *MemoryLimit) tryAdd(bytes int64) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.max > 0 && bytes > 0 && l.used+bytes > l.max {
+		return false
+	}
+	l.used += bytes
+	return true
+}
+
+// fits reports whether bytes more fit under the limit.
+func (l *MemoryLimit) fits(bytes int64) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.max <= 0 || bytes <= 0 || l.used+bytes <= l.max
+}
+
+// add accounts for bytes more whether or not they fit. Negative bytes
+// release memory.
+func (l *MemoryLimit) add(bytes int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.used += bytes
+}
+
+// Reserve accounts for bytes held beside the stores, such as replication
+// state, evicting keys to make room when the eviction policy allows. It
+// fails with ErrOutOfMemory if they still do not fit. The caller must not
+// hold the lock of any store.
+func (l *MemoryLimit) Reserve(bytes int64) error {
+	l.reclaim(bytes)
+	if !l.tryAdd(bytes) {
+		return ErrOutOfMemory
+	}
+	return nil
+}
+
+// Charge accounts for bytes held beside the stores whether or not they
+// fit, as for state merged from elsewhere. Negative bytes release memory
+// reserved or charged before.
+func (l *MemoryLimit) Charge(bytes int64) {
+	l.add(bytes)
+}
+
+func (l *MemoryLimit) settings() (max int64, policy EvictionPolicy) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.max, l.policy
+}
+
+func (l *MemoryLimit) register(m *MemoryStore) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.stores[m] = true
+}
+
+func (l *MemoryLimit) unregister(m *MemoryStore) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.stores, m)
+}
+
+// reclaim evicts keys from any store sharing the limit until need more
+// bytes fit, or nothing is left to evict. The caller must not hold the lock
+// of any store.
+func (l *MemoryLimit) reclaim(need int64) {
+	for {
+		l.mu.Lock()
+		fits := l.max <= 0 || l.used+need <= l.max || l.policy == NoEviction
+		stores := make([]*MemoryStore, 0, len(l.stores))
+		for m := range l.stores {
+			stores = append(stores, m)
+		}
+		l.mu.Unlock()
+
+		if fits {
+			return
+		}
+
+		// Take the best candidate of all stores, found from a sample of each.
+		var (
+			victim string
+			owner  *MemoryStore
+			best   int64
+		)
+		rand.Shuffle(len(stores), func(i, j int) { stores[i], stores[j] = stores[j], stores[i] })
+		for _, m := range stores {
+			m.mu.RLock()
+			key, score, ok := m.evictionCandidate("")
+			m.mu.RUnlock()
+			if ok && (owner == nil || score < best) {
+				victim, owner, best = key, m, score
+			}
+		}
+		if owner == nil {
+			return
+		}
+
+		owner.mu.Lock()
+		if _, ok := owner.data[victim]; ok && !owner.closed {
+			owner.evict(victim)
+		}
+		owner.mu.Unlock()
+	}
+}
+
+// WithMaxMemory limits the approximate number of bytes used by keys and
+// values. Zero means unlimited.
+func WithMaxMemory(bytes int64) MemoryOption {
+	return func(m *MemoryStore) {
+		m.memory.max = bytes
+	}
+}
+
+func WithEvictionPolicy(policy EvictionPolicy) MemoryOption {
+	return func(m *MemoryStore) {
+		m.memory.policy = policy
+	}
+}
+
+// WithMemoryLimit makes the store share limit with the other stores given
+// it, in place of a limit of its own. It overrides WithMaxMemory and
+// WithEvictionPolicy.
+func WithMemoryLimit(limit *MemoryLimit) MemoryOption {
+	return func(m *MemoryStore) {
+		m.memory = limit
+	}
+}
+
+// SetMaxMemory changes the memory limit of the store, and of every store
+// sharing it, see MemoryLimit.SetMax.
+func (m *MemoryStore) SetMaxMemory(bytes int64) {
+	m.memory.SetMax(bytes)
+}
+
+func (m *MemoryStore) SetEvictionPolicy(policy EvictionPolicy) {
+	m.memory.SetPolicy(policy)
+}
+
+// evict removes key to make room. Expired victims count as expired rather
+// than evicted. The caller must hold the write lock.
+func (m *MemoryStore) evict(key string) {
+	if m.isExpired(key) {
+		m.expire(key)
+		return
+	}
+
+	m.delete(key)
+	if m.hooks.Evicted != nil {
+		m.hooks.Evicted(m.name)
+	}
+}
+
+// reserve accounts for storing value under key, evicting other keys of the
+// store if the memory limit requires it. The caller must hold the write
+// lock, so keys of other stores are reclaimed before it is taken.
+func (m *MemoryStore) reserve(key, value string) error {
+	need := entrySize(key, value)
+	if old, ok := m.data[key]; ok {
+		need -= entrySize(key, old.value)
+	}
+
+	max, policy := m.memory.settings()
+	if max > 0 && entrySize(key, value) > max {
+		return fmt.Errorf("%w: entry of %d bytes exceeds max memory of %d bytes",
+			ErrOutOfMemory, entrySize(key, value), max)
+	}
+
+	for !m.memory.tryAdd(need) {
+		victim, _, ok := m.evictionCandidate(key)
+		if !ok {
+			return fmt.Errorf("%w: %d of %d bytes used, eviction policy %s",
+				ErrOutOfMemory, m.memory.Used(), max, policy)
+		}
+		m.evict(victim)
+	}
+
+	m.usedBytes += need
+	return nil
+}
+
+// evictionCandidate picks a key to evict under the current policy, never
+// choosing skip, and scores it: of two candidates the lower score goes
+// first. Expired keys are always preferred. The caller must hold the lock.
+func (m *MemoryStore) evictionCandidate(skip string) (string, int64, bool) {
+	_, policy := m.memory.settings()
+	if policy == NoEviction || m.closed {
+		return "", 0, false
+	}
+
+	var (
+		victim string
+		best   int64
+		seen   int
+	)
+
+	for k, e := range m.data {
+		if k == skip {
+			continue
+		}
+
+		if m.isExpired(k) {
+			return k, math.MinInt64, true
+		}
+
+		var score int64
+		switch policy {
+		case AllKeysLRU:
+			score = e.lastAccess.Load()
+		case VolatileTTL:
+			expiration, ok := m.ttl[k]
+			if !ok {
+				continue
+			}
+			score = expiration
+		}
+
+		if seen == 0 || score < best {
+			victim, best = k, score
+		}
+
+		seen++
+		if seen >= evictionSamples || policy == AllKeysRandom {
+			break
+		}
+	}
+
+	return victim, best, seen > 0
+}
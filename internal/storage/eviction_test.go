@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test noeviction rejects writes over the memory limit
+func TestMemoryStore_MaxMemoryNoEviction(t *testing.T) {
+	store := NewMemoryStore(WithMaxMemory(20))
+
+	require.NoError(t, store.Set("key1", "0123456789", nil))
+
+	err := store.Set("key2", "0123456789", nil)
+	assert.ErrorIs(t, err, ErrOutOfMemory)
+
+	// Overwriting with a value of the same size still fits
+	assert.NoError(t, store.Set("key1", "abcdefghij", nil))
+
+	// Deleting frees the space again
+	_, err = store.Delete("key1")
+	require.NoError(t, err)
+	assert.NoError(t, store.Set("key2", "0123456789", nil))
+}
+
+// Test allkeys-lru evicts the least recently used key
+func TestMemoryStore_EvictionLRU(t *testing.T) {
+	store := NewMemoryStore(WithMaxMemory(25), WithEvictionPolicy(AllKeysLRU))
+
+	require.NoError(t, store.Set("key1", "value1", nil))
+	require.NoError(t, store.Set("key2", "value2", nil))
+	time.Sleep(time.Millisecond)
+
+	// Touch key1 so key2 becomes the LRU entry
+	_, found := store.Get("key1")
+	require.True(t, found)
+
+	require.NoError(t, store.Set("key3", "value3", nil))
+
+	_, found = store.Get("key1")
+	assert.True(t, found)
+	_, found = store.Get("key2")
+	assert.False(t, found)
+	_, found = store.Get("key3")
+	assert.True(t, found)
+}
+
+// Test bytes reserved beside the stores evict keys to fit, and charged ones
+// count whether or not they fit
+func TestMemoryLimit_Reserve(t *testing.T) {
+	limit := NewMemoryLimit(20, AllKeysLRU)
+	store := NewMemoryStore(WithMemoryLimit(limit))
+	require.NoError(t, store.Set("key1", "value1", nil))
+
+	require.NoError(t, limit.Reserve(15))
+	_, found := store.Get("key1")
+	assert.False(t, found, "key1 was evicted to make room")
+	assert.EqualValues(t, 15, limit.Used())
+	assert.ErrorIs(t, limit.Reserve(10), ErrOutOfMemory)
+
+	limit.Charge(-15)
+	limit.Charge(30)
+	assert.EqualValues(t, 30, limit.Used())
+}
+
+// Test volatile-ttl only evicts keys with a TTL
+func TestMemoryStore_EvictionVolatileTTL(t *testing.T) {
+	store := NewMemoryStore(WithMaxMemory(25), WithEvictionPolicy(VolatileTTL))
+
+	require.NoError(t, store.Set("key1", "value1", nil))
+	require.NoError(t, store.Set("key2", "value2", int64Ptr(60)))
+	require.NoError(t, store.Set("key3", "value3", nil))
+
+	_, found := store.Get("key2")
+	assert.False(t, found)
+
+	// Nothing left with a TTL, so the next write fails
+	err := store.Set("key4", "value4", nil)
+	assert.ErrorIs(t, err, ErrOutOfMemory)
+}
+
+// Test entries larger than the limit are rejected outright
+func TestMemoryStore_EntryTooLarge(t *testing.T) {
+	store := NewMemoryStore(WithMaxMemory(8), WithEvictionPolicy(AllKeysRandom))
+
+	err := store.Set("key1", "much too large", nil)
+	assert.ErrorIs(t, err, ErrOutOfMemory)
+}
+
+// Test lowering the memory limit at runtime evicts down to the new limit
+func TestMemoryStore_SetMaxMemory(t *testing.T) {
+	store := NewMemoryStore()
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, store.Set(fmt.Sprintf("key%d", i), "value", nil))
+	}
+
+	// noeviction keeps the data and rejects new writes
+	store.SetMaxMemory(30)
+	result, err := store.List(0)
+	require.NoError(t, err)
+	assert.Len(t, result, 5)
+	assert.ErrorIs(t, store.Set("key5", "value", nil), ErrOutOfMemory)
+
+	// Switching policy makes room on the next limit change
+	store.SetEvictionPolicy(AllKeysRandom)
+	store.SetMaxMemory(20)
+	result, err = store.List(0)
+	require.NoError(t, err)
+	assert.Len(t, result, 2)
+}
+
+func TestParseEvictionPolicy(t *testing.T) {
+	policy, err := ParseEvictionPolicy("allkeys-lru")
+	assert.NoError(t, err)
+	assert.Equal(t, AllKeysLRU, policy)
+
+	_, err = ParseEvictionPolicy("lru")
+	assert.Error(t, err)
+}
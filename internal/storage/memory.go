@@ -1,56 +1,215 @@
 package storage
 
 import (
+	"context"
 	"fmt"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+type MemoryOption func(*MemoryStore)
+
+// WithQuota rejects writes that would take the store past quota. Unlike the
+// memory limit, a quota never evicts. name identifies the store in errors.
+func WithQuota(name string, quota Quota) MemoryOption {
+	return func(m *MemoryStore) {
+		m.name = name
+		m.quota = quota
+	}
+}
+
+// WithPrincipalQuotas charges writes made with SetAs to the writing
+// principal.
+func WithPrincipalQuotas(p *PrincipalQuotas) MemoryOption {
+	return func(m *MemoryStore) {
+		m.principals = p
+	}
+}
+
+// Hooks observe keys the store removes on its own. Nil hooks are skipped.
+// They run under the store lock and must not call back into the store.
+type Hooks struct {
+	// Expired is called with the store name for every expired key removed.
+	Expired func(name string)
+	// Evicted is called with the store name for every key evicted to stay
+	// under the memory limit.
+	Evicted func(name string)
+}
+
+func WithHooks(hooks Hooks) MemoryOption {
+	return func(m *MemoryStore) {
+		m.hooks = hooks
+	}
+}
+
+type entry struct {
+	value      string
+	lastAccess atomic.Int64
+	// owner is the principal charged for the entry, if any.
+	owner string
+}
+
 type MemoryStore struct {
-	data map[string]string
+	data map[string]*entry
 	ttl  map[string]int64
 	mu   sync.RWMutex
+
+	memory *MemoryLimit
+	// usedBytes is what this store holds of memory.
+	usedBytes int64
+
+	name       string
+	quota      Quota
+	principals *PrincipalQuotas
+
+	hooks Hooks
+
+	closed bool
 }
 
-func NewMemoryStore() *MemoryStore {
-	return &MemoryStore{
-		data: make(map[string]string),
-		ttl:  make(map[string]int64),
+func NewMemoryStore(opts ...MemoryOption) *MemoryStore {
+	m := &MemoryStore{
+		data:   make(map[string]*entry),
+		ttl:    make(map[string]int64),
+		memory: NewMemoryLimit(0, NoEviction),
+	}
+
+	for _, opt := range opts {
+		opt(m)
 	}
+	m.memory.register(m)
+
+	return m
 }
 
 func (m *MemoryStore) Get(key string) (string, bool) {
-	m.mu.RLock()
+	return m.GetContext(context.Background(), key)
+}
+
+// GetContext is Get recorded as a child span of ctx. The *Context methods
+// let callers see the time spent waiting for locks.
+func (m *MemoryStore) GetContext(ctx context.Context, key string) (string, bool) {
+	ctx, span := m.startSpan(ctx, "Get")
+	defer span.End()
+
+	m.rlock(ctx)
+
+	if m.closed {
+		m.mu.RUnlock()
+		return "", false
+	}
 
 	if m.isExpired(key) {
 		m.mu.RUnlock()
 
-		m.mu.Lock()
+		m.lock(ctx)
 		defer m.mu.Unlock()
 
-		if m.isExpired(key) {
-			m.delete(key)
+		if !m.closed && m.isExpired(key) {
+			m.expire(key)
 		}
 
 		return "", false
 	}
 
-	val, found := m.data[key]
+	e, found := m.data[key]
+	if !found {
+		m.mu.RUnlock()
+		return "", false
+	}
+
+	e.lastAccess.Store(time.Now().UnixNano())
+	val := e.value
 	m.mu.RUnlock()
 	return val, found
 }
 
 func (m *MemoryStore) Set(key, value string, ttlSeconds *int64) error {
+	return m.SetAs("", key, value, ttlSeconds)
+}
+
+// SetAs is Set on behalf of owner, who is charged for the entry against its
+// principal quota. The quotas are checked and charged under the store's
+// write lock, so concurrent writers cannot overshoot them.
+func (m *MemoryStore) SetAs(owner, key, value string, ttlSeconds *int64) error {
+	return m.SetAsContext(context.Background(), owner, key, value, ttlSeconds)
+}
+
+// SetAsContext is SetAs recorded as a child span of ctx.
+func (m *MemoryStore) SetAsContext(ctx context.Context, owner, key, value string, ttlSeconds *int64) error {
+	return m.PutRecordContext(ctx, Record{Key: key, Value: value, Owner: owner, ExpiresAt: ExpiresAt(ttlSeconds)})
+}
+
+// PutRecordContext stores r like SetAsContext, with an absolute expiry so
+// that replicas applying the same record expire it at the same moment.
+func (m *MemoryStore) PutRecordContext(ctx context.Context, r Record) (err error) {
+	ctx, span := m.startSpan(ctx, "Set")
+	defer func() { endSpan(span, err) }()
+
+	key, value, owner := r.Key, r.Value, r.Owner
 	if key == "" {
 		return fmt.Errorf("key cannot be empty")
 	}
 
-	m.mu.Lock()
+	// Keys of other stores sharing the memory limit can only be evicted
+	// before this store's lock is taken.
+	m.memory.reclaim(entrySize(key, value))
+
+	m.lock(ctx)
 	defer m.mu.Unlock()
-	m.data[key] = value
 
-	if ttlSeconds != nil && *ttlSeconds > 0 {
-		m.ttl[key] = time.Now().Unix() + *ttlSeconds
+	if m.closed {
+		return ErrClosed
+	}
+
+	// An expired key is about to be replaced; account for it as gone.
+	if m.isExpired(key) {
+		m.expire(key)
+	}
+
+	old, exists := m.data[key]
+	size := entrySize(key, value)
+	keys, bytes := int64(1), size
+	if exists {
+		keys, bytes = 0, size-entrySize(key, old.value)
+	}
+
+	if err := m.checkQuota(keys, bytes, int64(len(value))); err != nil {
+		return err
+	}
+
+	// The principal is charged for the whole entry when it takes over a key
+	// written by someone else.
+	chargeKeys, chargeBytes := keys, bytes
+	if exists && old.owner != owner {
+		chargeKeys, chargeBytes = 1, size
+	}
+	if m.principals != nil {
+		if err := m.principals.charge(owner, chargeKeys, chargeBytes, int64(len(value))); err != nil {
+			return err
+		}
+	}
+
+	if err := m.reserve(key, value); err != nil {
+		if m.principals != nil {
+			m.principals.refund(owner, chargeKeys, chargeBytes)
+		}
+		return err
+	}
+
+	if exists && old.owner != owner && m.principals != nil {
+		m.principals.refund(old.owner, 1, entrySize(key, old.value))
+	}
+
+	e := &entry{value: value, owner: owner}
+	e.lastAccess.Store(time.Now().UnixNano())
+	m.data[key] = e
+
+	if r.ExpiresAt > 0 {
+		m.ttl[key] = r.ExpiresAt
 	} else {
 		delete(m.ttl, key)
 	}
@@ -58,14 +217,71 @@ func (m *MemoryStore) Set(key, value string, ttlSeconds *int64) error {
 	return nil
 }
 
+// Admit reports whether PutRecordContext would accept r, without storing
+// it. A Raft leader admits writes before committing them, since every node
+// then applies them with Load, unchecked, and must not reject them.
+func (m *MemoryStore) Admit(r Record) error {
+	if r.Key == "" {
+		return fmt.Errorf("key cannot be empty")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+
+	size := entrySize(r.Key, r.Value)
+	keys, bytes := int64(1), size
+	chargeKeys, chargeBytes := keys, bytes
+	old, exists := m.data[r.Key]
+	if exists && !m.isExpired(r.Key) {
+		keys, bytes = 0, size-entrySize(r.Key, old.value)
+		if old.owner == r.Owner {
+			chargeKeys, chargeBytes = keys, bytes
+		}
+	}
+
+	if err := m.checkQuota(keys, bytes, int64(len(r.Value))); err != nil {
+		return err
+	}
+	if m.principals != nil {
+		if err := m.principals.check(r.Owner, chargeKeys, chargeBytes, int64(len(r.Value))); err != nil {
+			return err
+		}
+	}
+
+	max, policy := m.memory.settings()
+	if max > 0 && size > max {
+		return fmt.Errorf("%w: entry of %d bytes exceeds max memory of %d bytes", ErrOutOfMemory, size, max)
+	}
+	if policy == NoEviction && !m.memory.fits(bytes) {
+		return fmt.Errorf("%w: %d of %d bytes used, eviction policy %s", ErrOutOfMemory, m.memory.Used(), max, policy)
+	}
+	return nil
+}
+
 func (m *MemoryStore) Delete(key string) (bool, error) {
+	return m.DeleteContext(context.Background(), key)
+}
+
+// DeleteContext is Delete recorded as a child span of ctx.
+func (m *MemoryStore) DeleteContext(ctx context.Context, key string) (_ bool, err error) {
+	ctx, span := m.startSpan(ctx, "Delete")
+	defer func() { endSpan(span, err) }()
+
 	if key == "" {
 		return false, fmt.Errorf("key cannot be empty")
 	}
 
-	m.mu.Lock()
+	m.lock(ctx)
 	defer m.mu.Unlock()
 
+	if m.closed {
+		return false, ErrClosed
+	}
+
 	_, existed := m.data[key]
 	m.delete(key)
 
@@ -73,26 +289,119 @@ func (m *MemoryStore) Delete(key string) (bool, error) {
 }
 
 func (m *MemoryStore) List(limit int) (map[string]string, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	return m.Scan("", limit)
+}
 
-	result := make(map[string]string)
+func (m *MemoryStore) Scan(prefix string, limit int) (map[string]string, error) {
+	return m.ScanContext(context.Background(), prefix, limit)
+}
 
-	for k, v := range m.data {
-		if limit > 0 && len(result) >= limit {
-			break
-		}
+// ScanContext is Scan recorded as a child span of ctx.
+func (m *MemoryStore) ScanContext(ctx context.Context, prefix string, limit int) (map[string]string, error) {
+	return m.ScanFuncContext(ctx, prefix, limit, nil)
+}
+
+// ScanFuncContext is ScanContext returning only the keys keep accepts. The
+// limit counts those keys alone. A nil keep accepts every key.
+func (m *MemoryStore) ScanFuncContext(ctx context.Context, prefix string, limit int, keep func(key string) bool) (_ map[string]string, err error) {
+	ctx, span := m.startSpan(ctx, "Scan")
+	defer func() { endSpan(span, err) }()
 
-		if m.isExpired((k)) {
+	m.rlock(ctx)
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return nil, ErrClosed
+	}
+
+	var keys []string
+	for k := range m.data {
+		if !strings.HasPrefix(k, prefix) || m.isExpired(k) || (keep != nil && !keep(k)) {
 			continue
 		}
+		keys = append(keys, k)
+	}
+	if limit > 0 && len(keys) > limit {
+		slices.Sort(keys)
+		keys = keys[:limit]
+	}
 
-		result[k] = v
+	result := make(map[string]string, len(keys))
+	for _, k := range keys {
+		result[k] = m.data[k].value
 	}
 
 	return result, nil
 }
 
+// Close marks the store closed. Every later operation fails as if the store
+// were empty: reads find nothing, and writes and scans return ErrClosed.
+func (m *MemoryStore) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.closed = true
+	m.memory.unregister(m)
+	return nil
+}
+
+func (m *MemoryStore) Ready() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+	return nil
+}
+
+// clear deletes every key, refunding their owners.
+func (m *MemoryStore) clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for k := range m.data {
+		m.delete(k)
+	}
+}
+
+// SetQuota changes the store quota at runtime. Data above a lowered quota is
+// kept; only further growth is rejected.
+func (m *MemoryStore) SetQuota(quota Quota) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.quota = quota
+}
+
+func (m *MemoryStore) Quota() Quota {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.quota
+}
+
+// Usage returns the keys and bytes held, including expired keys that have
+// not been removed yet.
+func (m *MemoryStore) Usage() Usage {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.usage()
+}
+
+func (m *MemoryStore) usage() Usage {
+	return Usage{Keys: int64(len(m.data)), Bytes: m.usedBytes}
+}
+
+// SetHooks replaces the hooks at runtime.
+func (m *MemoryStore) SetHooks(hooks Hooks) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.hooks = hooks
+}
+
 func (m *MemoryStore) isExpired(key string) bool {
 	expiration, hasExpiration := m.ttl[key]
 	if !hasExpiration {
@@ -103,6 +412,49 @@ func (m *MemoryStore) isExpired(key string) bool {
 }
 
 func (m *MemoryStore) delete(key string) {
+	if e, ok := m.data[key]; ok {
+		m.usedBytes -= entrySize(key, e.value)
+		m.memory.add(-entrySize(key, e.value))
+		if m.principals != nil {
+			m.principals.refund(e.owner, 1, entrySize(key, e.value))
+		}
+	}
 	delete(m.data, key)
 	delete(m.ttl, key)
 }
+
+// expire removes a key whose TTL has passed. The caller must hold the write
+// lock.
+func (m *MemoryStore) expire(key string) {
+	m.delete(key)
+	if m.hooks.Expired != nil {
+		m.hooks.Expired(m.name)
+	}
+}
+
+// checkQuota reports whether adding keys and bytes for a value of valueBytes
+// fits the store quota. Expired keys are purged before giving up. The caller
+// must hold the write lock.
+func (m *MemoryStore) checkQuota(keys, bytes, valueBytes int64) error {
+	if m.quota.check(ScopeNamespace, m.name, m.usage(), keys, bytes, valueBytes) == nil {
+		return nil
+	}
+
+	m.purgeExpired()
+	return m.quota.check(ScopeNamespace, m.name, m.usage(), keys, bytes, valueBytes)
+}
+
+// purgeExpired removes every expired key. The caller must hold the write
+// lock.
+func (m *MemoryStore) purgeExpired() {
+	for k := range m.ttl {
+		if m.isExpired(k) {
+			m.expire(k)
+		}
+	}
+}
+
+// entrySize approximates the memory held by a single key/value pair.
+func entrySize(key, value string) int64 {
+	return int64(len(key) + len(value))
+}
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"testing"
@@ -365,6 +366,75 @@ func TestMemoryStore_DeleteTTLKeys(t *testing.T) {
     assert.Empty(t, value)
 }
 
+// Test Scan with prefix filtering
+func TestMemoryStore_Scan(t *testing.T) {
+	store := NewMemoryStore()
+
+	for _, key := range []string{"user:1", "user:2", "user:3", "order:1"} {
+		require.NoError(t, store.Set(key, "v-"+key, nil))
+	}
+
+	result, err := store.Scan("user:", 0)
+	assert.NoError(t, err)
+	assert.Len(t, result, 3)
+	assert.Equal(t, "v-user:2", result["user:2"])
+	assert.NotContains(t, result, "order:1")
+
+	// Limit applies to matching keys only, and keeps the first in key order
+	result, err = store.Scan("user:", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"user:1": "v-user:1", "user:2": "v-user:2"}, result)
+
+	// Empty prefix behaves like List
+	result, err = store.Scan("", 0)
+	assert.NoError(t, err)
+	assert.Len(t, result, 4)
+}
+
+// Test a scan filter applies before the limit
+func TestMemoryStore_ScanFunc(t *testing.T) {
+	store := NewMemoryStore()
+	for i := 0; i < 10; i++ {
+		require.NoError(t, store.Set(fmt.Sprintf("key%d", i), "value", nil))
+	}
+
+	even := func(key string) bool { return (key[len(key)-1]-'0')%2 == 0 }
+	result, err := store.ScanFuncContext(context.Background(), "key", 3, even)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"key0": "value", "key2": "value", "key4": "value"}, result)
+
+	result, err = store.ScanFuncContext(context.Background(), "key", 0, even)
+	require.NoError(t, err)
+	assert.Len(t, result, 5)
+}
+
+// Test writes are rejected after Close
+func TestMemoryStore_Close(t *testing.T) {
+	store := NewMemoryStore()
+	require.NoError(t, store.Set("key1", "value1", nil))
+	require.NoError(t, store.Ready())
+
+	require.NoError(t, store.Close())
+	assert.ErrorIs(t, store.Ready(), ErrClosed)
+	require.NoError(t, store.Close(), "Close is idempotent")
+
+	assert.ErrorIs(t, store.Set("key2", "value2", nil), ErrClosed)
+
+	_, err := store.Delete("key1")
+	assert.ErrorIs(t, err, ErrClosed)
+
+	_, err = store.List(0)
+	assert.ErrorIs(t, err, ErrClosed)
+
+	// Reads see an empty store rather than the data left behind
+	_, found := store.Get("key1")
+	assert.False(t, found)
+	_, found = store.Record("key1")
+	assert.False(t, found)
+	assert.Empty(t, store.Records(""))
+	assert.ErrorIs(t, store.Load(Record{Key: "key2", Value: "value2"}), ErrClosed)
+}
+
 // Helper function for creating int64 pointers
 func int64Ptr(i int64) *int64 {
 	return &i
@@ -0,0 +1,250 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// DefaultNamespace holds the keys of requests that do not name a namespace.
+// It always exists and cannot be dropped.
+const DefaultNamespace = "default"
+
+var (
+	ErrNamespaceNotFound = errors.New("storage: namespace not found")
+	ErrNamespaceExists   = errors.New("storage: namespace already exists")
+	ErrInvalidNamespace  = errors.New("storage: invalid namespace")
+)
+
+var namespaceName = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,62}$`)
+
+func ValidNamespace(name string) error {
+	if !namespaceName.MatchString(name) {
+		return fmt.Errorf("%w: %q must be 1-63 lowercase letters, digits, '_', '.' or '-', starting with a letter or digit",
+			ErrInvalidNamespace, name)
+	}
+	return nil
+}
+
+type NamespaceSettings struct {
+	// DefaultTTLSeconds applies to writes that do not set a TTL. Zero keeps
+	// such keys forever.
+	DefaultTTLSeconds int64
+	Quota             Quota
+}
+
+// Namespace is an isolated keyspace with its own settings. Keys in one
+// namespace are never visible from another.
+type Namespace struct {
+	*MemoryStore
+	name       string
+	defaultTTL int64
+}
+
+func (ns *Namespace) Name() string {
+	return ns.name
+}
+
+func (ns *Namespace) Settings() NamespaceSettings {
+	return NamespaceSettings{DefaultTTLSeconds: ns.defaultTTL, Quota: ns.Quota()}
+}
+
+// Set stores value under key. A nil ttlSeconds picks the namespace's default
+// TTL; an explicit zero keeps the key forever.
+func (ns *Namespace) Set(key, value string, ttlSeconds *int64) error {
+	return ns.SetAs("", key, value, ttlSeconds)
+}
+
+// SetAs is Set on behalf of principal owner, see MemoryStore.SetAs.
+func (ns *Namespace) SetAs(owner, key, value string, ttlSeconds *int64) error {
+	return ns.SetAsContext(context.Background(), owner, key, value, ttlSeconds)
+}
+
+// SetAsContext is SetAs recorded as a child span of ctx.
+func (ns *Namespace) SetAsContext(ctx context.Context, owner, key, value string, ttlSeconds *int64) error {
+	if ttlSeconds == nil && ns.defaultTTL > 0 {
+		ttlSeconds = &ns.defaultTTL
+	}
+	return ns.MemoryStore.SetAsContext(ctx, owner, key, value, ttlSeconds)
+}
+
+// Namespaces is the registry of namespaces, each backed by its own
+// MemoryStore. The memory limit and principal quotas span all of them.
+type Namespaces struct {
+	mu         sync.RWMutex
+	spaces     map[string]*Namespace
+	memory     *MemoryLimit
+	hooks      Hooks
+	principals *PrincipalQuotas
+}
+
+func NewNamespaces() *Namespaces {
+	n := &Namespaces{
+		spaces:     make(map[string]*Namespace),
+		memory:     NewMemoryLimit(0, NoEviction),
+		principals: NewPrincipalQuotas(),
+	}
+	n.spaces[DefaultNamespace] = n.newNamespace(DefaultNamespace, NamespaceSettings{})
+	return n
+}
+
+// newNamespace builds a namespace under the shared memory limit. The caller
+// must hold the lock or own n exclusively.
+func (n *Namespaces) newNamespace(name string, settings NamespaceSettings) *Namespace {
+	return &Namespace{
+		MemoryStore: NewMemoryStore(
+			WithMemoryLimit(n.memory),
+			WithQuota(name, settings.Quota),
+			WithPrincipalQuotas(n.principals),
+			WithHooks(n.hooks),
+		),
+		name:       name,
+		defaultTTL: settings.DefaultTTLSeconds,
+	}
+}
+
+// Principals returns the principal quotas shared by every namespace.
+func (n *Namespaces) Principals() *PrincipalQuotas {
+	return n.principals
+}
+
+// Get returns the named namespace. The empty name is the default namespace.
+func (n *Namespaces) Get(name string) (*Namespace, error) {
+	if name == "" {
+		name = DefaultNamespace
+	}
+
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	ns, ok := n.spaces[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNamespaceNotFound, name)
+	}
+	return ns, nil
+}
+
+// Default returns the default namespace.
+func (n *Namespaces) Default() *Namespace {
+	ns, _ := n.Get(DefaultNamespace)
+	return ns
+}
+
+func (n *Namespaces) Create(name string, settings NamespaceSettings) (*Namespace, error) {
+	if err := ValidNamespace(name); err != nil {
+		return nil, err
+	}
+	if settings.DefaultTTLSeconds < 0 {
+		return nil, fmt.Errorf("%w: default TTL must not be negative", ErrInvalidNamespace)
+	}
+	if err := settings.Quota.validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidNamespace, err)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if _, ok := n.spaces[name]; ok {
+		return nil, fmt.Errorf("%w: %s", ErrNamespaceExists, name)
+	}
+
+	ns := n.newNamespace(name, settings)
+	n.spaces[name] = ns
+	return ns, nil
+}
+
+// Drop deletes a namespace and every key in it.
+func (n *Namespaces) Drop(name string) error {
+	if name == DefaultNamespace {
+		return fmt.Errorf("%w: the %s namespace cannot be dropped", ErrInvalidNamespace, DefaultNamespace)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	ns, ok := n.spaces[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNamespaceNotFound, name)
+	}
+
+	delete(n.spaces, name)
+	err := ns.Close()
+	ns.clear()
+	return err
+}
+
+// SetQuota changes the quota of the named namespace.
+func (n *Namespaces) SetQuota(name string, quota Quota) (*Namespace, error) {
+	if err := quota.validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidNamespace, err)
+	}
+
+	ns, err := n.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	ns.MemoryStore.SetQuota(quota)
+	return ns, nil
+}
+
+// List returns every namespace sorted by name.
+func (n *Namespaces) List() []*Namespace {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	out := make([]*Namespace, 0, len(n.spaces))
+	for _, ns := range n.spaces {
+		out = append(out, ns)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].name < out[j].name })
+
+	return out
+}
+
+// Memory returns the memory limit shared by every namespace.
+func (n *Namespaces) Memory() *MemoryLimit {
+	return n.memory
+}
+
+// SetMaxMemory changes the memory limit of all namespaces together.
+func (n *Namespaces) SetMaxMemory(bytes int64) {
+	n.memory.SetMax(bytes)
+}
+
+func (n *Namespaces) SetEvictionPolicy(policy EvictionPolicy) {
+	n.memory.SetPolicy(policy)
+}
+
+// SetHooks installs hooks on every namespace, existing and future. Hooks are
+// called with the namespace name.
+func (n *Namespaces) SetHooks(hooks Hooks) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.hooks = hooks
+	for _, ns := range n.spaces {
+		ns.SetHooks(hooks)
+	}
+}
+
+// Ready reports the readiness of the default namespace, which every
+// namespace shares the fate of.
+func (n *Namespaces) Ready() error {
+	return n.Default().Ready()
+}
+
+// Close closes every namespace.
+func (n *Namespaces) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var errs []error
+	for _, ns := range n.spaces {
+		errs = append(errs, ns.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test keys in different namespaces never see each other
+func TestNamespaces_Isolation(t *testing.T) {
+	n := NewNamespaces()
+
+	a, err := n.Create("team-a", NamespaceSettings{})
+	require.NoError(t, err)
+
+	require.NoError(t, a.Set("shared", "a", nil))
+	require.NoError(t, n.Default().Set("shared", "default", nil))
+
+	value, _ := a.Get("shared")
+	assert.Equal(t, "a", value)
+
+	def, err := n.Get("")
+	require.NoError(t, err)
+	value, _ = def.Get("shared")
+	assert.Equal(t, "default", value)
+
+	data, err := a.Scan("", 0)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"shared": "a"}, data)
+}
+
+func TestNamespaces_CreateDropList(t *testing.T) {
+	n := NewNamespaces()
+
+	_, err := n.Create("team-a", NamespaceSettings{Quota: Quota{MaxKeys: 10}})
+	require.NoError(t, err)
+
+	_, err = n.Create("team-a", NamespaceSettings{})
+	assert.ErrorIs(t, err, ErrNamespaceExists)
+
+	for _, name := range []string{"", "Team-A", "-a", "a/b"} {
+		_, err = n.Create(name, NamespaceSettings{})
+		assert.ErrorIs(t, err, ErrInvalidNamespace, name)
+	}
+
+	var names []string
+	for _, ns := range n.List() {
+		names = append(names, ns.Name())
+	}
+	assert.Equal(t, []string{DefaultNamespace, "team-a"}, names)
+
+	a, err := n.Get("team-a")
+	require.NoError(t, err)
+	require.NoError(t, n.Drop("team-a"))
+
+	_, err = n.Get("team-a")
+	assert.ErrorIs(t, err, ErrNamespaceNotFound)
+	assert.ErrorIs(t, a.Set("k", "v", nil), ErrClosed)
+
+	assert.ErrorIs(t, n.Drop("team-a"), ErrNamespaceNotFound)
+	assert.ErrorIs(t, n.Drop(DefaultNamespace), ErrInvalidNamespace)
+}
+
+func TestNamespace_MaxKeys(t *testing.T) {
+	n := NewNamespaces()
+	ns, err := n.Create("small", NamespaceSettings{Quota: Quota{MaxKeys: 2}})
+	require.NoError(t, err)
+
+	require.NoError(t, ns.Set("a", "v", nil))
+	require.NoError(t, ns.Set("b", "v", nil))
+	assert.ErrorIs(t, ns.Set("c", "v", nil), ErrTooManyKeys)
+
+	// Overwriting an existing key does not need a new slot
+	assert.NoError(t, ns.Set("a", "v2", nil))
+
+	_, err = ns.Delete("b")
+	require.NoError(t, err)
+	assert.NoError(t, ns.Set("c", "v", nil))
+}
+
+// Test the default TTL applies only when no TTL is given
+func TestNamespace_DefaultTTL(t *testing.T) {
+	n := NewNamespaces()
+	ns, err := n.Create("sessions", NamespaceSettings{DefaultTTLSeconds: 1})
+	require.NoError(t, err)
+
+	require.NoError(t, ns.Set("default", "v", nil))
+	require.NoError(t, ns.Set("forever", "v", int64Ptr(0)))
+
+	time.Sleep(1100 * time.Millisecond)
+
+	_, found := ns.Get("default")
+	assert.False(t, found)
+	_, found = ns.Get("forever")
+	assert.True(t, found)
+}
+
+// Test hooks report expired and evicted keys with the namespace name
+func TestNamespaces_Hooks(t *testing.T) {
+	n := NewNamespaces()
+	ns, err := n.Create("cache", NamespaceSettings{})
+	require.NoError(t, err)
+
+	var expired, evicted []string
+	n.SetHooks(Hooks{
+		Expired: func(name string) { expired = append(expired, name) },
+		Evicted: func(name string) { evicted = append(evicted, name) },
+	})
+	n.SetEvictionPolicy(AllKeysLRU)
+
+	require.NoError(t, ns.Set("short", "v", int64Ptr(1)))
+	time.Sleep(1100 * time.Millisecond)
+	_, found := ns.Get("short")
+	assert.False(t, found)
+
+	require.NoError(t, ns.Set("a", "1", nil))
+	require.NoError(t, ns.Set("b", "2", nil))
+	n.SetMaxMemory(2)
+
+	assert.Equal(t, []string{"cache"}, expired)
+	assert.Equal(t, []string{"cache"}, evicted)
+}
+
+// Test the memory limit covers every namespace together
+func TestNamespaces_SharedMemoryLimit(t *testing.T) {
+	n := NewNamespaces()
+	a, err := n.Create("team-a", NamespaceSettings{})
+	require.NoError(t, err)
+	b, err := n.Create("team-b", NamespaceSettings{})
+	require.NoError(t, err)
+	n.SetMaxMemory(20)
+
+	require.NoError(t, a.Set("key1", "value", nil))
+	require.NoError(t, a.Set("key2", "value", nil))
+	assert.Equal(t, int64(18), n.Memory().Used())
+
+	// noeviction rejects a write to another namespace
+	assert.ErrorIs(t, b.Set("key3", "value", nil), ErrOutOfMemory)
+
+	// Otherwise the keys of other namespaces are evicted to make room
+	n.SetEvictionPolicy(AllKeysLRU)
+	require.NoError(t, b.Set("key3", "value", nil))
+	assert.Equal(t, int64(1), a.Usage().Keys)
+	assert.Equal(t, int64(18), n.Memory().Used())
+
+	require.NoError(t, n.Drop("team-b"))
+	assert.Equal(t, int64(9), n.Memory().Used())
+}
+
+// Test a snapshot restores into fresh namespaces and replaces existing ones
+func TestNamespaces_SnapshotRestore(t *testing.T) {
+	src := NewNamespaces()
+	ns, err := src.Create("team-a", NamespaceSettings{DefaultTTLSeconds: 3600})
+	require.NoError(t, err)
+	require.NoError(t, ns.SetAs("ci", "a", "1", nil))
+	require.NoError(t, ns.Set("gone", "v", int64Ptr(1)))
+	time.Sleep(1100 * time.Millisecond)
+
+	snaps := src.Snapshot()
+	require.Len(t, snaps, 2)
+	assert.Equal(t, "team-a", snaps[1].Name)
+	require.Len(t, snaps[1].Records, 1, "expired keys are not snapshotted")
+	assert.Equal(t, "ci", snaps[1].Records[0].Owner)
+	assert.NotZero(t, snaps[1].Records[0].ExpiresAt)
+
+	dst := NewNamespaces()
+	old := dst.Default()
+	require.NoError(t, dst.Default().Set("stale", "v", nil))
+	require.NoError(t, dst.Restore(snaps))
+
+	err = old.Set("x", "y", nil)
+	assert.ErrorIs(t, err, ErrClosed, "replaced namespaces are closed")
+	_, found := dst.Default().Get("stale")
+	assert.False(t, found)
+
+	restored, err := dst.Get("team-a")
+	require.NoError(t, err)
+	assert.Equal(t, snaps[1].Records, restored.Records(""))
+	assert.Equal(t, int64(1), dst.Principals().Usage("ci").Usage.Keys)
+
+	err = dst.Restore(append(snaps, snaps[1]))
+	assert.ErrorIs(t, err, ErrNamespaceExists)
+	_, err = dst.Get("team-a")
+	assert.NoError(t, err, "a rejected snapshot changes nothing")
+}
@@ -0,0 +1,221 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	// ErrQuotaExceeded is matched by every *QuotaError.
+	ErrQuotaExceeded = errors.New("storage: quota exceeded")
+	// ErrTooManyKeys is matched by a *QuotaError of a max_keys limit.
+	ErrTooManyKeys = errors.New("storage: too many keys")
+)
+
+// Quota caps what a namespace or principal may store. Zero fields are
+// unlimited. Sizes are counted like the memory limit: key plus value bytes.
+type Quota struct {
+	MaxKeys       int64
+	MaxBytes      int64
+	MaxValueBytes int64
+}
+
+type Usage struct {
+	Keys  int64
+	Bytes int64
+}
+
+// Quota scopes reported in QuotaError.
+const (
+	ScopeNamespace = "namespace"
+	ScopePrincipal = "principal"
+)
+
+// QuotaError reports which quota rejected a write.
+type QuotaError struct {
+	Scope string
+	Name  string
+	// Limit is max_keys, max_bytes or max_value_bytes.
+	Limit string
+	Max   int64
+	// Requested is the usage the write would have resulted in.
+	Requested int64
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("storage: quota exceeded: %s %s %s is %d, write needs %d",
+		e.Scope, e.Name, e.Limit, e.Max, e.Requested)
+}
+
+func (e *QuotaError) Is(target error) bool {
+	return target == ErrQuotaExceeded || (target == ErrTooManyKeys && e.Limit == "max_keys")
+}
+
+func (q Quota) validate() error {
+	if q.MaxKeys < 0 || q.MaxBytes < 0 || q.MaxValueBytes < 0 {
+		return errors.New("quota limits must not be negative")
+	}
+	return nil
+}
+
+// check reports whether usage after adding keys and bytes, for a value of
+// valueBytes, fits q.
+func (q Quota) check(scope, name string, usage Usage, keys, bytes, valueBytes int64) error {
+	if q.MaxValueBytes > 0 && valueBytes > q.MaxValueBytes {
+		return &QuotaError{Scope: scope, Name: name, Limit: "max_value_bytes", Max: q.MaxValueBytes, Requested: valueBytes}
+	}
+	if q.MaxKeys > 0 && keys > 0 && usage.Keys+keys > q.MaxKeys {
+		return &QuotaError{Scope: scope, Name: name, Limit: "max_keys", Max: q.MaxKeys, Requested: usage.Keys + keys}
+	}
+	if q.MaxBytes > 0 && bytes > 0 && usage.Bytes+bytes > q.MaxBytes {
+		return &QuotaError{Scope: scope, Name: name, Limit: "max_bytes", Max: q.MaxBytes, Requested: usage.Bytes + bytes}
+	}
+	return nil
+}
+
+// PrincipalQuotas tracks how much every principal stores across all
+// namespaces and enforces per-principal quotas. Stores charge a principal
+// when it writes a key and refund it when the key is overwritten by someone
+// else, deleted, evicted or expires.
+type PrincipalQuotas struct {
+	mu     sync.Mutex
+	quotas map[string]Quota
+	usage  map[string]*Usage
+}
+
+func NewPrincipalQuotas() *PrincipalQuotas {
+	return &PrincipalQuotas{
+		quotas: make(map[string]Quota),
+		usage:  make(map[string]*Usage),
+	}
+}
+
+// SetQuotas replaces every principal quota. Principals without an entry are
+// unlimited. Usage above a lowered quota is kept; only further growth is
+// rejected.
+func (p *PrincipalQuotas) SetQuotas(quotas map[string]Quota) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.quotas = make(map[string]Quota, len(quotas))
+	for principal, q := range quotas {
+		p.quotas[principal] = q
+	}
+}
+
+// charge adds keys and bytes to principal's usage if its quota allows.
+func (p *PrincipalQuotas) charge(principal string, keys, bytes, valueBytes int64) error {
+	if principal == "" {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	u := p.usage[principal]
+	if u == nil {
+		u = &Usage{}
+		p.usage[principal] = u
+	}
+
+	if err := p.quotas[principal].check(ScopePrincipal, principal, *u, keys, bytes, valueBytes); err != nil {
+		return err
+	}
+
+	u.Keys += keys
+	u.Bytes += bytes
+	return nil
+}
+
+// check reports whether charge would accept keys and bytes, without
+// charging them.
+func (p *PrincipalQuotas) check(principal string, keys, bytes, valueBytes int64) error {
+	if principal == "" {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var u Usage
+	if usage := p.usage[principal]; usage != nil {
+		u = *usage
+	}
+	return p.quotas[principal].check(ScopePrincipal, principal, u, keys, bytes, valueBytes)
+}
+
+// add charges principal without checking its quota.
+func (p *PrincipalQuotas) add(principal string, keys, bytes int64) {
+	if principal == "" {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	u := p.usage[principal]
+	if u == nil {
+		u = &Usage{}
+		p.usage[principal] = u
+	}
+	u.Keys += keys
+	u.Bytes += bytes
+}
+
+func (p *PrincipalQuotas) refund(principal string, keys, bytes int64) {
+	if principal == "" {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if u := p.usage[principal]; u != nil {
+		u.Keys -= keys
+		u.Bytes -= bytes
+		if u.Keys <= 0 && u.Bytes <= 0 {
+			delete(p.usage, principal)
+		}
+	}
+}
+
+type PrincipalUsage struct {
+	Principal string
+	Quota     Quota
+	Usage     Usage
+}
+
+// Usage returns the quota and usage of principal.
+func (p *PrincipalQuotas) Usage(principal string) PrincipalUsage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	out := PrincipalUsage{Principal: principal, Quota: p.quotas[principal]}
+	if u := p.usage[principal]; u != nil {
+		out.Usage = *u
+	}
+	return out
+}
+
+// All returns every principal that has a quota or stores data, sorted.
+func (p *PrincipalQuotas) All() []PrincipalUsage {
+	p.mu.Lock()
+	names := make(map[string]bool)
+	for principal := range p.quotas {
+		names[principal] = true
+	}
+	for principal := range p.usage {
+		names[principal] = true
+	}
+	p.mu.Unlock()
+
+	out := make([]PrincipalUsage, 0, len(names))
+	for principal := range names {
+		out = append(out, p.Usage(principal))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Principal < out[j].Principal })
+
+	return out
+}
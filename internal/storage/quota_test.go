@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuota_Namespace(t *testing.T) {
+	n := NewNamespaces()
+	ns, err := n.Create("small", NamespaceSettings{Quota: Quota{MaxKeys: 2, MaxBytes: 16, MaxValueBytes: 8}})
+	require.NoError(t, err)
+
+	require.NoError(t, ns.Set("a", "v", nil))
+	require.NoError(t, ns.Set("b", "v", nil))
+
+	var qe *QuotaError
+	err = ns.Set("c", "v", nil)
+	require.ErrorAs(t, err, &qe)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	assert.Equal(t, &QuotaError{Scope: ScopeNamespace, Name: "small", Limit: "max_keys", Max: 2, Requested: 3}, qe)
+
+	// Overwriting an existing key does not need a new slot
+	require.NoError(t, ns.Set("a", "1234567", nil))
+
+	err = ns.Set("b", "123456789", nil)
+	require.ErrorAs(t, err, &qe)
+	assert.Equal(t, "max_value_bytes", qe.Limit)
+
+	// a holds 8 bytes and b 2; growing b to 9 bytes would need 17
+	err = ns.Set("b", "12345678", nil)
+	require.ErrorAs(t, err, &qe)
+	assert.Equal(t, "max_bytes", qe.Limit)
+	assert.Contains(t, err.Error(), "namespace small max_bytes is 16")
+
+	_, err = ns.Delete("a")
+	require.NoError(t, err)
+	assert.NoError(t, ns.Set("c", "v", nil))
+	assert.Equal(t, Usage{Keys: 2, Bytes: 4}, ns.Usage())
+}
+
+// Test principal usage spans namespaces and follows key ownership
+func TestQuota_Principal(t *testing.T) {
+	n := NewNamespaces()
+	n.Principals().SetQuotas(map[string]Quota{"ci": {MaxKeys: 2}})
+
+	other, err := n.Create("other", NamespaceSettings{})
+	require.NoError(t, err)
+	def := n.Default()
+
+	require.NoError(t, def.SetAs("ci", "a", "v", nil))
+	require.NoError(t, other.SetAs("ci", "a", "v", nil))
+
+	var qe *QuotaError
+	require.ErrorAs(t, def.SetAs("ci", "b", "v", nil), &qe)
+	assert.Equal(t, ScopePrincipal, qe.Scope)
+	assert.Equal(t, "ci", qe.Name)
+
+	// Unlimited principals are unaffected
+	require.NoError(t, def.SetAs("alice", "b", "v", nil))
+
+	// Overwriting a key moves its charge to the writer
+	require.NoError(t, def.SetAs("alice", "a", "v", nil))
+	assert.Equal(t, Usage{Keys: 1, Bytes: 2}, n.Principals().Usage("ci").Usage)
+	assert.Equal(t, Usage{Keys: 2, Bytes: 4}, n.Principals().Usage("alice").Usage)
+	require.NoError(t, def.SetAs("ci", "c", "v", nil))
+
+	// Dropping a namespace refunds its keys
+	require.NoError(t, n.Drop("other"))
+	assert.Equal(t, Usage{Keys: 1, Bytes: 2}, n.Principals().Usage("ci").Usage)
+
+	var names []string
+	for _, u := range n.Principals().All() {
+		names = append(names, u.Principal)
+	}
+	assert.Equal(t, []string{"alice", "ci"}, names)
+}
+
+// Test concurrent writers cannot overshoot a quota
+func TestQuota_Concurrent(t *testing.T) {
+	n := NewNamespaces()
+	n.Principals().SetQuotas(map[string]Quota{"ci": {MaxKeys: 50}})
+	ns, err := n.Create("shared", NamespaceSettings{Quota: Quota{MaxBytes: 1000}})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				_ = ns.SetAs("ci", fmt.Sprintf("k%d-%d", i, j), strings.Repeat("v", 10), nil)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	usage := n.Principals().Usage("ci").Usage
+	assert.Equal(t, int64(50), usage.Keys)
+	assert.Equal(t, ns.Usage(), usage)
+	assert.LessOrEqual(t, ns.Usage().Bytes, int64(1000))
+}
+
+// Test Admit rejects what a write would be rejected for without storing
+// anything, and Load stores admitted records unchecked
+func TestQuota_Admit(t *testing.T) {
+	n := NewNamespaces()
+	n.Principals().SetQuotas(map[string]Quota{"ci": {MaxKeys: 1}})
+	ns, err := n.Create("small", NamespaceSettings{Quota: Quota{MaxKeys: 2}})
+	require.NoError(t, err)
+
+	require.NoError(t, ns.Admit(Record{Key: "a", Value: "v", Owner: "ci"}))
+	assert.Zero(t, ns.Usage().Keys, "admitting stores nothing")
+	require.NoError(t, ns.Load(Record{Key: "a", Value: "v", Owner: "ci"}))
+
+	assert.ErrorIs(t, ns.Admit(Record{Key: "b", Value: "v", Owner: "ci"}), ErrQuotaExceeded)
+	assert.NoError(t, ns.Admit(Record{Key: "a", Value: "w", Owner: "ci"}), "overwriting needs no new slot")
+	require.NoError(t, ns.Load(Record{Key: "b", Value: "v"}, Record{Key: "c", Value: "v"}))
+	assert.ErrorIs(t, ns.Admit(Record{Key: "d", Value: "v"}), ErrTooManyKeys)
+	assert.Equal(t, int64(3), ns.Usage().Keys, "Load is not checked")
+
+	n.SetMaxMemory(4)
+	assert.ErrorIs(t, ns.Admit(Record{Key: "a", Value: "12345"}), ErrOutOfMemory)
+}
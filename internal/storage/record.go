@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Record is a key with everything needed to recreate it in another store.
+// Replication and snapshots copy Records rather than relative TTLs.
+type Record struct {
+	Key   string
+	Value string
+	// Owner is the principal charged for the key, if any.
+	Owner string
+	// ExpiresAt is a Unix time in seconds. Zero never expires.
+	ExpiresAt int64
+}
+
+// ExpiresAt converts a TTL as accepted by Set into an absolute expiry.
+func ExpiresAt(ttlSeconds *int64) int64 {
+	if ttlSeconds == nil || *ttlSeconds <= 0 {
+		return 0
+	}
+	return time.Now().Unix() + *ttlSeconds
+}
+
+// Records returns every unexpired key starting with prefix, sorted by key.
+func (m *MemoryStore) Records(prefix string) []Record {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return nil
+	}
+
+	out := make([]Record, 0, len(m.data))
+	for k, e := range m.data {
+		if !strings.HasPrefix(k, prefix) || m.isExpired(k) {
+			continue
+		}
+		out = append(out, Record{Key: k, Value: e.value, Owner: e.owner, ExpiresAt: m.ttl[k]})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+
+	return out
+}
+
+// Record returns key as a Record, unless it is missing or expired.
+func (m *MemoryStore) Record(key string) (Record, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	e, ok := m.data[key]
+	if !ok || m.closed || m.isExpired(key) {
+		return Record{}, false
+	}
+	return Record{Key: key, Value: e.value, Owner: e.owner, ExpiresAt: m.ttl[key]}, true
+}
+
+// Load stores records without enforcing quotas or the memory limit, since
+// they were accepted by the store they come from.
+func (m *MemoryStore) Load(records ...Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+
+	for _, r := range records {
+		m.delete(r.Key)
+
+		e := &entry{value: r.Value, owner: r.Owner}
+		e.lastAccess.Store(time.Now().UnixNano())
+		m.data[r.Key] = e
+		if r.ExpiresAt > 0 {
+			m.ttl[r.Key] = r.ExpiresAt
+		}
+
+		m.usedBytes += entrySize(r.Key, r.Value)
+		m.memory.add(entrySize(r.Key, r.Value))
+		if m.principals != nil {
+			m.principals.add(r.Owner, 1, entrySize(r.Key, r.Value))
+		}
+	}
+
+	return nil
+}
+
+// ExpiresAt is the package-level ExpiresAt, applying the namespace's default
+// TTL when ttlSeconds is nil.
+func (ns *Namespace) ExpiresAt(ttlSeconds *int64) int64 {
+	if ttlSeconds == nil && ns.defaultTTL > 0 {
+		ttlSeconds = &ns.defaultTTL
+	}
+	return ExpiresAt(ttlSeconds)
+}
+
+// NamespaceSnapshot is the complete state of one namespace.
+type NamespaceSnapshot struct {
+	Name     string
+	Settings NamespaceSettings
+	Records  []Record
+}
+
+// Snapshot returns the state of every namespace, sorted by name.
+func (n *Namespaces) Snapshot() []NamespaceSnapshot {
+	var out []NamespaceSnapshot
+	for _, ns := range n.List() {
+		out = append(out, NamespaceSnapshot{
+			Name:     ns.Name(),
+			Settings: ns.Settings(),
+			Records:  ns.Records(""),
+		})
+	}
+	return out
+}
+
+// Restore replaces every namespace and key with snaps, as returned by
+// Snapshot. The replaced namespaces are closed, so requests still holding
+// them fail with ErrClosed.
+func (n *Namespaces) Restore(snaps []NamespaceSnapshot) error {
+	seen := make(map[string]bool, len(snaps))
+	for _, snap := range snaps {
+		if err := ValidNamespace(snap.Name); err != nil {
+			return err
+		}
+		if seen[snap.Name] {
+			return fmt.Errorf("%w: %s appears twice in the snapshot", ErrNamespaceExists, snap.Name)
+		}
+		seen[snap.Name] = true
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	old := n.spaces
+	n.spaces = make(map[string]*Namespace, len(snaps)+1)
+	for _, snap := range snaps {
+		ns := n.newNamespace(snap.Name, snap.Settings)
+		ns.Load(snap.Records...) // a new namespace is open
+		n.spaces[snap.Name] = ns
+	}
+	if _, ok := n.spaces[DefaultNamespace]; !ok {
+		n.spaces[DefaultNamespace] = n.newNamespace(DefaultNamespace, NamespaceSettings{})
+	}
+
+	for _, ns := range old {
+		ns.Close()
+		ns.clear()
+	}
+
+	return nil
+}
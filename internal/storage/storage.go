@@ -1,8 +1,28 @@
 package storage
 
+import "errors"
+
+// ErrClosed is returned by operations on a storage backend after Close.
+var ErrClosed = errors.New("storage: closed")
+
 type Storage interface {
 	Get(key string) (string, bool)
 	Set(key, value string, ttlSeconds *int64) error
 	Delete(key string) (bool, error)
 	List(limit int) (map[string]string, error)
+	// Scan returns the keys starting with prefix. With a positive limit it
+	// returns the first limit of them in key order, so that scans of
+	// several stores can be merged.
+	Scan(prefix string, limit int) (map[string]string, error)
+	// Close releases the backend. Afterwards every operation fails the same
+	// way: Set, Delete, List and Scan with ErrClosed, and Get finds nothing.
+	// Close is safe to call more than once.
+	Close() error
+}
+
+// Readiness is implemented by backends that may be temporarily unable to
+// serve, for example while replaying a log. Ready returns nil once the
+// backend can serve requests.
+type Readiness interface {
+	Ready() error
 }
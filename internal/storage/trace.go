@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer returns the tracer of the installed TracerProvider, a no-op until
+// one is, see internal/tracing. It is looked up on every call so that spans
+// follow the provider installed last.
+func tracer() trace.Tracer {
+	return otel.Tracer("kvstore/internal/storage")
+}
+
+// startSpan starts a child span of ctx for storage operation op. Calls
+// made outside a traced request, such as by expiry or replication, start
+// no trace of their own and get the non-recording span of ctx.
+func (m *MemoryStore) startSpan(ctx context.Context, op string) (context.Context, trace.Span) {
+	if span := trace.SpanFromContext(ctx); !span.IsRecording() {
+		return ctx, span
+	}
+	return tracer().Start(ctx, "storage."+op, trace.WithAttributes(attribute.String("kvstore.namespace", m.name)))
+}
+
+// lock takes the write lock, recording the wait as a span when ctx is
+// traced.
+func (m *MemoryStore) lock(ctx context.Context) {
+	if !trace.SpanFromContext(ctx).IsRecording() {
+		m.mu.Lock()
+		return
+	}
+
+	_, span := tracer().Start(ctx, "storage.lock_wait", trace.WithAttributes(attribute.String("kvstore.lock", "write")))
+	m.mu.Lock()
+	span.End()
+}
+
+// rlock takes the read lock, recording the wait as a span when ctx is
+// traced.
+func (m *MemoryStore) rlock(ctx context.Context) {
+	if !trace.SpanFromContext(ctx).IsRecording() {
+		m.mu.RLock()
+		return
+	}
+
+	_, span := tracer().Start(ctx, "storage.lock_wait", trace.WithAttributes(attribute.String("kvstore.lock", "read")))
+	m.mu.RLock()
+	span.End()
+}
+
+// endSpan ends span, marking it failed when err is not nil.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// Test storage operations are child spans of the caller, with lock waits
+// nested inside them
+func TestMemoryStore_Spans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "rpc")
+	n := NewNamespaces()
+	ns := n.Default()
+	require.NoError(t, ns.SetAsContext(ctx, "", "k", "v", nil))
+	_, found := ns.GetContext(ctx, "k")
+	require.True(t, found)
+	parent.End()
+
+	spans := recorder.Ended()
+	names := make(map[string]int)
+	for _, span := range spans {
+		names[span.Name()]++
+	}
+	assert.Equal(t, map[string]int{"rpc": 1, "storage.Set": 1, "storage.Get": 1, "storage.lock_wait": 2}, names)
+
+	for _, span := range spans {
+		switch span.Name() {
+		case "storage.Set", "storage.Get":
+			assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+		case "storage.lock_wait":
+			assert.NotEqual(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+		}
+	}
+
+	// Calls without a traced context record no spans
+	ns.Get("k")
+	assert.Len(t, recorder.Ended(), len(spans))
+}
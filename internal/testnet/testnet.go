@@ -0,0 +1,84 @@
+// Package testnet connects gRPC servers in-process for tests, and cuts the
+// links between them to simulate partitions.
+package testnet
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+// Network tracks the connections made through its dialers, by the pair of
+// addresses they link.
+type Network struct {
+	mu    sync.Mutex
+	cut   map[[2]string]bool
+	conns map[[2]string][]net.Conn
+}
+
+func New() *Network {
+	return &Network{cut: make(map[[2]string]bool), conns: make(map[[2]string][]net.Conn)}
+}
+
+func link(a, b string) [2]string {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]string{a, b}
+}
+
+// Dialer connects from the server at addr, unless the link is cut.
+func (n *Network) Dialer(from string) grpc.DialOption {
+	return grpc.WithContextDialer(func(ctx context.Context, to string) (net.Conn, error) {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+
+		l := link(from, to)
+		if n.cut[l] {
+			return nil, fmt.Errorf("partitioned from %s", to)
+		}
+		conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", to)
+		if err != nil {
+			return nil, err
+		}
+		n.conns[l] = append(n.conns[l], conn)
+		return conn, nil
+	})
+}
+
+// Partition cuts the links between the server at a and every other one, in
+// both directions, closing their connections.
+func (n *Network) Partition(a string, others ...string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, b := range others {
+		l := link(a, b)
+		n.cut[l] = true
+		for _, conn := range n.conns[l] {
+			conn.Close()
+		}
+		delete(n.conns, l)
+	}
+}
+
+// Heal restores the links between the server at a and every other one.
+func (n *Network) Heal(a string, others ...string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, b := range others {
+		delete(n.cut, link(a, b))
+	}
+}
+
+// HealAll restores every link.
+func (n *Network) HealAll() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	clear(n.cut)
+}
@@ -0,0 +1,235 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// ClientAuth values accepted in ServerOptions.
+const (
+	ClientAuthNone    = "none"
+	ClientAuthRequest = "request"
+	ClientAuthRequire = "require"
+)
+
+// watchInterval is the minimum time between checks of the certificate files
+// for changes.
+const watchInterval = 5 * time.Second
+
+type ServerOptions struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile is a PEM bundle used to verify client certificates.
+	ClientCAFile string
+	// ClientAuth is one of ClientAuthNone, ClientAuthRequest or
+	// ClientAuthRequire.
+	ClientAuth string
+}
+
+// Server returns a TLS configuration whose certificate and client CA bundle
+// are re-read whenever the files change on disk, so certificates can be
+// rotated without restarting the server.
+func Server(opts ServerOptions) (*tls.Config, error) {
+	clientAuth, err := parseClientAuth(opts.ClientAuth)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &watcher{opts: opts}
+	if err := w.load(); err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			cert, _ := w.current()
+			return cert, nil
+		},
+	}
+
+	// Client certificates are verified in VerifyConnection rather than by
+	// crypto/tls so that the CA pool can be swapped without rebuilding the
+	// config, which gRPC and net/http both clone.
+	switch clientAuth {
+	case tls.VerifyClientCertIfGiven:
+		cfg.ClientAuth = tls.RequestClientCert
+		cfg.VerifyConnection = w.verifyClient
+	case tls.RequireAndVerifyClientCert:
+		cfg.ClientAuth = tls.RequireAnyClientCert
+		cfg.VerifyConnection = w.verifyClient
+	}
+
+	return cfg, nil
+}
+
+type ClientOptions struct {
+	// CAFile verifies the server certificate. The system roots are used
+	// when empty.
+	CAFile string
+	// CertFile and KeyFile present a client certificate for mutual TLS.
+	CertFile   string
+	KeyFile    string
+	ServerName string
+}
+
+func Client(opts ClientOptions) (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: opts.ServerName,
+	}
+
+	if opts.CAFile != "" {
+		pool, err := loadPool(opts.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = pool
+	}
+
+	if (opts.CertFile == "") != (opts.KeyFile == "") {
+		return nil, fmt.Errorf("client certificate and key must be given together")
+	}
+	if opts.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+func ValidClientAuth(s string) error {
+	_, err := parseClientAuth(s)
+	return err
+}
+
+func parseClientAuth(s string) (tls.ClientAuthType, error) {
+	switch s {
+	case "", ClientAuthNone:
+		return tls.NoClientCert, nil
+	case ClientAuthRequest:
+		return tls.VerifyClientCertIfGiven, nil
+	case ClientAuthRequire:
+		return tls.RequireAndVerifyClientCert, nil
+	}
+	return 0, fmt.Errorf("unknown client auth mode %q (want %s, %s or %s)",
+		s, ClientAuthNone, ClientAuthRequest, ClientAuthRequire)
+}
+
+// watcher caches the parsed certificate and CA pool and reloads them when
+// the underlying files are modified.
+type watcher struct {
+	opts ServerOptions
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	pool      *x509.CertPool
+	modTimes  [3]time.Time
+	lastCheck time.Time
+}
+
+func (w *watcher) current() (*tls.Certificate, *x509.CertPool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if time.Since(w.lastCheck) >= watchInterval {
+		w.lastCheck = time.Now()
+
+		if w.modTimes != w.stat() {
+			// Keep serving the previous certificate if the new files are
+			// incomplete or invalid, e.g. halfway through being replaced.
+			_ = w.loadLocked()
+		}
+	}
+
+	return w.cert, w.pool
+}
+
+func (w *watcher) verifyClient(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return nil
+	}
+
+	_, pool := w.current()
+	if pool == nil {
+		return fmt.Errorf("tls: no client CA bundle configured")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         pool,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return fmt.Errorf("tls: invalid client certificate: %w", err)
+	}
+
+	return nil
+}
+
+func (w *watcher) load() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.loadLocked()
+}
+
+func (w *watcher) loadLocked() error {
+	modTimes := w.stat()
+
+	cert, err := tls.LoadX509KeyPair(w.opts.CertFile, w.opts.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load server certificate: %w", err)
+	}
+
+	var pool *x509.CertPool
+	if w.opts.ClientCAFile != "" {
+		pool, err = loadPool(w.opts.ClientCAFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	w.cert, w.pool, w.modTimes = &cert, pool, modTimes
+	w.lastCheck = time.Now()
+	return nil
+}
+
+func (w *watcher) stat() [3]time.Time {
+	var times [3]time.Time
+	for i, path := range []string{w.opts.CertFile, w.opts.KeyFile, w.opts.ClientCAFile} {
+		if path == "" {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil {
+			times[i] = info.ModTime()
+		}
+	}
+	return times
+}
+
+func loadPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in CA bundle %s", path)
+	}
+
+	return pool, nil
+}
@@ -0,0 +1,267 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	dir  string
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kvstore test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	ca := &testCA{cert: cert, key: key, dir: t.TempDir()}
+	writePEM(t, ca.path("ca.pem"), "CERTIFICATE", der)
+	return ca
+}
+
+func (ca *testCA) path(name string) string {
+	return filepath.Join(ca.dir, name)
+}
+
+// issue writes name.pem and name-key.pem signed by the CA.
+func (ca *testCA) issue(t *testing.T, name string, serial int64, usage x509.ExtKeyUsage) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	writePEM(t, ca.path(name+".pem"), "CERTIFICATE", der)
+	writePEM(t, ca.path(name+"-key.pem"), "EC PRIVATE KEY", keyDER)
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+}
+
+// handshake runs a TLS handshake over loopback and returns the server
+// certificate seen by the client.
+func handshake(serverCfg, clientCfg *tls.Config) (*x509.Certificate, error) {
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", serverCfg)
+	if err != nil {
+		return nil, err
+	}
+	defer ln.Close()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			serverErr <- err
+			return
+		}
+		defer conn.Close()
+		serverErr <- conn.(*tls.Conn).Handshake()
+	}()
+
+	conn, err := tls.Dial("tcp", ln.Addr().String(), clientCfg)
+	if err != nil {
+		<-serverErr
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := <-serverErr; err != nil {
+		return nil, err
+	}
+
+	return conn.ConnectionState().PeerCertificates[0], nil
+}
+
+// Test plain TLS verifies the server against the CA
+func TestServer_TLS(t *testing.T) {
+	ca := newTestCA(t)
+	ca.issue(t, "server", 2, x509.ExtKeyUsageServerAuth)
+
+	serverCfg, err := Server(ServerOptions{CertFile: ca.path("server.pem"), KeyFile: ca.path("server-key.pem")})
+	require.NoError(t, err)
+
+	clientCfg, err := Client(ClientOptions{CAFile: ca.path("ca.pem"), ServerName: "localhost"})
+	require.NoError(t, err)
+
+	cert, err := handshake(serverCfg, clientCfg)
+	require.NoError(t, err)
+	assert.Equal(t, "server", cert.Subject.CommonName)
+}
+
+// Test mutual TLS rejects clients without a certificate
+func TestServer_MutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	ca.issue(t, "server", 2, x509.ExtKeyUsageServerAuth)
+	ca.issue(t, "client", 3, x509.ExtKeyUsageClientAuth)
+
+	serverCfg, err := Server(ServerOptions{
+		CertFile:     ca.path("server.pem"),
+		KeyFile:      ca.path("server-key.pem"),
+		ClientCAFile: ca.path("ca.pem"),
+		ClientAuth:   ClientAuthRequire,
+	})
+	require.NoError(t, err)
+
+	anonymous, err := Client(ClientOptions{CAFile: ca.path("ca.pem"), ServerName: "localhost"})
+	require.NoError(t, err)
+	_, err = handshake(serverCfg, anonymous)
+	assert.Error(t, err)
+
+	authenticated, err := Client(ClientOptions{
+		CAFile:     ca.path("ca.pem"),
+		CertFile:   ca.path("client.pem"),
+		KeyFile:    ca.path("client-key.pem"),
+		ServerName: "localhost",
+	})
+	require.NoError(t, err)
+	_, err = handshake(serverCfg, authenticated)
+	assert.NoError(t, err)
+
+	// A certificate from another CA is rejected
+	other := newTestCA(t)
+	other.issue(t, "client", 5, x509.ExtKeyUsageClientAuth)
+	untrusted, err := Client(ClientOptions{
+		CAFile:     ca.path("ca.pem"),
+		CertFile:   other.path("client.pem"),
+		KeyFile:    other.path("client-key.pem"),
+		ServerName: "localhost",
+	})
+	require.NoError(t, err)
+	_, err = handshake(serverCfg, untrusted)
+	assert.Error(t, err)
+}
+
+// Test a rotated certificate is picked up without rebuilding the config
+func TestWatcher_Rotation(t *testing.T) {
+	ca := newTestCA(t)
+	ca.issue(t, "server", 2, x509.ExtKeyUsageServerAuth)
+
+	w := &watcher{opts: ServerOptions{CertFile: ca.path("server.pem"), KeyFile: ca.path("server-key.pem")}}
+	require.NoError(t, w.load())
+
+	cert, _ := w.current()
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), leaf.SerialNumber.Int64())
+
+	ca.issue(t, "server", 4, x509.ExtKeyUsageServerAuth)
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(ca.path("server.pem"), future, future))
+	w.lastCheck = time.Time{}
+
+	cert, _ = w.current()
+	leaf, err = x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), leaf.SerialNumber.Int64())
+
+	// A broken replacement keeps the last good certificate
+	require.NoError(t, os.WriteFile(ca.path("server.pem"), []byte("garbage"), 0o600))
+	later := future.Add(time.Minute)
+	require.NoError(t, os.Chtimes(ca.path("server.pem"), later, later))
+	w.lastCheck = time.Time{}
+
+	cert, _ = w.current()
+	leaf, err = x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), leaf.SerialNumber.Int64())
+}
+
+// Test the configs work with gRPC, which clones them and requires ALPN
+func TestServer_GRPC(t *testing.T) {
+	ca := newTestCA(t)
+	ca.issue(t, "server", 2, x509.ExtKeyUsageServerAuth)
+	ca.issue(t, "client", 3, x509.ExtKeyUsageClientAuth)
+
+	serverCfg, err := Server(ServerOptions{
+		CertFile:     ca.path("server.pem"),
+		KeyFile:      ca.path("server-key.pem"),
+		ClientCAFile: ca.path("ca.pem"),
+		ClientAuth:   ClientAuthRequire,
+	})
+	require.NoError(t, err)
+
+	srv := grpc.NewServer(grpc.Creds(credentials.NewTLS(serverCfg)))
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go srv.Serve(ln)
+	defer srv.Stop()
+
+	clientCfg, err := Client(ClientOptions{
+		CAFile:     ca.path("ca.pem"),
+		CertFile:   ca.path("client.pem"),
+		KeyFile:    ca.path("client-key.pem"),
+		ServerName: "localhost",
+	})
+	require.NoError(t, err)
+
+	conn, err := grpc.NewClient(ln.Addr().String(), grpc.WithTransportCredentials(credentials.NewTLS(clientCfg)))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+}
+
+func TestParseClientAuth(t *testing.T) {
+	assert.NoError(t, ValidClientAuth(""))
+	assert.NoError(t, ValidClientAuth(ClientAuthRequire))
+	assert.Error(t, ValidClientAuth("always"))
+}
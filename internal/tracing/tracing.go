@@ -0,0 +1,141 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Exporters accepted in Options.Exporter.
+const (
+	// ExporterNone disables tracing.
+	ExporterNone = "none"
+	// ExporterOTLP sends spans over OTLP/gRPC to a collector.
+	ExporterOTLP = "otlp"
+	// ExporterStdout writes spans as JSON to stdout, for local debugging.
+	ExporterStdout = "stdout"
+	// ExporterFile appends spans as JSON to a file, for local debugging.
+	ExporterFile = "file"
+)
+
+type Options struct {
+	Exporter string
+	// Endpoint is the OTLP collector address, host:port. Empty uses the
+	// OTEL_EXPORTER_OTLP_* environment variables or localhost:4317.
+	Endpoint string
+	// Insecure disables TLS to the OTLP collector.
+	Insecure bool
+	// File is the path written by the file exporter.
+	File string
+	// SampleRatio is the fraction of new traces recorded. Traces started by
+	// a caller follow the caller's sampling decision.
+	SampleRatio float64
+}
+
+func ValidExporter(exporter string) error {
+	switch exporter {
+	case ExporterNone, ExporterOTLP, ExporterStdout, ExporterFile:
+		return nil
+	}
+	return fmt.Errorf("unknown exporter %q (want %s, %s, %s or %s)",
+		exporter, ExporterNone, ExporterOTLP, ExporterStdout, ExporterFile)
+}
+
+// Setup installs the global TracerProvider and the W3C trace context
+// propagator, which carries traces across gRPC metadata and HTTP headers.
+// The returned function flushes pending spans and must be called on exit.
+// With ExporterNone the propagator is still installed, so the server keeps
+// forwarding the trace context of its callers.
+func Setup(ctx context.Context, service string, opts Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if opts.Exporter == "" || opts.Exporter == ExporterNone {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, closeOutput, err := newExporter(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(service)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		return errors.Join(provider.Shutdown(ctx), closeOutput())
+	}, nil
+}
+
+func newExporter(ctx context.Context, opts Options) (sdktrace.SpanExporter, func() error, error) {
+	noClose := func() error { return nil }
+
+	switch opts.Exporter {
+	case ExporterOTLP:
+		var clientOpts []otlptracegrpc.Option
+		if opts.Endpoint != "" {
+			clientOpts = append(clientOpts, otlptracegrpc.WithEndpoint(opts.Endpoint))
+		}
+		if opts.Insecure {
+			clientOpts = append(clientOpts, otlptracegrpc.WithInsecure())
+		}
+
+		exporter, err := otlptracegrpc.New(ctx, clientOpts...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		}
+		return exporter, noClose, nil
+
+	case ExporterStdout:
+		exporter, err := newWriterExporter(os.Stdout)
+		return exporter, noClose, err
+
+	case ExporterFile:
+		if opts.File == "" {
+			return nil, nil, errors.New("file exporter requires a file path")
+		}
+
+		f, err := os.OpenFile(opts.File, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open trace file: %w", err)
+		}
+
+		exporter, err := newWriterExporter(f)
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		return exporter, f.Close, nil
+	}
+
+	return nil, nil, ValidExporter(opts.Exporter)
+}
+
+func newWriterExporter(w io.Writer) (sdktrace.SpanExporter, error) {
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(w))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+	return exporter, nil
+}
@@ -0,0 +1,52 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Test the file exporter writes spans on shutdown
+func TestSetup_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.json")
+
+	shutdown, err := Setup(context.Background(), "kvstore-test", Options{Exporter: ExporterFile, File: path, SampleRatio: 1})
+	require.NoError(t, err)
+
+	_, span := otel.Tracer("test").Start(context.Background(), "work")
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"Name":"work"`)
+	assert.Contains(t, string(data), "kvstore-test")
+}
+
+// Test the propagator is installed even with tracing disabled
+func TestSetup_None(t *testing.T) {
+	shutdown, err := Setup(context.Background(), "kvstore-test", Options{Exporter: ExporterNone})
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.Background()))
+
+	carrier := propagation.MapCarrier{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), carrier)
+
+	out := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, out)
+	assert.Equal(t, carrier["traceparent"], out["traceparent"])
+}
+
+func TestSetup_Invalid(t *testing.T) {
+	_, err := Setup(context.Background(), "kvstore-test", Options{Exporter: "jaeger"})
+	assert.ErrorContains(t, err, `unknown exporter "jaeger"`)
+
+	_, err = Setup(context.Background(), "kvstore-test", Options{Exporter: ExporterFile})
+	assert.ErrorContains(t, err, "requires a file path")
+}
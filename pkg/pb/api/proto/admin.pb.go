@@ -0,0 +1,3290 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: api/proto/admin.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type MigrationState int32
+
+const (
+	// Not migrating; the node routes by its current map.
+	MigrationState_MIGRATION_STATE_IDLE MigrationState = 0
+	// Handing over keys.
+	MigrationState_MIGRATION_STATE_MIGRATING MigrationState = 1
+	// Every moving key has been handed over; waiting for CommitMigration.
+	MigrationState_MIGRATION_STATE_DONE MigrationState = 2
+)
+
+// Enum value maps for MigrationState.
+var (
+	MigrationState_name = map[int32]string{
+		0: "MIGRATION_STATE_IDLE",
+		1: "MIGRATION_STATE_MIGRATING",
+		2: "MIGRATION_STATE_DONE",
+	}
+	MigrationState_value = map[string]int32{
+		"MIGRATION_STATE_IDLE":      0,
+		"MIGRATION_STATE_MIGRATING": 1,
+		"MIGRATION_STATE_DONE":      2,
+	}
+)
+
+func (x MigrationState) Enum() *MigrationState {
+	p := new(MigrationState)
+	*p = x
+	return p
+}
+
+func (x MigrationState) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (MigrationState) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_proto_admin_proto_enumTypes[0].Descriptor()
+}
+
+func (MigrationState) Type() protoreflect.EnumType {
+	return &file_api_proto_admin_proto_enumTypes[0]
+}
+
+func (x MigrationState) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use MigrationState.Descriptor instead.
+func (MigrationState) EnumDescriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{0}
+}
+
+type ClusterMember_Role int32
+
+const (
+	ClusterMember_ROLE_UNSPECIFIED ClusterMember_Role = 0
+	ClusterMember_ROLE_VOTER       ClusterMember_Role = 1
+	ClusterMember_ROLE_LEARNER     ClusterMember_Role = 2
+)
+
+// Enum value maps for ClusterMember_Role.
+var (
+	ClusterMember_Role_name = map[int32]string{
+		0: "ROLE_UNSPECIFIED",
+		1: "ROLE_VOTER",
+		2: "ROLE_LEARNER",
+	}
+	ClusterMember_Role_value = map[string]int32{
+		"ROLE_UNSPECIFIED": 0,
+		"ROLE_VOTER":       1,
+		"ROLE_LEARNER":     2,
+	}
+)
+
+func (x ClusterMember_Role) Enum() *ClusterMember_Role {
+	p := new(ClusterMember_Role)
+	*p = x
+	return p
+}
+
+func (x ClusterMember_Role) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ClusterMember_Role) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_proto_admin_proto_enumTypes[1].Descriptor()
+}
+
+func (ClusterMember_Role) Type() protoreflect.EnumType {
+	return &file_api_proto_admin_proto_enumTypes[1]
+}
+
+func (x ClusterMember_Role) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ClusterMember_Role.Descriptor instead.
+func (ClusterMember_Role) EnumDescriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{21, 0}
+}
+
+type GetReplicationStatusResponse_Role int32
+
+const (
+	GetReplicationStatusResponse_ROLE_UNSPECIFIED GetReplicationStatusResponse_Role = 0
+	GetReplicationStatusResponse_ROLE_PRIMARY     GetReplicationStatusResponse_Role = 1
+	GetReplicationStatusResponse_ROLE_REPLICA     GetReplicationStatusResponse_Role = 2
+	GetReplicationStatusResponse_ROLE_ACTIVE      GetReplicationStatusResponse_Role = 3
+)
+
+// Enum value maps for GetReplicationStatusResponse_Role.
+var (
+	GetReplicationStatusResponse_Role_name = map[int32]string{
+		0: "ROLE_UNSPECIFIED",
+		1: "ROLE_PRIMARY",
+		2: "ROLE_REPLICA",
+		3: "ROLE_ACTIVE",
+	}
+	GetReplicationStatusResponse_Role_value = map[string]int32{
+		"ROLE_UNSPECIFIED": 0,
+		"ROLE_PRIMARY":     1,
+		"ROLE_REPLICA":     2,
+		"ROLE_ACTIVE":      3,
+	}
+)
+
+func (x GetReplicationStatusResponse_Role) Enum() *GetReplicationStatusResponse_Role {
+	p := new(GetReplicationStatusResponse_Role)
+	*p = x
+	return p
+}
+
+func (x GetReplicationStatusResponse_Role) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (GetReplicationStatusResponse_Role) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_proto_admin_proto_enumTypes[2].Descriptor()
+}
+
+func (GetReplicationStatusResponse_Role) Type() protoreflect.EnumType {
+	return &file_api_proto_admin_proto_enumTypes[2]
+}
+
+func (x GetReplicationStatusResponse_Role) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use GetReplicationStatusResponse_Role.Descriptor instead.
+func (GetReplicationStatusResponse_Role) EnumDescriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{31, 0}
+}
+
+type ReloadConfigRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReloadConfigRequest) Reset() {
+	*x = ReloadConfigRequest{}
+	mi := &file_api_proto_admin_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReloadConfigRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReloadConfigRequest) ProtoMessage() {}
+
+func (x *ReloadConfigRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReloadConfigRequest.ProtoReflect.Descriptor instead.
+func (*ReloadConfigRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{0}
+}
+
+type ReloadConfigResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Settings that changed and are now in effect.
+	Applied []string `protobuf:"bytes,1,rep,name=applied,proto3" json:"applied,omitempty"`
+	// Settings that changed but only take effect after a restart.
+	RequiresRestart []string `protobuf:"bytes,2,rep,name=requires_restart,json=requiresRestart,proto3" json:"requires_restart,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ReloadConfigResponse) Reset() {
+	*x = ReloadConfigResponse{}
+	mi := &file_api_proto_admin_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReloadConfigResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReloadConfigResponse) ProtoMessage() {}
+
+func (x *ReloadConfigResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReloadConfigResponse.ProtoReflect.Descriptor instead.
+func (*ReloadConfigResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ReloadConfigResponse) GetApplied() []string {
+	if x != nil {
+		return x.Applied
+	}
+	return nil
+}
+
+func (x *ReloadConfigResponse) GetRequiresRestart() []string {
+	if x != nil {
+		return x.RequiresRestart
+	}
+	return nil
+}
+
+// Quota limits are unlimited when zero. Sizes count key plus value bytes.
+type Quota struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MaxKeys       int64                  `protobuf:"varint,1,opt,name=max_keys,json=maxKeys,proto3" json:"max_keys,omitempty"`
+	MaxBytes      int64                  `protobuf:"varint,2,opt,name=max_bytes,json=maxBytes,proto3" json:"max_bytes,omitempty"`
+	MaxValueBytes int64                  `protobuf:"varint,3,opt,name=max_value_bytes,json=maxValueBytes,proto3" json:"max_value_bytes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Quota) Reset() {
+	*x = Quota{}
+	mi := &file_api_proto_admin_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Quota) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Quota) ProtoMessage() {}
+
+func (x *Quota) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Quota.ProtoReflect.Descriptor instead.
+func (*Quota) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Quota) GetMaxKeys() int64 {
+	if x != nil {
+		return x.MaxKeys
+	}
+	return 0
+}
+
+func (x *Quota) GetMaxBytes() int64 {
+	if x != nil {
+		return x.MaxBytes
+	}
+	return 0
+}
+
+func (x *Quota) GetMaxValueBytes() int64 {
+	if x != nil {
+		return x.MaxValueBytes
+	}
+	return 0
+}
+
+// Usage includes expired keys that have not been removed yet.
+type Usage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Keys          int64                  `protobuf:"varint,1,opt,name=keys,proto3" json:"keys,omitempty"`
+	Bytes         int64                  `protobuf:"varint,2,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Usage) Reset() {
+	*x = Usage{}
+	mi := &file_api_proto_admin_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Usage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Usage) ProtoMessage() {}
+
+func (x *Usage) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Usage.ProtoReflect.Descriptor instead.
+func (*Usage) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Usage) GetKeys() int64 {
+	if x != nil {
+		return x.Keys
+	}
+	return 0
+}
+
+func (x *Usage) GetBytes() int64 {
+	if x != nil {
+		return x.Bytes
+	}
+	return 0
+}
+
+type Namespace struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Name  string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Applied to writes that do not set a TTL. Zero keeps keys forever.
+	DefaultTtlSeconds int64  `protobuf:"varint,2,opt,name=default_ttl_seconds,json=defaultTtlSeconds,proto3" json:"default_ttl_seconds,omitempty"`
+	Quota             *Quota `protobuf:"bytes,5,opt,name=quota,proto3" json:"quota,omitempty"`
+	Usage             *Usage `protobuf:"bytes,6,opt,name=usage,proto3" json:"usage,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *Namespace) Reset() {
+	*x = Namespace{}
+	mi := &file_api_proto_admin_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Namespace) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Namespace) ProtoMessage() {}
+
+func (x *Namespace) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Namespace.ProtoReflect.Descriptor instead.
+func (*Namespace) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Namespace) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Namespace) GetDefaultTtlSeconds() int64 {
+	if x != nil {
+		return x.DefaultTtlSeconds
+	}
+	return 0
+}
+
+func (x *Namespace) GetQuota() *Quota {
+	if x != nil {
+		return x.Quota
+	}
+	return nil
+}
+
+func (x *Namespace) GetUsage() *Usage {
+	if x != nil {
+		return x.Usage
+	}
+	return nil
+}
+
+type PrincipalUsage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Principal     string                 `protobuf:"bytes,1,opt,name=principal,proto3" json:"principal,omitempty"`
+	Quota         *Quota                 `protobuf:"bytes,2,opt,name=quota,proto3" json:"quota,omitempty"`
+	Usage         *Usage                 `protobuf:"bytes,3,opt,name=usage,proto3" json:"usage,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PrincipalUsage) Reset() {
+	*x = PrincipalUsage{}
+	mi := &file_api_proto_admin_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PrincipalUsage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PrincipalUsage) ProtoMessage() {}
+
+func (x *PrincipalUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PrincipalUsage.ProtoReflect.Descriptor instead.
+func (*PrincipalUsage) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *PrincipalUsage) GetPrincipal() string {
+	if x != nil {
+		return x.Principal
+	}
+	return ""
+}
+
+func (x *PrincipalUsage) GetQuota() *Quota {
+	if x != nil {
+		return x.Quota
+	}
+	return nil
+}
+
+func (x *PrincipalUsage) GetUsage() *Usage {
+	if x != nil {
+		return x.Usage
+	}
+	return nil
+}
+
+type CreateNamespaceRequest struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Name              string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	DefaultTtlSeconds int64                  `protobuf:"varint,2,opt,name=default_ttl_seconds,json=defaultTtlSeconds,proto3" json:"default_ttl_seconds,omitempty"`
+	Quota             *Quota                 `protobuf:"bytes,4,opt,name=quota,proto3" json:"quota,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *CreateNamespaceRequest) Reset() {
+	*x = CreateNamespaceRequest{}
+	mi := &file_api_proto_admin_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateNamespaceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateNamespaceRequest) ProtoMessage() {}
+
+func (x *CreateNamespaceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateNamespaceRequest.ProtoReflect.Descriptor instead.
+func (*CreateNamespaceRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *CreateNamespaceRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CreateNamespaceRequest) GetDefaultTtlSeconds() int64 {
+	if x != nil {
+		return x.DefaultTtlSeconds
+	}
+	return 0
+}
+
+func (x *CreateNamespaceRequest) GetQuota() *Quota {
+	if x != nil {
+		return x.Quota
+	}
+	return nil
+}
+
+type CreateNamespaceResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Namespace     *Namespace             `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateNamespaceResponse) Reset() {
+	*x = CreateNamespaceResponse{}
+	mi := &file_api_proto_admin_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateNamespaceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateNamespaceResponse) ProtoMessage() {}
+
+func (x *CreateNamespaceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateNamespaceResponse.ProtoReflect.Descriptor instead.
+func (*CreateNamespaceResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *CreateNamespaceResponse) GetNamespace() *Namespace {
+	if x != nil {
+		return x.Namespace
+	}
+	return nil
+}
+
+type DropNamespaceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DropNamespaceRequest) Reset() {
+	*x = DropNamespaceRequest{}
+	mi := &file_api_proto_admin_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DropNamespaceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DropNamespaceRequest) ProtoMessage() {}
+
+func (x *DropNamespaceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DropNamespaceRequest.ProtoReflect.Descriptor instead.
+func (*DropNamespaceRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *DropNamespaceRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type DropNamespaceResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DropNamespaceResponse) Reset() {
+	*x = DropNamespaceResponse{}
+	mi := &file_api_proto_admin_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DropNamespaceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DropNamespaceResponse) ProtoMessage() {}
+
+func (x *DropNamespaceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DropNamespaceResponse.ProtoReflect.Descriptor instead.
+func (*DropNamespaceResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{9}
+}
+
+type ListNamespacesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListNamespacesRequest) Reset() {
+	*x = ListNamespacesRequest{}
+	mi := &file_api_proto_admin_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListNamespacesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListNamespacesRequest) ProtoMessage() {}
+
+func (x *ListNamespacesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListNamespacesRequest.ProtoReflect.Descriptor instead.
+func (*ListNamespacesRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{10}
+}
+
+type ListNamespacesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Namespaces    []*Namespace           `protobuf:"bytes,1,rep,name=namespaces,proto3" json:"namespaces,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListNamespacesResponse) Reset() {
+	*x = ListNamespacesResponse{}
+	mi := &file_api_proto_admin_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListNamespacesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListNamespacesResponse) ProtoMessage() {}
+
+func (x *ListNamespacesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListNamespacesResponse.ProtoReflect.Descriptor instead.
+func (*ListNamespacesResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ListNamespacesResponse) GetNamespaces() []*Namespace {
+	if x != nil {
+		return x.Namespaces
+	}
+	return nil
+}
+
+type SetNamespaceQuotaRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Quota         *Quota                 `protobuf:"bytes,2,opt,name=quota,proto3" json:"quota,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetNamespaceQuotaRequest) Reset() {
+	*x = SetNamespaceQuotaRequest{}
+	mi := &file_api_proto_admin_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetNamespaceQuotaRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetNamespaceQuotaRequest) ProtoMessage() {}
+
+func (x *SetNamespaceQuotaRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetNamespaceQuotaRequest.ProtoReflect.Descriptor instead.
+func (*SetNamespaceQuotaRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *SetNamespaceQuotaRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *SetNamespaceQuotaRequest) GetQuota() *Quota {
+	if x != nil {
+		return x.Quota
+	}
+	return nil
+}
+
+type SetNamespaceQuotaResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Namespace     *Namespace             `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetNamespaceQuotaResponse) Reset() {
+	*x = SetNamespaceQuotaResponse{}
+	mi := &file_api_proto_admin_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetNamespaceQuotaResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetNamespaceQuotaResponse) ProtoMessage() {}
+
+func (x *SetNamespaceQuotaResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetNamespaceQuotaResponse.ProtoReflect.Descriptor instead.
+func (*SetNamespaceQuotaResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *SetNamespaceQuotaResponse) GetNamespace() *Namespace {
+	if x != nil {
+		return x.Namespace
+	}
+	return nil
+}
+
+// GetUsageRequest selects one namespace or principal. When both are empty,
+// every namespace and every principal with a quota or data is reported.
+type GetUsageRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Namespace     string                 `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Principal     string                 `protobuf:"bytes,2,opt,name=principal,proto3" json:"principal,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetUsageRequest) Reset() {
+	*x = GetUsageRequest{}
+	mi := &file_api_proto_admin_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUsageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUsageRequest) ProtoMessage() {}
+
+func (x *GetUsageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUsageRequest.ProtoReflect.Descriptor instead.
+func (*GetUsageRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *GetUsageRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *GetUsageRequest) GetPrincipal() string {
+	if x != nil {
+		return x.Principal
+	}
+	return ""
+}
+
+type GetUsageResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Namespaces    []*Namespace           `protobuf:"bytes,1,rep,name=namespaces,proto3" json:"namespaces,omitempty"`
+	Principals    []*PrincipalUsage      `protobuf:"bytes,2,rep,name=principals,proto3" json:"principals,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetUsageResponse) Reset() {
+	*x = GetUsageResponse{}
+	mi := &file_api_proto_admin_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUsageResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUsageResponse) ProtoMessage() {}
+
+func (x *GetUsageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUsageResponse.ProtoReflect.Descriptor instead.
+func (*GetUsageResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *GetUsageResponse) GetNamespaces() []*Namespace {
+	if x != nil {
+		return x.Namespaces
+	}
+	return nil
+}
+
+func (x *GetUsageResponse) GetPrincipals() []*PrincipalUsage {
+	if x != nil {
+		return x.Principals
+	}
+	return nil
+}
+
+type SlowOperation struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Increases with every slow operation recorded since the server started.
+	Id        int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	StartTime *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
+	Duration  *durationpb.Duration   `protobuf:"bytes,3,opt,name=duration,proto3" json:"duration,omitempty"`
+	// Full gRPC method name, such as /kvstore.v1.KVStore/Get.
+	Method    string `protobuf:"bytes,4,opt,name=method,proto3" json:"method,omitempty"`
+	Namespace string `protobuf:"bytes,5,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	// The key or List prefix, redacted when the server redacts keys in logs.
+	Key string `protobuf:"bytes,6,opt,name=key,proto3" json:"key,omitempty"`
+	// gRPC status code name, such as OK or NotFound.
+	Code          string `protobuf:"bytes,7,opt,name=code,proto3" json:"code,omitempty"`
+	Principal     string `protobuf:"bytes,8,opt,name=principal,proto3" json:"principal,omitempty"`
+	Peer          string `protobuf:"bytes,9,opt,name=peer,proto3" json:"peer,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SlowOperation) Reset() {
+	*x = SlowOperation{}
+	mi := &file_api_proto_admin_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SlowOperation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SlowOperation) ProtoMessage() {}
+
+func (x *SlowOperation) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SlowOperation.ProtoReflect.Descriptor instead.
+func (*SlowOperation) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *SlowOperation) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *SlowOperation) GetStartTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartTime
+	}
+	return nil
+}
+
+func (x *SlowOperation) GetDuration() *durationpb.Duration {
+	if x != nil {
+		return x.Duration
+	}
+	return nil
+}
+
+func (x *SlowOperation) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *SlowOperation) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *SlowOperation) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *SlowOperation) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *SlowOperation) GetPrincipal() string {
+	if x != nil {
+		return x.Principal
+	}
+	return ""
+}
+
+func (x *SlowOperation) GetPeer() string {
+	if x != nil {
+		return x.Peer
+	}
+	return ""
+}
+
+type GetSlowLogRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Maximum number of operations to return. Zero returns all of them.
+	Limit         int32 `protobuf:"varint,1,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetSlowLogRequest) Reset() {
+	*x = GetSlowLogRequest{}
+	mi := &file_api_proto_admin_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetSlowLogRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSlowLogRequest) ProtoMessage() {}
+
+func (x *GetSlowLogRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSlowLogRequest.ProtoReflect.Descriptor instead.
+func (*GetSlowLogRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *GetSlowLogRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type GetSlowLogResponse struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Operations []*SlowOperation       `protobuf:"bytes,1,rep,name=operations,proto3" json:"operations,omitempty"`
+	// Threshold in effect; operations at least this slow are recorded.
+	Threshold     *durationpb.Duration `protobuf:"bytes,2,opt,name=threshold,proto3" json:"threshold,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetSlowLogResponse) Reset() {
+	*x = GetSlowLogResponse{}
+	mi := &file_api_proto_admin_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetSlowLogResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSlowLogResponse) ProtoMessage() {}
+
+func (x *GetSlowLogResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSlowLogResponse.ProtoReflect.Descriptor instead.
+func (*GetSlowLogResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *GetSlowLogResponse) GetOperations() []*SlowOperation {
+	if x != nil {
+		return x.Operations
+	}
+	return nil
+}
+
+func (x *GetSlowLogResponse) GetThreshold() *durationpb.Duration {
+	if x != nil {
+		return x.Threshold
+	}
+	return nil
+}
+
+type ResetSlowLogRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResetSlowLogRequest) Reset() {
+	*x = ResetSlowLogRequest{}
+	mi := &file_api_proto_admin_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResetSlowLogRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResetSlowLogRequest) ProtoMessage() {}
+
+func (x *ResetSlowLogRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResetSlowLogRequest.ProtoReflect.Descriptor instead.
+func (*ResetSlowLogRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{19}
+}
+
+type ResetSlowLogResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResetSlowLogResponse) Reset() {
+	*x = ResetSlowLogResponse{}
+	mi := &file_api_proto_admin_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResetSlowLogResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResetSlowLogResponse) ProtoMessage() {}
+
+func (x *ResetSlowLogResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResetSlowLogResponse.ProtoReflect.Descriptor instead.
+func (*ResetSlowLogResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{20}
+}
+
+type ClusterMember struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Id       string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	RaftAddr string                 `protobuf:"bytes,2,opt,name=raft_addr,json=raftAddr,proto3" json:"raft_addr,omitempty"`
+	// Empty for members added before their gRPC address was known.
+	GrpcAddr      string             `protobuf:"bytes,3,opt,name=grpc_addr,json=grpcAddr,proto3" json:"grpc_addr,omitempty"`
+	Role          ClusterMember_Role `protobuf:"varint,4,opt,name=role,proto3,enum=kvstore.v1.ClusterMember_Role" json:"role,omitempty"`
+	Leader        bool               `protobuf:"varint,5,opt,name=leader,proto3" json:"leader,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ClusterMember) Reset() {
+	*x = ClusterMember{}
+	mi := &file_api_proto_admin_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClusterMember) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClusterMember) ProtoMessage() {}
+
+func (x *ClusterMember) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClusterMember.ProtoReflect.Descriptor instead.
+func (*ClusterMember) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ClusterMember) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ClusterMember) GetRaftAddr() string {
+	if x != nil {
+		return x.RaftAddr
+	}
+	return ""
+}
+
+func (x *ClusterMember) GetGrpcAddr() string {
+	if x != nil {
+		return x.GrpcAddr
+	}
+	return ""
+}
+
+func (x *ClusterMember) GetRole() ClusterMember_Role {
+	if x != nil {
+		return x.Role
+	}
+	return ClusterMember_ROLE_UNSPECIFIED
+}
+
+func (x *ClusterMember) GetLeader() bool {
+	if x != nil {
+		return x.Leader
+	}
+	return false
+}
+
+type Membership struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Members []*ClusterMember       `protobuf:"bytes,1,rep,name=members,proto3" json:"members,omitempty"`
+	// Log index of the configuration.
+	Index         uint64 `protobuf:"varint,2,opt,name=index,proto3" json:"index,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Membership) Reset() {
+	*x = Membership{}
+	mi := &file_api_proto_admin_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Membership) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Membership) ProtoMessage() {}
+
+func (x *Membership) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Membership.ProtoReflect.Descriptor instead.
+func (*Membership) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *Membership) GetMembers() []*ClusterMember {
+	if x != nil {
+		return x.Members
+	}
+	return nil
+}
+
+func (x *Membership) GetIndex() uint64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+type GetMembershipRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMembershipRequest) Reset() {
+	*x = GetMembershipRequest{}
+	mi := &file_api_proto_admin_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMembershipRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMembershipRequest) ProtoMessage() {}
+
+func (x *GetMembershipRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMembershipRequest.ProtoReflect.Descriptor instead.
+func (*GetMembershipRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{23}
+}
+
+type GetMembershipResponse struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Membership *Membership            `protobuf:"bytes,1,opt,name=membership,proto3" json:"membership,omitempty"`
+	// The node that served the call, and its Raft state: Leader, Follower or
+	// Candidate.
+	NodeId        string `protobuf:"bytes,2,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	State         string `protobuf:"bytes,3,opt,name=state,proto3" json:"state,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMembershipResponse) Reset() {
+	*x = GetMembershipResponse{}
+	mi := &file_api_proto_admin_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMembershipResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMembershipResponse) ProtoMessage() {}
+
+func (x *GetMembershipResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMembershipResponse.ProtoReflect.Descriptor instead.
+func (*GetMembershipResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *GetMembershipResponse) GetMembership() *Membership {
+	if x != nil {
+		return x.Membership
+	}
+	return nil
+}
+
+func (x *GetMembershipResponse) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *GetMembershipResponse) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+type AddLearnerRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	RaftAddr      string                 `protobuf:"bytes,2,opt,name=raft_addr,json=raftAddr,proto3" json:"raft_addr,omitempty"`
+	GrpcAddr      string                 `protobuf:"bytes,3,opt,name=grpc_addr,json=grpcAddr,proto3" json:"grpc_addr,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddLearnerRequest) Reset() {
+	*x = AddLearnerRequest{}
+	mi := &file_api_proto_admin_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddLearnerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddLearnerRequest) ProtoMessage() {}
+
+func (x *AddLearnerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddLearnerRequest.ProtoReflect.Descriptor instead.
+func (*AddLearnerRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *AddLearnerRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *AddLearnerRequest) GetRaftAddr() string {
+	if x != nil {
+		return x.RaftAddr
+	}
+	return ""
+}
+
+func (x *AddLearnerRequest) GetGrpcAddr() string {
+	if x != nil {
+		return x.GrpcAddr
+	}
+	return ""
+}
+
+type PromoteLearnerRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PromoteLearnerRequest) Reset() {
+	*x = PromoteLearnerRequest{}
+	mi := &file_api_proto_admin_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PromoteLearnerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PromoteLearnerRequest) ProtoMessage() {}
+
+func (x *PromoteLearnerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PromoteLearnerRequest.ProtoReflect.Descriptor instead.
+func (*PromoteLearnerRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *PromoteLearnerRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type RemoveNodeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveNodeRequest) Reset() {
+	*x = RemoveNodeRequest{}
+	mi := &file_api_proto_admin_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveNodeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveNodeRequest) ProtoMessage() {}
+
+func (x *RemoveNodeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveNodeRequest.ProtoReflect.Descriptor instead.
+func (*RemoveNodeRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *RemoveNodeRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type TransferLeadershipRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Voter to hand over to. Empty picks the most up-to-date one.
+	Id            string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransferLeadershipRequest) Reset() {
+	*x = TransferLeadershipRequest{}
+	mi := &file_api_proto_admin_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransferLeadershipRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransferLeadershipRequest) ProtoMessage() {}
+
+func (x *TransferLeadershipRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransferLeadershipRequest.ProtoReflect.Descriptor instead.
+func (*TransferLeadershipRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *TransferLeadershipRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type MembershipChangeResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Configuration after the change, as seen by the node that made it.
+	Membership    *Membership `protobuf:"bytes,1,opt,name=membership,proto3" json:"membership,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MembershipChangeResponse) Reset() {
+	*x = MembershipChangeResponse{}
+	mi := &file_api_proto_admin_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MembershipChangeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MembershipChangeResponse) ProtoMessage() {}
+
+func (x *MembershipChangeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MembershipChangeResponse.ProtoReflect.Descriptor instead.
+func (*MembershipChangeResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *MembershipChangeResponse) GetMembership() *Membership {
+	if x != nil {
+		return x.Membership
+	}
+	return nil
+}
+
+type GetReplicationStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetReplicationStatusRequest) Reset() {
+	*x = GetReplicationStatusRequest{}
+	mi := &file_api_proto_admin_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetReplicationStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetReplicationStatusRequest) ProtoMessage() {}
+
+func (x *GetReplicationStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetReplicationStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetReplicationStatusRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{30}
+}
+
+type GetReplicationStatusResponse struct {
+	state protoimpl.MessageState            `protogen:"open.v1"`
+	Role  GetReplicationStatusResponse_Role `protobuf:"varint,1,opt,name=role,proto3,enum=kvstore.v1.GetReplicationStatusResponse_Role" json:"role,omitempty"`
+	// The mutation log the server writes (primary or active site) or follows
+	// (replica). It changes whenever the server writing it restarts.
+	LogId string `protobuf:"bytes,2,opt,name=log_id,json=logId,proto3" json:"log_id,omitempty"`
+	// Last mutation the server has applied, or an active site has made.
+	Offset uint64 `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"`
+	// Primary only: connected replicas.
+	Replicas []*ReplicaStatus `protobuf:"bytes,4,rep,name=replicas,proto3" json:"replicas,omitempty"`
+	// Replica only.
+	PrimaryAddr string `protobuf:"bytes,5,opt,name=primary_addr,json=primaryAddr,proto3" json:"primary_addr,omitempty"`
+	Connected   bool   `protobuf:"varint,6,opt,name=connected,proto3" json:"connected,omitempty"`
+	// When the replica last heard from the primary; unset if never.
+	LastContact *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=last_contact,json=lastContact,proto3" json:"last_contact,omitempty"`
+	// Last offset the primary reported; offset lags behind it by what is
+	// still in flight.
+	PrimaryOffset uint64 `protobuf:"varint,8,opt,name=primary_offset,json=primaryOffset,proto3" json:"primary_offset,omitempty"`
+	// How many times the replica replaced its state with a snapshot.
+	Resyncs uint64 `protobuf:"varint,9,opt,name=resyncs,proto3" json:"resyncs,omitempty"`
+	// When anti-entropy last compared the replica with the primary; unset if
+	// never.
+	LastRepair *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=last_repair,json=lastRepair,proto3" json:"last_repair,omitempty"`
+	// Key ranges and keys anti-entropy found diverged and repaired so far.
+	RangesRepaired uint64 `protobuf:"varint,11,opt,name=ranges_repaired,json=rangesRepaired,proto3" json:"ranges_repaired,omitempty"`
+	KeysRepaired   uint64 `protobuf:"varint,12,opt,name=keys_repaired,json=keysRepaired,proto3" json:"keys_repaired,omitempty"`
+	// Why the last anti-entropy pass failed, empty if it succeeded.
+	LastRepairError string `protobuf:"bytes,13,opt,name=last_repair_error,json=lastRepairError,proto3" json:"last_repair_error,omitempty"`
+	// Active site only.
+	SiteId        string            `protobuf:"bytes,14,opt,name=site_id,json=siteId,proto3" json:"site_id,omitempty"`
+	Peers         []*PeerSiteStatus `protobuf:"bytes,15,rep,name=peers,proto3" json:"peers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetReplicationStatusResponse) Reset() {
+	*x = GetReplicationStatusResponse{}
+	mi := &file_api_proto_admin_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetReplicationStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetReplicationStatusResponse) ProtoMessage() {}
+
+func (x *GetReplicationStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetReplicationStatusResponse.ProtoReflect.Descriptor instead.
+func (*GetReplicationStatusResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *GetReplicationStatusResponse) GetRole() GetReplicationStatusResponse_Role {
+	if x != nil {
+		return x.Role
+	}
+	return GetReplicationStatusResponse_ROLE_UNSPECIFIED
+}
+
+func (x *GetReplicationStatusResponse) GetLogId() string {
+	if x != nil {
+		return x.LogId
+	}
+	return ""
+}
+
+func (x *GetReplicationStatusResponse) GetOffset() uint64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *GetReplicationStatusResponse) GetReplicas() []*ReplicaStatus {
+	if x != nil {
+		return x.Replicas
+	}
+	return nil
+}
+
+func (x *GetReplicationStatusResponse) GetPrimaryAddr() string {
+	if x != nil {
+		return x.PrimaryAddr
+	}
+	return ""
+}
+
+func (x *GetReplicationStatusResponse) GetConnected() bool {
+	if x != nil {
+		return x.Connected
+	}
+	return false
+}
+
+func (x *GetReplicationStatusResponse) GetLastContact() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastContact
+	}
+	return nil
+}
+
+func (x *GetReplicationStatusResponse) GetPrimaryOffset() uint64 {
+	if x != nil {
+		return x.PrimaryOffset
+	}
+	return 0
+}
+
+func (x *GetReplicationStatusResponse) GetResyncs() uint64 {
+	if x != nil {
+		return x.Resyncs
+	}
+	return 0
+}
+
+func (x *GetReplicationStatusResponse) GetLastRepair() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastRepair
+	}
+	return nil
+}
+
+func (x *GetReplicationStatusResponse) GetRangesRepaired() uint64 {
+	if x != nil {
+		return x.RangesRepaired
+	}
+	return 0
+}
+
+func (x *GetReplicationStatusResponse) GetKeysRepaired() uint64 {
+	if x != nil {
+		return x.KeysRepaired
+	}
+	return 0
+}
+
+func (x *GetReplicationStatusResponse) GetLastRepairError() string {
+	if x != nil {
+		return x.LastRepairError
+	}
+	return ""
+}
+
+func (x *GetReplicationStatusResponse) GetSiteId() string {
+	if x != nil {
+		return x.SiteId
+	}
+	return ""
+}
+
+func (x *GetReplicationStatusResponse) GetPeers() []*PeerSiteStatus {
+	if x != nil {
+		return x.Peers
+	}
+	return nil
+}
+
+// PeerSiteStatus is another active-active site as seen by this one.
+type PeerSiteStatus struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Addr  string                 `protobuf:"bytes,1,opt,name=addr,proto3" json:"addr,omitempty"`
+	// Empty until the peer first answers.
+	SiteId    string `protobuf:"bytes,2,opt,name=site_id,json=siteId,proto3" json:"site_id,omitempty"`
+	Connected bool   `protobuf:"varint,3,opt,name=connected,proto3" json:"connected,omitempty"`
+	// When this site last heard from the peer; unset if never.
+	LastContact *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=last_contact,json=lastContact,proto3" json:"last_contact,omitempty"`
+	// Last change of the peer's log this site merged, and the last one the
+	// peer reported having made.
+	Offset     uint64 `protobuf:"varint,5,opt,name=offset,proto3" json:"offset,omitempty"`
+	PeerOffset uint64 `protobuf:"varint,6,opt,name=peer_offset,json=peerOffset,proto3" json:"peer_offset,omitempty"`
+	// How many times this site merged a snapshot of the peer.
+	Resyncs       uint64 `protobuf:"varint,7,opt,name=resyncs,proto3" json:"resyncs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PeerSiteStatus) Reset() {
+	*x = PeerSiteStatus{}
+	mi := &file_api_proto_admin_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PeerSiteStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeerSiteStatus) ProtoMessage() {}
+
+func (x *PeerSiteStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeerSiteStatus.ProtoReflect.Descriptor instead.
+func (*PeerSiteStatus) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *PeerSiteStatus) GetAddr() string {
+	if x != nil {
+		return x.Addr
+	}
+	return ""
+}
+
+func (x *PeerSiteStatus) GetSiteId() string {
+	if x != nil {
+		return x.SiteId
+	}
+	return ""
+}
+
+func (x *PeerSiteStatus) GetConnected() bool {
+	if x != nil {
+		return x.Connected
+	}
+	return false
+}
+
+func (x *PeerSiteStatus) GetLastContact() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastContact
+	}
+	return nil
+}
+
+func (x *PeerSiteStatus) GetOffset() uint64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *PeerSiteStatus) GetPeerOffset() uint64 {
+	if x != nil {
+		return x.PeerOffset
+	}
+	return 0
+}
+
+func (x *PeerSiteStatus) GetResyncs() uint64 {
+	if x != nil {
+		return x.Resyncs
+	}
+	return 0
+}
+
+type ReplicaStatus struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Address the replica connected from.
+	Addr string `protobuf:"bytes,2,opt,name=addr,proto3" json:"addr,omitempty"`
+	// Last mutation sent to the replica.
+	Offset         uint64                 `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"`
+	ConnectedSince *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=connected_since,json=connectedSince,proto3" json:"connected_since,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ReplicaStatus) Reset() {
+	*x = ReplicaStatus{}
+	mi := &file_api_proto_admin_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReplicaStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplicaStatus) ProtoMessage() {}
+
+func (x *ReplicaStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplicaStatus.ProtoReflect.Descriptor instead.
+func (*ReplicaStatus) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *ReplicaStatus) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ReplicaStatus) GetAddr() string {
+	if x != nil {
+		return x.Addr
+	}
+	return ""
+}
+
+func (x *ReplicaStatus) GetOffset() uint64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *ReplicaStatus) GetConnectedSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ConnectedSince
+	}
+	return nil
+}
+
+type CheckConsistencyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckConsistencyRequest) Reset() {
+	*x = CheckConsistencyRequest{}
+	mi := &file_api_proto_admin_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckConsistencyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckConsistencyRequest) ProtoMessage() {}
+
+func (x *CheckConsistencyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckConsistencyRequest.ProtoReflect.Descriptor instead.
+func (*CheckConsistencyRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{34}
+}
+
+type CheckConsistencyResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The offset the primary's trees reflect.
+	PrimaryOffset uint64 `protobuf:"varint,1,opt,name=primary_offset,json=primaryOffset,proto3" json:"primary_offset,omitempty"`
+	// The offset the replica's trees reflect. When it is ahead of
+	// primary_offset, because writes kept arriving during the check, ranges
+	// written in between may be reported although the two agree on them.
+	Offset uint64 `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	// Namespaces compared, and the key ranges each is split into.
+	Namespaces         uint32            `protobuf:"varint,3,opt,name=namespaces,proto3" json:"namespaces,omitempty"`
+	RangesPerNamespace uint32            `protobuf:"varint,4,opt,name=ranges_per_namespace,json=rangesPerNamespace,proto3" json:"ranges_per_namespace,omitempty"`
+	Divergent          []*DivergentRange `protobuf:"bytes,5,rep,name=divergent,proto3" json:"divergent,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *CheckConsistencyResponse) Reset() {
+	*x = CheckConsistencyResponse{}
+	mi := &file_api_proto_admin_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckConsistencyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckConsistencyResponse) ProtoMessage() {}
+
+func (x *CheckConsistencyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckConsistencyResponse.ProtoReflect.Descriptor instead.
+func (*CheckConsistencyResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *CheckConsistencyResponse) GetPrimaryOffset() uint64 {
+	if x != nil {
+		return x.PrimaryOffset
+	}
+	return 0
+}
+
+func (x *CheckConsistencyResponse) GetOffset() uint64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *CheckConsistencyResponse) GetNamespaces() uint32 {
+	if x != nil {
+		return x.Namespaces
+	}
+	return 0
+}
+
+func (x *CheckConsistencyResponse) GetRangesPerNamespace() uint32 {
+	if x != nil {
+		return x.RangesPerNamespace
+	}
+	return 0
+}
+
+func (x *CheckConsistencyResponse) GetDivergent() []*DivergentRange {
+	if x != nil {
+		return x.Divergent
+	}
+	return nil
+}
+
+type DivergentRange struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Namespace string                 `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	// Set when the namespace only exists on one side, "primary" or
+	// "replica" naming where it is missing. range and the hashes are then
+	// unset.
+	MissingOn string `protobuf:"bytes,2,opt,name=missing_on,json=missingOn,proto3" json:"missing_on,omitempty"`
+	Range     uint32 `protobuf:"varint,3,opt,name=range,proto3" json:"range,omitempty"`
+	// First and last key hash of the range.
+	FirstHash     uint64 `protobuf:"varint,4,opt,name=first_hash,json=firstHash,proto3" json:"first_hash,omitempty"`
+	LastHash      uint64 `protobuf:"varint,5,opt,name=last_hash,json=lastHash,proto3" json:"last_hash,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DivergentRange) Reset() {
+	*x = DivergentRange{}
+	mi := &file_api_proto_admin_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DivergentRange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DivergentRange) ProtoMessage() {}
+
+func (x *DivergentRange) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DivergentRange.ProtoReflect.Descriptor instead.
+func (*DivergentRange) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *DivergentRange) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *DivergentRange) GetMissingOn() string {
+	if x != nil {
+		return x.MissingOn
+	}
+	return ""
+}
+
+func (x *DivergentRange) GetRange() uint32 {
+	if x != nil {
+		return x.Range
+	}
+	return 0
+}
+
+func (x *DivergentRange) GetFirstHash() uint64 {
+	if x != nil {
+		return x.FirstHash
+	}
+	return 0
+}
+
+func (x *DivergentRange) GetLastHash() uint64 {
+	if x != nil {
+		return x.LastHash
+	}
+	return 0
+}
+
+type BeginMigrationRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The map the deployment routes by now, and the one it moves to. to must
+	// have a higher version.
+	From          *ShardMap `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	To            *ShardMap `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BeginMigrationRequest) Reset() {
+	*x = BeginMigrationRequest{}
+	mi := &file_api_proto_admin_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BeginMigrationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BeginMigrationRequest) ProtoMessage() {}
+
+func (x *BeginMigrationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BeginMigrationRequest.ProtoReflect.Descriptor instead.
+func (*BeginMigrationRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *BeginMigrationRequest) GetFrom() *ShardMap {
+	if x != nil {
+		return x.From
+	}
+	return nil
+}
+
+func (x *BeginMigrationRequest) GetTo() *ShardMap {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
+type GetMigrationStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMigrationStatusRequest) Reset() {
+	*x = GetMigrationStatusRequest{}
+	mi := &file_api_proto_admin_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMigrationStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMigrationStatusRequest) ProtoMessage() {}
+
+func (x *GetMigrationStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMigrationStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetMigrationStatusRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{38}
+}
+
+type CommitMigrationRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Version of the map to switch to, as a guard against committing a
+	// different migration than the one that was checked.
+	Version       uint64 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommitMigrationRequest) Reset() {
+	*x = CommitMigrationRequest{}
+	mi := &file_api_proto_admin_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommitMigrationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommitMigrationRequest) ProtoMessage() {}
+
+func (x *CommitMigrationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommitMigrationRequest.ProtoReflect.Descriptor instead.
+func (*CommitMigrationRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *CommitMigrationRequest) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type MigrationStatus struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	NodeId string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	State  MigrationState         `protobuf:"varint,2,opt,name=state,proto3,enum=kvstore.v1.MigrationState" json:"state,omitempty"`
+	// Version of the map the node routes by; while migrating, the map moved
+	// from.
+	Version uint64 `protobuf:"varint,3,opt,name=version,proto3" json:"version,omitempty"`
+	// While migrating, the version of the map moved to.
+	TargetVersion uint64 `protobuf:"varint,4,opt,name=target_version,json=targetVersion,proto3" json:"target_version,omitempty"`
+	// Keys handed over to other nodes and received from them.
+	KeysSent     uint64 `protobuf:"varint,5,opt,name=keys_sent,json=keysSent,proto3" json:"keys_sent,omitempty"`
+	KeysReceived uint64 `protobuf:"varint,6,opt,name=keys_received,json=keysReceived,proto3" json:"keys_received,omitempty"`
+	// Moving keys still held, as of the last pass over the keyspace.
+	KeysRemaining uint64                 `protobuf:"varint,7,opt,name=keys_remaining,json=keysRemaining,proto3" json:"keys_remaining,omitempty"`
+	StartedAt     *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	// The last failure to hand over keys, which is retried. Cleared once a
+	// batch goes through.
+	LastError     string `protobuf:"bytes,9,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MigrationStatus) Reset() {
+	*x = MigrationStatus{}
+	mi := &file_api_proto_admin_proto_msgTypes[40]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MigrationStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MigrationStatus) ProtoMessage() {}
+
+func (x *MigrationStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[40]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MigrationStatus.ProtoReflect.Descriptor instead.
+func (*MigrationStatus) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{40}
+}
+
+func (x *MigrationStatus) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *MigrationStatus) GetState() MigrationState {
+	if x != nil {
+		return x.State
+	}
+	return MigrationState_MIGRATION_STATE_IDLE
+}
+
+func (x *MigrationStatus) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *MigrationStatus) GetTargetVersion() uint64 {
+	if x != nil {
+		return x.TargetVersion
+	}
+	return 0
+}
+
+func (x *MigrationStatus) GetKeysSent() uint64 {
+	if x != nil {
+		return x.KeysSent
+	}
+	return 0
+}
+
+func (x *MigrationStatus) GetKeysReceived() uint64 {
+	if x != nil {
+		return x.KeysReceived
+	}
+	return 0
+}
+
+func (x *MigrationStatus) GetKeysRemaining() uint64 {
+	if x != nil {
+		return x.KeysRemaining
+	}
+	return 0
+}
+
+func (x *MigrationStatus) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *MigrationStatus) GetLastError() string {
+	if x != nil {
+		return x.LastError
+	}
+	return ""
+}
+
+type MigratedRecord struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Key   string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	// Principal charged for the key.
+	Owner string `protobuf:"bytes,3,opt,name=owner,proto3" json:"owner,omitempty"`
+	// Unix time in seconds; zero never expires.
+	ExpiresAt     int64 `protobuf:"varint,4,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MigratedRecord) Reset() {
+	*x = MigratedRecord{}
+	mi := &file_api_proto_admin_proto_msgTypes[41]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MigratedRecord) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MigratedRecord) ProtoMessage() {}
+
+func (x *MigratedRecord) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[41]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MigratedRecord.ProtoReflect.Descriptor instead.
+func (*MigratedRecord) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *MigratedRecord) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *MigratedRecord) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *MigratedRecord) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *MigratedRecord) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+type ImportKeysRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Version of the map the keys move to. The receiving node must be
+	// migrating to it and own every key in it.
+	Version uint64 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	// Created with these settings if the receiving node lacks it.
+	Namespace         string            `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	DefaultTtlSeconds int64             `protobuf:"varint,3,opt,name=default_ttl_seconds,json=defaultTtlSeconds,proto3" json:"default_ttl_seconds,omitempty"`
+	Quota             *Quota            `protobuf:"bytes,4,opt,name=quota,proto3" json:"quota,omitempty"`
+	Records           []*MigratedRecord `protobuf:"bytes,5,rep,name=records,proto3" json:"records,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *ImportKeysRequest) Reset() {
+	*x = ImportKeysRequest{}
+	mi := &file_api_proto_admin_proto_msgTypes[42]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportKeysRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportKeysRequest) ProtoMessage() {}
+
+func (x *ImportKeysRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[42]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportKeysRequest.ProtoReflect.Descriptor instead.
+func (*ImportKeysRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{42}
+}
+
+func (x *ImportKeysRequest) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *ImportKeysRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *ImportKeysRequest) GetDefaultTtlSeconds() int64 {
+	if x != nil {
+		return x.DefaultTtlSeconds
+	}
+	return 0
+}
+
+func (x *ImportKeysRequest) GetQuota() *Quota {
+	if x != nil {
+		return x.Quota
+	}
+	return nil
+}
+
+func (x *ImportKeysRequest) GetRecords() []*MigratedRecord {
+	if x != nil {
+		return x.Records
+	}
+	return nil
+}
+
+type ImportKeysResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportKeysResponse) Reset() {
+	*x = ImportKeysResponse{}
+	mi := &file_api_proto_admin_proto_msgTypes[43]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportKeysResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportKeysResponse) ProtoMessage() {}
+
+func (x *ImportKeysResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[43]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportKeysResponse.ProtoReflect.Descriptor instead.
+func (*ImportKeysResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{43}
+}
+
+type GetGossipMembersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetGossipMembersRequest) Reset() {
+	*x = GetGossipMembersRequest{}
+	mi := &file_api_proto_admin_proto_msgTypes[44]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetGossipMembersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetGossipMembersRequest) ProtoMessage() {}
+
+func (x *GetGossipMembersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[44]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetGossipMembersRequest.ProtoReflect.Descriptor instead.
+func (*GetGossipMembersRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{44}
+}
+
+type GetGossipMembersResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The server that served the call.
+	NodeId string `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	// Every member, including the server itself, sorted by ID. Dead and left
+	// members are listed until they are forgotten.
+	Members       []*GossipMemberStatus `protobuf:"bytes,2,rep,name=members,proto3" json:"members,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetGossipMembersResponse) Reset() {
+	*x = GetGossipMembersResponse{}
+	mi := &file_api_proto_admin_proto_msgTypes[45]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetGossipMembersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetGossipMembersResponse) ProtoMessage() {}
+
+func (x *GetGossipMembersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[45]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetGossipMembersResponse.ProtoReflect.Descriptor instead.
+func (*GetGossipMembersResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{45}
+}
+
+func (x *GetGossipMembersResponse) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *GetGossipMembersResponse) GetMembers() []*GossipMemberStatus {
+	if x != nil {
+		return x.Members
+	}
+	return nil
+}
+
+type GossipMemberStatus struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Member *GossipMember          `protobuf:"bytes,1,opt,name=member,proto3" json:"member,omitempty"`
+	// When the server learned of the member's current state.
+	Since         *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=since,proto3" json:"since,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GossipMemberStatus) Reset() {
+	*x = GossipMemberStatus{}
+	mi := &file_api_proto_admin_proto_msgTypes[46]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GossipMemberStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GossipMemberStatus) ProtoMessage() {}
+
+func (x *GossipMemberStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_admin_proto_msgTypes[46]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GossipMemberStatus.ProtoReflect.Descriptor instead.
+func (*GossipMemberStatus) Descriptor() ([]byte, []int) {
+	return file_api_proto_admin_proto_rawDescGZIP(), []int{46}
+}
+
+func (x *GossipMemberStatus) GetMember() *GossipMember {
+	if x != nil {
+		return x.Member
+	}
+	return nil
+}
+
+func (x *GossipMemberStatus) GetSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Since
+	}
+	return nil
+}
+
+var File_api_proto_admin_proto protoreflect.FileDescriptor
+
+const file_api_proto_admin_proto_rawDesc = "" +
+	"\n" +
+	"\x15api/proto/admin.proto\x12\n" +
+	"kvstore.v1\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\x1a\x16api/proto/gossip.proto\x1a\x17api/proto/kvstore.proto\"\x15\n" +
+	"\x13ReloadConfigRequest\"[\n" +
+	"\x14ReloadConfigResponse\x12\x18\n" +
+	"\aapplied\x18\x01 \x03(\tR\aapplied\x12)\n" +
+	"\x10requires_restart\x18\x02 \x03(\tR\x0frequiresRestart\"g\n" +
+	"\x05Quota\x12\x19\n" +
+	"\bmax_keys\x18\x01 \x01(\x03R\amaxKeys\x12\x1b\n" +
+	"\tmax_bytes\x18\x02 \x01(\x03R\bmaxBytes\x12&\n" +
+	"\x0fmax_value_bytes\x18\x03 \x01(\x03R\rmaxValueBytes\"1\n" +
+	"\x05Usage\x12\x12\n" +
+	"\x04keys\x18\x01 \x01(\x03R\x04keys\x12\x14\n" +
+	"\x05bytes\x18\x02 \x01(\x03R\x05bytes\"\xbd\x01\n" +
+	"\tNamespace\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12.\n" +
+	"\x13default_ttl_seconds\x18\x02 \x01(\x03R\x11defaultTtlSeconds\x12'\n" +
+	"\x05quota\x18\x05 \x01(\v2\x11.kvstore.v1.QuotaR\x05quota\x12'\n" +
+	"\x05usage\x18\x06 \x01(\v2\x11.kvstore.v1.UsageR\x05usageJ\x04\b\x03\x10\x04J\x04\b\x04\x10\x05R\bmax_keysR\x04keys\"\x80\x01\n" +
+	"\x0ePrincipalUsage\x12\x1c\n" +
+	"\tprincipal\x18\x01 \x01(\tR\tprincipal\x12'\n" +
+	"\x05quota\x18\x02 \x01(\v2\x11.kvstore.v1.QuotaR\x05quota\x12'\n" +
+	"\x05usage\x18\x03 \x01(\v2\x11.kvstore.v1.UsageR\x05usage\"\x95\x01\n" +
+	"\x16CreateNamespaceRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12.\n" +
+	"\x13default_ttl_seconds\x18\x02 \x01(\x03R\x11defaultTtlSeconds\x12'\n" +
+	"\x05quota\x18\x04 \x01(\v2\x11.kvstore.v1.QuotaR\x05quotaJ\x04\b\x03\x10\x04R\bmax_keys\"N\n" +
+	"\x17CreateNamespaceResponse\x123\n" +
+	"\tnamespace\x18\x01 \x01(\v2\x15.kvstore.v1.NamespaceR\tnamespace\"*\n" +
+	"\x14DropNamespaceRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\"\x17\n" +
+	"\x15DropNamespaceResponse\"\x17\n" +
+	"\x15ListNamespacesRequest\"O\n" +
+	"\x16ListNamespacesResponse\x125\n" +
+	"\n" +
+	"namespaces\x18\x01 \x03(\v2\x15.kvstore.v1.NamespaceR\n" +
+	"namespaces\"W\n" +
+	"\x18SetNamespaceQuotaRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12'\n" +
+	"\x05quota\x18\x02 \x01(\v2\x11.kvstore.v1.QuotaR\x05quota\"P\n" +
+	"\x19SetNamespaceQuotaResponse\x123\n" +
+	"\tnamespace\x18\x01 \x01(\v2\x15.kvstore.v1.NamespaceR\tnamespace\"M\n" +
+	"\x0fGetUsageRequest\x12\x1c\n" +
+	"\tnamespace\x18\x01 \x01(\tR\tnamespace\x12\x1c\n" +
+	"\tprincipal\x18\x02 \x01(\tR\tprincipal\"\x85\x01\n" +
+	"\x10GetUsageResponse\x125\n" +
+	"\n" +
+	"namespaces\x18\x01 \x03(\v2\x15.kvstore.v1.NamespaceR\n" +
+	"namespaces\x12:\n" +
+	"\n" +
+	"principals\x18\x02 \x03(\v2\x1a.kvstore.v1.PrincipalUsageR\n" +
+	"principals\"\x9f\x02\n" +
+	"\rSlowOperation\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x129\n" +
+	"\n" +
+	"start_time\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\tstartTime\x125\n" +
+	"\bduration\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\bduration\x12\x16\n" +
+	"\x06method\x18\x04 \x01(\tR\x06method\x12\x1c\n" +
+	"\tnamespace\x18\x05 \x01(\tR\tnamespace\x12\x10\n" +
+	"\x03key\x18\x06 \x01(\tR\x03key\x12\x12\n" +
+	"\x04code\x18\a \x01(\tR\x04code\x12\x1c\n" +
+	"\tprincipal\x18\b \x01(\tR\tprincipal\x12\x12\n" +
+	"\x04peer\x18\t \x01(\tR\x04peer\")\n" +
+	"\x11GetSlowLogRequest\x12\x14\n" +
+	"\x05limit\x18\x01 \x01(\x05R\x05limit\"\x88\x01\n" +
+	"\x12GetSlowLogResponse\x129\n" +
+	"\n" +
+	"operations\x18\x01 \x03(\v2\x19.kvstore.v1.SlowOperationR\n" +
+	"operations\x127\n" +
+	"\tthreshold\x18\x02 \x01(\v2\x19.google.protobuf.DurationR\tthreshold\"\x15\n" +
+	"\x13ResetSlowLogRequest\"\x16\n" +
+	"\x14ResetSlowLogResponse\"\xe5\x01\n" +
+	"\rClusterMember\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1b\n" +
+	"\traft_addr\x18\x02 \x01(\tR\braftAddr\x12\x1b\n" +
+	"\tgrpc_addr\x18\x03 \x01(\tR\bgrpcAddr\x122\n" +
+	"\x04role\x18\x04 \x01(\x0e2\x1e.kvstore.v1.ClusterMember.RoleR\x04role\x12\x16\n" +
+	"\x06leader\x18\x05 \x01(\bR\x06leader\">\n" +
+	"\x04Role\x12\x14\n" +
+	"\x10ROLE_UNSPECIFIED\x10\x00\x12\x0e\n" +
+	"\n" +
+	"ROLE_VOTER\x10\x01\x12\x10\n" +
+	"\fROLE_LEARNER\x10\x02\"W\n" +
+	"\n" +
+	"Membership\x123\n" +
+	"\amembers\x18\x01 \x03(\v2\x19.kvstore.v1.ClusterMemberR\amembers\x12\x14\n" +
+	"\x05index\x18\x02 \x01(\x04R\x05index\"\x16\n" +
+	"\x14GetMembershipRequest\"~\n" +
+	"\x15GetMembershipResponse\x126\n" +
+	"\n" +
+	"membership\x18\x01 \x01(\v2\x16.kvstore.v1.MembershipR\n" +
+	"membership\x12\x17\n" +
+	"\anode_id\x18\x02 \x01(\tR\x06nodeId\x12\x14\n" +
+	"\x05state\x18\x03 \x01(\tR\x05state\"]\n" +
+	"\x11AddLearnerRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1b\n" +
+	"\traft_addr\x18\x02 \x01(\tR\braftAddr\x12\x1b\n" +
+	"\tgrpc_addr\x18\x03 \x01(\tR\bgrpcAddr\"'\n" +
+	"\x15PromoteLearnerRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"#\n" +
+	"\x11RemoveNodeRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"+\n" +
+	"\x19TransferLeadershipRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"R\n" +
+	"\x18MembershipChangeResponse\x126\n" +
+	"\n" +
+	"membership\x18\x01 \x01(\v2\x16.kvstore.v1.MembershipR\n" +
+	"membership\"\x1d\n" +
+	"\x1bGetReplicationStatusRequest\"\xdd\x05\n" +
+	"\x1cGetReplicationStatusResponse\x12A\n" +
+	"\x04role\x18\x01 \x01(\x0e2-.kvstore.v1.GetReplicationStatusResponse.RoleR\x04role\x12\x15\n" +
+	"\x06log_id\x18\x02 \x01(\tR\x05logId\x12\x16\n" +
+	"\x06offset\x18\x03 \x01(\x04R\x06offset\x125\n" +
+	"\breplicas\x18\x04 \x03(\v2\x19.kvstore.v1.ReplicaStatusR\breplicas\x12!\n" +
+	"\fprimary_addr\x18\x05 \x01(\tR\vprimaryAddr\x12\x1c\n" +
+	"\tconnected\x18\x06 \x01(\bR\tconnected\x12=\n" +
+	"\flast_contact\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\vlastContact\x12%\n" +
+	"\x0eprimary_offset\x18\b \x01(\x04R\rprimaryOffset\x12\x18\n" +
+	"\aresyncs\x18\t \x01(\x04R\aresyncs\x12;\n" +
+	"\vlast_repair\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"lastRepair\x12'\n" +
+	"\x0franges_repaired\x18\v \x01(\x04R\x0erangesRepaired\x12#\n" +
+	"\rkeys_repaired\x18\f \x01(\x04R\fkeysRepaired\x12*\n" +
+	"\x11last_repair_error\x18\r \x01(\tR\x0flastRepairError\x12\x17\n" +
+	"\asite_id\x18\x0e \x01(\tR\x06siteId\x120\n" +
+	"\x05peers\x18\x0f \x03(\v2\x1a.kvstore.v1.PeerSiteStatusR\x05peers\"Q\n" +
+	"\x04Role\x12\x14\n" +
+	"\x10ROLE_UNSPECIFIED\x10\x00\x12\x10\n" +
+	"\fROLE_PRIMARY\x10\x01\x12\x10\n" +
+	"\fROLE_REPLICA\x10\x02\x12\x0f\n" +
+	"\vROLE_ACTIVE\x10\x03\"\xed\x01\n" +
+	"\x0ePeerSiteStatus\x12\x12\n" +
+	"\x04addr\x18\x01 \x01(\tR\x04addr\x12\x17\n" +
+	"\asite_id\x18\x02 \x01(\tR\x06siteId\x12\x1c\n" +
+	"\tconnected\x18\x03 \x01(\bR\tconnected\x12=\n" +
+	"\flast_contact\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\vlastContact\x12\x16\n" +
+	"\x06offset\x18\x05 \x01(\x04R\x06offset\x12\x1f\n" +
+	"\vpeer_offset\x18\x06 \x01(\x04R\n" +
+	"peerOffset\x12\x18\n" +
+	"\aresyncs\x18\a \x01(\x04R\aresyncs\"\x90\x01\n" +
+	"\rReplicaStatus\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04addr\x18\x02 \x01(\tR\x04addr\x12\x16\n" +
+	"\x06offset\x18\x03 \x01(\x04R\x06offset\x12C\n" +
+	"\x0fconnected_since\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x0econnectedSince\"\x19\n" +
+	"\x17CheckConsistencyRequest\"\xe5\x01\n" +
+	"\x18CheckConsistencyResponse\x12%\n" +
+	"\x0eprimary_offset\x18\x01 \x01(\x04R\rprimaryOffset\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x04R\x06offset\x12\x1e\n" +
+	"\n" +
+	"namespaces\x18\x03 \x01(\rR\n" +
+	"namespaces\x120\n" +
+	"\x14ranges_per_namespace\x18\x04 \x01(\rR\x12rangesPerNamespace\x128\n" +
+	"\tdivergent\x18\x05 \x03(\v2\x1a.kvstore.v1.DivergentRangeR\tdivergent\"\x9f\x01\n" +
+	"\x0eDivergentRange\x12\x1c\n" +
+	"\tnamespace\x18\x01 \x01(\tR\tnamespace\x12\x1d\n" +
+	"\n" +
+	"missing_on\x18\x02 \x01(\tR\tmissingOn\x12\x14\n" +
+	"\x05range\x18\x03 \x01(\rR\x05range\x12\x1d\n" +
+	"\n" +
+	"first_hash\x18\x04 \x01(\x04R\tfirstHash\x12\x1b\n" +
+	"\tlast_hash\x18\x05 \x01(\x04R\blastHash\"g\n" +
+	"\x15BeginMigrationRequest\x12(\n" +
+	"\x04from\x18\x01 \x01(\v2\x14.kvstore.v1.ShardMapR\x04from\x12$\n" +
+	"\x02to\x18\x02 \x01(\v2\x14.kvstore.v1.ShardMapR\x02to\"\x1b\n" +
+	"\x19GetMigrationStatusRequest\"2\n" +
+	"\x16CommitMigrationRequest\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\x04R\aversion\"\xe0\x02\n" +
+	"\x0fMigrationStatus\x12\x17\n" +
+	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x120\n" +
+	"\x05state\x18\x02 \x01(\x0e2\x1a.kvstore.v1.MigrationStateR\x05state\x12\x18\n" +
+	"\aversion\x18\x03 \x01(\x04R\aversion\x12%\n" +
+	"\x0etarget_version\x18\x04 \x01(\x04R\rtargetVersion\x12\x1b\n" +
+	"\tkeys_sent\x18\x05 \x01(\x04R\bkeysSent\x12#\n" +
+	"\rkeys_received\x18\x06 \x01(\x04R\fkeysReceived\x12%\n" +
+	"\x0ekeys_remaining\x18\a \x01(\x04R\rkeysRemaining\x129\n" +
+	"\n" +
+	"started_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tstartedAt\x12\x1d\n" +
+	"\n" +
+	"last_error\x18\t \x01(\tR\tlastError\"m\n" +
+	"\x0eMigratedRecord\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value\x12\x14\n" +
+	"\x05owner\x18\x03 \x01(\tR\x05owner\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x04 \x01(\x03R\texpiresAt\"\xda\x01\n" +
+	"\x11ImportKeysRequest\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\x04R\aversion\x12\x1c\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\x12.\n" +
+	"\x13default_ttl_seconds\x18\x03 \x01(\x03R\x11defaultTtlSeconds\x12'\n" +
+	"\x05quota\x18\x04 \x01(\v2\x11.kvstore.v1.QuotaR\x05quota\x124\n" +
+	"\arecords\x18\x05 \x03(\v2\x1a.kvstore.v1.MigratedRecordR\arecords\"\x14\n" +
+	"\x12ImportKeysResponse\"\x19\n" +
+	"\x17GetGossipMembersRequest\"m\n" +
+	"\x18GetGossipMembersResponse\x12\x17\n" +
+	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x128\n" +
+	"\amembers\x18\x02 \x03(\v2\x1e.kvstore.v1.GossipMemberStatusR\amembers\"x\n" +
+	"\x12GossipMemberStatus\x120\n" +
+	"\x06member\x18\x01 \x01(\v2\x18.kvstore.v1.GossipMemberR\x06member\x120\n" +
+	"\x05since\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x05since*c\n" +
+	"\x0eMigrationState\x12\x18\n" +
+	"\x14MIGRATION_STATE_IDLE\x10\x00\x12\x1d\n" +
+	"\x19MIGRATION_STATE_MIGRATING\x10\x01\x12\x18\n" +
+	"\x14MIGRATION_STATE_DONE\x10\x022\xde\r\n" +
+	"\x05Admin\x12Q\n" +
+	"\fReloadConfig\x12\x1f.kvstore.v1.ReloadConfigRequest\x1a .kvstore.v1.ReloadConfigResponse\x12Z\n" +
+	"\x0fCreateNamespace\x12\".kvstore.v1.CreateNamespaceRequest\x1a#.kvstore.v1.CreateNamespaceResponse\x12T\n" +
+	"\rDropNamespace\x12 .kvstore.v1.DropNamespaceRequest\x1a!.kvstore.v1.DropNamespaceResponse\x12W\n" +
+	"\x0eListNamespaces\x12!.kvstore.v1.ListNamespacesRequest\x1a\".kvstore.v1.ListNamespacesResponse\x12`\n" +
+	"\x11SetNamespaceQuota\x12$.kvstore.v1.SetNamespaceQuotaRequest\x1a%.kvstore.v1.SetNamespaceQuotaResponse\x12E\n" +
+	"\bGetUsage\x12\x1b.kvstore.v1.GetUsageRequest\x1a\x1c.kvstore.v1.GetUsageResponse\x12K\n" +
+	"\n" +
+	"GetSlowLog\x12\x1d.kvstore.v1.GetSlowLogRequest\x1a\x1e.kvstore.v1.GetSlowLogResponse\x12Q\n" +
+	"\fResetSlowLog\x12\x1f.kvstore.v1.ResetSlowLogRequest\x1a .kvstore.v1.ResetSlowLogResponse\x12T\n" +
+	"\rGetMembership\x12 .kvstore.v1.GetMembershipRequest\x1a!.kvstore.v1.GetMembershipResponse\x12Q\n" +
+	"\n" +
+	"AddLearner\x12\x1d.kvstore.v1.AddLearnerRequest\x1a$.kvstore.v1.MembershipChangeResponse\x12Y\n" +
+	"\x0ePromoteLearner\x12!.kvstore.v1.PromoteLearnerRequest\x1a$.kvstore.v1.MembershipChangeResponse\x12Q\n" +
+	"\n" +
+	"RemoveNode\x12\x1d.kvstore.v1.RemoveNodeRequest\x1a$.kvstore.v1.MembershipChangeResponse\x12a\n" +
+	"\x12TransferLeadership\x12%.kvstore.v1.TransferLeadershipRequest\x1a$.kvstore.v1.MembershipChangeResponse\x12i\n" +
+	"\x14GetReplicationStatus\x12'.kvstore.v1.GetReplicationStatusRequest\x1a(.kvstore.v1.GetReplicationStatusResponse\x12]\n" +
+	"\x10CheckConsistency\x12#.kvstore.v1.CheckConsistencyRequest\x1a$.kvstore.v1.CheckConsistencyResponse\x12P\n" +
+	"\x0eBeginMigration\x12!.kvstore.v1.BeginMigrationRequest\x1a\x1b.kvstore.v1.MigrationStatus\x12X\n" +
+	"\x12GetMigrationStatus\x12%.kvstore.v1.GetMigrationStatusRequest\x1a\x1b.kvstore.v1.MigrationStatus\x12R\n" +
+	"\x0fCommitMigration\x12\".kvstore.v1.CommitMigrationRequest\x1a\x1b.kvstore.v1.MigrationStatus\x12K\n" +
+	"\n" +
+	"ImportKeys\x12\x1d.kvstore.v1.ImportKeysRequest\x1a\x1e.kvstore.v1.ImportKeysResponse\x12]\n" +
+	"\x10GetGossipMembers\x12#.kvstore.v1.GetGossipMembersRequest\x1a$.kvstore.v1.GetGossipMembersResponseB,Z*github.com/khuongnguyenBlue/kvstore/pkg/pbb\x06proto3"
+
+var (
+	file_api_proto_admin_proto_rawDescOnce sync.Once
+	file_api_proto_admin_proto_rawDescData []byte
+)
+
+func file_api_proto_admin_proto_rawDescGZIP() []byte {
+	file_api_proto_admin_proto_rawDescOnce.Do(func() {
+		file_api_proto_admin_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_api_proto_admin_proto_rawDesc), len(file_api_proto_admin_proto_rawDesc)))
+	})
+	return file_api_proto_admin_proto_rawDescData
+}
+
+var file_api_proto_admin_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_api_proto_admin_proto_msgTypes = make([]protoimpl.MessageInfo, 47)
+var file_api_proto_admin_proto_goTypes = []any{
+	(MigrationState)(0),                    // 0: kvstore.v1.MigrationState
+	(ClusterMember_Role)(0),                // 1: kvstore.v1.ClusterMember.Role
+	(GetReplicationStatusResponse_Role)(0), // 2: kvstore.v1.GetReplicationStatusResponse.Role
+	(*ReloadConfigRequest)(nil),            // 3: kvstore.v1.ReloadConfigRequest
+	(*ReloadConfigResponse)(nil),           // 4: kvstore.v1.ReloadConfigResponse
+	(*Quota)(nil),                          // 5: kvstore.v1.Quota
+	(*Usage)(nil),                          // 6: kvstore.v1.Usage
+	(*Namespace)(nil),                      // 7: kvstore.v1.Namespace
+	(*PrincipalUsage)(nil),                 // 8: kvstore.v1.PrincipalUsage
+	(*CreateNamespaceRequest)(nil),         // 9: kvstore.v1.CreateNamespaceRequest
+	(*CreateNamespaceResponse)(nil),        // 10: kvstore.v1.CreateNamespaceResponse
+	(*DropNamespaceRequest)(nil),           // 11: kvstore.v1.DropNamespaceRequest
+	(*DropNamespaceResponse)(nil),          // 12: kvstore.v1.DropNamespaceResponse
+	(*ListNamespacesRequest)(nil),          // 13: kvstore.v1.ListNamespacesRequest
+	(*ListNamespacesResponse)(nil),         // 14: kvstore.v1.ListNamespacesResponse
+	(*SetNamespaceQuotaRequest)(nil),       // 15: kvstore.v1.SetNamespaceQuotaRequest
+	(*SetNamespaceQuotaResponse)(nil),      // 16: kvstore.v1.SetNamespaceQuotaResponse
+	(*GetUsageRequest)(nil),                // 17: kvstore.v1.GetUsageRequest
+	(*GetUsageResponse)(nil),               // 18: kvstore.v1.GetUsageResponse
+	(*SlowOperation)(nil),                  // 19: kvstore.v1.SlowOperation
+	(*GetSlowLogRequest)(nil),              // 20: kvstore.v1.GetSlowLogRequest
+	(*GetSlowLogResponse)(nil),             // 21: kvstore.v1.GetSlowLogResponse
+	(*ResetSlowLogRequest)(nil),            // 22: kvstore.v1.ResetSlowLogRequest
+	(*ResetSlowLogResponse)(nil),           // 23: kvstore.v1.ResetSlowLogResponse
+	(*ClusterMember)(nil),                  // 24: kvstore.v1.ClusterMember
+	(*Membership)(nil),                     // 25: kvstore.v1.Membership
+	(*GetMembershipRequest)(nil),           // 26: kvstore.v1.GetMembershipRequest
+	(*GetMembershipResponse)(nil),          // 27: kvstore.v1.GetMembershipResponse
+	(*AddLearnerRequest)(nil),              // 28: kvstore.v1.AddLearnerRequest
+	(*PromoteLearnerRequest)(nil),          // 29: kvstore.v1.PromoteLearnerRequest
+	(*RemoveNodeRequest)(nil),              // 30: kvstore.v1.RemoveNodeRequest
+	(*TransferLeadershipRequest)(nil),      // 31: kvstore.v1.TransferLeadershipRequest
+	(*MembershipChangeResponse)(nil),       // 32: kvstore.v1.MembershipChangeResponse
+	(*GetReplicationStatusRequest)(nil),    // 33: kvstore.v1.GetReplicationStatusRequest
+	(*GetReplicationStatusResponse)(nil),   // 34: kvstore.v1.GetReplicationStatusResponse
+	(*PeerSiteStatus)(nil),                 // 35: kvstore.v1.PeerSiteStatus
+	(*ReplicaStatus)(nil),                  // 36: kvstore.v1.ReplicaStatus
+	(*CheckConsistencyRequest)(nil),        // 37: kvstore.v1.CheckConsistencyRequest
+	(*CheckConsistencyResponse)(nil),       // 38: kvstore.v1.CheckConsistencyResponse
+	(*DivergentRange)(nil),                 // 39: kvstore.v1.DivergentRange
+	(*BeginMigrationRequest)(nil),          // 40: kvstore.v1.BeginMigrationRequest
+	(*GetMigrationStatusRequest)(nil),      // 41: kvstore.v1.GetMigrationStatusRequest
+	(*CommitMigrationRequest)(nil),         // 42: kvstore.v1.CommitMigrationRequest
+	(*MigrationStatus)(nil),                // 43: kvstore.v1.MigrationStatus
+	(*MigratedRecord)(nil),                 // 44: kvstore.v1.MigratedRecord
+	(*ImportKeysRequest)(nil),              // 45: kvstore.v1.ImportKeysRequest
+	(*ImportKeysResponse)(nil),             // 46: kvstore.v1.ImportKeysResponse
+	(*GetGossipMembersRequest)(nil),        // 47: kvstore.v1.GetGossipMembersRequest
+	(*GetGossipMembersResponse)(nil),       // 48: kvstore.v1.GetGossipMembersResponse
+	(*GossipMemberStatus)(nil),             // 49: kvstore.v1.GossipMemberStatus
+	(*timestamppb.Timestamp)(nil),          // 50: google.protobuf.Timestamp
+	(*durationpb.Duration)(nil),            // 51: google.protobuf.Duration
+	(*ShardMap)(nil),                       // 52: kvstore.v1.ShardMap
+	(*GossipMember)(nil),                   // 53: kvstore.v1.GossipMember
+}
+var file_api_proto_admin_proto_depIdxs = []int32{
+	5,  // 0: kvstore.v1.Namespace.quota:type_name -> kvstore.v1.Quota
+	6,  // 1: kvstore.v1.Namespace.usage:type_name -> kvstore.v1.Usage
+	5,  // 2: kvstore.v1.PrincipalUsage.quota:type_name -> kvstore.v1.Quota
+	6,  // 3: kvstore.v1.PrincipalUsage.usage:type_name -> kvstore.v1.Usage
+	5,  // 4: kvstore.v1.CreateNamespaceRequest.quota:type_name -> kvstore.v1.Quota
+	7,  // 5: kvstore.v1.CreateNamespaceResponse.namespace:type_name -> kvstore.v1.Namespace
+	7,  // 6: kvstore.v1.ListNamespacesResponse.namespaces:type_name -> kvstore.v1.Namespace
+	5,  // 7: kvstore.v1.SetNamespaceQuotaRequest.quota:type_name -> kvstore.v1.Quota
+	7,  // 8: kvstore.v1.SetNamespaceQuotaResponse.namespace:type_name -> kvstore.v1.Namespace
+	7,  // 9: kvstore.v1.GetUsageResponse.namespaces:type_name -> kvstore.v1.Namespace
+	8,  // 10: kvstore.v1.GetUsageResponse.principals:type_name -> kvstore.v1.PrincipalUsage
+	50, // 11: kvstore.v1.SlowOperation.start_time:type_name -> google.protobuf.Timestamp
+	51, // 12: kvstore.v1.SlowOperation.duration:type_name -> google.protobuf.Duration
+	19, // 13: kvstore.v1.GetSlowLogResponse.operations:type_name -> kvstore.v1.SlowOperation
+	51, // 14: kvstore.v1.GetSlowLogResponse.threshold:type_name -> google.protobuf.Duration
+	1,  // 15: kvstore.v1.ClusterMember.role:type_name -> kvstore.v1.ClusterMember.Role
+	24, // 16: kvstore.v1.Membership.members:type_name -> kvstore.v1.ClusterMember
+	25, // 17: kvstore.v1.GetMembershipResponse.membership:type_name -> kvstore.v1.Membership
+	25, // 18: kvstore.v1.MembershipChangeResponse.membership:type_name -> kvstore.v1.Membership
+	2,  // 19: kvstore.v1.GetReplicationStatusResponse.role:type_name -> kvstore.v1.GetReplicationStatusResponse.Role
+	36, // 20: kvstore.v1.GetReplicationStatusResponse.replicas:type_name -> kvstore.v1.ReplicaStatus
+	50, // 21: kvstore.v1.GetReplicationStatusResponse.last_contact:type_name -> google.protobuf.Timestamp
+	50, // 22: kvstore.v1.GetReplicationStatusResponse.last_repair:type_name -> google.protobuf.Timestamp
+	35, // 23: kvstore.v1.GetReplicationStatusResponse.peers:type_name -> kvstore.v1.PeerSiteStatus
+	50, // 24: kvstore.v1.PeerSiteStatus.last_contact:type_name -> google.protobuf.Timestamp
+	50, // 25: kvstore.v1.ReplicaStatus.connected_since:type_name -> google.protobuf.Timestamp
+	39, // 26: kvstore.v1.CheckConsistencyResponse.divergent:type_name -> kvstore.v1.DivergentRange
+	52, // 27: kvstore.v1.BeginMigrationRequest.from:type_name -> kvstore.v1.ShardMap
+	52, // 28: kvstore.v1.BeginMigrationRequest.to:type_name -> kvstore.v1.ShardMap
+	0,  // 29: kvstore.v1.MigrationStatus.state:type_name -> kvstore.v1.MigrationState
+	50, // 30: kvstore.v1.MigrationStatus.started_at:type_name -> google.protobuf.Timestamp
+	5,  // 31: kvstore.v1.ImportKeysRequest.quota:type_name -> kvstore.v1.Quota
+	44, // 32: kvstore.v1.ImportKeysRequest.records:type_name -> kvstore.v1.MigratedRecord
+	49, // 33: kvstore.v1.GetGossipMembersResponse.members:type_name -> kvstore.v1.GossipMemberStatus
+	53, // 34: kvstore.v1.GossipMemberStatus.member:type_name -> kvstore.v1.GossipMember
+	50, // 35: kvstore.v1.GossipMemberStatus.since:type_name -> google.protobuf.Timestamp
+	3,  // 36: kvstore.v1.Admin.ReloadConfig:input_type -> kvstore.v1.ReloadConfigRequest
+	9,  // 37: kvstore.v1.Admin.CreateNamespace:input_type -> kvstore.v1.CreateNamespaceRequest
+	11, // 38: kvstore.v1.Admin.DropNamespace:input_type -> kvstore.v1.DropNamespaceRequest
+	13, // 39: kvstore.v1.Admin.ListNamespaces:input_type -> kvstore.v1.ListNamespacesRequest
+	15, // 40: kvstore.v1.Admin.SetNamespaceQuota:input_type -> kvstore.v1.SetNamespaceQuotaRequest
+	17, // 41: kvstore.v1.Admin.GetUsage:input_type -> kvstore.v1.GetUsageRequest
+	20, // 42: kvstore.v1.Admin.GetSlowLog:input_type -> kvstore.v1.GetSlowLogRequest
+	22, // 43: kvstore.v1.Admin.ResetSlowLog:input_type -> kvstore.v1.ResetSlowLogRequest
+	26, // 44: kvstore.v1.Admin.GetMembership:input_type -> kvstore.v1.GetMembershipRequest
+	28, // 45: kvstore.v1.Admin.AddLearner:input_type -> kvstore.v1.AddLearnerRequest
+	29, // 46: kvstore.v1.Admin.PromoteLearner:input_type -> kvstore.v1.PromoteLearnerRequest
+	30, // 47: kvstore.v1.Admin.RemoveNode:input_type -> kvstore.v1.RemoveNodeRequest
+	31, // 48: kvstore.v1.Admin.TransferLeadership:input_type -> kvstore.v1.TransferLeadershipRequest
+	33, // 49: kvstore.v1.Admin.GetReplicationStatus:input_type -> kvstore.v1.GetReplicationStatusRequest
+	37, // 50: kvstore.v1.Admin.CheckConsistency:input_type -> kvstore.v1.CheckConsistencyRequest
+	40, // 51: kvstore.v1.Admin.BeginMigration:input_type -> kvstore.v1.BeginMigrationRequest
+	41, // 52: kvstore.v1.Admin.GetMigrationStatus:input_type -> kvstore.v1.GetMigrationStatusRequest
+	42, // 53: kvstore.v1.Admin.CommitMigration:input_type -> kvstore.v1.CommitMigrationRequest
+	45, // 54: kvstore.v1.Admin.ImportKeys:input_type -> kvstore.v1.ImportKeysRequest
+	47, // 55: kvstore.v1.Admin.GetGossipMembers:input_type -> kvstore.v1.GetGossipMembersRequest
+	4,  // 56: kvstore.v1.Admin.ReloadConfig:output_type -> kvstore.v1.ReloadConfigResponse
+	10, // 57: kvstore.v1.Admin.CreateNamespace:output_type -> kvstore.v1.CreateNamespaceResponse
+	12, // 58: kvstore.v1.Admin.DropNamespace:output_type -> kvstore.v1.DropNamespaceResponse
+	14, // 59: kvstore.v1.Admin.ListNamespaces:output_type -> kvstore.v1.ListNamespacesResponse
+	16, // 60: kvstore.v1.Admin.SetNamespaceQuota:output_type -> kvstore.v1.SetNamespaceQuotaResponse
+	18, // 61: kvstore.v1.Admin.GetUsage:output_type -> kvstore.v1.GetUsageResponse
+	21, // 62: kvstore.v1.Admin.GetSlowLog:output_type -> kvstore.v1.GetSlowLogResponse
+	23, // 63: kvstore.v1.Admin.ResetSlowLog:output_type -> kvstore.v1.ResetSlowLogResponse
+	27, // 64: kvstore.v1.Admin.GetMembership:output_type -> kvstore.v1.GetMembershipResponse
+	32, // 65: kvstore.v1.Admin.AddLearner:output_type -> kvstore.v1.MembershipChangeResponse
+	32, // 66: kvstore.v1.Admin.PromoteLearner:output_type -> kvstore.v1.MembershipChangeResponse
+	32, // 67: kvstore.v1.Admin.RemoveNode:output_type -> kvstore.v1.MembershipChangeResponse
+	32, // 68: kvstore.v1.Admin.TransferLeadership:output_type -> kvstore.v1.MembershipChangeResponse
+	34, // 69: kvstore.v1.Admin.GetReplicationStatus:output_type -> kvstore.v1.GetReplicationStatusResponse
+	38, // 70: kvstore.v1.Admin.CheckConsistency:output_type -> kvstore.v1.CheckConsistencyResponse
+	43, // 71: kvstore.v1.Admin.BeginMigration:output_type -> kvstore.v1.MigrationStatus
+	43, // 72: kvstore.v1.Admin.GetMigrationStatus:output_type -> kvstore.v1.MigrationStatus
+	43, // 73: kvstore.v1.Admin.CommitMigration:output_type -> kvstore.v1.MigrationStatus
+	46, // 74: kvstore.v1.Admin.ImportKeys:output_type -> kvstore.v1.ImportKeysResponse
+	48, // 75: kvstore.v1.Admin.GetGossipMembers:output_type -> kvstore.v1.GetGossipMembersResponse
+	56, // [56:76] is the sub-list for method output_type
+	36, // [36:56] is the sub-list for method input_type
+	36, // [36:36] is the sub-list for extension type_name
+	36, // [36:36] is the sub-list for extension extendee
+	0,  // [0:36] is the sub-list for field type_name
+}
+
+func init() { file_api_proto_admin_proto_init() }
+func file_api_proto_admin_proto_init() {
+	if File_api_proto_admin_proto != nil {
+		return
+	}
+	file_api_proto_gossip_proto_init()
+	file_api_proto_kvstore_proto_init()
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_proto_admin_proto_rawDesc), len(file_api_proto_admin_proto_rawDesc)),
+			NumEnums:      3,
+			NumMessages:   47,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_api_proto_admin_proto_goTypes,
+		DependencyIndexes: file_api_proto_admin_proto_depIdxs,
+		EnumInfos:         file_api_proto_admin_proto_enumTypes,
+		MessageInfos:      file_api_proto_admin_proto_msgTypes,
+	}.Build()
+	File_api_proto_admin_proto = out.File
+	file_api_proto_admin_proto_goTypes = nil
+	file_api_proto_admin_proto_depIdxs = nil
+}
@@ -0,0 +1,938 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: api/proto/admin.proto
+
+package pb
+
+import (
+	context "context"
+
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Admin_ReloadConfig_FullMethodName         = "/kvstore.v1.Admin/ReloadConfig"
+	Admin_CreateNamespace_FullMethodName      = "/kvstore.v1.Admin/CreateNamespace"
+	Admin_DropNamespace_FullMethodName        = "/kvstore.v1.Admin/DropNamespace"
+	Admin_ListNamespaces_FullMethodName       = "/kvstore.v1.Admin/ListNamespaces"
+	Admin_SetNamespaceQuota_FullMethodName    = "/kvstore.v1.Admin/SetNamespaceQuota"
+	Admin_GetUsage_FullMethodName             = "/kvstore.v1.Admin/GetUsage"
+	Admin_GetSlowLog_FullMethodName           = "/kvstore.v1.Admin/GetSlowLog"
+	Admin_ResetSlowLog_FullMethodName         = "/kvstore.v1.Admin/ResetSlowLog"
+	Admin_GetMembership_FullMethodName        = "/kvstore.v1.Admin/GetMembership"
+	Admin_AddLearner_FullMethodName           = "/kvstore.v1.Admin/AddLearner"
+	Admin_PromoteLearner_FullMethodName       = "/kvstore.v1.Admin/PromoteLearner"
+	Admin_RemoveNode_FullMethodName           = "/kvstore.v1.Admin/RemoveNode"
+	Admin_TransferLeadership_FullMethodName   = "/kvstore.v1.Admin/TransferLeadership"
+	Admin_GetReplicationStatus_FullMethodName = "/kvstore.v1.Admin/GetReplicationStatus"
+	Admin_CheckConsistency_FullMethodName     = "/kvstore.v1.Admin/CheckConsistency"
+	Admin_BeginMigration_FullMethodName       = "/kvstore.v1.Admin/BeginMigration"
+	Admin_GetMigrationStatus_FullMethodName   = "/kvstore.v1.Admin/GetMigrationStatus"
+	Admin_CommitMigration_FullMethodName      = "/kvstore.v1.Admin/CommitMigration"
+	Admin_ImportKeys_FullMethodName           = "/kvstore.v1.Admin/ImportKeys"
+	Admin_GetGossipMembers_FullMethodName     = "/kvstore.v1.Admin/GetGossipMembers"
+)
+
+// AdminClient is the client API for Admin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Admin exposes operational controls for a running server.
+type AdminClient interface {
+	// ReloadConfig re-reads the server configuration and applies the settings
+	// that can change at runtime.
+	ReloadConfig(ctx context.Context, in *ReloadConfigRequest, opts ...grpc.CallOption) (*ReloadConfigResponse, error)
+	CreateNamespace(ctx context.Context, in *CreateNamespaceRequest, opts ...grpc.CallOption) (*CreateNamespaceResponse, error)
+	// DropNamespace deletes a namespace and every key in it.
+	DropNamespace(ctx context.Context, in *DropNamespaceRequest, opts ...grpc.CallOption) (*DropNamespaceResponse, error)
+	ListNamespaces(ctx context.Context, in *ListNamespacesRequest, opts ...grpc.CallOption) (*ListNamespacesResponse, error)
+	SetNamespaceQuota(ctx context.Context, in *SetNamespaceQuotaRequest, opts ...grpc.CallOption) (*SetNamespaceQuotaResponse, error)
+	// GetUsage reports quotas and current usage of namespaces and principals.
+	GetUsage(ctx context.Context, in *GetUsageRequest, opts ...grpc.CallOption) (*GetUsageResponse, error)
+	// GetSlowLog returns the most recent operations that took longer than the
+	// slow log threshold, newest first.
+	GetSlowLog(ctx context.Context, in *GetSlowLogRequest, opts ...grpc.CallOption) (*GetSlowLogResponse, error)
+	ResetSlowLog(ctx context.Context, in *ResetSlowLogRequest, opts ...grpc.CallOption) (*ResetSlowLogResponse, error)
+	// GetMembership returns the Raft configuration as seen by the node
+	// serving the call. Every member can serve it.
+	GetMembership(ctx context.Context, in *GetMembershipRequest, opts ...grpc.CallOption) (*GetMembershipResponse, error)
+	// Membership changes apply one server at a time and are refused if the
+	// configuration changed concurrently. They must be sent to the leader;
+	// followers reply with a NOT_LEADER redirect.
+	//
+	// AddLearner adds a node that receives the log without voting. Start it
+	// with cluster.bootstrap off first.
+	AddLearner(ctx context.Context, in *AddLearnerRequest, opts ...grpc.CallOption) (*MembershipChangeResponse, error)
+	// PromoteLearner makes a learner a voter. Wait until it has caught up,
+	// or commits may stall until it does.
+	PromoteLearner(ctx context.Context, in *PromoteLearnerRequest, opts ...grpc.CallOption) (*MembershipChangeResponse, error)
+	// RemoveNode removes a learner or voter. The last voter cannot be
+	// removed.
+	RemoveNode(ctx context.Context, in *RemoveNodeRequest, opts ...grpc.CallOption) (*MembershipChangeResponse, error)
+	// TransferLeadership hands leadership to another voter and waits until
+	// it has taken over.
+	TransferLeadership(ctx context.Context, in *TransferLeadershipRequest, opts ...grpc.CallOption) (*MembershipChangeResponse, error)
+	// GetReplicationStatus reports the server's part in primary-replica
+	// replication: a primary lists its replicas, a replica how far behind the
+	// primary it is.
+	GetReplicationStatus(ctx context.Context, in *GetReplicationStatusRequest, opts ...grpc.CallOption) (*GetReplicationStatusResponse, error)
+	// CheckConsistency compares a replica with its primary using Merkle trees
+	// and reports the key ranges where they differ, without repairing them.
+	// Only replicas serve it; they also repair in the background when
+	// anti-entropy is enabled. Raft clusters and shards have no such check.
+	CheckConsistency(ctx context.Context, in *CheckConsistencyRequest, opts ...grpc.CallOption) (*CheckConsistencyResponse, error)
+	// Rebalancing moves keys between shards while they keep serving. Every
+	// node of the old and the new shard map is sent BeginMigration; each
+	// then pushes the keys it loses to their new owners. While a key is
+	// moving, its old owner serves it if it still holds it and otherwise
+	// answers with an ASK redirect to the new owner, which serves requests
+	// carrying the x-kvstore-asking header. Once every node reports
+	// MIGRATION_STATE_DONE, each is sent CommitMigration and routes by the
+	// new map alone.
+	//
+	// BeginMigration is idempotent, so an interrupted rebalance can be
+	// resumed by sending it again.
+	BeginMigration(ctx context.Context, in *BeginMigrationRequest, opts ...grpc.CallOption) (*MigrationStatus, error)
+	GetMigrationStatus(ctx context.Context, in *GetMigrationStatusRequest, opts ...grpc.CallOption) (*MigrationStatus, error)
+	// CommitMigration fails until the node has handed over all of its
+	// moving keys.
+	CommitMigration(ctx context.Context, in *CommitMigrationRequest, opts ...grpc.CallOption) (*MigrationStatus, error)
+	// ImportKeys is how nodes hand keys to their new owner during a
+	// migration. Records keep their absolute expiry and owner.
+	ImportKeys(ctx context.Context, in *ImportKeysRequest, opts ...grpc.CallOption) (*ImportKeysResponse, error)
+	// GetGossipMembers returns the server's view of the deployment, as
+	// learned by gossip: every server it has heard of, whether it is alive,
+	// and what it published about itself.
+	GetGossipMembers(ctx context.Context, in *GetGossipMembersRequest, opts ...grpc.CallOption) (*GetGossipMembersResponse, error)
+}
+
+type adminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAdminClient(cc grpc.ClientConnInterface) AdminClient {
+	return &adminClient{cc}
+}
+
+func (c *adminClient) ReloadConfig(ctx context.Context, in *ReloadConfigRequest, opts ...grpc.CallOption) (*ReloadConfigResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReloadConfigResponse)
+	err := c.cc.Invoke(ctx, Admin_ReloadConfig_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) CreateNamespace(ctx context.Context, in *CreateNamespaceRequest, opts ...grpc.CallOption) (*CreateNamespaceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreateNamespaceResponse)
+	err := c.cc.Invoke(ctx, Admin_CreateNamespace_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) DropNamespace(ctx context.Context, in *DropNamespaceRequest, opts ...grpc.CallOption) (*DropNamespaceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DropNamespaceResponse)
+	err := c.cc.Invoke(ctx, Admin_DropNamespace_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) ListNamespaces(ctx context.Context, in *ListNamespacesRequest, opts ...grpc.CallOption) (*ListNamespacesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListNamespacesResponse)
+	err := c.cc.Invoke(ctx, Admin_ListNamespaces_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) SetNamespaceQuota(ctx context.Context, in *SetNamespaceQuotaRequest, opts ...grpc.CallOption) (*SetNamespaceQuotaResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetNamespaceQuotaResponse)
+	err := c.cc.Invoke(ctx, Admin_SetNamespaceQuota_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) GetUsage(ctx context.Context, in *GetUsageRequest, opts ...grpc.CallOption) (*GetUsageResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetUsageResponse)
+	err := c.cc.Invoke(ctx, Admin_GetUsage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) GetSlowLog(ctx context.Context, in *GetSlowLogRequest, opts ...grpc.CallOption) (*GetSlowLogResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetSlowLogResponse)
+	err := c.cc.Invoke(ctx, Admin_GetSlowLog_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) ResetSlowLog(ctx context.Context, in *ResetSlowLogRequest, opts ...grpc.CallOption) (*ResetSlowLogResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ResetSlowLogResponse)
+	err := c.cc.Invoke(ctx, Admin_ResetSlowLog_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) GetMembership(ctx context.Context, in *GetMembershipRequest, opts ...grpc.CallOption) (*GetMembershipResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetMembershipResponse)
+	err := c.cc.Invoke(ctx, Admin_GetMembership_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) AddLearner(ctx context.Context, in *AddLearnerRequest, opts ...grpc.CallOption) (*MembershipChangeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MembershipChangeResponse)
+	err := c.cc.Invoke(ctx, Admin_AddLearner_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) PromoteLearner(ctx context.Context, in *PromoteLearnerRequest, opts ...grpc.CallOption) (*MembershipChangeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MembershipChangeResponse)
+	err := c.cc.Invoke(ctx, Admin_PromoteLearner_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) RemoveNode(ctx context.Context, in *RemoveNodeRequest, opts ...grpc.CallOption) (*MembershipChangeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MembershipChangeResponse)
+	err := c.cc.Invoke(ctx, Admin_RemoveNode_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) TransferLeadership(ctx context.Context, in *TransferLeadershipRequest, opts ...grpc.CallOption) (*MembershipChangeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MembershipChangeResponse)
+	err := c.cc.Invoke(ctx, Admin_TransferLeadership_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) GetReplicationStatus(ctx context.Context, in *GetReplicationStatusRequest, opts ...grpc.CallOption) (*GetReplicationStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetReplicationStatusResponse)
+	err := c.cc.Invoke(ctx, Admin_GetReplicationStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) CheckConsistency(ctx context.Context, in *CheckConsistencyRequest, opts ...grpc.CallOption) (*CheckConsistencyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CheckConsistencyResponse)
+	err := c.cc.Invoke(ctx, Admin_CheckConsistency_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) BeginMigration(ctx context.Context, in *BeginMigrationRequest, opts ...grpc.CallOption) (*MigrationStatus, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MigrationStatus)
+	err := c.cc.Invoke(ctx, Admin_BeginMigration_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) GetMigrationStatus(ctx context.Context, in *GetMigrationStatusRequest, opts ...grpc.CallOption) (*MigrationStatus, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MigrationStatus)
+	err := c.cc.Invoke(ctx, Admin_GetMigrationStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) CommitMigration(ctx context.Context, in *CommitMigrationRequest, opts ...grpc.CallOption) (*MigrationStatus, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MigrationStatus)
+	err := c.cc.Invoke(ctx, Admin_CommitMigration_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) ImportKeys(ctx context.Context, in *ImportKeysRequest, opts ...grpc.CallOption) (*ImportKeysResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ImportKeysResponse)
+	err := c.cc.Invoke(ctx, Admin_ImportKeys_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) GetGossipMembers(ctx context.Context, in *GetGossipMembersRequest, opts ...grpc.CallOption) (*GetGossipMembersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetGossipMembersResponse)
+	err := c.cc.Invoke(ctx, Admin_GetGossipMembers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AdminServer is the server API for Admin service.
+// All implementations must embed UnimplementedAdminServer
+// for forward compatibility.
+//
+// Admin exposes operational controls for a running server.
+type AdminServer interface {
+	// ReloadConfig re-reads the server configuration and applies the settings
+	// that can change at runtime.
+	ReloadConfig(context.Context, *ReloadConfigRequest) (*ReloadConfigResponse, error)
+	CreateNamespace(context.Context, *CreateNamespaceRequest) (*CreateNamespaceResponse, error)
+	// DropNamespace deletes a namespace and every key in it.
+	DropNamespace(context.Context, *DropNamespaceRequest) (*DropNamespaceResponse, error)
+	ListNamespaces(context.Context, *ListNamespacesRequest) (*ListNamespacesResponse, error)
+	SetNamespaceQuota(context.Context, *SetNamespaceQuotaRequest) (*SetNamespaceQuotaResponse, error)
+	// GetUsage reports quotas and current usage of namespaces and principals.
+	GetUsage(context.Context, *GetUsageRequest) (*GetUsageResponse, error)
+	// GetSlowLog returns the most recent operations that took longer than the
+	// slow log threshold, newest first.
+	GetSlowLog(context.Context, *GetSlowLogRequest) (*GetSlowLogResponse, error)
+	ResetSlowLog(context.Context, *ResetSlowLogRequest) (*ResetSlowLogResponse, error)
+	// GetMembership returns the Raft configuration as seen by the node
+	// serving the call. Every member can serve it.
+	GetMembership(context.Context, *GetMembershipRequest) (*GetMembershipResponse, error)
+	// Membership changes apply one server at a time and are refused if the
+	// configuration changed concurrently. They must be sent to the leader;
+	// followers reply with a NOT_LEADER redirect.
+	//
+	// AddLearner adds a node that receives the log without voting. Start it
+	// with cluster.bootstrap off first.
+	AddLearner(context.Context, *AddLearnerRequest) (*MembershipChangeResponse, error)
+	// PromoteLearner makes a learner a voter. Wait until it has caught up,
+	// or commits may stall until it does.
+	PromoteLearner(context.Context, *PromoteLearnerRequest) (*MembershipChangeResponse, error)
+	// RemoveNode removes a learner or voter. The last voter cannot be
+	// removed.
+	RemoveNode(context.Context, *RemoveNodeRequest) (*MembershipChangeResponse, error)
+	// TransferLeadership hands leadership to another voter and waits until
+	// it has taken over.
+	TransferLeadership(context.Context, *TransferLeadershipRequest) (*MembershipChangeResponse, error)
+	// GetReplicationStatus reports the server's part in primary-replica
+	// replication: a primary lists its replicas, a replica how far behind the
+	// primary it is.
+	GetReplicationStatus(context.Context, *GetReplicationStatusRequest) (*GetReplicationStatusResponse, error)
+	// CheckConsistency compares a replica with its primary using Merkle trees
+	// and reports the key ranges where they differ, without repairing them.
+	// Only replicas serve it; they also repair in the background when
+	// anti-entropy is enabled. Raft clusters and shards have no such check.
+	CheckConsistency(context.Context, *CheckConsistencyRequest) (*CheckConsistencyResponse, error)
+	// Rebalancing moves keys between shards while they keep serving. Every
+	// node of the old and the new shard map is sent BeginMigration; each
+	// then pushes the keys it loses to their new owners. While a key is
+	// moving, its old owner serves it if it still holds it and otherwise
+	// answers with an ASK redirect to the new owner, which serves requests
+	// carrying the x-kvstore-asking header. Once every node reports
+	// MIGRATION_STATE_DONE, each is sent CommitMigration and routes by the
+	// new map alone.
+	//
+	// BeginMigration is idempotent, so an interrupted rebalance can be
+	// resumed by sending it again.
+	BeginMigration(context.Context, *BeginMigrationRequest) (*MigrationStatus, error)
+	GetMigrationStatus(context.Context, *GetMigrationStatusRequest) (*MigrationStatus, error)
+	// CommitMigration fails until the node has handed over all of its
+	// moving keys.
+	CommitMigration(context.Context, *CommitMigrationRequest) (*MigrationStatus, error)
+	// ImportKeys is how nodes hand keys to their new owner during a
+	// migration. Records keep their absolute expiry and owner.
+	ImportKeys(context.Context, *ImportKeysRequest) (*ImportKeysResponse, error)
+	// GetGossipMembers returns the server's view of the deployment, as
+	// learned by gossip: every server it has heard of, whether it is alive,
+	// and what it published about itself.
+	GetGossipMembers(context.Context, *GetGossipMembersRequest) (*GetGossipMembersResponse, error)
+	mustEmbedUnimplementedAdminServer()
+}
+
+// UnimplementedAdminServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAdminServer struct{}
+
+func (UnimplementedAdminServer) ReloadConfig(context.Context, *ReloadConfigRequest) (*ReloadConfigResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReloadConfig not implemented")
+}
+func (UnimplementedAdminServer) CreateNamespace(context.Context, *CreateNamespaceRequest) (*CreateNamespaceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateNamespace not implemented")
+}
+func (UnimplementedAdminServer) DropNamespace(context.Context, *DropNamespaceRequest) (*DropNamespaceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DropNamespace not implemented")
+}
+func (UnimplementedAdminServer) ListNamespaces(context.Context, *ListNamespacesRequest) (*ListNamespacesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListNamespaces not implemented")
+}
+func (UnimplementedAdminServer) SetNamespaceQuota(context.Context, *SetNamespaceQuotaRequest) (*SetNamespaceQuotaResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetNamespaceQuota not implemented")
+}
+func (UnimplementedAdminServer) GetUsage(context.Context, *GetUsageRequest) (*GetUsageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUsage not implemented")
+}
+func (UnimplementedAdminServer) GetSlowLog(context.Context, *GetSlowLogRequest) (*GetSlowLogResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSlowLog not implemented")
+}
+func (UnimplementedAdminServer) ResetSlowLog(context.Context, *ResetSlowLogRequest) (*ResetSlowLogResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResetSlowLog not implemented")
+}
+func (UnimplementedAdminServer) GetMembership(context.Context, *GetMembershipRequest) (*GetMembershipResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMembership not implemented")
+}
+func (UnimplementedAdminServer) AddLearner(context.Context, *AddLearnerRequest) (*MembershipChangeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddLearner not implemented")
+}
+func (UnimplementedAdminServer) PromoteLearner(context.Context, *PromoteLearnerRequest) (*MembershipChangeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PromoteLearner not implemented")
+}
+func (UnimplementedAdminServer) RemoveNode(context.Context, *RemoveNodeRequest) (*MembershipChangeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveNode not implemented")
+}
+func (UnimplementedAdminServer) TransferLeadership(context.Context, *TransferLeadershipRequest) (*MembershipChangeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TransferLeadership not implemented")
+}
+func (UnimplementedAdminServer) GetReplicationStatus(context.Context, *GetReplicationStatusRequest) (*GetReplicationStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetReplicationStatus not implemented")
+}
+func (UnimplementedAdminServer) CheckConsistency(context.Context, *CheckConsistencyRequest) (*CheckConsistencyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CheckConsistency not implemented")
+}
+func (UnimplementedAdminServer) BeginMigration(context.Context, *BeginMigrationRequest) (*MigrationStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BeginMigration not implemented")
+}
+func (UnimplementedAdminServer) GetMigrationStatus(context.Context, *GetMigrationStatusRequest) (*MigrationStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMigrationStatus not implemented")
+}
+func (UnimplementedAdminServer) CommitMigration(context.Context, *CommitMigrationRequest) (*MigrationStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CommitMigration not implemented")
+}
+func (UnimplementedAdminServer) ImportKeys(context.Context, *ImportKeysRequest) (*ImportKeysResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ImportKeys not implemented")
+}
+func (UnimplementedAdminServer) GetGossipMembers(context.Context, *GetGossipMembersRequest) (*GetGossipMembersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetGossipMembers not implemented")
+}
+func (UnimplementedAdminServer) mustEmbedUnimplementedAdminServer() {}
+func (UnimplementedAdminServer) testEmbeddedByValue()               {}
+
+// UnsafeAdminServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AdminServer will
+// result in compilation errors.
+type UnsafeAdminServer interface {
+	mustEmbedUnimplementedAdminServer()
+}
+
+func RegisterAdminServer(s grpc.ServiceRegistrar, srv AdminServer) {
+	// If the following call pancis, it indicates UnimplementedAdminServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Admin_ServiceDesc, srv)
+}
+
+func _Admin_ReloadConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReloadConfigRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).ReloadConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_ReloadConfig_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).ReloadConfig(ctx, req.(*ReloadConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_CreateNamespace_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateNamespaceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).CreateNamespace(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_CreateNamespace_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).CreateNamespace(ctx, req.(*CreateNamespaceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_DropNamespace_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DropNamespaceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).DropNamespace(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_DropNamespace_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).DropNamespace(ctx, req.(*DropNamespaceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_ListNamespaces_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListNamespacesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).ListNamespaces(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_ListNamespaces_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).ListNamespaces(ctx, req.(*ListNamespacesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_SetNamespaceQuota_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetNamespaceQuotaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).SetNamespaceQuota(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_SetNamespaceQuota_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).SetNamespaceQuota(ctx, req.(*SetNamespaceQuotaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_GetUsage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUsageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).GetUsage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_GetUsage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).GetUsage(ctx, req.(*GetUsageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_GetSlowLog_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSlowLogRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).GetSlowLog(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_GetSlowLog_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).GetSlowLog(ctx, req.(*GetSlowLogRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_ResetSlowLog_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResetSlowLogRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).ResetSlowLog(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_ResetSlowLog_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).ResetSlowLog(ctx, req.(*ResetSlowLogRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_GetMembership_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMembershipRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).GetMembership(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_GetMembership_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).GetMembership(ctx, req.(*GetMembershipRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_AddLearner_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddLearnerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).AddLearner(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_AddLearner_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).AddLearner(ctx, req.(*AddLearnerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_PromoteLearner_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PromoteLearnerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).PromoteLearner(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_PromoteLearner_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).PromoteLearner(ctx, req.(*PromoteLearnerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_RemoveNode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveNodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).RemoveNode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_RemoveNode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).RemoveNode(ctx, req.(*RemoveNodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_TransferLeadership_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransferLeadershipRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).TransferLeadership(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_TransferLeadership_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).TransferLeadership(ctx, req.(*TransferLeadershipRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_GetReplicationStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetReplicationStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).GetReplicationStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_GetReplicationStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).GetReplicationStatus(ctx, req.(*GetReplicationStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_CheckConsistency_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckConsistencyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).CheckConsistency(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_CheckConsistency_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).CheckConsistency(ctx, req.(*CheckConsistencyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_BeginMigration_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BeginMigrationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).BeginMigration(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_BeginMigration_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).BeginMigration(ctx, req.(*BeginMigrationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_GetMigrationStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMigrationStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).GetMigrationStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_GetMigrationStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).GetMigrationStatus(ctx, req.(*GetMigrationStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_CommitMigration_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommitMigrationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).CommitMigration(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_CommitMigration_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).CommitMigration(ctx, req.(*CommitMigrationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_ImportKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImportKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).ImportKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_ImportKeys_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).ImportKeys(ctx, req.(*ImportKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_GetGossipMembers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetGossipMembersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).GetGossipMembers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_GetGossipMembers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).GetGossipMembers(ctx, req.(*GetGossipMembersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Admin_ServiceDesc is the grpc.ServiceDesc for Admin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Admin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "kvstore.v1.Admin",
+	HandlerType: (*AdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ReloadConfig",
+			Handler:    _Admin_ReloadConfig_Handler,
+		},
+		{
+			MethodName: "CreateNamespace",
+			Handler:    _Admin_CreateNamespace_Handler,
+		},
+		{
+			MethodName: "DropNamespace",
+			Handler:    _Admin_DropNamespace_Handler,
+		},
+		{
+			MethodName: "ListNamespaces",
+			Handler:    _Admin_ListNamespaces_Handler,
+		},
+		{
+			MethodName: "SetNamespaceQuota",
+			Handler:    _Admin_SetNamespaceQuota_Handler,
+		},
+		{
+			MethodName: "GetUsage",
+			Handler:    _Admin_GetUsage_Handler,
+		},
+		{
+			MethodName: "GetSlowLog",
+			Handler:    _Admin_GetSlowLog_Handler,
+		},
+		{
+			MethodName: "ResetSlowLog",
+			Handler:    _Admin_ResetSlowLog_Handler,
+		},
+		{
+			MethodName: "GetMembership",
+			Handler:    _Admin_GetMembership_Handler,
+		},
+		{
+			MethodName: "AddLearner",
+			Handler:    _Admin_AddLearner_Handler,
+		},
+		{
+			MethodName: "PromoteLearner",
+			Handler:    _Admin_PromoteLearner_Handler,
+		},
+		{
+			MethodName: "RemoveNode",
+			Handler:    _Admin_RemoveNode_Handler,
+		},
+		{
+			MethodName: "TransferLeadership",
+			Handler:    _Admin_TransferLeadership_Handler,
+		},
+		{
+			MethodName: "GetReplicationStatus",
+			Handler:    _Admin_GetReplicationStatus_Handler,
+		},
+		{
+			MethodName: "CheckConsistency",
+			Handler:    _Admin_CheckConsistency_Handler,
+		},
+		{
+			MethodName: "BeginMigration",
+			Handler:    _Admin_BeginMigration_Handler,
+		},
+		{
+			MethodName: "GetMigrationStatus",
+			Handler:    _Admin_GetMigrationStatus_Handler,
+		},
+		{
+			MethodName: "CommitMigration",
+			Handler:    _Admin_CommitMigration_Handler,
+		},
+		{
+			MethodName: "ImportKeys",
+			Handler:    _Admin_ImportKeys_Handler,
+		},
+		{
+			MethodName: "GetGossipMembers",
+			Handler:    _Admin_GetGossipMembers_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "api/proto/admin.proto",
+}
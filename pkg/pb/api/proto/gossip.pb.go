@@ -0,0 +1,592 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: api/proto/gossip.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type MemberState int32
+
+const (
+	MemberState_MEMBER_STATE_UNSPECIFIED MemberState = 0
+	MemberState_MEMBER_STATE_ALIVE       MemberState = 1
+	// The member missed a probe. It is declared dead unless it refutes the
+	// suspicion in time.
+	MemberState_MEMBER_STATE_SUSPECT MemberState = 2
+	MemberState_MEMBER_STATE_DEAD    MemberState = 3
+	// The member shut down and said so.
+	MemberState_MEMBER_STATE_LEFT MemberState = 4
+)
+
+// Enum value maps for MemberState.
+var (
+	MemberState_name = map[int32]string{
+		0: "MEMBER_STATE_UNSPECIFIED",
+		1: "MEMBER_STATE_ALIVE",
+		2: "MEMBER_STATE_SUSPECT",
+		3: "MEMBER_STATE_DEAD",
+		4: "MEMBER_STATE_LEFT",
+	}
+	MemberState_value = map[string]int32{
+		"MEMBER_STATE_UNSPECIFIED": 0,
+		"MEMBER_STATE_ALIVE":       1,
+		"MEMBER_STATE_SUSPECT":     2,
+		"MEMBER_STATE_DEAD":        3,
+		"MEMBER_STATE_LEFT":        4,
+	}
+)
+
+func (x MemberState) Enum() *MemberState {
+	p := new(MemberState)
+	*p = x
+	return p
+}
+
+func (x MemberState) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (MemberState) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_proto_gossip_proto_enumTypes[0].Descriptor()
+}
+
+func (MemberState) Type() protoreflect.EnumType {
+	return &file_api_proto_gossip_proto_enumTypes[0]
+}
+
+func (x MemberState) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use MemberState.Descriptor instead.
+func (MemberState) EnumDescriptor() ([]byte, []int) {
+	return file_api_proto_gossip_proto_rawDescGZIP(), []int{0}
+}
+
+// NodeMeta is what a server publishes about itself.
+type NodeMeta struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Replication or cluster role: primary, replica, active, cluster or
+	// standalone.
+	Role string `protobuf:"bytes,1,opt,name=role,proto3" json:"role,omitempty"`
+	// Server build version.
+	Version string `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	// The server's node ID in the shard map and the version of the map it
+	// serves; empty and zero when keys are not sharded.
+	ShardId         string `protobuf:"bytes,3,opt,name=shard_id,json=shardId,proto3" json:"shard_id,omitempty"`
+	ShardMapVersion uint64 `protobuf:"varint,4,opt,name=shard_map_version,json=shardMapVersion,proto3" json:"shard_map_version,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *NodeMeta) Reset() {
+	*x = NodeMeta{}
+	mi := &file_api_proto_gossip_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeMeta) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeMeta) ProtoMessage() {}
+
+func (x *NodeMeta) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_gossip_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeMeta.ProtoReflect.Descriptor instead.
+func (*NodeMeta) Descriptor() ([]byte, []int) {
+	return file_api_proto_gossip_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *NodeMeta) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *NodeMeta) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *NodeMeta) GetShardId() string {
+	if x != nil {
+		return x.ShardId
+	}
+	return ""
+}
+
+func (x *NodeMeta) GetShardMapVersion() uint64 {
+	if x != nil {
+		return x.ShardMapVersion
+	}
+	return 0
+}
+
+// GossipMember is what one server believes about a member. Of two
+// beliefs, the one with the higher incarnation wins, and at the same
+// incarnation the worse state. Only the member itself raises its
+// incarnation, to refute a suspicion or publish new metadata.
+type GossipMember struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// gRPC address the member is reached at.
+	Addr          string      `protobuf:"bytes,2,opt,name=addr,proto3" json:"addr,omitempty"`
+	Incarnation   uint64      `protobuf:"varint,3,opt,name=incarnation,proto3" json:"incarnation,omitempty"`
+	State         MemberState `protobuf:"varint,4,opt,name=state,proto3,enum=kvstore.v1.MemberState" json:"state,omitempty"`
+	Meta          *NodeMeta   `protobuf:"bytes,5,opt,name=meta,proto3" json:"meta,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GossipMember) Reset() {
+	*x = GossipMember{}
+	mi := &file_api_proto_gossip_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GossipMember) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GossipMember) ProtoMessage() {}
+
+func (x *GossipMember) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_gossip_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GossipMember.ProtoReflect.Descriptor instead.
+func (*GossipMember) Descriptor() ([]byte, []int) {
+	return file_api_proto_gossip_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GossipMember) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *GossipMember) GetAddr() string {
+	if x != nil {
+		return x.Addr
+	}
+	return ""
+}
+
+func (x *GossipMember) GetIncarnation() uint64 {
+	if x != nil {
+		return x.Incarnation
+	}
+	return 0
+}
+
+func (x *GossipMember) GetState() MemberState {
+	if x != nil {
+		return x.State
+	}
+	return MemberState_MEMBER_STATE_UNSPECIFIED
+}
+
+func (x *GossipMember) GetMeta() *NodeMeta {
+	if x != nil {
+		return x.Meta
+	}
+	return nil
+}
+
+type PingRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// ID of the member the caller means to probe.
+	TargetId      string          `protobuf:"bytes,1,opt,name=target_id,json=targetId,proto3" json:"target_id,omitempty"`
+	Updates       []*GossipMember `protobuf:"bytes,2,rep,name=updates,proto3" json:"updates,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PingRequest) Reset() {
+	*x = PingRequest{}
+	mi := &file_api_proto_gossip_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PingRequest) ProtoMessage() {}
+
+func (x *PingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_gossip_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PingRequest.ProtoReflect.Descriptor instead.
+func (*PingRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_gossip_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *PingRequest) GetTargetId() string {
+	if x != nil {
+		return x.TargetId
+	}
+	return ""
+}
+
+func (x *PingRequest) GetUpdates() []*GossipMember {
+	if x != nil {
+		return x.Updates
+	}
+	return nil
+}
+
+type PingResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Updates       []*GossipMember        `protobuf:"bytes,1,rep,name=updates,proto3" json:"updates,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PingResponse) Reset() {
+	*x = PingResponse{}
+	mi := &file_api_proto_gossip_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PingResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PingResponse) ProtoMessage() {}
+
+func (x *PingResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_gossip_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PingResponse.ProtoReflect.Descriptor instead.
+func (*PingResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_gossip_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *PingResponse) GetUpdates() []*GossipMember {
+	if x != nil {
+		return x.Updates
+	}
+	return nil
+}
+
+type PingReqRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TargetId      string                 `protobuf:"bytes,1,opt,name=target_id,json=targetId,proto3" json:"target_id,omitempty"`
+	TargetAddr    string                 `protobuf:"bytes,2,opt,name=target_addr,json=targetAddr,proto3" json:"target_addr,omitempty"`
+	Updates       []*GossipMember        `protobuf:"bytes,3,rep,name=updates,proto3" json:"updates,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PingReqRequest) Reset() {
+	*x = PingReqRequest{}
+	mi := &file_api_proto_gossip_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PingReqRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PingReqRequest) ProtoMessage() {}
+
+func (x *PingReqRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_gossip_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PingReqRequest.ProtoReflect.Descriptor instead.
+func (*PingReqRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_gossip_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *PingReqRequest) GetTargetId() string {
+	if x != nil {
+		return x.TargetId
+	}
+	return ""
+}
+
+func (x *PingReqRequest) GetTargetAddr() string {
+	if x != nil {
+		return x.TargetAddr
+	}
+	return ""
+}
+
+func (x *PingReqRequest) GetUpdates() []*GossipMember {
+	if x != nil {
+		return x.Updates
+	}
+	return nil
+}
+
+type SyncRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Members       []*GossipMember        `protobuf:"bytes,1,rep,name=members,proto3" json:"members,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SyncRequest) Reset() {
+	*x = SyncRequest{}
+	mi := &file_api_proto_gossip_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SyncRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SyncRequest) ProtoMessage() {}
+
+func (x *SyncRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_gossip_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SyncRequest.ProtoReflect.Descriptor instead.
+func (*SyncRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_gossip_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *SyncRequest) GetMembers() []*GossipMember {
+	if x != nil {
+		return x.Members
+	}
+	return nil
+}
+
+type SyncResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Members       []*GossipMember        `protobuf:"bytes,1,rep,name=members,proto3" json:"members,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SyncResponse) Reset() {
+	*x = SyncResponse{}
+	mi := &file_api_proto_gossip_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SyncResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SyncResponse) ProtoMessage() {}
+
+func (x *SyncResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_gossip_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SyncResponse.ProtoReflect.Descriptor instead.
+func (*SyncResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_gossip_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *SyncResponse) GetMembers() []*GossipMember {
+	if x != nil {
+		return x.Members
+	}
+	return nil
+}
+
+var File_api_proto_gossip_proto protoreflect.FileDescriptor
+
+const file_api_proto_gossip_proto_rawDesc = "" +
+	"\n" +
+	"\x16api/proto/gossip.proto\x12\n" +
+	"kvstore.v1\"\x7f\n" +
+	"\bNodeMeta\x12\x12\n" +
+	"\x04role\x18\x01 \x01(\tR\x04role\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\tR\aversion\x12\x19\n" +
+	"\bshard_id\x18\x03 \x01(\tR\ashardId\x12*\n" +
+	"\x11shard_map_version\x18\x04 \x01(\x04R\x0fshardMapVersion\"\xad\x01\n" +
+	"\fGossipMember\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04addr\x18\x02 \x01(\tR\x04addr\x12 \n" +
+	"\vincarnation\x18\x03 \x01(\x04R\vincarnation\x12-\n" +
+	"\x05state\x18\x04 \x01(\x0e2\x17.kvstore.v1.MemberStateR\x05state\x12(\n" +
+	"\x04meta\x18\x05 \x01(\v2\x14.kvstore.v1.NodeMetaR\x04meta\"^\n" +
+	"\vPingRequest\x12\x1b\n" +
+	"\ttarget_id\x18\x01 \x01(\tR\btargetId\x122\n" +
+	"\aupdates\x18\x02 \x03(\v2\x18.kvstore.v1.GossipMemberR\aupdates\"B\n" +
+	"\fPingResponse\x122\n" +
+	"\aupdates\x18\x01 \x03(\v2\x18.kvstore.v1.GossipMemberR\aupdates\"\x82\x01\n" +
+	"\x0ePingReqRequest\x12\x1b\n" +
+	"\ttarget_id\x18\x01 \x01(\tR\btargetId\x12\x1f\n" +
+	"\vtarget_addr\x18\x02 \x01(\tR\n" +
+	"targetAddr\x122\n" +
+	"\aupdates\x18\x03 \x03(\v2\x18.kvstore.v1.GossipMemberR\aupdates\"A\n" +
+	"\vSyncRequest\x122\n" +
+	"\amembers\x18\x01 \x03(\v2\x18.kvstore.v1.GossipMemberR\amembers\"B\n" +
+	"\fSyncResponse\x122\n" +
+	"\amembers\x18\x01 \x03(\v2\x18.kvstore.v1.GossipMemberR\amembers*\x8b\x01\n" +
+	"\vMemberState\x12\x1c\n" +
+	"\x18MEMBER_STATE_UNSPECIFIED\x10\x00\x12\x16\n" +
+	"\x12MEMBER_STATE_ALIVE\x10\x01\x12\x18\n" +
+	"\x14MEMBER_STATE_SUSPECT\x10\x02\x12\x15\n" +
+	"\x11MEMBER_STATE_DEAD\x10\x03\x12\x15\n" +
+	"\x11MEMBER_STATE_LEFT\x10\x042\xbf\x01\n" +
+	"\x06Gossip\x129\n" +
+	"\x04Ping\x12\x17.kvstore.v1.PingRequest\x1a\x18.kvstore.v1.PingResponse\x12?\n" +
+	"\aPingReq\x12\x1a.kvstore.v1.PingReqRequest\x1a\x18.kvstore.v1.PingResponse\x129\n" +
+	"\x04Sync\x12\x17.kvstore.v1.SyncRequest\x1a\x18.kvstore.v1.SyncResponseB,Z*github.com/khuongnguyenBlue/kvstore/pkg/pbb\x06proto3"
+
+var (
+	file_api_proto_gossip_proto_rawDescOnce sync.Once
+	file_api_proto_gossip_proto_rawDescData []byte
+)
+
+func file_api_proto_gossip_proto_rawDescGZIP() []byte {
+	file_api_proto_gossip_proto_rawDescOnce.Do(func() {
+		file_api_proto_gossip_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_api_proto_gossip_proto_rawDesc), len(file_api_proto_gossip_proto_rawDesc)))
+	})
+	return file_api_proto_gossip_proto_rawDescData
+}
+
+var file_api_proto_gossip_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_api_proto_gossip_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_api_proto_gossip_proto_goTypes = []any{
+	(MemberState)(0),       // 0: kvstore.v1.MemberState
+	(*NodeMeta)(nil),       // 1: kvstore.v1.NodeMeta
+	(*GossipMember)(nil),   // 2: kvstore.v1.GossipMember
+	(*PingRequest)(nil),    // 3: kvstore.v1.PingRequest
+	(*PingResponse)(nil),   // 4: kvstore.v1.PingResponse
+	(*PingReqRequest)(nil), // 5: kvstore.v1.PingReqRequest
+	(*SyncRequest)(nil),    // 6: kvstore.v1.SyncRequest
+	(*SyncResponse)(nil),   // 7: kvstore.v1.SyncResponse
+}
+var file_api_proto_gossip_proto_depIdxs = []int32{
+	0,  // 0: kvstore.v1.GossipMember.state:type_name -> kvstore.v1.MemberState
+	1,  // 1: kvstore.v1.GossipMember.meta:type_name -> kvstore.v1.NodeMeta
+	2,  // 2: kvstore.v1.PingRequest.updates:type_name -> kvstore.v1.GossipMember
+	2,  // 3: kvstore.v1.PingResponse.updates:type_name -> kvstore.v1.GossipMember
+	2,  // 4: kvstore.v1.PingReqRequest.updates:type_name -> kvstore.v1.GossipMember
+	2,  // 5: kvstore.v1.SyncRequest.members:type_name -> kvstore.v1.GossipMember
+	2,  // 6: kvstore.v1.SyncResponse.members:type_name -> kvstore.v1.GossipMember
+	3,  // 7: kvstore.v1.Gossip.Ping:input_type -> kvstore.v1.PingRequest
+	5,  // 8: kvstore.v1.Gossip.PingReq:input_type -> kvstore.v1.PingReqRequest
+	6,  // 9: kvstore.v1.Gossip.Sync:input_type -> kvstore.v1.SyncRequest
+	4,  // 10: kvstore.v1.Gossip.Ping:output_type -> kvstore.v1.PingResponse
+	4,  // 11: kvstore.v1.Gossip.PingReq:output_type -> kvstore.v1.PingResponse
+	7,  // 12: kvstore.v1.Gossip.Sync:output_type -> kvstore.v1.SyncResponse
+	10, // [10:13] is the sub-list for method output_type
+	7,  // [7:10] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
+}
+
+func init() { file_api_proto_gossip_proto_init() }
+func file_api_proto_gossip_proto_init() {
+	if File_api_proto_gossip_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_proto_gossip_proto_rawDesc), len(file_api_proto_gossip_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_api_proto_gossip_proto_goTypes,
+		DependencyIndexes: file_api_proto_gossip_proto_depIdxs,
+		EnumInfos:         file_api_proto_gossip_proto_enumTypes,
+		MessageInfos:      file_api_proto_gossip_proto_msgTypes,
+	}.Build()
+	File_api_proto_gossip_proto = out.File
+	file_api_proto_gossip_proto_goTypes = nil
+	file_api_proto_gossip_proto_depIdxs = nil
+}
@@ -0,0 +1,222 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: api/proto/gossip.proto
+
+package pb
+
+import (
+	context "context"
+
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Gossip_Ping_FullMethodName    = "/kvstore.v1.Gossip/Ping"
+	Gossip_PingReq_FullMethodName = "/kvstore.v1.Gossip/PingReq"
+	Gossip_Sync_FullMethodName    = "/kvstore.v1.Gossip/Sync"
+)
+
+// GossipClient is the client API for Gossip service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Gossip carries the SWIM membership protocol between servers. Probes
+// detect members that failed and piggyback the membership changes each
+// server learned, and full exchanges of the membership let new servers
+// join and partitioned ones catch up. Servers call it on each other; it
+// requires the admin permission.
+type GossipClient interface {
+	// Ping probes the server, which acknowledges it unless it is not the
+	// member the caller meant.
+	Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingResponse, error)
+	// PingReq asks the server to probe a member the caller could not reach,
+	// and acknowledges if the member answered.
+	PingReq(ctx context.Context, in *PingReqRequest, opts ...grpc.CallOption) (*PingResponse, error)
+	// Sync merges the caller's membership into the server's and returns the
+	// server's.
+	Sync(ctx context.Context, in *SyncRequest, opts ...grpc.CallOption) (*SyncResponse, error)
+}
+
+type gossipClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGossipClient(cc grpc.ClientConnInterface) GossipClient {
+	return &gossipClient{cc}
+}
+
+func (c *gossipClient) Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PingResponse)
+	err := c.cc.Invoke(ctx, Gossip_Ping_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gossipClient) PingReq(ctx context.Context, in *PingReqRequest, opts ...grpc.CallOption) (*PingResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PingResponse)
+	err := c.cc.Invoke(ctx, Gossip_PingReq_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gossipClient) Sync(ctx context.Context, in *SyncRequest, opts ...grpc.CallOption) (*SyncResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SyncResponse)
+	err := c.cc.Invoke(ctx, Gossip_Sync_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GossipServer is the server API for Gossip service.
+// All implementations must embed UnimplementedGossipServer
+// for forward compatibility.
+//
+// Gossip carries the SWIM membership protocol between servers. Probes
+// detect members that failed and piggyback the membership changes each
+// server learned, and full exchanges of the membership let new servers
+// join and partitioned ones catch up. Servers call it on each other; it
+// requires the admin permission.
+type GossipServer interface {
+	// Ping probes the server, which acknowledges it unless it is not the
+	// member the caller meant.
+	Ping(context.Context, *PingRequest) (*PingResponse, error)
+	// PingReq asks the server to probe a member the caller could not reach,
+	// and acknowledges if the member answered.
+	PingReq(context.Context, *PingReqRequest) (*PingResponse, error)
+	// Sync merges the caller's membership into the server's and returns the
+	// server's.
+	Sync(context.Context, *SyncRequest) (*SyncResponse, error)
+	mustEmbedUnimplementedGossipServer()
+}
+
+// UnimplementedGossipServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedGossipServer struct{}
+
+func (UnimplementedGossipServer) Ping(context.Context, *PingRequest) (*PingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Ping not implemented")
+}
+func (UnimplementedGossipServer) PingReq(context.Context, *PingReqRequest) (*PingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PingReq not implemented")
+}
+func (UnimplementedGossipServer) Sync(context.Context, *SyncRequest) (*SyncResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Sync not implemented")
+}
+func (UnimplementedGossipServer) mustEmbedUnimplementedGossipServer() {}
+func (UnimplementedGossipServer) testEmbeddedByValue()                {}
+
+// UnsafeGossipServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GossipServer will
+// result in compilation errors.
+type UnsafeGossipServer interface {
+	mustEmbedUnimplementedGossipServer()
+}
+
+func RegisterGossipServer(s grpc.ServiceRegistrar, srv GossipServer) {
+	// If the following call pancis, it indicates UnimplementedGossipServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Gossip_ServiceDesc, srv)
+}
+
+func _Gossip_Ping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GossipServer).Ping(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gossip_Ping_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GossipServer).Ping(ctx, req.(*PingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gossip_PingReq_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PingReqRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GossipServer).PingReq(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gossip_PingReq_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GossipServer).PingReq(ctx, req.(*PingReqRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gossip_Sync_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SyncRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GossipServer).Sync(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gossip_Sync_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GossipServer).Sync(ctx, req.(*SyncRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Gossip_ServiceDesc is the grpc.ServiceDesc for Gossip service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Gossip_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "kvstore.v1.Gossip",
+	HandlerType: (*GossipServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Ping",
+			Handler:    _Gossip_Ping_Handler,
+		},
+		{
+			MethodName: "PingReq",
+			Handler:    _Gossip_PingReq_Handler,
+		},
+		{
+			MethodName: "Sync",
+			Handler:    _Gossip_Sync_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "api/proto/gossip.proto",
+}
@@ -9,6 +9,7 @@ package pb
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -21,9 +22,101 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// ReadConsistency selects how up to date a read must be when the server
+// runs in a cluster. Writes are always linearizable. A standalone server
+// holds the only copy of the data, so every mode reads its latest state.
+// With primary-replica replication the primary stands for the leader and
+// replicas for followers, and indexes are offsets in the primary's mutation
+// log; replicas reject LINEARIZABLE and LEASE reads with a
+// READ_ONLY_REPLICA redirect to the primary.
+// Active-active sites have no leader: they serve SEQUENTIAL and STALE reads
+// from their own state, indexes are offsets in the site's own log, and
+// LINEARIZABLE and LEASE reads fail with FAILED_PRECONDITION.
+type ReadConsistency int32
+
+const (
+	// Same as SEQUENTIAL.
+	ReadConsistency_READ_CONSISTENCY_UNSPECIFIED ReadConsistency = 0
+	// The read observes every write acknowledged before it started. Only the
+	// leader serves it: it confirms it still leads with a heartbeat round to
+	// a majority (read index), then waits until it has applied everything
+	// committed so far. Followers reject it with a NOT_LEADER redirect.
+	ReadConsistency_READ_CONSISTENCY_LINEARIZABLE ReadConsistency = 1
+	// As LINEARIZABLE, but the leader skips the heartbeat round while it
+	// holds a lease. A lease starts with a heartbeat round a majority answers
+	// and lasts most of the heartbeat timeout, as no follower stands for
+	// election before that passes since it last heard from the leader.
+	// Cheaper, and linearizable as long as clocks advance at about the same
+	// rate on every node.
+	ReadConsistency_READ_CONSISTENCY_LEASE ReadConsistency = 2
+	// Served by any node from a prefix of the replicated log, so writes are
+	// never observed out of order, though possibly late. With min_index the
+	// node first waits until it has applied that index: passing the highest
+	// index seen in earlier responses gives read-your-writes and monotonic
+	// reads across nodes.
+	ReadConsistency_READ_CONSISTENCY_SEQUENTIAL ReadConsistency = 3
+	// Served immediately by any node, possibly missing recent writes. With
+	// max_staleness, a follower that has not heard from the leader within
+	// that long rejects the read with a TOO_STALE redirect to the leader.
+	ReadConsistency_READ_CONSISTENCY_STALE ReadConsistency = 4
+)
+
+// Enum value maps for ReadConsistency.
+var (
+	ReadConsistency_name = map[int32]string{
+		0: "READ_CONSISTENCY_UNSPECIFIED",
+		1: "READ_CONSISTENCY_LINEARIZABLE",
+		2: "READ_CONSISTENCY_LEASE",
+		3: "READ_CONSISTENCY_SEQUENTIAL",
+		4: "READ_CONSISTENCY_STALE",
+	}
+	ReadConsistency_value = map[string]int32{
+		"READ_CONSISTENCY_UNSPECIFIED":  0,
+		"READ_CONSISTENCY_LINEARIZABLE": 1,
+		"READ_CONSISTENCY_LEASE":        2,
+		"READ_CONSISTENCY_SEQUENTIAL":   3,
+		"READ_CONSISTENCY_STALE":        4,
+	}
+)
+
+func (x ReadConsistency) Enum() *ReadConsistency {
+	p := new(ReadConsistency)
+	*p = x
+	return p
+}
+
+func (x ReadConsistency) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ReadConsistency) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_proto_kvstore_proto_enumTypes[0].Descriptor()
+}
+
+func (ReadConsistency) Type() protoreflect.EnumType {
+	return &file_api_proto_kvstore_proto_enumTypes[0]
+}
+
+func (x ReadConsistency) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ReadConsistency.Descriptor instead.
+func (ReadConsistency) EnumDescriptor() ([]byte, []int) {
+	return file_api_proto_kvstore_proto_rawDescGZIP(), []int{0}
+}
+
 type GetRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Key   string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// Every request addresses the default namespace when this is empty.
+	Namespace   string          `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Consistency ReadConsistency `protobuf:"varint,3,opt,name=consistency,proto3,enum=kvstore.v1.ReadConsistency" json:"consistency,omitempty"`
+	// For SEQUENTIAL reads, the log index the node must have applied.
+	MinIndex uint64 `protobuf:"varint,4,opt,name=min_index,json=minIndex,proto3" json:"min_index,omitempty"`
+	// For STALE reads, how long ago a follower may last have heard from the
+	// leader. Unset is unbounded.
+	MaxStaleness  *durationpb.Duration `protobuf:"bytes,5,opt,name=max_staleness,json=maxStaleness,proto3" json:"max_staleness,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -65,10 +158,40 @@ func (x *GetRequest) GetKey() string {
 	return ""
 }
 
+func (x *GetRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *GetRequest) GetConsistency() ReadConsistency {
+	if x != nil {
+		return x.Consistency
+	}
+	return ReadConsistency_READ_CONSISTENCY_UNSPECIFIED
+}
+
+func (x *GetRequest) GetMinIndex() uint64 {
+	if x != nil {
+		return x.MinIndex
+	}
+	return 0
+}
+
+func (x *GetRequest) GetMaxStaleness() *durationpb.Duration {
+	if x != nil {
+		return x.MaxStaleness
+	}
+	return nil
+}
+
 type GetResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Value         string                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
-	Found         bool                   `protobuf:"varint,2,opt,name=found,proto3" json:"found,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Value string                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	Found bool                   `protobuf:"varint,2,opt,name=found,proto3" json:"found,omitempty"`
+	// Log index the serving node had applied. Zero on a standalone server.
+	Index         uint64 `protobuf:"varint,3,opt,name=index,proto3" json:"index,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -117,11 +240,20 @@ func (x *GetResponse) GetFound() bool {
 	return false
 }
 
+func (x *GetResponse) GetIndex() uint64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
 type SetRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
-	Value         string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
-	TtlSeconds    *int64                 `protobuf:"varint,3,opt,name=ttl_seconds,json=ttlSeconds,proto3,oneof" json:"ttl_seconds,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Key   string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	// When unset, the namespace's default TTL applies.
+	TtlSeconds    *int64 `protobuf:"varint,3,opt,name=ttl_seconds,json=ttlSeconds,proto3,oneof" json:"ttl_seconds,omitempty"`
+	Namespace     string `protobuf:"bytes,4,opt,name=namespace,proto3" json:"namespace,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -177,9 +309,18 @@ func (x *SetRequest) GetTtlSeconds() int64 {
 	return 0
 }
 
+func (x *SetRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
 type SetResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Success bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	// Log index the write was committed at. Zero on a standalone server.
+	Index         uint64 `protobuf:"varint,2,opt,name=index,proto3" json:"index,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -221,9 +362,17 @@ func (x *SetResponse) GetSuccess() bool {
 	return false
 }
 
+func (x *SetResponse) GetIndex() uint64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
 type DeleteRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Namespace     string                 `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -265,10 +414,19 @@ func (x *DeleteRequest) GetKey() string {
 	return ""
 }
 
+func (x *DeleteRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
 type DeleteResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Existed       bool                   `protobuf:"varint,2,opt,name=existed,proto3" json:"existed,omitempty"`
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Success bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Existed bool                   `protobuf:"varint,2,opt,name=existed,proto3" json:"existed,omitempty"`
+	// Log index the delete was committed at. Zero on a standalone server.
+	Index         uint64 `protobuf:"varint,3,opt,name=index,proto3" json:"index,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -317,16 +475,259 @@ func (x *DeleteResponse) GetExisted() bool {
 	return false
 }
 
-type ListRequest struct {
+func (x *DeleteResponse) GetIndex() uint64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+type IncrementRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Key       string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Namespace string                 `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	// May be negative. A missing key starts at zero.
+	Delta         int64 `protobuf:"varint,3,opt,name=delta,proto3" json:"delta,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IncrementRequest) Reset() {
+	*x = IncrementRequest{}
+	mi := &file_api_proto_kvstore_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IncrementRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IncrementRequest) ProtoMessage() {}
+
+func (x *IncrementRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_kvstore_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IncrementRequest.ProtoReflect.Descriptor instead.
+func (*IncrementRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_kvstore_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *IncrementRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *IncrementRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *IncrementRequest) GetDelta() int64 {
+	if x != nil {
+		return x.Delta
+	}
+	return 0
+}
+
+type IncrementResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The counter's total as this site sees it, including what it has merged
+	// from other sites so far.
+	Value int64 `protobuf:"varint,1,opt,name=value,proto3" json:"value,omitempty"`
+	// Offset of the change in the site's log.
+	Index         uint64 `protobuf:"varint,2,opt,name=index,proto3" json:"index,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IncrementResponse) Reset() {
+	*x = IncrementResponse{}
+	mi := &file_api_proto_kvstore_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IncrementResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IncrementResponse) ProtoMessage() {}
+
+func (x *IncrementResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_kvstore_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IncrementResponse.ProtoReflect.Descriptor instead.
+func (*IncrementResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_kvstore_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *IncrementResponse) GetValue() int64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *IncrementResponse) GetIndex() uint64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+type MembersRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Limit         *int32                 `protobuf:"varint,1,opt,name=limit,proto3,oneof" json:"limit,omitempty"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Namespace     string                 `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Members       []string               `protobuf:"bytes,3,rep,name=members,proto3" json:"members,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MembersRequest) Reset() {
+	*x = MembersRequest{}
+	mi := &file_api_proto_kvstore_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MembersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MembersRequest) ProtoMessage() {}
+
+func (x *MembersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_kvstore_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MembersRequest.ProtoReflect.Descriptor instead.
+func (*MembersRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_kvstore_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *MembersRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *MembersRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *MembersRequest) GetMembers() []string {
+	if x != nil {
+		return x.Members
+	}
+	return nil
+}
+
+type MembersResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The set's members as this site sees them, sorted.
+	Members []string `protobuf:"bytes,1,rep,name=members,proto3" json:"members,omitempty"`
+	// As in IncrementResponse.
+	Index         uint64 `protobuf:"varint,2,opt,name=index,proto3" json:"index,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MembersResponse) Reset() {
+	*x = MembersResponse{}
+	mi := &file_api_proto_kvstore_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MembersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MembersResponse) ProtoMessage() {}
+
+func (x *MembersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_kvstore_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MembersResponse.ProtoReflect.Descriptor instead.
+func (*MembersResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_kvstore_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *MembersResponse) GetMembers() []string {
+	if x != nil {
+		return x.Members
+	}
+	return nil
+}
+
+func (x *MembersResponse) GetIndex() uint64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+type ListRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Limit     *int32                 `protobuf:"varint,1,opt,name=limit,proto3,oneof" json:"limit,omitempty"`
+	Prefix    *string                `protobuf:"bytes,2,opt,name=prefix,proto3,oneof" json:"prefix,omitempty"`
+	Namespace string                 `protobuf:"bytes,3,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	// As in GetRequest.
+	Consistency   ReadConsistency      `protobuf:"varint,4,opt,name=consistency,proto3,enum=kvstore.v1.ReadConsistency" json:"consistency,omitempty"`
+	MinIndex      uint64               `protobuf:"varint,5,opt,name=min_index,json=minIndex,proto3" json:"min_index,omitempty"`
+	MaxStaleness  *durationpb.Duration `protobuf:"bytes,6,opt,name=max_staleness,json=maxStaleness,proto3" json:"max_staleness,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ListRequest) Reset() {
 	*x = ListRequest{}
-	mi := &file_api_proto_kvstore_proto_msgTypes[6]
+	mi := &file_api_proto_kvstore_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -338,7 +739,7 @@ func (x *ListRequest) String() string {
 func (*ListRequest) ProtoMessage() {}
 
 func (x *ListRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_kvstore_proto_msgTypes[6]
+	mi := &file_api_proto_kvstore_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -351,7 +752,7 @@ func (x *ListRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListRequest.ProtoReflect.Descriptor instead.
 func (*ListRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_kvstore_proto_rawDescGZIP(), []int{6}
+	return file_api_proto_kvstore_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *ListRequest) GetLimit() int32 {
@@ -361,16 +762,54 @@ func (x *ListRequest) GetLimit() int32 {
 	return 0
 }
 
+func (x *ListRequest) GetPrefix() string {
+	if x != nil && x.Prefix != nil {
+		return *x.Prefix
+	}
+	return ""
+}
+
+func (x *ListRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *ListRequest) GetConsistency() ReadConsistency {
+	if x != nil {
+		return x.Consistency
+	}
+	return ReadConsistency_READ_CONSISTENCY_UNSPECIFIED
+}
+
+func (x *ListRequest) GetMinIndex() uint64 {
+	if x != nil {
+		return x.MinIndex
+	}
+	return 0
+}
+
+func (x *ListRequest) GetMaxStaleness() *durationpb.Duration {
+	if x != nil {
+		return x.MaxStaleness
+	}
+	return nil
+}
+
 type ListResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Pairs         []*KeyValuePair        `protobuf:"bytes,1,rep,name=pairs,proto3" json:"pairs,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Sorted by key. With a limit, the first keys in that order.
+	Pairs []*KeyValuePair `protobuf:"bytes,1,rep,name=pairs,proto3" json:"pairs,omitempty"`
+	// Log index the serving node had applied. Zero on a standalone server.
+	Index         uint64 `protobuf:"varint,2,opt,name=index,proto3" json:"index,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ListResponse) Reset() {
 	*x = ListResponse{}
-	mi := &file_api_proto_kvstore_proto_msgTypes[7]
+	mi := &file_api_proto_kvstore_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -382,7 +821,7 @@ func (x *ListResponse) String() string {
 func (*ListResponse) ProtoMessage() {}
 
 func (x *ListResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_kvstore_proto_msgTypes[7]
+	mi := &file_api_proto_kvstore_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -395,7 +834,7 @@ func (x *ListResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListResponse.ProtoReflect.Descriptor instead.
 func (*ListResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_kvstore_proto_rawDescGZIP(), []int{7}
+	return file_api_proto_kvstore_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *ListResponse) GetPairs() []*KeyValuePair {
@@ -405,6 +844,13 @@ func (x *ListResponse) GetPairs() []*KeyValuePair {
 	return nil
 }
 
+func (x *ListResponse) GetIndex() uint64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
 type KeyValuePair struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
@@ -415,7 +861,7 @@ type KeyValuePair struct {
 
 func (x *KeyValuePair) Reset() {
 	*x = KeyValuePair{}
-	mi := &file_api_proto_kvstore_proto_msgTypes[8]
+	mi := &file_api_proto_kvstore_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -427,7 +873,7 @@ func (x *KeyValuePair) String() string {
 func (*KeyValuePair) ProtoMessage() {}
 
 func (x *KeyValuePair) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_kvstore_proto_msgTypes[8]
+	mi := &file_api_proto_kvstore_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -440,7 +886,7 @@ func (x *KeyValuePair) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use KeyValuePair.ProtoReflect.Descriptor instead.
 func (*KeyValuePair) Descriptor() ([]byte, []int) {
-	return file_api_proto_kvstore_proto_rawDescGZIP(), []int{8}
+	return file_api_proto_kvstore_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *KeyValuePair) GetKey() string {
@@ -457,45 +903,385 @@ func (x *KeyValuePair) GetValue() string {
 	return ""
 }
 
+type GetShardMapRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetShardMapRequest) Reset() {
+	*x = GetShardMapRequest{}
+	mi := &file_api_proto_kvstore_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetShardMapRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetShardMapRequest) ProtoMessage() {}
+
+func (x *GetShardMapRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_kvstore_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetShardMapRequest.ProtoReflect.Descriptor instead.
+func (*GetShardMapRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_kvstore_proto_rawDescGZIP(), []int{13}
+}
+
+type GetShardMapResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The node that answered.
+	NodeId   string    `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	ShardMap *ShardMap `protobuf:"bytes,2,opt,name=shard_map,json=shardMap,proto3" json:"shard_map,omitempty"`
+	// While a migration runs, the map keys are moving to. Until it is
+	// committed keys are still routed by shard_map, but they may already be
+	// stored on their owner in target.
+	Target        *ShardMap `protobuf:"bytes,3,opt,name=target,proto3" json:"target,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetShardMapResponse) Reset() {
+	*x = GetShardMapResponse{}
+	mi := &file_api_proto_kvstore_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetShardMapResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetShardMapResponse) ProtoMessage() {}
+
+func (x *GetShardMapResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_kvstore_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetShardMapResponse.ProtoReflect.Descriptor instead.
+func (*GetShardMapResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_kvstore_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *GetShardMapResponse) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *GetShardMapResponse) GetShardMap() *ShardMap {
+	if x != nil {
+		return x.ShardMap
+	}
+	return nil
+}
+
+func (x *GetShardMapResponse) GetTarget() *ShardMap {
+	if x != nil {
+		return x.Target
+	}
+	return nil
+}
+
+// ShardMap assigns keys to nodes with a consistent hash ring. A key's
+// position on the ring is the 64-bit FNV-1a hash of its bytes, finalized
+// with the splitmix64 mixer; it belongs to the node of the first token at
+// or after that position, wrapping around to the first token. Namespaces do
+// not affect placement. Nodes reject requests for keys they do not own with
+// a WRONG_SHARD redirect to the owner.
+type ShardMap struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Increases whenever the assignment changes.
+	Version uint64 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	// Points each node has on the ring. The token of a node's i-th virtual
+	// node is the hash of "<id>#<i>".
+	VirtualNodes int32        `protobuf:"varint,2,opt,name=virtual_nodes,json=virtualNodes,proto3" json:"virtual_nodes,omitempty"`
+	Nodes        []*ShardNode `protobuf:"bytes,3,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	// Sorted by token.
+	Tokens        []*ShardToken `protobuf:"bytes,4,rep,name=tokens,proto3" json:"tokens,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShardMap) Reset() {
+	*x = ShardMap{}
+	mi := &file_api_proto_kvstore_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShardMap) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShardMap) ProtoMessage() {}
+
+func (x *ShardMap) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_kvstore_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShardMap.ProtoReflect.Descriptor instead.
+func (*ShardMap) Descriptor() ([]byte, []int) {
+	return file_api_proto_kvstore_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ShardMap) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *ShardMap) GetVirtualNodes() int32 {
+	if x != nil {
+		return x.VirtualNodes
+	}
+	return 0
+}
+
+func (x *ShardMap) GetNodes() []*ShardNode {
+	if x != nil {
+		return x.Nodes
+	}
+	return nil
+}
+
+func (x *ShardMap) GetTokens() []*ShardToken {
+	if x != nil {
+		return x.Tokens
+	}
+	return nil
+}
+
+type ShardNode struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	GrpcAddr      string                 `protobuf:"bytes,2,opt,name=grpc_addr,json=grpcAddr,proto3" json:"grpc_addr,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShardNode) Reset() {
+	*x = ShardNode{}
+	mi := &file_api_proto_kvstore_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShardNode) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShardNode) ProtoMessage() {}
+
+func (x *ShardNode) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_kvstore_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShardNode.ProtoReflect.Descriptor instead.
+func (*ShardNode) Descriptor() ([]byte, []int) {
+	return file_api_proto_kvstore_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ShardNode) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ShardNode) GetGrpcAddr() string {
+	if x != nil {
+		return x.GrpcAddr
+	}
+	return ""
+}
+
+type ShardToken struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Token         uint64                 `protobuf:"varint,1,opt,name=token,proto3" json:"token,omitempty"`
+	NodeId        string                 `protobuf:"bytes,2,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShardToken) Reset() {
+	*x = ShardToken{}
+	mi := &file_api_proto_kvstore_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShardToken) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShardToken) ProtoMessage() {}
+
+func (x *ShardToken) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_kvstore_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShardToken.ProtoReflect.Descriptor instead.
+func (*ShardToken) Descriptor() ([]byte, []int) {
+	return file_api_proto_kvstore_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ShardToken) GetToken() uint64 {
+	if x != nil {
+		return x.Token
+	}
+	return 0
+}
+
+func (x *ShardToken) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
 var File_api_proto_kvstore_proto protoreflect.FileDescriptor
 
 const file_api_proto_kvstore_proto_rawDesc = "" +
 	"\n" +
 	"\x17api/proto/kvstore.proto\x12\n" +
-	"kvstore.v1\"\x1e\n" +
+	"kvstore.v1\x1a\x1egoogle/protobuf/duration.proto\"\xd8\x01\n" +
 	"\n" +
 	"GetRequest\x12\x10\n" +
-	"\x03key\x18\x01 \x01(\tR\x03key\"9\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x1c\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\x12=\n" +
+	"\vconsistency\x18\x03 \x01(\x0e2\x1b.kvstore.v1.ReadConsistencyR\vconsistency\x12\x1b\n" +
+	"\tmin_index\x18\x04 \x01(\x04R\bminIndex\x12>\n" +
+	"\rmax_staleness\x18\x05 \x01(\v2\x19.google.protobuf.DurationR\fmaxStaleness\"O\n" +
 	"\vGetResponse\x12\x14\n" +
 	"\x05value\x18\x01 \x01(\tR\x05value\x12\x14\n" +
-	"\x05found\x18\x02 \x01(\bR\x05found\"j\n" +
+	"\x05found\x18\x02 \x01(\bR\x05found\x12\x14\n" +
+	"\x05index\x18\x03 \x01(\x04R\x05index\"\x88\x01\n" +
 	"\n" +
 	"SetRequest\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
 	"\x05value\x18\x02 \x01(\tR\x05value\x12$\n" +
 	"\vttl_seconds\x18\x03 \x01(\x03H\x00R\n" +
-	"ttlSeconds\x88\x01\x01B\x0e\n" +
-	"\f_ttl_seconds\"'\n" +
+	"ttlSeconds\x88\x01\x01\x12\x1c\n" +
+	"\tnamespace\x18\x04 \x01(\tR\tnamespaceB\x0e\n" +
+	"\f_ttl_seconds\"=\n" +
 	"\vSetResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess\"!\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05index\x18\x02 \x01(\x04R\x05index\"?\n" +
 	"\rDeleteRequest\x12\x10\n" +
-	"\x03key\x18\x01 \x01(\tR\x03key\"D\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x1c\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\"Z\n" +
 	"\x0eDeleteResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
-	"\aexisted\x18\x02 \x01(\bR\aexisted\"2\n" +
+	"\aexisted\x18\x02 \x01(\bR\aexisted\x12\x14\n" +
+	"\x05index\x18\x03 \x01(\x04R\x05index\"X\n" +
+	"\x10IncrementRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x1c\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\x12\x14\n" +
+	"\x05delta\x18\x03 \x01(\x03R\x05delta\"?\n" +
+	"\x11IncrementResponse\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\x03R\x05value\x12\x14\n" +
+	"\x05index\x18\x02 \x01(\x04R\x05index\"Z\n" +
+	"\x0eMembersRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x1c\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\x12\x18\n" +
+	"\amembers\x18\x03 \x03(\tR\amembers\"A\n" +
+	"\x0fMembersResponse\x12\x18\n" +
+	"\amembers\x18\x01 \x03(\tR\amembers\x12\x14\n" +
+	"\x05index\x18\x02 \x01(\x04R\x05index\"\x94\x02\n" +
 	"\vListRequest\x12\x19\n" +
-	"\x05limit\x18\x01 \x01(\x05H\x00R\x05limit\x88\x01\x01B\b\n" +
-	"\x06_limit\">\n" +
+	"\x05limit\x18\x01 \x01(\x05H\x00R\x05limit\x88\x01\x01\x12\x1b\n" +
+	"\x06prefix\x18\x02 \x01(\tH\x01R\x06prefix\x88\x01\x01\x12\x1c\n" +
+	"\tnamespace\x18\x03 \x01(\tR\tnamespace\x12=\n" +
+	"\vconsistency\x18\x04 \x01(\x0e2\x1b.kvstore.v1.ReadConsistencyR\vconsistency\x12\x1b\n" +
+	"\tmin_index\x18\x05 \x01(\x04R\bminIndex\x12>\n" +
+	"\rmax_staleness\x18\x06 \x01(\v2\x19.google.protobuf.DurationR\fmaxStalenessB\b\n" +
+	"\x06_limitB\t\n" +
+	"\a_prefix\"T\n" +
 	"\fListResponse\x12.\n" +
-	"\x05pairs\x18\x01 \x03(\v2\x18.kvstore.v1.KeyValuePairR\x05pairs\"6\n" +
+	"\x05pairs\x18\x01 \x03(\v2\x18.kvstore.v1.KeyValuePairR\x05pairs\x12\x14\n" +
+	"\x05index\x18\x02 \x01(\x04R\x05index\"6\n" +
 	"\fKeyValuePair\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value2\xf5\x01\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value\"\x14\n" +
+	"\x12GetShardMapRequest\"\x8f\x01\n" +
+	"\x13GetShardMapResponse\x12\x17\n" +
+	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x121\n" +
+	"\tshard_map\x18\x02 \x01(\v2\x14.kvstore.v1.ShardMapR\bshardMap\x12,\n" +
+	"\x06target\x18\x03 \x01(\v2\x14.kvstore.v1.ShardMapR\x06target\"\xa6\x01\n" +
+	"\bShardMap\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\x04R\aversion\x12#\n" +
+	"\rvirtual_nodes\x18\x02 \x01(\x05R\fvirtualNodes\x12+\n" +
+	"\x05nodes\x18\x03 \x03(\v2\x15.kvstore.v1.ShardNodeR\x05nodes\x12.\n" +
+	"\x06tokens\x18\x04 \x03(\v2\x16.kvstore.v1.ShardTokenR\x06tokens\"8\n" +
+	"\tShardNode\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1b\n" +
+	"\tgrpc_addr\x18\x02 \x01(\tR\bgrpcAddr\";\n" +
+	"\n" +
+	"ShardToken\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\x04R\x05token\x12\x17\n" +
+	"\anode_id\x18\x02 \x01(\tR\x06nodeId*\xaf\x01\n" +
+	"\x0fReadConsistency\x12 \n" +
+	"\x1cREAD_CONSISTENCY_UNSPECIFIED\x10\x00\x12!\n" +
+	"\x1dREAD_CONSISTENCY_LINEARIZABLE\x10\x01\x12\x1a\n" +
+	"\x16READ_CONSISTENCY_LEASE\x10\x02\x12\x1f\n" +
+	"\x1bREAD_CONSISTENCY_SEQUENTIAL\x10\x03\x12\x1a\n" +
+	"\x16READ_CONSISTENCY_STALE\x10\x042\xa0\x04\n" +
 	"\aKVStore\x126\n" +
 	"\x03Get\x12\x16.kvstore.v1.GetRequest\x1a\x17.kvstore.v1.GetResponse\x126\n" +
 	"\x03Set\x12\x16.kvstore.v1.SetRequest\x1a\x17.kvstore.v1.SetResponse\x12?\n" +
 	"\x06Delete\x12\x19.kvstore.v1.DeleteRequest\x1a\x1a.kvstore.v1.DeleteResponse\x129\n" +
-	"\x04List\x12\x17.kvstore.v1.ListRequest\x1a\x18.kvstore.v1.ListResponseB,Z*github.com/khuongnguyenBlue/kvstore/pkg/pbb\x06proto3"
+	"\x04List\x12\x17.kvstore.v1.ListRequest\x1a\x18.kvstore.v1.ListResponse\x12H\n" +
+	"\tIncrement\x12\x1c.kvstore.v1.IncrementRequest\x1a\x1d.kvstore.v1.IncrementResponse\x12E\n" +
+	"\n" +
+	"AddMembers\x12\x1a.kvstore.v1.MembersRequest\x1a\x1b.kvstore.v1.MembersResponse\x12H\n" +
+	"\rRemoveMembers\x12\x1a.kvstore.v1.MembersRequest\x1a\x1b.kvstore.v1.MembersResponse\x12N\n" +
+	"\vGetShardMap\x12\x1e.kvstore.v1.GetShardMapRequest\x1a\x1f.kvstore.v1.GetShardMapResponseB,Z*github.com/khuongnguyenBlue/kvstore/pkg/pbb\x06proto3"
 
 var (
 	file_api_proto_kvstore_proto_rawDescOnce sync.Once
@@ -509,33 +1295,61 @@ func file_api_proto_kvstore_proto_rawDescGZIP() []byte {
 	return file_api_proto_kvstore_proto_rawDescData
 }
 
-var file_api_proto_kvstore_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_api_proto_kvstore_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_api_proto_kvstore_proto_msgTypes = make([]protoimpl.MessageInfo, 18)
 var file_api_proto_kvstore_proto_goTypes = []any{
-	(*GetRequest)(nil),     // 0: kvstore.v1.GetRequest
-	(*GetResponse)(nil),    // 1: kvstore.v1.GetResponse
-	(*SetRequest)(nil),     // 2: kvstore.v1.SetRequest
-	(*SetResponse)(nil),    // 3: kvstore.v1.SetResponse
-	(*DeleteRequest)(nil),  // 4: kvstore.v1.DeleteRequest
-	(*DeleteResponse)(nil), // 5: kvstore.v1.DeleteResponse
-	(*ListRequest)(nil),    // 6: kvstore.v1.ListRequest
-	(*ListResponse)(nil),   // 7: kvstore.v1.ListResponse
-	(*KeyValuePair)(nil),   // 8: kvstore.v1.KeyValuePair
+	(ReadConsistency)(0),        // 0: kvstore.v1.ReadConsistency
+	(*GetRequest)(nil),          // 1: kvstore.v1.GetRequest
+	(*GetResponse)(nil),         // 2: kvstore.v1.GetResponse
+	(*SetRequest)(nil),          // 3: kvstore.v1.SetRequest
+	(*SetResponse)(nil),         // 4: kvstore.v1.SetResponse
+	(*DeleteRequest)(nil),       // 5: kvstore.v1.DeleteRequest
+	(*DeleteResponse)(nil),      // 6: kvstore.v1.DeleteResponse
+	(*IncrementRequest)(nil),    // 7: kvstore.v1.IncrementRequest
+	(*IncrementResponse)(nil),   // 8: kvstore.v1.IncrementResponse
+	(*MembersRequest)(nil),      // 9: kvstore.v1.MembersRequest
+	(*MembersResponse)(nil),     // 10: kvstore.v1.MembersResponse
+	(*ListRequest)(nil),         // 11: kvstore.v1.ListRequest
+	(*ListResponse)(nil),        // 12: kvstore.v1.ListResponse
+	(*KeyValuePair)(nil),        // 13: kvstore.v1.KeyValuePair
+	(*GetShardMapRequest)(nil),  // 14: kvstore.v1.GetShardMapRequest
+	(*GetShardMapResponse)(nil), // 15: kvstore.v1.GetShardMapResponse
+	(*ShardMap)(nil),            // 16: kvstore.v1.ShardMap
+	(*ShardNode)(nil),           // 17: kvstore.v1.ShardNode
+	(*ShardToken)(nil),          // 18: kvstore.v1.ShardToken
+	(*durationpb.Duration)(nil), // 19: google.protobuf.Duration
 }
 var file_api_proto_kvstore_proto_depIdxs = []int32{
-	8, // 0: kvstore.v1.ListResponse.pairs:type_name -> kvstore.v1.KeyValuePair
-	0, // 1: kvstore.v1.KVStore.Get:input_type -> kvstore.v1.GetRequest
-	2, // 2: kvstore.v1.KVStore.Set:input_type -> kvstore.v1.SetRequest
-	4, // 3: kvstore.v1.KVStore.Delete:input_type -> kvstore.v1.DeleteRequest
-	6, // 4: kvstore.v1.KVStore.List:input_type -> kvstore.v1.ListRequest
-	1, // 5: kvstore.v1.KVStore.Get:output_type -> kvstore.v1.GetResponse
-	3, // 6: kvstore.v1.KVStore.Set:output_type -> kvstore.v1.SetResponse
-	5, // 7: kvstore.v1.KVStore.Delete:output_type -> kvstore.v1.DeleteResponse
-	7, // 8: kvstore.v1.KVStore.List:output_type -> kvstore.v1.ListResponse
-	5, // [5:9] is the sub-list for method output_type
-	1, // [1:5] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	0,  // 0: kvstore.v1.GetRequest.consistency:type_name -> kvstore.v1.ReadConsistency
+	19, // 1: kvstore.v1.GetRequest.max_staleness:type_name -> google.protobuf.Duration
+	0,  // 2: kvstore.v1.ListRequest.consistency:type_name -> kvstore.v1.ReadConsistency
+	19, // 3: kvstore.v1.ListRequest.max_staleness:type_name -> google.protobuf.Duration
+	13, // 4: kvstore.v1.ListResponse.pairs:type_name -> kvstore.v1.KeyValuePair
+	16, // 5: kvstore.v1.GetShardMapResponse.shard_map:type_name -> kvstore.v1.ShardMap
+	16, // 6: kvstore.v1.GetShardMapResponse.target:type_name -> kvstore.v1.ShardMap
+	17, // 7: kvstore.v1.ShardMap.nodes:type_name -> kvstore.v1.ShardNode
+	18, // 8: kvstore.v1.ShardMap.tokens:type_name -> kvstore.v1.ShardToken
+	1,  // 9: kvstore.v1.KVStore.Get:input_type -> kvstore.v1.GetRequest
+	3,  // 10: kvstore.v1.KVStore.Set:input_type -> kvstore.v1.SetRequest
+	5,  // 11: kvstore.v1.KVStore.Delete:input_type -> kvstore.v1.DeleteRequest
+	11, // 12: kvstore.v1.KVStore.List:input_type -> kvstore.v1.ListRequest
+	7,  // 13: kvstore.v1.KVStore.Increment:input_type -> kvstore.v1.IncrementRequest
+	9,  // 14: kvstore.v1.KVStore.AddMembers:input_type -> kvstore.v1.MembersRequest
+	9,  // 15: kvstore.v1.KVStore.RemoveMembers:input_type -> kvstore.v1.MembersRequest
+	14, // 16: kvstore.v1.KVStore.GetShardMap:input_type -> kvstore.v1.GetShardMapRequest
+	2,  // 17: kvstore.v1.KVStore.Get:output_type -> kvstore.v1.GetResponse
+	4,  // 18: kvstore.v1.KVStore.Set:output_type -> kvstore.v1.SetResponse
+	6,  // 19: kvstore.v1.KVStore.Delete:output_type -> kvstore.v1.DeleteResponse
+	12, // 20: kvstore.v1.KVStore.List:output_type -> kvstore.v1.ListResponse
+	8,  // 21: kvstore.v1.KVStore.Increment:output_type -> kvstore.v1.IncrementResponse
+	10, // 22: kvstore.v1.KVStore.AddMembers:output_type -> kvstore.v1.MembersResponse
+	10, // 23: kvstore.v1.KVStore.RemoveMembers:output_type -> kvstore.v1.MembersResponse
+	15, // 24: kvstore.v1.KVStore.GetShardMap:output_type -> kvstore.v1.GetShardMapResponse
+	17, // [17:25] is the sub-list for method output_type
+	9,  // [9:17] is the sub-list for method input_type
+	9,  // [9:9] is the sub-list for extension type_name
+	9,  // [9:9] is the sub-list for extension extendee
+	0,  // [0:9] is the sub-list for field type_name
 }
 
 func init() { file_api_proto_kvstore_proto_init() }
@@ -544,19 +1358,20 @@ func file_api_proto_kvstore_proto_init() {
 		return
 	}
 	file_api_proto_kvstore_proto_msgTypes[2].OneofWrappers = []any{}
-	file_api_proto_kvstore_proto_msgTypes[6].OneofWrappers = []any{}
+	file_api_proto_kvstore_proto_msgTypes[10].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_proto_kvstore_proto_rawDesc), len(file_api_proto_kvstore_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   9,
+			NumEnums:      1,
+			NumMessages:   18,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_api_proto_kvstore_proto_goTypes,
 		DependencyIndexes: file_api_proto_kvstore_proto_depIdxs,
+		EnumInfos:         file_api_proto_kvstore_proto_enumTypes,
 		MessageInfos:      file_api_proto_kvstore_proto_msgTypes,
 	}.Build()
 	File_api_proto_kvstore_proto = out.File
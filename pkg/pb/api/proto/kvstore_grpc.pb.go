@@ -20,10 +20,14 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	KVStore_Get_FullMethodName    = "/kvstore.v1.KVStore/Get"
-	KVStore_Set_FullMethodName    = "/kvstore.v1.KVStore/Set"
-	KVStore_Delete_FullMethodName = "/kvstore.v1.KVStore/Delete"
-	KVStore_List_FullMethodName   = "/kvstore.v1.KVStore/List"
+	KVStore_Get_FullMethodName           = "/kvstore.v1.KVStore/Get"
+	KVStore_Set_FullMethodName           = "/kvstore.v1.KVStore/Set"
+	KVStore_Delete_FullMethodName        = "/kvstore.v1.KVStore/Delete"
+	KVStore_List_FullMethodName          = "/kvstore.v1.KVStore/List"
+	KVStore_Increment_FullMethodName     = "/kvstore.v1.KVStore/Increment"
+	KVStore_AddMembers_FullMethodName    = "/kvstore.v1.KVStore/AddMembers"
+	KVStore_RemoveMembers_FullMethodName = "/kvstore.v1.KVStore/RemoveMembers"
+	KVStore_GetShardMap_FullMethodName   = "/kvstore.v1.KVStore/GetShardMap"
 )
 
 // KVStoreClient is the client API for KVStore service.
@@ -34,6 +38,20 @@ type KVStoreClient interface {
 	Set(ctx context.Context, in *SetRequest, opts ...grpc.CallOption) (*SetResponse, error)
 	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
 	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error)
+	// Increment adds to a counter, and AddMembers and RemoveMembers change a
+	// set of strings. These typed values need active-active replication,
+	// where concurrent changes made on different sites all take effect; other
+	// servers reject them with FAILED_PRECONDITION. Get and List read a
+	// counter as its total in decimal and a set as a sorted JSON array of its
+	// members. Set and Delete replace either like any other value, and
+	// changing a key that holds another kind of value fails with
+	// FAILED_PRECONDITION.
+	Increment(ctx context.Context, in *IncrementRequest, opts ...grpc.CallOption) (*IncrementResponse, error)
+	AddMembers(ctx context.Context, in *MembersRequest, opts ...grpc.CallOption) (*MembersResponse, error)
+	RemoveMembers(ctx context.Context, in *MembersRequest, opts ...grpc.CallOption) (*MembersResponse, error)
+	// Returns the shard map of a sharded deployment, so clients and routing
+	// layers can send each key straight to the node that owns it.
+	GetShardMap(ctx context.Context, in *GetShardMapRequest, opts ...grpc.CallOption) (*GetShardMapResponse, error)
 }
 
 type kVStoreClient struct {
@@ -84,6 +102,46 @@ func (c *kVStoreClient) List(ctx context.Context, in *ListRequest, opts ...grpc.
 	return out, nil
 }
 
+func (c *kVStoreClient) Increment(ctx context.Context, in *IncrementRequest, opts ...grpc.CallOption) (*IncrementResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(IncrementResponse)
+	err := c.cc.Invoke(ctx, KVStore_Increment_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVStoreClient) AddMembers(ctx context.Context, in *MembersRequest, opts ...grpc.CallOption) (*MembersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MembersResponse)
+	err := c.cc.Invoke(ctx, KVStore_AddMembers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVStoreClient) RemoveMembers(ctx context.Context, in *MembersRequest, opts ...grpc.CallOption) (*MembersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MembersResponse)
+	err := c.cc.Invoke(ctx, KVStore_RemoveMembers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVStoreClient) GetShardMap(ctx context.Context, in *GetShardMapRequest, opts ...grpc.CallOption) (*GetShardMapResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetShardMapResponse)
+	err := c.cc.Invoke(ctx, KVStore_GetShardMap_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // KVStoreServer is the server API for KVStore service.
 // All implementations must embed UnimplementedKVStoreServer
 // for forward compatibility.
@@ -92,6 +150,20 @@ type KVStoreServer interface {
 	Set(context.Context, *SetRequest) (*SetResponse, error)
 	Delete(context.Context, *DeleteRequest) (*DeleteResponse, error)
 	List(context.Context, *ListRequest) (*ListResponse, error)
+	// Increment adds to a counter, and AddMembers and RemoveMembers change a
+	// set of strings. These typed values need active-active replication,
+	// where concurrent changes made on different sites all take effect; other
+	// servers reject them with FAILED_PRECONDITION. Get and List read a
+	// counter as its total in decimal and a set as a sorted JSON array of its
+	// members. Set and Delete replace either like any other value, and
+	// changing a key that holds another kind of value fails with
+	// FAILED_PRECONDITION.
+	Increment(context.Context, *IncrementRequest) (*IncrementResponse, error)
+	AddMembers(context.Context, *MembersRequest) (*MembersResponse, error)
+	RemoveMembers(context.Context, *MembersRequest) (*MembersResponse, error)
+	// Returns the shard map of a sharded deployment, so clients and routing
+	// layers can send each key straight to the node that owns it.
+	GetShardMap(context.Context, *GetShardMapRequest) (*GetShardMapResponse, error)
 	mustEmbedUnimplementedKVStoreServer()
 }
 
@@ -114,6 +186,18 @@ func (UnimplementedKVStoreServer) Delete(context.Context, *DeleteRequest) (*Dele
 func (UnimplementedKVStoreServer) List(context.Context, *ListRequest) (*ListResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
 }
+func (UnimplementedKVStoreServer) Increment(context.Context, *IncrementRequest) (*IncrementResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Increment not implemented")
+}
+func (UnimplementedKVStoreServer) AddMembers(context.Context, *MembersRequest) (*MembersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddMembers not implemented")
+}
+func (UnimplementedKVStoreServer) RemoveMembers(context.Context, *MembersRequest) (*MembersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveMembers not implemented")
+}
+func (UnimplementedKVStoreServer) GetShardMap(context.Context, *GetShardMapRequest) (*GetShardMapResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetShardMap not implemented")
+}
 func (UnimplementedKVStoreServer) mustEmbedUnimplementedKVStoreServer() {}
 func (UnimplementedKVStoreServer) testEmbeddedByValue()                 {}
 
@@ -207,6 +291,78 @@ func _KVStore_List_Handler(srv interface{}, ctx context.Context, dec func(interf
 	return interceptor(ctx, in, info, handler)
 }
 
+func _KVStore_Increment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IncrementRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVStoreServer).Increment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVStore_Increment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVStoreServer).Increment(ctx, req.(*IncrementRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVStore_AddMembers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MembersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVStoreServer).AddMembers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVStore_AddMembers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVStoreServer).AddMembers(ctx, req.(*MembersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVStore_RemoveMembers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MembersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVStoreServer).RemoveMembers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVStore_RemoveMembers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVStoreServer).RemoveMembers(ctx, req.(*MembersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVStore_GetShardMap_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetShardMapRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVStoreServer).GetShardMap(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVStore_GetShardMap_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVStoreServer).GetShardMap(ctx, req.(*GetShardMapRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // KVStore_ServiceDesc is the grpc.ServiceDesc for KVStore service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -230,6 +386,22 @@ var KVStore_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "List",
 			Handler:    _KVStore_List_Handler,
 		},
+		{
+			MethodName: "Increment",
+			Handler:    _KVStore_Increment_Handler,
+		},
+		{
+			MethodName: "AddMembers",
+			Handler:    _KVStore_AddMembers_Handler,
+		},
+		{
+			MethodName: "RemoveMembers",
+			Handler:    _KVStore_RemoveMembers_Handler,
+		},
+		{
+			MethodName: "GetShardMap",
+			Handler:    _KVStore_GetShardMap_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "api/proto/kvstore.proto",
@@ -0,0 +1,996 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: api/proto/replication.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type StreamMutationsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// log_id and offset identify the last mutation the replica applied. A
+	// replica that has applied nothing yet leaves log_id empty.
+	LogId  string `protobuf:"bytes,1,opt,name=log_id,json=logId,proto3" json:"log_id,omitempty"`
+	Offset uint64 `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	// replica_id names the replica in the primary's replication status.
+	ReplicaId     string `protobuf:"bytes,3,opt,name=replica_id,json=replicaId,proto3" json:"replica_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamMutationsRequest) Reset() {
+	*x = StreamMutationsRequest{}
+	mi := &file_api_proto_replication_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamMutationsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamMutationsRequest) ProtoMessage() {}
+
+func (x *StreamMutationsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_replication_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamMutationsRequest.ProtoReflect.Descriptor instead.
+func (*StreamMutationsRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_replication_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *StreamMutationsRequest) GetLogId() string {
+	if x != nil {
+		return x.LogId
+	}
+	return ""
+}
+
+func (x *StreamMutationsRequest) GetOffset() uint64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *StreamMutationsRequest) GetReplicaId() string {
+	if x != nil {
+		return x.ReplicaId
+	}
+	return ""
+}
+
+type StreamMutationsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Message:
+	//
+	//	*StreamMutationsResponse_Mutation
+	//	*StreamMutationsResponse_Snapshot
+	//	*StreamMutationsResponse_Heartbeat
+	Message       isStreamMutationsResponse_Message `protobuf_oneof:"message"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamMutationsResponse) Reset() {
+	*x = StreamMutationsResponse{}
+	mi := &file_api_proto_replication_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamMutationsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamMutationsResponse) ProtoMessage() {}
+
+func (x *StreamMutationsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_replication_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamMutationsResponse.ProtoReflect.Descriptor instead.
+func (*StreamMutationsResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_replication_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *StreamMutationsResponse) GetMessage() isStreamMutationsResponse_Message {
+	if x != nil {
+		return x.Message
+	}
+	return nil
+}
+
+func (x *StreamMutationsResponse) GetMutation() *Mutation {
+	if x != nil {
+		if x, ok := x.Message.(*StreamMutationsResponse_Mutation); ok {
+			return x.Mutation
+		}
+	}
+	return nil
+}
+
+func (x *StreamMutationsResponse) GetSnapshot() *SnapshotChunk {
+	if x != nil {
+		if x, ok := x.Message.(*StreamMutationsResponse_Snapshot); ok {
+			return x.Snapshot
+		}
+	}
+	return nil
+}
+
+func (x *StreamMutationsResponse) GetHeartbeat() *Heartbeat {
+	if x != nil {
+		if x, ok := x.Message.(*StreamMutationsResponse_Heartbeat); ok {
+			return x.Heartbeat
+		}
+	}
+	return nil
+}
+
+type isStreamMutationsResponse_Message interface {
+	isStreamMutationsResponse_Message()
+}
+
+type StreamMutationsResponse_Mutation struct {
+	Mutation *Mutation `protobuf:"bytes,1,opt,name=mutation,proto3,oneof"`
+}
+
+type StreamMutationsResponse_Snapshot struct {
+	Snapshot *SnapshotChunk `protobuf:"bytes,2,opt,name=snapshot,proto3,oneof"`
+}
+
+type StreamMutationsResponse_Heartbeat struct {
+	Heartbeat *Heartbeat `protobuf:"bytes,3,opt,name=heartbeat,proto3,oneof"`
+}
+
+func (*StreamMutationsResponse_Mutation) isStreamMutationsResponse_Message() {}
+
+func (*StreamMutationsResponse_Snapshot) isStreamMutationsResponse_Message() {}
+
+func (*StreamMutationsResponse_Heartbeat) isStreamMutationsResponse_Message() {}
+
+// Mutation is one write applied by the primary.
+type Mutation struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Offsets increase by one with every mutation of a log.
+	Offset uint64 `protobuf:"varint,1,opt,name=offset,proto3" json:"offset,omitempty"`
+	// JSON-encoded operation, replayed as is by replicas.
+	Data          []byte `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Mutation) Reset() {
+	*x = Mutation{}
+	mi := &file_api_proto_replication_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Mutation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Mutation) ProtoMessage() {}
+
+func (x *Mutation) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_replication_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Mutation.ProtoReflect.Descriptor instead.
+func (*Mutation) Descriptor() ([]byte, []int) {
+	return file_api_proto_replication_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Mutation) GetOffset() uint64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *Mutation) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+// SnapshotChunk is part of the primary's complete state as of offset. The
+// replica replaces its own state once it has received the last chunk and
+// continues from offset with mutations of log_id.
+type SnapshotChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	LogId         string                 `protobuf:"bytes,1,opt,name=log_id,json=logId,proto3" json:"log_id,omitempty"`
+	Offset        uint64                 `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	Data          []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	Last          bool                   `protobuf:"varint,4,opt,name=last,proto3" json:"last,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SnapshotChunk) Reset() {
+	*x = SnapshotChunk{}
+	mi := &file_api_proto_replication_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SnapshotChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SnapshotChunk) ProtoMessage() {}
+
+func (x *SnapshotChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_replication_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SnapshotChunk.ProtoReflect.Descriptor instead.
+func (*SnapshotChunk) Descriptor() ([]byte, []int) {
+	return file_api_proto_replication_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SnapshotChunk) GetLogId() string {
+	if x != nil {
+		return x.LogId
+	}
+	return ""
+}
+
+func (x *SnapshotChunk) GetOffset() uint64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *SnapshotChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *SnapshotChunk) GetLast() bool {
+	if x != nil {
+		return x.Last
+	}
+	return false
+}
+
+// Heartbeat is sent while the primary has nothing else to send, so that
+// replicas know they are still connected and how far behind they are.
+type Heartbeat struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// offset is the last mutation the primary has applied.
+	Offset uint64 `protobuf:"varint,1,opt,name=offset,proto3" json:"offset,omitempty"`
+	// seen is sent by sites: how far the site had merged the changes of each
+	// other site as of offset, a JSON object of hybrid logical timestamps by
+	// site ID. Peers collect the tombstones every site has merged.
+	Seen          []byte `protobuf:"bytes,2,opt,name=seen,proto3" json:"seen,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Heartbeat) Reset() {
+	*x = Heartbeat{}
+	mi := &file_api_proto_replication_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Heartbeat) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Heartbeat) ProtoMessage() {}
+
+func (x *Heartbeat) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_replication_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Heartbeat.ProtoReflect.Descriptor instead.
+func (*Heartbeat) Descriptor() ([]byte, []int) {
+	return file_api_proto_replication_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Heartbeat) GetOffset() uint64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *Heartbeat) GetSeen() []byte {
+	if x != nil {
+		return x.Seen
+	}
+	return nil
+}
+
+type GetMerkleTreesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Each namespace is split into 2^depth key ranges. Zero uses 10; at most
+	// 16.
+	Depth         uint32 `protobuf:"varint,1,opt,name=depth,proto3" json:"depth,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMerkleTreesRequest) Reset() {
+	*x = GetMerkleTreesRequest{}
+	mi := &file_api_proto_replication_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMerkleTreesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMerkleTreesRequest) ProtoMessage() {}
+
+func (x *GetMerkleTreesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_replication_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMerkleTreesRequest.ProtoReflect.Descriptor instead.
+func (*GetMerkleTreesRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_replication_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetMerkleTreesRequest) GetDepth() uint32 {
+	if x != nil {
+		return x.Depth
+	}
+	return 0
+}
+
+// The trees reflect every mutation of log_id up to offset. A key belongs to
+// the range numbered by the top depth bits of the 64-bit FNV-1a hash of its
+// bytes. The hash of a range is the SHA-256 of its records in key order,
+// each encoded as the key, value and owner, every one preceded by its
+// length as a uvarint, followed by the expiry as a varint.
+type GetMerkleTreesResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The last mutation of log_id logged once the trees were built. The trees
+	// may lack mutations logged while they were.
+	LogId  string `protobuf:"bytes,1,opt,name=log_id,json=logId,proto3" json:"log_id,omitempty"`
+	Offset uint64 `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	Depth  uint32 `protobuf:"varint,3,opt,name=depth,proto3" json:"depth,omitempty"`
+	// Sorted by name.
+	Namespaces    []*NamespaceTree `protobuf:"bytes,4,rep,name=namespaces,proto3" json:"namespaces,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMerkleTreesResponse) Reset() {
+	*x = GetMerkleTreesResponse{}
+	mi := &file_api_proto_replication_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMerkleTreesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMerkleTreesResponse) ProtoMessage() {}
+
+func (x *GetMerkleTreesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_replication_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMerkleTreesResponse.ProtoReflect.Descriptor instead.
+func (*GetMerkleTreesResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_replication_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetMerkleTreesResponse) GetLogId() string {
+	if x != nil {
+		return x.LogId
+	}
+	return ""
+}
+
+func (x *GetMerkleTreesResponse) GetOffset() uint64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *GetMerkleTreesResponse) GetDepth() uint32 {
+	if x != nil {
+		return x.Depth
+	}
+	return 0
+}
+
+func (x *GetMerkleTreesResponse) GetNamespaces() []*NamespaceTree {
+	if x != nil {
+		return x.Namespaces
+	}
+	return nil
+}
+
+type NamespaceTree struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Namespace string                 `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	// JSON-encoded namespace settings, for creating it on a replica that
+	// lacks it.
+	Settings []byte `protobuf:"bytes,2,opt,name=settings,proto3" json:"settings,omitempty"`
+	// The hash of every range, in order; empty for ranges without keys.
+	Ranges        [][]byte `protobuf:"bytes,3,rep,name=ranges,proto3" json:"ranges,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NamespaceTree) Reset() {
+	*x = NamespaceTree{}
+	mi := &file_api_proto_replication_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NamespaceTree) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NamespaceTree) ProtoMessage() {}
+
+func (x *NamespaceTree) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_replication_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NamespaceTree.ProtoReflect.Descriptor instead.
+func (*NamespaceTree) Descriptor() ([]byte, []int) {
+	return file_api_proto_replication_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *NamespaceTree) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *NamespaceTree) GetSettings() []byte {
+	if x != nil {
+		return x.Settings
+	}
+	return nil
+}
+
+func (x *NamespaceTree) GetRanges() [][]byte {
+	if x != nil {
+		return x.Ranges
+	}
+	return nil
+}
+
+type GetRangeRecordsRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Namespace string                 `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	// As in GetMerkleTreesRequest.
+	Depth         uint32 `protobuf:"varint,2,opt,name=depth,proto3" json:"depth,omitempty"`
+	Range         uint32 `protobuf:"varint,3,opt,name=range,proto3" json:"range,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRangeRecordsRequest) Reset() {
+	*x = GetRangeRecordsRequest{}
+	mi := &file_api_proto_replication_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRangeRecordsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRangeRecordsRequest) ProtoMessage() {}
+
+func (x *GetRangeRecordsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_replication_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRangeRecordsRequest.ProtoReflect.Descriptor instead.
+func (*GetRangeRecordsRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_replication_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetRangeRecordsRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *GetRangeRecordsRequest) GetDepth() uint32 {
+	if x != nil {
+		return x.Depth
+	}
+	return 0
+}
+
+func (x *GetRangeRecordsRequest) GetRange() uint32 {
+	if x != nil {
+		return x.Range
+	}
+	return 0
+}
+
+type GetRangeRecordsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The records reflect every mutation of log_id up to offset, and perhaps
+	// later ones.
+	LogId  string `protobuf:"bytes,1,opt,name=log_id,json=logId,proto3" json:"log_id,omitempty"`
+	Offset uint64 `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	// JSON-encoded records, sorted by key.
+	Records       []byte `protobuf:"bytes,3,opt,name=records,proto3" json:"records,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRangeRecordsResponse) Reset() {
+	*x = GetRangeRecordsResponse{}
+	mi := &file_api_proto_replication_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRangeRecordsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRangeRecordsResponse) ProtoMessage() {}
+
+func (x *GetRangeRecordsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_replication_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRangeRecordsResponse.ProtoReflect.Descriptor instead.
+func (*GetRangeRecordsResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_replication_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetRangeRecordsResponse) GetLogId() string {
+	if x != nil {
+		return x.LogId
+	}
+	return ""
+}
+
+func (x *GetRangeRecordsResponse) GetOffset() uint64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *GetRangeRecordsResponse) GetRecords() []byte {
+	if x != nil {
+		return x.Records
+	}
+	return nil
+}
+
+type StreamChangesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// log_id and offset identify the last change the calling site merged.
+	// A site that has merged nothing yet leaves log_id empty.
+	LogId  string `protobuf:"bytes,1,opt,name=log_id,json=logId,proto3" json:"log_id,omitempty"`
+	Offset uint64 `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	// site_id names the calling site in the streaming site's status.
+	SiteId        string `protobuf:"bytes,3,opt,name=site_id,json=siteId,proto3" json:"site_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamChangesRequest) Reset() {
+	*x = StreamChangesRequest{}
+	mi := &file_api_proto_replication_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamChangesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamChangesRequest) ProtoMessage() {}
+
+func (x *StreamChangesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_replication_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamChangesRequest.ProtoReflect.Descriptor instead.
+func (*StreamChangesRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_replication_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *StreamChangesRequest) GetLogId() string {
+	if x != nil {
+		return x.LogId
+	}
+	return ""
+}
+
+func (x *StreamChangesRequest) GetOffset() uint64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *StreamChangesRequest) GetSiteId() string {
+	if x != nil {
+		return x.SiteId
+	}
+	return ""
+}
+
+// Changes are JSON-encoded, and so is the state in snapshot chunks. Both
+// are merged rather than replayed, so receiving them twice is harmless.
+type StreamChangesResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Message:
+	//
+	//	*StreamChangesResponse_Change
+	//	*StreamChangesResponse_Snapshot
+	//	*StreamChangesResponse_Heartbeat
+	Message isStreamChangesResponse_Message `protobuf_oneof:"message"`
+	// site_id names the streaming site.
+	SiteId        string `protobuf:"bytes,4,opt,name=site_id,json=siteId,proto3" json:"site_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamChangesResponse) Reset() {
+	*x = StreamChangesResponse{}
+	mi := &file_api_proto_replication_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamChangesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamChangesResponse) ProtoMessage() {}
+
+func (x *StreamChangesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_replication_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamChangesResponse.ProtoReflect.Descriptor instead.
+func (*StreamChangesResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_replication_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *StreamChangesResponse) GetMessage() isStreamChangesResponse_Message {
+	if x != nil {
+		return x.Message
+	}
+	return nil
+}
+
+func (x *StreamChangesResponse) GetChange() *Mutation {
+	if x != nil {
+		if x, ok := x.Message.(*StreamChangesResponse_Change); ok {
+			return x.Change
+		}
+	}
+	return nil
+}
+
+func (x *StreamChangesResponse) GetSnapshot() *SnapshotChunk {
+	if x != nil {
+		if x, ok := x.Message.(*StreamChangesResponse_Snapshot); ok {
+			return x.Snapshot
+		}
+	}
+	return nil
+}
+
+func (x *StreamChangesResponse) GetHeartbeat() *Heartbeat {
+	if x != nil {
+		if x, ok := x.Message.(*StreamChangesResponse_Heartbeat); ok {
+			return x.Heartbeat
+		}
+	}
+	return nil
+}
+
+func (x *StreamChangesResponse) GetSiteId() string {
+	if x != nil {
+		return x.SiteId
+	}
+	return ""
+}
+
+type isStreamChangesResponse_Message interface {
+	isStreamChangesResponse_Message()
+}
+
+type StreamChangesResponse_Change struct {
+	Change *Mutation `protobuf:"bytes,1,opt,name=change,proto3,oneof"`
+}
+
+type StreamChangesResponse_Snapshot struct {
+	Snapshot *SnapshotChunk `protobuf:"bytes,2,opt,name=snapshot,proto3,oneof"`
+}
+
+type StreamChangesResponse_Heartbeat struct {
+	Heartbeat *Heartbeat `protobuf:"bytes,3,opt,name=heartbeat,proto3,oneof"`
+}
+
+func (*StreamChangesResponse_Change) isStreamChangesResponse_Message() {}
+
+func (*StreamChangesResponse_Snapshot) isStreamChangesResponse_Message() {}
+
+func (*StreamChangesResponse_Heartbeat) isStreamChangesResponse_Message() {}
+
+var File_api_proto_replication_proto protoreflect.FileDescriptor
+
+const file_api_proto_replication_proto_rawDesc = "" +
+	"\n" +
+	"\x1bapi/proto/replication.proto\x12\n" +
+	"kvstore.v1\"f\n" +
+	"\x16StreamMutationsRequest\x12\x15\n" +
+	"\x06log_id\x18\x01 \x01(\tR\x05logId\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x04R\x06offset\x12\x1d\n" +
+	"\n" +
+	"replica_id\x18\x03 \x01(\tR\treplicaId\"\xc8\x01\n" +
+	"\x17StreamMutationsResponse\x122\n" +
+	"\bmutation\x18\x01 \x01(\v2\x14.kvstore.v1.MutationH\x00R\bmutation\x127\n" +
+	"\bsnapshot\x18\x02 \x01(\v2\x19.kvstore.v1.SnapshotChunkH\x00R\bsnapshot\x125\n" +
+	"\theartbeat\x18\x03 \x01(\v2\x15.kvstore.v1.HeartbeatH\x00R\theartbeatB\t\n" +
+	"\amessage\"6\n" +
+	"\bMutation\x12\x16\n" +
+	"\x06offset\x18\x01 \x01(\x04R\x06offset\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\"f\n" +
+	"\rSnapshotChunk\x12\x15\n" +
+	"\x06log_id\x18\x01 \x01(\tR\x05logId\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x04R\x06offset\x12\x12\n" +
+	"\x04data\x18\x03 \x01(\fR\x04data\x12\x12\n" +
+	"\x04last\x18\x04 \x01(\bR\x04last\"7\n" +
+	"\tHeartbeat\x12\x16\n" +
+	"\x06offset\x18\x01 \x01(\x04R\x06offset\x12\x12\n" +
+	"\x04seen\x18\x02 \x01(\fR\x04seen\"-\n" +
+	"\x15GetMerkleTreesRequest\x12\x14\n" +
+	"\x05depth\x18\x01 \x01(\rR\x05depth\"\x98\x01\n" +
+	"\x16GetMerkleTreesResponse\x12\x15\n" +
+	"\x06log_id\x18\x01 \x01(\tR\x05logId\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x04R\x06offset\x12\x14\n" +
+	"\x05depth\x18\x03 \x01(\rR\x05depth\x129\n" +
+	"\n" +
+	"namespaces\x18\x04 \x03(\v2\x19.kvstore.v1.NamespaceTreeR\n" +
+	"namespaces\"a\n" +
+	"\rNamespaceTree\x12\x1c\n" +
+	"\tnamespace\x18\x01 \x01(\tR\tnamespace\x12\x1a\n" +
+	"\bsettings\x18\x02 \x01(\fR\bsettings\x12\x16\n" +
+	"\x06ranges\x18\x03 \x03(\fR\x06ranges\"b\n" +
+	"\x16GetRangeRecordsRequest\x12\x1c\n" +
+	"\tnamespace\x18\x01 \x01(\tR\tnamespace\x12\x14\n" +
+	"\x05depth\x18\x02 \x01(\rR\x05depth\x12\x14\n" +
+	"\x05range\x18\x03 \x01(\rR\x05range\"b\n" +
+	"\x17GetRangeRecordsResponse\x12\x15\n" +
+	"\x06log_id\x18\x01 \x01(\tR\x05logId\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x04R\x06offset\x12\x18\n" +
+	"\arecords\x18\x03 \x01(\fR\arecords\"^\n" +
+	"\x14StreamChangesRequest\x12\x15\n" +
+	"\x06log_id\x18\x01 \x01(\tR\x05logId\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x04R\x06offset\x12\x17\n" +
+	"\asite_id\x18\x03 \x01(\tR\x06siteId\"\xdb\x01\n" +
+	"\x15StreamChangesResponse\x12.\n" +
+	"\x06change\x18\x01 \x01(\v2\x14.kvstore.v1.MutationH\x00R\x06change\x127\n" +
+	"\bsnapshot\x18\x02 \x01(\v2\x19.kvstore.v1.SnapshotChunkH\x00R\bsnapshot\x125\n" +
+	"\theartbeat\x18\x03 \x01(\v2\x15.kvstore.v1.HeartbeatH\x00R\theartbeat\x12\x17\n" +
+	"\asite_id\x18\x04 \x01(\tR\x06siteIdB\t\n" +
+	"\amessage2\xf8\x02\n" +
+	"\vReplication\x12\\\n" +
+	"\x0fStreamMutations\x12\".kvstore.v1.StreamMutationsRequest\x1a#.kvstore.v1.StreamMutationsResponse0\x01\x12W\n" +
+	"\x0eGetMerkleTrees\x12!.kvstore.v1.GetMerkleTreesRequest\x1a\".kvstore.v1.GetMerkleTreesResponse\x12Z\n" +
+	"\x0fGetRangeRecords\x12\".kvstore.v1.GetRangeRecordsRequest\x1a#.kvstore.v1.GetRangeRecordsResponse\x12V\n" +
+	"\rStreamChanges\x12 .kvstore.v1.StreamChangesRequest\x1a!.kvstore.v1.StreamChangesResponse0\x01B,Z*github.com/khuongnguyenBlue/kvstore/pkg/pbb\x06proto3"
+
+var (
+	file_api_proto_replication_proto_rawDescOnce sync.Once
+	file_api_proto_replication_proto_rawDescData []byte
+)
+
+func file_api_proto_replication_proto_rawDescGZIP() []byte {
+	file_api_proto_replication_proto_rawDescOnce.Do(func() {
+		file_api_proto_replication_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_api_proto_replication_proto_rawDesc), len(file_api_proto_replication_proto_rawDesc)))
+	})
+	return file_api_proto_replication_proto_rawDescData
+}
+
+var file_api_proto_replication_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_api_proto_replication_proto_goTypes = []any{
+	(*StreamMutationsRequest)(nil),  // 0: kvstore.v1.StreamMutationsRequest
+	(*StreamMutationsResponse)(nil), // 1: kvstore.v1.StreamMutationsResponse
+	(*Mutation)(nil),                // 2: kvstore.v1.Mutation
+	(*SnapshotChunk)(nil),           // 3: kvstore.v1.SnapshotChunk
+	(*Heartbeat)(nil),               // 4: kvstore.v1.Heartbeat
+	(*GetMerkleTreesRequest)(nil),   // 5: kvstore.v1.GetMerkleTreesRequest
+	(*GetMerkleTreesResponse)(nil),  // 6: kvstore.v1.GetMerkleTreesResponse
+	(*NamespaceTree)(nil),           // 7: kvstore.v1.NamespaceTree
+	(*GetRangeRecordsRequest)(nil),  // 8: kvstore.v1.GetRangeRecordsRequest
+	(*GetRangeRecordsResponse)(nil), // 9: kvstore.v1.GetRangeRecordsResponse
+	(*StreamChangesRequest)(nil),    // 10: kvstore.v1.StreamChangesRequest
+	(*StreamChangesResponse)(nil),   // 11: kvstore.v1.StreamChangesResponse
+}
+var file_api_proto_replication_proto_depIdxs = []int32{
+	2,  // 0: kvstore.v1.StreamMutationsResponse.mutation:type_name -> kvstore.v1.Mutation
+	3,  // 1: kvstore.v1.StreamMutationsResponse.snapshot:type_name -> kvstore.v1.SnapshotChunk
+	4,  // 2: kvstore.v1.StreamMutationsResponse.heartbeat:type_name -> kvstore.v1.Heartbeat
+	7,  // 3: kvstore.v1.GetMerkleTreesResponse.namespaces:type_name -> kvstore.v1.NamespaceTree
+	2,  // 4: kvstore.v1.StreamChangesResponse.change:type_name -> kvstore.v1.Mutation
+	3,  // 5: kvstore.v1.StreamChangesResponse.snapshot:type_name -> kvstore.v1.SnapshotChunk
+	4,  // 6: kvstore.v1.StreamChangesResponse.heartbeat:type_name -> kvstore.v1.Heartbeat
+	0,  // 7: kvstore.v1.Replication.StreamMutations:input_type -> kvstore.v1.StreamMutationsRequest
+	5,  // 8: kvstore.v1.Replication.GetMerkleTrees:input_type -> kvstore.v1.GetMerkleTreesRequest
+	8,  // 9: kvstore.v1.Replication.GetRangeRecords:input_type -> kvstore.v1.GetRangeRecordsRequest
+	10, // 10: kvstore.v1.Replication.StreamChanges:input_type -> kvstore.v1.StreamChangesRequest
+	1,  // 11: kvstore.v1.Replication.StreamMutations:output_type -> kvstore.v1.StreamMutationsResponse
+	6,  // 12: kvstore.v1.Replication.GetMerkleTrees:output_type -> kvstore.v1.GetMerkleTreesResponse
+	9,  // 13: kvstore.v1.Replication.GetRangeRecords:output_type -> kvstore.v1.GetRangeRecordsResponse
+	11, // 14: kvstore.v1.Replication.StreamChanges:output_type -> kvstore.v1.StreamChangesResponse
+	11, // [11:15] is the sub-list for method output_type
+	7,  // [7:11] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
+}
+
+func init() { file_api_proto_replication_proto_init() }
+func file_api_proto_replication_proto_init() {
+	if File_api_proto_replication_proto != nil {
+		return
+	}
+	file_api_proto_replication_proto_msgTypes[1].OneofWrappers = []any{
+		(*StreamMutationsResponse_Mutation)(nil),
+		(*StreamMutationsResponse_Snapshot)(nil),
+		(*StreamMutationsResponse_Heartbeat)(nil),
+	}
+	file_api_proto_replication_proto_msgTypes[11].OneofWrappers = []any{
+		(*StreamChangesResponse_Change)(nil),
+		(*StreamChangesResponse_Snapshot)(nil),
+		(*StreamChangesResponse_Heartbeat)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_proto_replication_proto_rawDesc), len(file_api_proto_replication_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   12,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_api_proto_replication_proto_goTypes,
+		DependencyIndexes: file_api_proto_replication_proto_depIdxs,
+		MessageInfos:      file_api_proto_replication_proto_msgTypes,
+	}.Build()
+	File_api_proto_replication_proto = out.File
+	file_api_proto_replication_proto_goTypes = nil
+	file_api_proto_replication_proto_depIdxs = nil
+}
@@ -0,0 +1,279 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: api/proto/replication.proto
+
+package pb
+
+import (
+	context "context"
+
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Replication_StreamMutations_FullMethodName = "/kvstore.v1.Replication/StreamMutations"
+	Replication_GetMerkleTrees_FullMethodName  = "/kvstore.v1.Replication/GetMerkleTrees"
+	Replication_GetRangeRecords_FullMethodName = "/kvstore.v1.Replication/GetRangeRecords"
+	Replication_StreamChanges_FullMethodName   = "/kvstore.v1.Replication/StreamChanges"
+)
+
+// ReplicationClient is the client API for Replication service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Replication streams the writes of a primary server to read-only replicas,
+// and lets replicas compare their state with the primary's to repair what
+// diverged. Replicas call it on the primary, and active-active sites on
+// each other; it requires the admin permission.
+type ReplicationClient interface {
+	// StreamMutations sends every mutation after the one the replica last
+	// applied, in order, preceded by a full snapshot when the primary no
+	// longer retains them. The stream stays open until either side goes
+	// away.
+	StreamMutations(ctx context.Context, in *StreamMutationsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StreamMutationsResponse], error)
+	// GetMerkleTrees returns a Merkle tree over the key ranges of every
+	// namespace. A replica that has applied the same offset builds its own
+	// and compares them to find the ranges where the two diverged.
+	GetMerkleTrees(ctx context.Context, in *GetMerkleTreesRequest, opts ...grpc.CallOption) (*GetMerkleTreesResponse, error)
+	// GetRangeRecords returns the records of one key range of a namespace,
+	// for a replica to repair the range with.
+	GetRangeRecords(ctx context.Context, in *GetRangeRecordsRequest, opts ...grpc.CallOption) (*GetRangeRecordsResponse, error)
+	// StreamChanges sends every change made on an active-active site after
+	// the one the calling site last merged, in order, preceded by the site's
+	// complete state when it no longer retains them. Only changes made on
+	// the site itself are sent, so every site streams from every other one.
+	StreamChanges(ctx context.Context, in *StreamChangesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StreamChangesResponse], error)
+}
+
+type replicationClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewReplicationClient(cc grpc.ClientConnInterface) ReplicationClient {
+	return &replicationClient{cc}
+}
+
+func (c *replicationClient) StreamMutations(ctx context.Context, in *StreamMutationsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StreamMutationsResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Replication_ServiceDesc.Streams[0], Replication_StreamMutations_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamMutationsRequest, StreamMutationsResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Replication_StreamMutationsClient = grpc.ServerStreamingClient[StreamMutationsResponse]
+
+func (c *replicationClient) GetMerkleTrees(ctx context.Context, in *GetMerkleTreesRequest, opts ...grpc.CallOption) (*GetMerkleTreesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetMerkleTreesResponse)
+	err := c.cc.Invoke(ctx, Replication_GetMerkleTrees_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *replicationClient) GetRangeRecords(ctx context.Context, in *GetRangeRecordsRequest, opts ...grpc.CallOption) (*GetRangeRecordsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetRangeRecordsResponse)
+	err := c.cc.Invoke(ctx, Replication_GetRangeRecords_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *replicationClient) StreamChanges(ctx context.Context, in *StreamChangesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StreamChangesResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Replication_ServiceDesc.Streams[1], Replication_StreamChanges_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamChangesRequest, StreamChangesResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Replication_StreamChangesClient = grpc.ServerStreamingClient[StreamChangesResponse]
+
+// ReplicationServer is the server API for Replication service.
+// All implementations must embed UnimplementedReplicationServer
+// for forward compatibility.
+//
+// Replication streams the writes of a primary server to read-only replicas,
+// and lets replicas compare their state with the primary's to repair what
+// diverged. Replicas call it on the primary, and active-active sites on
+// each other; it requires the admin permission.
+type ReplicationServer interface {
+	// StreamMutations sends every mutation after the one the replica last
+	// applied, in order, preceded by a full snapshot when the primary no
+	// longer retains them. The stream stays open until either side goes
+	// away.
+	StreamMutations(*StreamMutationsRequest, grpc.ServerStreamingServer[StreamMutationsResponse]) error
+	// GetMerkleTrees returns a Merkle tree over the key ranges of every
+	// namespace. A replica that has applied the same offset builds its own
+	// and compares them to find the ranges where the two diverged.
+	GetMerkleTrees(context.Context, *GetMerkleTreesRequest) (*GetMerkleTreesResponse, error)
+	// GetRangeRecords returns the records of one key range of a namespace,
+	// for a replica to repair the range with.
+	GetRangeRecords(context.Context, *GetRangeRecordsRequest) (*GetRangeRecordsResponse, error)
+	// StreamChanges sends every change made on an active-active site after
+	// the one the calling site last merged, in order, preceded by the site's
+	// complete state when it no longer retains them. Only changes made on
+	// the site itself are sent, so every site streams from every other one.
+	StreamChanges(*StreamChangesRequest, grpc.ServerStreamingServer[StreamChangesResponse]) error
+	mustEmbedUnimplementedReplicationServer()
+}
+
+// UnimplementedReplicationServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedReplicationServer struct{}
+
+func (UnimplementedReplicationServer) StreamMutations(*StreamMutationsRequest, grpc.ServerStreamingServer[StreamMutationsResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamMutations not implemented")
+}
+func (UnimplementedReplicationServer) GetMerkleTrees(context.Context, *GetMerkleTreesRequest) (*GetMerkleTreesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMerkleTrees not implemented")
+}
+func (UnimplementedReplicationServer) GetRangeRecords(context.Context, *GetRangeRecordsRequest) (*GetRangeRecordsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRangeRecords not implemented")
+}
+func (UnimplementedReplicationServer) StreamChanges(*StreamChangesRequest, grpc.ServerStreamingServer[StreamChangesResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamChanges not implemented")
+}
+func (UnimplementedReplicationServer) mustEmbedUnimplementedReplicationServer() {}
+func (UnimplementedReplicationServer) testEmbeddedByValue()                     {}
+
+// UnsafeReplicationServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ReplicationServer will
+// result in compilation errors.
+type UnsafeReplicationServer interface {
+	mustEmbedUnimplementedReplicationServer()
+}
+
+func RegisterReplicationServer(s grpc.ServiceRegistrar, srv ReplicationServer) {
+	// If the following call pancis, it indicates UnimplementedReplicationServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Replication_ServiceDesc, srv)
+}
+
+func _Replication_StreamMutations_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamMutationsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ReplicationServer).StreamMutations(m, &grpc.GenericServerStream[StreamMutationsRequest, StreamMutationsResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Replication_StreamMutationsServer = grpc.ServerStreamingServer[StreamMutationsResponse]
+
+func _Replication_GetMerkleTrees_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMerkleTreesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReplicationServer).GetMerkleTrees(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Replication_GetMerkleTrees_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReplicationServer).GetMerkleTrees(ctx, req.(*GetMerkleTreesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Replication_GetRangeRecords_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRangeRecordsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReplicationServer).GetRangeRecords(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Replication_GetRangeRecords_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReplicationServer).GetRangeRecords(ctx, req.(*GetRangeRecordsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Replication_StreamChanges_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamChangesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ReplicationServer).StreamChanges(m, &grpc.GenericServerStream[StreamChangesRequest, StreamChangesResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Replication_StreamChangesServer = grpc.ServerStreamingServer[StreamChangesResponse]
+
+// Replication_ServiceDesc is the grpc.ServiceDesc for Replication service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Replication_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "kvstore.v1.Replication",
+	HandlerType: (*ReplicationServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetMerkleTrees",
+			Handler:    _Replication_GetMerkleTrees_Handler,
+		},
+		{
+			MethodName: "GetRangeRecords",
+			Handler:    _Replication_GetRangeRecords_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamMutations",
+			Handler:       _Replication_StreamMutations_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamChanges",
+			Handler:       _Replication_StreamChanges_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "api/proto/replication.proto",
+}